@@ -20,23 +20,43 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/filters/labels"
 
 	"github.com/Peripli/service-manager/pkg/util"
 
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/views"
 	"github.com/Peripli/service-manager/pkg/ws"
 
 	apiNotifications "github.com/Peripli/service-manager/api/notifications"
 
+	"github.com/Peripli/service-manager/api/apidocs"
+	apicfvisibility "github.com/Peripli/service-manager/api/cfvisibility"
+	"github.com/Peripli/service-manager/api/features"
 	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/api/graphql"
 	"github.com/Peripli/service-manager/api/info"
+	"github.com/Peripli/service-manager/api/jobs"
 	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/api/watch"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/cfvisibility"
+	"github.com/Peripli/service-manager/pkg/credentials"
+	pkgfeatures "github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/federation"
 	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	pkgjobs "github.com/Peripli/service-manager/pkg/jobs"
+	"github.com/Peripli/service-manager/pkg/platformtypes"
+	"github.com/Peripli/service-manager/pkg/security/authenticators"
 	secfilters "github.com/Peripli/service-manager/pkg/security/filters"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/Peripli/service-manager/version"
 )
 
 const osbVersion = "2.13"
@@ -49,6 +69,56 @@ type Settings struct {
 	TokenBasicAuth    bool     `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
 	ProctedLabels     []string `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
 	OSBVersion        string   `mapstructure:"-"`
+
+	TokenCacheSize int           `mapstructure:"token_cache_size" description:"maximum number of recently verified bearer tokens whose verification is reused, tokens are verified on every call if 0"`
+	TokenCacheTTL  time.Duration `mapstructure:"token_cache_ttl" description:"time for which the verification of a bearer token is reused, at most until the token expires"`
+
+	CatalogFetchAttempts      int           `mapstructure:"catalog_fetch_attempts" description:"number of attempts to fetch the catalog of a broker registered asynchronously"`
+	CatalogFetchRetryInterval time.Duration `mapstructure:"catalog_fetch_retry_interval" description:"time to wait between attempts to fetch the catalog of a broker registered asynchronously"`
+	DuplicateBrokerURLs       string        `mapstructure:"duplicate_broker_urls" description:"policy for registering a broker whose URL is already registered: allow, reject or adopt, which registers the new name as an alias of the existing broker"`
+	CatalogUploadMaxBytes     int64         `mapstructure:"catalog_upload_max_bytes" description:"maximum size of the uploaded catalogs of brokers labeled with catalog_source=upload, before and after decompression"`
+	CatalogUploadTTL          time.Duration `mapstructure:"catalog_upload_ttl" description:"time after which the received chunks of an incomplete catalog upload are discarded"`
+	CatalogRecoveryInterval   time.Duration `mapstructure:"catalog_recovery_interval" description:"time between the background attempts to fetch the catalogs of brokers whose asynchronous registration failed or was interrupted, 0 disables the recovery"`
+	CatalogRecoveryAttempts   int           `mapstructure:"catalog_recovery_attempts" description:"number of background attempts to fetch the catalog of a broker whose asynchronous registration failed or was interrupted"`
+	BulkDeleteBatchSize       int           `mapstructure:"bulk_delete_batch_size" description:"number of brokers deleted together by an asynchronous bulk delete"`
+
+	OSBCallHistorySize int                   `mapstructure:"osb_call_history_size" description:"number of most recent proxied OSB calls per broker on which the broker statistics are based"`
+	OSBHeaders         *osb.HeaderSettings   `mapstructure:"osb_headers"`
+	OSBResponses       *osb.ResponseSettings `mapstructure:"osb_responses"`
+	BrokerProxy        *osb.ProxySettings    `mapstructure:"broker_proxy"`
+	RequestSigning     *osb.SigningSettings  `mapstructure:"request_signing"`
+
+	OrphanMitigation *osb.OrphanMitigationSettings `mapstructure:"orphan_mitigation"`
+
+	CatalogLabelsMetadata  []string `mapstructure:"catalog_labels_metadata" description:"labels of service offerings and plans exposed as metadata fields in OSB catalogs in the form label=field, a label without field is exposed under its own name"`
+	CatalogPricingMetadata string   `mapstructure:"catalog_pricing_metadata" description:"metadata field under which the pricing of service plans is exposed in OSB catalogs, the pricing is not exposed if empty"`
+	CatalogLifecyclePolicy string   `mapstructure:"catalog_lifecycle_policy" description:"how deprecated and retired service offerings and plans appear in OSB catalogs, flag adds a lifecycle metadata field and hide additionally removes retired ones"`
+	CatalogCurationRules   []string `mapstructure:"catalog_curation_rules" description:"rules hiding or exposing the services and plans of OSB catalogs served to platforms in the form hide|expose tag:tag|metadata.path:value [for platform selector], e.g. hide tag:beta for env = prod, the last matching rule applies"`
+
+	ProvisionContextLabels []string `mapstructure:"provision_context_labels" description:"labels of the calling platform added to the context of OSB provision requests in the form label=field, a label without field is added under its own name"`
+
+	EnforcePlanSchemas bool `mapstructure:"enforce_plan_schemas" description:"whether to reject OSB provision, update and bind requests whose parameters do not match the schemas of the requested plan"`
+
+	FieldScopes []string `mapstructure:"field_scopes" description:"fields of the returned resources which are only serialized for callers with a scope in the form path:field=scope, e.g. /v1/service_brokers:credentials=sm.admin, credentials are only serialized if a rule allows it"`
+
+	CriteriaDebugScope string `mapstructure:"criteria_debug_scope" description:"scope of the users allowed to request the criteria effectively used to query resources with the X-SM-Debug: criteria header, debugging is disabled if empty"`
+
+	Filters []string `mapstructure:"filters" description:"changes of the registered filters applied in order in the form factory[?option=value&...] [before|after filter] to add the filter of a filter factory or -filter to remove a filter, e.g. csrf before BasicAuthnFilter"`
+
+	ResponseCache *filters.ResponseCacheSettings `mapstructure:"response_cache"`
+	LoadShedding  *filters.LoadSheddingSettings  `mapstructure:"load_shedding"`
+	LoginThrottle *filters.LoginThrottleSettings `mapstructure:"login_throttle"`
+	Naming        *filters.NamingSettings        `mapstructure:"naming"`
+
+	CredentialsPolicy *filters.CredentialsPolicySettings `mapstructure:"credentials_policy"`
+	TrustedGateway    *filters.TrustedGatewaySettings    `mapstructure:"trusted_gateway"`
+
+	Watch *watch.Settings `mapstructure:"watch"`
+
+	TenantKeys *TenantKeySettings `mapstructure:"tenant_keys"`
+
+	GraphQLEnabled bool `mapstructure:"graphql_enabled" description:"whether to expose read access to the resources and their relationships via GraphQL"`
+	OperatorMode   bool `mapstructure:"operator_mode" description:"whether to expose the upsert endpoints and the change feed used by Kubernetes operators which manage brokers and visibilities as custom resources"`
 }
 
 // DefaultSettings returns default values for API settings
@@ -60,6 +130,40 @@ func DefaultSettings() *Settings {
 		TokenBasicAuth:    true, // RFC 6749 section 2.3.1
 		OSBVersion:        osbVersion,
 		ProctedLabels:     nil,
+
+		TokenCacheSize: 1000,
+		TokenCacheTTL:  10 * time.Second,
+
+		CatalogFetchAttempts:      3,
+		CatalogFetchRetryInterval: 10 * time.Second,
+		DuplicateBrokerURLs:       DuplicateBrokerURLsAllow,
+		CatalogUploadMaxBytes:     10 * 1024 * 1024,
+		CatalogUploadTTL:          time.Hour,
+		CatalogRecoveryInterval:   5 * time.Minute,
+		CatalogRecoveryAttempts:   12,
+		BulkDeleteBatchSize:       50,
+
+		OSBCallHistorySize: 100,
+		OSBHeaders:         osb.DefaultHeaderSettings(),
+		OSBResponses:       osb.DefaultResponseSettings(),
+		BrokerProxy:        osb.DefaultProxySettings(),
+		RequestSigning:     osb.DefaultSigningSettings(),
+
+		OrphanMitigation: osb.DefaultOrphanMitigationSettings(),
+
+		CatalogLifecyclePolicy: filters.CatalogLifecyclePolicyFlag,
+
+		ResponseCache: filters.DefaultResponseCacheSettings(),
+		LoadShedding:  filters.DefaultLoadSheddingSettings(),
+		LoginThrottle: filters.DefaultLoginThrottleSettings(),
+		Naming:        filters.DefaultNamingSettings(),
+
+		CredentialsPolicy: filters.DefaultCredentialsPolicySettings(),
+		TrustedGateway:    filters.DefaultTrustedGatewaySettings(),
+
+		Watch: watch.DefaultSettings(),
+
+		TenantKeys: DefaultTenantKeySettings(),
 	}
 }
 
@@ -68,6 +172,117 @@ func (s *Settings) Validate() error {
 	if (len(s.TokenIssuerURL)) == 0 {
 		return fmt.Errorf("validate Settings: APITokenIssuerURL missing")
 	}
+	if s.TokenCacheSize < 0 {
+		return fmt.Errorf("validate Settings: TokenCacheSize must not be negative")
+	}
+	if s.TokenCacheTTL < 0 {
+		return fmt.Errorf("validate Settings: TokenCacheTTL must not be negative")
+	}
+	if s.CatalogFetchRetryInterval < 0 {
+		return fmt.Errorf("validate Settings: CatalogFetchRetryInterval must not be negative")
+	}
+	switch s.DuplicateBrokerURLs {
+	case DuplicateBrokerURLsAllow, DuplicateBrokerURLsReject, DuplicateBrokerURLsAdopt:
+	default:
+		return fmt.Errorf("validate Settings: DuplicateBrokerURLs must be one of %s, %s or %s", DuplicateBrokerURLsAllow, DuplicateBrokerURLsReject, DuplicateBrokerURLsAdopt)
+	}
+	if s.CatalogUploadMaxBytes <= 0 {
+		return fmt.Errorf("validate Settings: CatalogUploadMaxBytes must be positive")
+	}
+	if s.CatalogUploadTTL <= 0 {
+		return fmt.Errorf("validate Settings: CatalogUploadTTL must be positive")
+	}
+	if s.CatalogRecoveryInterval < 0 {
+		return fmt.Errorf("validate Settings: CatalogRecoveryInterval must not be negative")
+	}
+	if s.CatalogRecoveryInterval > 0 {
+		// registrations whose catalog fetch is retried must not be considered interrupted
+		if s.CatalogRecoveryInterval <= s.CatalogFetchRetryInterval {
+			return fmt.Errorf("validate Settings: CatalogRecoveryInterval must be greater than CatalogFetchRetryInterval")
+		}
+		if s.CatalogRecoveryAttempts <= 0 {
+			return fmt.Errorf("validate Settings: CatalogRecoveryAttempts must be positive")
+		}
+	}
+	if s.BulkDeleteBatchSize <= 0 {
+		return fmt.Errorf("validate Settings: BulkDeleteBatchSize must be positive")
+	}
+	if s.OSBResponses != nil {
+		if err := s.OSBResponses.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.BrokerProxy != nil {
+		if err := s.BrokerProxy.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.RequestSigning != nil {
+		if err := s.RequestSigning.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.OrphanMitigation != nil {
+		if err := s.OrphanMitigation.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Watch != nil {
+		if err := s.Watch.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.TenantKeys != nil {
+		if err := s.TenantKeys.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := filters.ParseLabelMappings(s.CatalogLabelsMetadata); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if err := filters.ValidateCatalogLifecyclePolicy(s.CatalogLifecyclePolicy); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if _, err := filters.ParseCurationRules(s.CatalogCurationRules); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if _, err := filters.ParseFilterSpecs(s.Filters); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if _, err := filters.ParseLabelMappings(s.ProvisionContextLabels); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if _, err := filters.ParseFieldRules(s.FieldScopes); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if s.ResponseCache != nil {
+		if err := s.ResponseCache.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.LoadShedding != nil {
+		if err := s.LoadShedding.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.LoginThrottle != nil {
+		if err := s.LoginThrottle.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Naming != nil {
+		if err := s.Naming.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.CredentialsPolicy != nil {
+		if err := s.CredentialsPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := s.TrustedGateway.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -76,55 +291,399 @@ type Options struct {
 	APISettings *Settings
 	WSSettings  *ws.Settings
 	Notificator storage.Notificator
+	Features    *pkgfeatures.Manager
+	Scheduler   *pkgjobs.Scheduler
+
+	// FeatureStates keeps the feature states set through the features API, a source of the features manager backed
+	// by the cache store is created if it is nil
+	FeatureStates *pkgfeatures.StoreSource
+
+	// BrokerTransports provides the transports used for proxying calls to brokers
+	BrokerTransports *osb.Transports
+
+	// OSBStats records the OSB calls proxied to the brokers, new statistics are created if it is nil
+	OSBStats *osb.Stats
+
+	// Cache caches the broker lookups of the API, no lookups are cached if it is nil
+	Cache *storage.ObjectCache
+
+	// ResponseCache caches the responses of the service offerings and service plans APIs, no responses are cached if it is nil
+	ResponseCache *filters.ResponseCache
+
+	// LoadShedder queues and sheds the requests when the instance is overloaded, all requests are processed if it is nil
+	LoadShedder *filters.LoadShedder
+
+	// LoginThrottle locks out usernames after repeated failed basic authentications, no failures are throttled if it is nil
+	LoginThrottle *filters.LoginThrottle
+
+	// CFVisibility configures the mapping of the visibilities of Cloud Foundry platforms, no mapping is exposed if it is nil
+	CFVisibility *cfvisibility.Settings
+
+	// Federation configures the import of the brokers of peer Service Manager instances, no peers can be registered if it is nil
+	Federation *federation.Settings
+
+	// PlatformTypes customizes the behavior of the API per platform type, all platforms are treated identically if it is nil
+	PlatformTypes *platformtypes.Registry
+
+	// Views holds the alternative serializations of the resources, no views can be requested if it is nil
+	Views *views.Registry
+
+	// CredentialsPipeline transforms the binding credentials returned by brokers, they are returned as they are if it is nil
+	CredentialsPipeline *osb.CredentialsPipeline
+
+	// CredentialsPolicy checks the credentials supplied on broker registration, any non-empty credentials are accepted if it is nil
+	CredentialsPolicy *filters.CredentialsPolicy
+
+	// CredentialsProvider provides the generator of rotated platform credentials and the store of platform passwords,
+	// random credentials are generated and the passwords are persisted by the Service Manager if it is nil
+	CredentialsProvider *credentials.Provider
+
+	// WSConnections closes the notification connections gradually when drained, they are closed at once if it is nil
+	WSConnections *apiNotifications.Connections
+
+	// VisibilityResolver resolves which platforms can see which service plans, the resolution is not exposed if it is nil
+	VisibilityResolver storage.VisibilityResolver
+
+	// StorageStatistics reports the sizes of the storage tables, the statistics are not exposed if it is nil
+	StorageStatistics storage.StatisticsProvider
+
+	// QueryableFields lists the fields which can be used in field queries, the info endpoint reports no fields if it is nil
+	QueryableFields storage.QueryableFieldsProvider
+
+	// NotificationCleaner provides the metrics of the notification clean-ups to the storage statistics
+	NotificationCleaner *storage.NotificationCleaner
+
+	// TenantKeys encrypts the credentials of brokers with keys supplied by their tenants, tenants cannot supply keys if it is nil
+	TenantKeys *storage.TenantKeys
+
+	// HTTPClients creates the clients of the outbound calls, clients with the default settings are used if it is nil
+	HTTPClients *httpclient.Factory
+
+	// CacheStore holds the chunks of catalog uploads, each instance keeps the chunks it receives in memory if it is nil
+	CacheStore cache.Store
 }
 
 // New returns the minimum set of REST APIs needed for the Service Manager
 func New(ctx context.Context, options *Options) (*web.API, error) {
-	bearerAuthnFilter, err := filters.NewOIDCAuthnFilter(ctx, options.APISettings.TokenIssuerURL, options.APISettings.ClientID)
+	var err error
+	httpClients := options.HTTPClients
+	if httpClients == nil {
+		if httpClients, err = httpclient.NewFactory(httpclient.DefaultSettings(), http.DefaultClient.Timeout, options.APISettings.SkipSSLValidation); err != nil {
+			return nil, err
+		}
+	}
+
+	bearerAuthnFilter, err := filters.NewOIDCAuthnFilter(ctx, &authenticators.OIDCOptions{
+		IssuerURL:             options.APISettings.TokenIssuerURL,
+		ClientID:              options.APISettings.ClientID,
+		Client:                httpClients.Client("token issuer"),
+		VerificationCacheSize: options.APISettings.TokenCacheSize,
+		VerificationCacheTTL:  options.APISettings.TokenCacheTTL,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &web.API{
+	featuresManager := options.Features
+	if featuresManager == nil {
+		featuresManager = pkgfeatures.NewManager(pkgfeatures.DefaultSettings())
+	}
+	featuresManager.Register(AsyncOperationsFlag)
+
+	brokerFetcher := func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+		br, err := options.Cache.Load(types.ServiceBrokerType, brokerID, func() (types.Object, error) {
+			return options.Repository.Get(ctx, types.ServiceBrokerType, brokerID)
+		})
+		if err != nil {
+			return nil, util.HandleStorageError(err, "broker")
+		}
+		return br.(*types.ServiceBroker), nil
+	}
+	wsConnections := options.WSConnections
+	if wsConnections == nil {
+		wsConnections = apiNotifications.NewConnections(options.WSSettings)
+	}
+	connectionTokens := ws.NewConnectionTokens(options.WSSettings)
+
+	osbStats := options.OSBStats
+	if osbStats == nil {
+		osbStats = osb.NewStats(options.APISettings.OSBCallHistorySize)
+	}
+
+	brokerTransports := options.BrokerTransports
+	if brokerTransports == nil {
+		brokerTransports = osb.NewTransports(options.APISettings.BrokerProxy, httpClients)
+		brokerTransports.Signer = osb.NewRequestSigner(options.APISettings.RequestSigning)
+	}
+
+	var orphanMitigator *osb.OrphanMitigator
+	if options.APISettings.OrphanMitigation != nil && options.APISettings.OrphanMitigation.Enabled {
+		orphanMitigator = osb.NewOrphanMitigator(ctx, options.APISettings.OrphanMitigation, options.Repository,
+			options.APISettings.OSBResponses.DoRequestFuncProvider(brokerTransports.DoRequestFunc))
+	}
+
+	brokerValidator := &osb.BrokerValidator{
+		DoRequestFuncProvider: options.APISettings.OSBResponses.DoRequestFuncProvider(brokerTransports.DoRequestFunc),
+		BrokerAPIVersion:      options.APISettings.OSBVersion,
+		CatalogValidator: func(broker *types.ServiceBroker, catalog []byte) error {
+			_, err := interceptors.ParseCatalog(broker, catalog)
+			return err
+		},
+	}
+	cacheStore := options.CacheStore
+	if cacheStore == nil {
+		cacheStore = cache.NewMemoryStore()
+	}
+	featureStates := options.FeatureStates
+	if featureStates == nil {
+		featureStates = pkgfeatures.NewStoreSource(cacheStore)
+		featuresManager.AddSource(featureStates)
+	}
+	brokerController := NewServiceBrokerController(ctx, options.Repository, options.APISettings, brokerValidator, cacheStore)
+	platformController := NewController(options.Repository, web.PlatformsURL, types.PlatformType, func() types.Object {
+		return &types.Platform{}
+	})
+	visibilityController := NewController(options.Repository, web.VisibilitiesURL, types.VisibilityType, func() types.Object {
+		return &types.Visibility{}
+	})
+
+	smAPI := &web.API{
 		// Default controllers - more filters can be registered using the relevant API methods
 		Controllers: []web.Controller{
-			NewController(options.Repository, web.ServiceBrokersURL, types.ServiceBrokerType, func() types.Object {
-				return &types.ServiceBroker{}
-			}),
-			NewController(options.Repository, web.PlatformsURL, types.PlatformType, func() types.Object {
-				return &types.Platform{}
-			}),
-			NewController(options.Repository, web.VisibilitiesURL, types.VisibilityType, func() types.Object {
-				return &types.Visibility{}
-			}),
-			apiNotifications.NewController(ctx, options.Repository, options.WSSettings, options.Notificator),
+			brokerController,
+			platformController,
+			visibilityController,
+			NewHistoryController(options.Repository, brokerController.BaseController, platformController, visibilityController),
+			NewLockController(options.Repository, brokerController.BaseController, platformController, visibilityController),
+			NewPlatformCredentialsController(options.Repository, options.PlatformTypes, options.CredentialsProvider),
+			apiNotifications.NewController(ctx, options.Repository, options.WSSettings, options.Notificator, options.PlatformTypes, wsConnections, connectionTokens),
 			NewServiceOfferingController(options.Repository),
 			NewServicePlanController(options.Repository),
+			NewOperationController(options.Repository),
+			NewController(options.Repository, web.VisibilityPoliciesURL, types.VisibilityPolicyType, func() types.Object {
+				return &types.VisibilityPolicy{}
+			}),
+			NewController(options.Repository, web.CompositeBrokersURL, types.CompositeBrokerType, func() types.Object {
+				return &types.CompositeBroker{}
+			}),
+			NewSavedQueryController(options.Repository),
+			&ErrorCodeController{},
 			&info.Controller{
-				TokenIssuer:    options.APISettings.TokenIssuerURL,
-				TokenBasicAuth: options.APISettings.TokenBasicAuth,
+				TokenIssuer:     options.APISettings.TokenIssuerURL,
+				TokenBasicAuth:  options.APISettings.TokenBasicAuth,
+				QueryableFields: options.QueryableFields,
+			},
+			&features.Controller{
+				Manager:    featuresManager,
+				States:     featureStates,
+				AdminScope: featuresManager.AdminScope(),
 			},
 			&osb.Controller{
-				BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
-					br, err := options.Repository.Get(ctx, types.ServiceBrokerType, brokerID)
-					if err != nil {
-						return nil, util.HandleStorageError(err, "broker")
-					}
-					return br.(*types.ServiceBroker), nil
-				},
+				BrokerFetcher: brokerFetcher,
+				Stats:         osbStats,
+				Headers:       options.APISettings.OSBHeaders,
+				Responses:     options.APISettings.OSBResponses,
+				Transports:    brokerTransports,
+				Credentials:   options.CredentialsPipeline,
+
+				OrphanMitigator:  orphanMitigator,
+				CompositeFetcher: osb.NewCompositeFetcher(options.Repository, brokerFetcher),
+			},
+			&osb.StatisticsController{
+				BrokerFetcher: brokerFetcher,
+				Stats:         osbStats,
 			},
 		},
 		// Default filters - more filters can be registered using the relevant API methods
 		Filters: []web.Filter{
 			&filters.Logging{},
-			filters.NewBasicAuthnFilter(options.Repository),
+			&filters.Features{Manager: featuresManager},
+			filters.NewBasicAuthnFilter(options.Repository, options.CredentialsProvider),
 			bearerAuthnFilter,
 			secfilters.NewRequiredAuthnFilter(),
+			secfilters.NewRequiredScopesFilter(),
 			labels.NewForbiddenLabelOperationsFilter(options.APISettings.ProctedLabels),
 			&filters.SelectionCriteria{},
-			&filters.PlatformAwareVisibilityFilter{},
+			&filters.SavedQueryFilter{Repository: options.Repository},
+			&filters.ViewsFilter{Registry: options.Views},
+			&filters.PlatformAwareVisibilityFilter{
+				PlatformTypes: options.PlatformTypes,
+			},
 			&filters.PatchOnlyLabelsFilter{},
+			&filters.CatalogLifecycleFilter{
+				Repository:    options.Repository,
+				Policy:        options.APISettings.CatalogLifecyclePolicy,
+				BrokerFetcher: brokerFetcher,
+			},
+			&filters.RetiredPlansProvisionFilter{
+				Repository:    options.Repository,
+				BrokerFetcher: brokerFetcher,
+			},
+			&filters.MaintenanceInfoFilter{
+				Repository:    options.Repository,
+				BrokerFetcher: brokerFetcher,
+			},
 		},
 		Registry: health.NewDefaultRegistry(),
-	}, nil
+	}
+
+	if len(options.APISettings.CatalogLabelsMetadata) != 0 {
+		mappings, err := filters.ParseLabelMappings(options.APISettings.CatalogLabelsMetadata)
+		if err != nil {
+			return nil, err
+		}
+		smAPI.RegisterFilters(&filters.CatalogLabelsMetadataFilter{
+			Repository:    options.Repository,
+			Mappings:      mappings,
+			BrokerFetcher: brokerFetcher,
+		})
+	}
+
+	if options.APISettings.CatalogPricingMetadata != "" {
+		smAPI.RegisterFilters(&filters.CatalogPricingMetadataFilter{
+			Repository:    options.Repository,
+			Field:         options.APISettings.CatalogPricingMetadata,
+			BrokerFetcher: brokerFetcher,
+		})
+	}
+
+	if len(options.APISettings.CatalogCurationRules) != 0 {
+		rules, err := filters.ParseCurationRules(options.APISettings.CatalogCurationRules)
+		if err != nil {
+			return nil, err
+		}
+		smAPI.RegisterFilters(&filters.CatalogCurationFilter{
+			Rules: rules,
+		})
+	}
+
+	if len(options.APISettings.ProvisionContextLabels) != 0 {
+		mappings, err := filters.ParseLabelMappings(options.APISettings.ProvisionContextLabels)
+		if err != nil {
+			return nil, err
+		}
+		smAPI.RegisterFilters(&filters.ProvisionContextFilter{
+			Mappings: mappings,
+		})
+	}
+
+	if options.APISettings.EnforcePlanSchemas {
+		smAPI.RegisterFilters(&filters.PlanSchemasFilter{
+			Repository:    options.Repository,
+			BrokerFetcher: brokerFetcher,
+		})
+	}
+
+	// The field rules also apply to the resources returned by watches and GraphQL queries
+	var fieldAuthorization *filters.FieldAuthorizationFilter
+	if len(options.APISettings.FieldScopes) != 0 {
+		rules, err := filters.ParseFieldRules(options.APISettings.FieldScopes)
+		if err != nil {
+			return nil, err
+		}
+		fieldAuthorization = &filters.FieldAuthorizationFilter{
+			Rules: rules,
+		}
+		smAPI.RegisterFilters(fieldAuthorization)
+	}
+
+	if options.APISettings.Naming != nil {
+		smAPI.RegisterFilters(filters.NewNamingRulesFilter(options.APISettings.Naming))
+	}
+
+	if options.CredentialsPolicy != nil {
+		smAPI.RegisterFilters(filters.NewCredentialsPolicyFilter(options.CredentialsPolicy))
+	}
+
+	// Only the resources whose changes are recorded as notifications can be watched
+	if options.APISettings.Watch != nil {
+		smAPI.RegisterFiltersAfter(filters.ViewsFilterName, watch.NewFilter(ctx, options.APISettings.Watch, options.Repository, map[string]types.ObjectType{
+			web.ServiceBrokersURL:   types.ServiceBrokerType,
+			web.ServiceOfferingsURL: types.ServiceOfferingType,
+			web.ServicePlansURL:     types.ServicePlanType,
+			web.VisibilitiesURL:     types.VisibilityType,
+		}, fieldAuthorization))
+	}
+
+	if options.ResponseCache.Enabled() {
+		smAPI.RegisterFilters(&filters.ResponseCacheFilter{
+			Cache: options.ResponseCache,
+		})
+	}
+
+	// The criteria are recorded after the user is known and before any filter adds criteria
+	if options.APISettings.CriteriaDebugScope != "" {
+		smAPI.RegisterFiltersAfter(secfilters.RequiredAuthenticationFilterName, &filters.CriteriaDebugFilter{
+			Scope: options.APISettings.CriteriaDebugScope,
+		})
+	}
+
+	if connectionTokens != nil {
+		smAPI.RegisterFiltersAfter(filters.BasicAuthnFilterName, filters.NewConnectionTokenAuthnFilter(options.Repository, connectionTokens))
+	}
+
+	// Users identified by the trusted gateway are not required to present a token
+	if options.APISettings.TrustedGateway.Enabled() {
+		smAPI.RegisterFiltersBefore(filters.BearerAuthnFilterName, filters.NewTrustedGatewayAuthnFilter(options.APISettings.TrustedGateway))
+	}
+
+	if options.LoginThrottle.Enabled() {
+		smAPI.RegisterFiltersBefore(filters.BasicAuthnFilterName, &filters.LoginThrottleFilter{
+			Throttle: options.LoginThrottle,
+		})
+	}
+
+	// Requests are shed before they are authenticated, as authentication may already need a database connection
+	if options.LoadShedder.Enabled() {
+		smAPI.RegisterFiltersAfter(filters.LoggingFilterName, &filters.LoadSheddingFilter{
+			Shedder: options.LoadShedder,
+		})
+	}
+
+	if options.VisibilityResolver != nil {
+		smAPI.RegisterControllers(NewVisibilityResolutionController(options.Repository, options.VisibilityResolver))
+	}
+
+	if options.StorageStatistics != nil {
+		smAPI.RegisterControllers(NewStorageStatisticsController(options.StorageStatistics, options.Scheduler, options.NotificationCleaner))
+	}
+
+	if options.TenantKeys != nil {
+		smAPI.RegisterControllers(NewTenantKeyController(options.Repository, options.TenantKeys, options.APISettings.TenantKeys))
+	}
+
+	if options.CFVisibility != nil && options.CFVisibility.Enabled {
+		smAPI.RegisterControllers(&apicfvisibility.Controller{
+			Repository: options.Repository,
+			Mapper:     cfvisibility.NewMapper(options.CFVisibility, options.Repository),
+		})
+	}
+
+	if options.Federation != nil && options.Federation.Enabled {
+		smAPI.RegisterControllers(NewController(options.Repository, web.PeersURL, types.PeerType, func() types.Object {
+			return &types.Peer{}
+		}))
+	}
+
+	if options.APISettings.OperatorMode {
+		smAPI.RegisterControllers(NewOperatorController(options.Repository, fieldAuthorization, brokerController.BaseController, visibilityController))
+	}
+
+	if options.APISettings.GraphQLEnabled {
+		smAPI.RegisterControllers(graphql.NewController(options.Repository, fieldAuthorization))
+	}
+
+	// The API documentation is generated from the final set of controllers, including the ones of extensions
+	smAPI.RegisterControllers(&apidocs.Controller{
+		API:     smAPI,
+		Version: version.Version,
+	})
+
+	if options.Scheduler != nil {
+		smAPI.RegisterControllers(&jobs.Controller{
+			Scheduler: options.Scheduler,
+		})
+	}
+
+	return smAPI, nil
 }
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apidocs
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Controller API documentation controller
+type Controller struct {
+	// API is the API whose routes are documented. The document is generated on each request, so controllers
+	// registered by extensions after the controller was created are documented, too.
+	API *web.API
+
+	// Version is the version of the Service Manager stated in the document
+	Version string
+}
+
+var _ web.Controller = &Controller{}
+
+func (c *Controller) getDocument(request *web.Request) (*web.Response, error) {
+	return util.NewJSONResponse(http.StatusOK, NewDocument(c.Version, c.API.Controllers))
+}
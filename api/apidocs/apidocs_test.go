@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apidocs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/api/apidocs"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "API Docs Suite")
+}
+
+type routesController []web.Route
+
+func (c routesController) Routes() []web.Route {
+	return c
+}
+
+var _ = Describe("API Docs", func() {
+	var (
+		api        *web.API
+		controller *apidocs.Controller
+	)
+
+	BeforeEach(func() {
+		api = &web.API{}
+		controller = &apidocs.Controller{API: api, Version: "1.0.0"}
+		api.RegisterControllers(controller)
+	})
+
+	Describe("Routes", func() {
+		It("Returns one route", func() {
+			routes := controller.Routes()
+			Expect(len(routes)).To(Equal(1))
+
+			route := routes[0]
+			Expect(route.Endpoint.Path).To(Equal(apidocs.URL))
+			Expect(route.Endpoint.Method).To(Equal(http.MethodGet))
+		})
+	})
+
+	Describe("NewDocument", func() {
+		var document *apidocs.Document
+
+		JustBeforeEach(func() {
+			document = apidocs.NewDocument("1.0.0", api.Controllers)
+		})
+
+		BeforeEach(func() {
+			api.RegisterControllers(routesController{
+				{
+					Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/things"},
+					Doc:      &web.RouteDoc{Summary: "List things", Criteria: true},
+				},
+				{
+					Endpoint: web.Endpoint{Method: http.MethodPatch, Path: "/v1/things/{id}"},
+				},
+				{
+					Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/things/{id}/parts/{name:[a-z]+}"},
+				},
+			})
+		})
+
+		It("documents the routes of all controllers", func() {
+			Expect(document.OpenAPI).To(Equal("3.0.0"))
+			Expect(document.Info.Version).To(Equal("1.0.0"))
+			Expect(document.Paths).To(HaveKey(apidocs.URL))
+			Expect(document.Paths).To(HaveKey("/v1/things"))
+			Expect(document.Paths).To(HaveKey("/v1/things/{id}"))
+		})
+
+		It("uses the documentation of the route", func() {
+			operation := document.Paths["/v1/things"]["get"]
+			Expect(operation.Summary).To(Equal("List things"))
+			Expect(operation.Tags).To(ConsistOf("things"))
+			Expect(operation.OperationID).To(Equal("get_v1_things"))
+		})
+
+		It("documents the criteria parameters with their grammar", func() {
+			operation := document.Paths["/v1/things"]["get"]
+			Expect(operation.Parameters).To(HaveLen(2))
+			Expect(operation.Parameters[0].Ref).To(Equal("#/components/parameters/fieldQuery"))
+			Expect(operation.Parameters[1].Ref).To(Equal("#/components/parameters/labelQuery"))
+
+			fieldQuery := document.Components.Parameters["fieldQuery"]
+			Expect(fieldQuery.In).To(Equal("query"))
+			Expect(fieldQuery.Description).To(ContainSubstring("notin"))
+		})
+
+		It("derives the path parameters and the request body from the endpoint", func() {
+			operation := document.Paths["/v1/things/{id}"]["patch"]
+			Expect(operation.Parameters).To(HaveLen(1))
+			Expect(operation.Parameters[0].Name).To(Equal("id"))
+			Expect(operation.Parameters[0].In).To(Equal("path"))
+			Expect(operation.Parameters[0].Required).To(BeTrue())
+			Expect(operation.RequestBody).ToNot(BeNil())
+		})
+
+		It("drops the patterns of path parameters", func() {
+			Expect(document.Paths).To(HaveKey("/v1/things/{id}/parts/{name}"))
+			operation := document.Paths["/v1/things/{id}/parts/{name}"]["get"]
+			Expect(operation.Parameters).To(HaveLen(2))
+			Expect(operation.Parameters[1].Name).To(Equal("name"))
+			Expect(operation.RequestBody).To(BeNil())
+		})
+	})
+
+	Describe("getDocument", func() {
+		It("serves the document", func() {
+			response, err := controller.Routes()[0].Handler(&web.Request{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			document := &apidocs.Document{}
+			Expect(json.Unmarshal(response.Body, document)).To(Succeed())
+			Expect(document.Paths).To(HaveKey(apidocs.URL))
+		})
+	})
+})
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apidocs
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	openAPIVersion = "3.0.0"
+	jsonMediaType  = "application/json"
+
+	fieldQueryRef = "#/components/parameters/fieldQuery"
+	labelQueryRef = "#/components/parameters/labelQuery"
+	errorRef      = "#/components/schemas/Error"
+)
+
+// pathParamPattern matches the path parameters of a route, the optional regular expression of a parameter is dropped
+var pathParamPattern = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info contains the metadata of the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem contains the operations of a path by lower case HTTP method
+type PathItem map[string]*Operation
+
+// Operation describes a single route
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path or query parameter of an operation. If Ref is set, the parameter is defined in the
+// components of the document.
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes the body of a request
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes a response of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the content of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema of a parameter or body. If Ref is set, the schema is defined in the components of the document.
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+}
+
+// Components contains the parameters and schemas referenced by the operations
+type Components struct {
+	Parameters map[string]*Parameter `json:"parameters"`
+	Schemas    map[string]*Schema    `json:"schemas"`
+}
+
+// NewDocument generates an OpenAPI document describing the routes of the provided controllers
+func NewDocument(version string, controllers []web.Controller) *Document {
+	document := &Document{
+		OpenAPI: openAPIVersion,
+		Info: Info{
+			Title:       "Service Manager",
+			Description: "API of the Service Manager",
+			Version:     version,
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			Parameters: map[string]*Parameter{
+				string(query.FieldQuery): criteriaParameter(query.FieldQuery, "fields"),
+				string(query.LabelQuery): criteriaParameter(query.LabelQuery, "labels"),
+			},
+			Schemas: map[string]*Schema{
+				"Error": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"error":       {Type: "string"},
+						"description": {Type: "string"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, controller := range controllers {
+		for _, route := range controller.Routes() {
+			path := pathParamPattern.ReplaceAllString(route.Endpoint.Path, "{$1}")
+			pathItem, found := document.Paths[path]
+			if !found {
+				pathItem = make(PathItem)
+				document.Paths[path] = pathItem
+			}
+			method := strings.ToLower(route.Endpoint.Method)
+			// the first registered route handles the requests, so it is the one which is documented
+			if _, found := pathItem[method]; !found {
+				pathItem[method] = newOperation(route)
+			}
+		}
+	}
+	return document
+}
+
+func newOperation(route web.Route) *Operation {
+	doc := route.Doc
+	if doc == nil {
+		doc = &web.RouteDoc{}
+	}
+	path := route.Endpoint.Path
+	operation := &Operation{
+		OperationID: operationID(route.Endpoint),
+		Summary:     doc.Summary,
+		Responses: map[string]*Response{
+			"2XX": {
+				Description: "Successful response",
+				Content: map[string]*MediaType{
+					jsonMediaType: {Schema: &Schema{Type: "object"}},
+				},
+			},
+			"default": {
+				Description: "Error response",
+				Content: map[string]*MediaType{
+					jsonMediaType: {Schema: &Schema{Ref: errorRef}},
+				},
+			},
+		},
+	}
+
+	tag := doc.Tag
+	if tag == "" {
+		tag = defaultTag(path)
+	}
+	if tag != "" {
+		operation.Tags = []string{tag}
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		operation.Parameters = append(operation.Parameters, &Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	if doc.Criteria {
+		operation.Parameters = append(operation.Parameters, &Parameter{Ref: fieldQueryRef}, &Parameter{Ref: labelQueryRef})
+	}
+
+	switch route.Endpoint.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonMediaType: {Schema: &Schema{Type: "object"}},
+			},
+		}
+	}
+	return operation
+}
+
+// operationID returns a unique identifier of the endpoint, e.g. get_v1_service_brokers_id
+func operationID(endpoint web.Endpoint) string {
+	path := pathParamPattern.ReplaceAllString(endpoint.Path, "$1")
+	segments := strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	return strings.ToLower(endpoint.Method) + "_" + strings.Join(segments, "_")
+}
+
+// defaultTag returns the first path segment after the API version
+func defaultTag(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[1]
+}
+
+func criteriaParameter(criterionType query.CriterionType, target string) *Parameter {
+	operators := make([]string, 0, len(query.Operators()))
+	for _, operator := range query.Operators() {
+		operators = append(operators, string(operator))
+	}
+	description := fmt.Sprintf("Selects the resources by their %s. A query consists of criteria separated by %c, "+
+		"each criterion has the form `<key>%c<operator>%c<value>`. The supported operators are %s. "+
+		"The operators %s and %s require a multivalue operand of the form `%c<value1>%c%c<value2>%c`. "+
+		"The operators %s, %s, %s and %s require numeric operands. "+
+		"A %c in a value is escaped with a backslash.",
+		target, query.Separator, query.OperandSeparator, query.OperandSeparator, strings.Join(operators, ", "),
+		query.InOperator, query.NotInOperator, query.OpenBracket, query.Separator, query.Separator, query.CloseBracket,
+		query.GreaterThanOperator, query.GreaterThanOrEqualOperator, query.LessThanOperator, query.LessThanOrEqualOperator,
+		query.Separator)
+	return &Parameter{
+		Name:        string(criterionType),
+		In:          "query",
+		Description: description,
+		Schema:      &Schema{Type: "string"},
+	}
+}
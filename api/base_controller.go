@@ -17,9 +17,13 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/tidwall/sjson"
@@ -32,6 +36,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/views"
 	"github.com/Peripli/service-manager/pkg/web"
 )
 
@@ -78,6 +83,7 @@ func (c *BaseController) Routes() []web.Route {
 				Path:   c.resourceBaseURL,
 			},
 			Handler: c.ListObjects,
+			Doc:     c.criteriaDoc("List"),
 		},
 		{
 			Endpoint: web.Endpoint{
@@ -85,6 +91,7 @@ func (c *BaseController) Routes() []web.Route {
 				Path:   c.resourceBaseURL,
 			},
 			Handler: c.DeleteObjects,
+			Doc:     c.criteriaDoc("Delete"),
 		},
 		{
 			Endpoint: web.Endpoint{
@@ -93,6 +100,14 @@ func (c *BaseController) Routes() []web.Route {
 			},
 			Handler: c.DeleteSingleObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPatch,
+				Path:   c.resourceBaseURL,
+			},
+			Handler: c.PatchObjects,
+			Doc:     c.criteriaDoc("Patch"),
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodPatch,
@@ -103,13 +118,40 @@ func (c *BaseController) Routes() []web.Route {
 	}
 }
 
+// criteriaDoc returns the documentation of a route which handles the objects matching the criteria of the request
+func (c *BaseController) criteriaDoc(action string) *web.RouteDoc {
+	return &web.RouteDoc{
+		Summary:  fmt.Sprintf("%s %s", action, strings.Replace(path.Base(c.resourceBaseURL), "_", " ", -1)),
+		Criteria: true,
+	}
+}
+
 // CreateObject handles the creation of a new object
 func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 	ctx := r.Context()
 	log.C(ctx).Debugf("Creating new %s", c.objectType)
 
+	result, err := c.objectFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	createdObj, err := c.repository.Create(ctx, result)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+
+	return util.NewJSONResponse(http.StatusCreated, createdObj)
+}
+
+// objectFromRequest builds a new object from the request body and assigns it an ID and creation timestamps
+func (c *BaseController) objectFromRequest(r *web.Request) (types.Object, error) {
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
 	result := c.objectBlueprint()
-	if err := util.BytesToObject(r.Body, result); err != nil {
+	if err := util.BytesToObject(body, result); err != nil {
 		return nil, err
 	}
 
@@ -124,12 +166,7 @@ func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 	result.SetCreatedAt(currentTime)
 	result.SetUpdatedAt(currentTime)
 
-	createdObj, err := c.repository.Create(ctx, result)
-	if err != nil {
-		return nil, util.HandleStorageError(err, string(c.objectType))
-	}
-
-	return util.NewJSONResponse(http.StatusCreated, createdObj)
+	return result, nil
 }
 
 // DeleteObjects handles the deletion of the objects specified in the request
@@ -174,7 +211,11 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 
 	stripCredentials(ctx, object)
 
-	return util.NewJSONResponse(http.StatusOK, object)
+	rendered, err := views.Render(ctx, c.apiVersion(), c.objectType, object)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, rendered)
 }
 
 // ListObjects handles the fetching of all objects
@@ -191,7 +232,16 @@ func (c *BaseController) ListObjects(r *web.Request) (*web.Response, error) {
 		stripCredentials(ctx, obj)
 	}
 
-	return util.NewJSONResponse(http.StatusOK, objectList)
+	rendered, err := views.RenderList(ctx, c.apiVersion(), c.objectType, objectList)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, rendered)
+}
+
+// apiVersion returns the API version of the resource, which is the first segment of its base URL, e.g. v1
+func (c *BaseController) apiVersion() string {
+	return strings.SplitN(strings.TrimPrefix(c.resourceBaseURL, "/"), "/", 2)[0]
 }
 
 // PatchObject handles the update of the object with the id specified in the request
@@ -200,7 +250,11 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 	ctx := r.Context()
 	log.C(ctx).Debugf("Updating %s with id %s", c.objectType, objectID)
 
-	labelChanges, err := query.LabelChangesFromJSON(r.Body)
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	labelChanges, err := query.LabelChangesFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
@@ -210,13 +264,98 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 		return nil, util.HandleStorageError(err, string(c.objectType))
 	}
 
-	if r.Body, err = sjson.DeleteBytes(r.Body, "labels"); err != nil {
+	if body, err = sjson.DeleteBytes(body, "labels"); err != nil {
+		return nil, err
+	}
+	r.SetBody(body)
+
+	object, err := c.patch(ctx, objFromDB, body, labelChanges)
+	if err != nil {
 		return nil, err
 	}
+
+	return util.NewJSONResponse(http.StatusOK, object)
+}
+
+// PatchObjects handles the update of all objects matching the criteria of the request with the fields and label
+// changes of the request body. The objects are updated in a single transaction, so that either all or none of them
+// are updated. Each update is validated, locked and notified like by an individual patch request.
+func (c *BaseController) PatchObjects(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	criteria := query.CriteriaForContext(ctx)
+	if len(criteria) == 0 {
+		return nil, badRequest("patching all %ss requires a %s or %s", c.objectType, query.FieldQuery, query.LabelQuery)
+	}
+	bulkUpdater, ok := c.repository.(storage.BulkUpdater)
+	if !ok {
+		return nil, fmt.Errorf("the storage does not support updating multiple %ss at once", c.objectType)
+	}
+	log.C(ctx).Debugf("Updating %ss matching %d criteria", c.objectType, len(criteria))
+
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	labelChanges, err := query.LabelChangesFromJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	body, err = sjson.DeleteBytes(body, "labels")
+	if err != nil {
+		return nil, err
+	}
+
+	objectList, err := c.repository.List(ctx, c.objectType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	objects := make([]types.Object, 0, objectList.Len())
+	for i := 0; i < objectList.Len(); i++ {
+		object, err := applyPatch(objectList.ItemAt(i), body, labelChanges)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	updated, err := bulkUpdater.UpdateAll(ctx, objects, labelChanges...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	for _, object := range updated {
+		stripCredentials(ctx, object)
+	}
+
+	// the updated objects are listed under the same key as in the list response, e.g. service_brokers
+	return util.NewJSONResponse(http.StatusOK, map[string][]types.Object{
+		path.Base(c.resourceBaseURL): updated,
+	})
+}
+
+// patch applies the fields of the body and the label changes to the stored object and updates it
+func (c *BaseController) patch(ctx context.Context, objFromDB types.Object, body []byte, labelChanges []*query.LabelChange) (types.Object, error) {
+	objFromDB, err := applyPatch(objFromDB, body, labelChanges)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := c.repository.Update(ctx, objFromDB, labelChanges...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+
+	stripCredentials(ctx, object)
+
+	return object, nil
+}
+
+// applyPatch applies the fields of the body and the label changes to the stored object, its id and timestamps are kept
+func applyPatch(objFromDB types.Object, body []byte, labelChanges []*query.LabelChange) (types.Object, error) {
+	objectID := objFromDB.GetID()
 	createdAt := objFromDB.GetCreatedAt()
 	updatedAt := objFromDB.GetUpdatedAt()
 
-	if err := util.BytesToObject(r.Body, objFromDB); err != nil {
+	if err := util.BytesToObject(body, objFromDB); err != nil {
 		return nil, err
 	}
 
@@ -227,7 +366,66 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 	labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, objFromDB.GetLabels())
 	objFromDB.SetLabels(labels)
 
-	object, err := c.repository.Update(ctx, objFromDB, labelChanges...)
+	return objFromDB, nil
+}
+
+// UpsertObject handles the idempotent creation or update of the object with the id specified in the request. The
+// request body contains the full desired state of the object, so fields and labels which are missing are removed.
+// An object which already has the desired state is not updated.
+func (c *BaseController) UpsertObject(r *web.Request) (*web.Response, error) {
+	objectID := r.PathParams[PathParamID]
+	ctx := r.Context()
+	log.C(ctx).Debugf("Upserting %s with id %s", c.objectType, objectID)
+
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	desired := c.objectBlueprint()
+	if err := util.BytesToObject(body, desired); err != nil {
+		return nil, err
+	}
+	if desired.GetID() != "" && desired.GetID() != objectID {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("id %s in the request body does not match the id in the path", desired.GetID()),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	desired.SetID(objectID)
+
+	current, err := c.repository.Get(ctx, c.objectType, objectID)
+	if err == util.ErrNotFoundInStorage {
+		currentTime := time.Now().UTC()
+		desired.SetCreatedAt(currentTime)
+		desired.SetUpdatedAt(currentTime)
+		created, err := c.repository.Create(ctx, desired)
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(c.objectType))
+		}
+		stripCredentials(ctx, created)
+		return util.NewJSONResponse(http.StatusCreated, created)
+	}
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+
+	desired.SetCreatedAt(current.GetCreatedAt())
+	desired.SetUpdatedAt(current.GetUpdatedAt())
+	labelChanges := query.LabelChangesBetween(current.GetLabels(), desired.GetLabels())
+	desiredLabels := desired.GetLabels()
+	desired.SetLabels(current.GetLabels())
+	unchanged, err := sameState(current, desired)
+	if err != nil {
+		return nil, err
+	}
+	if unchanged && len(labelChanges) == 0 {
+		stripCredentials(ctx, current)
+		return util.NewJSONResponse(http.StatusOK, current)
+	}
+
+	desired.SetLabels(desiredLabels)
+	object, err := c.repository.Update(ctx, desired, labelChanges...)
 	if err != nil {
 		return nil, util.HandleStorageError(err, string(c.objectType))
 	}
@@ -237,7 +435,23 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 	return util.NewJSONResponse(http.StatusOK, object)
 }
 
+// sameState returns true if the serialized fields of the objects are equal
+func sameState(object, otherObject types.Object) (bool, error) {
+	objectBytes, err := json.Marshal(object)
+	if err != nil {
+		return false, err
+	}
+	otherObjectBytes, err := json.Marshal(otherObject)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(objectBytes, otherObjectBytes), nil
+}
+
 func stripCredentials(ctx context.Context, object types.Object) {
+	if web.MetadataFromContext(ctx).CredentialsRevealed() {
+		return
+	}
 	if secured, ok := object.(types.Secured); ok {
 		secured.SetCredentials(nil)
 	} else {
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Asynchronous registration of brokers", func() {
+	var (
+		repository *storagefakes.FakeStorage
+		controller *api.ServiceBrokerController
+		manager    *features.Manager
+
+		mutex sync.Mutex
+		users []string
+	)
+
+	createBroker := func() (*web.Response, error) {
+		httpRequest, err := http.NewRequest(http.MethodPost, "https://sm.example.com/v1/service_brokers?async=true", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := features.ContextWithManager(httpRequest.Context(), manager)
+		ctx = web.ContextWithUser(ctx, &web.UserContext{Name: "operator"})
+		return controller.CreateObject(&web.Request{
+			Request: httpRequest.WithContext(ctx),
+			Body:    []byte(`{"id":"broker-id","name":"broker","broker_url":"https://broker.example.com"}`),
+		})
+	}
+
+	BeforeEach(func() {
+		manager = features.NewManager(features.DefaultSettings())
+		manager.Register(api.AsyncOperationsFlag)
+		users = nil
+
+		repository = &storagefakes.FakeStorage{}
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			return obj, nil
+		})
+		repository.GetCalls(func(ctx context.Context, objectType types.ObjectType, id string) (types.Object, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if user, found := web.UserFromContext(ctx); found {
+				users = append(users, user.Name)
+			}
+			return nil, errors.New("broker not found")
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, changes ...*query.LabelChange) (types.Object, error) {
+			return obj, nil
+		})
+		settings := api.DefaultSettings()
+		settings.CatalogFetchAttempts = 1
+		controller = api.NewServiceBrokerController(context.Background(), repository, settings, nil, cache.NewMemoryStore())
+	})
+
+	It("fetches the catalog on behalf of the user of the request", func() {
+		response, err := createBroker()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+
+		Eventually(func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return users
+		}).Should(ContainElement("operator"))
+	})
+
+	It("deletes the broker if its operation cannot be created", func() {
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			if obj.GetType() == types.OperationObjectType {
+				return nil, errors.New("connection lost")
+			}
+			return obj, nil
+		})
+
+		_, err := createBroker()
+		Expect(err).To(HaveOccurred())
+		Expect(repository.DeleteCallCount()).To(Equal(1))
+		_, objectType, criteria := repository.DeleteArgsForCall(0)
+		Expect(objectType).To(Equal(types.ServiceBrokerType))
+		Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "id", "broker-id")))
+	})
+})
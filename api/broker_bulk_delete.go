@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// BulkResourceID is the resource id of the operations which process all resources matching the criteria of a request
+const BulkResourceID = "*"
+
+// bulkDeleteFailure is a resource which could not be deleted by a bulk delete
+type bulkDeleteFailure struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// DeleteObjects handles the deletion of the brokers matching the criteria of the request. If the request is
+// asynchronous the brokers are deleted in batches in the background and an operation tracking the progress is returned.
+// Deletes are asynchronous only on request as clients of the synchronous deletes expect the brokers to be gone once
+// the response is received.
+func (c *ServiceBrokerController) DeleteObjects(r *web.Request) (*web.Response, error) {
+	if !isAsync(r) {
+		return c.BaseController.DeleteObjects(r)
+	}
+
+	ctx := r.Context()
+	log.C(ctx).Debugf("Deleting %ss asynchronously", c.objectType)
+
+	objectList, err := c.repository.List(ctx, c.objectType, query.CriteriaForContext(ctx)...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	brokers := make([]*types.ServiceBroker, 0, objectList.Len())
+	for i := 0; i < objectList.Len(); i++ {
+		broker := objectList.ItemAt(i).(*types.ServiceBroker)
+		brokers = append(brokers, &types.ServiceBroker{Base: types.Base{ID: broker.ID}, Name: broker.Name})
+	}
+
+	operation, err := newOperation(types.DELETE, BulkResourceID, c.objectType)
+	if err != nil {
+		return nil, err
+	}
+	operation.Description = fmt.Sprintf("deleting %d brokers", len(brokers))
+	if _, err := c.repository.Create(ctx, operation); err != nil {
+		return nil, util.HandleStorageError(err, string(types.OperationObjectType))
+	}
+
+	go c.deleteBrokers(c.backgroundContext(ctx), brokers, operation)
+
+	response, err := util.NewJSONResponse(http.StatusAccepted, operation)
+	if err != nil {
+		return nil, err
+	}
+	response.Header.Set("Location", r.ExternalURL(fmt.Sprintf("%s/%s", web.OperationsURL, operation.ID)).String())
+
+	return response, nil
+}
+
+// deleteBrokers deletes the brokers in batches and records the progress and the brokers which could not be deleted
+// in the provided operation. The brokers of a batch which cannot be deleted together are deleted one by one, so that
+// a broker which cannot be deleted, e.g. because another user locked it, does not prevent the deletion of the others.
+func (c *ServiceBrokerController) deleteBrokers(ctx context.Context, brokers []*types.ServiceBroker, operation *types.Operation) {
+	var failures []bulkDeleteFailure
+	deleted := 0
+	for start := 0; start < len(brokers); start += c.settings.BulkDeleteBatchSize {
+		select {
+		case <-ctx.Done():
+			c.updateBulkDeleteOperation(ctx, operation, types.FAILED, fmt.Sprintf("bulk delete was interrupted after deleting %d of %d brokers", deleted, len(brokers)), failures)
+			return
+		default:
+		}
+
+		end := start + c.settings.BulkDeleteBatchSize
+		if end > len(brokers) {
+			end = len(brokers)
+		}
+		batch := brokers[start:end]
+		ids := make([]string, 0, len(batch))
+		for _, broker := range batch {
+			ids = append(ids, broker.ID)
+		}
+
+		if _, err := c.repository.Delete(ctx, c.objectType, query.ByField(query.InOperator, "id", ids...)); err == nil {
+			deleted += len(batch)
+		} else {
+			log.C(ctx).WithError(err).Warnf("Could not delete a batch of %d brokers, deleting them one by one", len(batch))
+			for _, broker := range batch {
+				if _, err := c.repository.Delete(ctx, c.objectType, query.ByField(query.EqualsOperator, "id", broker.ID)); err != nil && err != util.ErrNotFoundInStorage {
+					failures = append(failures, bulkDeleteFailure{ID: broker.ID, Name: broker.Name, Error: util.HandleStorageError(err, string(c.objectType)).Error()})
+					continue
+				}
+				deleted++
+			}
+		}
+		c.updateBulkDeleteOperation(ctx, operation, types.IN_PROGRESS, fmt.Sprintf("deleted %d of %d brokers", deleted, len(brokers)), failures)
+	}
+
+	if len(failures) != 0 {
+		c.updateBulkDeleteOperation(ctx, operation, types.FAILED, fmt.Sprintf("deleted %d of %d brokers, %d could not be deleted", deleted, len(brokers), len(failures)), failures)
+		return
+	}
+	c.updateBulkDeleteOperation(ctx, operation, types.SUCCEEDED, fmt.Sprintf("deleted %d brokers", deleted), nil)
+}
+
+// backgroundContext returns the context of the processing of a request in the background. It outlives the request
+// but keeps its logger and user, so that the locks of other users apply to the background processing as well.
+func (c *ServiceBrokerController) backgroundContext(ctx context.Context) context.Context {
+	background := log.ContextWithLogger(c.ctx, log.C(ctx))
+	if user, found := web.UserFromContext(ctx); found {
+		background = web.ContextWithUser(background, user)
+	}
+	return background
+}
+
+// updateBulkDeleteOperation records the progress of a bulk delete, the errors list the brokers which could not be deleted
+func (c *ServiceBrokerController) updateBulkDeleteOperation(ctx context.Context, operation *types.Operation, state types.OperationState, description string, failures []bulkDeleteFailure) {
+	operation.State = state
+	operation.Description = description
+	operation.UpdatedAt = time.Now().UTC()
+	operation.Errors = nil
+	if len(failures) != 0 {
+		errorBytes, err := json.Marshal(map[string]interface{}{
+			"description": fmt.Sprintf("%d of the brokers could not be deleted", len(failures)),
+			"resources":   failures,
+		})
+		if err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not marshal errors of operation with id %s", operation.ID)
+		}
+		operation.Errors = errorBytes
+	}
+
+	if _, err := c.repository.Update(ctx, operation); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not update operation with id %s to state %s", operation.ID, state)
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Asynchronous bulk delete of brokers", func() {
+	var (
+		repository  *storagefakes.FakeStorage
+		controller  *api.ServiceBrokerController
+		manager     *features.Manager
+		brokers     []*types.ServiceBroker
+		undeletable string
+
+		mutex      sync.Mutex
+		deleted    []string
+		operations []types.Operation
+	)
+
+	lastOperation := func() types.Operation {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if len(operations) == 0 {
+			return types.Operation{}
+		}
+		return operations[len(operations)-1]
+	}
+
+	deleteBrokers := func(url string) *web.Response {
+		httpRequest, err := http.NewRequest(http.MethodDelete, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		httpRequest = httpRequest.WithContext(features.ContextWithManager(httpRequest.Context(), manager))
+		response, err := controller.DeleteObjects(&web.Request{Request: httpRequest})
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		settings := api.DefaultSettings()
+		settings.BulkDeleteBatchSize = 2
+		manager = features.NewManager(features.DefaultSettings())
+		manager.Register(api.AsyncOperationsFlag)
+
+		brokers = nil
+		for _, id := range []string{"broker-1", "broker-2", "broker-3", "broker-4", "broker-5"} {
+			brokers = append(brokers, &types.ServiceBroker{Base: types.Base{ID: id}, Name: "name-" + id})
+		}
+		undeletable = ""
+		deleted = nil
+		operations = nil
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			return &types.ServiceBrokers{ServiceBrokers: brokers}, nil
+		})
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			return obj, nil
+		})
+		repository.DeleteCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if len(criteria) == 0 {
+				return &types.ServiceBrokers{}, nil
+			}
+			for _, id := range criteria[0].RightOp {
+				if id == undeletable {
+					return nil, errors.New("broker has service instances")
+				}
+			}
+			deleted = append(deleted, criteria[0].RightOp...)
+			return &types.ServiceBrokers{}, nil
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, changes ...*query.LabelChange) (types.Object, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			operations = append(operations, *obj.(*types.Operation))
+			return obj, nil
+		})
+		controller = api.NewServiceBrokerController(context.Background(), repository, settings, nil, cache.NewMemoryStore())
+	})
+
+	It("returns an operation tracking the deletion of the brokers matching the criteria", func() {
+		response := deleteBrokers("https://sm.example.com/v1/service_brokers?async=true")
+		Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+
+		operation := &types.Operation{}
+		Expect(json.Unmarshal(response.Body, operation)).To(Succeed())
+		Expect(operation.Type).To(Equal(types.DELETE))
+		Expect(operation.ResourceID).To(Equal(api.BulkResourceID))
+		Expect(operation.ResourceType).To(Equal(types.ServiceBrokerType))
+		Expect(response.Header.Get("Location")).To(Equal("http://sm.example.com/v1/operations/" + operation.ID))
+
+		Eventually(func() types.OperationState { return lastOperation().State }).Should(Equal(types.SUCCEEDED))
+		Expect(lastOperation().Description).To(Equal("deleted 5 brokers"))
+		Expect(deleted).To(ConsistOf("broker-1", "broker-2", "broker-3", "broker-4", "broker-5"))
+		Expect(repository.DeleteCallCount()).To(Equal(3))
+	})
+
+	It("reports the progress of each batch", func() {
+		deleteBrokers("https://sm.example.com/v1/service_brokers?async=true")
+
+		Eventually(func() types.OperationState { return lastOperation().State }).Should(Equal(types.SUCCEEDED))
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(operations[0].State).To(Equal(types.IN_PROGRESS))
+		Expect(operations[0].Description).To(Equal("deleted 2 of 5 brokers"))
+	})
+
+	It("collects the brokers which cannot be deleted", func() {
+		undeletable = "broker-3"
+		deleteBrokers("https://sm.example.com/v1/service_brokers?async=true")
+
+		Eventually(func() types.OperationState { return lastOperation().State }).Should(Equal(types.FAILED))
+		operation := lastOperation()
+		Expect(operation.Description).To(Equal("deleted 4 of 5 brokers, 1 could not be deleted"))
+
+		var errs struct {
+			Resources []map[string]string `json:"resources"`
+		}
+		Expect(json.Unmarshal(operation.Errors, &errs)).To(Succeed())
+		Expect(errs.Resources).To(HaveLen(1))
+		Expect(errs.Resources[0]).To(HaveKeyWithValue("id", "broker-3"))
+		Expect(errs.Resources[0]).To(HaveKeyWithValue("name", "name-broker-3"))
+		Expect(errs.Resources[0]["error"]).To(ContainSubstring("broker has service instances"))
+		Expect(deleted).To(ConsistOf("broker-1", "broker-2", "broker-4", "broker-5"))
+	})
+
+	It("deletes the brokers on behalf of the user of the request", func() {
+		var users []string
+		repository.DeleteCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if user, found := web.UserFromContext(ctx); found {
+				users = append(users, user.Name)
+			}
+			return &types.ServiceBrokers{}, nil
+		})
+
+		httpRequest, err := http.NewRequest(http.MethodDelete, "https://sm.example.com/v1/service_brokers?async=true", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := features.ContextWithManager(httpRequest.Context(), manager)
+		ctx = web.ContextWithUser(ctx, &web.UserContext{Name: "operator"})
+		_, err = controller.DeleteObjects(&web.Request{Request: httpRequest.WithContext(ctx)})
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() types.OperationState { return lastOperation().State }).Should(Equal(types.SUCCEEDED))
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(users).To(Equal([]string{"operator", "operator", "operator"}))
+	})
+
+	It("deletes the brokers synchronously if the request is not asynchronous", func() {
+		response := deleteBrokers("https://sm.example.com/v1/service_brokers")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(repository.DeleteCallCount()).To(Equal(1))
+		Expect(repository.ListCallCount()).To(Equal(0))
+	})
+
+	It("deletes the brokers synchronously if asynchronous operations are disabled", func() {
+		manager = features.NewManager(&features.Settings{Disabled: []string{api.AsyncOperationsFeature}})
+		manager.Register(api.AsyncOperationsFlag)
+
+		response := deleteBrokers("https://sm.example.com/v1/service_brokers?async=true")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(repository.DeleteCallCount()).To(Equal(1))
+		Expect(repository.ListCallCount()).To(Equal(0))
+	})
+})
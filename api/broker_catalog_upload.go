@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/catalog"
+)
+
+const catalogUploadKeyPrefix = "catalog_upload:"
+
+var contentRangePattern = regexp.MustCompile(`^bytes (\d+)-(\d+)/(\d+)$`)
+
+// chunk is a part of an uploaded catalog as specified by the Content-Range header of its request
+type chunk struct {
+	start, end, total int64
+}
+
+func parseContentRange(contentRange string) (*chunk, error) {
+	matches := contentRangePattern.FindStringSubmatch(contentRange)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid Content-Range %q, expected bytes <start>-<end>/<total>", contentRange)
+	}
+	c := &chunk{}
+	for i, value := range []*int64{&c.start, &c.end, &c.total} {
+		parsed, err := strconv.ParseInt(matches[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Content-Range %q: %s", contentRange, err)
+		}
+		*value = parsed
+	}
+	if c.start > c.end || c.end >= c.total {
+		return nil, fmt.Errorf("invalid Content-Range %q, the range must be within the total size", contentRange)
+	}
+	return c, nil
+}
+
+// UploadCatalog handles the upload of the catalog of a broker whose catalog is not fetched from the broker. The catalog
+// can be compressed with gzip and large catalogs can be uploaded in chunks, which must be sent in order with
+// Content-Range headers. The catalog is applied to the broker once its last chunk is received.
+func (c *ServiceBrokerController) UploadCatalog(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	brokerID := r.PathParams[PathParamID]
+	obj, err := c.repository.Get(ctx, types.ServiceBrokerType, brokerID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	broker := obj.(*types.ServiceBroker)
+	if !catalog.IsUploaded(broker) {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("the catalog of broker %s is fetched from the broker, it must be labeled with %s=%s to upload its catalog", broker.Name, catalog.SourceLabel, catalog.SourceUpload),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	catalogBytes, received, err := c.receiveChunk(ctx, brokerID, r.Header.Get("Content-Range"), body)
+	if err != nil {
+		return nil, err
+	}
+	if catalogBytes == nil {
+		log.C(ctx).Debugf("Received %d bytes of the catalog of broker %s", received, broker.Name)
+		response, err := util.NewJSONResponse(http.StatusAccepted, map[string]int64{"received_bytes": received})
+		if err != nil {
+			return nil, err
+		}
+		response.Header.Set("Range", fmt.Sprintf("bytes=0-%d", received-1))
+		return response, nil
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		if catalogBytes, err = c.decompressCatalog(catalogBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	log.C(ctx).Infof("Applying uploaded catalog of %d bytes to broker %s", len(catalogBytes), broker.Name)
+	broker.UpdatedAt = time.Now().UTC()
+	updated, err := c.repository.Update(catalog.ContextWithUploadedCatalog(ctx, catalogBytes), broker)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	return util.NewJSONResponse(http.StatusOK, updated)
+}
+
+// receiveChunk stores the chunk of the catalog upload of the broker and returns the complete catalog once the last
+// chunk is received. Otherwise it returns the number of bytes received so far. A body without Content-Range is
+// the complete catalog.
+func (c *ServiceBrokerController) receiveChunk(ctx context.Context, brokerID, contentRange string, body []byte) ([]byte, int64, error) {
+	key := catalogUploadKeyPrefix + brokerID
+	if contentRange == "" {
+		if int64(len(body)) > c.settings.CatalogUploadMaxBytes {
+			return nil, 0, c.catalogTooLarge()
+		}
+		if err := c.uploads.Delete(ctx, key); err != nil {
+			return nil, 0, err
+		}
+		return body, int64(len(body)), nil
+	}
+
+	chunk, err := parseContentRange(contentRange)
+	if err != nil {
+		return nil, 0, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: err.Error(),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if chunk.end-chunk.start+1 != int64(len(body)) {
+		return nil, 0, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("the chunk has %d bytes but its Content-Range specifies %d", len(body), chunk.end-chunk.start+1),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if chunk.total > c.settings.CatalogUploadMaxBytes {
+		return nil, 0, c.catalogTooLarge()
+	}
+
+	var received []byte
+	if chunk.start > 0 {
+		var found bool
+		if received, found, err = c.uploads.Get(ctx, key); err != nil {
+			return nil, 0, err
+		}
+		if !found || int64(len(received)) != chunk.start {
+			// the client resumes the upload from the reported number of received bytes
+			return nil, 0, &util.HTTPError{
+				ErrorType:   "Conflict",
+				Description: fmt.Sprintf("the chunk starts at byte %d but %d bytes of the catalog were received", chunk.start, len(received)),
+				StatusCode:  http.StatusConflict,
+				Details:     map[string]int{"received_bytes": len(received)},
+			}
+		}
+	}
+	received = append(received, body...)
+
+	if chunk.end+1 == chunk.total {
+		if err := c.uploads.Delete(ctx, key); err != nil {
+			return nil, 0, err
+		}
+		return received, chunk.total, nil
+	}
+	if err := c.uploads.Set(ctx, key, received, c.settings.CatalogUploadTTL); err != nil {
+		return nil, 0, err
+	}
+	return nil, int64(len(received)), nil
+}
+
+func (c *ServiceBrokerController) decompressCatalog(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("could not decompress the catalog: %s", err),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	defer reader.Close()
+
+	// the limit applies to the decompressed catalog, so that small uploads cannot expand unboundedly
+	catalogBytes, err := ioutil.ReadAll(io.LimitReader(reader, c.settings.CatalogUploadMaxBytes+1))
+	if err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("could not decompress the catalog: %s", err),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if int64(len(catalogBytes)) > c.settings.CatalogUploadMaxBytes {
+		return nil, c.catalogTooLarge()
+	}
+	return catalogBytes, nil
+}
+
+func (c *ServiceBrokerController) catalogTooLarge() error {
+	return &util.HTTPError{
+		ErrorType:   "PayloadTooLarge",
+		Description: fmt.Sprintf("the catalog exceeds the maximum size of %d bytes", c.settings.CatalogUploadMaxBytes),
+		StatusCode:  http.StatusRequestEntityTooLarge,
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// CatalogRecoveryJobName is the name under which the catalog recovery job is registered
+	CatalogRecoveryJobName = "broker_catalog_recovery"
+
+	// CatalogRecoveryAttemptsLabel is the label of the operations of asynchronous broker registrations with the number
+	// of attempts of the catalog recovery to persist the catalog of the broker
+	CatalogRecoveryAttemptsLabel = "catalog_recovery_attempts"
+)
+
+// CatalogRecoveryJob persists the catalogs of the brokers whose asynchronous registration failed or was interrupted,
+// e.g. because the instance which fetched the catalog stopped, so that they do not stay registered without catalog.
+// The progress is tracked in the operation of the registration, which is exposed by the operations API. Persisting
+// a catalog is idempotent, so an attempt can be repeated after a failure at any point.
+type CatalogRecoveryJob struct {
+	settings   *Settings
+	repository storage.Repository
+}
+
+// NewCatalogRecoveryJob returns a catalog recovery job
+func NewCatalogRecoveryJob(settings *Settings, repository storage.Repository) *CatalogRecoveryJob {
+	return &CatalogRecoveryJob{
+		settings:   settings,
+		repository: repository,
+	}
+}
+
+// Name implements jobs.Job
+func (j *CatalogRecoveryJob) Name() string {
+	return CatalogRecoveryJobName
+}
+
+// Run implements jobs.Job and retries the catalog persistence of the unfinished broker registrations
+func (j *CatalogRecoveryJob) Run(ctx context.Context) error {
+	operations, err := j.repository.List(ctx, types.OperationObjectType,
+		query.ByField(query.EqualsOperator, "type", string(types.CREATE)),
+		query.ByField(query.EqualsOperator, "resource_type", string(types.ServiceBrokerType)),
+		query.ByField(query.InOperator, "state", string(types.IN_PROGRESS), string(types.FAILED)))
+	if err != nil {
+		return err
+	}
+
+	var failures int
+	for i := 0; i < operations.Len(); i++ {
+		operation := operations.ItemAt(i).(*types.Operation)
+		if err := j.recover(ctx, operation); err != nil {
+			log.C(ctx).WithError(err).Warnf("Could not recover catalog of broker with id %s", operation.ResourceID)
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("could not recover the catalogs of %d brokers", failures)
+	}
+	return nil
+}
+
+func (j *CatalogRecoveryJob) recover(ctx context.Context, operation *types.Operation) error {
+	attempts := recoveryAttempts(operation)
+	if attempts >= j.settings.CatalogRecoveryAttempts {
+		return nil
+	}
+	// registrations in progress are still fetching their catalog unless the instance fetching it stopped
+	if operation.State == types.IN_PROGRESS && time.Since(operation.UpdatedAt) < j.settings.CatalogRecoveryInterval {
+		return nil
+	}
+
+	broker, err := j.repository.Get(ctx, types.ServiceBrokerType, operation.ResourceID)
+	if err == util.ErrNotFoundInStorage {
+		return j.updateOperation(ctx, operation, j.settings.CatalogRecoveryAttempts, types.FAILED, "broker no longer exists", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if len(broker.(*types.ServiceBroker).Catalog) != 0 {
+		// the catalog was persisted by an update of the broker in the meantime
+		return j.updateOperation(ctx, operation, attempts, types.SUCCEEDED, "broker catalog fetched successfully", nil)
+	}
+
+	attempts++
+	log.C(ctx).Infof("Recovering catalog of broker with id %s (attempt %d of %d)", operation.ResourceID, attempts, j.settings.CatalogRecoveryAttempts)
+	if err := j.updateOperation(ctx, operation, attempts, types.IN_PROGRESS, fmt.Sprintf("recovering broker catalog (attempt %d of %d)", attempts, j.settings.CatalogRecoveryAttempts), nil); err != nil {
+		return err
+	}
+	broker.SetUpdatedAt(time.Now().UTC())
+	if _, fetchErr := j.repository.Update(ctx, broker); fetchErr != nil {
+		description := "could not fetch broker catalog, the fetch is retried in the background"
+		if attempts >= j.settings.CatalogRecoveryAttempts {
+			description = "could not fetch broker catalog, update the broker to retry"
+		}
+		if err := j.updateOperation(ctx, operation, attempts, types.FAILED, description, fetchErr); err != nil {
+			return err
+		}
+		return fetchErr
+	}
+	return j.updateOperation(ctx, operation, attempts, types.SUCCEEDED, "broker catalog fetched successfully", nil)
+}
+
+func (j *CatalogRecoveryJob) updateOperation(ctx context.Context, operation *types.Operation, attempts int, state types.OperationState, description string, opErr error) error {
+	operation.State = state
+	operation.Description = description
+	operation.UpdatedAt = time.Now().UTC()
+	operation.Errors = nil
+	if opErr != nil {
+		operation.Errors = operationErrors(ctx, operation, opErr)
+	}
+
+	var labelChanges []*query.LabelChange
+	if attempts != recoveryAttempts(operation) {
+		if _, found := operation.Labels[CatalogRecoveryAttemptsLabel]; found {
+			labelChanges = append(labelChanges, &query.LabelChange{Operation: query.RemoveLabelOperation, Key: CatalogRecoveryAttemptsLabel})
+		}
+		labelChanges = append(labelChanges, &query.LabelChange{Operation: query.AddLabelOperation, Key: CatalogRecoveryAttemptsLabel, Values: []string{strconv.Itoa(attempts)}})
+	}
+	if _, err := j.repository.Update(ctx, operation, labelChanges...); err != nil {
+		return fmt.Errorf("could not update operation with id %s to state %s: %s", operation.ID, state, err)
+	}
+	if len(labelChanges) != 0 {
+		if operation.Labels == nil {
+			operation.Labels = types.Labels{}
+		}
+		operation.Labels[CatalogRecoveryAttemptsLabel] = []string{strconv.Itoa(attempts)}
+	}
+	return nil
+}
+
+func recoveryAttempts(operation *types.Operation) int {
+	if values := operation.Labels[CatalogRecoveryAttemptsLabel]; len(values) > 0 {
+		if attempts, err := strconv.Atoi(values[0]); err == nil {
+			return attempts
+		}
+	}
+	return 0
+}
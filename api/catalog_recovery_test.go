@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog recovery job", func() {
+	var (
+		repository   *storagefakes.FakeStorage
+		job          *api.CatalogRecoveryJob
+		operation    *types.Operation
+		broker       *types.ServiceBroker
+		fetchErr     error
+		states       []types.OperationState
+		labelChanges []*query.LabelChange
+		fetches      int
+	)
+
+	BeforeEach(func() {
+		settings := api.DefaultSettings()
+		settings.CatalogRecoveryAttempts = 2
+
+		operation = &types.Operation{
+			Base: types.Base{
+				ID:        "operation-id",
+				UpdatedAt: time.Now().Add(-time.Hour),
+				Labels:    types.Labels{},
+			},
+			Type:         types.CREATE,
+			State:        types.FAILED,
+			ResourceID:   "broker-id",
+			ResourceType: types.ServiceBrokerType,
+		}
+		broker = &types.ServiceBroker{
+			Base: types.Base{ID: "broker-id"},
+			Name: "broker",
+		}
+		fetchErr = nil
+		states = nil
+		labelChanges = nil
+		fetches = 0
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListReturns(&types.Operations{Operations: []*types.Operation{operation}}, nil)
+		repository.GetCalls(func(ctx context.Context, objectType types.ObjectType, id string) (types.Object, error) {
+			if broker == nil {
+				return nil, util.ErrNotFoundInStorage
+			}
+			return broker, nil
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, changes ...*query.LabelChange) (types.Object, error) {
+			if op, ok := obj.(*types.Operation); ok {
+				states = append(states, op.State)
+				labelChanges = append(labelChanges, changes...)
+				return op, nil
+			}
+			fetches++
+			if fetchErr != nil {
+				return nil, fetchErr
+			}
+			broker.Catalog = []byte(`{"services":[]}`)
+			return obj, nil
+		})
+		job = api.NewCatalogRecoveryJob(settings, repository)
+	})
+
+	It("fetches the catalog of failed registrations", func() {
+		Expect(job.Run(context.Background())).To(Succeed())
+		Expect(fetches).To(Equal(1))
+		Expect(states).To(Equal([]types.OperationState{types.IN_PROGRESS, types.SUCCEEDED}))
+		Expect(labelChanges).To(HaveLen(1))
+		Expect(labelChanges[0].Key).To(Equal(api.CatalogRecoveryAttemptsLabel))
+		Expect(labelChanges[0].Values).To(Equal([]string{"1"}))
+	})
+
+	It("records failed attempts and stops after the last one", func() {
+		fetchErr = errors.New("broker unreachable")
+		Expect(job.Run(context.Background())).ToNot(Succeed())
+		Expect(job.Run(context.Background())).ToNot(Succeed())
+		Expect(job.Run(context.Background())).To(Succeed())
+		Expect(fetches).To(Equal(2))
+		Expect(operation.State).To(Equal(types.FAILED))
+		Expect(operation.Labels[api.CatalogRecoveryAttemptsLabel]).To(Equal([]string{"2"}))
+	})
+
+	It("leaves registrations which are still in progress", func() {
+		operation.State = types.IN_PROGRESS
+		operation.UpdatedAt = time.Now()
+		Expect(job.Run(context.Background())).To(Succeed())
+		Expect(fetches).To(BeZero())
+		Expect(states).To(BeEmpty())
+	})
+
+	It("completes registrations whose broker got a catalog in the meantime", func() {
+		broker.Catalog = []byte(`{"services":[]}`)
+		Expect(job.Run(context.Background())).To(Succeed())
+		Expect(fetches).To(BeZero())
+		Expect(states).To(Equal([]types.OperationState{types.SUCCEEDED}))
+	})
+
+	It("gives up registrations whose broker was deleted", func() {
+		broker = nil
+		Expect(job.Run(context.Background())).To(Succeed())
+		Expect(states).To(Equal([]types.OperationState{types.FAILED}))
+		Expect(operation.Labels[api.CatalogRecoveryAttemptsLabel]).To(Equal([]string{"2"}))
+	})
+})
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cfvisibility contains logic for the Service Manager API which exposes the visibilities of Cloud Foundry
+// platforms in the form of Cloud Foundry service plan visibilities
+package cfvisibility
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// PathParamID is the name of the path parameter containing the platform ID
+	PathParamID = "id"
+
+	// URL is the path of the CF visibilities endpoint
+	URL = web.PlatformsURL + "/{" + PathParamID + "}/cf_visibilities"
+)
+
+// Routes returns a slice of the routes that handle CF visibility operations
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   URL,
+			},
+			Handler: c.getVisibilities,
+			Doc: &web.RouteDoc{
+				Summary: "Get the visibilities of a platform of type cloudfoundry as CF service plan visibilities",
+			},
+		},
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cfvisibility
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/cfvisibility"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Controller CF visibilities controller
+type Controller struct {
+	Repository storage.Repository
+	Mapper     *cfvisibility.Mapper
+}
+
+var _ web.Controller = &Controller{}
+
+type visibilitiesResponse struct {
+	Visibilities []*cfvisibility.PlanVisibility `json:"visibilities"`
+}
+
+func (c *Controller) getVisibilities(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	platformID := r.PathParams[PathParamID]
+
+	// platforms authenticated with their credentials may only read their own visibilities
+	if user, ok := web.UserFromContext(ctx); ok {
+		caller := &types.Platform{}
+		if err := user.Data.Data(caller); err != nil {
+			return nil, err
+		}
+		if caller.ID != "" && caller.ID != platformID {
+			return nil, &util.HTTPError{
+				ErrorType:   "Forbidden",
+				Description: "platforms can only read their own visibilities",
+				StatusCode:  http.StatusForbidden,
+			}
+		}
+	}
+
+	platform, err := c.Repository.Get(ctx, types.PlatformType, platformID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, "platform")
+	}
+	visibilities, err := c.Mapper.Map(ctx, platform.(*types.Platform))
+	if err == cfvisibility.ErrUnsupportedPlatform {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("platform %s is not of type %s", platformID, cfvisibility.PlatformType),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, &visibilitiesResponse{
+		Visibilities: visibilities,
+	})
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// ErrorCodeController implements api.Controller by documenting the codes of the error responses
+type ErrorCodeController struct{}
+
+var _ web.Controller = &ErrorCodeController{}
+
+// Routes returns the routes of the error code catalog
+func (c *ErrorCodeController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ErrorCodesURL,
+			},
+			Handler: c.listErrorCodes,
+		},
+	}
+}
+
+func (c *ErrorCodeController) listErrorCodes(request *web.Request) (*web.Response, error) {
+	return util.NewJSONResponse(http.StatusOK, &struct {
+		Items []util.ErrorCode `json:"items"`
+	}{
+		Items: util.ErrorCodes(),
+	})
+}
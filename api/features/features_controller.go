@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package features
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Controller feature flags controller
+type Controller struct {
+	Manager *features.Manager
+	States  *features.StoreSource
+
+	// AdminScope is the scope required for changing the feature states
+	AdminScope string
+}
+
+var _ web.Controller = &Controller{}
+
+type featuresResponse struct {
+	Features []features.State `json:"features"`
+}
+
+type featureRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// Validate implements InputValidator and verifies that the state of the feature is provided
+func (r *featureRequest) Validate() error {
+	if r.Enabled == nil {
+		return fmt.Errorf("missing enabled")
+	}
+	return nil
+}
+
+func (c *Controller) listFeatures(request *web.Request) (*web.Response, error) {
+	return util.NewJSONResponse(http.StatusOK, &featuresResponse{
+		Features: c.Manager.States(),
+	})
+}
+
+func (c *Controller) setFeature(r *web.Request) (*web.Response, error) {
+	name, err := c.flagName(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	request := &featureRequest{}
+	if err := util.BytesToObject(body, request); err != nil {
+		return nil, err
+	}
+	log.C(r.Context()).Infof("Setting feature %s to enabled=%t", name, *request.Enabled)
+
+	if err := c.States.Set(r.Context(), name, *request.Enabled); err != nil {
+		return nil, err
+	}
+	return c.featureResponse(name)
+}
+
+func (c *Controller) resetFeature(r *web.Request) (*web.Response, error) {
+	name, err := c.flagName(r)
+	if err != nil {
+		return nil, err
+	}
+	log.C(r.Context()).Infof("Resetting feature %s", name)
+
+	if err := c.States.Reset(r.Context(), name); err != nil {
+		return nil, err
+	}
+	return c.featureResponse(name)
+}
+
+func (c *Controller) flagName(r *web.Request) (string, error) {
+	name := r.PathParams[PathParamName]
+	if _, found := c.Manager.Flag(name); !found {
+		return "", &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("could not find feature %s", name),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+	return name, nil
+}
+
+func (c *Controller) featureResponse(name string) (*web.Response, error) {
+	for _, state := range c.Manager.States() {
+		if state.Name == name {
+			return util.NewJSONResponse(http.StatusOK, state)
+		}
+	}
+	return nil, fmt.Errorf("feature %s is not registered", name)
+}
@@ -17,10 +17,16 @@
 package filters
 
 import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 
+	"github.com/Peripli/service-manager/pkg/credentials"
 	"github.com/Peripli/service-manager/pkg/security/filters"
 
 	"github.com/Peripli/service-manager/pkg/types"
@@ -35,9 +41,18 @@ import (
 
 const BasicAuthnFilterName string = "BasicAuthnFilter"
 
-func NewBasicAuthnFilter(repository storage.Repository) *filters.AuthenticationFilter {
+// verifiedPasswordsSize is the number of persisted hashes whose verified password digests are kept
+const verifiedPasswordsSize = 1000
+
+// NewBasicAuthnFilter returns a filter which authenticates platforms by their basic credentials. Platforms are not
+// cached, as they emit no notifications and changed credentials would be accepted until the cached platforms expire.
+// Passwords stored in a secret store are resolved with the secret store of the provided secrets. Passwords persisted
+// as hashes are verified with bcrypt once and then against a digest of the verified password, as bcrypt is too slow
+// for every request.
+func NewBasicAuthnFilter(repository storage.Repository, secrets *credentials.Provider) *filters.AuthenticationFilter {
 	return filters.NewAuthenticationFilter(&basicAuthenticator{
 		Repository: repository,
+		Secrets:    secrets,
 	}, BasicAuthnFilterName, basicAuthnMatchers())
 }
 
@@ -52,6 +67,9 @@ func (bad *basicAuthnData) Data(v interface{}) error {
 // basicAuthenticator for basic security
 type basicAuthenticator struct {
 	Repository storage.Repository
+	Secrets    *credentials.Provider
+
+	verified verifiedPasswords
 }
 
 // Authenticate authenticates by using the provided Basic credentials
@@ -73,12 +91,16 @@ func (a *basicAuthenticator) Authenticate(request *http.Request) (*web.UserConte
 	}
 
 	obj := objectList.ItemAt(0)
+	if err := a.resolvePassword(ctx, obj); err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
 	securedObj, isSecured := obj.(types.Secured)
 	if !isSecured {
 		return nil, httpsec.Abstain, fmt.Errorf("object of type %s is used in authentication and must be secured", obj.GetType())
 	}
 
-	if securedObj.GetCredentials().Basic.Password != password {
+	if !a.verify(securedObj.GetCredentials().Basic.Password, password) {
 		return nil, httpsec.Deny, fmt.Errorf("provided credentials are invalid")
 	}
 
@@ -95,6 +117,91 @@ func (a *basicAuthenticator) Authenticate(request *http.Request) (*web.UserConte
 	}, httpsec.Allow, nil
 }
 
+// verify returns true if the password matches the persisted one. Hashes are verified with bcrypt only if the
+// password differs from the one last verified against the hash.
+func (a *basicAuthenticator) verify(persisted, password string) bool {
+	if !credentials.IsHash(persisted) {
+		return credentials.Verify(persisted, password)
+	}
+	digest := sha256.Sum256([]byte(password))
+	if verifiedDigest, found := a.verified.get(persisted); found &&
+		subtle.ConstantTimeCompare(verifiedDigest[:], digest[:]) == 1 {
+		return true
+	}
+	if !credentials.Verify(persisted, password) {
+		return false
+	}
+	a.verified.put(persisted, digest)
+	return true
+}
+
+// verifiedPasswords holds the digests of the passwords verified against the persisted hashes by hash in a bounded
+// LRU cache, so that the hashes of changed passwords are evicted eventually
+type verifiedPasswords struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type verifiedPassword struct {
+	hash   string
+	digest [sha256.Size]byte
+}
+
+func (v *verifiedPasswords) get(hash string) ([sha256.Size]byte, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	element, found := v.entries[hash]
+	if !found {
+		return [sha256.Size]byte{}, false
+	}
+	v.order.MoveToFront(element)
+	return element.Value.(*verifiedPassword).digest, true
+}
+
+func (v *verifiedPasswords) put(hash string, digest [sha256.Size]byte) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.entries == nil {
+		v.entries = make(map[string]*list.Element)
+		v.order = list.New()
+	}
+	if element, found := v.entries[hash]; found {
+		v.order.Remove(element)
+	}
+	v.entries[hash] = v.order.PushFront(&verifiedPassword{hash: hash, digest: digest})
+	for v.order.Len() > verifiedPasswordsSize {
+		oldest := v.order.Back()
+		v.order.Remove(oldest)
+		delete(v.entries, oldest.Value.(*verifiedPassword).hash)
+	}
+}
+
+// resolvePassword replaces the reference to the password of the platform in the secret store with the password
+func (a *basicAuthenticator) resolvePassword(ctx context.Context, obj types.Object) error {
+	securedObj, isSecured := obj.(types.Secured)
+	if !isSecured || securedObj.GetCredentials() == nil || securedObj.GetCredentials().Basic == nil {
+		return nil
+	}
+	basic := securedObj.GetCredentials().Basic
+	reference, isReference := credentials.ParseReference(basic.Password)
+	if !isReference {
+		return nil
+	}
+	store := a.Secrets.SecretStore()
+	if store == nil {
+		return fmt.Errorf("password of %s with id %s is stored in a secret store but none is configured", obj.GetType(), obj.GetID())
+	}
+	password, err := store.Get(ctx, reference)
+	if err != nil {
+		return fmt.Errorf("could not resolve password from secret store %s: %s", store.Name(), err)
+	}
+	basic.Password = password
+	return nil
+}
+
 func basicAuthnMatchers() []web.FilterMatcher {
 	return []web.FilterMatcher{
 		{
@@ -138,5 +245,11 @@ func basicAuthnMatchers() []web.FilterMatcher {
 				web.Path(web.NotificationsURL + "/**"),
 			},
 		},
+		{
+			Matchers: []web.Matcher{
+				web.Methods(http.MethodPost),
+				web.Path(web.NotificationsTokenURL),
+			},
+		},
 	}
 }
@@ -17,11 +17,14 @@
 package filters
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"net/http"
 	"testing"
 
+	"github.com/Peripli/service-manager/pkg/credentials"
 	"github.com/Peripli/service-manager/storage/storagefakes"
 
 	httpsec "github.com/Peripli/service-manager/pkg/security/http"
@@ -197,7 +200,158 @@ var _ = Describe("Basic Authenticator", func() {
 					Expect(user).To(Not(BeNil()))
 					Expect(decision).To(Equal(httpsec.Allow))
 				})
+
+				It("Should deny as soon as the password is changed", func() {
+					_, decision, err := authenticator.Authenticate(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(decision).To(Equal(httpsec.Allow))
+
+					fakeRepository.ListReturns(&types.Platforms{
+						Platforms: []*types.Platform{
+							{
+								Base: types.Base{
+									ID: "id1",
+								},
+								Credentials: &types.Credentials{
+									Basic: &types.Basic{
+										Username: "username",
+										Password: "changed-password",
+									},
+								},
+							},
+						},
+					}, nil)
+					user, decision, err := authenticator.Authenticate(request)
+					Expect(err).To(HaveOccurred())
+					Expect(user).To(BeNil())
+					Expect(decision).To(Equal(httpsec.Deny))
+				})
+			})
+
+			Context("When the password is hashed", func() {
+				BeforeEach(func() {
+					hash, err := credentials.Hash("password", 4)
+					Expect(err).ToNot(HaveOccurred())
+					fakeRepository.ListReturns(&types.Platforms{
+						Platforms: []*types.Platform{
+							{
+								Base: types.Base{
+									ID: "id1",
+								},
+								Credentials: &types.Credentials{
+									Basic: &types.Basic{
+										Username: "username",
+										Password: hash,
+									},
+								},
+							},
+						},
+					}, nil)
+				})
+
+				It("Should allow if the password matches the hash", func() {
+					for i := 0; i < 2; i++ {
+						user, decision, err := authenticator.Authenticate(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(user).To(Not(BeNil()))
+						Expect(decision).To(Equal(httpsec.Allow))
+					}
+				})
+
+				It("Should deny if the password does not match the hash", func() {
+					_, decision, err := authenticator.Authenticate(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(decision).To(Equal(httpsec.Allow))
+
+					request.SetBasicAuth("username", "not-matching-password")
+					user, decision, err := authenticator.Authenticate(request)
+					Expect(err).To(HaveOccurred())
+					Expect(user).To(BeNil())
+					Expect(decision).To(Equal(httpsec.Deny))
+				})
+
+				It("Should evict the least recently verified hashes", func() {
+					_, decision, err := authenticator.Authenticate(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(decision).To(Equal(httpsec.Allow))
+					Expect(authenticator.verified.entries).To(HaveLen(1))
+
+					for i := 0; i < verifiedPasswordsSize; i++ {
+						authenticator.verified.put(fmt.Sprintf("hash-%d", i), [sha256.Size]byte{})
+					}
+					Expect(authenticator.verified.entries).To(HaveLen(verifiedPasswordsSize))
+					Expect(authenticator.verified.order.Back().Value.(*verifiedPassword).hash).To(Equal("hash-0"))
+				})
+			})
+
+			Context("When the password is stored in a secret store", func() {
+				BeforeEach(func() {
+					fakeRepository.ListReturns(&types.Platforms{
+						Platforms: []*types.Platform{
+							{
+								Base: types.Base{
+									ID: "id1",
+								},
+								Credentials: &types.Credentials{
+									Basic: &types.Basic{
+										Username: "username",
+										Password: credentials.Reference("id1"),
+									},
+								},
+							},
+						},
+					}, nil)
+				})
+
+				It("Should allow with the resolved password", func() {
+					authenticator.Secrets = credentials.NewProvider(nil)
+					authenticator.Secrets.UseSecretStore(staticSecretStore{"id1": "password"})
+					user, decision, err := authenticator.Authenticate(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(user).To(Not(BeNil()))
+					Expect(decision).To(Equal(httpsec.Allow))
+				})
+
+				It("Should deny if the resolved password does not match", func() {
+					authenticator.Secrets = credentials.NewProvider(nil)
+					authenticator.Secrets.UseSecretStore(staticSecretStore{"id1": "not-matching-password"})
+					user, decision, err := authenticator.Authenticate(request)
+					Expect(err).To(HaveOccurred())
+					Expect(user).To(BeNil())
+					Expect(decision).To(Equal(httpsec.Deny))
+				})
+
+				It("Should abstain with error if no secret store is configured", func() {
+					user, decision, err := authenticator.Authenticate(request)
+					Expect(err).To(HaveOccurred())
+					Expect(user).To(BeNil())
+					Expect(decision).To(Equal(httpsec.Abstain))
+				})
 			})
 		})
 	})
 })
+
+type staticSecretStore map[string]string
+
+func (staticSecretStore) Name() string {
+	return "static"
+}
+
+func (s staticSecretStore) Put(ctx context.Context, platformID, password string) (string, error) {
+	s[platformID] = password
+	return platformID, nil
+}
+
+func (s staticSecretStore) Get(ctx context.Context, reference string) (string, error) {
+	password, found := s[reference]
+	if !found {
+		return "", fmt.Errorf("password %s not found", reference)
+	}
+	return password, nil
+}
+
+func (s staticSecretStore) Delete(ctx context.Context, reference string) error {
+	delete(s, reference)
+	return nil
+}
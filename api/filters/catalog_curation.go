@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/visibilitypolicy"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// CatalogCurationFilterName is the name of the filter which hides catalog entries from platforms
+	CatalogCurationFilterName = "CatalogCurationFilter"
+
+	// CurationHide hides the matching catalog entries
+	CurationHide = "hide"
+
+	// CurationExpose exposes the matching catalog entries, e.g. to platforms excluded from an earlier hide rule
+	CurationExpose = "expose"
+)
+
+// CurationRule hides or exposes the services and plans of OSB catalogs with a tag or a metadata value from the
+// platforms matching a selector
+type CurationRule struct {
+	// Action is either hide or expose
+	Action string
+
+	// Tag is the tag of the services to which the rule applies, it is empty if the rule matches metadata
+	Tag string
+
+	// MetadataPath is the path of the metadata field of the services and plans to which the rule applies
+	MetadataPath string
+
+	// MetadataValue is the value the metadata field must have
+	MetadataValue string
+
+	// PlatformSelector are the criteria of the platforms to which the rule applies, it applies to all if empty
+	PlatformSelector []query.Criterion
+}
+
+// ParseCurationRules parses curation rules in the form action entry [for platform selector], where the entry is
+// tag:tag or metadata.path:value and the selector is a label query like in visibility policies,
+// e.g. hide tag:beta for env = prod
+func ParseCurationRules(rules []string) ([]CurationRule, error) {
+	result := make([]CurationRule, 0, len(rules))
+	for _, rule := range rules {
+		curationRule := CurationRule{}
+		definition := strings.TrimSpace(rule)
+		if forIndex := strings.Index(definition, " for "); forIndex != -1 {
+			selector, err := visibilitypolicy.ParseSelector(strings.TrimSpace(definition[forIndex+len(" for "):]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid curation rule %q: %s", rule, err)
+			}
+			curationRule.PlatformSelector = selector
+			definition = definition[:forIndex]
+		}
+		fields := strings.Fields(definition)
+		if len(fields) != 2 || (fields[0] != CurationHide && fields[0] != CurationExpose) {
+			return nil, fmt.Errorf("invalid curation rule %q: expected the form hide|expose entry [for selector]", rule)
+		}
+		curationRule.Action = fields[0]
+		separator := strings.Index(fields[1], ":")
+		if separator <= 0 || separator == len(fields[1])-1 {
+			return nil, fmt.Errorf("invalid curation rule %q: expected the entry tag:tag or metadata.path:value", rule)
+		}
+		key, value := fields[1][:separator], fields[1][separator+1:]
+		switch {
+		case key == "tag":
+			curationRule.Tag = value
+		case strings.HasPrefix(key, "metadata.") && len(key) > len("metadata."):
+			curationRule.MetadataPath = key
+			curationRule.MetadataValue = value
+		default:
+			return nil, fmt.Errorf("invalid curation rule %q: expected the entry tag:tag or metadata.path:value", rule)
+		}
+		result = append(result, curationRule)
+	}
+	return result, nil
+}
+
+// matches returns true if the rule applies to a catalog entry served to the platform. The tags are the ones of the
+// service of the entry.
+func (r CurationRule) matches(entry gjson.Result, tags []gjson.Result, platform *types.Platform) bool {
+	if len(r.PlatformSelector) != 0 && !visibilitypolicy.MatchesSelector(platform.Labels, r.PlatformSelector) {
+		return false
+	}
+	if r.Tag != "" {
+		for _, tag := range tags {
+			if tag.String() == r.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	value := entry.Get(r.MetadataPath)
+	return value.Exists() && value.String() == r.MetadataValue
+}
+
+// CatalogCurationFilter removes the services and plans hidden by the curation rules from the OSB catalogs served to
+// platforms. The last rule matching an entry decides whether it is hidden, entries matched by no rule are exposed.
+// Services whose plans are all hidden are removed as well. The catalogs served to other callers are complete.
+type CatalogCurationFilter struct {
+	Rules []CurationRule
+}
+
+func (*CatalogCurationFilter) Name() string {
+	return CatalogCurationFilterName
+}
+
+func (f *CatalogCurationFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	user, ok := web.UserFromContext(req.Context())
+	if !ok {
+		return nil, errors.New("user details not found in request context")
+	}
+	platform := &types.Platform{}
+	if err := user.Data.Data(platform); err != nil {
+		return nil, err
+	}
+	response, err := next.Handle(req)
+	if err != nil || response.StatusCode != http.StatusOK || platform.ID == "" {
+		return response, err
+	}
+
+	body := response.Body
+	services := gjson.GetBytes(body, "services").Array()
+	// elements are removed from the last to the first, so the paths of the remaining ones stay valid
+	for i := len(services) - 1; i >= 0; i-- {
+		servicePath := fmt.Sprintf("services.%d", i)
+		tags := services[i].Get("tags").Array()
+		if f.hidden(services[i], tags, platform) {
+			if body, err = sjson.DeleteBytes(body, servicePath); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		plans := services[i].Get("plans").Array()
+		remaining := len(plans)
+		for j := len(plans) - 1; j >= 0; j-- {
+			if !f.hidden(plans[j], tags, platform) {
+				continue
+			}
+			if body, err = sjson.DeleteBytes(body, fmt.Sprintf("%s.plans.%d", servicePath, j)); err != nil {
+				return nil, err
+			}
+			remaining--
+		}
+		if remaining == 0 && len(plans) != 0 {
+			if body, err = sjson.DeleteBytes(body, servicePath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	response.Body = body
+
+	return response, nil
+}
+
+func (f *CatalogCurationFilter) hidden(entry gjson.Result, tags []gjson.Result, platform *types.Platform) bool {
+	hidden := false
+	for _, rule := range f.Rules {
+		if rule.matches(entry, tags, platform) {
+			hidden = rule.Action == CurationHide
+		}
+	}
+	return hidden
+}
+
+func (*CatalogCurationFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/catalog"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("Catalog curation filter", func() {
+	const catalog = `{"services": [
+		{"id": "stable", "tags": ["db"], "plans": [{"id": "small"}, {"id": "preview", "metadata": {"preview": true}}]},
+		{"id": "beta", "tags": ["db", "beta"], "plans": [{"id": "beta-small"}]},
+		{"id": "previews", "plans": [{"id": "only-preview", "metadata": {"preview": true}}]}
+	]}`
+
+	var filter *CatalogCurationFilter
+
+	serve := func(user string) []byte {
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/osb/broker-id/v2/catalog", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request := &web.Request{Request: httpRequest}
+		request.Request = request.WithContext(web.ContextWithUser(request.Context(), &web.UserContext{
+			Name: "platform",
+			Data: &basicAuthnData{data: []byte(user)},
+		}))
+		response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK, Body: []byte(catalog)}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		return response.Body
+	}
+
+	BeforeEach(func() {
+		rules, err := ParseCurationRules([]string{
+			"hide tag:beta for env = prod",
+			"hide metadata.preview:true",
+			"expose metadata.preview:true for env = dev",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		filter = &CatalogCurationFilter{Rules: rules}
+	})
+
+	It("hides the matching services and plans from the selected platforms", func() {
+		body := serve(`{"id": "platform-id", "labels": {"env": ["prod"]}}`)
+
+		Expect(gjson.GetBytes(body, "services.#.id").Raw).To(MatchJSON(`["stable"]`))
+		Expect(gjson.GetBytes(body, "services.0.plans.#.id").Raw).To(MatchJSON(`["small"]`))
+	})
+
+	It("applies the last matching rule", func() {
+		body := serve(`{"id": "platform-id", "labels": {"env": ["dev"]}}`)
+
+		Expect(gjson.GetBytes(body, "services.#.id").Raw).To(MatchJSON(`["stable", "beta", "previews"]`))
+		Expect(gjson.GetBytes(body, "services.0.plans.#.id").Raw).To(MatchJSON(`["small", "preview"]`))
+	})
+
+	It("serves the complete catalog to other callers", func() {
+		Expect(serve(`{}`)).To(MatchJSON(catalog))
+	})
+
+	It("rejects invalid rules", func() {
+		for _, rule := range []string{"remove tag:beta", "hide beta", "hide tag:", "hide tag:beta for env"} {
+			_, err := ParseCurationRules([]string{rule})
+			Expect(err).To(HaveOccurred(), rule)
+		}
+	})
+})
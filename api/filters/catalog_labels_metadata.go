@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const CatalogLabelsMetadataFilterName = "CatalogLabelsMetadataFilter"
+
+var pathEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`)
+
+// ParseLabelMappings parses label to metadata field mappings in the form label=field. A mapping without
+// a field exposes the label under its own name.
+func ParseLabelMappings(mappings []string) (map[string]string, error) {
+	result := make(map[string]string, len(mappings))
+	fields := make(map[string]bool, len(mappings))
+	for _, mapping := range mappings {
+		parts := strings.SplitN(mapping, "=", 2)
+		label := strings.TrimSpace(parts[0])
+		field := label
+		if len(parts) == 2 {
+			field = strings.TrimSpace(parts[1])
+		}
+		if label == "" || field == "" {
+			return nil, fmt.Errorf("invalid label mapping %q: label and metadata field must not be empty", mapping)
+		}
+		if _, found := result[label]; found {
+			return nil, fmt.Errorf("invalid label mapping %q: label %s is mapped more than once", mapping, label)
+		}
+		if fields[field] {
+			return nil, fmt.Errorf("invalid label mapping %q: metadata field %s is mapped more than once", mapping, field)
+		}
+		result[label] = field
+		fields[field] = true
+	}
+	return result, nil
+}
+
+// CatalogLabelsMetadataFilter exposes selected labels of the service offerings and plans of a broker
+// as metadata fields in the catalog served on the OSB API
+type CatalogLabelsMetadataFilter struct {
+	Repository storage.Repository
+	Mappings   map[string]string
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*CatalogLabelsMetadataFilter) Name() string {
+	return CatalogLabelsMetadataFilterName
+}
+
+func (f *CatalogLabelsMetadataFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	response, err := next.Handle(req)
+	if err != nil || response.StatusCode != http.StatusOK || len(f.Mappings) == 0 {
+		return response, err
+	}
+
+	ctx := req.Context()
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(ctx, brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(ctx, brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	offeringsByCatalogID := make(map[string]*types.ServiceOffering, len(offerings.ServiceOfferings))
+	for _, offering := range offerings.ServiceOfferings {
+		offeringsByCatalogID[offering.CatalogID] = offering
+	}
+	isOffering := knownOffering(offeringsByCatalogID)
+
+	body := response.Body
+	for i, service := range gjson.GetBytes(body, "services").Array() {
+		offering, found := offeringsByCatalogID[namespace.Remove(service.Get("id").String(), isOffering)]
+		if !found {
+			log.C(ctx).Debugf("Service with catalog id %s of broker %s not found, skipping labels propagation", service.Get("id").String(), brokerID)
+			continue
+		}
+		if body, err = f.setMetadata(body, fmt.Sprintf("services.%d.metadata", i), offering.Labels); err != nil {
+			return nil, err
+		}
+
+		plansByCatalogID := make(map[string]*types.ServicePlan, len(offering.Plans))
+		for _, plan := range offering.Plans {
+			plansByCatalogID[plan.CatalogID] = plan
+		}
+		for j, catalogPlan := range service.Get("plans").Array() {
+			plan, found := plansByCatalogID[namespace.Remove(catalogPlan.Get("id").String(), knownPlan(plansByCatalogID))]
+			if !found {
+				continue
+			}
+			if body, err = f.setMetadata(body, fmt.Sprintf("services.%d.plans.%d.metadata", i, j), plan.Labels); err != nil {
+				return nil, err
+			}
+		}
+	}
+	response.Body = body
+
+	return response, nil
+}
+
+func (f *CatalogLabelsMetadataFilter) setMetadata(body []byte, metadataPath string, labels types.Labels) ([]byte, error) {
+	var err error
+	for label, field := range f.Mappings {
+		values, found := labels[label]
+		if !found {
+			continue
+		}
+		if body, err = sjson.SetBytes(body, metadataPath+"."+pathEscaper.Replace(field), values); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func (*CatalogLabelsMetadataFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/catalog"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
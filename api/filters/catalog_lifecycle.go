@@ -0,0 +1,261 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	CatalogLifecycleFilterName      = "CatalogLifecycleFilter"
+	RetiredPlansProvisionFilterName = "RetiredPlansProvisionFilter"
+
+	// CatalogLifecyclePolicyFlag flags deprecated and retired offerings and plans in the OSB catalog
+	CatalogLifecyclePolicyFlag = "flag"
+
+	// CatalogLifecyclePolicyHide flags deprecated offerings and plans and removes retired ones from the OSB catalog
+	CatalogLifecyclePolicyHide = "hide"
+
+	lifecycleMetadataField = "lifecycle"
+)
+
+// ValidateCatalogLifecyclePolicy verifies that the policy is one of the known catalog lifecycle policies
+func ValidateCatalogLifecyclePolicy(policy string) error {
+	if policy != CatalogLifecyclePolicyFlag && policy != CatalogLifecyclePolicyHide {
+		return fmt.Errorf("catalog lifecycle policy must be %s or %s but was %q", CatalogLifecyclePolicyFlag, CatalogLifecyclePolicyHide, policy)
+	}
+	return nil
+}
+
+// CatalogLifecycleFilter applies the lifecycle of the service offerings and plans of a broker to the catalog served
+// on the OSB API according to the configured policy. Deprecated and retired offerings and plans are flagged with
+// a lifecycle metadata field, or retired ones are removed.
+type CatalogLifecycleFilter struct {
+	Repository storage.Repository
+	Policy     string
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*CatalogLifecycleFilter) Name() string {
+	return CatalogLifecycleFilterName
+}
+
+func (f *CatalogLifecycleFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	response, err := next.Handle(req)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(req.Context(), brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(req.Context(), brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	offeringsByCatalogID, plansByCatalogID := catalogIndex(offerings)
+	isOffering := knownOffering(offeringsByCatalogID)
+	isPlan := knownPlan(plansByCatalogID)
+
+	body := response.Body
+	services := gjson.GetBytes(body, "services").Array()
+	// elements are removed from the last to the first, so the paths of the remaining ones stay valid
+	for i := len(services) - 1; i >= 0; i-- {
+		offering, found := offeringsByCatalogID[namespace.Remove(services[i].Get("id").String(), isOffering)]
+		if !found {
+			continue
+		}
+		servicePath := fmt.Sprintf("services.%d", i)
+		if body, err = f.apply(body, servicePath, offering.Lifecycle); err != nil {
+			return nil, err
+		}
+		if f.Policy == CatalogLifecyclePolicyHide && offering.Lifecycle == types.LifecycleRetired {
+			continue
+		}
+
+		plans := services[i].Get("plans").Array()
+		for j := len(plans) - 1; j >= 0; j-- {
+			plan, found := plansByCatalogID[namespace.Remove(plans[j].Get("id").String(), isPlan)]
+			if !found {
+				continue
+			}
+			if body, err = f.apply(body, fmt.Sprintf("%s.plans.%d", servicePath, j), plan.Lifecycle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	response.Body = body
+
+	return response, nil
+}
+
+func (f *CatalogLifecycleFilter) apply(body []byte, path string, lifecycle types.LifecycleState) ([]byte, error) {
+	switch {
+	case lifecycle == types.LifecycleActive:
+		return body, nil
+	case lifecycle == types.LifecycleRetired && f.Policy == CatalogLifecyclePolicyHide:
+		return sjson.DeleteBytes(body, path)
+	default:
+		return sjson.SetBytes(body, path+".metadata."+lifecycleMetadataField, lifecycle)
+	}
+}
+
+func (*CatalogLifecycleFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/catalog"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
+
+// RetiredPlansProvisionFilter rejects the provisioning of service instances of retired offerings and plans
+type RetiredPlansProvisionFilter struct {
+	Repository storage.Repository
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*RetiredPlansProvisionFilter) Name() string {
+	return RetiredPlansProvisionFilterName
+}
+
+func (f *RetiredPlansProvisionFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	serviceID := gjson.GetBytes(body, "service_id").String()
+	planID := gjson.GetBytes(body, "plan_id").String()
+
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(req.Context(), brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(req.Context(), brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	offeringsByCatalogID, plansByCatalogID := catalogIndex(offerings)
+	isOffering := knownOffering(offeringsByCatalogID)
+	isPlan := knownPlan(plansByCatalogID)
+
+	if offering, found := offeringsByCatalogID[namespace.Remove(serviceID, isOffering)]; found && offering.Lifecycle == types.LifecycleRetired {
+		return nil, retiredError("service offering", offering.Name)
+	}
+	if plan, found := plansByCatalogID[namespace.Remove(planID, isPlan)]; found && plan.Lifecycle == types.LifecycleRetired {
+		return nil, retiredError("service plan", plan.Name)
+	}
+	return next.Handle(req)
+}
+
+func (*RetiredPlansProvisionFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/service_instances/*"),
+				web.Methods(http.MethodPut),
+			},
+		},
+	}
+}
+
+func retiredError(kind, name string) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("%s %s is retired and cannot be provisioned", kind, name),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
+
+// catalogNamespace returns the catalog namespace of the broker, which prefixes the catalog IDs in the OSB requests
+// and catalogs of the broker. Requests for composite brokers have no namespace. The broker is fetched with the
+// fetcher, which serves it from the broker cache, or from the repository if there is no fetcher.
+func catalogNamespace(ctx context.Context, brokerID string, fetcher osb.BrokerFetcherFunc, repository storage.Repository) (osb.CatalogNamespace, error) {
+	if fetcher != nil {
+		broker, err := fetcher(ctx, brokerID)
+		if httpErr, ok := err.(*util.HTTPError); ok && httpErr.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return osb.CatalogNamespaceOf(broker), nil
+	}
+	obj, err := repository.Get(ctx, types.ServiceBrokerType, brokerID)
+	if err == util.ErrNotFoundInStorage {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	broker, isBroker := obj.(*types.ServiceBroker)
+	if !isBroker {
+		return "", nil
+	}
+	return osb.CatalogNamespaceOf(broker), nil
+}
+
+// catalogIndex indexes the offerings and plans by the catalog IDs of the broker, without its catalog namespace
+func catalogIndex(offerings *types.ServiceOfferings) (map[string]*types.ServiceOffering, map[string]*types.ServicePlan) {
+	offeringsByCatalogID := make(map[string]*types.ServiceOffering, len(offerings.ServiceOfferings))
+	plansByCatalogID := make(map[string]*types.ServicePlan)
+	for _, offering := range offerings.ServiceOfferings {
+		offeringsByCatalogID[offering.CatalogID] = offering
+		for _, plan := range offering.Plans {
+			plansByCatalogID[plan.CatalogID] = plan
+		}
+	}
+	return offeringsByCatalogID, plansByCatalogID
+}
+
+// knownOffering returns whether a catalog ID is the ID of one of the indexed offerings
+func knownOffering(offeringsByCatalogID map[string]*types.ServiceOffering) func(string) bool {
+	return func(catalogID string) bool {
+		_, found := offeringsByCatalogID[catalogID]
+		return found
+	}
+}
+
+// knownPlan returns whether a catalog ID is the ID of one of the indexed plans
+func knownPlan(plansByCatalogID map[string]*types.ServicePlan) func(string) bool {
+	return func(catalogID string) bool {
+		_, found := plansByCatalogID[catalogID]
+		return found
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lifecycle filters", func() {
+	const catalogJSON = `{
+		"services": [
+			{
+				"id": "service-catalog-id",
+				"name": "service",
+				"plans": [
+					{"id": "active-plan-catalog-id", "name": "active"},
+					{"id": "deprecated-plan-catalog-id", "name": "deprecated"},
+					{"id": "retired-plan-catalog-id", "name": "retired"}
+				]
+			},
+			{
+				"id": "retired-service-catalog-id",
+				"name": "retired-service",
+				"plans": [{"id": "other-plan-catalog-id", "name": "other"}]
+			}
+		]
+	}`
+
+	var (
+		fakeRepository *storagefakes.FakeStorage
+		request        *web.Request
+		handler        web.Handler
+	)
+
+	BeforeEach(func() {
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.ServiceOfferingType {
+				return &types.ServiceOfferings{
+					ServiceOfferings: []*types.ServiceOffering{
+						{Base: types.Base{ID: "service-id"}, CatalogID: "service-catalog-id", Name: "service"},
+						{Base: types.Base{ID: "retired-service-id"}, CatalogID: "retired-service-catalog-id", Name: "retired-service", Lifecycle: types.LifecycleRetired},
+					},
+				}, nil
+			}
+			return &types.ServicePlans{
+				ServicePlans: []*types.ServicePlan{
+					{CatalogID: "active-plan-catalog-id", ServiceOfferingID: "service-id", Name: "active"},
+					{CatalogID: "deprecated-plan-catalog-id", ServiceOfferingID: "service-id", Name: "deprecated", Lifecycle: types.LifecycleDeprecated},
+					{CatalogID: "retired-plan-catalog-id", ServiceOfferingID: "service-id", Name: "retired", Lifecycle: types.LifecycleRetired},
+					{CatalogID: "other-plan-catalog-id", ServiceOfferingID: "retired-service-id", Name: "other"},
+				},
+			}, nil
+		}
+
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/osb/broker-id/v2/catalog", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{
+			Request:    httpRequest,
+			PathParams: map[string]string{osb.BrokerIDPathParam: "broker-id"},
+		}
+		handler = web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK, Body: []byte(catalogJSON)}, nil
+		})
+	})
+
+	Describe("Catalog lifecycle filter", func() {
+		It("flags deprecated and retired offerings and plans with the flag policy", func() {
+			filter := &CatalogLifecycleFilter{Repository: fakeRepository, Policy: CatalogLifecyclePolicyFlag}
+			response, err := filter.Run(request, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{
+				"services": [
+					{
+						"id": "service-catalog-id",
+						"name": "service",
+						"plans": [
+							{"id": "active-plan-catalog-id", "name": "active"},
+							{"id": "deprecated-plan-catalog-id", "name": "deprecated", "metadata": {"lifecycle": "deprecated"}},
+							{"id": "retired-plan-catalog-id", "name": "retired", "metadata": {"lifecycle": "retired"}}
+						]
+					},
+					{
+						"id": "retired-service-catalog-id",
+						"name": "retired-service",
+						"metadata": {"lifecycle": "retired"},
+						"plans": [{"id": "other-plan-catalog-id", "name": "other"}]
+					}
+				]
+			}`))
+		})
+
+		It("removes retired offerings and plans with the hide policy", func() {
+			filter := &CatalogLifecycleFilter{Repository: fakeRepository, Policy: CatalogLifecyclePolicyHide}
+			response, err := filter.Run(request, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{
+				"services": [
+					{
+						"id": "service-catalog-id",
+						"name": "service",
+						"plans": [
+							{"id": "active-plan-catalog-id", "name": "active"},
+							{"id": "deprecated-plan-catalog-id", "name": "deprecated", "metadata": {"lifecycle": "deprecated"}}
+						]
+					}
+				]
+			}`))
+		})
+
+		It("applies the lifecycle to the prefixed catalog IDs of brokers with a catalog namespace", func() {
+			fakeRepository.GetReturns(&types.ServiceBroker{
+				Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+			}, nil)
+			filter := &CatalogLifecycleFilter{Repository: fakeRepository, Policy: CatalogLifecyclePolicyHide}
+			response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusOK, Body: []byte(`{
+					"services": [
+						{"id": "acme-service-catalog-id", "plans": [{"id": "acme-retired-plan-catalog-id"}]},
+						{"id": "acme-retired-service-catalog-id", "plans": [{"id": "acme-other-plan-catalog-id"}]}
+					]
+				}`)}, nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{"services": [{"id": "acme-service-catalog-id", "plans": []}]}`))
+		})
+
+		It("fetches the catalog namespace with the broker fetcher", func() {
+			fetched := 0
+			filter := &CatalogLifecycleFilter{
+				Repository: fakeRepository,
+				Policy:     CatalogLifecyclePolicyHide,
+				BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+					fetched++
+					return &types.ServiceBroker{
+						Base: types.Base{ID: brokerID, Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+					}, nil
+				},
+			}
+			response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusOK, Body: []byte(`{
+					"services": [{"id": "acme-retired-service-catalog-id", "plans": []}]
+				}`)}, nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{"services": []}`))
+			Expect(fetched).To(Equal(1))
+			Expect(fakeRepository.GetCallCount()).To(Equal(0))
+		})
+
+		It("keeps catalog IDs which start with the namespace but are not prefixed", func() {
+			fakeRepository.GetReturns(&types.ServiceBroker{
+				Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"retired-service"}}},
+			}, nil)
+			filter := &CatalogLifecycleFilter{Repository: fakeRepository, Policy: CatalogLifecyclePolicyHide}
+			response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusOK, Body: []byte(`{
+					"services": [{"id": "retired-service-catalog-id", "plans": []}]
+				}`)}, nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{"services": []}`))
+		})
+
+		It("rejects unknown policies", func() {
+			Expect(ValidateCatalogLifecyclePolicy("ignore")).To(HaveOccurred())
+		})
+	})
+
+	Describe("Retired plans provision filter", func() {
+		var filter *RetiredPlansProvisionFilter
+
+		provision := func(serviceID, planID string) (*web.Response, error) {
+			request.Body = []byte(`{"service_id": "` + serviceID + `", "plan_id": "` + planID + `"}`)
+			return filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusCreated}, nil
+			}))
+		}
+
+		BeforeEach(func() {
+			filter = &RetiredPlansProvisionFilter{Repository: fakeRepository}
+		})
+
+		It("allows provisioning active and deprecated plans", func() {
+			response, err := provision("service-catalog-id", "deprecated-plan-catalog-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects provisioning retired plans", func() {
+			_, err := provision("service-catalog-id", "retired-plan-catalog-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects provisioning retired plans with prefixed catalog IDs", func() {
+			fakeRepository.GetReturns(&types.ServiceBroker{
+				Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+			}, nil)
+			_, err := provision("acme-service-catalog-id", "acme-retired-plan-catalog-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+
+			_, err = provision("acme-retired-service-catalog-id", "acme-other-plan-catalog-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects provisioning plans of retired offerings", func() {
+			_, err := provision("retired-service-catalog-id", "other-plan-catalog-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const CatalogPricingMetadataFilterName = "CatalogPricingMetadataFilter"
+
+// PlanPricing is the pricing of a plan exposed in the catalog served on the OSB API
+type PlanPricing struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Unit     string  `json:"unit"`
+}
+
+// CatalogPricingMetadataFilter exposes the pricing of the service plans of a broker as a metadata field of the plans
+// in the catalog served on the OSB API
+type CatalogPricingMetadataFilter struct {
+	Repository storage.Repository
+	Field      string
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*CatalogPricingMetadataFilter) Name() string {
+	return CatalogPricingMetadataFilterName
+}
+
+func (f *CatalogPricingMetadataFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	response, err := next.Handle(req)
+	if err != nil || response.StatusCode != http.StatusOK || f.Field == "" {
+		return response, err
+	}
+
+	ctx := req.Context()
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(ctx, brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(ctx, brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	plansByCatalogID := make(map[string]*types.ServicePlan)
+	for _, offering := range offerings.ServiceOfferings {
+		for _, plan := range offering.Plans {
+			plansByCatalogID[plan.CatalogID] = plan
+		}
+	}
+	isPlan := knownPlan(plansByCatalogID)
+
+	body := response.Body
+	for i, service := range gjson.GetBytes(body, "services").Array() {
+		for j, catalogPlan := range service.Get("plans").Array() {
+			plan, found := plansByCatalogID[namespace.Remove(catalogPlan.Get("id").String(), isPlan)]
+			if !found || plan.PriceAmount == nil {
+				continue
+			}
+			pricing := &PlanPricing{
+				Amount:   *plan.PriceAmount,
+				Currency: plan.PriceCurrency,
+				Unit:     plan.PriceUnit,
+			}
+			metadataPath := fmt.Sprintf("services.%d.plans.%d.metadata.%s", i, j, pathEscaper.Replace(f.Field))
+			if body, err = sjson.SetBytes(body, metadataPath, pricing); err != nil {
+				return nil, err
+			}
+		}
+	}
+	response.Body = body
+
+	return response, nil
+}
+
+func (*CatalogPricingMetadataFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/catalog"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog pricing metadata filter", func() {
+	const catalogJSON = `{
+		"services": [{
+			"id": "service-catalog-id",
+			"name": "service",
+			"plans": [
+				{"id": "paid-plan-catalog-id", "name": "paid", "metadata": {"costs": [{"amount": {"eur": 10.5}, "unit": "MONTHLY"}]}},
+				{"id": "free-plan-catalog-id", "name": "free"}
+			]
+		}]
+	}`
+
+	var (
+		fakeRepository *storagefakes.FakeStorage
+		filter         *CatalogPricingMetadataFilter
+		request        *web.Request
+		handler        web.Handler
+	)
+
+	BeforeEach(func() {
+		amount := 10.5
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.ServiceOfferingType {
+				return &types.ServiceOfferings{
+					ServiceOfferings: []*types.ServiceOffering{
+						{Base: types.Base{ID: "service-id"}, CatalogID: "service-catalog-id"},
+					},
+				}, nil
+			}
+			return &types.ServicePlans{
+				ServicePlans: []*types.ServicePlan{
+					{
+						Base:              types.Base{ID: "paid-plan-id"},
+						CatalogID:         "paid-plan-catalog-id",
+						ServiceOfferingID: "service-id",
+						PriceAmount:       &amount,
+						PriceCurrency:     "EUR",
+						PriceUnit:         "MONTHLY",
+					},
+					{
+						Base:              types.Base{ID: "free-plan-id"},
+						CatalogID:         "free-plan-catalog-id",
+						ServiceOfferingID: "service-id",
+					},
+				},
+			}, nil
+		}
+
+		filter = &CatalogPricingMetadataFilter{
+			Repository: fakeRepository,
+			Field:      "pricing",
+		}
+
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/osb/broker-id/v2/catalog", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{
+			Request:    httpRequest,
+			PathParams: map[string]string{osb.BrokerIDPathParam: "broker-id"},
+		}
+		handler = web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK, Body: []byte(catalogJSON)}, nil
+		})
+	})
+
+	It("exposes the pricing of the plans as a metadata field", func() {
+		response, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(response.Body)).To(MatchJSON(`{
+			"services": [{
+				"id": "service-catalog-id",
+				"name": "service",
+				"plans": [
+					{
+						"id": "paid-plan-catalog-id",
+						"name": "paid",
+						"metadata": {
+							"costs": [{"amount": {"eur": 10.5}, "unit": "MONTHLY"}],
+							"pricing": {"amount": 10.5, "currency": "EUR", "unit": "MONTHLY"}
+						}
+					},
+					{"id": "free-plan-catalog-id", "name": "free"}
+				]
+			}]
+		}`))
+	})
+
+	Context("when the broker has a catalog namespace", func() {
+		It("exposes the pricing of the plans with prefixed catalog IDs", func() {
+			fakeRepository.GetReturns(&types.ServiceBroker{
+				Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+			}, nil)
+			response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusOK, Body: []byte(`{
+					"services": [{"id": "acme-service-catalog-id", "plans": [{"id": "acme-paid-plan-catalog-id"}]}]
+				}`)}, nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(MatchJSON(`{
+				"services": [{
+					"id": "acme-service-catalog-id",
+					"plans": [{
+						"id": "acme-paid-plan-catalog-id",
+						"metadata": {"pricing": {"amount": 10.5, "currency": "EUR", "unit": "MONTHLY"}}
+					}]
+				}]
+			}`))
+		})
+	})
+
+	Context("when the catalog response is not successful", func() {
+		It("returns the response unchanged", func() {
+			response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+				return &web.Response{StatusCode: http.StatusBadGateway, Body: []byte(`{}`)}, nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(response.Body)).To(Equal(`{}`))
+			Expect(fakeRepository.ListCallCount()).To(Equal(0))
+		})
+	})
+})
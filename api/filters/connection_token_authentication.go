@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/security/filters"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/ws"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const ConnectionTokenAuthnFilterName string = "ConnectionTokenAuthnFilter"
+
+// NewConnectionTokenAuthnFilter returns a filter which authenticates platforms opening notification connections
+// by the connection tokens issued to them
+func NewConnectionTokenAuthnFilter(repository storage.Repository, tokens *ws.ConnectionTokens) *filters.AuthenticationFilter {
+	return filters.NewAuthenticationFilter(&connectionTokenAuthenticator{
+		Repository: repository,
+		Tokens:     tokens,
+	}, ConnectionTokenAuthnFilterName, connectionTokenAuthnMatchers())
+}
+
+// connectionTokenAuthenticator authenticates the platform to which the bearer token was issued
+type connectionTokenAuthenticator struct {
+	Repository storage.Repository
+	Tokens     *ws.ConnectionTokens
+}
+
+// Authenticate authenticates by using the provided connection token
+func (a *connectionTokenAuthenticator) Authenticate(request *http.Request) (*web.UserContext, httpsec.Decision, error) {
+	authorizationHeader := request.Header.Get("Authorization")
+	if !strings.HasPrefix(strings.ToLower(authorizationHeader), "bearer ") {
+		return nil, httpsec.Abstain, nil
+	}
+
+	claims, err := a.Tokens.Verify(strings.TrimSpace(authorizationHeader[len("Bearer "):]))
+	if err != nil {
+		return nil, httpsec.Deny, err
+	}
+
+	obj, err := a.Repository.Get(request.Context(), types.PlatformType, claims.PlatformID)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, httpsec.Deny, fmt.Errorf("platform %s of the connection token no longer exists", claims.PlatformID)
+		}
+		return nil, httpsec.Abstain, fmt.Errorf("could not get platform from storage: %s", err)
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
+	name := claims.PlatformID
+	if credentials := obj.(*types.Platform).Credentials; credentials != nil && credentials.Basic != nil {
+		name = credentials.Basic.Username
+	}
+	return &web.UserContext{
+		Data: &basicAuthnData{
+			data: bytes,
+		},
+		Name: name,
+	}, httpsec.Allow, nil
+}
+
+func connectionTokenAuthnMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Methods(http.MethodGet),
+				web.Path(web.NotificationsURL),
+			},
+		},
+	}
+}
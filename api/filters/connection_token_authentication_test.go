@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/ws"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Token Authenticator", func() {
+	var (
+		request        *http.Request
+		fakeRepository *storagefakes.FakeStorage
+		tokens         *ws.ConnectionTokens
+		authenticator  *connectionTokenAuthenticator
+	)
+
+	BeforeEach(func() {
+		settings := ws.DefaultSettings()
+		settings.TokenSigningKey = "signing-key"
+		tokens = ws.NewConnectionTokens(settings)
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.GetReturns(&types.Platform{
+			Base: types.Base{ID: "platform-id"},
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "username", Password: "password"},
+			},
+		}, nil)
+		authenticator = &connectionTokenAuthenticator{
+			Repository: fakeRepository,
+			Tokens:     tokens,
+		}
+
+		var err error
+		request, err = http.NewRequest(http.MethodGet, "https://example.com/v1/notifications", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("abstains without a bearer token", func() {
+		request.SetBasicAuth("username", "password")
+		user, decision, err := authenticator.Authenticate(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	It("denies invalid tokens", func() {
+		request.Header.Set("Authorization", "Bearer invalid.token")
+		user, decision, err := authenticator.Authenticate(request)
+		Expect(err).To(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("denies tokens of deleted platforms", func() {
+		token, err := tokens.Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+		fakeRepository.GetReturns(nil, util.ErrNotFoundInStorage)
+
+		request.Header.Set("Authorization", "Bearer "+token.Token)
+		_, decision, err := authenticator.Authenticate(request)
+		Expect(err).To(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("authenticates the platform of the token", func() {
+		token, err := tokens.Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		request.Header.Set("Authorization", "Bearer "+token.Token)
+		user, decision, err := authenticator.Authenticate(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Allow))
+		Expect(user.Name).To(Equal("username"))
+
+		platform := &types.Platform{}
+		Expect(user.Data.Data(platform)).To(Succeed())
+		Expect(platform.ID).To(Equal("platform-id"))
+		_, _, id := fakeRepository.GetArgsForCall(0)
+		Expect(id).To(Equal("platform-id"))
+	})
+})
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/gjson"
+)
+
+// CredentialsPolicyFilterName is the name of the filter which enforces the policy for the credentials of brokers
+const CredentialsPolicyFilterName = "CredentialsPolicyFilter"
+
+// WeakCredentialsErrorType is the error type of the responses rejecting broker credentials which violate the policy
+const WeakCredentialsErrorType = "WeakCredentials"
+
+// CredentialsPolicySettings type to be loaded from the environment
+type CredentialsPolicySettings struct {
+	MinPasswordLength        int      `mapstructure:"min_password_length" description:"minimum number of characters of the passwords of brokers, 0 for no limit"`
+	RejectedPasswords        []string `mapstructure:"rejected_passwords" description:"passwords of brokers which are rejected regardless of their case, e.g. the default passwords of broker frameworks"`
+	RejectUsernameAsPassword bool     `mapstructure:"reject_username_as_password" description:"whether to reject broker passwords which equal the username"`
+}
+
+// DefaultCredentialsPolicySettings returns default values for the credentials policy, which accepts any non-empty
+// password
+func DefaultCredentialsPolicySettings() *CredentialsPolicySettings {
+	return &CredentialsPolicySettings{
+		MinPasswordLength:        0,
+		RejectedPasswords:        []string{},
+		RejectUsernameAsPassword: false,
+	}
+}
+
+// Validate validates the credentials policy settings
+func (s *CredentialsPolicySettings) Validate() error {
+	if s.MinPasswordLength < 0 {
+		return fmt.Errorf("validate Settings: credentials policy min password length must be >= 0")
+	}
+	return nil
+}
+
+// CredentialsViolation describes why credentials violate the policy
+type CredentialsViolation struct {
+	// Rule is the name of the violated rule, e.g. min_password_length or the name of a check
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+}
+
+// CredentialsCheck is an additional rule of the credentials policy, e.g. one which looks the passwords up in a
+// database of breached passwords or asks an external validation service
+type CredentialsCheck interface {
+	web.Named
+
+	// CheckCredentials returns the violations of the check, none if the credentials are acceptable. An error fails
+	// the request instead of rejecting the credentials.
+	CheckCredentials(ctx context.Context, credentials *types.Basic) ([]CredentialsViolation, error)
+}
+
+// CredentialsPolicy checks the credentials supplied on broker registration and update against the configured rules
+// and the registered checks
+type CredentialsPolicy struct {
+	settings *CredentialsPolicySettings
+	checks   []CredentialsCheck
+}
+
+// NewCredentialsPolicy returns a policy of the validated settings, only the registered checks apply if they are nil
+func NewCredentialsPolicy(settings *CredentialsPolicySettings) *CredentialsPolicy {
+	if settings == nil {
+		settings = &CredentialsPolicySettings{}
+	}
+	return &CredentialsPolicy{settings: settings}
+}
+
+// Register adds checks to the policy. Checks should be registered before the policy is used.
+func (p *CredentialsPolicy) Register(checks ...CredentialsCheck) {
+	p.checks = append(p.checks, checks...)
+}
+
+// Check returns all violations of the credentials, the username is empty if only the password is changed
+func (p *CredentialsPolicy) Check(ctx context.Context, credentials *types.Basic) ([]CredentialsViolation, error) {
+	var violations []CredentialsViolation
+	if credentials.Password == "" {
+		violations = append(violations, CredentialsViolation{Rule: "empty_password", Description: "password must not be empty"})
+	}
+	if p.settings.MinPasswordLength > 0 && utf8.RuneCountInString(credentials.Password) < p.settings.MinPasswordLength {
+		violations = append(violations, CredentialsViolation{
+			Rule:        "min_password_length",
+			Description: fmt.Sprintf("password must have at least %d characters", p.settings.MinPasswordLength),
+		})
+	}
+	for _, rejected := range p.settings.RejectedPasswords {
+		if strings.EqualFold(credentials.Password, rejected) {
+			violations = append(violations, CredentialsViolation{Rule: "rejected_passwords", Description: "password is a well-known default password"})
+			break
+		}
+	}
+	if p.settings.RejectUsernameAsPassword && credentials.Username != "" && credentials.Username == credentials.Password {
+		violations = append(violations, CredentialsViolation{Rule: "reject_username_as_password", Description: "password must differ from the username"})
+	}
+	for _, check := range p.checks {
+		checkViolations, err := check.CheckCredentials(ctx, credentials)
+		if err != nil {
+			return nil, fmt.Errorf("credentials check %s failed: %s", check.Name(), err)
+		}
+		violations = append(violations, checkViolations...)
+	}
+	return violations, nil
+}
+
+// CredentialsPolicyFilter rejects the registration and the update of brokers whose basic credentials violate the
+// credentials policy. All violations are returned as details of the error.
+type CredentialsPolicyFilter struct {
+	policy *CredentialsPolicy
+}
+
+// NewCredentialsPolicyFilter returns a filter enforcing the policy
+func NewCredentialsPolicyFilter(policy *CredentialsPolicy) *CredentialsPolicyFilter {
+	return &CredentialsPolicyFilter{policy: policy}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*CredentialsPolicyFilter) Name() string {
+	return CredentialsPolicyFilterName
+}
+
+// Run checks the basic credentials in the request body. Updates which do not change the credentials are not checked.
+func (f *CredentialsPolicyFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	basic := gjson.GetBytes(body, "credentials.basic")
+	if !basic.Exists() {
+		return next.Handle(req)
+	}
+	credentials := &types.Basic{
+		Username: basic.Get("username").String(),
+		Password: basic.Get("password").String(),
+	}
+	violations, err := f.policy.Check(req.Context(), credentials)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) != 0 {
+		descriptions := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			descriptions = append(descriptions, violation.Description)
+		}
+		return nil, &util.HTTPError{
+			ErrorType:   WeakCredentialsErrorType,
+			Description: fmt.Sprintf("broker credentials violate the credentials policy: %s", strings.Join(descriptions, "; ")),
+			StatusCode:  http.StatusBadRequest,
+			Details:     violations,
+		}
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*CredentialsPolicyFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBrokersURL),
+				web.Methods(http.MethodPost),
+			},
+		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBrokersURL + "/*"),
+				web.Methods(http.MethodPatch, http.MethodPut),
+			},
+		},
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type funcCredentialsCheck func(credentials *types.Basic) ([]CredentialsViolation, error)
+
+func (funcCredentialsCheck) Name() string {
+	return "func"
+}
+
+func (f funcCredentialsCheck) CheckCredentials(ctx context.Context, credentials *types.Basic) ([]CredentialsViolation, error) {
+	return f(credentials)
+}
+
+var _ = Describe("Credentials policy filter", func() {
+	var (
+		policy    *CredentialsPolicy
+		forwarded bool
+	)
+
+	run := func(method, url, body string) error {
+		httpRequest, err := http.NewRequest(method, "https://example.com"+url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		forwarded = false
+		_, err = NewCredentialsPolicyFilter(policy).Run(&web.Request{Request: httpRequest, Body: []byte(body)}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			forwarded = true
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		return err
+	}
+
+	expectViolations := func(err error, rules ...string) {
+		Expect(err).To(HaveOccurred())
+		httpErr := err.(*util.HTTPError)
+		Expect(httpErr.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(httpErr.ErrorType).To(Equal(WeakCredentialsErrorType))
+		violated := make([]string, 0)
+		for _, violation := range httpErr.Details.([]CredentialsViolation) {
+			violated = append(violated, violation.Rule)
+		}
+		Expect(violated).To(ConsistOf(rules))
+		Expect(forwarded).To(BeFalse())
+	}
+
+	BeforeEach(func() {
+		policy = NewCredentialsPolicy(&CredentialsPolicySettings{
+			MinPasswordLength:        12,
+			RejectedPasswords:        []string{"changeme"},
+			RejectUsernameAsPassword: true,
+		})
+	})
+
+	It("forwards strong credentials", func() {
+		Expect(run(http.MethodPost, web.ServiceBrokersURL, `{"credentials": {"basic": {"username": "admin", "password": "a-long-random-password"}}}`)).To(Succeed())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	It("forwards updates which do not change the credentials", func() {
+		Expect(run(http.MethodPatch, web.ServiceBrokersURL+"/id", `{"name": "broker"}`)).To(Succeed())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	It("rejects empty passwords", func() {
+		expectViolations(run(http.MethodPatch, web.ServiceBrokersURL+"/id", `{"credentials": {"basic": {"password": ""}}}`), "empty_password", "min_password_length")
+	})
+
+	It("returns all violations", func() {
+		expectViolations(run(http.MethodPost, web.ServiceBrokersURL, `{"credentials": {"basic": {"username": "CHANGEME", "password": "CHANGEME"}}}`),
+			"min_password_length", "rejected_passwords", "reject_username_as_password")
+	})
+
+	It("accepts any non-empty password by default", func() {
+		policy = NewCredentialsPolicy(DefaultCredentialsPolicySettings())
+		Expect(run(http.MethodPut, web.ServiceBrokersURL+"/id", `{"credentials": {"basic": {"username": "admin", "password": "admin"}}}`)).To(Succeed())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	Context("with registered checks", func() {
+		It("rejects the credentials violating a check", func() {
+			policy.Register(funcCredentialsCheck(func(credentials *types.Basic) ([]CredentialsViolation, error) {
+				return []CredentialsViolation{{Rule: "breached", Description: "password was breached"}}, nil
+			}))
+			expectViolations(run(http.MethodPost, web.ServiceBrokersURL, `{"credentials": {"basic": {"username": "admin", "password": "a-long-random-password"}}}`), "breached")
+		})
+
+		It("fails the request if a check fails", func() {
+			policy.Register(funcCredentialsCheck(func(credentials *types.Basic) ([]CredentialsViolation, error) {
+				return nil, fmt.Errorf("validation service unavailable")
+			}))
+			err := run(http.MethodPost, web.ServiceBrokersURL, `{"credentials": {"basic": {"username": "admin", "password": "a-long-random-password"}}}`)
+			Expect(err).To(HaveOccurred())
+			Expect(err).ToNot(BeAssignableToTypeOf(&util.HTTPError{}))
+			Expect(forwarded).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// CriteriaDebugFilterName is the name of the criteria debug filter
+	CriteriaDebugFilterName = "CriteriaDebugFilter"
+
+	// DebugHeader is the request header with which clients enable debug information in the response
+	DebugHeader = "X-SM-Debug"
+	// DebugCriteria is the value of the debug header requesting the effective criteria of the request
+	DebugCriteria = "criteria"
+	// DebugCriteriaHeader is the response header holding the effective criteria of the request separated by semicolons
+	DebugCriteriaHeader = "X-SM-Debug-Criteria"
+)
+
+// CriteriaDebugFilter returns the criteria effectively used to query the resources of a request, i.e. the selection
+// criteria of the request merged with the criteria added by filters and controllers, to users allowed to debug them.
+type CriteriaDebugFilter struct {
+	// Scope is the scope of the users allowed to request the criteria
+	Scope string
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*CriteriaDebugFilter) Name() string {
+	return CriteriaDebugFilterName
+}
+
+// Run records the criteria of requests with the debug header and returns them in the debug criteria header
+func (f *CriteriaDebugFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if !strings.EqualFold(req.Header.Get(DebugHeader), DebugCriteria) {
+		return next.Handle(req)
+	}
+	ctx := req.Context()
+	if !f.allowed(req) {
+		log.C(ctx).Debugf("Ignoring %s header of user not allowed to debug criteria", DebugHeader)
+		return next.Handle(req)
+	}
+
+	ctx, recorder := query.ContextWithCriteriaRecorder(ctx)
+	req.Request = req.WithContext(ctx)
+	resp, err := next.Handle(req)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria := recorder.Criteria()
+	formatted := make([]string, 0, len(criteria))
+	for _, criterion := range criteria {
+		formatted = append(formatted, criterion.String())
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	resp.Header.Set(DebugCriteriaHeader, strings.Join(formatted, "; "))
+	return resp, nil
+}
+
+func (f *CriteriaDebugFilter) allowed(req *web.Request) bool {
+	user, found := req.Metadata().User()
+	if !found || f.Scope == "" {
+		return false
+	}
+	return user.Scopes()[f.Scope]
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*CriteriaDebugFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+				web.Methods(http.MethodGet, http.MethodDelete, http.MethodPatch),
+			},
+		},
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Criteria debug filter", func() {
+	var (
+		filter  *CriteriaDebugFilter
+		request *web.Request
+	)
+
+	// the handler stands in for the filters and controllers adding criteria after the debug filter
+	handler := web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+		ctx, err := query.AddCriteria(req.Context(), query.ByLabel(query.InOperator, "tenant", "a", "b"))
+		Expect(err).ToNot(HaveOccurred())
+		ctx, err = query.AddCriteria(ctx, query.ByField(query.EqualsOperator, "name", "broker"))
+		Expect(err).ToNot(HaveOccurred())
+		req.Request = req.WithContext(ctx)
+		return &web.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	BeforeEach(func() {
+		filter = &CriteriaDebugFilter{Scope: "sm.debug"}
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/service_brokers", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{Request: httpRequest}
+		request.Header.Set(DebugHeader, DebugCriteria)
+	})
+
+	authenticateAs := func(name, claims string) {
+		request.SetMetadata(request.Metadata().WithUser(&web.UserContext{
+			Name: name,
+			Data: &basicAuthnData{data: []byte(claims)},
+		}))
+	}
+
+	It("returns the merged criteria to allowed users", func() {
+		authenticateAs("admin", `{"scope":["sm.debug"]}`)
+		resp, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header.Get(DebugCriteriaHeader)).To(Equal("labelQuery tenant in [a,b]; fieldQuery name = broker"))
+	})
+
+	It("includes the criteria added before it", func() {
+		authenticateAs("admin", `{"scope":["sm.debug"]}`)
+		ctx, err := query.AddCriteria(request.Context(), query.ByField(query.EqualsOperator, "id", "1"))
+		Expect(err).ToNot(HaveOccurred())
+		request.Request = request.WithContext(ctx)
+
+		resp, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header.Get(DebugCriteriaHeader)).To(HavePrefix("fieldQuery id = 1; "))
+	})
+
+	It("does not return the criteria to users without the scope", func() {
+		authenticateAs("admin", `{"scope":["sm.read"]}`)
+		resp, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header).ToNot(HaveKey(DebugCriteriaHeader))
+	})
+
+	It("does not return the criteria to unauthenticated users", func() {
+		resp, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header).ToNot(HaveKey(DebugCriteriaHeader))
+	})
+
+	It("does not return the criteria without the debug header", func() {
+		authenticateAs("admin", `{"scope":["sm.debug"]}`)
+		request.Header.Del(DebugHeader)
+		resp, err := filter.Run(request, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header).ToNot(HaveKey(DebugCriteriaHeader))
+	})
+})
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// CSRFFilterName is the name of the filter which protects browser sessions against cross-site request forgery
+const CSRFFilterName = "CSRFFilter"
+
+// CSRFOptions configures the CSRF filter
+type CSRFOptions struct {
+	// CookieName is the name of the cookie holding the token
+	CookieName string
+	// HeaderName is the name of the header in which browsers submit the token
+	HeaderName string
+	// ExemptPaths are the path patterns of the routes which are not protected, e.g. the ones used by platforms
+	ExemptPaths []string
+}
+
+// DefaultCSRFOptions returns the default CSRF options, which exempt the OSB API and the notifications of platforms
+func DefaultCSRFOptions() *CSRFOptions {
+	return &CSRFOptions{
+		CookieName: "sm_csrf_token",
+		HeaderName: "X-CSRF-Token",
+		ExemptPaths: []string{
+			web.OSBURL + "/**",
+			web.NotificationsURL + "/**",
+		},
+	}
+}
+
+// CSRFFilter protects browser based access, e.g. by the dashboard of an extension authenticating with cookies, against
+// cross-site request forgery with double-submit tokens. It does not keep sessions: the token is issued as a cookie
+// on safe requests and changing requests carrying cookies must submit the same token in the header, which other sites
+// cannot read. Requests without cookies are not subject to CSRF and are passed through. The filter is not registered
+// by default, extensions register it with the filter registration API, e.g. before the authentication filters.
+type CSRFFilter struct {
+	options *CSRFOptions
+}
+
+// NewCSRFFilter returns a CSRF filter with the options, the default options are used if they are nil
+func NewCSRFFilter(options *CSRFOptions) *CSRFFilter {
+	if options == nil {
+		options = DefaultCSRFOptions()
+	}
+	return &CSRFFilter{options: options}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*CSRFFilter) Name() string {
+	return CSRFFilterName
+}
+
+// Run verifies the token of changing requests and issues the token on safe requests without one
+func (f *CSRFFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	// A missing cookie is reported as an error and results in a nil cookie
+	cookie, _ := req.Cookie(f.options.CookieName)
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		resp, err := next.Handle(req)
+		if err != nil || cookie != nil {
+			return resp, err
+		}
+		token, err := newCSRFToken()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		resp.Header.Add("Set-Cookie", (&http.Cookie{
+			Name:     f.options.CookieName,
+			Value:    token,
+			Path:     "/",
+			Secure:   req.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		}).String())
+		return resp, nil
+	}
+
+	if req.Header.Get("Cookie") == "" {
+		return next.Handle(req)
+	}
+	submitted := req.Header.Get(f.options.HeaderName)
+	if cookie == nil || submitted == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(submitted)) != 1 {
+		return nil, &util.HTTPError{
+			ErrorType:   "Forbidden",
+			Description: "missing or invalid CSRF token",
+			StatusCode:  http.StatusForbidden,
+		}
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+// All routes except the exempt ones are protected.
+func (f *CSRFFilter) FilterMatchers() []web.FilterMatcher {
+	exempt := web.Path(f.options.ExemptPaths...)
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.MatcherFunc(func(endpoint web.Endpoint) (bool, error) {
+					if len(f.options.ExemptPaths) == 0 {
+						return true, nil
+					}
+					matches, err := exempt.Matches(endpoint)
+					return !matches, err
+				}),
+			},
+		},
+	}
+}
+
+func newCSRFToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSRF filter", func() {
+	var (
+		filter    *CSRFFilter
+		forwarded bool
+	)
+
+	run := func(method string, headers map[string]string) (*web.Response, error) {
+		httpRequest, err := http.NewRequest(method, "https://example.com"+web.ServiceBrokersURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		for name, value := range headers {
+			httpRequest.Header.Set(name, value)
+		}
+		forwarded = false
+		return filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			forwarded = true
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+	}
+
+	BeforeEach(func() {
+		filter = NewCSRFFilter(nil)
+	})
+
+	It("issues a token on safe requests without one", func() {
+		resp, err := run(http.MethodGet, nil)
+		Expect(err).ToNot(HaveOccurred())
+		cookie := resp.Header.Get("Set-Cookie")
+		Expect(cookie).To(HavePrefix("sm_csrf_token="))
+		Expect(cookie).To(ContainSubstring("SameSite=Strict"))
+
+		resp, err = run(http.MethodGet, map[string]string{"Cookie": strings.Split(cookie, ";")[0]})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header.Get("Set-Cookie")).To(BeEmpty())
+	})
+
+	It("passes changing requests without cookies", func() {
+		_, err := run(http.MethodPost, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	It("passes changing requests submitting the token of the cookie", func() {
+		_, err := run(http.MethodPatch, map[string]string{"Cookie": "session=s; sm_csrf_token=token", "X-CSRF-Token": "token"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	It("rejects changing requests with cookies and a missing or different token", func() {
+		for _, headers := range []map[string]string{
+			{"Cookie": "session=s"},
+			{"Cookie": "session=s; sm_csrf_token=token"},
+			{"Cookie": "session=s; sm_csrf_token=token", "X-CSRF-Token": "other"},
+		} {
+			_, err := run(http.MethodDelete, headers)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+			Expect(forwarded).To(BeFalse())
+		}
+	})
+
+	It("does not match the exempt routes", func() {
+		matches := func(path string) bool {
+			matched, err := filter.FilterMatchers()[0].Matchers[0].Matches(web.Endpoint{Method: http.MethodPost, Path: path})
+			Expect(err).ToNot(HaveOccurred())
+			return matched
+		}
+		Expect(matches(web.ServiceBrokersURL)).To(BeTrue())
+		Expect(matches(web.OSBURL + "/broker-id/v2/service_instances/instance-id")).To(BeFalse())
+		Expect(matches(web.NotificationsURL)).To(BeFalse())
+	})
+})
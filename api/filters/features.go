@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// FeaturesFilterName is the name of the features filter
+	FeaturesFilterName = "FeaturesFilter"
+)
+
+// Features is filter that makes the feature flags manager available in the request context so that
+// subsequent filters, controllers and storage interceptors can check whether a feature is enabled.
+type Features struct {
+	Manager *features.Manager
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*Features) Name() string {
+	return FeaturesFilterName
+}
+
+// Run represents the features middleware function that stores the feature flags manager in the request context.
+func (f *Features) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := features.ContextWithManager(req.Context(), f.Manager)
+	req.Request = req.WithContext(ctx)
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*Features) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
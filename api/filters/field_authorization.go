@@ -0,0 +1,300 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// FieldAuthorizationFilterName is the name of the filter which removes the fields the caller is not allowed to see
+const FieldAuthorizationFilterName = "FieldAuthorizationFilter"
+
+// CredentialsField is the field of brokers and platforms which is not serialized at all unless a field rule allows it
+const CredentialsField = "credentials"
+
+// ResourceURLs are the base URLs of the resources in the REST API, whose field rules apply to the resources of the type
+var ResourceURLs = map[types.ObjectType]string{
+	types.ServiceBrokerType:   web.ServiceBrokersURL,
+	types.ServiceOfferingType: web.ServiceOfferingsURL,
+	types.ServicePlanType:     web.ServicePlansURL,
+	types.VisibilityType:      web.VisibilitiesURL,
+	types.PlatformType:        web.PlatformsURL,
+}
+
+// FieldRule restricts the serialization of a field of the resources under a path to the callers with a scope
+type FieldRule struct {
+	// Path is the base URL of the resources, e.g. /v1/service_brokers
+	Path string
+
+	// Field is the JSON path of the field. A path ending with * matches the keys of the parent object by prefix,
+	// e.g. labels.internal.* matches all labels starting with internal.
+	Field string
+
+	// Scope is the scope the caller needs to see the field
+	Scope string
+}
+
+// ParseFieldRules parses field rules in the form path:field=scope, e.g. /v1/service_brokers:credentials=sm.admin
+func ParseFieldRules(rules []string) ([]FieldRule, error) {
+	result := make([]FieldRule, 0, len(rules))
+	for _, rule := range rules {
+		separator := strings.Index(rule, ":")
+		assignment := strings.LastIndex(rule, "=")
+		if separator == -1 || assignment < separator {
+			return nil, fmt.Errorf("invalid field rule %q: expected the form path:field=scope", rule)
+		}
+		fieldRule := FieldRule{
+			Path:  strings.TrimSuffix(strings.TrimSpace(rule[:separator]), "/"),
+			Field: strings.TrimSpace(rule[separator+1 : assignment]),
+			Scope: strings.TrimSpace(rule[assignment+1:]),
+		}
+		if !strings.HasPrefix(fieldRule.Path, "/") || fieldRule.Field == "" || fieldRule.Scope == "" {
+			return nil, fmt.Errorf("invalid field rule %q: path, field and scope must not be empty", rule)
+		}
+		if strings.HasPrefix(fieldRule.Path, web.OSBURL) {
+			return nil, fmt.Errorf("invalid field rule %q: fields of the OSB API cannot be restricted", rule)
+		}
+		result = append(result, fieldRule)
+	}
+	return result, nil
+}
+
+// FieldAuthorizationFilter removes the fields of the returned resources which the caller is not allowed to see
+// according to the field rules. It applies to single resources, to the items of lists, to the changes of their
+// history, to the resources returned by reverts and to the related resources of a resource, e.g. the service plans a
+// platform can see, but not to the resources returned by their creation, which the caller just provided. The
+// credentials of brokers and platforms are only returned to the callers allowed to see them by a rule. Endpoints which
+// return the resources in another form, e.g. watches and GraphQL queries, apply the rules with Redact.
+type FieldAuthorizationFilter struct {
+	Rules []FieldRule
+}
+
+// Name implements the web.Named interface and returns the name of the filter
+func (*FieldAuthorizationFilter) Name() string {
+	return FieldAuthorizationFilterName
+}
+
+// Run implements the web.Middleware interface and removes the fields of the response the caller may not see
+func (f *FieldAuthorizationFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	denied, revealed := f.deniedRules(req.Context(), resourcePath(req.URL.Path))
+	if revealed {
+		req.SetMetadata(req.Metadata().WithCredentialsRevealed())
+	}
+
+	response, err := next.Handle(req)
+	if err != nil || len(denied) == 0 || response.StatusCode >= http.StatusMultipleChoices {
+		return response, err
+	}
+	if req.Method == http.MethodPost && response.StatusCode == http.StatusCreated && f.isCreation(req.URL.Path) {
+		return response, nil
+	}
+
+	body := response.Body
+	if history := gjson.GetBytes(body, "history"); history.IsArray() && strings.HasSuffix(req.URL.Path, "/history") {
+		for i := range history.Array() {
+			if body, err = removeChanges(body, fmt.Sprintf("history.%d.changes", i), denied); err != nil {
+				return nil, err
+			}
+		}
+	} else if items := gjson.GetBytes(body, "items"); items.IsArray() {
+		for i := range items.Array() {
+			if body, err = removeFields(body, fmt.Sprintf("items.%d.", i), denied); err != nil {
+				return nil, err
+			}
+		}
+	} else if body, err = removeFields(body, "", denied); err != nil {
+		return nil, err
+	}
+	response.Body = body
+	response.Header.Del("Content-Length")
+	return response, nil
+}
+
+// Redact removes the fields of the resource under the path which the caller of the context is not allowed to see.
+// Like in the responses of the API, the credentials are only kept if a rule allows the caller to see them, also if
+// there is no filter.
+func (f *FieldAuthorizationFilter) Redact(ctx context.Context, path string, object []byte) ([]byte, error) {
+	var denied []FieldRule
+	revealed := false
+	if f != nil {
+		denied, revealed = f.deniedRules(ctx, path)
+	}
+	if !revealed {
+		denied = append(denied, FieldRule{Path: path, Field: CredentialsField})
+	}
+	return removeFields(object, "", denied)
+}
+
+// deniedRules returns the rules of the path for which the caller of the context has no scope and whether the caller
+// may see the credentials
+func (f *FieldAuthorizationFilter) deniedRules(ctx context.Context, path string) ([]FieldRule, bool) {
+	scopes := callerScopes(ctx)
+	var denied []FieldRule
+	revealed := false
+	for _, rule := range f.Rules {
+		if path != rule.Path && !strings.HasPrefix(path, rule.Path+"/") {
+			continue
+		}
+		if !scopes[rule.Scope] {
+			denied = append(denied, rule)
+		} else if rule.Field == CredentialsField {
+			revealed = true
+		}
+	}
+	return denied, revealed
+}
+
+// resourcePath returns the path whose field rules apply to the resources returned under the path. The related
+// resources of a resource, e.g. the service plans under /v1/platforms/{id}/service_plans, are those of their own
+// base URL, e.g. /v1/service_plans.
+func resourcePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != 4 {
+		return path
+	}
+	related := "/" + segments[0] + "/" + segments[3]
+	for _, resourceURL := range ResourceURLs {
+		if resourceURL == related {
+			return related
+		}
+	}
+	return path
+}
+
+// isCreation returns true if the path is the base URL of the resources of a rule, which resources are created at
+func (f *FieldAuthorizationFilter) isCreation(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, rule := range f.Rules {
+		if path == rule.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+// Besides the paths of the rules it matches the paths under which other resources return them as related resources.
+func (f *FieldAuthorizationFilter) FilterMatchers() []web.FilterMatcher {
+	paths := make([]string, 0, 2*len(f.Rules)+len(ResourceURLs))
+	for _, rule := range f.Rules {
+		paths = append(paths, rule.Path, rule.Path+"/**")
+	}
+	for _, resourceURL := range ResourceURLs {
+		paths = append(paths, resourceURL+"/*/*")
+	}
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(paths...),
+			},
+		},
+	}
+}
+
+// removeFields removes the fields of the rules from the object at the prefix of the body
+func removeFields(body []byte, prefix string, rules []FieldRule) ([]byte, error) {
+	var err error
+	for _, rule := range rules {
+		if !strings.HasSuffix(rule.Field, "*") {
+			if body, err = sjson.DeleteBytes(body, prefix+rule.Field); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// wildcards match the keys of a top-level object, e.g. labels, or of the resource itself
+		parent, keyPrefix := strings.TrimSuffix(prefix, "."), strings.TrimSuffix(rule.Field, "*")
+		if dot := strings.Index(rule.Field, "."); dot != -1 {
+			parent, keyPrefix = prefix+rule.Field[:dot], strings.TrimSuffix(rule.Field[dot+1:], "*")
+		}
+		object := gjson.ParseBytes(body)
+		if parent != "" {
+			object = gjson.GetBytes(body, parent)
+		}
+		var keys []string
+		object.ForEach(func(key, _ gjson.Result) bool {
+			if strings.HasPrefix(key.String(), keyPrefix) {
+				keys = append(keys, key.String())
+			}
+			return true
+		})
+		for _, key := range keys {
+			path := pathEscaper.Replace(key)
+			if parent != "" {
+				path = parent + "." + path
+			}
+			if body, err = sjson.DeleteBytes(body, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return body, nil
+}
+
+// removeChanges removes the changes of the history at the path whose fields the rules deny as a whole and the
+// denied parts of the old and new values of the other changes. The fields of the changes of labels are the keys of
+// the labels prefixed with labels.
+func removeChanges(body []byte, path string, rules []FieldRule) ([]byte, error) {
+	changes := make([]string, 0)
+	for _, change := range gjson.GetBytes(body, path).Array() {
+		field := change.Get("field").String()
+		fieldPath := pathEscaper.Replace(field)
+		if strings.HasPrefix(field, "labels.") {
+			fieldPath = "labels." + pathEscaper.Replace(strings.TrimPrefix(field, "labels."))
+		}
+		redacted, kept := change.Raw, false
+		for _, side := range []string{"old", "new"} {
+			value := change.Get(side)
+			if !value.Exists() {
+				continue
+			}
+			object, err := sjson.SetRawBytes([]byte(`{}`), fieldPath, []byte(value.Raw))
+			if err != nil {
+				return nil, err
+			}
+			if object, err = removeFields(object, "", rules); err != nil {
+				return nil, err
+			}
+			if value = gjson.GetBytes(object, fieldPath); value.Exists() {
+				redacted, err = sjson.SetRaw(redacted, side, value.Raw)
+				kept = true
+			} else {
+				redacted, err = sjson.Delete(redacted, side)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if kept {
+			changes = append(changes, redacted)
+		}
+	}
+	return sjson.SetRawBytes(body, path, []byte("["+strings.Join(changes, ",")+"]"))
+}
+
+// callerScopes returns the scopes of the token of the caller of the context
+func callerScopes(ctx context.Context) map[string]bool {
+	user, _ := web.UserFromContext(ctx)
+	return user.Scopes()
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Field authorization filter", func() {
+	const broker = `{"id":"b1","name":"broker","credentials":{"basic":{"username":"u","password":"p"}},"labels":{"internal.owner":["team"],"internal.cost":["1"],"env":["dev"]}}`
+
+	var (
+		filter   *FieldAuthorizationFilter
+		revealed bool
+		status   int
+	)
+
+	run := func(method, path, claims, body string) *web.Response {
+		httpRequest, err := http.NewRequest(method, "https://example.com"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		request := &web.Request{Request: httpRequest}
+		if claims != "" {
+			request.Request = request.WithContext(web.ContextWithUser(request.Context(), &web.UserContext{
+				Name: "admin",
+				Data: &basicAuthnData{data: []byte(claims)},
+			}))
+		}
+		response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			revealed = req.Metadata().CredentialsRevealed()
+			return &web.Response{StatusCode: status, Header: http.Header{}, Body: []byte(body)}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		rules, err := ParseFieldRules([]string{
+			"/v1/service_brokers:credentials=sm.credentials",
+			"/v1/service_brokers:labels.internal.*=sm.internal",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		filter = &FieldAuthorizationFilter{Rules: rules}
+		revealed = false
+		status = http.StatusOK
+	})
+
+	It("removes the fields the caller has no scope for", func() {
+		response := run(http.MethodGet, "/v1/service_brokers/b1", `{"scope":["sm.read"]}`, broker)
+		Expect(response.Body).To(MatchJSON(`{"id":"b1","name":"broker","labels":{"env":["dev"]}}`))
+		Expect(revealed).To(BeFalse())
+	})
+
+	It("keeps the fields the caller has the scope for", func() {
+		response := run(http.MethodGet, "/v1/service_brokers/b1", `{"scope":"sm.credentials sm.internal"}`, broker)
+		Expect(response.Body).To(MatchJSON(broker))
+		Expect(revealed).To(BeTrue())
+	})
+
+	It("removes the fields from the items of lists", func() {
+		response := run(http.MethodGet, "/v1/service_brokers", `{"scope":["sm.credentials"]}`, `{"items":[`+broker+`,`+broker+`]}`)
+		expected := `{"id":"b1","name":"broker","credentials":{"basic":{"username":"u","password":"p"}},"labels":{"env":["dev"]}}`
+		Expect(response.Body).To(MatchJSON(`{"items":[` + expected + `,` + expected + `]}`))
+	})
+
+	It("leaves the responses of creations as they are", func() {
+		status = http.StatusCreated
+		response := run(http.MethodPost, "/v1/service_brokers", "", broker)
+		Expect(response.Body).To(MatchJSON(broker))
+	})
+
+	It("removes the fields from the responses of creations which return an existing resource", func() {
+		response := run(http.MethodPost, "/v1/service_brokers", `{"scope":["sm.credentials"]}`, broker)
+		Expect(response.Body).To(MatchJSON(`{"id":"b1","name":"broker","credentials":{"basic":{"username":"u","password":"p"}},"labels":{"env":["dev"]}}`))
+	})
+
+	It("removes the fields from the responses of reverts", func() {
+		response := run(http.MethodPost, "/v1/service_brokers/b1/revert", `{"scope":["sm.credentials"]}`, broker)
+		Expect(response.Body).To(MatchJSON(`{"id":"b1","name":"broker","credentials":{"basic":{"username":"u","password":"p"}},"labels":{"env":["dev"]}}`))
+	})
+
+	It("removes the changes of the fields from the history", func() {
+		history := `{"history":[{"id":"r1","operation":"update","changes":[` +
+			`{"field":"name","old":"old","new":"broker"},` +
+			`{"field":"labels.internal.owner","new":["team"]},` +
+			`{"field":"labels.env","old":["test"],"new":["dev"]}]}]}`
+		response := run(http.MethodGet, "/v1/service_brokers/b1/history", `{"scope":["sm.read"]}`, history)
+		Expect(response.Body).To(MatchJSON(`{"history":[{"id":"r1","operation":"update","changes":[` +
+			`{"field":"name","old":"old","new":"broker"},` +
+			`{"field":"labels.env","old":["test"],"new":["dev"]}]}]}`))
+	})
+
+	It("applies the rules of the related resources returned under another resource", func() {
+		rules, err := ParseFieldRules([]string{
+			"/v1/platforms:labels.internal.*=sm.internal",
+			"/v1/service_plans:labels.pricing.*=sm.pricing",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		filter = &FieldAuthorizationFilter{Rules: rules}
+		platform := `{"id":"p1","labels":{"internal.owner":["team"],"pricing.tier":["gold"]}}`
+		plan := `{"id":"sp1","labels":{"internal.owner":["team"],"pricing.tier":["gold"]}}`
+
+		response := run(http.MethodGet, "/v1/service_plans/sp1/platforms", `{"scope":["sm.read"]}`, `{"items":[`+platform+`]}`)
+		Expect(response.Body).To(MatchJSON(`{"items":[{"id":"p1","labels":{"pricing.tier":["gold"]}}]}`))
+
+		response = run(http.MethodGet, "/v1/platforms/p1/service_plans", `{"scope":["sm.read"]}`, `{"items":[`+plan+`]}`)
+		Expect(response.Body).To(MatchJSON(`{"items":[{"id":"sp1","labels":{"internal.owner":["team"]}}]}`))
+	})
+
+	It("matches the paths of the related resources", func() {
+		rules, err := ParseFieldRules([]string{"/v1/platforms:labels.internal.*=sm.internal"})
+		Expect(err).ToNot(HaveOccurred())
+		filter = &FieldAuthorizationFilter{Rules: rules}
+		matches, err := filter.FilterMatchers()[0].Matchers[0].Matches(web.Endpoint{Method: http.MethodGet, Path: "/v1/service_plans/{id}/platforms"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeTrue())
+	})
+
+	It("redacts resources returned without passing through the filter", func() {
+		ctx := web.ContextWithUser(context.Background(), &web.UserContext{
+			Name: "admin",
+			Data: &basicAuthnData{data: []byte(`{"scope":["sm.internal"]}`)},
+		})
+		redacted, err := filter.Redact(ctx, "/v1/service_brokers", []byte(broker))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(redacted).To(MatchJSON(`{"id":"b1","name":"broker","labels":{"internal.owner":["team"],"internal.cost":["1"],"env":["dev"]}}`))
+
+		filter = nil
+		redacted, err = filter.Redact(ctx, "/v1/service_brokers", []byte(broker))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(redacted).To(MatchJSON(`{"id":"b1","name":"broker","labels":{"internal.owner":["team"],"internal.cost":["1"],"env":["dev"]}}`))
+	})
+
+	It("rejects invalid rules", func() {
+		for _, rule := range []string{"credentials=sm.admin", "/v1/service_brokers:=sm.admin", "/v1/service_brokers:credentials=", "/v1/osb:credentials=sm.admin"} {
+			_, err := ParseFieldRules([]string{rule})
+			Expect(err).To(HaveOccurred(), rule)
+		}
+	})
+})
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FilterSpec declares a change of the registered filters in the configuration. It either adds the filter created
+// by a filter factory or removes a registered filter.
+type FilterSpec struct {
+	// Factory is the name of the filter factory creating the added filter
+	Factory string
+	// Options are passed to the filter factory
+	Options url.Values
+	// Before and After are the names of the filters the added filter is registered before or after, it is
+	// registered after all filters if both are empty
+	Before string
+	After  string
+
+	// Remove is the name of the removed filter
+	Remove string
+}
+
+// ParseFilterSpecs parses filter specs in the form factory[?option=value&...] [before|after filter] or -filter, e.g.
+// csrf?header_name=X-XSRF-Token before BasicAuthnFilter or -LoginThrottleFilter
+func ParseFilterSpecs(specs []string) ([]FilterSpec, error) {
+	result := make([]FilterSpec, 0, len(specs))
+	for _, spec := range specs {
+		fields := strings.Fields(spec)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid filter spec %q: expected the form factory[?options] [before|after filter] or -filter", spec)
+		}
+		if strings.HasPrefix(fields[0], "-") {
+			if len(fields) != 1 || len(fields[0]) == 1 {
+				return nil, fmt.Errorf("invalid filter spec %q: expected the form -filter", spec)
+			}
+			result = append(result, FilterSpec{Remove: fields[0][1:]})
+			continue
+		}
+
+		filterSpec := FilterSpec{Factory: fields[0], Options: url.Values{}}
+		if query := strings.Index(fields[0], "?"); query != -1 {
+			options, err := url.ParseQuery(fields[0][query+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter spec %q: invalid options: %s", spec, err)
+			}
+			filterSpec.Factory = fields[0][:query]
+			filterSpec.Options = options
+		}
+		if filterSpec.Factory == "" {
+			return nil, fmt.Errorf("invalid filter spec %q: missing filter factory", spec)
+		}
+		switch {
+		case len(fields) == 1:
+		case len(fields) == 3 && fields[1] == "before":
+			filterSpec.Before = fields[2]
+		case len(fields) == 3 && fields[1] == "after":
+			filterSpec.After = fields[2]
+		default:
+			return nil, fmt.Errorf("invalid filter spec %q: expected the form factory[?options] [before|after filter]", spec)
+		}
+		result = append(result, filterSpec)
+	}
+	return result, nil
+}
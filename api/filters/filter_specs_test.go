@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filter specs", func() {
+	It("parses added and removed filters", func() {
+		specs, err := ParseFilterSpecs([]string{
+			"csrf",
+			"csrf?header_name=X-XSRF-Token&exempt_paths=/v1/hooks/** before BasicAuthnFilter",
+			"audit after LoggingFilter",
+			"-LoginThrottleFilter",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(specs).To(Equal([]FilterSpec{
+			{Factory: "csrf", Options: url.Values{}},
+			{Factory: "csrf", Options: url.Values{"header_name": {"X-XSRF-Token"}, "exempt_paths": {"/v1/hooks/**"}}, Before: "BasicAuthnFilter"},
+			{Factory: "audit", Options: url.Values{}, After: "LoggingFilter"},
+			{Remove: "LoginThrottleFilter"},
+		}))
+	})
+
+	It("rejects invalid specs", func() {
+		for _, spec := range []string{
+			"",
+			"-",
+			"-LoggingFilter after BasicAuthnFilter",
+			"?header_name=X",
+			"csrf?header_name=%zz",
+			"csrf before",
+			"csrf between LoggingFilter",
+		} {
+			_, err := ParseFilterSpecs([]string{spec})
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+})
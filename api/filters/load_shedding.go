@@ -0,0 +1,355 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// LoadSheddingFilterName is the name of the load shedding filter
+	LoadSheddingFilterName = "LoadSheddingFilter"
+
+	// loadSheddingLatencySamples is the maximum number of request latencies from which the p99 latency is computed
+	loadSheddingLatencySamples = 1000
+
+	// loadSheddingLatencyRefresh is the time for which a computed p99 latency is reused
+	loadSheddingLatencyRefresh = time.Second
+)
+
+// LowPriorityPaths are the paths of the list endpoints, whose requests are shed first when the instance is overloaded
+var LowPriorityPaths = []string{
+	web.ServiceBrokersURL,
+	web.ServiceOfferingsURL,
+	web.ServicePlansURL,
+	web.VisibilitiesURL,
+	web.VisibilityPoliciesURL,
+	web.CompositeBrokersURL,
+	web.SavedQueriesURL,
+	web.PlatformsURL,
+	web.OperationsURL,
+	web.PeersURL,
+	web.ChangesURL,
+}
+
+// LoadSheddingSettings type to be loaded from the environment
+type LoadSheddingSettings struct {
+	Enabled          bool          `mapstructure:"enabled" description:"whether requests are queued and shed when the instance is overloaded"`
+	MaxInFlight      int           `mapstructure:"max_in_flight" description:"maximum number of requests processed concurrently, should be lower than the number of database connections the instance can open"`
+	QueueTimeout     time.Duration `mapstructure:"queue_timeout" description:"maximum time a request waits for a slot while max_in_flight requests are processed"`
+	LowPriorityShare float64       `mapstructure:"low_priority_share" description:"share of max_in_flight above which low priority requests, such as list requests, are shed"`
+	LatencyThreshold time.Duration `mapstructure:"latency_threshold" description:"p99 latency of the requests processed within the latency window above which low priority requests are shed"`
+	LatencyWindow    time.Duration `mapstructure:"latency_window" description:"time for which the latency of a processed request is considered"`
+	RetryAfter       time.Duration `mapstructure:"retry_after" description:"time after which the clients of shed requests are asked to retry"`
+}
+
+// DefaultLoadSheddingSettings returns default values for the load shedding settings
+func DefaultLoadSheddingSettings() *LoadSheddingSettings {
+	return &LoadSheddingSettings{
+		Enabled:          false,
+		MaxInFlight:      50,
+		QueueTimeout:     time.Second,
+		LowPriorityShare: 0.8,
+		LatencyThreshold: 2 * time.Second,
+		LatencyWindow:    time.Minute,
+		RetryAfter:       5 * time.Second,
+	}
+}
+
+// Validate validates the load shedding settings
+func (s *LoadSheddingSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.MaxInFlight < 1 {
+		return fmt.Errorf("validate Settings: load shedding max in flight (%d) should be at least 1", s.MaxInFlight)
+	}
+	if s.QueueTimeout < 0 {
+		return fmt.Errorf("validate Settings: load shedding queue timeout (%s) should be greater or equal to 0", s.QueueTimeout)
+	}
+	if s.LowPriorityShare <= 0 || s.LowPriorityShare > 1 {
+		return fmt.Errorf("validate Settings: load shedding low priority share (%v) should be greater than 0 and at most 1", s.LowPriorityShare)
+	}
+	if s.LatencyThreshold <= 0 {
+		return fmt.Errorf("validate Settings: load shedding latency threshold (%s) should be greater than 0", s.LatencyThreshold)
+	}
+	if s.LatencyWindow <= 0 {
+		return fmt.Errorf("validate Settings: load shedding latency window (%s) should be greater than 0", s.LatencyWindow)
+	}
+	if s.RetryAfter < time.Second {
+		return fmt.Errorf("validate Settings: load shedding retry after (%s) should be at least 1s", s.RetryAfter)
+	}
+	return nil
+}
+
+// LoadSheddingStatistics are the statistics of the requests admitted and shed by a LoadShedder
+type LoadSheddingStatistics struct {
+	InFlight        int    `json:"in_flight"`
+	Queued          int    `json:"queued"`
+	P99Latency      string `json:"p99_latency"`
+	Admitted        uint64 `json:"admitted"`
+	ShedLowPriority uint64 `json:"shed_low_priority"`
+	ShedQueued      uint64 `json:"shed_queued"`
+}
+
+type latencySample struct {
+	latency     time.Duration
+	completedAt time.Time
+}
+
+// LoadShedder limits the number of requests processed concurrently by the instance. Requests exceeding the limit wait
+// in a queue for a bounded time, as failing them right away would turn short bursts into errors. Low priority requests
+// are shed without waiting once the requests in flight exceed their share of the limit or the p99 latency exceeds the
+// threshold, so that the capacity left is used by the requests changing resources and by the OSB API. Shed requests
+// are answered with 503 and a Retry-After header, before they open database connections.
+type LoadShedder struct {
+	settings *LoadSheddingSettings
+	slots    chan struct{}
+
+	mutex       sync.Mutex
+	queued      int
+	samples     []latencySample
+	nextSample  int
+	p99         time.Duration
+	p99Computed time.Time
+
+	admitted, shedLowPriority, shedQueued uint64
+}
+
+// NewLoadShedder returns a LoadShedder configured with the provided settings
+func NewLoadShedder(settings *LoadSheddingSettings) *LoadShedder {
+	if settings == nil {
+		settings = DefaultLoadSheddingSettings()
+	}
+	maxInFlight := settings.MaxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &LoadShedder{
+		settings: settings,
+		slots:    make(chan struct{}, maxInFlight),
+		samples:  make([]latencySample, 0, loadSheddingLatencySamples),
+	}
+}
+
+// Enabled returns whether requests are queued and shed
+func (s *LoadShedder) Enabled() bool {
+	return s != nil && s.settings.Enabled
+}
+
+// Acquire waits for a slot to process a request and returns false if the request should be shed. Admitted requests
+// must release their slot once they are processed.
+func (s *LoadShedder) Acquire(ctx context.Context, lowPriority bool) bool {
+	s.mutex.Lock()
+	if lowPriority && s.overloaded(time.Now()) {
+		s.shedLowPriority++
+		s.mutex.Unlock()
+		return false
+	}
+	s.mutex.Unlock()
+
+	select {
+	case s.slots <- struct{}{}:
+		s.admit()
+		return true
+	default:
+	}
+
+	s.mutex.Lock()
+	s.queued++
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.queued--
+		s.mutex.Unlock()
+	}()
+
+	timer := time.NewTimer(s.settings.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		s.admit()
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.shedQueued++
+	return false
+}
+
+// Release frees the slot of an admitted request and records its latency
+func (s *LoadShedder) Release(latency time.Duration) {
+	<-s.slots
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sample := latencySample{latency: latency, completedAt: time.Now()}
+	if len(s.samples) < loadSheddingLatencySamples {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.nextSample] = sample
+		s.nextSample = (s.nextSample + 1) % loadSheddingLatencySamples
+	}
+}
+
+// RetryAfter returns the time after which the clients of shed requests should retry
+func (s *LoadShedder) RetryAfter() time.Duration {
+	return s.settings.RetryAfter
+}
+
+// Statistics returns the statistics of the admitted and shed requests
+func (s *LoadShedder) Statistics() LoadSheddingStatistics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return LoadSheddingStatistics{
+		InFlight:        len(s.slots),
+		Queued:          s.queued,
+		P99Latency:      s.p99Latency(time.Now()).String(),
+		Admitted:        s.admitted,
+		ShedLowPriority: s.shedLowPriority,
+		ShedQueued:      s.shedQueued,
+	}
+}
+
+// Name implements health.Indicator
+func (s *LoadShedder) Name() string {
+	return "load_shedding"
+}
+
+// Health implements health.Indicator and reports the load shedding statistics
+func (s *LoadShedder) Health() *health.Health {
+	healthz := health.New().Up()
+	if !s.settings.Enabled {
+		return healthz.WithDetail("enabled", false)
+	}
+	return healthz.WithDetail("enabled", true).WithDetail("statistics", s.Statistics())
+}
+
+func (s *LoadShedder) admit() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.admitted++
+}
+
+// overloaded returns whether low priority requests should be shed. It must be called with the mutex.
+func (s *LoadShedder) overloaded(now time.Time) bool {
+	if float64(len(s.slots)) >= s.settings.LowPriorityShare*float64(cap(s.slots)) {
+		return true
+	}
+	return s.p99Latency(now) > s.settings.LatencyThreshold
+}
+
+// p99Latency returns the p99 latency of the requests completed within the latency window. It is computed at most
+// once per refresh interval. It must be called with the mutex.
+func (s *LoadShedder) p99Latency(now time.Time) time.Duration {
+	if now.Sub(s.p99Computed) < loadSheddingLatencyRefresh {
+		return s.p99
+	}
+
+	latencies := make([]time.Duration, 0, len(s.samples))
+	for _, sample := range s.samples {
+		if now.Sub(sample.completedAt) <= s.settings.LatencyWindow {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	s.p99 = 0
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		s.p99 = latencies[(len(latencies)*99)/100]
+	}
+	s.p99Computed = now
+	return s.p99
+}
+
+// LoadSheddingFilter queues and sheds the requests to the Service Manager when it is overloaded
+type LoadSheddingFilter struct {
+	Shedder *LoadShedder
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*LoadSheddingFilter) Name() string {
+	return LoadSheddingFilterName
+}
+
+// Run processes the request once it acquired a slot and responds with 503 if it is shed
+func (f *LoadSheddingFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	// health checks keep passing under load, so that the instance is not restarted because it is busy
+	if !f.Shedder.Enabled() || strings.HasPrefix(req.URL.Path, web.MonitorHealthURL) {
+		return next.Handle(req)
+	}
+
+	ctx := req.Context()
+	if !f.Shedder.Acquire(ctx, isLowPriorityRequest(req)) {
+		log.C(ctx).Warnf("Shedding request %s %s as the instance is overloaded", req.Method, req.URL.Path)
+		response, err := util.NewJSONResponse(http.StatusServiceUnavailable, &util.HTTPError{
+			ErrorType:   "ServiceUnavailable",
+			Description: "the service manager is overloaded, retry later",
+		})
+		if err != nil {
+			return nil, err
+		}
+		response.Header.Set("Retry-After", strconv.Itoa(int(f.Shedder.RetryAfter()/time.Second)))
+		return response, nil
+	}
+
+	start := time.Now()
+	defer func() {
+		f.Shedder.Release(time.Since(start))
+	}()
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*LoadSheddingFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
+
+// isLowPriorityRequest returns whether the request lists the resources of one of the low priority paths
+func isLowPriorityRequest(req *web.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	for _, lowPriorityPath := range LowPriorityPaths {
+		if path == lowPriorityPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load shedding filter", func() {
+	var (
+		settings *LoadSheddingSettings
+		shedder  *LoadShedder
+		filter   *LoadSheddingFilter
+		blocked  chan struct{}
+	)
+
+	run := func(method, path string) *web.Response {
+		httpRequest, err := http.NewRequest(method, "https://example.com"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			<-blocked
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	runInBackground := func(method, path string) <-chan *web.Response {
+		responses := make(chan *web.Response, 1)
+		go func() {
+			defer GinkgoRecover()
+			responses <- run(method, path)
+		}()
+		return responses
+	}
+
+	BeforeEach(func() {
+		settings = DefaultLoadSheddingSettings()
+		settings.Enabled = true
+		settings.MaxInFlight = 2
+		settings.LowPriorityShare = 0.5
+		settings.QueueTimeout = 100 * time.Millisecond
+		blocked = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		shedder = NewLoadShedder(settings)
+		filter = &LoadSheddingFilter{Shedder: shedder}
+	})
+
+	AfterEach(func() {
+		select {
+		case <-blocked:
+		default:
+			close(blocked)
+		}
+	})
+
+	It("is disabled by default", func() {
+		Expect(DefaultLoadSheddingSettings().Enabled).To(BeFalse())
+		Expect(DefaultLoadSheddingSettings().Validate()).To(Succeed())
+	})
+
+	It("processes the requests while the instance is not overloaded", func() {
+		close(blocked)
+		Expect(run(http.MethodGet, web.ServicePlansURL).StatusCode).To(Equal(http.StatusOK))
+		Expect(run(http.MethodPost, web.ServiceBrokersURL).StatusCode).To(Equal(http.StatusOK))
+		Expect(shedder.Statistics().Admitted).To(Equal(uint64(2)))
+	})
+
+	Context("when the requests in flight exceed the share of low priority requests", func() {
+		It("sheds list requests with retry after", func() {
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(1))
+
+			response := run(http.MethodGet, web.ServicePlansURL+"/")
+			Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Header.Get("Retry-After")).To(Equal("5"))
+			Expect(shedder.Statistics().ShedLowPriority).To(Equal(uint64(1)))
+		})
+
+		It("processes other requests", func() {
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(1))
+
+			responses := runInBackground(http.MethodGet, web.ServicePlansURL+"/id")
+			Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(2))
+			close(blocked)
+			Expect((<-responses).StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when max in flight requests are processed", func() {
+		It("queues requests until a slot is released", func() {
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(2))
+
+			responses := runInBackground(http.MethodDelete, web.VisibilitiesURL+"/id")
+			Eventually(func() int { return shedder.Statistics().Queued }).Should(Equal(1))
+			close(blocked)
+			Expect((<-responses).StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("sheds requests queued longer than the queue timeout", func() {
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+			Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(2))
+
+			response := run(http.MethodDelete, web.VisibilitiesURL+"/id")
+			Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(shedder.Statistics().ShedQueued).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("when the p99 latency exceeds the threshold", func() {
+		It("sheds list requests", func() {
+			Expect(shedder.Acquire(context.Background(), false)).To(BeTrue())
+			shedder.Release(settings.LatencyThreshold + time.Second)
+
+			Expect(run(http.MethodGet, web.PlatformsURL).StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(shedder.Statistics().P99Latency).To(Equal((settings.LatencyThreshold + time.Second).String()))
+		})
+	})
+
+	It("does not shed health checks", func() {
+		runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+		runInBackground(http.MethodPatch, web.ServiceBrokersURL+"/id")
+		Eventually(func() int { return shedder.Statistics().InFlight }).Should(Equal(2))
+
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com"+web.MonitorHealthURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(shedder.Statistics().ShedQueued).To(Equal(uint64(0)))
+	})
+})
@@ -17,6 +17,8 @@
 package filters
 
 import (
+	"strings"
+
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/web"
 )
@@ -39,14 +41,26 @@ func (*Logging) Name() string {
 func (l *Logging) Run(req *web.Request, next web.Handler) (*web.Response, error) {
 	ctx := req.Context()
 	entry := log.C(ctx)
+	metadata := req.Metadata().WithAPIVersion(apiVersion(req.URL.Path))
 	if correlationID := log.CorrelationIDForRequest(req.Request); correlationID != "" {
 		entry = entry.WithField(log.FieldCorrelationID, correlationID)
+		metadata = metadata.WithCorrelationID(correlationID)
 	}
 	ctx = log.ContextWithLogger(ctx, entry)
 	req.Request = req.WithContext(ctx)
+	req.SetMetadata(metadata)
 	return next.Handle(req)
 }
 
+// apiVersion returns the version segment of versioned API paths such as /v1/service_brokers
+func apiVersion(path string) string {
+	segment := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+	if len(segment) > 1 && segment[0] == 'v' && strings.Trim(segment[1:], "0123456789") == "" {
+		return segment
+	}
+	return ""
+}
+
 // FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
 func (*Logging) FilterMatchers() []web.FilterMatcher {
 	return []web.FilterMatcher{
@@ -18,6 +18,7 @@ package filters
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/web"
@@ -31,7 +32,7 @@ var _ = Describe("Logging Filter", func() {
 	var request *web.Request
 	var handler *webfakes.FakeHandler
 	BeforeEach(func() {
-		request = &web.Request{Request: &http.Request{}}
+		request = &web.Request{Request: &http.Request{URL: &url.URL{Path: "/v1/service_brokers"}}}
 		request.Header = http.Header{}
 		handler = &webfakes.FakeHandler{}
 	})
@@ -53,6 +54,25 @@ var _ = Describe("Logging Filter", func() {
 				correlationId := logger.Data[log.FieldCorrelationID].(string)
 				Expect(correlationId).To(Equal(expectedCorrelationId))
 			})
+
+			It("Stores it in the request metadata", func() {
+				request.Header.Set("X-Correlation-ID", "correlationId")
+				loggingFilter.Run(request, handler)
+				Expect(request.Metadata().CorrelationID()).To(Equal("correlationId"))
+			})
+		})
+	})
+
+	Describe("API version", func() {
+		It("Stores the version of versioned paths in the request metadata", func() {
+			loggingFilter.Run(request, handler)
+			Expect(request.Metadata().APIVersion()).To(Equal("v1"))
+		})
+
+		It("Leaves it empty for unversioned paths", func() {
+			request.URL.Path = "/healthz"
+			loggingFilter.Run(request, handler)
+			Expect(request.Metadata().APIVersion()).To(BeEmpty())
 		})
 	})
 })
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/sirupsen/logrus"
+)
+
+// LoginThrottleFilterName is the name of the filter which throttles failed basic authentications
+const LoginThrottleFilterName = "LoginThrottleFilter"
+
+// LoginThrottleSettings type to be loaded from the environment
+type LoginThrottleSettings struct {
+	Enabled         bool          `mapstructure:"enabled" description:"whether repeated failed basic authentications of a username from an address lock them out temporarily"`
+	MaxFailures     int           `mapstructure:"max_failures" description:"number of failed basic authentications of a username from an address after which they are locked out"`
+	FailureWindow   time.Duration `mapstructure:"failure_window" description:"time after the last failed basic authentication after which the failures are forgotten"`
+	LockoutDuration time.Duration `mapstructure:"lockout_duration" description:"duration of the first lockout, which doubles with every further failure"`
+	MaxLockout      time.Duration `mapstructure:"max_lockout" description:"maximum duration of a lockout"`
+	TrustedProxies  []string      `mapstructure:"trusted_proxies" description:"networks in CIDR notation of the proxies whose X-Forwarded-For header identifies the address of the client, the address of the connection is used for requests from other networks"`
+	MaxTracked      int           `mapstructure:"max_tracked" description:"maximum number of usernames and addresses whose failures are tracked within the failure window, further failures are tracked per address only"`
+}
+
+// DefaultLoginThrottleSettings returns default values for the login throttle settings
+func DefaultLoginThrottleSettings() *LoginThrottleSettings {
+	return &LoginThrottleSettings{
+		Enabled:         false,
+		MaxFailures:     5,
+		FailureWindow:   time.Hour,
+		LockoutDuration: 10 * time.Second,
+		MaxLockout:      15 * time.Minute,
+		TrustedProxies:  []string{},
+		MaxTracked:      10000,
+	}
+}
+
+// Validate validates the login throttle settings
+func (s *LoginThrottleSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.MaxFailures < 1 {
+		return fmt.Errorf("validate Settings: login throttle max failures (%d) should be at least 1", s.MaxFailures)
+	}
+	if s.FailureWindow <= 0 {
+		return fmt.Errorf("validate Settings: login throttle failure window (%s) should be greater than 0", s.FailureWindow)
+	}
+	if s.LockoutDuration < time.Second {
+		return fmt.Errorf("validate Settings: login throttle lockout duration (%s) should be at least 1s", s.LockoutDuration)
+	}
+	if s.MaxLockout < s.LockoutDuration {
+		return fmt.Errorf("validate Settings: login throttle max lockout (%s) should be at least the lockout duration (%s)", s.MaxLockout, s.LockoutDuration)
+	}
+	if s.MaxTracked < 1 {
+		return fmt.Errorf("validate Settings: login throttle max tracked (%d) should be at least 1", s.MaxTracked)
+	}
+	if _, err := parseCIDRs(s.TrustedProxies); err != nil {
+		return fmt.Errorf("validate Settings: login throttle trusted proxies: %s", err)
+	}
+	return nil
+}
+
+// LoginThrottle counts the failed basic authentications per username and client address and locks them out
+// after too many failures. Each further failure doubles the lockout up to the maximum, a successful authentication
+// resets the failures. The state is kept in a cache.Store, which is shared by all instances if it is backed by Redis.
+// Once the maximum number of usernames and addresses are tracked, the failures of further usernames are counted per
+// address, so that failures with ever new usernames neither grow the store nor escape the lockout.
+type LoginThrottle struct {
+	settings       *LoginThrottleSettings
+	store          cache.Store
+	trustedProxies []*net.IPNet
+}
+
+// NewLoginThrottle returns a LoginThrottle configured with the provided settings which keeps its state in the
+// provided store or in memory if it is nil
+func NewLoginThrottle(settings *LoginThrottleSettings, store cache.Store) *LoginThrottle {
+	if settings == nil {
+		settings = DefaultLoginThrottleSettings()
+	}
+	if store == nil {
+		store = cache.NewMemoryStore()
+	}
+	trustedProxies, _ := parseCIDRs(settings.TrustedProxies)
+	return &LoginThrottle{
+		settings:       settings,
+		store:          store,
+		trustedProxies: trustedProxies,
+	}
+}
+
+// Enabled returns whether failed authentications are throttled
+func (t *LoginThrottle) Enabled() bool {
+	return t != nil && t.settings.Enabled
+}
+
+// ClientAddress returns the address by which the failures of the request are counted. The X-Forwarded-For header is
+// only used for requests from the trusted proxies, as any other client can send a new value with every attempt.
+func (t *LoginThrottle) ClientAddress(req *web.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range t.trustedProxies {
+			if network.Contains(ip) {
+				return req.ClientIP()
+			}
+		}
+	}
+	return host
+}
+
+// LockedOut returns the remaining time of the lockout of the username from the address or zero if it is not
+// locked out
+func (t *LoginThrottle) LockedOut(ctx context.Context, username, address string) (time.Duration, error) {
+	var remaining time.Duration
+	for _, principal := range []string{username, anyUsername} {
+		value, found, err := t.store.Get(ctx, t.key("lockout", principal, address))
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			continue
+		}
+		until, err := time.Parse(time.RFC3339Nano, string(value))
+		if err != nil {
+			return 0, err
+		}
+		if lockout := time.Until(until); lockout > remaining {
+			remaining = lockout
+		}
+	}
+	return remaining, nil
+}
+
+// Failed records a failed authentication of the username from the address and returns the lockout it caused, which
+// is zero while the failures are below the maximum
+func (t *LoginThrottle) Failed(ctx context.Context, username, address string) (time.Duration, error) {
+	failuresKey := t.key("failures", username, address)
+	failures, err := t.store.Increment(ctx, failuresKey, t.settings.FailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	if failures == 1 {
+		tracked, err := t.store.Increment(ctx, "login_throttle:tracked", t.settings.FailureWindow)
+		if err != nil {
+			return 0, err
+		}
+		if tracked > int64(t.settings.MaxTracked) {
+			if err := t.store.Delete(ctx, failuresKey); err != nil {
+				return 0, err
+			}
+			username = anyUsername
+			if failures, err = t.store.Increment(ctx, t.key("failures", username, address), t.settings.FailureWindow); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if failures < int64(t.settings.MaxFailures) {
+		return 0, nil
+	}
+	lockout := t.settings.MaxLockout
+	if exponent := failures - int64(t.settings.MaxFailures); exponent < 32 {
+		if doubled := time.Duration(float64(t.settings.LockoutDuration) * math.Pow(2, float64(exponent))); doubled < lockout {
+			lockout = doubled
+		}
+	}
+	until := time.Now().Add(lockout).UTC().Format(time.RFC3339Nano)
+	return lockout, t.store.Set(ctx, t.key("lockout", username, address), []byte(until), lockout)
+}
+
+// Succeeded resets the failed authentications of the username from the address
+func (t *LoginThrottle) Succeeded(ctx context.Context, username, address string) error {
+	return t.store.Delete(ctx, t.key("failures", username, address), t.key("lockout", username, address))
+}
+
+// anyUsername counts the failures of an address once the maximum number of usernames and addresses are tracked
+const anyUsername = "*"
+
+func (t *LoginThrottle) key(kind, username, address string) string {
+	return fmt.Sprintf("login_throttle:%s:%s:%s", kind, address, username)
+}
+
+// LoginThrottleFilter rejects the basic authentications of locked out usernames with 429 before their credentials
+// are checked and records the outcome of the others. It runs before the basic authentication filter.
+type LoginThrottleFilter struct {
+	Throttle *LoginThrottle
+}
+
+// Name implements the web.Named interface and returns the name of the filter
+func (*LoginThrottleFilter) Name() string {
+	return LoginThrottleFilterName
+}
+
+// Run implements the web.Middleware interface and throttles the failed basic authentications
+func (f *LoginThrottleFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	username, _, ok := req.BasicAuth()
+	if !ok || !f.Throttle.Enabled() {
+		return next.Handle(req)
+	}
+
+	ctx := req.Context()
+	address := f.Throttle.ClientAddress(req)
+	remaining, err := f.Throttle.LockedOut(ctx, username, address)
+	if err != nil {
+		log.C(ctx).WithError(err).Warn("Could not check login lockout")
+	}
+	if remaining > 0 {
+		log.C(ctx).WithFields(logrus.Fields{
+			"audit":     "authentication_throttled",
+			"principal": username,
+			"ip":        address,
+		}).Warnf("Rejecting basic authentication of %s from %s which is locked out for %s", username, address, remaining)
+		response, err := util.NewJSONResponse(http.StatusTooManyRequests, &util.HTTPError{
+			ErrorType:   "TooManyRequests",
+			Description: "too many failed authentications, retry later",
+		})
+		if err != nil {
+			return nil, err
+		}
+		response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+		return response, nil
+	}
+
+	response, err := next.Handle(req)
+	if httpError, isHTTPError := err.(*util.HTTPError); isHTTPError && httpError.StatusCode == http.StatusUnauthorized {
+		lockout, throttleErr := f.Throttle.Failed(ctx, username, address)
+		if throttleErr != nil {
+			log.C(ctx).WithError(throttleErr).Warn("Could not record failed authentication")
+		} else if lockout > 0 {
+			log.C(ctx).WithFields(logrus.Fields{
+				"audit":     "authentication_locked_out",
+				"principal": username,
+				"ip":        address,
+			}).Warnf("Locking out basic authentication of %s from %s for %s", username, address, lockout)
+		}
+		return response, err
+	}
+	if _, authenticated := web.UserFromContext(req.Context()); authenticated {
+		if throttleErr := f.Throttle.Succeeded(ctx, username, address); throttleErr != nil {
+			log.C(ctx).WithError(throttleErr).Warn("Could not reset failed authentications")
+		}
+	}
+	return response, err
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*LoginThrottleFilter) FilterMatchers() []web.FilterMatcher {
+	return basicAuthnMatchers()
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Login throttle filter", func() {
+	var (
+		settings *LoginThrottleSettings
+		filter   *LoginThrottleFilter
+		password string
+	)
+
+	// the handler plays the basic authentication filter, which accepts only the password "valid"
+	runWithForwardedFor := func(remoteAddr, forwardedFor string) (*web.Response, error) {
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/platforms", nil)
+		Expect(err).ToNot(HaveOccurred())
+		httpRequest.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			httpRequest.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		httpRequest.SetBasicAuth("platform", password)
+		return filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			if _, pass, _ := req.BasicAuth(); pass != "valid" {
+				return nil, security.UnauthorizedHTTPError("provided credentials are invalid")
+			}
+			req.Request = req.WithContext(web.ContextWithUser(req.Context(), &web.UserContext{Name: "platform"}))
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+	}
+
+	run := func(remoteAddr string) (*web.Response, error) {
+		return runWithForwardedFor(remoteAddr, "")
+	}
+
+	BeforeEach(func() {
+		settings = DefaultLoginThrottleSettings()
+		settings.Enabled = true
+		settings.MaxFailures = 3
+		settings.LockoutDuration = time.Minute
+		settings.MaxLockout = 3 * time.Minute
+		filter = &LoginThrottleFilter{Throttle: NewLoginThrottle(settings, cache.NewMemoryStore())}
+		password = "invalid"
+	})
+
+	It("locks out the username after the maximum failures", func() {
+		for i := 0; i < settings.MaxFailures; i++ {
+			_, err := run("10.0.0.1:1234")
+			Expect(err).To(HaveOccurred())
+		}
+
+		password = "valid"
+		response, err := run("10.0.0.1:1234")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(response.Header.Get("Retry-After")).To(Equal("60"))
+	})
+
+	It("does not lock out the username from other addresses", func() {
+		for i := 0; i < settings.MaxFailures; i++ {
+			_, err := run("10.0.0.1:1234")
+			Expect(err).To(HaveOccurred())
+		}
+
+		password = "valid"
+		response, err := run("10.0.0.2:1234")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("ignores the X-Forwarded-For header of clients other than the trusted proxies", func() {
+		for i := 0; i < settings.MaxFailures; i++ {
+			_, err := runWithForwardedFor("10.0.0.1:1234", fmt.Sprintf("192.168.0.%d", i))
+			Expect(err).To(HaveOccurred())
+		}
+
+		password = "valid"
+		response, err := runWithForwardedFor("10.0.0.1:1234", "192.168.0.100")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("uses the X-Forwarded-For header of the trusted proxies", func() {
+		settings.TrustedProxies = []string{"10.0.0.0/24"}
+		filter = &LoginThrottleFilter{Throttle: NewLoginThrottle(settings, cache.NewMemoryStore())}
+		for i := 0; i < settings.MaxFailures; i++ {
+			_, err := runWithForwardedFor("10.0.0.1:1234", "192.168.0.1")
+			Expect(err).To(HaveOccurred())
+		}
+
+		password = "valid"
+		response, err := runWithForwardedFor("10.0.0.1:1234", "192.168.0.2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		response, err = runWithForwardedFor("10.0.0.1:1234", "192.168.0.1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("counts the failures per address once the maximum usernames are tracked", func() {
+		settings.MaxTracked = 2
+		store := cache.NewMemoryStore()
+		throttle := NewLoginThrottle(settings, store)
+		for i := 0; i < 10; i++ {
+			_, err := throttle.Failed(context.TODO(), fmt.Sprintf("user-%d", i), "10.0.0.1")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		// the failures of two usernames, the failures of the address and the number of tracked failures
+		Expect(store.Len()).To(Equal(5))
+
+		remaining, err := throttle.LockedOut(context.TODO(), "user-100", "10.0.0.1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(remaining).To(BeNumerically(">", 0))
+	})
+
+	It("resets the failures after a successful authentication", func() {
+		for i := 0; i < settings.MaxFailures-1; i++ {
+			_, err := run("10.0.0.1:1234")
+			Expect(err).To(HaveOccurred())
+		}
+		password = "valid"
+		_, err := run("10.0.0.1:1234")
+		Expect(err).ToNot(HaveOccurred())
+
+		password = "invalid"
+		_, err = run("10.0.0.1:1234")
+		Expect(err).To(HaveOccurred())
+		password = "valid"
+		response, err := run("10.0.0.1:1234")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("doubles the lockout with every further failure up to the maximum", func() {
+		throttle := filter.Throttle
+		var lockouts []time.Duration
+		for i := 0; i < settings.MaxFailures+3; i++ {
+			lockout, err := throttle.Failed(context.TODO(), "platform", "10.0.0.1")
+			Expect(err).ToNot(HaveOccurred())
+			lockouts = append(lockouts, lockout)
+		}
+		Expect(lockouts).To(Equal([]time.Duration{0, 0, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}))
+	})
+
+	It("passes requests without basic credentials", func() {
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com/v1/platforms", nil)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/tidwall/gjson"
+)
+
+const MaintenanceInfoFilterName = "MaintenanceInfoFilter"
+
+// MaintenanceInfoFilter rejects provision and update requests whose maintenance info version does not match the
+// maintenance info version of the requested plan, as required by OSB API 2.15. The plan of an update without
+// plan_id is taken from its previous values. Requests without maintenance info or for unknown plans are passed
+// through to the broker.
+type MaintenanceInfoFilter struct {
+	Repository storage.Repository
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*MaintenanceInfoFilter) Name() string {
+	return MaintenanceInfoFilterName
+}
+
+func (f *MaintenanceInfoFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	requestedVersion := gjson.GetBytes(body, "maintenance_info.version")
+	if !requestedVersion.Exists() {
+		return next.Handle(req)
+	}
+	planID := gjson.GetBytes(body, "plan_id").String()
+	if planID == "" {
+		planID = gjson.GetBytes(body, "previous_values.plan_id").String()
+	}
+	if planID == "" {
+		return next.Handle(req)
+	}
+
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(req.Context(), brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(req.Context(), brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	_, plansByCatalogID := catalogIndex(offerings)
+
+	plan, found := plansByCatalogID[namespace.Remove(planID, knownPlan(plansByCatalogID))]
+	if !found {
+		return next.Handle(req)
+	}
+	if planVersion := plan.MaintenanceInfo.MaintenanceVersion(); planVersion != requestedVersion.String() {
+		return nil, &util.HTTPError{
+			ErrorType:   "MaintenanceInfoConflict",
+			Description: fmt.Sprintf("maintenance info version %q does not match version %q of service plan %s", requestedVersion.String(), planVersion, plan.Name),
+			StatusCode:  http.StatusUnprocessableEntity,
+		}
+	}
+	return next.Handle(req)
+}
+
+func (*MaintenanceInfoFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/service_instances/*"),
+				web.Methods(http.MethodPut, http.MethodPatch),
+			},
+		},
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Maintenance info filter", func() {
+	var (
+		fakeRepository *storagefakes.FakeStorage
+		filter         *MaintenanceInfoFilter
+	)
+
+	run := func(body string) (*web.Response, error) {
+		httpRequest, err := http.NewRequest(http.MethodPatch, "https://example.com/v1/osb/broker-id/v2/service_instances/instance-id", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request := &web.Request{
+			Request:    httpRequest,
+			PathParams: map[string]string{osb.BrokerIDPathParam: "broker-id"},
+			Body:       []byte(body),
+		}
+		return filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+	}
+
+	expectConflict := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(err.(*util.HTTPError).ErrorType).To(Equal("MaintenanceInfoConflict"))
+	}
+
+	BeforeEach(func() {
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.ServiceOfferingType {
+				return &types.ServiceOfferings{
+					ServiceOfferings: []*types.ServiceOffering{
+						{Base: types.Base{ID: "service-id"}, CatalogID: "service-catalog-id", Name: "service"},
+					},
+				}, nil
+			}
+			return &types.ServicePlans{
+				ServicePlans: []*types.ServicePlan{
+					{CatalogID: "versioned-plan-catalog-id", ServiceOfferingID: "service-id", Name: "versioned", MaintenanceInfo: &types.MaintenanceInfo{Version: "2.0.0"}},
+					{CatalogID: "unversioned-plan-catalog-id", ServiceOfferingID: "service-id", Name: "unversioned"},
+				},
+			}, nil
+		}
+		filter = &MaintenanceInfoFilter{Repository: fakeRepository}
+	})
+
+	It("passes requests without maintenance info through", func() {
+		response, err := run(`{"plan_id": "versioned-plan-catalog-id"}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(fakeRepository.ListCallCount()).To(Equal(0))
+	})
+
+	It("passes requests with the version of the plan through", func() {
+		response, err := run(`{"plan_id": "versioned-plan-catalog-id", "maintenance_info": {"version": "2.0.0"}}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("rejects requests with another version than the one of the plan", func() {
+		_, err := run(`{"plan_id": "versioned-plan-catalog-id", "maintenance_info": {"version": "1.0.0"}}`)
+		expectConflict(err)
+	})
+
+	It("rejects requests with a version for plans without maintenance info", func() {
+		_, err := run(`{"plan_id": "unversioned-plan-catalog-id", "maintenance_info": {"version": "1.0.0"}}`)
+		expectConflict(err)
+	})
+
+	It("takes the plan of updates from the previous values", func() {
+		_, err := run(`{"previous_values": {"plan_id": "versioned-plan-catalog-id"}, "maintenance_info": {"version": "1.0.0"}}`)
+		expectConflict(err)
+	})
+
+	It("rejects requests with another version than the one of a plan with a prefixed catalog ID", func() {
+		fakeRepository.GetReturns(&types.ServiceBroker{
+			Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+		}, nil)
+		_, err := run(`{"plan_id": "acme-versioned-plan-catalog-id", "maintenance_info": {"version": "1.0.0"}}`)
+		expectConflict(err)
+	})
+
+	It("passes requests for unknown plans through", func() {
+		response, err := run(`{"plan_id": "unknown-plan-catalog-id", "maintenance_info": {"version": "1.0.0"}}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/gjson"
+)
+
+// NamingRulesFilterName is the name of the filter which enforces the naming rules of brokers and platforms
+const NamingRulesFilterName = "NamingRulesFilter"
+
+// NamingSettings type to be loaded from the environment
+type NamingSettings struct {
+	NameMaxLength int    `mapstructure:"name_max_length" description:"maximum number of characters of the names of brokers and platforms, 0 for no limit"`
+	NamePattern   string `mapstructure:"name_pattern" description:"regular expression the names of brokers and platforms must fully match, any name is accepted if empty"`
+	IDMaxLength   int    `mapstructure:"id_max_length" description:"maximum number of characters of the IDs chosen by the clients creating brokers and platforms, 0 for no limit"`
+	IDPattern     string `mapstructure:"id_pattern" description:"regular expression the IDs chosen by the clients creating brokers and platforms must fully match, any ID is accepted if empty"`
+}
+
+// DefaultNamingSettings returns default values for the naming settings, which only limit the lengths to the
+// sizes of the database columns
+func DefaultNamingSettings() *NamingSettings {
+	return &NamingSettings{
+		NameMaxLength: 255,
+		IDMaxLength:   100,
+	}
+}
+
+// Validate validates the naming settings
+func (s *NamingSettings) Validate() error {
+	if s.NameMaxLength < 0 {
+		return fmt.Errorf("validate Settings: naming name max length must be >= 0")
+	}
+	if s.IDMaxLength < 0 {
+		return fmt.Errorf("validate Settings: naming id max length must be >= 0")
+	}
+	if _, err := compileNamingPattern(s.NamePattern); err != nil {
+		return fmt.Errorf("validate Settings: invalid naming name pattern: %s", err)
+	}
+	if _, err := compileNamingPattern(s.IDPattern); err != nil {
+		return fmt.Errorf("validate Settings: invalid naming id pattern: %s", err)
+	}
+	return nil
+}
+
+// compileNamingPattern compiles a pattern which must match the whole value, an empty pattern results in nil
+func compileNamingPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// namingRule restricts the length and the characters of a field
+type namingRule struct {
+	field     string
+	maxLength int
+	pattern   *regexp.Regexp
+}
+
+func (r namingRule) check(value string) error {
+	if r.maxLength > 0 && utf8.RuneCountInString(value) > r.maxLength {
+		return fmt.Errorf("%s %q is longer than %d characters", r.field, value, r.maxLength)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		return fmt.Errorf("%s %q does not match the pattern %s", r.field, value, r.pattern.String())
+	}
+	return nil
+}
+
+// NamingRulesFilter rejects the creation and the update of brokers and platforms whose names or IDs violate the
+// naming rules of the deployment, e.g. because the platforms they are propagated to do not accept them. The names
+// remain unique among all resources of a type.
+type NamingRulesFilter struct {
+	name namingRule
+	id   namingRule
+}
+
+// NewNamingRulesFilter returns a filter enforcing the naming rules of the validated settings
+func NewNamingRulesFilter(settings *NamingSettings) *NamingRulesFilter {
+	namePattern, _ := compileNamingPattern(settings.NamePattern)
+	idPattern, _ := compileNamingPattern(settings.IDPattern)
+	return &NamingRulesFilter{
+		name: namingRule{field: "name", maxLength: settings.NameMaxLength, pattern: namePattern},
+		id:   namingRule{field: "id", maxLength: settings.IDMaxLength, pattern: idPattern},
+	}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*NamingRulesFilter) Name() string {
+	return NamingRulesFilterName
+}
+
+// Run checks the name and the ID of the resource in the request body. Upserts take the ID from the path.
+func (f *NamingRulesFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	if name := gjson.GetBytes(body, "name"); name.Exists() {
+		if err := f.name.check(name.String()); err != nil {
+			return nil, namingError(err)
+		}
+	}
+	id := gjson.GetBytes(body, "id").String()
+	if req.Method == http.MethodPut {
+		id = path.Base(strings.TrimSuffix(req.URL.Path, "/"))
+	}
+	if id != "" && req.Method != http.MethodPatch {
+		if err := f.id.check(id); err != nil {
+			return nil, namingError(err)
+		}
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*NamingRulesFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBrokersURL, web.PlatformsURL),
+				web.Methods(http.MethodPost),
+			},
+		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBrokersURL+"/*", web.PlatformsURL+"/*"),
+				web.Methods(http.MethodPatch, http.MethodPut),
+			},
+		},
+	}
+}
+
+func namingError(err error) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: err.Error(),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
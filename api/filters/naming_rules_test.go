@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Naming rules filter", func() {
+	var (
+		settings  *NamingSettings
+		forwarded bool
+	)
+
+	run := func(method, url, body string) error {
+		httpRequest, err := http.NewRequest(method, "https://example.com"+url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		forwarded = false
+		_, err = NewNamingRulesFilter(settings).Run(&web.Request{Request: httpRequest, Body: []byte(body)}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			forwarded = true
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		return err
+	}
+
+	expectBadRequest := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(forwarded).To(BeFalse())
+	}
+
+	BeforeEach(func() {
+		settings = DefaultNamingSettings()
+		settings.NameMaxLength = 10
+		settings.NamePattern = "[a-z][a-z0-9-]*"
+		settings.IDPattern = "[a-z0-9-]+"
+	})
+
+	It("forwards resources with valid names and IDs", func() {
+		Expect(run(http.MethodPost, web.ServiceBrokersURL, `{"id": "broker-1", "name": "broker"}`)).To(Succeed())
+		Expect(forwarded).To(BeTrue())
+	})
+
+	It("rejects names which are too long", func() {
+		expectBadRequest(run(http.MethodPost, web.PlatformsURL, `{"name": "a-very-long-platform"}`))
+	})
+
+	It("rejects names which do not fully match the pattern", func() {
+		expectBadRequest(run(http.MethodPatch, web.ServiceBrokersURL+"/broker-1", `{"name": "broker_1"}`))
+		expectBadRequest(run(http.MethodPost, web.ServiceBrokersURL, `{"name": "1broker"}`))
+	})
+
+	It("rejects invalid IDs chosen by the client", func() {
+		expectBadRequest(run(http.MethodPost, web.PlatformsURL, `{"id": "Platform.1", "name": "platform"}`))
+	})
+
+	It("takes the ID of upserts from the path", func() {
+		expectBadRequest(run(http.MethodPut, web.ServiceBrokersURL+"/Broker.1", `{"name": "broker"}`))
+		Expect(run(http.MethodPut, web.ServiceBrokersURL+"/broker-1", `{"name": "broker"}`)).To(Succeed())
+	})
+
+	It("does not check the names of updates which keep them", func() {
+		Expect(run(http.MethodPatch, web.ServiceBrokersURL+"/broker-1", `{"description": "desc"}`)).To(Succeed())
+	})
+
+	It("rejects invalid patterns in the settings", func() {
+		settings.NamePattern = "[a-z"
+		Expect(settings.Validate()).To(HaveOccurred())
+	})
+})
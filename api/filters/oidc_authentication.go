@@ -29,12 +29,10 @@ import (
 // BearerAuthnFilterName is the name of the bearer authentication filter
 const BearerAuthnFilterName string = "BearerAuthnFilter"
 
-// NewOIDCAuthnFilter returns a web.Filter for Bearer authentication
-func NewOIDCAuthnFilter(ctx context.Context, tokenIssuer, clientID string) (*filters.AuthenticationFilter, error) {
-	authenticator, _, err := authenticators.NewOIDCAuthenticator(ctx, &authenticators.OIDCOptions{
-		IssuerURL: tokenIssuer,
-		ClientID:  clientID,
-	})
+// NewOIDCAuthnFilter returns a web.Filter for Bearer authentication which calls the token issuer with the client
+// of the options
+func NewOIDCAuthnFilter(ctx context.Context, options *authenticators.OIDCOptions) (*filters.AuthenticationFilter, error) {
+	authenticator, _, err := authenticators.NewOIDCAuthenticator(ctx, options)
 	if err != nil {
 		return nil, err
 	}
@@ -50,9 +48,19 @@ func oidcAuthnMatchers() []web.FilterMatcher {
 				web.Path(
 					web.ServiceBrokersURL+"/**",
 					web.PlatformsURL+"/**",
+					web.PeersURL+"/**",
 					web.ServiceOfferingsURL+"/**",
 					web.ServicePlansURL+"/**",
 					web.VisibilitiesURL+"/**",
+					web.VisibilityPoliciesURL+"/**",
+					web.CompositeBrokersURL+"/**",
+					web.SavedQueriesURL+"/**",
+					web.OperationsURL+"/**",
+					web.AdminURL+"/**",
+					web.TenantKeysURL+"/**",
+					web.MonitorStorageURL,
+					web.GraphQLURL,
+					web.ChangesURL,
 				),
 			},
 		},
@@ -25,7 +25,7 @@ import (
 
 const PatchOnlyLabelsFilterName = "PatchOnlyLabelsFilter"
 
-// PatchOnlyLabelsFilter checks patch request for service offerings and plans include only label changes
+// PatchOnlyLabelsFilter checks patch request for service offerings and plans include only label and lifecycle changes
 type PatchOnlyLabelsFilter struct {
 }
 
@@ -34,13 +34,18 @@ func (*PatchOnlyLabelsFilter) Name() string {
 }
 
 func (*PatchOnlyLabelsFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
-	jsonMap := gjson.ParseBytes(req.Body).Map()
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	jsonMap := gjson.ParseBytes(body).Map()
 	delete(jsonMap, "labels")
+	delete(jsonMap, "lifecycle")
 
 	if len(jsonMap) > 0 {
 		return nil, &util.HTTPError{
 			ErrorType:   "BadRequest",
-			Description: "Only labels can be patched for service offerings and plans",
+			Description: "Only labels and the lifecycle can be patched for service offerings and plans",
 			StatusCode:  http.StatusBadRequest,
 		}
 	}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/tidwall/gjson"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const PlanSchemasFilterName = "PlanSchemasFilter"
+
+// PlanSchemasFilter validates the parameters of provision, update and bind requests against the schemas of the
+// requested plan and rejects violations before the request reaches the broker. Requests without parameters,
+// for unknown plans or for plans without a schema for the operation are passed through to the broker.
+type PlanSchemasFilter struct {
+	Repository storage.Repository
+
+	// BrokerFetcher fetches the brokers of the requests, e.g. from the broker cache. The brokers are fetched from the
+	// repository if it is nil.
+	BrokerFetcher osb.BrokerFetcherFunc
+}
+
+func (*PlanSchemasFilter) Name() string {
+	return PlanSchemasFilterName
+}
+
+func (f *PlanSchemasFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	parameters := gjson.GetBytes(body, "parameters")
+	if !parameters.Exists() {
+		return next.Handle(req)
+	}
+	planID := gjson.GetBytes(body, "plan_id").String()
+	if planID == "" {
+		planID = gjson.GetBytes(body, "previous_values.plan_id").String()
+	}
+	if planID == "" {
+		return next.Handle(req)
+	}
+
+	brokerID := req.PathParams[osb.BrokerIDPathParam]
+	offerings, err := catalog.Load(req.Context(), brokerID, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := catalogNamespace(req.Context(), brokerID, f.BrokerFetcher, f.Repository)
+	if err != nil {
+		return nil, err
+	}
+	_, plansByCatalogID := catalogIndex(offerings)
+	plan, found := plansByCatalogID[namespace.Remove(planID, knownPlan(plansByCatalogID))]
+	if !found {
+		return next.Handle(req)
+	}
+
+	schemaPath := schemaPathFor(req)
+	schema := gjson.GetBytes(plan.Schemas, schemaPath)
+	if !schema.Exists() {
+		return next.Handle(req)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(schema.Raw), gojsonschema.NewStringLoader(parameters.Raw))
+	if err != nil {
+		// an invalid schema is the responsibility of the broker, which receives the request as it is
+		log.C(req.Context()).WithError(err).Warnf("Could not validate parameters against schema %s of service plan %s", schemaPath, plan.Name)
+		return next.Handle(req)
+	}
+	if !result.Valid() {
+		violations := make([]string, 0, len(result.Errors()))
+		for _, violation := range result.Errors() {
+			violations = append(violations, violation.String())
+		}
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("parameters do not match the schema of service plan %s: %s", plan.Name, strings.Join(violations, "; ")),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return next.Handle(req)
+}
+
+// schemaPathFor returns the path of the parameters schema of the operation in the plan schemas
+func schemaPathFor(req *web.Request) string {
+	switch {
+	case strings.Contains(req.URL.Path, "/service_bindings/"):
+		return "service_binding.create.parameters"
+	case req.Method == http.MethodPatch:
+		return "service_instance.update.parameters"
+	default:
+		return "service_instance.create.parameters"
+	}
+}
+
+func (*PlanSchemasFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/service_instances/*"),
+				web.Methods(http.MethodPut, http.MethodPatch),
+			},
+		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/service_instances/*/service_bindings/*"),
+				web.Methods(http.MethodPut),
+			},
+		},
+	}
+}
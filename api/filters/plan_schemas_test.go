@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan schemas filter", func() {
+	const schemas = `{
+		"service_instance": {
+			"create": {"parameters": {"type": "object", "properties": {"size": {"type": "integer"}}, "required": ["size"]}},
+			"update": {"parameters": {"type": "object", "properties": {"size": {"type": "integer", "maximum": 10}}}}
+		},
+		"service_binding": {
+			"create": {"parameters": {"type": "object", "additionalProperties": false}}
+		}
+	}`
+
+	var (
+		fakeRepository *storagefakes.FakeStorage
+		filter         *PlanSchemasFilter
+	)
+
+	run := func(method, path, body string) (*web.Response, error) {
+		httpRequest, err := http.NewRequest(method, "https://example.com/v1/osb/broker-id/v2/"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		request := &web.Request{
+			Request:    httpRequest,
+			PathParams: map[string]string{osb.BrokerIDPathParam: "broker-id"},
+			Body:       []byte(body),
+		}
+		return filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+	}
+
+	expectPassed := func(response *web.Response, err error) {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	expectRejected := func(err error, violation string) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(err.(*util.HTTPError).Description).To(ContainSubstring(violation))
+	}
+
+	BeforeEach(func() {
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.ServiceOfferingType {
+				return &types.ServiceOfferings{
+					ServiceOfferings: []*types.ServiceOffering{
+						{Base: types.Base{ID: "service-id"}, CatalogID: "service-catalog-id", Name: "service"},
+					},
+				}, nil
+			}
+			return &types.ServicePlans{
+				ServicePlans: []*types.ServicePlan{
+					{CatalogID: "schema-plan-catalog-id", ServiceOfferingID: "service-id", Name: "with-schema", Schemas: json.RawMessage(schemas)},
+					{CatalogID: "plain-plan-catalog-id", ServiceOfferingID: "service-id", Name: "without-schema"},
+				},
+			}, nil
+		}
+		filter = &PlanSchemasFilter{Repository: fakeRepository}
+	})
+
+	It("passes requests without parameters through", func() {
+		expectPassed(run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "schema-plan-catalog-id"}`))
+		Expect(fakeRepository.ListCallCount()).To(Equal(0))
+	})
+
+	It("passes provision requests with valid parameters through", func() {
+		expectPassed(run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "schema-plan-catalog-id", "parameters": {"size": 3}}`))
+	})
+
+	It("rejects provision requests with invalid parameters", func() {
+		_, err := run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "schema-plan-catalog-id", "parameters": {"size": "large"}}`)
+		expectRejected(err, "size")
+	})
+
+	It("rejects provision requests without required parameters", func() {
+		_, err := run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "schema-plan-catalog-id", "parameters": {}}`)
+		expectRejected(err, "size is required")
+	})
+
+	It("validates updates against the update schema of the plan from the previous values", func() {
+		expectPassed(run(http.MethodPatch, "service_instances/instance-id", `{"previous_values": {"plan_id": "schema-plan-catalog-id"}, "parameters": {}}`))
+		_, err := run(http.MethodPatch, "service_instances/instance-id", `{"previous_values": {"plan_id": "schema-plan-catalog-id"}, "parameters": {"size": 20}}`)
+		expectRejected(err, "size")
+	})
+
+	It("validates bind requests against the binding schema", func() {
+		_, err := run(http.MethodPut, "service_instances/instance-id/service_bindings/binding-id", `{"plan_id": "schema-plan-catalog-id", "parameters": {"size": 3}}`)
+		expectRejected(err, "Additional property size is not allowed")
+	})
+
+	It("validates requests for plans with prefixed catalog IDs", func() {
+		fakeRepository.GetReturns(&types.ServiceBroker{
+			Base: types.Base{ID: "broker-id", Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}},
+		}, nil)
+		_, err := run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "acme-schema-plan-catalog-id", "parameters": {"size": "large"}}`)
+		expectRejected(err, "size")
+	})
+
+	It("passes requests for plans without schemas through", func() {
+		expectPassed(run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "plain-plan-catalog-id", "parameters": {"size": "large"}}`))
+	})
+
+	It("passes requests for unknown plans through", func() {
+		expectPassed(run(http.MethodPut, "service_instances/instance-id", `{"plan_id": "unknown-plan-catalog-id", "parameters": {"size": "large"}}`))
+	})
+})
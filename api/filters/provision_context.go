@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/sjson"
+)
+
+const ProvisionContextFilterName = "ProvisionContextFilter"
+
+// ProvisionContextFilter adds selected labels of the calling platform, e.g. its tenant or cost center, to the context
+// of the provision requests forwarded to brokers, so that brokers can do chargeback without out of band data.
+// The labels overwrite context fields with the same names sent by the platform.
+type ProvisionContextFilter struct {
+	Mappings map[string]string
+}
+
+func (*ProvisionContextFilter) Name() string {
+	return ProvisionContextFilterName
+}
+
+func (f *ProvisionContextFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	user, ok := web.UserFromContext(ctx)
+	if !ok {
+		return nil, errors.New("user details not found in request context")
+	}
+	platform := &types.Platform{}
+	if err := user.Data.Data(platform); err != nil {
+		return nil, err
+	}
+	if platform.ID == "" {
+		return next.Handle(req)
+	}
+
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for label, field := range f.Mappings {
+		values, found := platform.Labels[label]
+		if !found || len(values) == 0 {
+			continue
+		}
+		var value interface{} = values
+		if len(values) == 1 {
+			value = values[0]
+		}
+		if body, err = sjson.SetBytes(body, "context."+pathEscaper.Replace(field), value); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if changed {
+		log.C(ctx).Debugf("Added labels of platform %s to the context of the provision request", platform.Name)
+		req.SetBody(body)
+	}
+	return next.Handle(req)
+}
+
+func (*ProvisionContextFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/*/v2/service_instances/*"),
+				web.Methods(http.MethodPut),
+			},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("Provision context filter", func() {
+	const platform = `{"id": "platform-id", "name": "platform", "labels": {"tenant": ["tenant-a"], "cost_center": ["cc-1", "cc-2"]}}`
+
+	var (
+		filter        *ProvisionContextFilter
+		forwardedBody []byte
+	)
+
+	run := func(user, body string) {
+		httpRequest, err := http.NewRequest(http.MethodPut, "https://example.com/v1/osb/broker-id/v2/service_instances/instance-id", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request := &web.Request{Request: httpRequest, Body: []byte(body)}
+		request.Request = request.WithContext(web.ContextWithUser(request.Context(), &web.UserContext{
+			Name: "platform",
+			Data: &basicAuthnData{data: []byte(user)},
+		}))
+		_, err = filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			forwardedBody, err = req.BodyBytes()
+			return &web.Response{StatusCode: http.StatusCreated}, err
+		}))
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		mappings, err := ParseLabelMappings([]string{"tenant=tenant_id", "cost_center", "missing"})
+		Expect(err).ToNot(HaveOccurred())
+		filter = &ProvisionContextFilter{Mappings: mappings}
+		forwardedBody = nil
+	})
+
+	It("adds the mapped labels of the platform to the context", func() {
+		run(platform, `{"service_id": "service-id", "context": {"platform": "cloudfoundry"}}`)
+
+		Expect(gjson.GetBytes(forwardedBody, "context.platform").String()).To(Equal("cloudfoundry"))
+		Expect(gjson.GetBytes(forwardedBody, "context.tenant_id").String()).To(Equal("tenant-a"))
+		Expect(gjson.GetBytes(forwardedBody, "context.cost_center").Raw).To(MatchJSON(`["cc-1", "cc-2"]`))
+		Expect(gjson.GetBytes(forwardedBody, "context.missing").Exists()).To(BeFalse())
+	})
+
+	It("overwrites context fields sent by the platform", func() {
+		run(platform, `{"context": {"tenant_id": "tenant-b"}}`)
+
+		Expect(gjson.GetBytes(forwardedBody, "context.tenant_id").String()).To(Equal("tenant-a"))
+	})
+
+	It("forwards requests of other users unchanged", func() {
+		run(`{}`, `{"context": {"tenant_id": "tenant-b"}}`)
+
+		Expect(string(forwardedBody)).To(Equal(`{"context": {"tenant_id": "tenant-b"}}`))
+	})
+})
@@ -0,0 +1,437 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/views"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// ResponseCacheFilterName is the name of the response cache filter
+	ResponseCacheFilterName = "ResponseCacheFilter"
+
+	// ResponseCacheHookName is the name of the hooks which flush the response cache on changes in this instance
+	ResponseCacheHookName = "ResponseCacheHook"
+)
+
+// ResponseCachedTypes are the types of the objects whose changes affect the cached responses
+var ResponseCachedTypes = []types.ObjectType{
+	types.ServiceBrokerType,
+	types.ServiceOfferingType,
+	types.ServicePlanType,
+	types.VisibilityType,
+}
+
+// ResponseCacheSettings type to be loaded from the environment
+type ResponseCacheSettings struct {
+	Enabled bool          `mapstructure:"enabled" description:"whether the responses of the service offerings and service plans APIs are cached"`
+	TTL     time.Duration `mapstructure:"ttl" description:"maximum time a response is cached, bounds the staleness of responses which are not invalidated through notifications"`
+}
+
+// DefaultResponseCacheSettings returns default values for the response cache settings
+func DefaultResponseCacheSettings() *ResponseCacheSettings {
+	return &ResponseCacheSettings{
+		Enabled: false,
+		TTL:     time.Minute,
+	}
+}
+
+// Validate validates the response cache settings
+func (s *ResponseCacheSettings) Validate() error {
+	if s.Enabled && s.TTL <= 0 {
+		return fmt.Errorf("validate Settings: response cache TTL (%s) should be greater than 0", s.TTL)
+	}
+	return nil
+}
+
+// responseCacheGenerationKey is the key of the counter which is part of the keys of all cached responses, incrementing
+// it flushes the cached responses of all instances sharing the store
+const responseCacheGenerationKey = "response_cache:generation"
+
+// ResponseCache is a cache of the responses of the service offerings and service plans APIs. The responses are cached
+// per normalized selection criteria and user, as platforms see only the offerings and plans visible to them. They are
+// kept in a cache.Store, which is shared by all instances if it is backed by Redis. All responses are flushed when a
+// broker, service offering, service plan or visibility is changed in this instance, when a notification about one of
+// them is received from the notification stream or when their TTL expires. While the cache is not consuming the
+// notification stream this instance caches nothing so that changes made by other instances are not missed.
+type ResponseCache struct {
+	settings *ResponseCacheSettings
+	store    cache.Store
+
+	mutex     sync.RWMutex
+	entries   int
+	consuming bool
+	revision  int64
+
+	hits, misses, invalidations uint64
+}
+
+// NewResponseCache returns a ResponseCache configured with the provided settings which keeps the responses in the
+// provided store or in memory if it is nil
+func NewResponseCache(settings *ResponseCacheSettings, store cache.Store) *ResponseCache {
+	if settings == nil {
+		settings = DefaultResponseCacheSettings()
+	}
+	if store == nil {
+		store = cache.NewMemoryStore()
+	}
+	return &ResponseCache{
+		settings: settings,
+		store:    store,
+		revision: types.InvalidRevision,
+	}
+}
+
+// Enabled returns whether responses are cached
+func (c *ResponseCache) Enabled() bool {
+	return c != nil && c.settings.Enabled
+}
+
+// Get returns the response cached with the key
+func (c *ResponseCache) Get(ctx context.Context, key string) (*web.Response, bool) {
+	if !c.Enabled() {
+		return nil, false
+	}
+
+	response, err := c.get(ctx, key)
+	if err != nil {
+		log.C(ctx).WithError(err).Warn("Could not get cached response")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if response == nil {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return response, true
+}
+
+func (c *ResponseCache) get(ctx context.Context, key string) (*web.Response, error) {
+	storeKey, err := c.storeKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	value, found, err := c.store.Get(ctx, storeKey)
+	if err != nil || !found {
+		return nil, err
+	}
+	response := &web.Response{}
+	if err := json.Unmarshal(value, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Put caches the response with the key. Nothing is cached while the notification stream is not consumed.
+func (c *ResponseCache) Put(ctx context.Context, key string, response *web.Response) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mutex.RLock()
+	consuming := c.consuming
+	c.mutex.RUnlock()
+	if !consuming {
+		return
+	}
+
+	if err := c.put(ctx, key, response); err != nil {
+		log.C(ctx).WithError(err).Warn("Could not cache response")
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries++
+}
+
+func (c *ResponseCache) put(ctx context.Context, key string, response *web.Response) error {
+	storeKey, err := c.storeKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, storeKey, value, c.settings.TTL)
+}
+
+// storeKey returns the key of the response in the store, which contains the current generation of the cache
+func (c *ResponseCache) storeKey(ctx context.Context, key string) (string, error) {
+	generation, found, err := c.store.Get(ctx, responseCacheGenerationKey)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		generation = []byte("0")
+	}
+	hash := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("response_cache:%s:%s", generation, hex.EncodeToString(hash[:])), nil
+}
+
+// Flush removes all cached responses
+func (c *ResponseCache) Flush(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	if _, err := c.store.Increment(ctx, responseCacheGenerationKey, 0); err != nil {
+		log.C(ctx).WithError(err).Warn("Could not flush response cache")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries > 0 {
+		c.invalidations++
+	}
+	c.entries = 0
+}
+
+// Statistics returns the usage statistics of the cache
+func (c *ResponseCache) Statistics() storage.CacheStatistics {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	statistics := storage.CacheStatistics{
+		Entries:       c.entries,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Invalidations: c.invalidations,
+		Revision:      c.revision,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		statistics.HitRate = float64(c.hits) / float64(lookups)
+	}
+	return statistics
+}
+
+// Name implements health.Indicator and returns the name of the response cache component
+func (c *ResponseCache) Name() string {
+	return "response_cache"
+}
+
+// Health implements health.Indicator and reports the response cache statistics
+func (c *ResponseCache) Health() *health.Health {
+	healthz := health.New().Up()
+	if !c.settings.Enabled {
+		return healthz.WithDetail("enabled", false)
+	}
+	return healthz.WithDetail("enabled", true).WithDetail("statistics", c.Statistics())
+}
+
+// Hooks returns the hooks which flush the cached responses when objects of the specified type are changed in this instance
+func (c *ResponseCache) Hooks() (*storage.CreateHook, *storage.UpdateHook, *storage.DeleteHook) {
+	createHook := &storage.CreateHook{
+		HookName: ResponseCacheHookName,
+		After: func(ctx context.Context, obj types.Object) error {
+			c.Flush(ctx)
+			return nil
+		},
+	}
+	updateHook := &storage.UpdateHook{
+		HookName: ResponseCacheHookName,
+		After: func(ctx context.Context, obj types.Object) error {
+			c.Flush(ctx)
+			return nil
+		},
+	}
+	deleteHook := &storage.DeleteHook{
+		HookName: ResponseCacheHookName,
+		After: func(ctx context.Context, objects types.ObjectList) error {
+			c.Flush(ctx)
+			return nil
+		},
+	}
+	return createHook, updateHook, deleteHook
+}
+
+// Start consumes the notification stream and flushes the cached responses when a notification about a broker, service
+// offering, service plan or visibility is received. If the notification queue is closed the cache is flushed and the
+// consumer is registered again.
+func (c *ResponseCache) Start(ctx context.Context, notificator storage.Notificator, group *sync.WaitGroup) error {
+	if !c.Enabled() {
+		return nil
+	}
+	util.StartInWaitGroupWithContext(ctx, func(ctx context.Context) {
+		consumer := &types.Platform{
+			Base: types.Base{
+				ID: "service-manager-response-cache",
+			},
+			Name: "service-manager-response-cache",
+		}
+		for {
+			c.consume(ctx, notificator, consumer)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}, group)
+	return nil
+}
+
+func (c *ResponseCache) consume(ctx context.Context, notificator storage.Notificator, consumer *types.Platform) {
+	queue, revision, err := notificator.RegisterConsumer(consumer, types.InvalidRevision)
+	if err != nil {
+		log.C(ctx).WithError(err).Debug("Could not register response cache as notification consumer")
+		return
+	}
+	defer func() {
+		if err := notificator.UnregisterConsumer(queue); err != nil {
+			log.C(ctx).WithError(err).Warn("Could not unregister response cache notification consumer")
+		}
+	}()
+	c.setConsuming(ctx, true, revision)
+	defer c.setConsuming(ctx, false, types.InvalidRevision)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-queue.Channel():
+			if !ok {
+				log.C(ctx).Info("Response cache notification queue closed, flushing response cache")
+				return
+			}
+			c.process(ctx, notification)
+		}
+	}
+}
+
+func (c *ResponseCache) setConsuming(ctx context.Context, consuming bool, revision int64) {
+	c.Flush(ctx)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.consuming = consuming
+	c.revision = revision
+}
+
+func (c *ResponseCache) process(ctx context.Context, notification *types.Notification) {
+	for _, objectType := range ResponseCachedTypes {
+		if notification.Resource == objectType {
+			c.Flush(ctx)
+			break
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if notification.Revision > c.revision {
+		c.revision = notification.Revision
+	}
+}
+
+// ResponseCacheFilter serves the responses of the service offerings and service plans APIs from the response cache
+type ResponseCacheFilter struct {
+	Cache *ResponseCache
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*ResponseCacheFilter) Name() string {
+	return ResponseCacheFilterName
+}
+
+// Run returns the cached response of the request, if any, and caches successful responses otherwise
+func (f *ResponseCacheFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if !f.Cache.Enabled() {
+		return next.Handle(req)
+	}
+
+	ctx := req.Context()
+	key := responseCacheKey(req)
+	if response, found := f.Cache.Get(ctx, key); found {
+		return response, nil
+	}
+
+	response, err := next.Handle(req)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusOK {
+		f.Cache.Put(ctx, key, response)
+	}
+	return response, nil
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*ResponseCacheFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceOfferingsURL + "/**"),
+				web.Methods(http.MethodGet),
+			},
+		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServicePlansURL + "/**"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
+
+// responseCacheKey identifies the response of the request by its path, its selection criteria independent of their
+// order, its remaining query parameters, its requested view and its user
+func responseCacheKey(req *web.Request) string {
+	criteria := query.Normalize(query.CriteriaForContext(req.Context()))
+	normalizedCriteria := make([]string, 0, len(criteria))
+	for _, criterion := range criteria {
+		normalizedCriteria = append(normalizedCriteria, fmt.Sprintf("%s|%s|%s|%s",
+			criterion.Type, criterion.LeftOp, criterion.Operator, strings.Join(criterion.RightOp, ",")))
+	}
+
+	parameters := url.Values{}
+	for name, values := range req.URL.Query() {
+		if name == string(query.FieldQuery) || name == string(query.LabelQuery) {
+			continue
+		}
+		values = append([]string{}, values...)
+		sort.Strings(values)
+		parameters[name] = values
+	}
+
+	user := ""
+	if userContext, found := web.UserFromContext(req.Context()); found {
+		user = userContext.Name
+	}
+	return strings.Join([]string{req.URL.Path, strings.Join(normalizedCriteria, ";"), parameters.Encode(), views.RequestedView(req.Request), user}, "\n")
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Response cache filter", func() {
+	var (
+		ctx          context.Context
+		cancel       context.CancelFunc
+		wg           *sync.WaitGroup
+		settings     *ResponseCacheSettings
+		cache        *ResponseCache
+		filter       *ResponseCacheFilter
+		notificator  *storagefakes.FakeNotificator
+		channel      chan *types.Notification
+		handlerCalls int
+		statusCode   int
+	)
+
+	run := func(user string, criteria ...query.Criterion) *web.Response {
+		httpRequest, err := http.NewRequest(http.MethodGet, "https://example.com"+web.ServicePlansURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		requestContext := query.ContextWithCriteria(context.Background(), criteria)
+		if user != "" {
+			requestContext = web.ContextWithUser(requestContext, &web.UserContext{Name: user})
+		}
+		request := &web.Request{Request: httpRequest.WithContext(requestContext)}
+		response, err := filter.Run(request, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			handlerCalls++
+			return &web.Response{StatusCode: statusCode, Body: []byte(`{"items":[]}`)}, nil
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		settings = DefaultResponseCacheSettings()
+		settings.Enabled = true
+		channel = make(chan *types.Notification)
+		queue := &storagefakes.FakeNotificationQueue{}
+		queue.ChannelReturns(channel)
+		notificator = &storagefakes.FakeNotificator{}
+		notificator.RegisterConsumerReturns(queue, 5, nil)
+		handlerCalls = 0
+		statusCode = http.StatusOK
+	})
+
+	JustBeforeEach(func() {
+		cache = NewResponseCache(settings, nil)
+		filter = &ResponseCacheFilter{Cache: cache}
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	It("is disabled by default", func() {
+		Expect(DefaultResponseCacheSettings().Enabled).To(BeFalse())
+		Expect(DefaultResponseCacheSettings().Validate()).To(Succeed())
+	})
+
+	Context("when the notification stream is not consumed", func() {
+		It("does not cache responses", func() {
+			run("platform")
+			run("platform")
+			Expect(handlerCalls).To(Equal(2))
+		})
+	})
+
+	Context("when the notification stream is consumed", func() {
+		JustBeforeEach(func() {
+			Expect(cache.Start(ctx, notificator, wg)).To(Succeed())
+			Eventually(func() int {
+				cache.Flush(ctx)
+				handlerCalls = 0
+				run("platform")
+				run("platform")
+				return handlerCalls
+			}).Should(Equal(1))
+		})
+
+		It("caches responses independent of the order of their criteria", func() {
+			byName := query.ByField(query.EqualsOperator, "name", "small")
+			byLabel := query.ByLabel(query.InOperator, "region", "eu", "us")
+			run("platform", byName, byLabel)
+			response := run("platform", query.ByLabel(query.InOperator, "region", "us", "eu"), byName)
+			Expect(handlerCalls).To(Equal(2))
+			Expect(string(response.Body)).To(Equal(`{"items":[]}`))
+		})
+
+		It("caches responses per user", func() {
+			run("other-platform")
+			Expect(handlerCalls).To(Equal(2))
+		})
+
+		It("does not cache failed responses", func() {
+			statusCode = http.StatusInternalServerError
+			run("platform", query.ByField(query.EqualsOperator, "name", "small"))
+			run("platform", query.ByField(query.EqualsOperator, "name", "small"))
+			Expect(handlerCalls).To(Equal(3))
+		})
+
+		It("flushes the responses on notifications about the objects they are based on", func() {
+			channel <- &types.Notification{Resource: types.PlatformType, Revision: 6}
+			Eventually(func() int64 { return cache.Statistics().Revision }).Should(Equal(int64(6)))
+			Expect(cache.Statistics().Entries).To(Equal(1))
+
+			channel <- &types.Notification{Resource: types.VisibilityType, Revision: 7}
+			Eventually(func() int { return cache.Statistics().Entries }).Should(Equal(0))
+			run("platform")
+			Expect(handlerCalls).To(Equal(2))
+		})
+
+		It("flushes the responses on changes through the hooks", func() {
+			_, updateHook, _ := cache.Hooks()
+			Expect(updateHook.After(ctx, &types.ServicePlan{})).To(Succeed())
+			run("platform")
+			Expect(handlerCalls).To(Equal(2))
+			Expect(cache.Statistics().Invalidations).To(BeNumerically(">", 0))
+		})
+
+		It("shares the responses with the caches of other instances using the same store", func() {
+			other := NewResponseCache(settings, cache.store)
+			filter = &ResponseCacheFilter{Cache: other}
+			run("platform")
+			Expect(handlerCalls).To(Equal(1))
+
+			other.Flush(ctx)
+			filter = &ResponseCacheFilter{Cache: cache}
+			run("platform")
+			Expect(handlerCalls).To(Equal(2))
+		})
+
+		It("reports the hit rate", func() {
+			statistics := cache.Statistics()
+			Expect(statistics.Hits).To(BeNumerically(">", 0))
+			Expect(statistics.HitRate).To(BeNumerically("<", 1))
+		})
+	})
+
+	Context("when the cache is disabled", func() {
+		BeforeEach(func() {
+			settings.Enabled = false
+		})
+
+		It("passes all requests through", func() {
+			Expect(cache.Start(ctx, notificator, wg)).To(Succeed())
+			Consistently(notificator.RegisterConsumerCallCount, 100*time.Millisecond).Should(Equal(0))
+			run("platform")
+			run("platform")
+			Expect(handlerCalls).To(Equal(2))
+		})
+	})
+})
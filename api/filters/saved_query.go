@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// SavedQueryFilterName is the name of the saved query filter
+	SavedQueryFilterName = "SavedQueryFilter"
+
+	// SavedQueryParam is the query parameter with which list calls reference a saved query by name
+	SavedQueryParam = "query"
+)
+
+// SavedQueryFilter adds the criteria of the saved query referenced by a list call to the criteria of the request.
+// The own saved queries of the user take precedence over the shared ones with the same name.
+type SavedQueryFilter struct {
+	Repository storage.Repository
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*SavedQueryFilter) Name() string {
+	return SavedQueryFilterName
+}
+
+// Run implements the web.Filter interface and adds the criteria of the referenced saved query to the request context
+func (f *SavedQueryFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	name := req.URL.Query().Get(SavedQueryParam)
+	if name == "" {
+		return next.Handle(req)
+	}
+	ctx := req.Context()
+	user, found := web.UserFromContext(ctx)
+	if !found || user.Name == "" {
+		return nil, security.UnauthorizedHTTPError("saved queries require an authenticated user")
+	}
+
+	objectList, err := f.Repository.List(ctx, types.SavedQueryType,
+		query.ByField(query.EqualsOperator, "name", name), query.VisibleSavedQueries(user.Name))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.SavedQueryType))
+	}
+	var savedQuery *types.SavedQuery
+	for i := 0; i < objectList.Len(); i++ {
+		candidate := objectList.ItemAt(i).(*types.SavedQuery)
+		if savedQuery == nil || !candidate.Shared {
+			savedQuery = candidate
+		}
+	}
+	if savedQuery == nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("saved query %s not found", name),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	criteria, err := query.SavedQueryCriteria(savedQuery)
+	if err != nil {
+		return nil, err
+	}
+	log.C(ctx).Debugf("Adding the criteria of saved query %s (%s) to the request", savedQuery.Name, savedQuery.ID)
+	ctx, err = query.AddCriteria(ctx, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	req.Request = req.WithContext(ctx)
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed
+func (*SavedQueryFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(LowPriorityPaths...),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Saved query filter", func() {
+	var (
+		fakeRepository *storagefakes.FakeStorage
+		filter         *SavedQueryFilter
+		savedQueries   []*types.SavedQuery
+		listCriteria   []query.Criterion
+		nextCriteria   []query.Criterion
+	)
+
+	run := func(url string, user *web.UserContext) error {
+		httpRequest, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		if user != nil {
+			httpRequest = httpRequest.WithContext(web.ContextWithUser(httpRequest.Context(), user))
+		}
+		_, err = filter.Run(&web.Request{Request: httpRequest}, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			nextCriteria = query.CriteriaForContext(req.Context())
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+		return err
+	}
+
+	BeforeEach(func() {
+		savedQueries = nil
+		listCriteria = nil
+		nextCriteria = nil
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			listCriteria = criteria
+			return &types.SavedQueries{SavedQueries: savedQueries}, nil
+		}
+		filter = &SavedQueryFilter{Repository: fakeRepository}
+	})
+
+	It("passes list calls without a saved query through", func() {
+		Expect(run("https://example.com/v1/service_brokers", nil)).To(Succeed())
+		Expect(fakeRepository.ListCallCount()).To(Equal(0))
+		Expect(nextCriteria).To(BeEmpty())
+	})
+
+	It("looks up the saved query among the queries of the user and the shared ones", func() {
+		savedQueries = []*types.SavedQuery{{Name: "dev", LabelQuery: "env = dev", Shared: true}}
+		Expect(run("https://example.com/v1/service_brokers?query=dev", &web.UserContext{Name: "alice"})).To(Succeed())
+		Expect(listCriteria).To(ConsistOf(
+			query.ByField(query.EqualsOperator, "name", "dev"),
+			query.ByField(query.EqualsOrNilOperator, "owner", "alice"),
+		))
+	})
+
+	It("adds the criteria of the saved query", func() {
+		savedQueries = []*types.SavedQuery{{Name: "dev", FieldQuery: "name in [a||b]", LabelQuery: "env = dev", OrderBy: "name", Order: "desc"}}
+		Expect(run("https://example.com/v1/service_brokers?query=dev", &web.UserContext{Name: "alice"})).To(Succeed())
+		Expect(nextCriteria).To(ConsistOf(
+			query.ByField(query.InOperator, "name", "a", "b"),
+			query.ByLabel(query.EqualsOperator, "env", "dev"),
+			query.OrderResultBy("name", query.DescOrder),
+		))
+	})
+
+	It("prefers the own saved query of the user over a shared one with the same name", func() {
+		savedQueries = []*types.SavedQuery{
+			{Name: "dev", LabelQuery: "env = shared", Shared: true},
+			{Name: "dev", LabelQuery: "env = own", Owner: "alice"},
+			{Name: "dev", LabelQuery: "env = other", Shared: true},
+		}
+		Expect(run("https://example.com/v1/service_brokers?query=dev", &web.UserContext{Name: "alice"})).To(Succeed())
+		Expect(nextCriteria).To(ConsistOf(query.ByLabel(query.EqualsOperator, "env", "own")))
+	})
+
+	It("rejects unknown saved queries", func() {
+		err := run("https://example.com/v1/service_brokers?query=missing", &web.UserContext{Name: "alice"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects saved queries without an authenticated user", func() {
+		err := run("https://example.com/v1/service_brokers?query=dev", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
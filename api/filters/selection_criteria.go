@@ -58,7 +58,7 @@ func (*SelectionCriteria) FilterMatchers() []web.FilterMatcher {
 		{
 			Matchers: []web.Matcher{
 				web.Path("/**"),
-				web.Methods(http.MethodGet, http.MethodDelete),
+				web.Methods(http.MethodGet, http.MethodDelete, http.MethodPatch),
 			},
 		},
 	}
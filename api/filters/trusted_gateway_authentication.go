@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security/filters"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// TrustedGatewayAuthnFilterName is the name of the filter which authenticates the users identified by a trusted gateway
+const TrustedGatewayAuthnFilterName string = "TrustedGatewayAuthnFilter"
+
+// TrustedGatewaySettings type to be loaded from the environment
+type TrustedGatewaySettings struct {
+	CIDRs              []string `mapstructure:"cidrs" description:"networks in CIDR notation from which the gateway connects, the identity headers of other clients are ignored"`
+	ClientCertSubjects []string `mapstructure:"client_cert_subjects" description:"common names of the verified client certificates of the gateway, the identity headers of other clients are ignored"`
+	UserHeader         string   `mapstructure:"user_header" description:"header containing the name of the user authenticated by the gateway"`
+	GroupsHeader       string   `mapstructure:"groups_header" description:"header containing the comma separated groups of the user, which are used as the scopes of the user"`
+}
+
+// DefaultTrustedGatewaySettings returns default values for the trusted gateway, which is disabled until its networks
+// or client certificates are configured
+func DefaultTrustedGatewaySettings() *TrustedGatewaySettings {
+	return &TrustedGatewaySettings{
+		CIDRs:              []string{},
+		ClientCertSubjects: []string{},
+		UserHeader:         "X-Forwarded-User",
+		GroupsHeader:       "X-Forwarded-Groups",
+	}
+}
+
+// Enabled returns true if the gateway is identified by its network or its client certificate
+func (s *TrustedGatewaySettings) Enabled() bool {
+	return s != nil && (len(s.CIDRs) != 0 || len(s.ClientCertSubjects) != 0)
+}
+
+// Validate validates the trusted gateway settings
+func (s *TrustedGatewaySettings) Validate() error {
+	if !s.Enabled() {
+		return nil
+	}
+	if _, err := parseCIDRs(s.CIDRs); err != nil {
+		return fmt.Errorf("validate Settings: trusted gateway: %s", err)
+	}
+	if s.UserHeader == "" {
+		return fmt.Errorf("validate Settings: trusted gateway user header missing")
+	}
+	return nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %s", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// NewTrustedGatewayAuthnFilter returns a filter which authenticates the users identified by the headers of a gateway
+// which already authenticated them, e.g. an API gateway in front of the Service Manager. The headers are only trusted
+// on requests from the networks and with the client certificates of the validated settings.
+func NewTrustedGatewayAuthnFilter(settings *TrustedGatewaySettings) *filters.AuthenticationFilter {
+	networks, _ := parseCIDRs(settings.CIDRs)
+	subjects := make(map[string]bool, len(settings.ClientCertSubjects))
+	for _, subject := range settings.ClientCertSubjects {
+		subjects[subject] = true
+	}
+	return filters.NewAuthenticationFilter(&trustedGatewayAuthenticator{
+		networks:     networks,
+		subjects:     subjects,
+		userHeader:   settings.UserHeader,
+		groupsHeader: settings.GroupsHeader,
+	}, TrustedGatewayAuthnFilterName, oidcAuthnMatchers())
+}
+
+// trustedGatewayAuthenticator maps the identity headers of the trusted gateway to the user. The groups are exposed
+// as the scopes of the user, so that they are authorized like the scopes of a token.
+type trustedGatewayAuthenticator struct {
+	networks     []*net.IPNet
+	subjects     map[string]bool
+	userHeader   string
+	groupsHeader string
+}
+
+// trustedGatewayUser is the data of the users authenticated by the trusted gateway
+type trustedGatewayUser struct {
+	UserName string   `json:"user_name"`
+	Groups   []string `json:"groups"`
+	Scope    []string `json:"scope"`
+}
+
+// Authenticate authenticates by using the identity headers of the trusted gateway
+func (a *trustedGatewayAuthenticator) Authenticate(request *http.Request) (*web.UserContext, httpsec.Decision, error) {
+	username := request.Header.Get(a.userHeader)
+	if username == "" {
+		return nil, httpsec.Abstain, nil
+	}
+	if !a.trusted(request) {
+		log.C(request.Context()).Warnf("Ignoring %s header of request from untrusted client %s", a.userHeader, request.RemoteAddr)
+		return nil, httpsec.Abstain, nil
+	}
+
+	groups := []string{}
+	if a.groupsHeader != "" {
+		for _, group := range strings.Split(request.Header.Get(a.groupsHeader), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	bytes, err := json.Marshal(&trustedGatewayUser{UserName: username, Groups: groups, Scope: groups})
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+	return &web.UserContext{
+		Data: &basicAuthnData{
+			data: bytes,
+		},
+		Name: username,
+	}, httpsec.Allow, nil
+}
+
+// trusted returns true if the request comes directly from the gateway. Forwarded addresses are not considered, as
+// they are set by the clients. If both networks and client certificates are configured, both must match.
+func (a *trustedGatewayAuthenticator) trusted(request *http.Request) bool {
+	if len(a.networks) != 0 {
+		host, _, err := net.SplitHostPort(request.RemoteAddr)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !containsIP(a.networks, ip) {
+			return false
+		}
+	}
+	if len(a.subjects) != 0 {
+		if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 {
+			return false
+		}
+		if !a.subjects[request.TLS.VerifiedChains[0][0].Subject.CommonName] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Trusted gateway authenticator", func() {
+	var (
+		settings *TrustedGatewaySettings
+		request  *http.Request
+	)
+
+	authenticate := func() (*trustedGatewayUser, httpsec.Decision) {
+		filter := NewTrustedGatewayAuthnFilter(settings)
+		user, decision, err := filter.Authentication.Authenticator.Authenticate(request)
+		Expect(err).ToNot(HaveOccurred())
+		if user == nil {
+			return nil, decision
+		}
+		data := &trustedGatewayUser{}
+		Expect(user.Data.Data(data)).To(Succeed())
+		Expect(user.Name).To(Equal(data.UserName))
+		return data, decision
+	}
+
+	BeforeEach(func() {
+		settings = DefaultTrustedGatewaySettings()
+		settings.CIDRs = []string{"10.0.0.0/8"}
+
+		var err error
+		request, err = http.NewRequest(http.MethodGet, "https://example.com/v1/service_brokers", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.RemoteAddr = "10.1.2.3:41234"
+		request.Header.Set("X-Forwarded-User", "jdoe")
+		request.Header.Set("X-Forwarded-Groups", "sm.admin, sm.read")
+	})
+
+	It("maps the identity headers of the gateway to the user", func() {
+		user, decision := authenticate()
+		Expect(decision).To(Equal(httpsec.Allow))
+		Expect(user.UserName).To(Equal("jdoe"))
+		Expect(user.Groups).To(Equal([]string{"sm.admin", "sm.read"}))
+		Expect(user.Scope).To(Equal(user.Groups))
+	})
+
+	It("abstains without a user header", func() {
+		request.Header.Del("X-Forwarded-User")
+		_, decision := authenticate()
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	It("ignores the headers of clients outside the networks of the gateway", func() {
+		request.RemoteAddr = "192.168.1.1:41234"
+		request.Header.Set("X-Forwarded-For", "10.1.2.3")
+		user, decision := authenticate()
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	Context("with client certificate subjects", func() {
+		BeforeEach(func() {
+			settings.ClientCertSubjects = []string{"gateway"}
+		})
+
+		verifiedBy := func(commonName string) *tls.ConnectionState {
+			certificate := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+			return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}}
+		}
+
+		It("trusts the gateway with a verified certificate", func() {
+			request.TLS = verifiedBy("gateway")
+			_, decision := authenticate()
+			Expect(decision).To(Equal(httpsec.Allow))
+		})
+
+		It("ignores the headers of clients with other certificates", func() {
+			request.TLS = verifiedBy("client")
+			_, decision := authenticate()
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("ignores the headers of clients without certificates", func() {
+			_, decision := authenticate()
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+	})
+
+	It("rejects invalid networks", func() {
+		settings.CIDRs = []string{"10.0.0.0"}
+		Expect(settings.Validate()).To(HaveOccurred())
+	})
+
+	It("is disabled by default", func() {
+		Expect(DefaultTrustedGatewaySettings().Enabled()).To(BeFalse())
+	})
+})
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/views"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// ViewsFilterName is the name of the views filter
+	ViewsFilterName = "ViewsFilter"
+)
+
+// ViewsFilter selects the view with which the objects of a request are serialized, if the request asks for one
+type ViewsFilter struct {
+	Registry *views.Registry
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*ViewsFilter) Name() string {
+	return ViewsFilterName
+}
+
+// Run stores the requested view in the context of the request, the controllers render the objects with it
+func (f *ViewsFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if name := views.RequestedView(req.Request); name != "" {
+		req.Request = req.WithContext(views.ContextWithView(req.Context(), f.Registry, name))
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*ViewsFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
@@ -17,24 +17,31 @@
 package filters
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/Peripli/service-manager/pkg/platformtypes"
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
 )
 
 const PlatformAwareVisibilityFilterName = "PlatformAwareVisibilityFilter"
 
 type PlatformAwareVisibilityFilter struct {
+	// PlatformTypes restricts the visibilities which apply to the platforms of a type
+	PlatformTypes *platformtypes.Registry
 }
 
 func (*PlatformAwareVisibilityFilter) Name() string {
 	return PlatformAwareVisibilityFilterName
 }
 
-func (*PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+func (f *PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
 	ctx := req.Context()
 	user, ok := web.UserFromContext(ctx)
 	if !ok {
@@ -53,10 +60,52 @@ func (*PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*
 			return nil, err
 		}
 		req.Request = req.WithContext(ctx)
+
+		response, err := next.Handle(req)
+		if err != nil || response.StatusCode != http.StatusOK {
+			return response, err
+		}
+		return f.applicableVisibilities(req, response, p)
 	}
 	return next.Handle(req)
 }
 
+// applicableVisibilities hides the visibilities which are not in effect yet or anymore from platforms,
+// as well as the visibilities which do not apply to the type of the platform
+func (f *PlatformAwareVisibilityFilter) applicableVisibilities(req *web.Request, response *web.Response, platform *types.Platform) (*web.Response, error) {
+	now := time.Now()
+	isApplicable := func(visibility *types.Visibility) bool {
+		return visibility.IsActive(now) && f.PlatformTypes.IsVisible(platform, visibility)
+	}
+	if strings.TrimSuffix(req.URL.Path, "/") == web.VisibilitiesURL {
+		visibilities := &types.Visibilities{}
+		if err := json.Unmarshal(response.Body, visibilities); err != nil {
+			return nil, err
+		}
+		active := make([]*types.Visibility, 0, len(visibilities.Visibilities))
+		for _, visibility := range visibilities.Visibilities {
+			if isApplicable(visibility) {
+				active = append(active, visibility)
+			}
+		}
+		visibilities.Visibilities = active
+		return util.NewJSONResponse(http.StatusOK, visibilities)
+	}
+
+	visibility := &types.Visibility{}
+	if err := json.Unmarshal(response.Body, visibility); err != nil {
+		return nil, err
+	}
+	if !isApplicable(visibility) {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: "visibility not found",
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+	return response, nil
+}
+
 func (*PlatformAwareVisibilityFilter) FilterMatchers() []web.FilterMatcher {
 	return []web.FilterMatcher{
 		{
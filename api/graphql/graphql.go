@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graphql contains logic for the Service Manager GraphQL API which provides read access to the
+// Service Manager resources and their relationships in a single request
+package graphql
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// URL is the path of the GraphQL endpoint
+const URL = web.GraphQLURL
+
+// Routes returns a slice of the routes that handle GraphQL operations
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   URL,
+			},
+			Handler: c.query,
+			Doc: &web.RouteDoc{
+				Summary: "Execute a GraphQL query passed in the query parameter",
+			},
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   URL,
+			},
+			Handler: c.query,
+			Doc: &web.RouteDoc{
+				Summary: "Execute a GraphQL query passed in the request body",
+			},
+		},
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// Controller GraphQL controller
+type Controller struct {
+	schema *gql.Schema
+}
+
+var _ web.Controller = &Controller{}
+
+// NewController returns a GraphQL controller which reads the resources from the provided repository. The fields of
+// the resources are removed according to the field authorization filter, if any, like in the REST API.
+func NewController(repository storage.Repository, fields *filters.FieldAuthorizationFilter) *Controller {
+	return &Controller{
+		schema: gql.MustParseSchema(schema, &resolver{repository: repository, fields: fields}),
+	}
+}
+
+type queryRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (c *Controller) query(r *web.Request) (*web.Response, error) {
+	request := &queryRequest{}
+	if r.Method == http.MethodGet {
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := util.BytesToObject([]byte(variables), &request.Variables); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		body, err := r.BodyBytes()
+		if err != nil {
+			return nil, err
+		}
+		if err := util.BytesToObject(body, request); err != nil {
+			return nil, err
+		}
+	}
+	if request.Query == "" {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "missing GraphQL query",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	// errors of the query are part of the response as mandated by the GraphQL specification
+	response := c.schema.Exec(r.Context(), request.Query, request.OperationName, request.Variables)
+	return util.NewJSONResponse(http.StatusOK, response)
+}
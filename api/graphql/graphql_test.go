@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/api/graphql"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GraphQL Suite")
+}
+
+var _ = Describe("GraphQL API", func() {
+	var (
+		repository *storagefakes.FakeRepository
+		controller *graphql.Controller
+	)
+
+	execute := func(method, q string) *web.Response {
+		var request *web.Request
+		if method == http.MethodGet {
+			request = &web.Request{Request: httptest.NewRequest(method, graphql.URL+"?query="+url.QueryEscape(q), nil)}
+		} else {
+			request = &web.Request{
+				Request: httptest.NewRequest(method, graphql.URL, nil),
+				Body:    []byte(`{"query":` + `"` + q + `"}`),
+			}
+		}
+		for _, route := range controller.Routes() {
+			if route.Endpoint.Method == method {
+				response, err := route.Handler(request)
+				Expect(err).ToNot(HaveOccurred())
+				return response
+			}
+		}
+		Fail("no route for method " + method)
+		return nil
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeRepository{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServiceBrokerType:
+				return &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{
+					{Base: types.Base{ID: "broker-1", Labels: types.Labels{"env": {"dev"}}}, Name: "broker"},
+				}}, nil
+			case types.ServiceOfferingType:
+				return &types.ServiceOfferings{ServiceOfferings: []*types.ServiceOffering{
+					{Base: types.Base{ID: "offering-1"}, Name: "offering", BrokerID: "broker-1"},
+				}}, nil
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: []*types.ServicePlan{
+					{Base: types.Base{ID: "plan-1"}, Name: "plan", ServiceOfferingID: "offering-1"},
+				}}, nil
+			}
+			return &types.Visibilities{}, nil
+		})
+		controller = graphql.NewController(repository, nil)
+	})
+
+	Describe("Routes", func() {
+		It("Returns a GET and a POST route", func() {
+			routes := controller.Routes()
+			Expect(routes).To(HaveLen(2))
+			Expect(routes[0].Endpoint.Path).To(Equal(graphql.URL))
+			Expect(routes[0].Endpoint.Method).To(Equal(http.MethodGet))
+			Expect(routes[1].Endpoint.Method).To(Equal(http.MethodPost))
+		})
+	})
+
+	Describe("query", func() {
+		It("traverses the relationships of the resources", func() {
+			response := execute(http.MethodPost, "{ service_brokers { id labels { key values } service_offerings { name service_plans { name } } } }")
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Body).To(MatchJSON(`{"data":{"service_brokers":[{"id":"broker-1","labels":[{"key":"env","values":["dev"]}],` +
+				`"service_offerings":[{"name":"offering","service_plans":[{"name":"plan"}]}]}]}}`))
+
+			_, objectType, criteria := repository.ListArgsForCall(1)
+			Expect(objectType).To(Equal(types.ServiceOfferingType))
+			Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "broker_id", "broker-1")))
+		})
+
+		It("maps the query arguments to criteria", func() {
+			response := execute(http.MethodGet, `{ service_plans(fieldQuery: "name = plan", labelQuery: "env in [dev||test]") { id } }`)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			_, objectType, criteria := repository.ListArgsForCall(0)
+			Expect(objectType).To(Equal(types.ServicePlanType))
+			Expect(criteria).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "plan"),
+				query.ByLabel(query.InOperator, "env", "dev", "test")))
+		})
+
+		It("returns null for resources which do not exist", func() {
+			repository.GetReturns(nil, util.ErrNotFoundInStorage)
+			response := execute(http.MethodGet, `{ platform(id: "missing") { name } }`)
+			Expect(response.Body).To(MatchJSON(`{"data":{"platform":null}}`))
+		})
+
+		It("removes the fields the caller may not see according to the field rules", func() {
+			rules, err := filters.ParseFieldRules([]string{
+				"/v1/service_brokers:broker_url=sm.admin",
+				"/v1/service_brokers:labels.internal.*=sm.admin",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			controller = graphql.NewController(repository, &filters.FieldAuthorizationFilter{Rules: rules})
+			repository.GetReturns(&types.ServiceBroker{
+				Base:      types.Base{ID: "broker-1", Labels: types.Labels{"env": {"dev"}, "internal.owner": {"team"}}},
+				Name:      "broker",
+				BrokerURL: "https://broker.example.com",
+			}, nil)
+			response := execute(http.MethodGet, `{ service_broker(id: "broker-1") { name broker_url labels { key } } }`)
+			Expect(response.Body).To(MatchJSON(`{"data":{"service_broker":{"name":"broker","broker_url":"","labels":[{"key":"env"}]}}}`))
+		})
+
+		It("returns the errors of invalid queries", func() {
+			response := execute(http.MethodGet, `{ service_brokers { unknown } }`)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(response.Body)).To(ContainSubstring(`"errors"`))
+		})
+	})
+})
@@ -0,0 +1,542 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// criteriaArgs are the arguments of the fields which list resources
+type criteriaArgs struct {
+	FieldQuery *string
+	LabelQuery *string
+}
+
+// criteria returns the criteria of the arguments together with the criteria of the relationship being traversed
+func (a *criteriaArgs) criteria(relationship ...query.Criterion) ([]query.Criterion, error) {
+	criteria := relationship
+	for criteriaType, input := range map[query.CriterionType]*string{query.FieldQuery: a.FieldQuery, query.LabelQuery: a.LabelQuery} {
+		if input == nil {
+			continue
+		}
+		parsed, err := query.Parse(criteriaType, *input)
+		if err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, parsed...)
+	}
+	return criteria, nil
+}
+
+type idArgs struct {
+	ID gql.ID
+}
+
+// resolver resolves the fields of the query type
+type resolver struct {
+	repository storage.Repository
+	fields     *filters.FieldAuthorizationFilter
+}
+
+func (r *resolver) list(ctx context.Context, objectType types.ObjectType, args *criteriaArgs, relationship ...query.Criterion) ([]types.Object, error) {
+	criteria, err := args.criteria(relationship...)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := r.repository.List(ctx, objectType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+	result := make([]types.Object, 0, objects.Len())
+	for i := 0; i < objects.Len(); i++ {
+		object, err := r.redact(ctx, objects.ItemAt(i))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, object)
+	}
+	return result, nil
+}
+
+// get returns the object with the provided id or nil if there is no such object
+func (r *resolver) get(ctx context.Context, objectType types.ObjectType, id string) (types.Object, error) {
+	if id == "" {
+		return nil, nil
+	}
+	object, err := r.repository.Get(ctx, objectType, id)
+	if err == util.ErrNotFoundInStorage {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+	return r.redact(ctx, object)
+}
+
+// redact returns a copy of the object without the fields the caller may not see according to the field rules of its
+// resource in the REST API
+func (r *resolver) redact(ctx context.Context, object types.Object) (types.Object, error) {
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	if bytes, err = r.fields.Redact(ctx, filters.ResourceURLs[object.GetType()], bytes); err != nil {
+		return nil, err
+	}
+	redacted := reflect.New(reflect.TypeOf(object).Elem()).Interface().(types.Object)
+	if err := json.Unmarshal(bytes, redacted); err != nil {
+		return nil, err
+	}
+	return redacted, nil
+}
+
+func (r *resolver) ServiceBrokers(ctx context.Context, args criteriaArgs) ([]*brokerResolver, error) {
+	objects, err := r.list(ctx, types.ServiceBrokerType, &args)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*brokerResolver, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, &brokerResolver{resolver: r, broker: object.(*types.ServiceBroker)})
+	}
+	return result, nil
+}
+
+func (r *resolver) ServiceBroker(ctx context.Context, args idArgs) (*brokerResolver, error) {
+	object, err := r.get(ctx, types.ServiceBrokerType, string(args.ID))
+	if object == nil {
+		return nil, err
+	}
+	return &brokerResolver{resolver: r, broker: object.(*types.ServiceBroker)}, nil
+}
+
+func (r *resolver) ServiceOfferings(ctx context.Context, args criteriaArgs) ([]*offeringResolver, error) {
+	return r.offerings(ctx, &args)
+}
+
+func (r *resolver) offerings(ctx context.Context, args *criteriaArgs, relationship ...query.Criterion) ([]*offeringResolver, error) {
+	objects, err := r.list(ctx, types.ServiceOfferingType, args, relationship...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*offeringResolver, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, &offeringResolver{resolver: r, offering: object.(*types.ServiceOffering)})
+	}
+	return result, nil
+}
+
+func (r *resolver) ServiceOffering(ctx context.Context, args idArgs) (*offeringResolver, error) {
+	object, err := r.get(ctx, types.ServiceOfferingType, string(args.ID))
+	if object == nil {
+		return nil, err
+	}
+	return &offeringResolver{resolver: r, offering: object.(*types.ServiceOffering)}, nil
+}
+
+func (r *resolver) ServicePlans(ctx context.Context, args criteriaArgs) ([]*planResolver, error) {
+	return r.plans(ctx, &args)
+}
+
+func (r *resolver) plans(ctx context.Context, args *criteriaArgs, relationship ...query.Criterion) ([]*planResolver, error) {
+	objects, err := r.list(ctx, types.ServicePlanType, args, relationship...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*planResolver, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, &planResolver{resolver: r, plan: object.(*types.ServicePlan)})
+	}
+	return result, nil
+}
+
+func (r *resolver) ServicePlan(ctx context.Context, args idArgs) (*planResolver, error) {
+	object, err := r.get(ctx, types.ServicePlanType, string(args.ID))
+	if object == nil {
+		return nil, err
+	}
+	return &planResolver{resolver: r, plan: object.(*types.ServicePlan)}, nil
+}
+
+func (r *resolver) Visibilities(ctx context.Context, args criteriaArgs) ([]*visibilityResolver, error) {
+	return r.visibilities(ctx, &args)
+}
+
+func (r *resolver) visibilities(ctx context.Context, args *criteriaArgs, relationship ...query.Criterion) ([]*visibilityResolver, error) {
+	objects, err := r.list(ctx, types.VisibilityType, args, relationship...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*visibilityResolver, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, &visibilityResolver{resolver: r, visibility: object.(*types.Visibility)})
+	}
+	return result, nil
+}
+
+func (r *resolver) Visibility(ctx context.Context, args idArgs) (*visibilityResolver, error) {
+	object, err := r.get(ctx, types.VisibilityType, string(args.ID))
+	if object == nil {
+		return nil, err
+	}
+	return &visibilityResolver{resolver: r, visibility: object.(*types.Visibility)}, nil
+}
+
+func (r *resolver) Platforms(ctx context.Context, args criteriaArgs) ([]*platformResolver, error) {
+	objects, err := r.list(ctx, types.PlatformType, &args)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*platformResolver, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, &platformResolver{resolver: r, platform: object.(*types.Platform)})
+	}
+	return result, nil
+}
+
+func (r *resolver) Platform(ctx context.Context, args idArgs) (*platformResolver, error) {
+	object, err := r.get(ctx, types.PlatformType, string(args.ID))
+	if object == nil {
+		return nil, err
+	}
+	return &platformResolver{resolver: r, platform: object.(*types.Platform)}, nil
+}
+
+// baseResolver resolves the fields common to all resources
+type baseResolver struct {
+	base *types.Base
+}
+
+func (b baseResolver) ID() gql.ID {
+	return gql.ID(b.base.ID)
+}
+
+func (b baseResolver) CreatedAt() string {
+	return b.base.CreatedAt.Format(time.RFC3339Nano)
+}
+
+func (b baseResolver) UpdatedAt() string {
+	return b.base.UpdatedAt.Format(time.RFC3339Nano)
+}
+
+func (b baseResolver) Labels() []*labelResolver {
+	keys := make([]string, 0, len(b.base.Labels))
+	for key := range b.base.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*labelResolver, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, &labelResolver{key: key, values: b.base.Labels[key]})
+	}
+	return result
+}
+
+type labelResolver struct {
+	key    string
+	values []string
+}
+
+func (l *labelResolver) Key() string {
+	return l.key
+}
+
+func (l *labelResolver) Values() []string {
+	return l.values
+}
+
+type brokerResolver struct {
+	*resolver
+	broker *types.ServiceBroker
+}
+
+func (b *brokerResolver) ID() gql.ID {
+	return baseResolver{&b.broker.Base}.ID()
+}
+
+func (b *brokerResolver) Name() string {
+	return b.broker.Name
+}
+
+func (b *brokerResolver) Description() string {
+	return b.broker.Description
+}
+
+func (b *brokerResolver) BrokerURL() string {
+	return b.broker.BrokerURL
+}
+
+func (b *brokerResolver) CreatedAt() string {
+	return baseResolver{&b.broker.Base}.CreatedAt()
+}
+
+func (b *brokerResolver) UpdatedAt() string {
+	return baseResolver{&b.broker.Base}.UpdatedAt()
+}
+
+func (b *brokerResolver) Labels() []*labelResolver {
+	return baseResolver{&b.broker.Base}.Labels()
+}
+
+func (b *brokerResolver) ServiceOfferings(ctx context.Context, args criteriaArgs) ([]*offeringResolver, error) {
+	return b.offerings(ctx, &args, query.ByField(query.EqualsOperator, "broker_id", b.broker.ID))
+}
+
+type offeringResolver struct {
+	*resolver
+	offering *types.ServiceOffering
+}
+
+func (o *offeringResolver) ID() gql.ID {
+	return baseResolver{&o.offering.Base}.ID()
+}
+
+func (o *offeringResolver) Name() string {
+	return o.offering.Name
+}
+
+func (o *offeringResolver) Description() string {
+	return o.offering.Description
+}
+
+func (o *offeringResolver) Bindable() bool {
+	return o.offering.Bindable
+}
+
+func (o *offeringResolver) InstancesRetrievable() bool {
+	return o.offering.InstancesRetrievable
+}
+
+func (o *offeringResolver) BindingsRetrievable() bool {
+	return o.offering.BindingsRetrievable
+}
+
+func (o *offeringResolver) PlanUpdateable() bool {
+	return o.offering.PlanUpdatable
+}
+
+func (o *offeringResolver) Tags() *string {
+	return rawJSON(o.offering.Tags)
+}
+
+func (o *offeringResolver) Requires() *string {
+	return rawJSON(o.offering.Requires)
+}
+
+func (o *offeringResolver) Metadata() *string {
+	return rawJSON(o.offering.Metadata)
+}
+
+func (o *offeringResolver) CatalogID() string {
+	return o.offering.CatalogID
+}
+
+func (o *offeringResolver) CatalogName() string {
+	return o.offering.CatalogName
+}
+
+func (o *offeringResolver) BrokerID() gql.ID {
+	return gql.ID(o.offering.BrokerID)
+}
+
+func (o *offeringResolver) CreatedAt() string {
+	return baseResolver{&o.offering.Base}.CreatedAt()
+}
+
+func (o *offeringResolver) UpdatedAt() string {
+	return baseResolver{&o.offering.Base}.UpdatedAt()
+}
+
+func (o *offeringResolver) Labels() []*labelResolver {
+	return baseResolver{&o.offering.Base}.Labels()
+}
+
+func (o *offeringResolver) ServiceBroker(ctx context.Context) (*brokerResolver, error) {
+	return o.resolver.ServiceBroker(ctx, idArgs{ID: gql.ID(o.offering.BrokerID)})
+}
+
+func (o *offeringResolver) ServicePlans(ctx context.Context, args criteriaArgs) ([]*planResolver, error) {
+	return o.plans(ctx, &args, query.ByField(query.EqualsOperator, "service_offering_id", o.offering.ID))
+}
+
+type planResolver struct {
+	*resolver
+	plan *types.ServicePlan
+}
+
+func (p *planResolver) ID() gql.ID {
+	return baseResolver{&p.plan.Base}.ID()
+}
+
+func (p *planResolver) Name() string {
+	return p.plan.Name
+}
+
+func (p *planResolver) Description() string {
+	return p.plan.Description
+}
+
+func (p *planResolver) CatalogID() string {
+	return p.plan.CatalogID
+}
+
+func (p *planResolver) CatalogName() string {
+	return p.plan.CatalogName
+}
+
+func (p *planResolver) Free() bool {
+	return p.plan.Free
+}
+
+func (p *planResolver) Bindable() bool {
+	return p.plan.Bindable
+}
+
+func (p *planResolver) PlanUpdateable() bool {
+	return p.plan.PlanUpdatable
+}
+
+func (p *planResolver) Metadata() *string {
+	return rawJSON(p.plan.Metadata)
+}
+
+func (p *planResolver) Schemas() *string {
+	return rawJSON(p.plan.Schemas)
+}
+
+func (p *planResolver) ServiceOfferingID() gql.ID {
+	return gql.ID(p.plan.ServiceOfferingID)
+}
+
+func (p *planResolver) CreatedAt() string {
+	return baseResolver{&p.plan.Base}.CreatedAt()
+}
+
+func (p *planResolver) UpdatedAt() string {
+	return baseResolver{&p.plan.Base}.UpdatedAt()
+}
+
+func (p *planResolver) Labels() []*labelResolver {
+	return baseResolver{&p.plan.Base}.Labels()
+}
+
+func (p *planResolver) ServiceOffering(ctx context.Context) (*offeringResolver, error) {
+	return p.resolver.ServiceOffering(ctx, idArgs{ID: gql.ID(p.plan.ServiceOfferingID)})
+}
+
+func (p *planResolver) Visibilities(ctx context.Context, args criteriaArgs) ([]*visibilityResolver, error) {
+	return p.visibilities(ctx, &args, query.ByField(query.EqualsOperator, "service_plan_id", p.plan.ID))
+}
+
+type visibilityResolver struct {
+	*resolver
+	visibility *types.Visibility
+}
+
+func (v *visibilityResolver) ID() gql.ID {
+	return baseResolver{&v.visibility.Base}.ID()
+}
+
+// PlatformID returns nil for public visibilities which are not bound to a platform
+func (v *visibilityResolver) PlatformID() *gql.ID {
+	if v.visibility.PlatformID == "" {
+		return nil
+	}
+	id := gql.ID(v.visibility.PlatformID)
+	return &id
+}
+
+func (v *visibilityResolver) ServicePlanID() gql.ID {
+	return gql.ID(v.visibility.ServicePlanID)
+}
+
+func (v *visibilityResolver) CreatedAt() string {
+	return baseResolver{&v.visibility.Base}.CreatedAt()
+}
+
+func (v *visibilityResolver) UpdatedAt() string {
+	return baseResolver{&v.visibility.Base}.UpdatedAt()
+}
+
+func (v *visibilityResolver) Labels() []*labelResolver {
+	return baseResolver{&v.visibility.Base}.Labels()
+}
+
+func (v *visibilityResolver) Platform(ctx context.Context) (*platformResolver, error) {
+	return v.resolver.Platform(ctx, idArgs{ID: gql.ID(v.visibility.PlatformID)})
+}
+
+func (v *visibilityResolver) ServicePlan(ctx context.Context) (*planResolver, error) {
+	return v.resolver.ServicePlan(ctx, idArgs{ID: gql.ID(v.visibility.ServicePlanID)})
+}
+
+type platformResolver struct {
+	*resolver
+	platform *types.Platform
+}
+
+func (p *platformResolver) ID() gql.ID {
+	return baseResolver{&p.platform.Base}.ID()
+}
+
+func (p *platformResolver) Type() string {
+	return p.platform.Type
+}
+
+func (p *platformResolver) Name() string {
+	return p.platform.Name
+}
+
+func (p *platformResolver) Description() string {
+	return p.platform.Description
+}
+
+func (p *platformResolver) CreatedAt() string {
+	return baseResolver{&p.platform.Base}.CreatedAt()
+}
+
+func (p *platformResolver) UpdatedAt() string {
+	return baseResolver{&p.platform.Base}.UpdatedAt()
+}
+
+func (p *platformResolver) Labels() []*labelResolver {
+	return baseResolver{&p.platform.Base}.Labels()
+}
+
+func (p *platformResolver) Visibilities(ctx context.Context, args criteriaArgs) ([]*visibilityResolver, error) {
+	return p.visibilities(ctx, &args, query.ByField(query.EqualsOperator, "platform_id", p.platform.ID))
+}
+
+// rawJSON returns the JSON as string or nil if it is empty
+func rawJSON(value json.RawMessage) *string {
+	if len(value) == 0 {
+		return nil
+	}
+	result := string(value)
+	return &result
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+// schema is the GraphQL schema of the Service Manager resources. The fields are named like the fields of the REST API.
+// Lists accept the fieldQuery and labelQuery arguments with the syntax of the corresponding REST API query parameters.
+const schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	service_brokers(fieldQuery: String, labelQuery: String): [ServiceBroker!]!
+	service_broker(id: ID!): ServiceBroker
+	service_offerings(fieldQuery: String, labelQuery: String): [ServiceOffering!]!
+	service_offering(id: ID!): ServiceOffering
+	service_plans(fieldQuery: String, labelQuery: String): [ServicePlan!]!
+	service_plan(id: ID!): ServicePlan
+	visibilities(fieldQuery: String, labelQuery: String): [Visibility!]!
+	visibility(id: ID!): Visibility
+	platforms(fieldQuery: String, labelQuery: String): [Platform!]!
+	platform(id: ID!): Platform
+}
+
+type Label {
+	key: String!
+	values: [String!]!
+}
+
+type ServiceBroker {
+	id: ID!
+	name: String!
+	description: String!
+	broker_url: String!
+	created_at: String!
+	updated_at: String!
+	labels: [Label!]!
+	service_offerings(fieldQuery: String, labelQuery: String): [ServiceOffering!]!
+}
+
+type ServiceOffering {
+	id: ID!
+	name: String!
+	description: String!
+	bindable: Boolean!
+	instances_retrievable: Boolean!
+	bindings_retrievable: Boolean!
+	plan_updateable: Boolean!
+	tags: String
+	requires: String
+	metadata: String
+	catalog_id: String!
+	catalog_name: String!
+	broker_id: ID!
+	created_at: String!
+	updated_at: String!
+	labels: [Label!]!
+	service_broker: ServiceBroker
+	service_plans(fieldQuery: String, labelQuery: String): [ServicePlan!]!
+}
+
+type ServicePlan {
+	id: ID!
+	name: String!
+	description: String!
+	catalog_id: String!
+	catalog_name: String!
+	free: Boolean!
+	bindable: Boolean!
+	plan_updateable: Boolean!
+	metadata: String
+	schemas: String
+	service_offering_id: ID!
+	created_at: String!
+	updated_at: String!
+	labels: [Label!]!
+	service_offering: ServiceOffering
+	visibilities(fieldQuery: String, labelQuery: String): [Visibility!]!
+}
+
+type Visibility {
+	id: ID!
+	platform_id: ID
+	service_plan_id: ID!
+	created_at: String!
+	updated_at: String!
+	labels: [Label!]!
+	platform: Platform
+	service_plan: ServicePlan
+}
+
+type Platform {
+	id: ID!
+	type: String!
+	name: String!
+	description: String!
+	created_at: String!
+	updated_at: String!
+	labels: [Label!]!
+	visibilities(fieldQuery: String, labelQuery: String): [Visibility!]!
+}
+`
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+)
+
+// QueryParamToRevision is the query parameter containing the ID of the revision to which an object is reverted
+const QueryParamToRevision = "to_revision"
+
+// HistoryController provides the change history of the resources of the provided controllers based on the
+// revisions which are persisted on every create, update and delete
+type HistoryController struct {
+	repository  storage.Repository
+	controllers []*BaseController
+}
+
+// NewHistoryController returns a controller which provides the history of the resources of the provided controllers
+func NewHistoryController(repository storage.Repository, controllers ...*BaseController) *HistoryController {
+	return &HistoryController{
+		repository:  repository,
+		controllers: controllers,
+	}
+}
+
+// Routes returns the history and revert routes of the resources
+func (c *HistoryController) Routes() []web.Route {
+	routes := make([]web.Route, 0, 2*len(c.controllers))
+	for _, controller := range c.controllers {
+		controller := controller
+		objectType := controller.objectType
+		routes = append(routes, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}/history", controller.resourceBaseURL, PathParamID),
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.history(r, objectType)
+			},
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("List the revisions of an object of type %s with the changes between them", objectType),
+			},
+		}, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/revert", controller.resourceBaseURL, PathParamID),
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.revert(r, controller)
+			},
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("Restore a previous revision of an object of type %s", objectType),
+			},
+		})
+	}
+	return routes
+}
+
+type historyEntry struct {
+	ID        string                         `json:"id"`
+	Operation types.OperationCategory        `json:"operation"`
+	User      string                         `json:"user,omitempty"`
+	CreatedAt string                         `json:"created_at"`
+	Changes   []*interceptors.RevisionChange `json:"changes"`
+}
+
+type historyResponse struct {
+	History []*historyEntry `json:"history"`
+}
+
+func (c *HistoryController) history(r *web.Request, objectType types.ObjectType) (*web.Response, error) {
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+	log.C(ctx).Debugf("Getting history of %s with id %s", objectType, objectID)
+
+	// the revisions of deleted objects are kept, so the history is available after the deletion
+	revisions, err := c.repository.List(ctx, types.RevisionType,
+		query.ByField(query.EqualsOperator, "resource_id", objectID),
+		query.ByField(query.EqualsOperator, "resource_type", string(objectType)),
+		query.OrderResultBy("created_at", query.AscOrder))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.RevisionType))
+	}
+	if revisions.Len() == 0 {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("no history of %s with id %s found", objectType, objectID),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+
+	response := &historyResponse{
+		History: make([]*historyEntry, 0, revisions.Len()),
+	}
+	var previous *types.Revision
+	for i := 0; i < revisions.Len(); i++ {
+		revision := revisions.ItemAt(i).(*types.Revision)
+		entry := &historyEntry{
+			ID:        revision.ID,
+			Operation: revision.Operation,
+			User:      revision.User,
+			CreatedAt: util.ToRFCFormat(revision.CreatedAt),
+			Changes:   []*interceptors.RevisionChange{},
+		}
+		// a deletion does not change any fields, its payload is the last state of the object
+		if revision.Operation != types.DELETE {
+			var oldPayload []byte
+			if previous != nil {
+				oldPayload = previous.Payload
+			}
+			if entry.Changes, err = interceptors.RevisionChanges(oldPayload, revision.Payload); err != nil {
+				return nil, err
+			}
+		}
+		response.History = append(response.History, entry)
+		if revision.Operation == types.DELETE {
+			previous = nil
+		} else {
+			previous = revision
+		}
+	}
+
+	return util.NewJSONResponse(http.StatusOK, response)
+}
+
+// revert restores the state of the object from one of its revisions. The credentials of the object are not part of
+// its revisions, so the current credentials are kept. The restored state is validated and updated like a regular
+// update, e.g. the catalog of a broker is fetched again, and the update fails if the object changed concurrently.
+func (c *HistoryController) revert(r *web.Request, controller *BaseController) (*web.Response, error) {
+	ctx := r.Context()
+	objectType := controller.objectType
+	objectID := r.PathParams[PathParamID]
+	revisionID := r.URL.Query().Get(QueryParamToRevision)
+	if revisionID == "" {
+		return nil, badRequest("missing query parameter %s", QueryParamToRevision)
+	}
+	log.C(ctx).Debugf("Reverting %s with id %s to revision %s", objectType, objectID, revisionID)
+
+	object, err := c.repository.Get(ctx, types.RevisionType, revisionID)
+	if err != nil && err != util.ErrNotFoundInStorage {
+		return nil, util.HandleStorageError(err, string(types.RevisionType))
+	}
+	revision, _ := object.(*types.Revision)
+	if revision == nil || revision.ResourceID != objectID || revision.ResourceType != objectType {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("revision %s of %s with id %s not found", revisionID, objectType, objectID),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+	if revision.Operation == types.DELETE {
+		return nil, badRequest("revision %s is a deletion and cannot be restored", revisionID)
+	}
+
+	current, err := c.repository.Get(ctx, objectType, objectID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+
+	desired := controller.objectBlueprint()
+	if err := json.Unmarshal(revision.Payload, desired); err != nil {
+		return nil, fmt.Errorf("could not parse payload of revision %s: %s", revisionID, err)
+	}
+	if secured, ok := desired.(types.Secured); ok {
+		secured.SetCredentials(current.(types.Secured).GetCredentials())
+	}
+	desired.SetID(objectID)
+	desired.SetCreatedAt(current.GetCreatedAt())
+	desired.SetUpdatedAt(current.GetUpdatedAt())
+	if err := desired.Validate(); err != nil {
+		return nil, badRequest("revision %s cannot be restored: %s", revisionID, err)
+	}
+
+	labelChanges := query.LabelChangesBetween(current.GetLabels(), desired.GetLabels())
+	reverted, err := c.repository.Update(ctx, desired, labelChanges...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+
+	stripCredentials(ctx, reverted)
+
+	return util.NewJSONResponse(http.StatusOK, reverted)
+}
@@ -28,15 +28,18 @@ import (
 
 // HTTPHandler converts a pkg/web.Handler and pkg/web.HandlerFunc to a standard http.Handler
 type HTTPHandler struct {
-	Handler            web.Handler
-	requestBodyMaxSize int
+	Handler                    web.Handler
+	requestBodyMaxSize         int
+	requestBodyMemoryThreshold int
 }
 
-// NewHTTPHandler creates a new HTTPHandler from the provided web.Handler
-func NewHTTPHandler(handler web.Handler, requestBodyMaxSize int) *HTTPHandler {
+// NewHTTPHandler creates a new HTTPHandler from the provided web.Handler. Request bodies larger than
+// requestBodyMemoryThreshold are buffered in temporary files.
+func NewHTTPHandler(handler web.Handler, requestBodyMaxSize, requestBodyMemoryThreshold int) *HTTPHandler {
 	return &HTTPHandler{
-		Handler:            handler,
-		requestBodyMaxSize: requestBodyMaxSize,
+		Handler:                    handler,
+		requestBodyMaxSize:         requestBodyMaxSize,
+		requestBodyMemoryThreshold: requestBodyMemoryThreshold,
 	}
 }
 
@@ -48,17 +51,24 @@ func (h *HTTPHandler) Handle(req *web.Request) (resp *web.Response, err error) {
 // ServeHTTP implements the http.Handler interface and allows wrapping web.Handlers into http.Handlers
 func (h *HTTPHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	if err := h.serve(res, req); err != nil {
-		util.WriteError(err, res)
+		util.WriteLocalizedError(err, res, req)
 	}
 }
 
 func (h *HTTPHandler) serve(res http.ResponseWriter, req *http.Request) error {
 	req.Body = http.MaxBytesReader(res, req.Body, int64(h.requestBodyMaxSize))
 
-	request, err := convertToWebRequest(req, res)
+	request, body, err := convertToWebRequest(req, res, int64(h.requestBodyMemoryThreshold))
 	if err != nil {
 		return err
 	}
+	if body != nil {
+		defer func() {
+			if err := body.Close(); err != nil {
+				log.C(req.Context()).WithError(err).Warn("Could not remove buffered request body")
+			}
+		}()
+	}
 
 	response, err := h.Handler.Handle(request)
 	if request.IsResponseWriterHijacked() {
@@ -85,23 +95,24 @@ func (h *HTTPHandler) serve(res http.ResponseWriter, req *http.Request) error {
 	return nil
 }
 
-func convertToWebRequest(request *http.Request, rw http.ResponseWriter) (*web.Request, error) {
+func convertToWebRequest(request *http.Request, rw http.ResponseWriter, memoryThreshold int64) (*web.Request, *web.BufferedBody, error) {
 	pathParams := mux.Vars(request)
 
-	var body []byte
-	var err error
-	if request.Method == "PUT" || request.Method == "POST" || request.Method == "PATCH" {
-		body, err = util.RequestBodyToBytes(request)
-		err = isPayloadTooLargeErr(request.Context(), err)
-	}
-
 	webReq := &web.Request{
 		Request:    request,
 		PathParams: pathParams,
-		Body:       body,
 	}
 	webReq.SetResponseWriter(rw)
-	return webReq, err
+
+	if request.Method == "PUT" || request.Method == "POST" || request.Method == "PATCH" {
+		body, err := util.RequestBodyToBuffer(request, memoryThreshold)
+		if err != nil {
+			return webReq, nil, isPayloadTooLargeErr(request.Context(), err)
+		}
+		webReq.SetBufferedBody(body)
+		return webReq, body, nil
+	}
+	return webReq, nil, nil
 }
 
 func isPayloadTooLargeErr(ctx context.Context, err error) error {
@@ -37,7 +37,8 @@ import (
 )
 
 const (
-	bodyMaxSize = 2000000
+	bodyMaxSize         = 2000000
+	bodyMemoryThreshold = 1024
 )
 
 func generateJSON(size int) string {
@@ -64,7 +65,7 @@ var _ = Describe("Handler", func() {
 	BeforeEach(func() {
 		fakeHandler = &webfakes.FakeHandler{}
 		responseRecorder = httptest.NewRecorder()
-		handler = NewHTTPHandler(fakeHandler, bodyMaxSize)
+		handler = NewHTTPHandler(fakeHandler, bodyMaxSize, bodyMemoryThreshold)
 	})
 
 	makeRequest := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
@@ -120,6 +121,42 @@ var _ = Describe("Handler", func() {
 			})
 		})
 
+		Context("when http request has a body larger than the memory threshold", func() {
+			var body string
+
+			BeforeEach(func() {
+				body = generateJSON(10 * bodyMemoryThreshold)
+				fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusOK}, nil)
+			})
+
+			It("provides the whole body through the request accessors", func() {
+				var bodyInHandler []byte
+				fakeHandler.HandleStub = func(request *web.Request) (*web.Response, error) {
+					Expect(request.Body).To(BeNil())
+					Expect(request.BodySize()).To(Equal(int64(len(body))))
+					var err error
+					bodyInHandler, err = request.BodyBytes()
+					Expect(err).ToNot(HaveOccurred())
+					return &web.Response{StatusCode: http.StatusOK}, nil
+				}
+
+				response := makeRequest(http.MethodPost, "http://example.com", body, map[string]string{
+					"Content-Type": "application/json",
+				})
+
+				Expect(response.Code).To(Equal(http.StatusOK))
+				Expect(string(bodyInHandler)).To(Equal(body))
+			})
+
+			It("rejects invalid json", func() {
+				response := makeRequest(http.MethodPost, "http://example.com", body+"}", map[string]string{
+					"Content-Type": "application/json",
+				})
+
+				validateHTTPErrorOccurred(response, http.StatusBadRequest)
+			})
+		})
+
 		Context("when call to web handler returns an error", func() {
 			Specify("response contains a proper HTTPError", func() {
 				handlerError := fmt.Errorf("error")
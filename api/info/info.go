@@ -26,6 +26,9 @@ import (
 // URL is the path of the info endpoint
 const URL = web.InfoURL
 
+// QueryURL is the path of the endpoint which describes the query grammar
+const QueryURL = URL + "/query"
+
 // Routes returns a slice of the routs that handle info operations
 func (c *Controller) Routes() []web.Route {
 	return []web.Route{
@@ -36,5 +39,12 @@ func (c *Controller) Routes() []web.Route {
 			},
 			Handler: c.getInfo,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   QueryURL,
+			},
+			Handler: c.getQueryInfo,
+		},
 	}
 }
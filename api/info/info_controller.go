@@ -17,7 +17,10 @@
 package info
 
 import (
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
 	"net/http"
 
 	"github.com/Peripli/service-manager/pkg/web"
@@ -30,6 +33,15 @@ type Controller struct {
 	// TokenBasicAuth specifies if client credentials should be sent in the header
 	// as basic auth (true) or in the body (false)
 	TokenBasicAuth bool `json:"token_basic_auth"`
+
+	// QueryableFields lists the fields which can be used in field queries, no fields are reported if it is nil
+	QueryableFields storage.QueryableFieldsProvider `json:"-"`
+}
+
+// QueryInfo describes the query grammar and the fields which can be queried for each resource
+type QueryInfo struct {
+	*query.Grammar
+	Fields map[types.ObjectType][]storage.QueryableField `json:"fields"`
 }
 
 var _ web.Controller = &Controller{}
@@ -37,3 +49,14 @@ var _ web.Controller = &Controller{}
 func (c *Controller) getInfo(request *web.Request) (*web.Response, error) {
 	return util.NewJSONResponse(http.StatusOK, c)
 }
+
+func (c *Controller) getQueryInfo(request *web.Request) (*web.Response, error) {
+	info := &QueryInfo{
+		Grammar: query.DescribeGrammar(),
+		Fields:  map[types.ObjectType][]storage.QueryableField{},
+	}
+	if c.QueryableFields != nil {
+		info.Fields = c.QueryableFields.QueryableFields()
+	}
+	return util.NewJSONResponse(http.StatusOK, info)
+}
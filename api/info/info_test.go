@@ -42,13 +42,17 @@ var _ = Describe("Info API", func() {
 	})
 
 	Describe("Routes", func() {
-		It("Returns one route", func() {
+		It("Returns the info and the query routes", func() {
 			routes := controller.Routes()
-			Expect(len(routes)).To(Equal(1))
+			Expect(len(routes)).To(Equal(2))
 
 			route := routes[0]
 			Expect(route.Endpoint.Path).To(Equal(info.URL))
 			Expect(route.Endpoint.Method).To(Equal(http.MethodGet))
+
+			route = routes[1]
+			Expect(route.Endpoint.Path).To(Equal(info.QueryURL))
+			Expect(route.Endpoint.Method).To(Equal(http.MethodGet))
 		})
 	})
 })
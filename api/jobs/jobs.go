@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package jobs contains logic for the Service Manager background jobs API
+package jobs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// URL is the path of the jobs endpoint
+	URL = web.JobsURL
+
+	// PathParamName is the name of the path parameter containing the job name
+	PathParamName = "name"
+)
+
+// Routes returns a slice of the routes that handle background jobs operations
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   URL,
+			},
+			Handler: c.listJobs,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/run", URL, PathParamName),
+			},
+			Handler: c.runJob,
+		},
+	}
+}
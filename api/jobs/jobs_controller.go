@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package jobs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/jobs"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Controller background jobs controller
+type Controller struct {
+	Scheduler *jobs.Scheduler
+}
+
+var _ web.Controller = &Controller{}
+
+type jobsResponse struct {
+	Leader bool          `json:"leader"`
+	Jobs   []jobs.Status `json:"jobs"`
+}
+
+func (c *Controller) listJobs(r *web.Request) (*web.Response, error) {
+	return util.NewJSONResponse(http.StatusOK, &jobsResponse{
+		Leader: c.Scheduler.IsLeader(),
+		Jobs:   c.Scheduler.Statuses(),
+	})
+}
+
+func (c *Controller) runJob(r *web.Request) (*web.Response, error) {
+	name := r.PathParams[PathParamName]
+	log.C(r.Context()).Infof("Manually triggering job %s", name)
+
+	if err := c.Scheduler.Trigger(name); err != nil {
+		if err == jobs.ErrJobNotFound {
+			return nil, &util.HTTPError{
+				ErrorType:   "NotFound",
+				Description: fmt.Sprintf("could not find job %s", name),
+				StatusCode:  http.StatusNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusAccepted, map[string]string{})
+}
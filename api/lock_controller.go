@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// QueryParamForce is the query parameter which allows removing the lock of another user
+	QueryParamForce = "force"
+
+	// DefaultLockTTL is the time to live of a lock whose request does not specify one
+	DefaultLockTTL = time.Hour
+
+	// MaxLockTTL is the longest time to live of a lock
+	MaxLockTTL = 24 * time.Hour
+)
+
+// LockController places and removes locks on the resources of the provided controllers. A lock blocks the
+// modification and deletion of a resource by other users than the one who placed it until it expires.
+type LockController struct {
+	repository  storage.Repository
+	controllers []*BaseController
+}
+
+// NewLockController returns a controller which locks the resources of the provided controllers
+func NewLockController(repository storage.Repository, controllers ...*BaseController) *LockController {
+	return &LockController{
+		repository:  repository,
+		controllers: controllers,
+	}
+}
+
+// Routes returns the lock routes of the resources
+func (c *LockController) Routes() []web.Route {
+	routes := make([]web.Route, 0, 3*len(c.controllers))
+	for _, controller := range c.controllers {
+		objectType := controller.objectType
+		path := fmt.Sprintf("%s/{%s}/lock", controller.resourceBaseURL, PathParamID)
+		routes = append(routes, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPut,
+				Path:   path,
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.lock(r, objectType)
+			},
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("Lock an object of type %s against changes by other users", objectType),
+			},
+		}, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   path,
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.get(r, objectType)
+			},
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("Get the lock of an object of type %s", objectType),
+			},
+		}, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   path,
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.unlock(r, objectType)
+			},
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("Remove the lock of an object of type %s", objectType),
+			},
+		})
+	}
+	return routes
+}
+
+type lockRequest struct {
+	Reason string `json:"reason"`
+	TTL    string `json:"ttl"`
+}
+
+// lock places a lock on the object or extends the lock of the current user. The lock of another user can only be
+// replaced after it expired.
+func (c *LockController) lock(r *web.Request, objectType types.ObjectType) (*web.Response, error) {
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+
+	request := &lockRequest{}
+	if err := json.NewDecoder(r.BodyReader()).Decode(request); err != nil {
+		return nil, badRequest("could not parse lock request: %s", err)
+	}
+	if request.Reason == "" {
+		return nil, badRequest("missing lock reason")
+	}
+	ttl := DefaultLockTTL
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil {
+			return nil, badRequest("invalid lock ttl %q: %s", request.TTL, err)
+		}
+	}
+	if ttl <= 0 || ttl > MaxLockTTL {
+		return nil, badRequest("lock ttl must be positive and at most %s", MaxLockTTL)
+	}
+
+	if _, err := c.repository.Get(ctx, objectType, objectID); err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+	owner := currentUser(r)
+	now := time.Now().UTC()
+
+	lock, err := interceptors.FindLock(ctx, c.repository, objectType, objectID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.LockType))
+	}
+	if lock != nil && lock.IsActive(now) && lock.Owner != owner {
+		return nil, lockedByOther(lock)
+	}
+
+	log.C(ctx).Infof("User %s locks %s with id %s for %s: %s", owner, objectType, objectID, ttl, request.Reason)
+	if lock != nil {
+		lock.Owner = owner
+		lock.Reason = request.Reason
+		lock.ExpiresAt = now.Add(ttl)
+		updated, err := c.repository.Update(ctx, lock)
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(types.LockType))
+		}
+		return util.NewJSONResponse(http.StatusOK, updated)
+	}
+
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for lock: %s", err)
+	}
+	lock = &types.Lock{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		ResourceID:   objectID,
+		ResourceType: objectType,
+		Owner:        owner,
+		Reason:       request.Reason,
+		ExpiresAt:    now.Add(ttl),
+	}
+	created, err := c.repository.Create(ctx, lock)
+	if err != nil {
+		// a concurrent request locked the object first
+		return nil, util.HandleStorageError(err, string(types.LockType))
+	}
+	return util.NewJSONResponse(http.StatusCreated, created)
+}
+
+func (c *LockController) get(r *web.Request, objectType types.ObjectType) (*web.Response, error) {
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+
+	lock, err := interceptors.FindLock(ctx, c.repository, objectType, objectID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.LockType))
+	}
+	if lock == nil || !lock.IsActive(time.Now().UTC()) {
+		return nil, notLocked(objectType, objectID)
+	}
+	return util.NewJSONResponse(http.StatusOK, lock)
+}
+
+// unlock removes the lock of the object. The lock of another user is only removed with the force query parameter.
+func (c *LockController) unlock(r *web.Request, objectType types.ObjectType) (*web.Response, error) {
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+	user := currentUser(r)
+
+	lock, err := interceptors.FindLock(ctx, c.repository, objectType, objectID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.LockType))
+	}
+	if lock == nil || !lock.IsActive(time.Now().UTC()) {
+		return nil, notLocked(objectType, objectID)
+	}
+	if lock.Owner != user && r.URL.Query().Get(QueryParamForce) != "true" {
+		return nil, lockedByOther(lock)
+	}
+
+	log.C(ctx).Infof("User %s removes the lock of %s on %s with id %s", user, lock.Owner, objectType, objectID)
+	if _, err := c.repository.Delete(ctx, types.LockType, query.ByField(query.EqualsOperator, "id", lock.ID)); err != nil {
+		return nil, util.HandleStorageError(err, string(types.LockType))
+	}
+	return util.NewJSONResponse(http.StatusOK, map[string]string{})
+}
+
+func currentUser(r *web.Request) string {
+	if userContext, found := web.UserFromContext(r.Context()); found {
+		return userContext.Name
+	}
+	return ""
+}
+
+func lockedByOther(lock *types.Lock) error {
+	return &util.HTTPError{
+		ErrorType:   "Conflict",
+		Description: fmt.Sprintf("%s with id %s is locked by %s until %s: %s", lock.ResourceType, lock.ResourceID, lock.Owner, util.ToRFCFormat(lock.ExpiresAt), lock.Reason),
+		StatusCode:  http.StatusConflict,
+	}
+}
+
+func notLocked(objectType types.ObjectType, objectID string) error {
+	return &util.HTTPError{
+		ErrorType:   "NotFound",
+		Description: fmt.Sprintf("%s with id %s is not locked", objectType, objectID),
+		StatusCode:  http.StatusNotFound,
+	}
+}
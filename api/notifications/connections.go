@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifications
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/ws"
+	"github.com/gorilla/websocket"
+)
+
+// ReconnectCloseCode is the code of the close frame sent to consumers which should reconnect, e.g. because the
+// instance shuts down or serves more consumers than the other instances
+const ReconnectCloseCode = websocket.CloseServiceRestart
+
+// ReconnectHint is the reason of a close frame with ReconnectCloseCode. Consumers should wait for the hinted delay
+// before reconnecting, so that they do not all reconnect at once.
+type ReconnectHint struct {
+	ReconnectAfterMillis int64 `json:"reconnect_after_ms"`
+}
+
+// connection is an open websocket connection. It receives the delay to hint when it is its turn to be closed.
+type connection struct {
+	reconnect chan time.Duration
+	closed    chan struct{}
+}
+
+// Connections tracks the open websocket connections, so that they are closed gradually when the instance shuts down
+type Connections struct {
+	settings *ws.Settings
+
+	mutex       sync.Mutex
+	connections map[*connection]struct{}
+	draining    bool
+}
+
+// NewConnections returns an empty set of connections
+func NewConnections(settings *ws.Settings) *Connections {
+	return &Connections{
+		settings:    settings,
+		connections: make(map[*connection]struct{}),
+	}
+}
+
+// Start closes the connections at the configured rate once the context is cancelled
+func (c *Connections) Start(ctx context.Context, wg *sync.WaitGroup) {
+	util.StartInWaitGroupWithContext(ctx, c.drain, wg)
+}
+
+func (c *Connections) drain(ctx context.Context) {
+	<-ctx.Done()
+
+	c.mutex.Lock()
+	c.draining = true
+	remaining := len(c.connections)
+	c.mutex.Unlock()
+	log.C(ctx).Infof("Closing %d websocket connections at %d per second...", remaining, c.settings.DrainRate)
+
+	ticker := time.NewTicker(time.Second / time.Duration(c.settings.DrainRate))
+	defer ticker.Stop()
+	timeout := time.NewTimer(c.settings.DrainTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			c.mutex.Lock()
+			log.C(ctx).Infof("Closing the remaining %d websocket connections after %s", len(c.connections), c.settings.DrainTimeout)
+			for conn := range c.connections {
+				c.close(conn)
+			}
+			c.mutex.Unlock()
+			return
+		case <-ticker.C:
+			if !c.closeNext() {
+				return
+			}
+		}
+	}
+}
+
+// closeNext closes one of the connections and returns false if there are none left
+func (c *Connections) closeNext() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for conn := range c.connections {
+		c.close(conn)
+		return true
+	}
+	return false
+}
+
+// close lets the connection close itself with a reconnect hint. It must be called with the mutex.
+func (c *Connections) close(conn *connection) {
+	delete(c.connections, conn)
+	select {
+	case conn.reconnect <- c.backoff():
+	default:
+	}
+}
+
+// backoff returns a random delay up to the configured backoff, which spreads the reconnects of the consumers
+func (c *Connections) backoff() time.Duration {
+	if c.settings.ReconnectBackoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(c.settings.ReconnectBackoff) + 1))
+}
+
+func (c *Connections) register() *connection {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	conn := &connection{
+		reconnect: make(chan time.Duration, 1),
+		closed:    make(chan struct{}),
+	}
+	c.connections[conn] = struct{}{}
+	if c.draining {
+		c.close(conn)
+	}
+	return conn
+}
+
+func (c *Connections) unregister(conn *connection) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.connections, conn)
+	close(conn.closed)
+}
+
+func (c *Connections) isDraining() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.draining
+}
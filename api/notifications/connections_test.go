@@ -0,0 +1,114 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package notifications
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/ws"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connections", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		wg          *sync.WaitGroup
+		settings    *ws.Settings
+		connections *Connections
+	)
+
+	reconnected := func(conns ...*connection) int {
+		count := 0
+		for _, conn := range conns {
+			if len(conn.reconnect) > 0 {
+				count++
+			}
+		}
+		return count
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		settings = ws.DefaultSettings()
+		settings.DrainRate = 10
+		settings.ReconnectBackoff = time.Second
+	})
+
+	JustBeforeEach(func() {
+		connections = NewConnections(settings)
+		connections.Start(ctx, wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	It("keeps the connections open while the context is not cancelled", func() {
+		conn := connections.register()
+		Consistently(func() int { return reconnected(conn) }, 200*time.Millisecond).Should(Equal(0))
+		Expect(connections.isDraining()).To(BeFalse())
+	})
+
+	It("closes the connections gradually when the context is cancelled", func() {
+		conns := []*connection{connections.register(), connections.register(), connections.register()}
+		cancel()
+		Eventually(func() int { return reconnected(conns...) }).Should(Equal(1))
+		Eventually(func() int { return reconnected(conns...) }).Should(Equal(3))
+		wg.Wait()
+
+		for _, conn := range conns {
+			Expect(<-conn.reconnect).To(BeNumerically("<=", settings.ReconnectBackoff))
+		}
+	})
+
+	It("does not close unregistered connections", func() {
+		conn := connections.register()
+		connections.unregister(conn)
+		Expect(conn.closed).To(BeClosed())
+		cancel()
+		wg.Wait()
+		Expect(reconnected(conn)).To(Equal(0))
+	})
+
+	It("closes connections opened while draining at once", func() {
+		connections.register()
+		cancel()
+		Eventually(connections.isDraining).Should(BeTrue())
+		conn := connections.register()
+		Expect(reconnected(conn)).To(Equal(1))
+	})
+
+	Context("when the drain timeout elapses", func() {
+		BeforeEach(func() {
+			settings.DrainRate = 1
+			settings.DrainTimeout = 100 * time.Millisecond
+		})
+
+		It("closes the remaining connections at once", func() {
+			conns := []*connection{connections.register(), connections.register(), connections.register()}
+			cancel()
+			wg.Wait()
+			Expect(reconnected(conns...)).To(Equal(3))
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/Peripli/service-manager/pkg/platformtypes"
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/ws"
@@ -32,13 +33,16 @@ type Controller struct {
 	baseCtx    context.Context
 	repository storage.Repository
 
-	wsSettings  *ws.Settings
-	notificator storage.Notificator
+	wsSettings    *ws.Settings
+	notificator   storage.Notificator
+	platformTypes *platformtypes.Registry
+	connections   *Connections
+	tokens        *ws.ConnectionTokens
 }
 
 // Routes returns the routes for notifications
 func (c *Controller) Routes() []web.Route {
-	return []web.Route{
+	routes := []web.Route{
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -47,14 +51,29 @@ func (c *Controller) Routes() []web.Route {
 			Handler: c.handleWS,
 		},
 	}
+	if c.tokens != nil {
+		routes = append(routes, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.NotificationsTokenURL,
+			},
+			Handler: c.issueToken,
+		})
+	}
+	return routes
 }
 
-// NewController creates new notifications controller
-func NewController(baseCtx context.Context, repository storage.Repository, wsSettings *ws.Settings, notificator storage.Notificator) *Controller {
+// NewController creates new notifications controller. The notifications are transformed by the plugins of the
+// platform types before they are sent to the platforms. The connections are closed gradually when they are drained.
+// Platforms can exchange their credentials for connection tokens unless the tokens are nil.
+func NewController(baseCtx context.Context, repository storage.Repository, wsSettings *ws.Settings, notificator storage.Notificator, platformTypes *platformtypes.Registry, connections *Connections, tokens *ws.ConnectionTokens) *Controller {
 	return &Controller{
-		baseCtx:     baseCtx,
-		repository:  repository,
-		wsSettings:  wsSettings,
-		notificator: notificator,
+		baseCtx:       baseCtx,
+		repository:    repository,
+		wsSettings:    wsSettings,
+		notificator:   notificator,
+		platformTypes: platformTypes,
+		connections:   connections,
+		tokens:        tokens,
 	}
 }
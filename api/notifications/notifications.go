@@ -2,14 +2,17 @@ package notifications
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/ws"
 	"github.com/Peripli/service-manager/storage"
 	"github.com/gorilla/websocket"
 
@@ -51,6 +54,12 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	// connections opened with a connection token are closed when the token expires unless it is renewed
+	var tokenExpiry *time.Time
+	if claims, found := c.connectionTokenClaims(req); found {
+		expiry := claims.Expiry()
+		tokenExpiry = &expiry
+	}
 	notificationQueue, lastKnownToSMRevision, err := c.notificator.RegisterConsumer(platform, revisionKnownToProxy)
 	if err != nil {
 		if err == util.ErrInvalidNotificationRevision {
@@ -59,8 +68,7 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 		return nil, err
 	}
 
-	correlationID := logger.Data[log.FieldCorrelationID].(string)
-	childCtx := newContextWithCorrelationID(c.baseCtx, correlationID)
+	childCtx := newContextWithMetadata(c.baseCtx, req.Metadata())
 
 	defer func() {
 		if err := recover(); err != nil {
@@ -81,15 +89,17 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 	}
 
 	done := make(chan struct{}, 2)
+	renewals := make(chan time.Time, 1)
+	connection := c.connections.register()
 
-	go c.closeConn(childCtx, conn, done)
-	go c.writeLoop(childCtx, conn, notificationQueue, done)
-	go c.readLoop(childCtx, conn, done)
+	go c.closeConn(childCtx, conn, connection, done)
+	go c.writeLoop(childCtx, conn, connection, platform, notificationQueue, tokenExpiry, renewals, done)
+	go c.readLoop(childCtx, conn, platform, renewals, done)
 
 	return &web.Response{}, nil
 }
 
-func (c *Controller) writeLoop(ctx context.Context, conn *websocket.Conn, q storage.NotificationQueue, done chan<- struct{}) {
+func (c *Controller) writeLoop(ctx context.Context, conn *websocket.Conn, connection *connection, platform *types.Platform, q storage.NotificationQueue, tokenExpiry *time.Time, renewals <-chan time.Time, done chan<- struct{}) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.C(ctx).Errorf("recovered from panic while writing to websocket connection: %s", err)
@@ -103,25 +113,65 @@ func (c *Controller) writeLoop(ctx context.Context, conn *websocket.Conn, q stor
 
 	notificationChannel := q.Channel()
 
+	var expiryTimer *time.Timer
+	var expired <-chan time.Time
+	if tokenExpiry != nil {
+		expiryTimer = time.NewTimer(time.Until(*tokenExpiry))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+
 	for {
 		select {
-		case <-ctx.Done():
+		case <-connection.closed:
+			return
+		case <-expired:
+			log.C(ctx).Infof("Connection token of platform %s expired. Closing websocket connection...", platform.ID)
+			if err := c.sendClose(ctx, conn, websocket.ClosePolicyViolation, "connection token expired"); err != nil {
+				log.C(ctx).WithError(err).Error("Could not send close")
+			}
+			return
+		case expiry := <-renewals:
+			if expiryTimer == nil {
+				// connections opened with credentials do not expire
+				continue
+			}
+			log.C(ctx).Debugf("Connection token of platform %s renewed until %s", platform.ID, expiry)
+			if !expiryTimer.Stop() {
+				<-expiryTimer.C
+			}
+			expiryTimer.Reset(time.Until(expiry))
+		case backoff := <-connection.reconnect:
 			log.C(ctx).Infof("Websocket connection shutting down")
+			c.sendReconnect(ctx, conn, backoff)
 			return
 		case notification, ok := <-notificationChannel:
 			if !ok {
+				if c.connections.isDraining() {
+					// the consumers are closed when the instance shuts down, the connection waits for its turn instead
+					notificationChannel = nil
+					continue
+				}
 				log.C(ctx).Infof("Notifications channel is closed. Closing websocket connection...")
+				c.sendReconnect(ctx, conn, c.connections.backoff())
 				return
 			}
 
-			if !c.sendWsMessage(ctx, conn, notification) {
+			// the connection is closed instead of skipping the notification, so that no notification is lost
+			transformed, err := c.platformTypes.TransformNotification(platform, notification)
+			if err != nil {
+				log.C(ctx).WithError(err).Errorf("Could not transform notification %s for platform %s", notification.ID, platform.ID)
+				return
+			}
+
+			if !c.sendWsMessage(ctx, conn, transformed) {
 				return
 			}
 		}
 	}
 }
 
-func (c *Controller) readLoop(ctx context.Context, conn *websocket.Conn, done chan<- struct{}) {
+func (c *Controller) readLoop(ctx context.Context, conn *websocket.Conn, platform *types.Platform, renewals chan time.Time, done chan<- struct{}) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.C(ctx).Errorf("recovered from panic while reading from websocket connection: %s", err)
@@ -133,13 +183,27 @@ func (c *Controller) readLoop(ctx context.Context, conn *websocket.Conn, done ch
 	}()
 
 	for {
-		// ReadMessage is needed only to receive ping/pong/close control messages
-		// currently we don't expect to receive something else from the proxies
-		_, _, err := conn.ReadMessage()
+		// ReadMessage is needed to receive ping/pong/close control messages, the only other
+		// messages expected from the proxies are renewals of their connection tokens
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			log.C(ctx).Errorf("ws: could not read: %v", err)
 			return
 		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		expiry, err := c.renewal(platform, message)
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Rejected connection token renewal of platform %s", platform.ID)
+			continue
+		}
+		// only the latest renewal is kept if the write loop has not yet received the previous one
+		select {
+		case <-renewals:
+		default:
+		}
+		renewals <- expiry
 	}
 }
 
@@ -173,7 +237,42 @@ func extractPlatformFromContext(userContext *web.UserContext) (*types.Platform,
 	return platform, nil
 }
 
-func newContextWithCorrelationID(baseCtx context.Context, correlationID string) context.Context {
-	entry := log.C(baseCtx).WithField(log.FieldCorrelationID, correlationID)
-	return log.ContextWithLogger(baseCtx, entry)
+// renewal returns the expiry of the connection token in a renewal message of the platform
+func (c *Controller) renewal(platform *types.Platform, message []byte) (time.Time, error) {
+	if c.tokens == nil {
+		return time.Time{}, errors.New("connection tokens are not enabled")
+	}
+	renewal := &ws.ConnectionToken{}
+	if err := json.Unmarshal(message, renewal); err != nil {
+		return time.Time{}, fmt.Errorf("invalid renewal message: %s", err)
+	}
+	claims, err := c.tokens.Verify(renewal.Token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.PlatformID != platform.ID {
+		return time.Time{}, fmt.Errorf("connection token was issued to platform %s", claims.PlatformID)
+	}
+	return claims.Expiry(), nil
+}
+
+// connectionTokenClaims returns the claims of the connection token with which the request was authenticated
+func (c *Controller) connectionTokenClaims(req *web.Request) (*ws.ConnectionClaims, bool) {
+	if c.tokens == nil {
+		return nil, false
+	}
+	authorizationHeader := req.Header.Get("Authorization")
+	if !strings.HasPrefix(strings.ToLower(authorizationHeader), "bearer ") {
+		return nil, false
+	}
+	claims, err := c.tokens.Verify(strings.TrimSpace(authorizationHeader[len("Bearer "):]))
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+func newContextWithMetadata(baseCtx context.Context, metadata web.Metadata) context.Context {
+	entry := log.C(baseCtx).WithField(log.FieldCorrelationID, metadata.CorrelationID())
+	return web.ContextWithMetadata(log.ContextWithLogger(baseCtx, entry), metadata)
 }
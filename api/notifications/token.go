@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package notifications
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// issueToken exchanges the credentials of the platform for a connection token, with which the platform opens
+// and renews its notifications connection
+func (c *Controller) issueToken(req *web.Request) (*web.Response, error) {
+	user, ok := web.UserFromContext(req.Context())
+	if !ok {
+		return nil, errors.New("user details not found in request context")
+	}
+	platform, err := extractPlatformFromContext(user)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.tokens.Issue(platform.ID)
+	if err != nil {
+		return nil, err
+	}
+	token.URL = notificationsURL(req)
+	log.C(req.Context()).Debugf("Issued connection token for platform %s valid until %s", platform.ID, token.ExpiresAt)
+	return util.NewJSONResponse(http.StatusCreated, token)
+}
+
+// notificationsURL returns the websocket URL of the notifications endpoint as reached by the platform
+func notificationsURL(req *web.Request) string {
+	notificationsURL := req.ExternalURL(web.NotificationsURL)
+	if notificationsURL.Scheme == "https" {
+		notificationsURL.Scheme = "wss"
+	} else {
+		notificationsURL.Scheme = "ws"
+	}
+	return notificationsURL.String()
+}
@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	"net/http"
 	"time"
@@ -64,17 +65,18 @@ func (c *Controller) configureConn(ctx context.Context, conn *websocket.Conn) {
 	})
 }
 
-func (c *Controller) closeConn(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) {
+func (c *Controller) closeConn(ctx context.Context, conn *websocket.Conn, connection *connection, done <-chan struct{}) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.C(ctx).Errorf("recovered from panic while closing websocket connection: %s", err)
 		}
 	}()
 
-	// if base context is cancelled, write loop will quit and write to done
+	// if the connection is drained, write loop will quit and write to done
 	<-done
+	c.connections.unregister(connection)
 
-	if err := c.sendClose(ctx, conn, websocket.CloseGoingAway); err != nil {
+	if err := c.sendClose(ctx, conn, websocket.CloseGoingAway, ""); err != nil {
 		log.C(ctx).WithError(err).Error("Could not send close")
 	}
 
@@ -83,8 +85,19 @@ func (c *Controller) closeConn(ctx context.Context, conn *websocket.Conn, done <
 	}
 }
 
-func (c *Controller) sendClose(ctx context.Context, conn *websocket.Conn, closeCode int) error {
-	message := websocket.FormatCloseMessage(closeCode, "")
+// sendReconnect closes the connection with a hint after which delay the consumer should reconnect
+func (c *Controller) sendReconnect(ctx context.Context, conn *websocket.Conn, backoff time.Duration) {
+	hint, err := json.Marshal(&ReconnectHint{ReconnectAfterMillis: int64(backoff / time.Millisecond)})
+	if err != nil {
+		log.C(ctx).WithError(err).Error("Could not marshal reconnect hint")
+	}
+	if err := c.sendClose(ctx, conn, ReconnectCloseCode, string(hint)); err != nil {
+		log.C(ctx).WithError(err).Error("Could not send reconnect")
+	}
+}
+
+func (c *Controller) sendClose(ctx context.Context, conn *websocket.Conn, closeCode int, text string) error {
+	message := websocket.FormatCloseMessage(closeCode, text)
 	err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(c.wsSettings.WriteTimeout))
 	if err != nil && err != websocket.ErrCloseSent {
 		log.C(ctx).WithError(err).Error("Could not write websocket close message")
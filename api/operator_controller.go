@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// QueryParamSinceRevision is the query parameter containing the revision after which changes are returned
+	QueryParamSinceRevision = "since_revision"
+
+	// QueryParamResource is the query parameter which restricts the changes to a resource, e.g. service_brokers
+	QueryParamResource = "resource"
+
+	// QueryParamMaxItems is the query parameter limiting the number of returned changes
+	QueryParamMaxItems = "max_items"
+
+	defaultMaxChanges = 100
+	maxMaxChanges     = 1000
+)
+
+// OperatorController provides the endpoints used by Kubernetes operators which manage resources of the
+// Service Manager as custom resources. The resources are upserted with their full desired state and their changes
+// are polled from a feed based on the notifications of the Service Manager.
+type OperatorController struct {
+	repository  storage.Repository
+	fields      *filters.FieldAuthorizationFilter
+	controllers []*BaseController
+	resources   map[string]types.ObjectType
+}
+
+// NewOperatorController returns an operator controller which provides upsert endpoints and the change feed
+// for the resources of the provided controllers. The changes are returned without the fields the caller may not see.
+func NewOperatorController(repository storage.Repository, fields *filters.FieldAuthorizationFilter, controllers ...*BaseController) *OperatorController {
+	resources := make(map[string]types.ObjectType, len(controllers))
+	for _, controller := range controllers {
+		resources[path.Base(controller.resourceBaseURL)] = controller.objectType
+	}
+	return &OperatorController{
+		repository:  repository,
+		fields:      fields,
+		controllers: controllers,
+		resources:   resources,
+	}
+}
+
+// Routes returns the upsert routes of the resources and the route of the change feed
+func (c *OperatorController) Routes() []web.Route {
+	routes := make([]web.Route, 0, len(c.controllers)+1)
+	for _, controller := range c.controllers {
+		routes = append(routes, web.Route{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPut,
+				Path:   fmt.Sprintf("%s/{%s}", controller.resourceBaseURL, PathParamID),
+			},
+			Handler: controller.UpsertObject,
+			Doc: &web.RouteDoc{
+				Summary: fmt.Sprintf("Create or update an object of type %s with its full desired state", controller.objectType),
+			},
+		})
+	}
+	return append(routes, web.Route{
+		Endpoint: web.Endpoint{
+			Method: http.MethodGet,
+			Path:   web.ChangesURL,
+		},
+		Handler: c.listChanges,
+		Doc: &web.RouteDoc{
+			Summary: "List the changes of the resources after a revision",
+		},
+	})
+}
+
+type changesResponse struct {
+	Changes []*types.Notification `json:"changes"`
+
+	// Revision is the revision after which the next changes should be requested
+	Revision int64 `json:"revision"`
+}
+
+func (c *OperatorController) listChanges(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	params := r.URL.Query()
+
+	sinceRevision, err := intParam(params.Get(QueryParamSinceRevision), 0)
+	if err != nil {
+		return nil, err
+	}
+	maxItems, err := intParam(params.Get(QueryParamMaxItems), defaultMaxChanges)
+	if err != nil {
+		return nil, err
+	}
+	if maxItems <= 0 || maxItems > maxMaxChanges {
+		return nil, badRequest("%s must be between 1 and %d", QueryParamMaxItems, maxMaxChanges)
+	}
+
+	resources := make([]string, 0, len(c.resources))
+	if resource := params.Get(QueryParamResource); resource != "" {
+		objectType, found := c.resources[resource]
+		if !found {
+			return nil, badRequest("changes of resource %s are not supported", resource)
+		}
+		resources = append(resources, string(objectType))
+	} else {
+		for _, objectType := range c.resources {
+			resources = append(resources, string(objectType))
+		}
+	}
+
+	// the revision after which changes are requested must still be known, otherwise changes may have been missed
+	if sinceRevision > 0 {
+		known, err := c.repository.List(ctx, types.NotificationType, query.ByField(query.EqualsOperator, "revision", strconv.FormatInt(sinceRevision, 10)))
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(types.NotificationType))
+		}
+		if known.Len() == 0 {
+			return nil, &util.HTTPError{
+				ErrorType:   "Gone",
+				Description: fmt.Sprintf("revision %d is no longer known, the resources should be listed again", sinceRevision),
+				StatusCode:  http.StatusGone,
+			}
+		}
+	}
+
+	notifications, err := c.repository.List(ctx, types.NotificationType,
+		query.ByField(query.GreaterThanOperator, "revision", strconv.FormatInt(sinceRevision, 10)),
+		query.ByField(query.InOperator, "resource", resources...),
+		query.OrderResultBy("revision", query.AscOrder),
+		query.LimitResultBy(int(maxItems)))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.NotificationType))
+	}
+
+	response := &changesResponse{
+		Changes:  make([]*types.Notification, 0, notifications.Len()),
+		Revision: sinceRevision,
+	}
+	for i := 0; i < notifications.Len(); i++ {
+		notification := notifications.ItemAt(i).(*types.Notification)
+		if notification.Payload, err = c.redact(ctx, notification); err != nil {
+			return nil, err
+		}
+		response.Changes = append(response.Changes, notification)
+		if notification.Revision > response.Revision {
+			response.Revision = notification.Revision
+		}
+	}
+	return util.NewJSONResponse(http.StatusOK, response)
+}
+
+// redact removes the fields the caller may not see from the old and new state of the resource in the payload of
+// the notification
+func (c *OperatorController) redact(ctx context.Context, notification *types.Notification) ([]byte, error) {
+	payload := []byte(notification.Payload)
+	for _, side := range []string{"old", "new"} {
+		resource := gjson.GetBytes(payload, side+".resource")
+		if !resource.Exists() {
+			continue
+		}
+		redacted, err := c.fields.Redact(ctx, filters.ResourceURLs[notification.Resource], []byte(resource.Raw))
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = sjson.SetRawBytes(payload, side+".resource", redacted); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+func intParam(value string, defaultValue int64) (int64, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, badRequest("invalid query parameter value %s", value)
+	}
+	return result, nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// CheckReachability verifies that the broker URL can be reached
+	CheckReachability = "reachability"
+
+	// CheckCredentials verifies that the broker accepts the credentials
+	CheckCredentials = "credentials"
+
+	// CheckOSBVersion verifies that the broker supports the OSB API version used by the Service Manager
+	CheckOSBVersion = "osb_version"
+
+	// CheckCatalog verifies that the broker catalog is well-formed and can be stored
+	CheckCatalog = "catalog"
+)
+
+// CheckStatus is the outcome of a single broker validation check
+type CheckStatus string
+
+const (
+	// CheckPassed is the status of a successful check
+	CheckPassed CheckStatus = "passed"
+
+	// CheckFailed is the status of an unsuccessful check
+	CheckFailed CheckStatus = "failed"
+
+	// CheckSkipped is the status of a check which was not performed because a preceding check failed
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// BrokerValidationCheck is the outcome of a single check of a broker validation
+type BrokerValidationCheck struct {
+	Name        string      `json:"name"`
+	Status      CheckStatus `json:"status"`
+	Description string      `json:"description,omitempty"`
+}
+
+// BrokerValidationReport contains the outcome of all checks of a broker validation in the order they are performed
+type BrokerValidationReport struct {
+	Valid  bool                     `json:"valid"`
+	Checks []*BrokerValidationCheck `json:"checks"`
+}
+
+// BrokerValidator checks whether a broker can be registered by calling its catalog endpoint the same way as the
+// registration does, without persisting anything
+type BrokerValidator struct {
+	DoRequestFuncProvider func(broker *types.ServiceBroker) (util.DoRequestFunc, error)
+	BrokerAPIVersion      string
+	CatalogValidator      func(broker *types.ServiceBroker, catalog []byte) error
+}
+
+// Validate performs the checks of the broker. A check is skipped if a preceding check failed.
+func (v *BrokerValidator) Validate(ctx context.Context, broker *types.ServiceBroker) (*BrokerValidationReport, error) {
+	report := &BrokerValidationReport{}
+	failed := false
+	check := func(name string, err error) {
+		result := &BrokerValidationCheck{Name: name, Status: CheckPassed}
+		switch {
+		case failed:
+			result.Status = CheckSkipped
+		case err != nil:
+			result.Status = CheckFailed
+			result.Description = err.Error()
+			failed = true
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	doRequestFunc, err := v.DoRequestFuncProvider(broker)
+	if err != nil {
+		return nil, err
+	}
+	log.C(ctx).Debugf("Validating broker with name %s and URL %s", broker.Name, broker.BrokerURL)
+	requestWithBasicAuth := util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequestFunc)
+	response, err := util.SendRequestWithHeaders(ctx, requestWithBasicAuth, http.MethodGet, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), map[string]string{}, nil, map[string]string{
+		brokerAPIVersionHeader: v.BrokerAPIVersion,
+	})
+	if err != nil {
+		check(CheckReachability, fmt.Errorf("could not reach broker at %s: %s", broker.BrokerURL, err))
+	} else {
+		check(CheckReachability, nil)
+	}
+
+	var catalog []byte
+	if response != nil {
+		if catalog, err = util.BodyToBytes(response.Body); err != nil {
+			return nil, fmt.Errorf("error getting content from body of response with status %s: %s", response.Status, err)
+		}
+	}
+
+	var credentialsErr, versionErr, catalogErr error
+	switch {
+	case response == nil:
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		credentialsErr = fmt.Errorf("broker rejected the credentials with %s", response.Status)
+	case response.StatusCode == http.StatusPreconditionFailed:
+		versionErr = fmt.Errorf("broker does not support OSB API version %s", v.BrokerAPIVersion)
+	case response.StatusCode != http.StatusOK:
+		catalogErr = fmt.Errorf("broker responded with %s", response.Status)
+	default:
+		catalogErr = v.CatalogValidator(broker, catalog)
+	}
+	check(CheckCredentials, credentialsErr)
+	check(CheckOSBVersion, versionErr)
+	check(CheckCatalog, catalogErr)
+
+	report.Valid = !failed
+	return report, nil
+}
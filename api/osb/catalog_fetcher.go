@@ -24,6 +24,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/catalog"
 )
 
 const brokerCatalogURL = "%s/v2/catalog"
@@ -31,14 +32,43 @@ const brokerAPIVersionHeader = "X-Broker-API-Version"
 
 // CatalogFetcher creates a broker catalog fetcher that uses the provided request function to call the specified broker's catalog endpoint
 func CatalogFetcher(doRequestFunc util.DoRequestFunc, brokerAPIVersion string) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+	return BrokerCatalogFetcher(func(*types.ServiceBroker) (util.DoRequestFunc, error) {
+		return doRequestFunc, nil
+	}, brokerAPIVersion)
+}
+
+// BrokerCatalogFetcher creates a broker catalog fetcher that calls the specified broker's catalog endpoint with the request function
+// provided for the broker. This allows brokers to be called through different transports (e.g. different proxies).
+// The validators returned by the broker with its catalog are set on the broker, so that they are persisted together
+// with the catalog. If a conditional fetch is requested in the context, the stored validators are sent to the broker
+// and catalog.ErrNotModified is returned if the broker catalog has not changed.
+func BrokerCatalogFetcher(doRequestFuncProvider func(broker *types.ServiceBroker) (util.DoRequestFunc, error), brokerAPIVersion string) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
 	return func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
 		log.C(ctx).Debugf("Attempting to fetch catalog from broker with name %s and URL %s", broker.Name, broker.BrokerURL)
-		requestWithBasicAuth := util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequestFunc)
-		response, err := util.SendRequestWithHeaders(ctx, requestWithBasicAuth, http.MethodGet, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), map[string]string{}, nil, map[string]string{
+		doRequestFunc, err := doRequestFuncProvider(broker)
+		if err != nil {
+			return nil, err
+		}
+		headers := map[string]string{
 			brokerAPIVersionHeader: brokerAPIVersion,
-		})
+		}
+		conditional := catalog.IsConditionalFetch(ctx) && len(broker.Catalog) != 0
+		if conditional {
+			if broker.CatalogETag != "" {
+				headers["If-None-Match"] = broker.CatalogETag
+			}
+			if broker.CatalogLastModified != "" {
+				headers["If-Modified-Since"] = broker.CatalogLastModified
+			}
+		}
+		requestWithBasicAuth := util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequestFunc)
+		response, err := util.SendRequestWithHeaders(ctx, requestWithBasicAuth, http.MethodGet, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), map[string]string{}, nil, headers)
 		if err != nil {
 			log.C(ctx).WithError(err).Errorf("Error while forwarding request to service broker %s", broker.Name)
+			if httpErr, ok := err.(*util.HTTPError); ok {
+				// the response of the broker was rejected
+				return nil, httpErr
+			}
 			return nil, &util.HTTPError{
 				ErrorType:   "ServiceBrokerErr",
 				Description: fmt.Sprintf("could not reach service broker %s at %s", broker.Name, broker.BrokerURL),
@@ -51,6 +81,11 @@ func CatalogFetcher(doRequestFunc util.DoRequestFunc, brokerAPIVersion string) f
 			return nil, fmt.Errorf("error getting content from body of response with status %s: %s", response.Status, err)
 		}
 
+		if conditional && response.StatusCode == http.StatusNotModified {
+			log.C(ctx).Debugf("Catalog of broker with name %s and URL %s has not been modified", broker.Name, broker.BrokerURL)
+			return nil, catalog.ErrNotModified
+		}
+
 		if response.StatusCode != http.StatusOK {
 			log.C(ctx).WithError(err).Errorf("error fetching catalog for broker with name %s: %s", broker.Name, util.HandleResponseError(response))
 			return nil, &util.HTTPError{
@@ -60,6 +95,8 @@ func CatalogFetcher(doRequestFunc util.DoRequestFunc, brokerAPIVersion string) f
 			}
 		}
 		log.C(ctx).Debugf("Successfully fetched catalog from broker with name %s and URL %s", broker.Name, broker.BrokerURL)
+		broker.CatalogETag = response.Header.Get("ETag")
+		broker.CatalogLastModified = response.Header.Get("Last-Modified")
 
 		return responseBytes, nil
 	}
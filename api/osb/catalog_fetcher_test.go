@@ -25,6 +25,7 @@ import (
 	"github.com/Peripli/service-manager/api/osb"
 
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/catalog"
 
 	. "github.com/onsi/gomega"
 
@@ -166,4 +167,82 @@ var _ = Describe("Catalog CatalogFetcher", func() {
 			Expect(rawCatalog).To(Equal(t.expectedResponse))
 		}
 	}, entries...)
+
+	Describe("Conditional fetch", func() {
+		const (
+			etag         = `"v1"`
+			lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+		)
+
+		var status int
+		var requestHeaders http.Header
+		var fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			requestHeaders = nil
+			fetcher = osb.CatalogFetcher(func(request *http.Request) (*http.Response, error) {
+				requestHeaders = request.Header
+				response := &http.Response{
+					StatusCode: status,
+					Header:     http.Header{},
+					Body:       common.Closer(""),
+					Request:    request,
+				}
+				if status == http.StatusOK {
+					response.Header.Set("ETag", etag)
+					response.Header.Set("Last-Modified", lastModified)
+					response.Body = common.Closer(simpleCatalog)
+				}
+				return response, nil
+			}, version)
+		})
+
+		It("records the validators returned with the catalog on the broker", func() {
+			_, err := fetcher(context.TODO(), testBroker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testBroker.CatalogETag).To(Equal(etag))
+			Expect(testBroker.CatalogLastModified).To(Equal(lastModified))
+		})
+
+		Context("when the broker has a stored catalog with validators", func() {
+			BeforeEach(func() {
+				testBroker.Catalog = []byte(simpleCatalog)
+				testBroker.CatalogETag = etag
+				testBroker.CatalogLastModified = lastModified
+			})
+
+			It("does not send the validators unless requested", func() {
+				_, err := fetcher(context.TODO(), testBroker)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requestHeaders.Get("If-None-Match")).To(BeEmpty())
+				Expect(requestHeaders.Get("If-Modified-Since")).To(BeEmpty())
+			})
+
+			It("returns not modified when the broker responds with 304", func() {
+				status = http.StatusNotModified
+
+				_, err := fetcher(catalog.ContextWithConditionalFetch(context.TODO()), testBroker)
+				Expect(err).To(Equal(catalog.ErrNotModified))
+				Expect(requestHeaders.Get("If-None-Match")).To(Equal(etag))
+				Expect(requestHeaders.Get("If-Modified-Since")).To(Equal(lastModified))
+			})
+
+			It("returns the catalog when the broker catalog has changed", func() {
+				rawCatalog, err := fetcher(catalog.ContextWithConditionalFetch(context.TODO()), testBroker)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rawCatalog).To(Equal([]byte(simpleCatalog)))
+			})
+		})
+
+		Context("when the broker has no stored catalog", func() {
+			It("does not send the validators", func() {
+				testBroker.CatalogETag = etag
+
+				_, err := fetcher(catalog.ContextWithConditionalFetch(context.TODO()), testBroker)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requestHeaders.Get("If-None-Match")).To(BeEmpty())
+			})
+		})
+	})
 })
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CompositeFetcherFunc returns the composite broker with the ID resolved to the brokers owning its plans
+type CompositeFetcherFunc func(ctx context.Context, compositeID string) (*Composite, error)
+
+// Composite is a composite broker resolved to the registered brokers owning its plans
+type Composite struct {
+	*types.CompositeBroker
+
+	// Members are the brokers owning the plans of the composite broker
+	Members []*CompositeMember
+}
+
+// CompositeMember is a broker owning plans of a composite broker
+type CompositeMember struct {
+	Broker *types.ServiceBroker
+
+	// Plans are the catalog IDs of the selected plans per catalog ID of their service, as served to the platforms
+	Plans map[string]map[string]bool
+}
+
+// NewCompositeFetcher returns a fetcher which loads the composite brokers and the brokers owning their plans
+func NewCompositeFetcher(repository storage.Repository, brokerFetcher BrokerFetcherFunc) CompositeFetcherFunc {
+	return func(ctx context.Context, compositeID string) (*Composite, error) {
+		obj, err := repository.Get(ctx, types.CompositeBrokerType, compositeID)
+		if err != nil {
+			return nil, util.HandleStorageError(err, "composite broker")
+		}
+		composite := &Composite{CompositeBroker: obj.(*types.CompositeBroker)}
+
+		plans, err := repository.List(ctx, types.ServicePlanType, query.ByField(query.InOperator, "id", composite.PlanIDs...))
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(types.ServicePlanType))
+		}
+		plansPerOffering := make(map[string][]*types.ServicePlan)
+		offeringIDs := make([]string, 0, plans.Len())
+		for i := 0; i < plans.Len(); i++ {
+			plan := plans.ItemAt(i).(*types.ServicePlan)
+			if _, found := plansPerOffering[plan.ServiceOfferingID]; !found {
+				offeringIDs = append(offeringIDs, plan.ServiceOfferingID)
+			}
+			plansPerOffering[plan.ServiceOfferingID] = append(plansPerOffering[plan.ServiceOfferingID], plan)
+		}
+		if len(offeringIDs) == 0 {
+			return composite, nil
+		}
+		offerings, err := repository.List(ctx, types.ServiceOfferingType, query.ByField(query.InOperator, "id", offeringIDs...))
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(types.ServiceOfferingType))
+		}
+
+		members := make(map[string]*CompositeMember)
+		owners := make(map[string]string)
+		for i := 0; i < offerings.Len(); i++ {
+			offering := offerings.ItemAt(i).(*types.ServiceOffering)
+			member, found := members[offering.BrokerID]
+			if !found {
+				broker, err := brokerFetcher(ctx, offering.BrokerID)
+				if err != nil {
+					return nil, err
+				}
+				member = &CompositeMember{Broker: broker, Plans: make(map[string]map[string]bool)}
+				members[offering.BrokerID] = member
+				composite.Members = append(composite.Members, member)
+			}
+			namespace := CatalogNamespaceOf(member.Broker)
+			serviceID := namespace.Add(offering.CatalogID)
+			if owner, found := owners[serviceID]; found && owner != offering.BrokerID {
+				return nil, &util.HTTPError{
+					ErrorType:   "Conflict",
+					Description: fmt.Sprintf("the service id %s of composite broker %s is offered by several brokers", serviceID, composite.Name),
+					StatusCode:  http.StatusConflict,
+				}
+			}
+			owners[serviceID] = offering.BrokerID
+			if member.Plans[serviceID] == nil {
+				member.Plans[serviceID] = make(map[string]bool)
+			}
+			for _, plan := range plansPerOffering[offering.ID] {
+				member.Plans[serviceID][namespace.Add(plan.CatalogID)] = true
+			}
+		}
+		return composite, nil
+	}
+}
+
+// Catalog returns the catalog of the composite broker, which consists of the selected plans of the catalogs of its
+// members. Instances and bindings are not retrievable, as the calls to fetch them cannot be routed.
+func (c *Composite) Catalog() ([]byte, error) {
+	catalog := []byte(`{"services":[]}`)
+	for _, member := range c.Members {
+		memberCatalog, err := CatalogNamespaceOf(member.Broker).Catalog(member.Broker.Catalog)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range gjson.GetBytes(memberCatalog, "services").Array() {
+			selectedPlans, found := member.Plans[service.Get("id").String()]
+			if !found {
+				continue
+			}
+			serviceJSON := []byte(service.Raw)
+			if serviceJSON, err = sjson.SetRawBytes(serviceJSON, "plans", []byte("[]")); err != nil {
+				return nil, err
+			}
+			for _, plan := range service.Get("plans").Array() {
+				if !selectedPlans[plan.Get("id").String()] {
+					continue
+				}
+				if serviceJSON, err = sjson.SetRawBytes(serviceJSON, "plans.-1", []byte(plan.Raw)); err != nil {
+					return nil, err
+				}
+			}
+			for _, field := range []string{"instances_retrievable", "bindings_retrievable"} {
+				if serviceJSON, err = sjson.SetBytes(serviceJSON, field, false); err != nil {
+					return nil, err
+				}
+			}
+			if catalog, err = sjson.SetRawBytes(catalog, "services.-1", serviceJSON); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// Route returns the member owning the service of the request. The service and the plan are taken from the body
+// or, e.g. for deprovisioning, from the query of the request.
+func (c *Composite) Route(r *web.Request) (*types.ServiceBroker, error) {
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	serviceID := gjson.GetBytes(body, "service_id").String()
+	if serviceID == "" {
+		serviceID = r.URL.Query().Get("service_id")
+	}
+	planID := gjson.GetBytes(body, "plan_id").String()
+	if planID == "" {
+		planID = r.URL.Query().Get("plan_id")
+	}
+	if serviceID == "" {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("the service id is required to route calls to composite broker %s", c.Name),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	for _, member := range c.Members {
+		plans, found := member.Plans[serviceID]
+		if !found {
+			continue
+		}
+		if planID != "" && !plans[planID] {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: fmt.Sprintf("plan %s is not offered by composite broker %s", planID, c.Name),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		return member.Broker, nil
+	}
+	return nil, &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("service %s is not offered by composite broker %s", serviceID, c.Name),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
+
+// compositeBroker returns the broker handling a call to a composite broker. Catalog calls are answered by a broker
+// with the catalog of the composite broker, the other calls are routed to the members.
+func (c *Controller) compositeBroker(r *web.Request, compositeID string) (*types.ServiceBroker, error) {
+	composite, err := c.CompositeFetcher(r.Context(), compositeID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/v2/catalog") {
+		return composite.Route(r)
+	}
+	catalog, err := composite.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	return &types.ServiceBroker{
+		Base:    types.Base{ID: composite.ID},
+		Name:    composite.Name,
+		Catalog: catalog,
+	}, nil
+}
+
+func isNotFound(err error) bool {
+	httpErr, ok := err.(*util.HTTPError)
+	return ok && httpErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	"github.com/tidwall/gjson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Composite brokers", func() {
+	var (
+		repository *storagefakes.FakeStorage
+		brokers    map[string]*types.ServiceBroker
+		composite  *osb.Composite
+	)
+
+	brokers = map[string]*types.ServiceBroker{
+		"broker-a": {
+			Base:    types.Base{ID: "broker-a"},
+			Name:    "a",
+			Catalog: []byte(`{"services":[{"id":"service-a","instances_retrievable":true,"plans":[{"id":"plan-a1"},{"id":"plan-a2"}]}]}`),
+		},
+		"broker-b": {
+			Base:    types.Base{ID: "broker-b", Labels: types.Labels{osb.CatalogNamespaceLabel: {"ns"}}},
+			Name:    "b",
+			Catalog: []byte(`{"services":[{"id":"service-b","plans":[{"id":"plan-b1"}]},{"id":"service-c","plans":[{"id":"plan-c1"}]}]}`),
+		},
+	}
+
+	request := func(method, path, body string) *web.Request {
+		return &web.Request{Request: httptest.NewRequest(method, path, nil), Body: []byte(body)}
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		repository.GetReturns(&types.CompositeBroker{
+			Base:    types.Base{ID: "composite"},
+			Name:    "marketplace",
+			PlanIDs: []string{"sm-plan-a1", "sm-plan-b1"},
+		}, nil)
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: []*types.ServicePlan{
+					{Base: types.Base{ID: "sm-plan-a1"}, CatalogID: "plan-a1", ServiceOfferingID: "sm-service-a"},
+					{Base: types.Base{ID: "sm-plan-b1"}, CatalogID: "plan-b1", ServiceOfferingID: "sm-service-b"},
+				}}, nil
+			case types.ServiceOfferingType:
+				return &types.ServiceOfferings{ServiceOfferings: []*types.ServiceOffering{
+					{Base: types.Base{ID: "sm-service-a"}, CatalogID: "service-a", BrokerID: "broker-a"},
+					{Base: types.Base{ID: "sm-service-b"}, CatalogID: "service-b", BrokerID: "broker-b"},
+				}}, nil
+			}
+			return nil, util.ErrNotFoundInStorage
+		})
+		fetcher := osb.NewCompositeFetcher(repository, func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+			return brokers[brokerID], nil
+		})
+		var err error
+		composite, err = fetcher(context.Background(), "composite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(composite.Members).To(HaveLen(2))
+	})
+
+	It("composes the catalog of the selected plans", func() {
+		catalog, err := composite.Catalog()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(gjson.GetBytes(catalog, "services.#.id").String()).To(MatchJSON(`["service-a", "ns-service-b"]`))
+		Expect(gjson.GetBytes(catalog, "services.0.plans.#.id").String()).To(MatchJSON(`["plan-a1"]`))
+		Expect(gjson.GetBytes(catalog, "services.1.plans.#.id").String()).To(MatchJSON(`["ns-plan-b1"]`))
+		Expect(gjson.GetBytes(catalog, "services.0.instances_retrievable").Bool()).To(BeFalse())
+	})
+
+	It("routes calls to the broker owning the service", func() {
+		broker, err := composite.Route(request(http.MethodPut, "/v2/service_instances/instance", `{"service_id":"ns-service-b","plan_id":"ns-plan-b1"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.ID).To(Equal("broker-b"))
+
+		broker, err = composite.Route(request(http.MethodDelete, "/v2/service_instances/instance?service_id=service-a&plan_id=plan-a1", ""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.ID).To(Equal("broker-a"))
+	})
+
+	It("rejects calls for plans which are not selected", func() {
+		_, err := composite.Route(request(http.MethodPut, "/v2/service_instances/instance", `{"service_id":"service-a","plan_id":"plan-a2"}`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects calls without a service", func() {
+		_, err := composite.Route(request(http.MethodGet, "/v2/service_instances/instance", ""))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// credentialsOperations are the OSB operations whose responses contain binding credentials
+var credentialsOperations = map[string]bool{
+	"bind":              true,
+	"fetch_binding":     true,
+	"adapt_credentials": true,
+}
+
+// BindingDetails identifies the service binding whose credentials are transformed
+type BindingDetails struct {
+	Broker     *types.ServiceBroker
+	InstanceID string
+	BindingID  string
+}
+
+// CredentialsTransformer transforms or redacts the credentials of service bindings before they are returned to
+// the platform, e.g. it replaces them with a reference to a secrets store
+type CredentialsTransformer interface {
+	web.Named
+
+	// TransformCredentials returns the credentials which are returned to the platform and whether they differ
+	// from the provided credentials
+	TransformCredentials(ctx context.Context, binding *BindingDetails, credentials json.RawMessage) (json.RawMessage, bool, error)
+}
+
+// CredentialsPipeline applies the registered credentials transformers to the binding credentials returned by brokers
+// in order of registration. Each transformation is recorded in an audit log entry.
+type CredentialsPipeline struct {
+	transformers []CredentialsTransformer
+}
+
+// Register adds transformers to the end of the pipeline. Transformers should be registered before the pipeline is used.
+func (p *CredentialsPipeline) Register(transformers ...CredentialsTransformer) {
+	p.transformers = append(p.transformers, transformers...)
+}
+
+// Transform applies the pipeline to the credentials in the body of a broker response. A body without credentials
+// is returned as it is.
+func (p *CredentialsPipeline) Transform(ctx context.Context, binding *BindingDetails, body []byte) ([]byte, error) {
+	if p == nil || len(p.transformers) == 0 {
+		return body, nil
+	}
+	credentials := gjson.GetBytes(body, "credentials")
+	if !credentials.Exists() {
+		return body, nil
+	}
+
+	transformed := json.RawMessage(credentials.Raw)
+	changedBy := make([]string, 0, len(p.transformers))
+	for _, transformer := range p.transformers {
+		result, changed, err := transformer.TransformCredentials(ctx, binding, transformed)
+		if err != nil {
+			return nil, fmt.Errorf("credentials transformer %s failed: %s", transformer.Name(), err)
+		}
+		if changed {
+			transformed = result
+			changedBy = append(changedBy, transformer.Name())
+		}
+	}
+	if len(changedBy) == 0 {
+		return body, nil
+	}
+
+	user := ""
+	if userContext, found := web.UserFromContext(ctx); found {
+		user = userContext.Name
+	}
+	log.C(ctx).WithFields(logrus.Fields{
+		"audit":        "binding_credentials_transformed",
+		"broker_id":    binding.Broker.ID,
+		"instance_id":  binding.InstanceID,
+		"binding_id":   binding.BindingID,
+		"transformers": changedBy,
+		"user":         user,
+	}).Infof("Credentials of binding %s of instance %s of broker %s were transformed before returning them to the platform",
+		binding.BindingID, binding.InstanceID, binding.Broker.Name)
+
+	return sjson.SetRawBytes(body, "credentials", transformed)
+}
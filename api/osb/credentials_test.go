@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type credentialsTransformerFunc struct {
+	name      string
+	transform func(credentials json.RawMessage) (json.RawMessage, bool, error)
+}
+
+func (t *credentialsTransformerFunc) Name() string {
+	return t.name
+}
+
+func (t *credentialsTransformerFunc) TransformCredentials(ctx context.Context, binding *osb.BindingDetails, credentials json.RawMessage) (json.RawMessage, bool, error) {
+	return t.transform(credentials)
+}
+
+var _ = Describe("CredentialsPipeline", func() {
+	const body = `{"credentials": {"password": "secret"}, "route_service_url": "https://route.example.com"}`
+
+	var (
+		pipeline *osb.CredentialsPipeline
+		binding  *osb.BindingDetails
+		redactor *credentialsTransformerFunc
+	)
+
+	BeforeEach(func() {
+		pipeline = &osb.CredentialsPipeline{}
+		binding = &osb.BindingDetails{
+			Broker:     &types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "broker"},
+			InstanceID: "instance-id",
+			BindingID:  "binding-id",
+		}
+		redactor = &credentialsTransformerFunc{
+			name: "redactor",
+			transform: func(credentials json.RawMessage) (json.RawMessage, bool, error) {
+				return json.RawMessage(`{"secret_ref": "vault://binding-id"}`), true, nil
+			},
+		}
+	})
+
+	It("returns the body as it is without transformers", func() {
+		transformed, err := pipeline.Transform(context.TODO(), binding, []byte(body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(transformed)).To(Equal(body))
+	})
+
+	It("replaces the credentials and keeps the other fields", func() {
+		pipeline.Register(redactor)
+		transformed, err := pipeline.Transform(context.TODO(), binding, []byte(body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(transformed)).To(MatchJSON(`{"credentials": {"secret_ref": "vault://binding-id"}, "route_service_url": "https://route.example.com"}`))
+	})
+
+	It("passes the result of a transformer to the next one", func() {
+		var received json.RawMessage
+		pipeline.Register(redactor, &credentialsTransformerFunc{
+			name: "observer",
+			transform: func(credentials json.RawMessage) (json.RawMessage, bool, error) {
+				received = credentials
+				return nil, false, nil
+			},
+		})
+		transformed, err := pipeline.Transform(context.TODO(), binding, []byte(body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(received)).To(MatchJSON(`{"secret_ref": "vault://binding-id"}`))
+		Expect(string(transformed)).To(ContainSubstring("secret_ref"))
+	})
+
+	It("returns bodies without credentials as they are", func() {
+		pipeline.Register(redactor)
+		transformed, err := pipeline.Transform(context.TODO(), binding, []byte(`{"operation": "bind"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(transformed)).To(Equal(`{"operation": "bind"}`))
+	})
+
+	It("fails if a transformer fails", func() {
+		pipeline.Register(&credentialsTransformerFunc{
+			name: "failing",
+			transform: func(credentials json.RawMessage) (json.RawMessage, bool, error) {
+				return nil, false, errors.New("secrets store unavailable")
+			},
+		})
+		_, err := pipeline.Transform(context.TODO(), binding, []byte(body))
+		Expect(err).To(MatchError(ContainSubstring("secrets store unavailable")))
+	})
+})
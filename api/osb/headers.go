@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+const (
+	// RequestHeadersAllowedLabel is the broker label which overrides the request headers allowed to be forwarded to the broker
+	RequestHeadersAllowedLabel = "osb_request_headers_allowed"
+
+	// RequestHeadersDeniedLabel is the broker label which overrides the request headers that are not forwarded to the broker
+	RequestHeadersDeniedLabel = "osb_request_headers_denied"
+
+	// ResponseHeadersAllowedLabel is the broker label which overrides the broker response headers allowed to be returned to the client
+	ResponseHeadersAllowedLabel = "osb_response_headers_allowed"
+
+	// ResponseHeadersDeniedLabel is the broker label which overrides the broker response headers that are not returned to the client
+	ResponseHeadersDeniedLabel = "osb_response_headers_denied"
+)
+
+// hopByHopHeaders are meaningful only for a single connection and are never forwarded (RFC 7230 section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// credentialRequestHeaders carry the credentials of the caller and are never forwarded to the brokers, whatever
+// the settings and broker labels, as the Service Manager authenticates with its own credentials for the broker
+var credentialRequestHeaders = []string{"Authorization", "Cookie"}
+
+// credentialResponseHeaders carry the credentials of the broker and are never returned to the clients
+var credentialResponseHeaders = []string{"Set-Cookie"}
+
+// requiredRequestHeaders are always forwarded to the brokers as the brokers cannot process the requests without them
+var requiredRequestHeaders = []string{brokerAPIVersionHeader}
+
+// HeaderSettings type to be loaded from the environment
+type HeaderSettings struct {
+	RequestAllowed  []string `mapstructure:"request_allowed" description:"headers forwarded to the brokers, if empty all headers that are not denied are forwarded"`
+	RequestDenied   []string `mapstructure:"request_denied" description:"headers which are never forwarded to the brokers"`
+	ResponseAllowed []string `mapstructure:"response_allowed" description:"broker response headers returned to the clients, if empty all headers that are not denied are returned"`
+	ResponseDenied  []string `mapstructure:"response_denied" description:"broker response headers which are never returned to the clients"`
+}
+
+// DefaultHeaderSettings returns the default OSB proxy header policy. The credentials of the caller are not
+// forwarded to the brokers as the Service Manager authenticates with its own credentials for the broker.
+func DefaultHeaderSettings() *HeaderSettings {
+	return &HeaderSettings{
+		RequestAllowed:  []string{},
+		RequestDenied:   append([]string{}, credentialRequestHeaders...),
+		ResponseAllowed: []string{},
+		ResponseDenied:  append([]string{}, credentialResponseHeaders...),
+	}
+}
+
+type headerPolicy struct {
+	allowed  map[string]bool
+	denied   map[string]bool
+	required map[string]bool
+}
+
+func newHeaderPolicy(allowed, denied, alwaysDenied, required []string) *headerPolicy {
+	policy := &headerPolicy{
+		allowed:  make(map[string]bool, len(allowed)),
+		denied:   make(map[string]bool, len(denied)+len(alwaysDenied)+len(hopByHopHeaders)),
+		required: make(map[string]bool, len(required)),
+	}
+	for _, header := range allowed {
+		policy.allowed[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	for _, header := range denied {
+		policy.denied[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	for _, header := range alwaysDenied {
+		policy.denied[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, header := range hopByHopHeaders {
+		policy.denied[header] = true
+	}
+	for _, header := range required {
+		policy.required[http.CanonicalHeaderKey(header)] = true
+	}
+	return policy
+}
+
+// apply returns a copy of the provided headers which contains only the headers permitted by the policy
+func (p *headerPolicy) apply(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		if !p.required[key] {
+			if p.denied[key] {
+				continue
+			}
+			if len(p.allowed) > 0 && !p.allowed[key] {
+				continue
+			}
+		}
+		result[key] = append([]string{}, values...)
+	}
+	return result
+}
+
+// headerPolicies returns the request and response header policies for the specified broker.
+// Broker labels take precedence over the settings, but neither can forward the credential headers or drop the
+// OSB API version header.
+func headerPolicies(settings *HeaderSettings, broker *types.ServiceBroker) (*headerPolicy, *headerPolicy) {
+	if settings == nil {
+		settings = DefaultHeaderSettings()
+	}
+	request := newHeaderPolicy(
+		labelOrDefault(broker, RequestHeadersAllowedLabel, settings.RequestAllowed),
+		labelOrDefault(broker, RequestHeadersDeniedLabel, settings.RequestDenied),
+		credentialRequestHeaders,
+		requiredRequestHeaders,
+	)
+	response := newHeaderPolicy(
+		labelOrDefault(broker, ResponseHeadersAllowedLabel, settings.ResponseAllowed),
+		labelOrDefault(broker, ResponseHeadersDeniedLabel, settings.ResponseDenied),
+		credentialResponseHeaders,
+		nil,
+	)
+	return request, response
+}
+
+func labelOrDefault(broker *types.ServiceBroker, label string, defaultValues []string) []string {
+	if values, found := broker.Labels[label]; found {
+		return values
+	}
+	return defaultValues
+}
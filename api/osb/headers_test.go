@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header policies", func() {
+	var (
+		broker *types.ServiceBroker
+		header http.Header
+	)
+
+	BeforeEach(func() {
+		broker = &types.ServiceBroker{
+			Base: types.Base{
+				Labels: types.Labels{},
+			},
+		}
+		header = http.Header{
+			"Authorization":                     []string{"Bearer token"},
+			"Connection":                        []string{"keep-alive"},
+			"X-Broker-Api-Version":              []string{"2.13"},
+			"X-Custom-Header":                   []string{"value"},
+			"X-Correlation-Id":                  []string{"id"},
+			"Set-Cookie":                        []string{"session=1"},
+			"X-Broker-Api-Originating-Identity": []string{"identity"},
+		}
+	})
+
+	Context("with the default settings", func() {
+		It("strips the caller credentials and hop-by-hop headers from requests", func() {
+			request, _ := headerPolicies(nil, broker)
+			result := request.apply(header)
+
+			Expect(result).ToNot(HaveKey("Authorization"))
+			Expect(result).ToNot(HaveKey("Connection"))
+			Expect(result).To(HaveKey("X-Broker-Api-Version"))
+			Expect(result).To(HaveKey("X-Custom-Header"))
+		})
+
+		It("strips cookies from responses", func() {
+			_, response := headerPolicies(nil, broker)
+			Expect(response.apply(header)).ToNot(HaveKey("Set-Cookie"))
+		})
+
+		It("does not modify the original headers", func() {
+			request, _ := headerPolicies(nil, broker)
+			request.apply(header)
+			Expect(header).To(HaveKey("Authorization"))
+		})
+	})
+
+	Context("with an allowlist", func() {
+		It("forwards only the allowed headers", func() {
+			settings := DefaultHeaderSettings()
+			settings.RequestAllowed = []string{"x-broker-api-version", "Connection"}
+
+			request, _ := headerPolicies(settings, broker)
+			Expect(request.apply(header)).To(Equal(http.Header{
+				"X-Broker-Api-Version": []string{"2.13"},
+			}))
+		})
+
+		It("always forwards the OSB API version", func() {
+			settings := DefaultHeaderSettings()
+			settings.RequestAllowed = []string{"X-Custom-Header"}
+
+			request, _ := headerPolicies(settings, broker)
+			Expect(request.apply(header)).To(Equal(http.Header{
+				"X-Broker-Api-Version": []string{"2.13"},
+				"X-Custom-Header":      []string{"value"},
+			}))
+		})
+	})
+
+	Context("when the broker overrides the settings", func() {
+		It("uses the broker labels", func() {
+			broker.Labels[RequestHeadersDeniedLabel] = []string{"X-Custom-Header"}
+
+			request, _ := headerPolicies(DefaultHeaderSettings(), broker)
+			result := request.apply(header)
+			Expect(result).ToNot(HaveKey("X-Custom-Header"))
+			Expect(result).To(HaveKey("X-Correlation-Id"))
+		})
+
+		It("does not forward the credential headers", func() {
+			broker.Labels[RequestHeadersDeniedLabel] = []string{"X-Custom-Header"}
+			broker.Labels[ResponseHeadersDeniedLabel] = []string{}
+
+			request, response := headerPolicies(DefaultHeaderSettings(), broker)
+			Expect(request.apply(header)).ToNot(HaveKey("Authorization"))
+			Expect(request.apply(http.Header{"Cookie": []string{"session=1"}})).ToNot(HaveKey("Cookie"))
+			Expect(response.apply(header)).ToNot(HaveKey("Set-Cookie"))
+		})
+
+		It("does not drop the OSB API version", func() {
+			broker.Labels[RequestHeadersAllowedLabel] = []string{"X-Custom-Header"}
+			broker.Labels[RequestHeadersDeniedLabel] = []string{"X-Broker-API-Version"}
+
+			request, _ := headerPolicies(DefaultHeaderSettings(), broker)
+			Expect(request.apply(header)).To(Equal(http.Header{
+				"X-Broker-Api-Version": []string{"2.13"},
+				"X-Custom-Header":      []string{"value"},
+			}))
+		})
+	})
+})
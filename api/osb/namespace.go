@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CatalogNamespaceLabel is the broker label which prefixes the catalog IDs of the services and plans of the broker
+// served through the OSB API, so that brokers with colliding catalog IDs can be registered in the same platform
+const CatalogNamespaceLabel = "osb_catalog_namespace"
+
+const namespaceSeparator = "-"
+
+// catalogIDFields are the fields of OSB requests and instance responses which contain catalog IDs
+var catalogIDFields = []string{"service_id", "plan_id", "previous_values.service_id", "previous_values.plan_id"}
+
+// CatalogNamespace prefixes the catalog IDs served to the platforms and removes the prefix from the catalog IDs
+// sent to the broker. An empty namespace leaves the catalog IDs as they are.
+type CatalogNamespace string
+
+// CatalogNamespaceOf returns the catalog namespace of the broker, which is empty if the broker has none
+func CatalogNamespaceOf(broker *types.ServiceBroker) CatalogNamespace {
+	if values := broker.Labels[CatalogNamespaceLabel]; len(values) == 1 {
+		return CatalogNamespace(strings.TrimSpace(values[0]))
+	}
+	return ""
+}
+
+// Add returns the catalog ID served to the platforms for the catalog ID of the broker
+func (ns CatalogNamespace) Add(catalogID string) string {
+	if ns == "" || catalogID == "" {
+		return catalogID
+	}
+	return string(ns) + namespaceSeparator + catalogID
+}
+
+// Remove returns the catalog ID of the broker for the catalog ID served to the platforms. The prefix is only removed
+// if the catalog ID without it is known, i.e. is in the catalog of the broker. Other catalog IDs, e.g. of instances
+// created before the namespace was set, are returned as they are, even if they happen to start with the prefix.
+func (ns CatalogNamespace) Remove(catalogID string, known func(catalogID string) bool) string {
+	if ns == "" || !strings.HasPrefix(catalogID, string(ns)+namespaceSeparator) {
+		return catalogID
+	}
+	if unprefixed := strings.TrimPrefix(catalogID, string(ns)+namespaceSeparator); known(unprefixed) {
+		return unprefixed
+	}
+	return catalogID
+}
+
+// catalogIDs returns whether a catalog ID is the ID of a service or plan in the catalog of the broker
+func catalogIDs(catalog []byte) func(catalogID string) bool {
+	ids := make(map[string]bool)
+	for _, service := range gjson.GetBytes(catalog, "services").Array() {
+		ids[service.Get("id").String()] = true
+		for _, plan := range service.Get("plans").Array() {
+			ids[plan.Get("id").String()] = true
+		}
+	}
+	return func(catalogID string) bool {
+		return ids[catalogID]
+	}
+}
+
+// Catalog prefixes the IDs of the services and plans in the catalog of the broker
+func (ns CatalogNamespace) Catalog(catalog []byte) ([]byte, error) {
+	if ns == "" {
+		return catalog, nil
+	}
+	// the catalog of the broker must not be modified as the broker might be cached
+	catalog = append([]byte{}, catalog...)
+	var err error
+	services := gjson.GetBytes(catalog, "services").Array()
+	for i, service := range services {
+		if catalog, err = sjson.SetBytes(catalog, fmt.Sprintf("services.%d.id", i), ns.Add(service.Get("id").String())); err != nil {
+			return nil, err
+		}
+		for j, plan := range service.Get("plans").Array() {
+			if catalog, err = sjson.SetBytes(catalog, fmt.Sprintf("services.%d.plans.%d.id", i, j), ns.Add(plan.Get("id").String())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// Request removes the prefix from the catalog IDs in the body and the query of a request to the broker with the
+// provided catalog
+func (ns CatalogNamespace) Request(body []byte, requestURL *url.URL, catalog []byte) ([]byte, error) {
+	if ns == "" {
+		return body, nil
+	}
+	known := catalogIDs(catalog)
+	remove := func(catalogID string) string {
+		return ns.Remove(catalogID, known)
+	}
+	query := requestURL.Query()
+	for _, param := range []string{"service_id", "plan_id"} {
+		if value := query.Get(param); value != "" {
+			query.Set(param, remove(value))
+		}
+	}
+	requestURL.RawQuery = query.Encode()
+	return ns.mapFields(body, remove)
+}
+
+// Instance prefixes the catalog IDs in the response of the broker to a fetch instance request
+func (ns CatalogNamespace) Instance(body []byte) ([]byte, error) {
+	if ns == "" {
+		return body, nil
+	}
+	return ns.mapFields(body, ns.Add)
+}
+
+func (ns CatalogNamespace) mapFields(body []byte, mapping func(string) string) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+	var err error
+	for _, field := range catalogIDFields {
+		if value := gjson.GetBytes(body, field); value.Type == gjson.String {
+			if body, err = sjson.SetBytes(body, field, mapping(value.String())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return body, nil
+}
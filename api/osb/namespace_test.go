@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"net/url"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CatalogNamespace", func() {
+	namespace := osb.CatalogNamespace("acme")
+
+	It("is read from the broker labels", func() {
+		broker := &types.ServiceBroker{Base: types.Base{Labels: types.Labels{osb.CatalogNamespaceLabel: {"acme"}}}}
+		Expect(osb.CatalogNamespaceOf(broker)).To(Equal(namespace))
+		Expect(osb.CatalogNamespaceOf(&types.ServiceBroker{})).To(BeEmpty())
+	})
+
+	It("prefixes the service and plan IDs of the catalog", func() {
+		catalog := []byte(`{"services":[{"id":"service","name":"db","plans":[{"id":"small"},{"id":"large"}]}]}`)
+
+		result, err := namespace.Catalog(catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"services":[{"id":"acme-service","name":"db","plans":[{"id":"acme-small"},{"id":"acme-large"}]}]}`))
+		Expect(catalog).To(MatchJSON(`{"services":[{"id":"service","name":"db","plans":[{"id":"small"},{"id":"large"}]}]}`))
+	})
+
+	catalog := []byte(`{"services":[{"id":"service","plans":[{"id":"small"},{"id":"large"}]},{"id":"acme-legacy"}]}`)
+
+	It("removes the prefix from the catalog IDs of requests", func() {
+		requestURL, _ := url.Parse("/v2/service_instances/1?service_id=acme-service&plan_id=acme-small")
+		body := []byte(`{"service_id":"acme-service","plan_id":"acme-large","previous_values":{"plan_id":"acme-small"}}`)
+
+		result, err := namespace.Request(body, requestURL, catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"service_id":"service","plan_id":"large","previous_values":{"plan_id":"small"}}`))
+		Expect(requestURL.Query().Get("service_id")).To(Equal("service"))
+		Expect(requestURL.Query().Get("plan_id")).To(Equal("small"))
+	})
+
+	It("leaves catalog IDs without the prefix as they are", func() {
+		requestURL, _ := url.Parse("/v2/service_instances/1")
+
+		result, err := namespace.Request([]byte(`{"service_id":"service"}`), requestURL, catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"service_id":"service"}`))
+	})
+
+	It("leaves catalog IDs which start with the prefix but are not prefixed as they are", func() {
+		requestURL, _ := url.Parse("/v2/service_instances/1?service_id=acme-legacy")
+
+		result, err := namespace.Request([]byte(`{"service_id":"acme-legacy","plan_id":"acme-unknown"}`), requestURL, catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"service_id":"acme-legacy","plan_id":"acme-unknown"}`))
+		Expect(requestURL.Query().Get("service_id")).To(Equal("acme-legacy"))
+	})
+
+	It("prefixes the catalog IDs of instances", func() {
+		result, err := namespace.Instance([]byte(`{"service_id":"service","plan_id":"small","parameters":{}}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"service_id":"acme-service","plan_id":"acme-small","parameters":{}}`))
+	})
+
+	It("changes nothing without a namespace", func() {
+		catalog := []byte(`{"services":[{"id":"service"}]}`)
+		result, err := osb.CatalogNamespace("").Catalog(catalog)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(catalog))
+	})
+})
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// ServiceInstanceResourceType is the resource type of the operations which mitigate orphaned service instances
+	ServiceInstanceResourceType types.ObjectType = "osb.ServiceInstance"
+
+	// ServiceBindingResourceType is the resource type of the operations which mitigate orphaned service bindings
+	ServiceBindingResourceType types.ObjectType = "osb.ServiceBinding"
+
+	originatingIdentityHeader = "X-Broker-API-Originating-Identity"
+)
+
+// OrphanMitigationSettings type to be loaded from the environment
+type OrphanMitigationSettings struct {
+	Enabled       bool          `mapstructure:"enabled" description:"whether failed provision and bind calls which may have left orphans at the broker are followed by a deprovision or unbind call, disable it if the platforms perform the orphan mitigation themselves"`
+	Attempts      int           `mapstructure:"attempts" description:"maximum number of attempts of the deprovision or unbind call of an orphan mitigation"`
+	RetryInterval time.Duration `mapstructure:"retry_interval" description:"time to wait before the first retry of an orphan mitigation, doubled with each further retry"`
+}
+
+// DefaultOrphanMitigationSettings returns the default orphan mitigation settings
+func DefaultOrphanMitigationSettings() *OrphanMitigationSettings {
+	return &OrphanMitigationSettings{
+		Enabled:       false,
+		Attempts:      5,
+		RetryInterval: 10 * time.Second,
+	}
+}
+
+// Validate validates the orphan mitigation settings
+func (s *OrphanMitigationSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.Attempts < 1 {
+		return fmt.Errorf("validate Settings: orphan mitigation attempts must be > 0")
+	}
+	if s.RetryInterval < 0 {
+		return fmt.Errorf("validate Settings: orphan mitigation retry interval must be >= 0")
+	}
+	return nil
+}
+
+// Orphan is a service instance or binding which the broker may have created although its creation failed
+type Orphan struct {
+	InstanceID string
+	BindingID  string
+	ServiceID  string
+	PlanID     string
+
+	// Header contains the OSB headers of the failed call, e.g. the API version, which are sent with the mitigation call
+	Header http.Header
+}
+
+// requiresOrphanMitigation returns whether the outcome of the OSB operation may have left an orphan at the broker.
+// As defined by the OSB specification these are timeouts, server errors and successful responses which are malformed.
+func requiresOrphanMitigation(operation string, statusCode int, body []byte) bool {
+	if operation != "provision" && operation != "bind" {
+		return false
+	}
+	switch {
+	case statusCode == http.StatusRequestTimeout || statusCode >= http.StatusInternalServerError:
+		return true
+	case statusCode == http.StatusOK || statusCode == http.StatusCreated:
+		return !json.Valid(body)
+	default:
+		return false
+	}
+}
+
+// OrphanMitigator deprovisions service instances and unbinds service bindings whose creation failed in a way which
+// may have left them orphaned at the broker. Each mitigation runs in the background, is retried with an exponential
+// backoff and is recorded as an operation.
+type OrphanMitigator struct {
+	ctx                   context.Context
+	settings              *OrphanMitigationSettings
+	repository            storage.Repository
+	doRequestFuncProvider func(broker *types.ServiceBroker) (util.DoRequestFunc, error)
+}
+
+// NewOrphanMitigator returns an orphan mitigator which calls the brokers with the request functions of the provider.
+// Pending mitigations are abandoned when the provided context is done.
+func NewOrphanMitigator(ctx context.Context, settings *OrphanMitigationSettings, repository storage.Repository, doRequestFuncProvider func(broker *types.ServiceBroker) (util.DoRequestFunc, error)) *OrphanMitigator {
+	return &OrphanMitigator{
+		ctx:                   ctx,
+		settings:              settings,
+		repository:            repository,
+		doRequestFuncProvider: doRequestFuncProvider,
+	}
+}
+
+// Mitigate records an operation for the mitigation of the orphan and starts it in the background
+func (m *OrphanMitigator) Mitigate(ctx context.Context, broker *types.ServiceBroker, orphan *Orphan) (*types.Operation, error) {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for %s: %s", types.OperationObjectType, err)
+	}
+	resourceID, resourceType := orphan.InstanceID, ServiceInstanceResourceType
+	if orphan.BindingID != "" {
+		resourceID, resourceType = orphan.BindingID, ServiceBindingResourceType
+	}
+	currentTime := time.Now().UTC()
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Labels:    types.Labels{},
+		},
+		Type:         types.DELETE,
+		State:        types.IN_PROGRESS,
+		ResourceID:   resourceID,
+		ResourceType: resourceType,
+		Description:  fmt.Sprintf("orphan mitigation at broker %s scheduled", broker.Name),
+	}
+	if _, err := m.repository.Create(ctx, operation); err != nil {
+		return nil, util.HandleStorageError(err, string(types.OperationObjectType))
+	}
+
+	log.C(ctx).Infof("Scheduled orphan mitigation of %s with id %s at broker %s", resourceType, resourceID, broker.Name)
+	go m.mitigate(log.ContextWithLogger(m.ctx, log.C(ctx)), broker, orphan, operation)
+	return operation, nil
+}
+
+func (m *OrphanMitigator) mitigate(ctx context.Context, broker *types.ServiceBroker, orphan *Orphan, operation *types.Operation) {
+	attempts := m.settings.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	retryInterval := m.settings.RetryInterval
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		m.updateOperation(ctx, operation, types.IN_PROGRESS, fmt.Sprintf("orphan mitigation at broker %s (attempt %d of %d)", broker.Name, attempt, attempts), nil)
+
+		var statusCode int
+		if statusCode, err = m.delete(ctx, broker, orphan); err == nil {
+			m.updateOperation(ctx, operation, types.SUCCEEDED, fmt.Sprintf("orphan mitigation at broker %s completed with status %d", broker.Name, statusCode), nil)
+			return
+		}
+		log.C(ctx).WithError(err).Warnf("Attempt %d of %d to mitigate orphan %s at broker %s failed", attempt, attempts, operation.ResourceID, broker.Name)
+
+		if attempt < attempts {
+			select {
+			case <-ctx.Done():
+				m.updateOperation(ctx, operation, types.FAILED, "orphan mitigation was interrupted", ctx.Err())
+				return
+			case <-time.After(retryInterval):
+			}
+			retryInterval *= 2
+		}
+	}
+
+	m.updateOperation(ctx, operation, types.FAILED, fmt.Sprintf("could not mitigate orphan at broker %s", broker.Name), err)
+}
+
+// delete deprovisions the orphaned instance or unbinds the orphaned binding. Gone and accepted responses are successful,
+// as the orphan is either already deleted or the broker took over its deletion.
+func (m *OrphanMitigator) delete(ctx context.Context, broker *types.ServiceBroker, orphan *Orphan) (int, error) {
+	doRequestFunc, err := m.doRequestFuncProvider(broker)
+	if err != nil {
+		return 0, err
+	}
+	path := fmt.Sprintf("%s/v2/service_instances/%s", broker.BrokerURL, orphan.InstanceID)
+	if orphan.BindingID != "" {
+		path = fmt.Sprintf("%s/service_bindings/%s", path, orphan.BindingID)
+	}
+	params := map[string]string{
+		"service_id":         orphan.ServiceID,
+		"plan_id":            orphan.PlanID,
+		"accepts_incomplete": "true",
+	}
+	headers := map[string]string{}
+	for _, header := range []string{brokerAPIVersionHeader, originatingIdentityHeader} {
+		if value := orphan.Header.Get(header); value != "" {
+			headers[header] = value
+		}
+	}
+
+	requestWithBasicAuth := util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequestFunc)
+	response, err := util.SendRequestWithHeaders(ctx, requestWithBasicAuth, http.MethodDelete, path, params, nil, headers)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusGone:
+		return response.StatusCode, nil
+	default:
+		return response.StatusCode, fmt.Errorf("broker %s responded with %s", broker.Name, response.Status)
+	}
+}
+
+func (m *OrphanMitigator) updateOperation(ctx context.Context, operation *types.Operation, state types.OperationState, description string, opErr error) {
+	operation.State = state
+	operation.Description = description
+	operation.UpdatedAt = time.Now().UTC()
+	operation.Errors = nil
+	if opErr != nil {
+		errorBytes, err := json.Marshal(map[string]string{"description": opErr.Error()})
+		if err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not marshal errors of operation with id %s", operation.ID)
+		}
+		operation.Errors = errorBytes
+	}
+
+	if _, err := m.repository.Update(ctx, operation); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not update operation with id %s to state %s", operation.ID, state)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	"github.com/Peripli/service-manager/test/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Orphan mitigation", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		settings   *osb.OrphanMitigationSettings
+		repository *storagefakes.FakeStorage
+		broker     *types.ServiceBroker
+		mitigator  *osb.OrphanMitigator
+
+		mutex     sync.Mutex
+		requests  []*http.Request
+		statuses  []int
+		operation types.Operation
+	)
+
+	operationState := func() types.OperationState {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return operation.State
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		settings = osb.DefaultOrphanMitigationSettings()
+		settings.Enabled = true
+		settings.Attempts = 2
+		settings.RetryInterval = time.Millisecond
+
+		broker = &types.ServiceBroker{
+			Base: types.Base{ID: "broker-id"},
+			Name: "broker",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "username", Password: "password"},
+			},
+			BrokerURL: "http://broker.example.com",
+		}
+
+		requests = nil
+		statuses = []int{http.StatusOK}
+		operation = types.Operation{}
+		repository = &storagefakes.FakeStorage{}
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			return obj, nil
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			operation = *obj.(*types.Operation)
+			return obj, nil
+		})
+
+		mitigator = osb.NewOrphanMitigator(ctx, settings, repository, func(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+			return func(request *http.Request) (*http.Response, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				requests = append(requests, request)
+				status := statuses[0]
+				if len(statuses) > 1 {
+					statuses = statuses[1:]
+				}
+				return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: common.Closer("{}"), Request: request}, nil
+			}, nil
+		})
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(osb.DefaultOrphanMitigationSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid when enabled without attempts", func() {
+			settings.Attempts = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Context("when a provision failed", func() {
+		orphan := func() *osb.Orphan {
+			return &osb.Orphan{
+				InstanceID: "instance-id",
+				ServiceID:  "service-id",
+				PlanID:     "plan-id",
+				Header:     http.Header{"X-Broker-Api-Version": []string{"2.13"}},
+			}
+		}
+
+		It("deprovisions the instance and records the mitigation", func() {
+			op, err := mitigator.Mitigate(ctx, broker, orphan())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.Type).To(Equal(types.DELETE))
+			Expect(op.ResourceID).To(Equal("instance-id"))
+			Expect(op.ResourceType).To(Equal(osb.ServiceInstanceResourceType))
+			Expect(repository.CreateCallCount()).To(Equal(1))
+
+			Eventually(operationState).Should(Equal(types.SUCCEEDED))
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodDelete))
+			Expect(requests[0].URL.Path).To(Equal("/v2/service_instances/instance-id"))
+			Expect(requests[0].URL.Query().Get("service_id")).To(Equal("service-id"))
+			Expect(requests[0].URL.Query().Get("plan_id")).To(Equal("plan-id"))
+			Expect(requests[0].Header.Get("X-Broker-API-Version")).To(Equal("2.13"))
+		})
+
+		It("treats a gone instance as mitigated", func() {
+			statuses = []int{http.StatusGone}
+
+			_, err := mitigator.Mitigate(ctx, broker, orphan())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(operationState).Should(Equal(types.SUCCEEDED))
+		})
+
+		It("retries failed deprovisions", func() {
+			statuses = []int{http.StatusInternalServerError, http.StatusOK}
+
+			_, err := mitigator.Mitigate(ctx, broker, orphan())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(operationState).Should(Equal(types.SUCCEEDED))
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(requests).To(HaveLen(2))
+		})
+
+		It("fails the operation when all attempts fail", func() {
+			statuses = []int{http.StatusInternalServerError}
+
+			_, err := mitigator.Mitigate(ctx, broker, orphan())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(operationState).Should(Equal(types.FAILED))
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(requests).To(HaveLen(settings.Attempts))
+			Expect(string(operation.Errors)).To(ContainSubstring("Internal Server Error"))
+		})
+	})
+
+	Context("when a bind failed", func() {
+		It("unbinds the binding", func() {
+			op, err := mitigator.Mitigate(ctx, broker, &osb.Orphan{
+				InstanceID: "instance-id",
+				BindingID:  "binding-id",
+				Header:     http.Header{},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(op.ResourceType).To(Equal(osb.ServiceBindingResourceType))
+
+			Eventually(operationState).Should(Equal(types.SUCCEEDED))
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(requests[0].URL.Path).To(Equal("/v2/service_instances/instance-id/service_bindings/binding-id"))
+		})
+	})
+})
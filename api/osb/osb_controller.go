@@ -19,6 +19,7 @@ package osb
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -26,9 +27,12 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"regexp"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 
+	"github.com/Peripli/service-manager/pkg/httpclient"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
@@ -43,6 +47,22 @@ type BrokerFetcherFunc func(ctx context.Context, brokerID string) (*types.Servic
 // Controller implements api.Controller by providing OSB API logic
 type Controller struct {
 	BrokerFetcher BrokerFetcherFunc
+	Stats         *Stats
+	Headers       *HeaderSettings
+	Transports    *Transports
+
+	// Credentials transforms the binding credentials returned by brokers, they are returned as they are if it is nil
+	Credentials *CredentialsPipeline
+
+	// Responses limits the responses of the brokers, all responses are returned if it is nil
+	Responses *ResponseSettings
+
+	// OrphanMitigator cleans up after failed provision and bind calls, orphans are left to the platforms if it is nil
+	OrphanMitigator *OrphanMitigator
+
+	// CompositeFetcher resolves the composite brokers, whose calls are routed to the brokers owning their plans,
+	// composite brokers cannot be called if it is nil
+	CompositeFetcher CompositeFetcherFunc
 }
 
 var _ web.Controller = &Controller{}
@@ -71,6 +91,15 @@ func (c *Controller) handler(request *web.Request, f func(r *web.Request, logger
 	logger.Debugf("Obtained path parameter [brokerID = %s] from path params", brokerID)
 
 	broker, err := c.BrokerFetcher(ctx, brokerID)
+	if isNotFound(err) && c.CompositeFetcher != nil {
+		compositeBroker, compositeErr := c.compositeBroker(request, brokerID)
+		if compositeErr != nil && !isNotFound(compositeErr) {
+			return nil, compositeErr
+		}
+		if compositeErr == nil {
+			broker, err = compositeBroker, nil
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +123,11 @@ func (c *Controller) catalog(r *web.Request, logger *logrus.Entry, broker *types
 		return c.proxy(r, logger, broker)
 	}
 
-	return util.NewJSONResponse(http.StatusOK, &broker.Catalog)
+	catalog, err := CatalogNamespaceOf(broker).Catalog(broker.Catalog)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, json.RawMessage(catalog))
 }
 
 func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.ServiceBroker) (*web.Response, error) {
@@ -107,36 +140,114 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 		return nil, fmt.Errorf("could not get OSB path from URL %s", r.URL)
 	}
 
+	requestHeaders, responseHeaders := headerPolicies(c.Headers, broker)
+	namespace := CatalogNamespaceOf(broker)
+
+	requestBody, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
 	modifiedRequest := r.Request.WithContext(ctx)
+	body, err := namespace.Request(requestBody, modifiedRequest.URL, broker.Catalog)
+	if err != nil {
+		return nil, err
+	}
+	modifiedRequest.Header = requestHeaders.apply(r.Request.Header)
 	modifiedRequest.SetBasicAuth(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password)
-	modifiedRequest.Body = ioutil.NopCloser(bytes.NewReader(r.Body))
-	modifiedRequest.ContentLength = int64(len(r.Body))
+	modifiedRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	modifiedRequest.ContentLength = int64(len(body))
 	modifiedRequest.URL.Path = m[1]
 
 	// This is needed because the request is shallow copy of the request to the Service Manager
 	// This sets the host header to point to the service broker that the request will be proxied to
 	modifiedRequest.Host = targetBrokerURL.Host
 
-	proxy := buildProxy(targetBrokerURL, logger, broker)
+	proxy := buildProxy(targetBrokerURL, logger, broker, c.Responses)
+	if c.Transports != nil {
+		transport, err := c.Transports.ForBroker(broker)
+		if err != nil {
+			return nil, err
+		}
+		proxy.Transport = httpclient.Instrument("broker", transport)
+	}
 
 	recorder := httptest.NewRecorder()
 
+	operation := operationFor(modifiedRequest.Method, m[1])
+	started := time.Now()
 	proxy.ServeHTTP(recorder, modifiedRequest)
+	latency := time.Since(started)
 
 	respBody, err := ioutil.ReadAll(recorder.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.Stats != nil {
+		c.Stats.Record(broker.ID, Call{
+			Timestamp:  started.UTC(),
+			Operation:  operation,
+			Method:     modifiedRequest.Method,
+			StatusCode: recorder.Code,
+			LatencyMs:  float64(latency) / float64(time.Millisecond),
+			Error:      callError(recorder.Code, respBody),
+		})
+	}
+
+	if c.OrphanMitigator != nil && requiresOrphanMitigation(operation, recorder.Code, respBody) {
+		orphan := &Orphan{
+			InstanceID: r.PathParams["instance_id"],
+			BindingID:  r.PathParams["binding_id"],
+			ServiceID:  gjson.GetBytes(body, "service_id").String(),
+			PlanID:     gjson.GetBytes(body, "plan_id").String(),
+			Header:     modifiedRequest.Header,
+		}
+		if _, err := c.OrphanMitigator.Mitigate(ctx, broker, orphan); err != nil {
+			logger.WithError(err).Errorf("Could not schedule orphan mitigation for %s call to broker %s", operation, broker.Name)
+		}
+	}
+
+	if namespace != "" && recorder.Code == http.StatusOK {
+		switch operation {
+		case "catalog":
+			respBody, err = namespace.Catalog(respBody)
+		case "fetch_instance":
+			respBody, err = namespace.Instance(respBody)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the length of the body changes if the catalog IDs are prefixed
+		recorder.Header().Del("Content-Length")
+	}
+
+	if credentialsOperations[operation] && (recorder.Code == http.StatusOK || recorder.Code == http.StatusCreated) {
+		binding := &BindingDetails{
+			Broker:     broker,
+			InstanceID: r.PathParams["instance_id"],
+			BindingID:  r.PathParams["binding_id"],
+		}
+		if respBody, err = c.Credentials.Transform(ctx, binding, respBody); err != nil {
+			// the credentials are not returned untransformed, as they might have been meant to be redacted
+			logger.WithError(err).Errorf("Could not transform credentials of binding %s", binding.BindingID)
+			return util.NewJSONResponse(http.StatusBadGateway, &util.HTTPError{
+				ErrorType:   "CredentialsTransformationErr",
+				Description: fmt.Sprintf("could not transform credentials of binding %s", binding.BindingID),
+			})
+		}
+		// the length of the body changes if the credentials are transformed
+		recorder.Header().Del("Content-Length")
+	}
+
 	resp := &web.Response{
 		StatusCode: recorder.Code,
-		Header:     recorder.Header(),
+		Header:     responseHeaders.apply(recorder.Header()),
 		Body:       respBody,
 	}
 	return resp, nil
 }
 
-func buildProxy(targetBrokerURL *url.URL, logger *logrus.Entry, broker *types.ServiceBroker) *httputil.ReverseProxy {
+func buildProxy(targetBrokerURL *url.URL, logger *logrus.Entry, broker *types.ServiceBroker, responses *ResponseSettings) *httputil.ReverseProxy {
 	proxy := httputil.NewSingleHostReverseProxy(targetBrokerURL)
 	director := proxy.Director
 	proxy.Director = func(request *http.Request) {
@@ -145,10 +256,15 @@ func buildProxy(targetBrokerURL *url.URL, logger *logrus.Entry, broker *types.Se
 	}
 	proxy.ModifyResponse = func(response *http.Response) error {
 		logger.Debugf("Service broker %s replied with status %d", broker.Name, response.StatusCode)
-		return nil
+		return responses.Check(response)
 	}
 	proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, e error) {
 		logger.WithError(e).Errorf("Error while forwarding request to service broker %s", broker.Name)
+		if httpErr, ok := e.(*util.HTTPError); ok {
+			// the response of the broker was rejected
+			util.WriteError(httpErr, writer)
+			return
+		}
 		util.WriteError(&util.HTTPError{
 			ErrorType:   "ServiceBrokerErr",
 			Description: fmt.Sprintf("could not reach service broker %s at %s", broker.Name, request.URL),
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package osb_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+func benchmarkProxy(b *testing.B, requestSize, responseSize int) {
+	responseBody := bytes.Repeat([]byte("a"), responseSize)
+	brokerServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.Copy(ioutil.Discard, req.Body)
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write(responseBody)
+	}))
+	defer brokerServer.Close()
+
+	broker := &types.ServiceBroker{
+		Base: types.Base{
+			ID: "broker-id",
+		},
+		Name:      "broker",
+		BrokerURL: brokerServer.URL,
+		Credentials: &types.Credentials{
+			Basic: &types.Basic{
+				Username: "username",
+				Password: "password",
+			},
+		},
+	}
+	controller := &osb.Controller{
+		BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+			return broker, nil
+		},
+	}
+	var handler web.HandlerFunc
+	for _, route := range controller.Routes() {
+		if route.Endpoint.Method == http.MethodPut && route.Endpoint.Path == web.OSBURL+"/{brokerID}/v2/service_instances/{instance_id}" {
+			handler = route.Handler
+		}
+	}
+	if handler == nil {
+		b.Fatal("provision route not found")
+	}
+	requestBody := bytes.Repeat([]byte("a"), requestSize)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(requestSize + responseSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		request, err := http.NewRequest(http.MethodPut, web.OSBURL+"/broker-id/v2/service_instances/instance-id", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		response, err := handler(&web.Request{
+			Request:    request,
+			PathParams: map[string]string{osb.BrokerIDPathParam: broker.ID},
+			Body:       requestBody,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if response.StatusCode != http.StatusCreated {
+			b.Fatalf("expected status %d but got %d", http.StatusCreated, response.StatusCode)
+		}
+	}
+}
+
+func BenchmarkProxySmallPayload(b *testing.B) {
+	benchmarkProxy(b, 1024, 1024)
+}
+
+func BenchmarkProxyLargePayload(b *testing.B) {
+	benchmarkProxy(b, 1024*1024, 10*1024*1024)
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// ResponseSettings type to be loaded from the environment
+type ResponseSettings struct {
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes" description:"maximum size of the broker responses, larger responses are rejected, 0 disables the limit"`
+	RequireJSON  bool  `mapstructure:"require_json" description:"whether broker responses with a body whose content type is not application/json are rejected"`
+}
+
+// DefaultResponseSettings returns the default limits of the broker responses
+func DefaultResponseSettings() *ResponseSettings {
+	return &ResponseSettings{
+		MaxBodyBytes: 10 * 1024 * 1024,
+		RequireJSON:  false,
+	}
+}
+
+// Validate validates the broker response settings
+func (s *ResponseSettings) Validate() error {
+	if s.MaxBodyBytes < 0 {
+		return fmt.Errorf("validate Settings: broker response max body bytes (%d) should be greater or equal to 0", s.MaxBodyBytes)
+	}
+	return nil
+}
+
+// Check reads the body of the broker response and rejects the response if the body exceeds the size limit or if JSON
+// is required and the body is not declared as JSON. At most one byte more than the limit is read. The body is replaced
+// by the bytes read, so that it can be read again.
+func (s *ResponseSettings) Check(response *http.Response) error {
+	if s == nil {
+		return nil
+	}
+	defer response.Body.Close()
+
+	broker := "service broker"
+	if response.Request != nil && response.Request.URL != nil {
+		broker = fmt.Sprintf("service broker at %s", response.Request.URL.Host)
+	}
+	tooLarge := &util.HTTPError{
+		ErrorType:   "ServiceBrokerErr",
+		Description: fmt.Sprintf("response of %s exceeds the limit of %d bytes", broker, s.MaxBodyBytes),
+		StatusCode:  http.StatusBadGateway,
+	}
+	if s.MaxBodyBytes > 0 && response.ContentLength > s.MaxBodyBytes {
+		return tooLarge
+	}
+
+	var body io.Reader = response.Body
+	if s.MaxBodyBytes > 0 {
+		body = io.LimitReader(response.Body, s.MaxBodyBytes+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if s.MaxBodyBytes > 0 && int64(len(data)) > s.MaxBodyBytes {
+		return tooLarge
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if s.RequireJSON && len(bytes.TrimSpace(data)) > 0 && !isJSONContentType(contentType) {
+		return &util.HTTPError{
+			ErrorType:   "ServiceBrokerErr",
+			Description: fmt.Sprintf("response of %s has content type %q instead of application/json", broker, contentType),
+			StatusCode:  http.StatusBadGateway,
+		}
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(data))
+	response.ContentLength = int64(len(data))
+	return nil
+}
+
+// DoRequestFuncProvider returns a provider of functions which send requests to brokers and check their responses
+func (s *ResponseSettings) DoRequestFuncProvider(provider func(broker *types.ServiceBroker) (util.DoRequestFunc, error)) func(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+	return func(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+		doRequest, err := provider(broker)
+		if err != nil {
+			return nil, err
+		}
+		return func(request *http.Request) (*http.Response, error) {
+			response, err := doRequest(request)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.Check(response); err != nil {
+				return nil, err
+			}
+			return response, nil
+		}, nil
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker response limits", func() {
+	var settings *ResponseSettings
+
+	response := func(contentType, body string) *http.Response {
+		request, err := http.NewRequest(http.MethodGet, "https://broker.example.com/v2/catalog", nil)
+		Expect(err).ToNot(HaveOccurred())
+		header := http.Header{}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+			ContentLength: -1,
+			Request:       request,
+		}
+	}
+
+	expectRejected := func(err error, description string) {
+		Expect(err).To(HaveOccurred())
+		httpErr, ok := err.(*util.HTTPError)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(httpErr.Description).To(ContainSubstring(description))
+	}
+
+	BeforeEach(func() {
+		settings = DefaultResponseSettings()
+		settings.MaxBodyBytes = 10
+	})
+
+	It("validates the default settings", func() {
+		Expect(DefaultResponseSettings().Validate()).To(Succeed())
+		Expect((&ResponseSettings{MaxBodyBytes: -1}).Validate()).ToNot(Succeed())
+	})
+
+	It("keeps responses within the limit readable", func() {
+		resp := response("", `{"a":"b"}`)
+		Expect(settings.Check(resp)).To(Succeed())
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"a":"b"}`))
+		Expect(resp.ContentLength).To(Equal(int64(9)))
+	})
+
+	It("rejects responses exceeding the limit", func() {
+		expectRejected(settings.Check(response("application/json", `{"a":"bcdef"}`)), "broker.example.com exceeds the limit of 10 bytes")
+	})
+
+	It("rejects responses declaring a length exceeding the limit", func() {
+		resp := response("application/json", `{}`)
+		resp.ContentLength = 11
+		expectRejected(settings.Check(resp), "exceeds the limit")
+	})
+
+	It("does not limit the responses if the limit is 0", func() {
+		settings.MaxBodyBytes = 0
+		Expect(settings.Check(response("", strings.Repeat("a", 100)))).To(Succeed())
+	})
+
+	Context("when JSON is required", func() {
+		BeforeEach(func() {
+			settings.RequireJSON = true
+		})
+
+		It("accepts JSON responses with parameters", func() {
+			Expect(settings.Check(response("application/json; charset=utf-8", `{}`))).To(Succeed())
+		})
+
+		It("accepts empty responses without content type", func() {
+			Expect(settings.Check(response("", ""))).To(Succeed())
+		})
+
+		It("rejects responses which are not declared as JSON", func() {
+			expectRejected(settings.Check(response("text/html", `<html/>`)), `content type "text/html" instead of application/json`)
+		})
+	})
+
+	It("checks the responses of the request functions it provides", func() {
+		provider := settings.DoRequestFuncProvider(func(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+			return func(request *http.Request) (*http.Response, error) {
+				return response("application/json", `{"a":"bcdef"}`), nil
+			}, nil
+		})
+		doRequest, err := provider(&types.ServiceBroker{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = doRequest(nil)
+		expectRejected(err, "exceeds the limit")
+	})
+
+	It("passes on the errors of the provider", func() {
+		provider := settings.DoRequestFuncProvider(func(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+			return nil, errors.New("invalid proxy")
+		})
+		_, err := provider(&types.ServiceBroker{})
+		Expect(err).To(MatchError("invalid proxy"))
+	})
+})
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+const (
+	// RequestSigningKeyLabel is the broker label which specifies the name of the signing key with which the requests
+	// to the broker are signed. Requests to brokers without the label are not signed.
+	RequestSigningKeyLabel = "request_signing_key"
+
+	// RequestSigningAlgorithmLabel is the broker label which specifies the algorithm of the signatures of the requests
+	// to the broker, HMACSHA256 if it is not specified
+	RequestSigningAlgorithmLabel = "request_signing_algorithm"
+
+	// RequestSigningHeaderLabel is the broker label which overrides the header in which the signatures of the requests
+	// to the broker are sent
+	RequestSigningHeaderLabel = "request_signing_header"
+
+	// HMACSHA256 is the signing algorithm which signs the requests with HMAC-SHA256
+	HMACSHA256 = "hmac-sha256"
+
+	// HMACSHA512 is the signing algorithm which signs the requests with HMAC-SHA512
+	HMACSHA512 = "hmac-sha512"
+)
+
+var signingAlgorithms = map[string]func() hash.Hash{
+	HMACSHA256: sha256.New,
+	HMACSHA512: sha512.New,
+}
+
+// SigningSettings type to be loaded from the environment
+type SigningSettings struct {
+	Keys    []string `mapstructure:"keys" description:"keys with which the requests to brokers can be signed in the form name=secret, brokers reference a key by name with the request_signing_key label"`
+	Brokers []string `mapstructure:"brokers" description:"ids of the brokers which may reference each key in the form key=broker-id,broker-id, brokers referencing a key they are not listed for are not called"`
+	Header  string   `mapstructure:"header" description:"header in which the hex encoded HMAC of the body of a request is sent to brokers which require signed requests"`
+}
+
+// DefaultSigningSettings returns the default request signing settings
+func DefaultSigningSettings() *SigningSettings {
+	return &SigningSettings{
+		Keys:    []string{},
+		Brokers: []string{},
+		Header:  "X-Signature",
+	}
+}
+
+// Validate validates the request signing settings
+func (s *SigningSettings) Validate() error {
+	if s.Header == "" {
+		return fmt.Errorf("validate Settings: request signing header must not be empty")
+	}
+	keys, err := parseSigningKeys(s.Keys)
+	if err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	if _, err := parseSigningKeyBrokers(s.Brokers, keys); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	return nil
+}
+
+func parseSigningKeys(keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		separator := strings.Index(key, "=")
+		if separator <= 0 || separator == len(key)-1 {
+			return nil, fmt.Errorf("invalid request signing key, expected name=secret")
+		}
+		name := strings.TrimSpace(key[:separator])
+		if _, found := result[name]; found {
+			return nil, fmt.Errorf("request signing key %s is configured twice", name)
+		}
+		result[name] = []byte(key[separator+1:])
+	}
+	return result, nil
+}
+
+func parseSigningKeyBrokers(bindings []string, keys map[string][]byte) (map[string]map[string]bool, error) {
+	result := make(map[string]map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		parts := strings.SplitN(binding, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid request signing brokers %s, expected key=broker-id,broker-id", binding)
+		}
+		if _, found := keys[name]; !found {
+			return nil, fmt.Errorf("request signing brokers %s reference unknown key %s", binding, name)
+		}
+		if result[name] == nil {
+			result[name] = make(map[string]bool)
+		}
+		for _, broker := range strings.Split(parts[1], ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				result[name][broker] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+// RequestSigner signs the requests to the brokers which reference a signing key with a HMAC of the request body
+type RequestSigner struct {
+	keys    map[string][]byte
+	brokers map[string]map[string]bool
+	header  string
+}
+
+// NewRequestSigner returns a signer of the keys in the settings, the settings are expected to be valid
+func NewRequestSigner(settings *SigningSettings) *RequestSigner {
+	if settings == nil {
+		settings = DefaultSigningSettings()
+	}
+	keys, _ := parseSigningKeys(settings.Keys)
+	brokers, _ := parseSigningKeyBrokers(settings.Brokers, keys)
+	return &RequestSigner{
+		keys:    keys,
+		brokers: brokers,
+		header:  settings.Header,
+	}
+}
+
+// Transport returns a transport which signs the requests to the broker before they are sent with the provided
+// transport, or the provided transport if the requests to the broker are not signed
+func (s *RequestSigner) Transport(broker *types.ServiceBroker, transport http.RoundTripper) (http.RoundTripper, error) {
+	keyName := firstLabelValue(broker, RequestSigningKeyLabel)
+	if s == nil || keyName == "" {
+		return transport, nil
+	}
+	key, found := s.keys[keyName]
+	if !found {
+		return nil, fmt.Errorf("unknown request signing key %s of broker %s", keyName, broker.Name)
+	}
+	// the key is only used for the brokers it is configured for, as everyone who can label a broker could otherwise
+	// have arbitrary requests signed with it. Brokers are bound by id, as names can be reused by other brokers.
+	if !s.brokers[keyName][broker.ID] {
+		return nil, fmt.Errorf("broker %s is not allowed to use request signing key %s", broker.Name, keyName)
+	}
+	algorithm := firstLabelValue(broker, RequestSigningAlgorithmLabel)
+	if algorithm == "" {
+		algorithm = HMACSHA256
+	}
+	newHash, found := signingAlgorithms[strings.ToLower(algorithm)]
+	if !found {
+		return nil, fmt.Errorf("unsupported request signing algorithm %s of broker %s, supported are %s and %s", algorithm, broker.Name, HMACSHA256, HMACSHA512)
+	}
+	header := firstLabelValue(broker, RequestSigningHeaderLabel)
+	if header == "" {
+		header = s.header
+	}
+	return &signingTransport{
+		next:    transport,
+		key:     key,
+		newHash: newHash,
+		header:  header,
+	}, nil
+}
+
+func firstLabelValue(broker *types.ServiceBroker, label string) string {
+	if values := broker.Labels[label]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type signingTransport struct {
+	next    http.RoundTripper
+	key     []byte
+	newHash func() hash.Hash
+	header  string
+}
+
+// RoundTrip implements http.RoundTripper and sends a copy of the request with the signature of its body
+func (t *signingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	mac := hmac.New(t.newHash, t.key)
+	mac.Write(body)
+
+	// round trippers must not modify the request
+	signed := new(http.Request)
+	*signed = *request
+	signed.Header = make(http.Header, len(request.Header)+1)
+	for key, values := range request.Header {
+		signed.Header[key] = values
+	}
+	signed.Header.Set(t.header, hex.EncodeToString(mac.Sum(nil)))
+	if request.Body != nil {
+		signed.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return t.next.RoundTrip(signed)
+}
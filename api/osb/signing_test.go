@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request signing", func() {
+	const body = `{"service_id":"service","plan_id":"plan"}`
+
+	var (
+		settings     *SigningSettings
+		broker       *types.ServiceBroker
+		server       *httptest.Server
+		received     http.Header
+		receivedBody string
+	)
+
+	signature := func(newHash func() hash.Hash, key string) string {
+		mac := hmac.New(newHash, []byte(key))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	send := func() {
+		transport, err := NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		response, err := transport.RoundTrip(request)
+		Expect(err).ToNot(HaveOccurred())
+		response.Body.Close()
+		Expect(receivedBody).To(Equal(body))
+		Expect(request.Header.Get("X-Signature")).To(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		settings = DefaultSigningSettings()
+		settings.Keys = []string{"internal=secret", "other=other-secret"}
+		settings.Brokers = []string{"internal=broker-id", "other=broker-id,other-broker-id"}
+		broker = &types.ServiceBroker{
+			Base: types.Base{
+				ID:     "broker-id",
+				Labels: types.Labels{},
+			},
+			Name: "broker",
+		}
+		received = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+			requestBody, _ := ioutil.ReadAll(r.Body)
+			receivedBody = string(requestBody)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("does not sign the requests to brokers without signing key", func() {
+		transport, err := NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(transport).To(BeIdenticalTo(http.DefaultTransport))
+	})
+
+	It("signs the body with HMAC-SHA256 by default", func() {
+		broker.Labels[RequestSigningKeyLabel] = []string{"internal"}
+		send()
+		Expect(received.Get("X-Signature")).To(Equal(signature(sha256.New, "secret")))
+	})
+
+	It("signs with the algorithm, key and header of the broker", func() {
+		broker.Labels[RequestSigningKeyLabel] = []string{"other"}
+		broker.Labels[RequestSigningAlgorithmLabel] = []string{HMACSHA512}
+		broker.Labels[RequestSigningHeaderLabel] = []string{"X-Broker-Signature"}
+		send()
+		Expect(received.Get("X-Broker-Signature")).To(Equal(signature(sha512.New, "other-secret")))
+		Expect(received.Get("X-Signature")).To(BeEmpty())
+	})
+
+	It("fails for unknown keys and algorithms", func() {
+		broker.Labels[RequestSigningKeyLabel] = []string{"unknown"}
+		_, err := NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).To(HaveOccurred())
+
+		broker.Labels[RequestSigningKeyLabel] = []string{"internal"}
+		broker.Labels[RequestSigningAlgorithmLabel] = []string{"md5"}
+		_, err = NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for keys which are not configured for the broker", func() {
+		settings.Brokers = []string{"internal=other-broker-id"}
+		broker.Labels[RequestSigningKeyLabel] = []string{"internal"}
+		_, err := NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).To(HaveOccurred())
+
+		settings.Brokers = []string{"internal=broker"}
+		_, err = NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).To(HaveOccurred())
+
+		settings.Brokers = []string{}
+		_, err = NewRequestSigner(settings).Transport(broker, http.DefaultTransport)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects invalid keys", func() {
+		settings.Keys = []string{"secret"}
+		Expect(settings.Validate()).To(HaveOccurred())
+		settings.Keys = []string{"key=one", "key=two"}
+		Expect(settings.Validate()).To(HaveOccurred())
+		settings.Keys = []string{"key=one"}
+		settings.Brokers = []string{"unknown=broker"}
+		Expect(settings.Validate()).To(HaveOccurred())
+	})
+})
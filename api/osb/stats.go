@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+const defaultCallHistorySize = 100
+
+// StatsHookName is the name of the hook which drops the statistics of deleted brokers
+const StatsHookName = "OSBStatsHook"
+
+// Call describes a single OSB call proxied to a service broker
+type Call struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Operation  string    `json:"operation"`
+	Method     string    `json:"method"`
+	StatusCode int       `json:"status_code"`
+	LatencyMs  float64   `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Statistics contains rolling statistics about the recent OSB calls proxied to a service broker
+type Statistics struct {
+	BrokerID         string                    `json:"broker_id"`
+	Calls            int                       `json:"calls"`
+	CallsByOperation map[string]map[string]int `json:"calls_by_operation"`
+	AverageLatencyMs float64                   `json:"average_latency_ms"`
+	LastError        *Call                     `json:"last_error,omitempty"`
+	History          []Call                    `json:"history"`
+}
+
+type callHistory struct {
+	calls     []Call
+	next      int
+	full      bool
+	lastError *Call
+}
+
+func (h *callHistory) add(call Call) {
+	h.calls[h.next] = call
+	h.next = (h.next + 1) % len(h.calls)
+	if h.next == 0 {
+		h.full = true
+	}
+	if call.Error != "" {
+		lastError := call
+		h.lastError = &lastError
+	}
+}
+
+// ordered returns the recorded calls from the oldest to the newest
+func (h *callHistory) ordered() []Call {
+	if !h.full {
+		return append([]Call{}, h.calls[:h.next]...)
+	}
+	return append(append([]Call{}, h.calls[h.next:]...), h.calls[:h.next]...)
+}
+
+// Stats keeps a ring buffer with the most recent OSB calls proxied to each service broker.
+// The statistics are local to the Service Manager instance which proxied the calls.
+type Stats struct {
+	mutex       sync.RWMutex
+	historySize int
+	brokers     map[string]*callHistory
+}
+
+// NewStats returns a new Stats which keeps up to historySize calls per broker
+func NewStats(historySize int) *Stats {
+	if historySize <= 0 {
+		historySize = defaultCallHistorySize
+	}
+	return &Stats{
+		historySize: historySize,
+		brokers:     make(map[string]*callHistory),
+	}
+}
+
+// Record records a call to the broker with the specified id
+func (s *Stats) Record(brokerID string, call Call) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	history, found := s.brokers[brokerID]
+	if !found {
+		history = &callHistory{
+			calls: make([]Call, s.historySize),
+		}
+		s.brokers[brokerID] = history
+	}
+	history.add(call)
+}
+
+// Forget drops the statistics of the broker with the specified id
+func (s *Stats) Forget(brokerID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.brokers, brokerID)
+}
+
+// DeleteHook returns a hook which drops the statistics of the brokers deleted in this instance
+func (s *Stats) DeleteHook() *storage.DeleteHook {
+	return &storage.DeleteHook{
+		HookName: StatsHookName,
+		After: func(ctx context.Context, objects types.ObjectList) error {
+			for i := 0; i < objects.Len(); i++ {
+				s.Forget(objects.ItemAt(i).GetID())
+			}
+			return nil
+		},
+	}
+}
+
+// Statistics returns the statistics of the broker with the specified id
+func (s *Stats) Statistics(brokerID string) *Statistics {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	statistics := &Statistics{
+		BrokerID:         brokerID,
+		CallsByOperation: make(map[string]map[string]int),
+		History:          []Call{},
+	}
+	history, found := s.brokers[brokerID]
+	if !found {
+		return statistics
+	}
+
+	statistics.History = history.ordered()
+	statistics.Calls = len(statistics.History)
+	statistics.LastError = history.lastError
+
+	totalLatency := 0.0
+	for _, call := range statistics.History {
+		byStatus, found := statistics.CallsByOperation[call.Operation]
+		if !found {
+			byStatus = make(map[string]int)
+			statistics.CallsByOperation[call.Operation] = byStatus
+		}
+		byStatus[strconv.Itoa(call.StatusCode)]++
+		totalLatency += call.LatencyMs
+	}
+	if statistics.Calls > 0 {
+		statistics.AverageLatencyMs = totalLatency / float64(statistics.Calls)
+	}
+
+	return statistics
+}
+
+var operationPatterns = []struct {
+	method    string
+	pattern   *regexp.Regexp
+	operation string
+}{
+	{http.MethodGet, regexp.MustCompile("^/v2/catalog$"), "catalog"},
+	{http.MethodGet, regexp.MustCompile("^/v2/service_instances/[^/]+/last_operation$"), "poll_instance"},
+	{http.MethodGet, regexp.MustCompile("^/v2/service_instances/[^/]+/service_bindings/[^/]+/last_operation$"), "poll_binding"},
+	{http.MethodPost, regexp.MustCompile("^/v2/service_instances/[^/]+/service_bindings/[^/]+/adapt_credentials$"), "adapt_credentials"},
+	{http.MethodGet, regexp.MustCompile("^/v2/service_instances/[^/]+/service_bindings/[^/]+$"), "fetch_binding"},
+	{http.MethodPut, regexp.MustCompile("^/v2/service_instances/[^/]+/service_bindings/[^/]+$"), "bind"},
+	{http.MethodDelete, regexp.MustCompile("^/v2/service_instances/[^/]+/service_bindings/[^/]+$"), "unbind"},
+	{http.MethodGet, regexp.MustCompile("^/v2/service_instances/[^/]+$"), "fetch_instance"},
+	{http.MethodPut, regexp.MustCompile("^/v2/service_instances/[^/]+$"), "provision"},
+	{http.MethodPatch, regexp.MustCompile("^/v2/service_instances/[^/]+$"), "update_instance"},
+	{http.MethodDelete, regexp.MustCompile("^/v2/service_instances/[^/]+$"), "deprovision"},
+}
+
+// operationFor returns the name of the OSB operation for the provided method and broker path
+func operationFor(method, path string) string {
+	for _, op := range operationPatterns {
+		if op.method == method && op.pattern.MatchString(path) {
+			return op.operation
+		}
+	}
+	return "unknown"
+}
+
+// callError returns a description of the error from the broker response or an empty string if the call succeeded
+func callError(statusCode int, body []byte) string {
+	if statusCode < http.StatusBadRequest {
+		return ""
+	}
+	for _, field := range []string{"description", "error"} {
+		if value := gjson.GetBytes(body, field); value.Exists() && value.String() != "" {
+			return value.String()
+		}
+	}
+	return http.StatusText(statusCode)
+}
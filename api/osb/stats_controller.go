@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// StatisticsURL is the path of the endpoint which returns the statistics of the calls proxied to a broker
+const StatisticsURL = web.ServiceBrokersURL + "/{" + BrokerIDPathParam + "}/statistics"
+
+// StatisticsController implements api.Controller by providing the broker statistics API
+type StatisticsController struct {
+	BrokerFetcher BrokerFetcherFunc
+	Stats         *Stats
+}
+
+var _ web.Controller = &StatisticsController{}
+
+// Routes implements api.Controller.Routes by providing the routes for the broker statistics API
+func (c *StatisticsController) Routes() []web.Route {
+	return []web.Route{
+		{Endpoint: web.Endpoint{Method: http.MethodGet, Path: StatisticsURL}, Handler: c.statistics},
+	}
+}
+
+func (c *StatisticsController) statistics(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	brokerID := r.PathParams[BrokerIDPathParam]
+	log.C(ctx).Debugf("Getting statistics of broker with id %s", brokerID)
+
+	if _, err := c.BrokerFetcher(ctx, brokerID); err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, c.Stats.Statistics(brokerID))
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+	const brokerID = "broker-id"
+
+	var stats *osb.Stats
+
+	BeforeEach(func() {
+		stats = osb.NewStats(2)
+	})
+
+	Context("when no calls were recorded", func() {
+		It("returns empty statistics", func() {
+			statistics := stats.Statistics(brokerID)
+			Expect(statistics.BrokerID).To(Equal(brokerID))
+			Expect(statistics.Calls).To(Equal(0))
+			Expect(statistics.History).To(BeEmpty())
+			Expect(statistics.LastError).To(BeNil())
+		})
+	})
+
+	Context("when calls were recorded", func() {
+		BeforeEach(func() {
+			stats.Record(brokerID, osb.Call{Operation: "provision", StatusCode: http.StatusBadGateway, LatencyMs: 30, Error: "unreachable"})
+			stats.Record(brokerID, osb.Call{Operation: "provision", StatusCode: http.StatusCreated, LatencyMs: 10})
+			stats.Record(brokerID, osb.Call{Operation: "bind", StatusCode: http.StatusCreated, LatencyMs: 20})
+		})
+
+		It("keeps only the most recent calls", func() {
+			statistics := stats.Statistics(brokerID)
+			Expect(statistics.Calls).To(Equal(2))
+			Expect(statistics.History[0].Operation).To(Equal("provision"))
+			Expect(statistics.History[1].Operation).To(Equal("bind"))
+		})
+
+		It("counts the calls by operation and status code", func() {
+			Expect(stats.Statistics(brokerID).CallsByOperation).To(Equal(map[string]map[string]int{
+				"provision": {"201": 1},
+				"bind":      {"201": 1},
+			}))
+		})
+
+		It("computes the average latency", func() {
+			Expect(stats.Statistics(brokerID).AverageLatencyMs).To(Equal(15.0))
+		})
+
+		It("keeps the last error even if it is no longer in the history", func() {
+			lastError := stats.Statistics(brokerID).LastError
+			Expect(lastError).ToNot(BeNil())
+			Expect(lastError.Error).To(Equal("unreachable"))
+		})
+
+		It("drops the statistics of forgotten brokers", func() {
+			stats.Forget(brokerID)
+			Expect(stats.Statistics(brokerID).Calls).To(Equal(0))
+		})
+
+		It("drops the statistics of deleted brokers", func() {
+			deleted := &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{{Base: types.Base{ID: brokerID}}}}
+			Expect(stats.DeleteHook().After(context.Background(), deleted)).To(Succeed())
+			Expect(stats.Statistics(brokerID).Calls).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// ProxyURLLabel is the broker label which specifies the HTTP(S) proxy through which the broker is reached.
+	// The value DirectConnection bypasses any configured proxy.
+	ProxyURLLabel = "proxy_url"
+
+	// DirectConnection is the value of the ProxyURLLabel which instructs to connect to the broker without a proxy
+	DirectConnection = "direct"
+
+	// CertificatePinsLabel is the broker label which specifies the SHA-256 fingerprints of the certificates, in the
+	// form sha256:<hex fingerprint>, one of which the broker has to present in addition to the globally pinned ones
+	CertificatePinsLabel = "certificate_pins"
+)
+
+// environmentProxy resolves the proxy of the requests for which no proxy is configured in the settings
+var environmentProxy = http.ProxyFromEnvironment
+
+// ProxySettings type to be loaded from the environment
+type ProxySettings struct {
+	HTTPProxy  string `mapstructure:"http_proxy" description:"proxy used for calls to brokers with http URLs, if no proxy is configured the HTTP_PROXY environment variable is used"`
+	HTTPSProxy string `mapstructure:"https_proxy" description:"proxy used for calls to brokers with https URLs, if no proxy is configured the HTTPS_PROXY environment variable is used"`
+	NoProxy    string `mapstructure:"no_proxy" description:"comma separated list of hosts and domains of brokers which are called without a proxy"`
+}
+
+// DefaultProxySettings returns the default broker proxy settings
+func DefaultProxySettings() *ProxySettings {
+	return &ProxySettings{}
+}
+
+// Validate validates the broker proxy settings
+func (s *ProxySettings) Validate() error {
+	for _, proxy := range []string{s.HTTPProxy, s.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if _, err := url.Parse(proxy); err != nil {
+			return fmt.Errorf("validate Settings: invalid broker proxy URL %s: %s", proxy, err)
+		}
+	}
+	return nil
+}
+
+func (s *ProxySettings) configured() bool {
+	return s.HTTPProxy != "" || s.HTTPSProxy != ""
+}
+
+// proxyFunc returns the proxy for the request according to the settings. The proxy of a scheme which is not
+// configured in the settings is taken from the environment, as it is for brokers without any proxy settings.
+func (s *ProxySettings) proxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		if bypassProxy(s.NoProxy, request.URL.Hostname()) {
+			return nil, nil
+		}
+		proxy := s.HTTPProxy
+		if request.URL.Scheme == "https" {
+			proxy = s.HTTPSProxy
+		}
+		if proxy == "" {
+			return environmentProxy(request)
+		}
+		return url.Parse(proxy)
+	}
+}
+
+// bypassProxy returns whether the host matches any of the comma separated no proxy rules. A rule matches
+// the host itself and all of its subdomains and a rule of * matches all hosts.
+func bypassProxy(noProxy, host string) bool {
+	host = strings.ToLower(host)
+	for _, rule := range strings.Split(noProxy, ",") {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+		if rule == "*" {
+			return true
+		}
+		if ruleHost, _, err := net.SplitHostPort(rule); err == nil {
+			rule = ruleHost
+		}
+		rule = strings.TrimPrefix(rule, ".")
+		if host == rule || strings.HasSuffix(host, "."+rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transports provides the HTTP transports used for calling brokers taking into account the global and per broker
+// proxy settings and certificate pins. Transports are reused for brokers with the same proxy and pins so that
+// connections can be pooled.
+type Transports struct {
+	// Signer signs the requests to the brokers which require signed requests, no requests are signed if it is nil
+	Signer *RequestSigner
+
+	settings *ProxySettings
+	clients  *httpclient.Factory
+
+	mutex      sync.Mutex
+	transports map[string]*httpclient.Transport
+}
+
+// NewTransports returns Transports which derive from the transports and clients of the factory
+func NewTransports(settings *ProxySettings, clients *httpclient.Factory) *Transports {
+	if settings == nil {
+		settings = DefaultProxySettings()
+	}
+	return &Transports{
+		settings:   settings,
+		clients:    clients,
+		transports: make(map[string]*httpclient.Transport),
+	}
+}
+
+// ForBroker returns the transport through which the specified broker should be called
+func (t *Transports) ForBroker(broker *types.ServiceBroker) (http.RoundTripper, error) {
+	transport, err := t.connectionTransport(broker)
+	if err != nil {
+		return nil, err
+	}
+	return t.Signer.Transport(broker, transport)
+}
+
+// connectionTransport returns the shared transport which connects to the broker with its proxy and pins
+func (t *Transports) connectionTransport(broker *types.ServiceBroker) (http.RoundTripper, error) {
+	proxyURL := ""
+	if values, found := broker.Labels[ProxyURLLabel]; found && len(values) > 0 {
+		proxyURL = values[0]
+	}
+	pins := broker.Labels[CertificatePinsLabel]
+	if proxyURL == "" && !t.settings.configured() && len(pins) == 0 {
+		return t.clients.Transport(), nil
+	}
+
+	var hostPins []httpclient.HostPins
+	key := proxyURL
+	if len(pins) != 0 {
+		brokerURL, err := url.Parse(broker.BrokerURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL of broker %s: %s", broker.Name, err)
+		}
+		if hostPins, err = brokerPins(brokerURL.Hostname(), pins); err != nil {
+			return nil, fmt.Errorf("invalid certificate pins of broker %s: %s", broker.Name, err)
+		}
+		key = strings.Join(append([]string{proxyURL, brokerURL.Hostname()}, pins...), " ")
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if transport, found := t.transports[key]; found {
+		return transport, nil
+	}
+
+	var proxy func(*http.Request) (*url.URL, error)
+	switch proxyURL {
+	case "":
+		proxy = t.settings.proxyFunc()
+	case DirectConnection:
+		proxy = nil
+	default:
+		parsedURL, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s of broker %s: %s", proxyURL, broker.Name, err)
+		}
+		proxy = http.ProxyURL(parsedURL)
+	}
+
+	transport := t.clients.NewTransport(proxy, hostPins...)
+	t.transports[key] = transport
+	return transport, nil
+}
+
+// brokerPins parses the fingerprint pins of the broker host, bundles of CAs can only be pinned globally as they are
+// files of the Service Manager
+func brokerPins(host string, pins []string) ([]httpclient.HostPins, error) {
+	hostPins := httpclient.HostPins{Host: host}
+	for _, pin := range pins {
+		if !strings.HasPrefix(pin, "sha256:") {
+			return nil, fmt.Errorf("only certificate fingerprints can be pinned for brokers, got %s", pin)
+		}
+		parsed, err := httpclient.ParsePin(pin)
+		if err != nil {
+			return nil, err
+		}
+		hostPins.Pins = append(hostPins.Pins, parsed)
+	}
+	return []httpclient.HostPins{hostPins}, nil
+}
+
+// DoRequestFunc returns a function which sends requests to the specified broker
+func (t *Transports) DoRequestFunc(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+	transport, err := t.ForBroker(broker)
+	if err != nil {
+		return nil, err
+	}
+	return t.clients.ClientWithTransport("broker", transport).Do, nil
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker transports", func() {
+	var (
+		settings   *ProxySettings
+		clients    *httpclient.Factory
+		transports *Transports
+		broker     *types.ServiceBroker
+	)
+
+	proxyFor := func(rawURL string) *url.URL {
+		transport, err := transports.ForBroker(broker)
+		Expect(err).ToNot(HaveOccurred())
+		httpTransport, ok := transport.(*httpclient.Transport)
+		Expect(ok).To(BeTrue())
+		if httpTransport.Proxy == nil {
+			return nil
+		}
+		request, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		proxyURL, err := httpTransport.Proxy(request)
+		Expect(err).ToNot(HaveOccurred())
+		return proxyURL
+	}
+
+	BeforeEach(func() {
+		settings = DefaultProxySettings()
+		var err error
+		clients, err = httpclient.NewFactory(httpclient.DefaultSettings(), 0, false)
+		Expect(err).ToNot(HaveOccurred())
+		broker = &types.ServiceBroker{
+			Base: types.Base{
+				Labels: types.Labels{},
+			},
+			Name: "broker",
+		}
+	})
+
+	JustBeforeEach(func() {
+		transports = NewTransports(settings, clients)
+	})
+
+	Context("when no proxy is configured", func() {
+		It("returns the base transport", func() {
+			transport, err := transports.ForBroker(broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport).To(BeIdenticalTo(clients.Transport()))
+		})
+	})
+
+	Context("when a global proxy is configured", func() {
+		BeforeEach(func() {
+			settings.HTTPProxy = "http://proxy:8080"
+			settings.HTTPSProxy = "http://secure-proxy:8080"
+			settings.NoProxy = "internal.example.com, localhost"
+		})
+
+		It("uses the proxy matching the broker URL scheme", func() {
+			Expect(proxyFor("http://broker.example.com").String()).To(Equal("http://proxy:8080"))
+			Expect(proxyFor("https://broker.example.com").String()).To(Equal("http://secure-proxy:8080"))
+		})
+
+		It("bypasses the proxy for hosts in the no proxy list", func() {
+			Expect(proxyFor("https://broker.internal.example.com")).To(BeNil())
+			Expect(proxyFor("http://localhost:8080")).To(BeNil())
+		})
+
+		It("reuses the transport for brokers with the same proxy", func() {
+			first, err := transports.ForBroker(broker)
+			Expect(err).ToNot(HaveOccurred())
+			second, err := transports.ForBroker(&types.ServiceBroker{Name: "other"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(BeIdenticalTo(second))
+		})
+
+		Context("and the broker connects directly", func() {
+			BeforeEach(func() {
+				broker.Labels[ProxyURLLabel] = []string{DirectConnection}
+			})
+
+			It("does not use a proxy", func() {
+				Expect(proxyFor("http://broker.example.com")).To(BeNil())
+			})
+		})
+	})
+
+	Context("when a global proxy is configured for one scheme only", func() {
+		var originalEnvironmentProxy func(*http.Request) (*url.URL, error)
+
+		BeforeEach(func() {
+			settings.HTTPSProxy = "http://secure-proxy:8080"
+			settings.NoProxy = "internal.example.com"
+
+			originalEnvironmentProxy = environmentProxy
+			environmentProxy = func(*http.Request) (*url.URL, error) {
+				return url.Parse("http://environment-proxy:3128")
+			}
+		})
+
+		AfterEach(func() {
+			environmentProxy = originalEnvironmentProxy
+		})
+
+		It("uses the configured proxy for its scheme", func() {
+			Expect(proxyFor("https://broker.example.com").String()).To(Equal("http://secure-proxy:8080"))
+		})
+
+		It("uses the proxy from the environment for the other scheme", func() {
+			Expect(proxyFor("http://broker.example.com").String()).To(Equal("http://environment-proxy:3128"))
+		})
+
+		It("bypasses the proxy from the environment for hosts in the no proxy list", func() {
+			Expect(proxyFor("http://broker.internal.example.com")).To(BeNil())
+		})
+	})
+
+	Context("when the broker specifies a proxy", func() {
+		BeforeEach(func() {
+			broker.Labels[ProxyURLLabel] = []string{"http://broker-proxy:3128"}
+		})
+
+		It("uses the broker proxy", func() {
+			Expect(proxyFor("https://broker.example.com").String()).To(Equal("http://broker-proxy:3128"))
+		})
+	})
+
+	Context("when the broker pins its certificate", func() {
+		BeforeEach(func() {
+			broker.BrokerURL = "https://broker.example.com"
+			broker.Labels[CertificatePinsLabel] = []string{"sha256:" + strings.Repeat("ab", 32)}
+		})
+
+		It("uses a transport of its own", func() {
+			transport, err := transports.ForBroker(broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport).ToNot(BeIdenticalTo(clients.Transport()))
+		})
+
+		It("rejects pinned CA bundles", func() {
+			broker.Labels[CertificatePinsLabel] = []string{"ca:/etc/ssl/cert.pem"}
+			_, err := transports.ForBroker(broker)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the broker proxy is invalid", func() {
+		BeforeEach(func() {
+			broker.Labels[ProxyURLLabel] = []string{"http://%zz"}
+		})
+
+		It("returns an error", func() {
+			_, err := transports.ForBroker(broker)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
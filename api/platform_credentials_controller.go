@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/platformtypes"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+)
+
+// PlatformCredentialsController rotates the credentials of platforms. The new credentials are generated like the
+// ones of new platforms and are returned only once.
+type PlatformCredentialsController struct {
+	repository    storage.Repository
+	platformTypes *platformtypes.Registry
+	generators    *credentials.Provider
+}
+
+// NewPlatformCredentialsController returns a controller which rotates the credentials of platforms
+func NewPlatformCredentialsController(repository storage.Repository, platformTypes *platformtypes.Registry, generators *credentials.Provider) *PlatformCredentialsController {
+	return &PlatformCredentialsController{
+		repository:    repository,
+		platformTypes: platformTypes,
+		generators:    generators,
+	}
+}
+
+// Routes returns the credentials routes of the platforms
+func (c *PlatformCredentialsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/credentials", web.PlatformsURL, PathParamID),
+			},
+			Handler: c.rotate,
+			Doc: &web.RouteDoc{
+				Summary: "Replace the credentials of a platform with newly generated ones",
+			},
+		},
+	}
+}
+
+// rotate generates new credentials for the platform. If a secret store is configured, the new password is stored in
+// it by the interceptors of the platform update.
+func (c *PlatformCredentialsController) rotate(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	platformID := r.PathParams[PathParamID]
+
+	obj, err := c.repository.Get(ctx, types.PlatformType, platformID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.PlatformType))
+	}
+	platform := obj.(*types.Platform)
+
+	generated, credentialsType, err := interceptors.GenerateCredentials(ctx, c.platformTypes, c.generators, platform)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate credentials for platform %s: %s", platformID, err)
+	}
+	platform.Credentials = generated
+	platform.CredentialsType = credentialsType
+	platform.UpdatedAt = time.Now().UTC()
+
+	log.C(ctx).Infof("Rotating credentials of platform %s", platformID)
+	updated, err := c.repository.Update(ctx, platform)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.PlatformType))
+	}
+	return util.NewJSONResponse(http.StatusOK, updated)
+}
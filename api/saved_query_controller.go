@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+// SavedQueryController implements api.Controller by providing the API to manage the saved queries. The users see
+// and manage their own saved queries and the shared ones.
+type SavedQueryController struct {
+	*BaseController
+}
+
+func NewSavedQueryController(repository storage.Repository) *SavedQueryController {
+	return &SavedQueryController{
+		BaseController: NewController(repository, web.SavedQueriesURL, types.SavedQueryType, func() types.Object {
+			return &types.SavedQuery{}
+		}),
+	}
+}
+
+func (c *SavedQueryController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.SavedQueriesURL,
+			},
+			Handler: c.create,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.SavedQueriesURL, PathParamID),
+			},
+			Handler: c.get,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.SavedQueriesURL,
+			},
+			Handler: c.list,
+			Doc:     c.criteriaDoc("List"),
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPatch,
+				Path:   fmt.Sprintf("%s/{%s}", web.SavedQueriesURL, PathParamID),
+			},
+			Handler: c.patchSavedQuery,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   fmt.Sprintf("%s/{%s}", web.SavedQueriesURL, PathParamID),
+			},
+			Handler: c.delete,
+		},
+	}
+}
+
+func (c *SavedQueryController) create(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	user, err := savedQueryUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	object, err := c.objectFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	savedQuery := object.(*types.SavedQuery)
+	savedQuery.Owner = ""
+	if !savedQuery.Shared {
+		savedQuery.Owner = user
+	}
+	if _, err := query.SavedQueryCriteria(savedQuery); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Debugf("Creating saved query %s of owner %q", savedQuery.Name, savedQuery.Owner)
+	createdObj, err := c.repository.Create(ctx, savedQuery)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	return util.NewJSONResponse(http.StatusCreated, createdObj)
+}
+
+func (c *SavedQueryController) get(r *web.Request) (*web.Response, error) {
+	if _, err := c.visibleSavedQuery(r); err != nil {
+		return nil, err
+	}
+	return c.GetSingleObject(r)
+}
+
+func (c *SavedQueryController) list(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	user, err := savedQueryUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = query.AddCriteria(ctx, query.VisibleSavedQueries(user))
+	if err != nil {
+		return nil, err
+	}
+	r.Request = r.WithContext(ctx)
+	return c.ListObjects(r)
+}
+
+func (c *SavedQueryController) patchSavedQuery(r *web.Request) (*web.Response, error) {
+	savedQuery, err := c.visibleSavedQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	if gjson.GetBytes(body, "owner").Exists() || gjson.GetBytes(body, "shared").Exists() {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "the owner of a saved query cannot be changed",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if err := util.BytesToObject(body, savedQuery); err != nil {
+		return nil, err
+	}
+	if _, err := query.SavedQueryCriteria(savedQuery); err != nil {
+		return nil, err
+	}
+	return c.PatchObject(r)
+}
+
+func (c *SavedQueryController) delete(r *web.Request) (*web.Response, error) {
+	if _, err := c.visibleSavedQuery(r); err != nil {
+		return nil, err
+	}
+	return c.DeleteSingleObject(r)
+}
+
+// visibleSavedQuery returns the saved query with the id of the request if it is visible to the user of the request
+func (c *SavedQueryController) visibleSavedQuery(r *web.Request) (*types.SavedQuery, error) {
+	ctx := r.Context()
+	user, err := savedQueryUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	object, err := c.repository.Get(ctx, c.objectType, r.PathParams[PathParamID])
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	savedQuery := object.(*types.SavedQuery)
+	if !savedQuery.Shared && savedQuery.Owner != user {
+		return nil, util.HandleStorageError(util.ErrNotFoundInStorage, string(c.objectType))
+	}
+	return savedQuery, nil
+}
+
+func savedQueryUser(ctx context.Context) (string, error) {
+	user, found := web.UserFromContext(ctx)
+	if !found || user.Name == "" {
+		return "", security.UnauthorizedHTTPError("saved queries require an authenticated user")
+	}
+	return user.Name, nil
+}
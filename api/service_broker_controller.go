@@ -0,0 +1,552 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+)
+
+const (
+	// QueryParamAsync is the query parameter which requests a resource to be processed asynchronously
+	QueryParamAsync = "async"
+
+	// QueryParamValidate is the query parameter which requests a broker to be validated instead of persisted
+	QueryParamValidate = "validate"
+
+	// DuplicateBrokerURLsAllow registers brokers regardless of whether their URL is already registered
+	DuplicateBrokerURLsAllow = "allow"
+
+	// DuplicateBrokerURLsReject rejects the registration of brokers whose URL is already registered
+	DuplicateBrokerURLsReject = "reject"
+
+	// DuplicateBrokerURLsAdopt registers the name of a broker whose URL is already registered as an alias of the
+	// existing broker, which shares its catalog instead of creating a duplicate one
+	DuplicateBrokerURLsAdopt = "adopt"
+
+	// BrokerAliasesLabel is the label with the names under which a broker was registered again
+	BrokerAliasesLabel = "aliases"
+
+	// AsyncOperationsFeature is the name of the feature which allows brokers to be processed asynchronously
+	AsyncOperationsFeature = "async_operations"
+)
+
+// AsyncOperationsFlag is the feature flag which allows brokers to be registered and deleted asynchronously. If it is
+// disabled the asynchronous requests are processed synchronously.
+var AsyncOperationsFlag = features.Flag{
+	Name:        AsyncOperationsFeature,
+	Description: "whether brokers can be registered and deleted asynchronously",
+	Default:     true,
+}
+
+// ServiceBrokerController implements api.Controller by providing service brokers API logic
+type ServiceBrokerController struct {
+	*BaseController
+
+	ctx       context.Context
+	settings  *Settings
+	validator *osb.BrokerValidator
+	uploads   cache.Store
+}
+
+// isAsync returns whether the request asks to be processed asynchronously and asynchronous operations are enabled
+func isAsync(r *web.Request) bool {
+	return r.URL.Query().Get(QueryParamAsync) == "true" && features.IsEnabled(r.Context(), AsyncOperationsFeature)
+}
+
+// NewServiceBrokerController returns a new service brokers controller. The provided context bounds the lifetime
+// of the catalog fetches of brokers registered asynchronously, the store holds the chunks of catalog uploads.
+func NewServiceBrokerController(ctx context.Context, repository storage.Repository, settings *Settings, validator *osb.BrokerValidator, uploads cache.Store) *ServiceBrokerController {
+	return &ServiceBrokerController{
+		BaseController: NewController(repository, web.ServiceBrokersURL, types.ServiceBrokerType, func() types.Object {
+			return &types.ServiceBroker{}
+		}),
+		ctx:       ctx,
+		settings:  settings,
+		validator: validator,
+		uploads:   uploads,
+	}
+}
+
+func (c *ServiceBrokerController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ServiceBrokersURL,
+			},
+			Handler: c.CreateObject,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ServiceBrokersURL + "/validate",
+			},
+			Handler: c.ValidateObject,
+			Doc: &web.RouteDoc{
+				Summary: "Check the reachability, credentials, OSB API version and catalog of a broker without registering it",
+			},
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.ServiceBrokersURL, PathParamID),
+			},
+			Handler: c.GetSingleObject,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ServiceBrokersURL,
+			},
+			Handler: c.ListObjects,
+			Doc:     c.criteriaDoc("List"),
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   web.ServiceBrokersURL,
+			},
+			Handler: c.DeleteObjects,
+			Doc:     c.criteriaDoc("Delete"),
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   fmt.Sprintf("%s/{%s}", web.ServiceBrokersURL, PathParamID),
+			},
+			Handler: c.DeleteSingleObject,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPatch,
+				Path:   web.ServiceBrokersURL,
+			},
+			Handler: c.PatchObjects,
+			Doc:     c.criteriaDoc("Patch"),
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPatch,
+				Path:   fmt.Sprintf("%s/{%s}", web.ServiceBrokersURL, PathParamID),
+			},
+			Handler: c.PatchObject,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPut,
+				Path:   fmt.Sprintf("%s/{%s}/catalog", web.ServiceBrokersURL, PathParamID),
+			},
+			Handler: c.UploadCatalog,
+			Doc: &web.RouteDoc{
+				Summary: "Upload the catalog of a broker labeled with catalog_source=upload, optionally gzipped or in chunks with Content-Range headers",
+			},
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/catalog/refetch", web.ServiceBrokersURL, PathParamID),
+			},
+			Handler: c.RefetchCatalog,
+			Doc: &web.RouteDoc{
+				Summary: "Fetch the catalog of a broker again and update its service offerings and plans",
+			},
+		},
+	}
+}
+
+// CreateObject handles the registration of a new broker. If the request is asynchronous the broker is persisted
+// right away, its catalog is fetched in the background and an operation tracking the progress is returned.
+func (c *ServiceBrokerController) CreateObject(r *web.Request) (*web.Response, error) {
+	if r.URL.Query().Get(QueryParamValidate) == "true" {
+		return c.ValidateObject(r)
+	}
+	if c.settings.DuplicateBrokerURLs == DuplicateBrokerURLsReject || c.settings.DuplicateBrokerURLs == DuplicateBrokerURLsAdopt {
+		response, err := c.registerDuplicateURL(r)
+		if err != nil || response != nil {
+			return response, err
+		}
+	}
+	if !isAsync(r) {
+		return c.BaseController.CreateObject(r)
+	}
+
+	ctx := r.Context()
+	log.C(ctx).Debugf("Creating new %s asynchronously", c.objectType)
+
+	broker, err := c.objectFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	createdBroker, err := c.repository.Create(interceptors.ContextWithDeferredCatalogFetch(ctx), broker)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+
+	operation, err := c.createOperation(ctx, createdBroker)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.fetchCatalog(c.backgroundContext(ctx), createdBroker.GetID(), operation)
+
+	response, err := util.NewJSONResponse(http.StatusAccepted, operation)
+	if err != nil {
+		return nil, err
+	}
+	response.Header.Set("Location", r.ExternalURL(fmt.Sprintf("%s/%s", web.OperationsURL, operation.ID)).String())
+
+	return response, nil
+}
+
+// createOperation creates the operation tracking the catalog fetch of the broker registered asynchronously. If the
+// operation cannot be created the broker is deleted again, as its catalog would never be fetched otherwise.
+func (c *ServiceBrokerController) createOperation(ctx context.Context, broker types.Object) (*types.Operation, error) {
+	operation, err := newOperation(types.CREATE, broker.GetID(), broker.GetType())
+	if err != nil {
+		c.deleteBroker(ctx, broker.GetID())
+		return nil, err
+	}
+	if _, err := c.repository.Create(ctx, operation); err != nil {
+		c.deleteBroker(ctx, broker.GetID())
+		return nil, util.HandleStorageError(err, string(types.OperationObjectType))
+	}
+	return operation, nil
+}
+
+func (c *ServiceBrokerController) deleteBroker(ctx context.Context, brokerID string) {
+	byID := query.ByField(query.EqualsOperator, "id", brokerID)
+	if _, err := c.repository.Delete(ctx, c.objectType, byID); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not delete %s with id %s whose operation could not be created", c.objectType, brokerID)
+	}
+}
+
+// registerDuplicateURL applies the policy for duplicate broker URLs if the URL of the broker in the request is
+// already registered. It returns no response if the broker can be registered.
+func (c *ServiceBrokerController) registerDuplicateURL(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	obj, err := c.objectFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	broker := obj.(*types.ServiceBroker)
+	existing, err := c.brokerWithURL(ctx, broker.BrokerURL, "")
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if c.settings.DuplicateBrokerURLs == DuplicateBrokerURLsReject {
+		return nil, &util.HTTPError{
+			ErrorType:   "Conflict",
+			Description: fmt.Sprintf("a broker with URL %s is already registered with name %s", broker.BrokerURL, existing.Name),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	// only callers who know the credentials of the existing broker can adopt it
+	if !sameCredentials(broker.Credentials, existing.Credentials) {
+		return nil, &util.HTTPError{
+			ErrorType:   "Conflict",
+			Description: fmt.Sprintf("a broker with URL %s is already registered with name %s and other credentials", broker.BrokerURL, existing.Name),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	if broker.Name != existing.Name && !isAlias(existing, broker.Name) {
+		log.C(ctx).Infof("Adopting broker %s with URL %s as alias %s", existing.Name, existing.BrokerURL, broker.Name)
+		updated, err := c.repository.Update(ctx, existing, &query.LabelChange{
+			Operation: query.AddLabelValuesOperation,
+			Key:       BrokerAliasesLabel,
+			Values:    []string{broker.Name},
+		})
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(c.objectType))
+		}
+		existing = updated.(*types.ServiceBroker)
+	}
+	stripCredentials(ctx, existing)
+	return util.NewJSONResponse(http.StatusOK, existing)
+}
+
+// brokerWithURL returns a broker other than the one with the excluded id which is registered with the URL, if any
+func (c *ServiceBrokerController) brokerWithURL(ctx context.Context, brokerURL, excludedID string) (*types.ServiceBroker, error) {
+	url := strings.TrimSuffix(brokerURL, "/")
+	if url == "" {
+		return nil, nil
+	}
+	existingBrokers, err := c.repository.List(ctx, types.ServiceBrokerType, query.ByField(query.InOperator, "broker_url", url, url+"/"))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	for i := 0; i < existingBrokers.Len(); i++ {
+		if existing := existingBrokers.ItemAt(i).(*types.ServiceBroker); existing.ID != excludedID {
+			return existing, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkURLChange rejects the update of the brokers matching the criteria with the URL in the body if the URL is
+// registered for another broker and duplicate URLs are not allowed. Brokers can only be adopted on registration.
+func (c *ServiceBrokerController) checkURLChange(ctx context.Context, body []byte, criteria ...query.Criterion) error {
+	if c.settings.DuplicateBrokerURLs != DuplicateBrokerURLsReject && c.settings.DuplicateBrokerURLs != DuplicateBrokerURLsAdopt {
+		return nil
+	}
+	brokerURL := gjson.GetBytes(body, "broker_url")
+	if brokerURL.Type != gjson.String {
+		return nil
+	}
+	brokers, err := c.repository.List(ctx, types.ServiceBrokerType, criteria...)
+	if err != nil {
+		return util.HandleStorageError(err, string(c.objectType))
+	}
+	if brokers.Len() == 0 {
+		return nil
+	}
+	if brokers.Len() > 1 {
+		return &util.HTTPError{
+			ErrorType:   "Conflict",
+			Description: fmt.Sprintf("the URL %s cannot be registered for %d brokers", brokerURL.String(), brokers.Len()),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	existing, err := c.brokerWithURL(ctx, brokerURL.String(), brokers.ItemAt(0).GetID())
+	if err != nil || existing == nil {
+		return err
+	}
+	return &util.HTTPError{
+		ErrorType:   "Conflict",
+		Description: fmt.Sprintf("a broker with URL %s is already registered with name %s", brokerURL.String(), existing.Name),
+		StatusCode:  http.StatusConflict,
+	}
+}
+
+func isAlias(broker *types.ServiceBroker, name string) bool {
+	for _, alias := range broker.Labels[BrokerAliasesLabel] {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sameCredentials(credentials, other *types.Credentials) bool {
+	if credentials == nil || credentials.Basic == nil || other == nil || other.Basic == nil {
+		return credentials == nil && other == nil
+	}
+	sameUsername := subtle.ConstantTimeCompare([]byte(credentials.Basic.Username), []byte(other.Basic.Username))
+	samePassword := subtle.ConstantTimeCompare([]byte(credentials.Basic.Password), []byte(other.Basic.Password))
+	return sameUsername&samePassword == 1
+}
+
+// PatchObjects handles the update of the brokers matching the criteria of the request. If only labels are changed
+// the catalogs of the brokers are not fetched again, so that brokers which are unreachable can be relabeled as well.
+func (c *ServiceBrokerController) PatchObjects(r *web.Request) (*web.Response, error) {
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := sjson.DeleteBytes(body, "labels")
+	if err != nil {
+		return nil, err
+	}
+	var changedFields map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &changedFields); err == nil && len(changedFields) == 0 {
+		r.Request = r.WithContext(interceptors.ContextWithLabelsOnlyUpdate(r.Context()))
+	}
+	if criteria := query.CriteriaForContext(r.Context()); len(criteria) != 0 {
+		if err := c.checkURLChange(r.Context(), fields, criteria...); err != nil {
+			return nil, err
+		}
+	}
+	return c.BaseController.PatchObjects(r)
+}
+
+// ValidateObject checks whether the broker in the request can be registered and returns a report of the checks.
+// Nothing is persisted.
+func (c *ServiceBrokerController) ValidateObject(r *web.Request) (*web.Response, error) {
+	broker, err := c.objectFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.validate(r.Context(), broker.(*types.ServiceBroker))
+}
+
+// PatchObject handles the update of a broker. If validation is requested the updated broker is validated
+// instead of persisted.
+func (c *ServiceBrokerController) PatchObject(r *web.Request) (*web.Response, error) {
+	if r.URL.Query().Get(QueryParamValidate) != "true" {
+		body, err := r.BodyBytes()
+		if err != nil {
+			return nil, err
+		}
+		byID := query.ByField(query.EqualsOperator, "id", r.PathParams[PathParamID])
+		if err := c.checkURLChange(r.Context(), body, byID); err != nil {
+			return nil, err
+		}
+		return c.BaseController.PatchObject(r)
+	}
+
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+	broker, err := c.repository.Get(ctx, c.objectType, objectID)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(c.objectType))
+	}
+	body, err := r.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	body, err = sjson.DeleteBytes(body, "labels")
+	if err != nil {
+		return nil, err
+	}
+	if err := util.BytesToObject(body, broker); err != nil {
+		return nil, err
+	}
+	return c.validate(ctx, broker.(*types.ServiceBroker))
+}
+
+func (c *ServiceBrokerController) validate(ctx context.Context, broker *types.ServiceBroker) (*web.Response, error) {
+	log.C(ctx).Debugf("Validating %s with name %s", c.objectType, broker.Name)
+	report, err := c.validator.Validate(ctx, broker)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, report)
+}
+
+// fetchCatalog fetches and persists the catalog of the broker with the specified id by updating the broker.
+// Failed attempts are retried according to the settings and the outcome is recorded in the provided operation.
+func (c *ServiceBrokerController) fetchCatalog(ctx context.Context, brokerID string, operation *types.Operation) {
+	attempts := c.settings.CatalogFetchAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		c.updateOperation(ctx, operation, types.IN_PROGRESS, fmt.Sprintf("fetching broker catalog (attempt %d of %d)", attempt, attempts), nil)
+
+		if err = c.refetchCatalog(ctx, brokerID); err == nil {
+			c.updateOperation(ctx, operation, types.SUCCEEDED, "broker catalog fetched successfully", nil)
+			return
+		}
+		log.C(ctx).WithError(err).Warnf("Attempt %d of %d to fetch catalog of broker with id %s failed", attempt, attempts, brokerID)
+
+		if attempt < attempts {
+			select {
+			case <-ctx.Done():
+				c.updateOperation(ctx, operation, types.FAILED, "broker catalog fetch was interrupted", ctx.Err())
+				return
+			case <-time.After(c.settings.CatalogFetchRetryInterval):
+			}
+		}
+	}
+
+	description := "could not fetch broker catalog, update the broker to retry"
+	if c.settings.CatalogRecoveryInterval > 0 {
+		description = "could not fetch broker catalog, the fetch is retried in the background"
+	}
+	c.updateOperation(ctx, operation, types.FAILED, description, err)
+}
+
+// RefetchCatalog handles the refetching of the catalog of a broker and returns the updated broker
+func (c *ServiceBrokerController) RefetchCatalog(r *web.Request) (*web.Response, error) {
+	brokerID := r.PathParams[PathParamID]
+	log.C(r.Context()).Debugf("Refetching catalog of %s with id %s", c.objectType, brokerID)
+
+	if err := c.refetchCatalog(r.Context(), brokerID); err != nil {
+		return nil, err
+	}
+	return c.GetSingleObject(r)
+}
+
+func (c *ServiceBrokerController) refetchCatalog(ctx context.Context, brokerID string) error {
+	broker, err := c.repository.Get(ctx, types.ServiceBrokerType, brokerID)
+	if err != nil {
+		return util.HandleStorageError(err, string(c.objectType))
+	}
+	broker.SetUpdatedAt(time.Now().UTC())
+
+	if _, err := c.repository.Update(ctx, broker); err != nil {
+		return util.HandleStorageError(err, string(c.objectType))
+	}
+	return nil
+}
+
+func (c *ServiceBrokerController) updateOperation(ctx context.Context, operation *types.Operation, state types.OperationState, description string, opErr error) {
+	operation.State = state
+	operation.Description = description
+	operation.UpdatedAt = time.Now().UTC()
+	operation.Errors = nil
+	if opErr != nil {
+		operation.Errors = operationErrors(ctx, operation, opErr)
+	}
+
+	if _, err := c.repository.Update(ctx, operation); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not update operation with id %s to state %s", operation.ID, state)
+	}
+}
+
+func operationErrors(ctx context.Context, operation *types.Operation, opErr error) json.RawMessage {
+	errorBytes, err := json.Marshal(map[string]string{"description": opErr.Error()})
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not marshal errors of operation with id %s", operation.ID)
+	}
+	return errorBytes
+}
+
+func newOperation(category types.OperationCategory, resourceID string, resourceType types.ObjectType) (*types.Operation, error) {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for %s: %s", types.OperationObjectType, err)
+	}
+
+	currentTime := time.Now().UTC()
+	return &types.Operation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Labels:    types.Labels{},
+		},
+		Type:         category,
+		State:        types.IN_PROGRESS,
+		ResourceID:   resourceID,
+		ResourceType: resourceType,
+	}, nil
+}
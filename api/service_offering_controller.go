@@ -53,6 +53,7 @@ func (c *ServiceOfferingController) Routes() []web.Route {
 				Path:   web.ServiceOfferingsURL,
 			},
 			Handler: c.ListObjects,
+			Doc:     c.criteriaDoc("List"),
 		},
 		{
 			Endpoint: web.Endpoint{
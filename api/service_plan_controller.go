@@ -53,6 +53,7 @@ func (c *ServicePlanController) Routes() []web.Route {
 				Path:   web.ServicePlansURL,
 			},
 			Handler: c.ListObjects,
+			Doc:     c.criteriaDoc("List"),
 		},
 		{
 			Endpoint: web.Endpoint{
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/jobs"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// StorageStatisticsController reports the sizes of the storage tables and the state of the notification clean-up
+// to operators for capacity planning
+type StorageStatisticsController struct {
+	statistics storage.StatisticsProvider
+	scheduler  *jobs.Scheduler
+	cleaner    *storage.NotificationCleaner
+}
+
+// NewStorageStatisticsController returns a controller which reports the statistics of the provider. The last run of
+// the notification cleaner is taken from the scheduler and its metrics from the cleaner, if any.
+func NewStorageStatisticsController(statistics storage.StatisticsProvider, scheduler *jobs.Scheduler, cleaner *storage.NotificationCleaner) *StorageStatisticsController {
+	return &StorageStatisticsController{
+		statistics: statistics,
+		scheduler:  scheduler,
+		cleaner:    cleaner,
+	}
+}
+
+type storageStatisticsResponse struct {
+	*storage.Statistics
+
+	// NotificationCleaner describes the runs of the cleaner on this instance only
+	NotificationCleaner *jobs.Status `json:"notification_cleaner,omitempty"`
+
+	NotificationCleanerMetrics *storage.NotificationCleanerMetrics `json:"notification_cleaner_metrics,omitempty"`
+}
+
+// Routes returns the storage statistics route
+func (c *StorageStatisticsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.MonitorStorageURL,
+			},
+			Handler: c.statisticsHandler,
+			Doc: &web.RouteDoc{
+				Summary: "Get the estimated sizes of the storage tables and the state of the notification clean-up",
+			},
+		},
+	}
+}
+
+func (c *StorageStatisticsController) statisticsHandler(r *web.Request) (*web.Response, error) {
+	statistics, err := c.statistics.Statistics(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	response := &storageStatisticsResponse{Statistics: statistics}
+	if c.scheduler != nil {
+		for _, status := range c.scheduler.Statuses() {
+			if status.Name == storage.NotificationCleanerJobName {
+				status := status
+				response.NotificationCleaner = &status
+			}
+		}
+	}
+	if c.cleaner != nil {
+		metrics := c.cleaner.Metrics()
+		response.NotificationCleanerMetrics = &metrics
+	}
+	return util.NewJSONResponse(http.StatusOK, response)
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// TenantKeySettings configures which callers can supply and read the keys of which tenants
+type TenantKeySettings struct {
+	TenantClaim string `mapstructure:"tenant_claim" description:"claim of the token of a caller holding its tenant, callers can only supply and read the key of their own tenant"`
+	AdminScope  string `mapstructure:"admin_scope" description:"scope of the callers allowed to supply and read the keys of all tenants, no caller is if empty"`
+	KeyPrefix   string `mapstructure:"key_prefix" description:"prefix of the key references of all tenants, the key reference of a tenant is the prefix followed by the tenant and optionally a dot and a suffix"`
+}
+
+// DefaultTenantKeySettings returns the default values for the tenant keys API
+func DefaultTenantKeySettings() *TenantKeySettings {
+	return &TenantKeySettings{
+		TenantClaim: "zid",
+		AdminScope:  "",
+		KeyPrefix:   "sm.",
+	}
+}
+
+// Validate validates the tenant keys API settings
+func (s *TenantKeySettings) Validate() error {
+	if s.TenantClaim == "" {
+		return fmt.Errorf("validate Settings: TenantKeys.TenantClaim missing")
+	}
+	if s.KeyPrefix == "" {
+		return fmt.Errorf("validate Settings: TenantKeys.KeyPrefix missing")
+	}
+	return nil
+}
+
+// TenantKeyController implements api.Controller by providing the API to supply the key management service keys
+// with which the credentials of the brokers of a tenant are encrypted
+type TenantKeyController struct {
+	*BaseController
+
+	tenantKeys *storage.TenantKeys
+	settings   *TenantKeySettings
+}
+
+func NewTenantKeyController(repository storage.Repository, tenantKeys *storage.TenantKeys, settings *TenantKeySettings) *TenantKeyController {
+	if settings == nil {
+		settings = DefaultTenantKeySettings()
+	}
+	return &TenantKeyController{
+		BaseController: NewController(repository, web.TenantKeysURL, types.TenantKeyType, func() types.Object {
+			return &types.TenantKey{}
+		}),
+		tenantKeys: tenantKeys,
+		settings:   settings,
+	}
+}
+
+func (c *TenantKeyController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.TenantKeysURL, PathParamID),
+			},
+			Handler: c.get,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.TenantKeysURL,
+			},
+			Handler: c.list,
+			Doc:     c.criteriaDoc("List"),
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPut,
+				Path:   fmt.Sprintf("%s/{%s}", web.TenantKeysURL, PathParamID),
+			},
+			Handler: c.set,
+			Doc: &web.RouteDoc{
+				Summary: "Supply the key with which the encryption key of a tenant is wrapped, an existing encryption key is rewrapped",
+			},
+		},
+	}
+}
+
+type tenantKeyRequest struct {
+	Provider string `json:"provider"`
+	KeyRef   string `json:"key_ref"`
+}
+
+func (c *TenantKeyController) get(r *web.Request) (*web.Response, error) {
+	if err := c.authorize(r, r.PathParams[PathParamID]); err != nil {
+		return nil, err
+	}
+	return c.GetSingleObject(r)
+}
+
+// list returns the keys of all tenants to administrators and only the key of their own tenant to other callers
+func (c *TenantKeyController) list(r *web.Request) (*web.Response, error) {
+	user, found := web.UserFromContext(r.Context())
+	if !found {
+		return nil, security.UnauthorizedHTTPError("No authenticated user found")
+	}
+	if c.isAdmin(user) {
+		return c.ListObjects(r)
+	}
+	tenant := c.tenantOf(user)
+	if tenant == "" {
+		return nil, security.ForbiddenHTTPError("The caller does not belong to a tenant")
+	}
+	ctx, err := query.AddCriteria(r.Context(), query.ByField(query.EqualsOperator, "id", tenant))
+	if err != nil {
+		return nil, err
+	}
+	r.Request = r.WithContext(ctx)
+	return c.ListObjects(r)
+}
+
+func (c *TenantKeyController) set(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	tenant := r.PathParams[PathParamID]
+	if err := c.authorize(r, tenant); err != nil {
+		return nil, err
+	}
+
+	request := &tenantKeyRequest{}
+	if err := json.NewDecoder(r.BodyReader()).Decode(request); err != nil {
+		return nil, badRequest("could not parse tenant key request: %s", err)
+	}
+	if request.Provider == "" || request.KeyRef == "" {
+		return nil, badRequest("provider and key_ref are required")
+	}
+	if !c.isKeyOf(tenant, request.KeyRef) {
+		return nil, badRequest("key_ref of tenant %s must be %s%s or start with %s%s.", tenant, c.settings.KeyPrefix, tenant, c.settings.KeyPrefix, tenant)
+	}
+	log.C(ctx).Debugf("Setting key %s of provider %s for tenant %s", request.KeyRef, request.Provider, tenant)
+
+	tenantKey, err := c.tenantKeys.Set(ctx, c.repository, tenant, request.Provider, request.KeyRef)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.TenantKeyType))
+	}
+	return util.NewJSONResponse(http.StatusOK, tenantKey)
+}
+
+// authorize allows administrators to access the key of any tenant and other callers only the key of their own tenant
+func (c *TenantKeyController) authorize(r *web.Request, tenant string) error {
+	user, found := web.UserFromContext(r.Context())
+	if !found {
+		return security.UnauthorizedHTTPError("No authenticated user found")
+	}
+	if c.isAdmin(user) {
+		return nil
+	}
+	if callerTenant := c.tenantOf(user); callerTenant == "" || callerTenant != tenant {
+		return security.ForbiddenHTTPError(fmt.Sprintf("The caller is not allowed to access the key of tenant %s", tenant))
+	}
+	return nil
+}
+
+// isKeyOf returns true if the key reference is bound to the tenant, so that no caller can wrap the encryption key of
+// a tenant with a key of the key management service which belongs to another tenant or to the Service Manager itself
+func (c *TenantKeyController) isKeyOf(tenant, keyRef string) bool {
+	tenantKey := c.settings.KeyPrefix + tenant
+	return keyRef == tenantKey || strings.HasPrefix(keyRef, tenantKey+".")
+}
+
+func (c *TenantKeyController) isAdmin(user *web.UserContext) bool {
+	return c.settings.AdminScope != "" && user.Scopes()[c.settings.AdminScope]
+}
+
+// tenantOf returns the tenant claim of the token of the user or an empty string if it has none
+func (c *TenantKeyController) tenantOf(user *web.UserContext) string {
+	if user.Data == nil {
+		return ""
+	}
+	claims := make(map[string]interface{})
+	if err := user.Data.Data(&claims); err != nil {
+		return ""
+	}
+	tenant, _ := claims[c.settings.TenantClaim].(string)
+	return tenant
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type tokenClaims map[string]interface{}
+
+func (c tokenClaims) Data(v interface{}) error {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
+
+var _ = Describe("Tenant key controller", func() {
+	var controller *api.TenantKeyController
+
+	setKey := func(tenant, keyRef string) error {
+		httpRequest, err := http.NewRequest(http.MethodPut, "https://sm.example.com/v1/tenant_keys/"+tenant, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := web.ContextWithUser(httpRequest.Context(), &web.UserContext{Name: "tenant-a", Data: tokenClaims{"zid": "tenant-a"}})
+		request := &web.Request{
+			Request:    httpRequest.WithContext(ctx),
+			PathParams: map[string]string{api.PathParamID: tenant},
+			Body:       []byte(`{"provider":"vault-transit","key_ref":"` + keyRef + `"}`),
+		}
+		for _, route := range controller.Routes() {
+			if route.Endpoint.Method == http.MethodPut && strings.HasPrefix(route.Endpoint.Path, web.TenantKeysURL) {
+				_, err = route.Handler(request)
+				return err
+			}
+		}
+		Fail("no route for supplying tenant keys")
+		return nil
+	}
+
+	BeforeEach(func() {
+		controller = api.NewTenantKeyController(&storagefakes.FakeStorage{}, nil, api.DefaultTenantKeySettings())
+	})
+
+	DescribeTable("rejects keys which are not bound to the tenant",
+		func(keyRef string) {
+			err := setKey("tenant-a", keyRef)
+			Expect(err).To(HaveOccurred())
+			httpErr, ok := err.(*util.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("key without prefix", "tenant-a"),
+		Entry("key of another tenant", "sm.tenant-b"),
+		Entry("key of a tenant with the same prefix", "sm.tenant-ab"),
+		Entry("key of the Service Manager", "sm"),
+	)
+})
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// VisibilityResolutionController returns which platforms can see a service plan and which service plans a platform
+// can see, as resolved from the visibilities in effect
+type VisibilityResolutionController struct {
+	repository storage.Repository
+	resolver   storage.VisibilityResolver
+}
+
+// NewVisibilityResolutionController returns a controller which resolves the visibilities with the provided resolver
+func NewVisibilityResolutionController(repository storage.Repository, resolver storage.VisibilityResolver) *VisibilityResolutionController {
+	return &VisibilityResolutionController{
+		repository: repository,
+		resolver:   resolver,
+	}
+}
+
+// Routes returns the visibility resolution routes of service plans and platforms
+func (c *VisibilityResolutionController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}/platforms", web.ServicePlansURL, PathParamID),
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.resolve(r, types.ServicePlanType, c.resolver.PlatformsForPlan)
+			},
+			Doc: &web.RouteDoc{
+				Summary: "List the platforms which can see a service plan through the visibilities in effect",
+			},
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}/service_plans", web.PlatformsURL, PathParamID),
+			},
+			Handler: func(r *web.Request) (*web.Response, error) {
+				return c.resolve(r, types.PlatformType, c.resolver.PlansForPlatform)
+			},
+			Doc: &web.RouteDoc{
+				Summary: "List the service plans which a platform can see through the visibilities in effect",
+			},
+		},
+	}
+}
+
+type resolveFunc func(ctx context.Context, id string, at time.Time) (types.ObjectList, error)
+
+func (c *VisibilityResolutionController) resolve(r *web.Request, objectType types.ObjectType, resolve resolveFunc) (*web.Response, error) {
+	ctx := r.Context()
+	objectID := r.PathParams[PathParamID]
+	log.C(ctx).Debugf("Resolving visibilities of %s with id %s", objectType, objectID)
+
+	if _, err := c.repository.Get(ctx, objectType, objectID); err != nil {
+		return nil, util.HandleStorageError(err, string(objectType))
+	}
+
+	objectList, err := resolve(ctx, objectID, time.Now().UTC())
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.VisibilityType))
+	}
+	for i := 0; i < objectList.Len(); i++ {
+		stripCredentials(ctx, objectList.ItemAt(i))
+	}
+
+	return util.NewJSONResponse(http.StatusOK, objectList)
+}
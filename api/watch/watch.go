@@ -0,0 +1,348 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package watch contains the watch of the resources of the API, which streams their changes recorded as notifications
+// to clients, e.g. Kubernetes-style controllers reconciling Service Manager resources
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// FilterName is the name of the watch filter
+	FilterName = "WatchFilter"
+
+	// QueryParam is the query parameter which turns a list request into a watch, e.g. /v1/service_brokers?watch=true
+	QueryParam = "watch"
+
+	// FromRevisionQueryParam is the query parameter with the revision after which the changes are streamed. Only the
+	// changes after the start of the watch are streamed if it is missing.
+	FromRevisionQueryParam = "from_revision"
+
+	// ContentType is the content type of the stream of events, one JSON event per line
+	ContentType = "application/x-ndjson"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	PollInterval time.Duration `mapstructure:"poll_interval" description:"time between the checks of a watch for new changes of the watched resource"`
+	Timeout      time.Duration `mapstructure:"timeout" description:"time after which a watch is closed, clients continue watching from the revision of the last event they received"`
+	BatchSize    int           `mapstructure:"batch_size" description:"maximum number of changes read from the storage per check of a watch"`
+}
+
+// DefaultSettings returns the default values for the watch settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		PollInterval: time.Second,
+		Timeout:      30 * time.Minute,
+		BatchSize:    100,
+	}
+}
+
+// Validate validates the watch settings
+func (s *Settings) Validate() error {
+	if s.PollInterval <= 0 {
+		return fmt.Errorf("validate Settings: watch poll interval must be > 0")
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("validate Settings: watch timeout must be > 0")
+	}
+	if s.BatchSize < 1 {
+		return fmt.Errorf("validate Settings: watch batch size must be > 0")
+	}
+	return nil
+}
+
+// Event describes a change of a watched resource. Object is the state of the resource after the change or, if it
+// was deleted, before the deletion.
+type Event struct {
+	Type     types.OperationType `json:"type"`
+	Revision int64               `json:"revision"`
+	Object   json.RawMessage     `json:"object"`
+}
+
+// Filter turns the list requests of the watched resources with the watch query parameter into a stream of the
+// changes of the resources. Only resources whose changes are recorded as notifications can be watched.
+type Filter struct {
+	ctx        context.Context
+	settings   *Settings
+	repository storage.Repository
+	resources  map[string]types.ObjectType
+	fields     *filters.FieldAuthorizationFilter
+}
+
+// NewFilter returns a filter which watches the resources with the specified base URLs. Watches are closed when the
+// context is done. The fields of the streamed resources are removed according to the field authorization filter,
+// if any.
+func NewFilter(ctx context.Context, settings *Settings, repository storage.Repository, resources map[string]types.ObjectType, fields *filters.FieldAuthorizationFilter) *Filter {
+	return &Filter{
+		ctx:        ctx,
+		settings:   settings,
+		repository: repository,
+		resources:  resources,
+		fields:     fields,
+	}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*Filter) Name() string {
+	return FilterName
+}
+
+// Run streams the changes of the resource of the request if it asks for a watch
+func (f *Filter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if req.URL.Query().Get(QueryParam) != "true" {
+		return next.Handle(req)
+	}
+	ctx := req.Context()
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	objectType, found := f.resources[path]
+	if !found {
+		return nil, badRequest("resource %s cannot be watched", req.URL.Path)
+	}
+	if req.URL.Query().Get(string(query.FieldQuery)) != "" || req.URL.Query().Get(string(query.LabelQuery)) != "" {
+		return nil, badRequest("selection criteria are not supported by watches")
+	}
+	user, err := f.checkUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fromRevision, err := f.fromRevision(ctx, req.URL.Query().Get(FromRevisionQueryParam))
+	if err != nil {
+		return nil, err
+	}
+
+	hijacker, ok := req.HijackResponseWriter().(http.Hijacker)
+	if !ok {
+		return nil, errors.New("response writer does not support streaming watches")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// the watch outlives the request, it is logged with the correlation id of the request and stopped on shutdown.
+	// The user of the request decides which fields of the resources are streamed.
+	streamCtx := web.ContextWithUser(log.ContextWithLogger(f.ctx, log.C(ctx)), user)
+	log.C(ctx).Infof("Watching %s from revision %d", objectType, fromRevision)
+	go f.stream(streamCtx, conn, rw, path, objectType, fromRevision)
+
+	return &web.Response{}, nil
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (f *Filter) FilterMatchers() []web.FilterMatcher {
+	paths := make([]string, 0, len(f.resources))
+	for path := range f.resources {
+		paths = append(paths, path)
+	}
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(paths...),
+				web.Methods(http.MethodGet),
+			},
+		},
+	}
+}
+
+// checkUser rejects watches of platforms, which receive the changes relevant to them through their notifications
+// websocket. It returns the user of the watch.
+func (f *Filter) checkUser(ctx context.Context) (*web.UserContext, error) {
+	user, ok := web.UserFromContext(ctx)
+	if !ok {
+		return nil, errors.New("user details not found in request context")
+	}
+	platform := &types.Platform{}
+	if err := user.Data.Data(platform); err != nil {
+		return nil, err
+	}
+	if platform.ID != "" {
+		return nil, &util.HTTPError{
+			ErrorType:   "Forbidden",
+			Description: "platforms cannot watch resources, they receive notifications instead",
+			StatusCode:  http.StatusForbidden,
+		}
+	}
+	return user, nil
+}
+
+// fromRevision returns the revision after which the changes are streamed, which is the latest revision if none is
+// requested. The requested revision must still be known like in the change feed, otherwise changes may have been missed.
+func (f *Filter) fromRevision(ctx context.Context, value string) (int64, error) {
+	if value == "" {
+		latest, err := f.repository.List(ctx, types.NotificationType,
+			query.OrderResultBy("revision", query.DescOrder),
+			query.LimitResultBy(1))
+		if err != nil {
+			return 0, util.HandleStorageError(err, string(types.NotificationType))
+		}
+		if latest.Len() == 0 {
+			return 0, nil
+		}
+		return latest.ItemAt(0).(*types.Notification).Revision, nil
+	}
+
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, badRequest("invalid %s query parameter", FromRevisionQueryParam)
+	}
+	if revision == 0 {
+		return 0, nil
+	}
+	known, err := f.repository.List(ctx, types.NotificationType, query.ByField(query.EqualsOperator, "revision", value))
+	if err != nil {
+		return 0, util.HandleStorageError(err, string(types.NotificationType))
+	}
+	if known.Len() == 0 {
+		return 0, &util.HTTPError{
+			ErrorType:   "Gone",
+			Description: fmt.Sprintf("revision %d is no longer known, the resources should be listed again", revision),
+			StatusCode:  http.StatusGone,
+		}
+	}
+	return revision, nil
+}
+
+// changes returns the changes of the resource after the revision in the order of their revisions without the fields
+// the user of the watch may not see
+func (f *Filter) changes(ctx context.Context, path string, objectType types.ObjectType, afterRevision int64) ([]*Event, error) {
+	notifications, err := f.repository.List(ctx, types.NotificationType,
+		query.ByField(query.EqualsOperator, "resource", string(objectType)),
+		query.ByField(query.GreaterThanOperator, "revision", strconv.FormatInt(afterRevision, 10)),
+		query.OrderResultBy("revision", query.AscOrder),
+		query.LimitResultBy(f.settings.BatchSize))
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*Event, 0, notifications.Len())
+	for i := 0; i < notifications.Len(); i++ {
+		notification := notifications.ItemAt(i).(*types.Notification)
+		object := gjson.GetBytes(notification.Payload, "new.resource")
+		if notification.Type == types.DELETED {
+			object = gjson.GetBytes(notification.Payload, "old.resource")
+		}
+		redacted, err := f.fields.Redact(ctx, path, []byte(object.Raw))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &Event{
+			Type:     notification.Type,
+			Revision: notification.Revision,
+			Object:   json.RawMessage(redacted),
+		})
+	}
+	return events, nil
+}
+
+// stream writes the changes of the resource as a chunked response until the watch times out, the client closes
+// the connection or the Service Manager shuts down
+func (f *Filter) stream(ctx context.Context, conn net.Conn, rw *bufio.ReadWriter, path string, objectType types.ObjectType, revision int64) {
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, f.settings.Timeout)
+	defer cancel()
+
+	// the deadlines of the request do not apply to the watch
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		log.C(ctx).WithError(err).Error("Could not reset the deadlines of the watch connection")
+		return
+	}
+	// clients do not send anything after the request, so reading only returns once the connection is closed
+	go func() {
+		defer cancel()
+		_, _ = rw.Read(make([]byte, 1))
+	}()
+
+	header := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: %s\r\nCache-Control: no-cache\r\nTransfer-Encoding: chunked\r\n\r\n", ContentType)
+	if _, err := rw.WriteString(header); err != nil {
+		log.C(ctx).WithError(err).Error("Could not write the header of the watch")
+		return
+	}
+	chunks := httputil.NewChunkedWriter(rw)
+	defer func() {
+		if err := chunks.Close(); err == nil {
+			_, _ = rw.WriteString("\r\n")
+			_ = rw.Flush()
+		}
+	}()
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(f.settings.PollInterval)
+	defer ticker.Stop()
+	for {
+		events, err := f.changes(ctx, path, objectType, revision)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.C(ctx).WithError(err).Errorf("Could not read the changes of %s, closing the watch", objectType)
+			}
+			return
+		}
+		for _, event := range events {
+			line, err := json.Marshal(event)
+			if err != nil {
+				log.C(ctx).WithError(err).Errorf("Could not serialize the change of %s with revision %d", objectType, event.Revision)
+				return
+			}
+			if _, err := chunks.Write(append(line, '\n')); err != nil {
+				return
+			}
+			revision = event.Revision
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+		if len(events) == f.settings.BatchSize {
+			// there are probably more changes, they are read without waiting
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			log.C(ctx).Debugf("Watch of %s closed at revision %d", objectType, revision)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/api/watch"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Watch Suite")
+}
+
+var _ = Describe("Watch filter", func() {
+	var (
+		ctx           context.Context
+		cancel        context.CancelFunc
+		repository    *storagefakes.FakeRepository
+		notifications []*types.Notification
+		platformID    string
+		filter        *watch.Filter
+		server        *httptest.Server
+	)
+
+	notification := func(revision int64, operation types.OperationType, payload string) *types.Notification {
+		return &types.Notification{
+			Base:     types.Base{ID: "notification"},
+			Resource: types.ServiceBrokerType,
+			Type:     operation,
+			Revision: revision,
+			Payload:  json.RawMessage(payload),
+		}
+	}
+
+	request := func(url string) (*web.Response, error) {
+		httpRequest := httptest.NewRequest(http.MethodGet, url, nil)
+		data := &webfakes.FakeData{}
+		data.DataCalls(func(v interface{}) error {
+			if platform, ok := v.(*types.Platform); ok {
+				platform.ID = platformID
+			}
+			return nil
+		})
+		req := &web.Request{Request: httpRequest.WithContext(web.ContextWithUser(httpRequest.Context(), &web.UserContext{Name: "user", Data: data}))}
+		return filter.Run(req, web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK}, nil
+		}))
+	}
+
+	expectHTTPError := func(err error, statusCode int) {
+		Expect(err).To(HaveOccurred())
+		httpErr, ok := err.(*util.HTTPError)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(statusCode))
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		notifications = nil
+		platformID = ""
+		repository = &storagefakes.FakeRepository{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			result := &types.Notifications{}
+			var after, equals int64 = -1, -1
+			for _, criterion := range criteria {
+				if criterion.LeftOp != "revision" {
+					continue
+				}
+				var value int64
+				Expect(json.Unmarshal([]byte(criterion.RightOp[0]), &value)).To(Succeed())
+				if criterion.Operator == query.GreaterThanOperator {
+					after = value
+				} else {
+					equals = value
+				}
+			}
+			for _, n := range notifications {
+				if n.Revision > after && (equals < 0 || n.Revision == equals) {
+					result.Add(n)
+				}
+			}
+			return result, nil
+		})
+		settings := watch.DefaultSettings()
+		settings.PollInterval = 10 * time.Millisecond
+		filter = watch.NewFilter(ctx, settings, repository, map[string]types.ObjectType{
+			web.ServiceBrokersURL: types.ServiceBrokerType,
+		}, nil)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := &web.Request{Request: r.WithContext(web.ContextWithUser(r.Context(), &web.UserContext{Name: "user", Data: &webfakes.FakeData{}}))}
+			req.SetResponseWriter(w)
+			if _, err := filter.Run(req, nil); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		cancel()
+	})
+
+	It("passes list requests without the watch query parameter", func() {
+		response, err := request(web.ServiceBrokersURL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("rejects watches of resources without notifications", func() {
+		_, err := request(web.PlatformsURL + "?watch=true")
+		expectHTTPError(err, http.StatusBadRequest)
+	})
+
+	It("rejects watches with selection criteria", func() {
+		_, err := request(web.ServiceBrokersURL + "?watch=true&fieldQuery=name+eq+'broker'")
+		expectHTTPError(err, http.StatusBadRequest)
+	})
+
+	It("rejects watches of platforms", func() {
+		platformID = "platform-id"
+		_, err := request(web.ServiceBrokersURL + "?watch=true")
+		expectHTTPError(err, http.StatusForbidden)
+	})
+
+	It("rejects invalid revisions", func() {
+		_, err := request(web.ServiceBrokersURL + "?watch=true&from_revision=abc")
+		expectHTTPError(err, http.StatusBadRequest)
+	})
+
+	It("responds with gone if the revision is no longer known", func() {
+		notifications = []*types.Notification{notification(5, types.CREATED, `{"new":{"resource":{"id":"broker-1"}}}`)}
+		_, err := request(web.ServiceBrokersURL + "?watch=true&from_revision=3")
+		expectHTTPError(err, http.StatusGone)
+	})
+
+	It("streams the changes after the revision", func() {
+		notifications = []*types.Notification{
+			notification(3, types.CREATED, `{"new":{"resource":{"id":"broker-1"}}}`),
+			notification(4, types.MODIFIED, `{"new":{"resource":{"id":"broker-1","name":"updated"}}}`),
+			notification(5, types.DELETED, `{"old":{"resource":{"id":"broker-2"}}}`),
+		}
+		response, err := http.Get(server.URL + web.ServiceBrokersURL + "?watch=true&from_revision=3")
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(Equal(watch.ContentType))
+
+		lines := bufio.NewScanner(response.Body)
+		Expect(lines.Scan()).To(BeTrue())
+		Expect(lines.Text()).To(MatchJSON(`{"type":"MODIFIED","revision":4,"object":{"id":"broker-1","name":"updated"}}`))
+		Expect(lines.Scan()).To(BeTrue())
+		Expect(lines.Text()).To(MatchJSON(`{"type":"DELETED","revision":5,"object":{"id":"broker-2"}}`))
+	})
+
+	It("streams the resources without the fields the user may not see", func() {
+		rules, err := filters.ParseFieldRules([]string{"/v1/service_brokers:labels.internal.*=sm.internal"})
+		Expect(err).ToNot(HaveOccurred())
+		filter = watch.NewFilter(ctx, watch.DefaultSettings(), repository, map[string]types.ObjectType{
+			web.ServiceBrokersURL: types.ServiceBrokerType,
+		}, &filters.FieldAuthorizationFilter{Rules: rules})
+		notifications = []*types.Notification{
+			notification(3, types.CREATED, `{"new":{"resource":{"id":"broker-1"}}}`),
+			notification(4, types.MODIFIED, `{"new":{"resource":{"id":"broker-1","credentials":{"basic":{"username":"u","password":"p"}},"labels":{"env":["dev"],"internal.owner":["team"]}}}}`),
+		}
+		response, err := http.Get(server.URL + web.ServiceBrokersURL + "?watch=true&from_revision=3")
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		lines := bufio.NewScanner(response.Body)
+		Expect(lines.Scan()).To(BeTrue())
+		Expect(lines.Text()).To(MatchJSON(`{"type":"MODIFIED","revision":4,"object":{"id":"broker-1","labels":{"env":["dev"]}}}`))
+	})
+})
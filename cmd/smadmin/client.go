@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// resourceClient performs operations on Service Manager resources either through the storage or through the API
+type resourceClient interface {
+	// List returns all resources of the given kind
+	List(ctx context.Context, r resource) (types.ObjectList, error)
+
+	// Create creates the provided object and returns the created one
+	Create(ctx context.Context, r resource, object types.Object) (types.Object, error)
+
+	// Delete deletes the resource of the given kind with the provided id
+	Delete(ctx context.Context, r resource, id string) error
+
+	// RefetchCatalog triggers refetching of the catalog of the broker with the provided id
+	RefetchCatalog(ctx context.Context, brokerID string) error
+
+	// SetTenantKey wraps the encryption key of the tenant with the provided key of a key management service
+	SetTenantKey(ctx context.Context, tenant, provider, keyRef string) error
+}
+
+// storageClient is a resourceClient that works directly with the storage. Storage interceptors are not
+// executed which allows deleting resources which cannot be deleted through the API.
+type storageClient struct {
+	repository storage.Repository
+}
+
+func (sc *storageClient) List(ctx context.Context, r resource) (types.ObjectList, error) {
+	return sc.repository.List(ctx, r.objectType)
+}
+
+func (sc *storageClient) Create(ctx context.Context, r resource, object types.Object) (types.Object, error) {
+	return sc.repository.Create(ctx, object)
+}
+
+func (sc *storageClient) Delete(ctx context.Context, r resource, id string) error {
+	_, err := sc.repository.Delete(ctx, r.objectType, query.ByField(query.EqualsOperator, "id", id))
+	return err
+}
+
+func (sc *storageClient) RefetchCatalog(ctx context.Context, brokerID string) error {
+	return fmt.Errorf("refetching broker catalogs requires the Service Manager API, please provide --sm-url")
+}
+
+func (sc *storageClient) SetTenantKey(ctx context.Context, tenant, provider, keyRef string) error {
+	// key providers registered as extensions are only available in the Service Manager
+	return fmt.Errorf("wrapping tenant keys requires the Service Manager API, please provide --sm-url")
+}
+
+// apiClient is a resourceClient that calls the Service Manager API
+type apiClient struct {
+	url       string
+	doRequest util.DoRequestFunc
+}
+
+func newAPIClient(url, token string) *apiClient {
+	return &apiClient{
+		url: strings.TrimSuffix(url, "/"),
+		doRequest: func(request *http.Request) (*http.Response, error) {
+			if token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+			request.Header.Set("Content-Type", "application/json")
+			return http.DefaultClient.Do(request)
+		},
+	}
+}
+
+func (ac *apiClient) List(ctx context.Context, r resource) (types.ObjectList, error) {
+	list := r.newList()
+	if err := ac.call(ctx, http.MethodGet, r.url, nil, http.StatusOK, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (ac *apiClient) Create(ctx context.Context, r resource, object types.Object) (types.Object, error) {
+	created := r.newObject()
+	if err := ac.call(ctx, http.MethodPost, r.url, object, http.StatusCreated, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (ac *apiClient) Delete(ctx context.Context, r resource, id string) error {
+	return ac.call(ctx, http.MethodDelete, r.url+"/"+id, nil, http.StatusOK, nil)
+}
+
+func (ac *apiClient) RefetchCatalog(ctx context.Context, brokerID string) error {
+	return ac.call(ctx, http.MethodPost, web.ServiceBrokersURL+"/"+brokerID+"/catalog/refetch", map[string]string{}, http.StatusOK, nil)
+}
+
+func (ac *apiClient) SetTenantKey(ctx context.Context, tenant, provider, keyRef string) error {
+	return ac.call(ctx, http.MethodPut, web.TenantKeysURL+"/"+tenant, map[string]string{
+		"provider": provider,
+		"key_ref":  keyRef,
+	}, http.StatusOK, nil)
+}
+
+func (ac *apiClient) call(ctx context.Context, method, path string, body interface{}, expectedStatus int, result interface{}) error {
+	response, err := util.SendRequest(ctx, ac.doRequest, method, ac.url+path, nil, body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode == http.StatusConflict {
+		if _, err := util.BodyToBytes(response.Body); err != nil {
+			return err
+		}
+		return util.ErrAlreadyExistsInStorage
+	}
+	if response.StatusCode != expectedStatus {
+		return util.HandleResponseError(response)
+	}
+	bytes, err := util.BodyToBytes(response.Body)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(bytes, result)
+}
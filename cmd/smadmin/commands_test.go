@@ -0,0 +1,270 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/postgres"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeMigrator struct {
+	statuses   []*postgres.SchemaStatus
+	migrateErr error
+	migrated   bool
+	closed     bool
+}
+
+func (fm *fakeMigrator) Status(ctx context.Context) (*postgres.SchemaStatus, error) {
+	status := fm.statuses[0]
+	if len(fm.statuses) > 1 {
+		fm.statuses = fm.statuses[1:]
+	}
+	return status, nil
+}
+
+func (fm *fakeMigrator) Migrate(ctx context.Context) error {
+	fm.migrated = true
+	return fm.migrateErr
+}
+
+func (fm *fakeMigrator) Close() error {
+	fm.closed = true
+	return nil
+}
+
+type recordedRequest struct {
+	method        string
+	path          string
+	authorization string
+	body          string
+}
+
+var _ = Describe("smadmin", func() {
+	var (
+		opts   *options
+		output *bytes.Buffer
+	)
+
+	execute := func(cmd *cobra.Command, args ...string) error {
+		cmd.SetArgs(args)
+		cmd.SetOutput(output)
+		return cmd.Execute()
+	}
+
+	BeforeEach(func() {
+		opts = &options{
+			ctx: context.Background(),
+		}
+		output = &bytes.Buffer{}
+	})
+
+	Describe("migrate", func() {
+		var migrator *fakeMigrator
+
+		BeforeEach(func() {
+			migrator = &fakeMigrator{}
+			opts.newMigrator = func() (schemaMigrator, error) {
+				return migrator, nil
+			}
+		})
+
+		It("applies the pending migrations", func() {
+			migrator.statuses = []*postgres.SchemaStatus{{Version: 3, Latest: 5}, {Version: 5, Latest: 5}}
+
+			Expect(execute(newMigrateCommand(opts))).To(Succeed())
+			Expect(migrator.migrated).To(BeTrue())
+			Expect(migrator.closed).To(BeTrue())
+			Expect(output.String()).To(ContainSubstring("Migrated database schema from version 3 to 5"))
+		})
+
+		It("does not migrate an up to date schema", func() {
+			migrator.statuses = []*postgres.SchemaStatus{{Version: 5, Latest: 5}}
+
+			Expect(execute(newMigrateCommand(opts))).To(Succeed())
+			Expect(migrator.migrated).To(BeFalse())
+			Expect(output.String()).To(ContainSubstring("Database schema is up to date at version 5"))
+		})
+
+		It("only reports the pending migrations in a dry run", func() {
+			migrator.statuses = []*postgres.SchemaStatus{{Version: 3, Latest: 5}}
+
+			Expect(execute(newMigrateCommand(opts), "--dry-run")).To(Succeed())
+			Expect(migrator.migrated).To(BeFalse())
+			Expect(output.String()).To(ContainSubstring("would be migrated from version 3 to 5"))
+		})
+
+		It("fails for a dirty schema", func() {
+			migrator.statuses = []*postgres.SchemaStatus{{Version: 4, Latest: 5, Dirty: true}}
+
+			Expect(execute(newMigrateCommand(opts))).To(MatchError(ContainSubstring("version 4 is dirty")))
+			Expect(migrator.migrated).To(BeFalse())
+		})
+
+		It("fails if the migrations fail", func() {
+			migrator.statuses = []*postgres.SchemaStatus{{Version: 3, Latest: 5}}
+			migrator.migrateErr = errors.New("syntax error")
+
+			Expect(execute(newMigrateCommand(opts))).To(MatchError(ContainSubstring("syntax error")))
+		})
+	})
+
+	Context("with the Service Manager API", func() {
+		var (
+			server   *httptest.Server
+			mutex    sync.Mutex
+			requests []recordedRequest
+			handler  func(w http.ResponseWriter, r *http.Request)
+		)
+
+		recorded := func() []recordedRequest {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]recordedRequest{}, requests...)
+		}
+
+		BeforeEach(func() {
+			requests = nil
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{}`))
+			}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				mutex.Lock()
+				requests = append(requests, recordedRequest{
+					method:        r.Method,
+					path:          r.URL.Path,
+					authorization: r.Header.Get("Authorization"),
+					body:          string(body),
+				})
+				mutex.Unlock()
+				handler(w, r)
+			}))
+			opts.smURL = server.URL
+			opts.token = "token"
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Describe("refetch-catalog", func() {
+			It("refetches the catalogs of the brokers", func() {
+				Expect(execute(newRefetchCatalogCommand(opts), "broker-1", "broker-2")).To(Succeed())
+
+				Expect(recorded()).To(Equal([]recordedRequest{
+					{method: http.MethodPost, path: web.ServiceBrokersURL + "/broker-1/catalog/refetch", authorization: "Bearer token", body: "{}"},
+					{method: http.MethodPost, path: web.ServiceBrokersURL + "/broker-2/catalog/refetch", authorization: "Bearer token", body: "{}"},
+				}))
+				Expect(output.String()).To(ContainSubstring("Refetched catalog of broker broker-2"))
+			})
+
+			It("fails if a catalog cannot be refetched", func() {
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+					w.Write([]byte(`{"error":"ServiceBrokerErr","description":"could not reach service broker"}`))
+				}
+
+				Expect(execute(newRefetchCatalogCommand(opts), "broker-1")).To(MatchError(ContainSubstring("could not refetch 1 of 1 catalogs")))
+			})
+
+			It("requires the Service Manager API", func() {
+				opts.smURL = ""
+
+				Expect(execute(newRefetchCatalogCommand(opts), "broker-1")).To(MatchError(ContainSubstring("--sm-url")))
+			})
+
+			It("requires a token", func() {
+				opts.token = ""
+
+				Expect(execute(newRefetchCatalogCommand(opts), "broker-1")).To(MatchError(ContainSubstring("--token")))
+				Expect(recorded()).To(BeEmpty())
+			})
+		})
+
+		Describe("import", func() {
+			var file string
+
+			writeImport := func(content string) {
+				Expect(ioutil.WriteFile(file, []byte(content), 0600)).To(Succeed())
+			}
+
+			BeforeEach(func() {
+				dir, err := ioutil.TempDir("", "smadmin")
+				Expect(err).ToNot(HaveOccurred())
+				file = filepath.Join(dir, "export.json")
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+					if r.URL.Path == web.PlatformsURL {
+						w.Write([]byte(`{"id":"platform-1","name":"platform","credentials":{"basic":{"username":"new-user","password":"new-password"}}}`))
+						return
+					}
+					w.Write([]byte(`{"id":"broker-1","name":"broker"}`))
+				}
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(filepath.Dir(file))
+			})
+
+			It("creates the resources with the token and prints the new credentials of the platforms", func() {
+				writeImport(`{
+					"platforms": [{"id":"platform-1","name":"platform","type":"cf"}],
+					"service_brokers": [{"id":"broker-1","name":"broker","broker_url":"https://broker.example.com",
+						"credentials":{"basic":{"username":"admin","password":"secret"}}}],
+					"service_plans": [{"id":"plan-1","name":"plan"}]
+				}`)
+
+				Expect(execute(newImportCommand(opts), "--file", file)).To(Succeed())
+
+				requests := recorded()
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[0].path).To(Equal(web.PlatformsURL))
+				Expect(requests[0].authorization).To(Equal("Bearer token"))
+				Expect(requests[1].path).To(Equal(web.ServiceBrokersURL))
+				Expect(requests[1].body).To(ContainSubstring(`"password":"secret"`))
+				Expect(output.String()).To(ContainSubstring("Platform with id platform-1 has new credentials, username: new-user password: new-password"))
+				Expect(output.String()).To(ContainSubstring("Skipping 1 service_plans"))
+			})
+
+			It("does not import anything if a broker has no credentials", func() {
+				writeImport(`{
+					"platforms": [{"id":"platform-1","name":"platform","type":"cf"}],
+					"service_brokers": [{"id":"broker-1","name":"broker","broker_url":"https://broker.example.com"}]
+				}`)
+
+				Expect(execute(newImportCommand(opts), "--file", file)).To(MatchError(ContainSubstring("service broker with id broker-1 has no credentials")))
+				Expect(recorded()).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newDeleteCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <resource> <id>",
+		Short: "Deletes a resource",
+		Long: `Deletes the resource with the given id.
+When operating on the storage, storage interceptors are not executed which allows force-deleting stuck resources.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := resourceByName(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, closeFunc, err := opts.client()
+			if err != nil {
+				return err
+			}
+			defer closeFunc()
+
+			if err := client.Delete(opts.ctx, r, args[1]); err != nil {
+				return fmt.Errorf("could not delete %s with id %s: %s", r.name, args[1], err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s with id %s\n", r.name, args[1])
+			return nil
+		},
+	}
+}
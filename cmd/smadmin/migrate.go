@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// schemaMigrator reads and migrates the database schema
+type schemaMigrator interface {
+	// Status returns the version of the database schema and the latest version of the migrations
+	Status(ctx context.Context) (*postgres.SchemaStatus, error)
+
+	// Migrate applies the pending migrations
+	Migrate(ctx context.Context) error
+
+	// Close releases the connection to the database
+	Close() error
+}
+
+// postgresMigrator is a schemaMigrator which migrates the schema of the PostgreSQL storage
+type postgresMigrator struct {
+	db            *sql.DB
+	migrationsURL string
+}
+
+func (pm *postgresMigrator) Status(ctx context.Context) (*postgres.SchemaStatus, error) {
+	return postgres.ReadSchemaStatus(ctx, pm.db, pm.migrationsURL)
+}
+
+func (pm *postgresMigrator) Migrate(ctx context.Context) error {
+	return postgres.MigrateSchema(pm.db, pm.migrationsURL)
+}
+
+func (pm *postgresMigrator) Close() error {
+	return pm.db.Close()
+}
+
+func newMigrateCommand(opts *options) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Applies all pending database migrations",
+		Long: `Applies all pending database migrations and reports the version of the database schema before and after.
+A dirty schema, left behind by a failed migration, has to be repaired manually before migrating again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrator, err := opts.migrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			status, err := migrator.Status(opts.ctx)
+			if err != nil {
+				return fmt.Errorf("could not read database schema version: %s", err)
+			}
+			if status.Dirty {
+				return fmt.Errorf("database schema version %d is dirty, a previous migration failed and has to be repaired manually", status.Version)
+			}
+			if !status.Pending() {
+				fmt.Fprintf(cmd.OutOrStdout(), "Database schema is up to date at version %d\n", status.Version)
+				return nil
+			}
+			if dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "Database schema would be migrated from version %d to %d\n", status.Version, status.Latest)
+				return nil
+			}
+
+			if err := migrator.Migrate(opts.ctx); err != nil {
+				return fmt.Errorf("could not migrate database schema from version %d: %s", status.Version, err)
+			}
+			migrated, err := migrator.Status(opts.ctx)
+			if err != nil {
+				return fmt.Errorf("could not read database schema version: %s", err)
+			}
+			if migrated.Pending() || migrated.Dirty {
+				return fmt.Errorf("database schema was migrated to version %d instead of %d", migrated.Version, migrated.Latest)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated database schema from version %d to %d\n", status.Version, migrated.Version)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report the pending migrations without applying them")
+
+	return cmd
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newRefetchCatalogCommand(opts *options) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "refetch-catalog [broker-id...]",
+		Short: "Refetches the catalogs of service brokers",
+		Long:  "Refetches the catalogs of the service brokers with the given ids or of all brokers if --all is provided. Requires --sm-url.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return fmt.Errorf("either broker ids or --all must be provided")
+			}
+			if opts.smURL == "" {
+				return fmt.Errorf("refetching broker catalogs requires the Service Manager API, please provide --sm-url")
+			}
+
+			client, closeFunc, err := opts.client()
+			if err != nil {
+				return err
+			}
+			defer closeFunc()
+
+			brokerIDs := args
+			if all {
+				r, err := resourceByName("service_brokers")
+				if err != nil {
+					return err
+				}
+				brokers, err := client.List(opts.ctx, r)
+				if err != nil {
+					return err
+				}
+				for i := 0; i < brokers.Len(); i++ {
+					brokerIDs = append(brokerIDs, brokers.ItemAt(i).GetID())
+				}
+			}
+
+			failed := 0
+			for _, brokerID := range brokerIDs {
+				if err := client.RefetchCatalog(opts.ctx, brokerID); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Could not refetch catalog of broker %s: %s\n", brokerID, err)
+					failed++
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Refetched catalog of broker %s\n", brokerID)
+			}
+			if failed > 0 {
+				return fmt.Errorf("could not refetch %d of %d catalogs", failed, len(brokerIDs))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "refetch the catalogs of all service brokers")
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// resource describes a Service Manager resource that can be managed by smadmin
+type resource struct {
+	name       string
+	objectType types.ObjectType
+	url        string
+	newObject  func() types.Object
+	newList    func() types.ObjectList
+}
+
+// resources contains all manageable resources in the order in which they depend on each other
+var resources = []resource{
+	{
+		name:       "platforms",
+		objectType: types.PlatformType,
+		url:        web.PlatformsURL,
+		newObject:  func() types.Object { return &types.Platform{} },
+		newList:    func() types.ObjectList { return &types.Platforms{} },
+	},
+	{
+		name:       "service_brokers",
+		objectType: types.ServiceBrokerType,
+		url:        web.ServiceBrokersURL,
+		newObject:  func() types.Object { return &types.ServiceBroker{} },
+		newList:    func() types.ObjectList { return &types.ServiceBrokers{} },
+	},
+	{
+		name:       "service_offerings",
+		objectType: types.ServiceOfferingType,
+		url:        web.ServiceOfferingsURL,
+		newObject:  func() types.Object { return &types.ServiceOffering{} },
+		newList:    func() types.ObjectList { return &types.ServiceOfferings{} },
+	},
+	{
+		name:       "service_plans",
+		objectType: types.ServicePlanType,
+		url:        web.ServicePlansURL,
+		newObject:  func() types.Object { return &types.ServicePlan{} },
+		newList:    func() types.ObjectList { return &types.ServicePlans{} },
+	},
+	{
+		name:       "visibilities",
+		objectType: types.VisibilityType,
+		url:        web.VisibilitiesURL,
+		newObject:  func() types.Object { return &types.Visibility{} },
+		newList:    func() types.ObjectList { return &types.Visibilities{} },
+	},
+}
+
+func resourceByName(name string) (resource, error) {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		if r.name == name {
+			return r, nil
+		}
+		names = append(names, r.name)
+	}
+	return resource{}, fmt.Errorf("unknown resource %s, must be one of: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newRewrapTenantKeyCommand(opts *options) *cobra.Command {
+	var provider, keyRef string
+
+	cmd := &cobra.Command{
+		Use:   "rewrap-tenant-key <tenant>",
+		Short: "Wraps the encryption key of a tenant with another key of a key management service",
+		Long: `Wraps the encryption key of the tenant with the provided key of a key management service, e.g. after the tenant
+rotated or moved its key. The encryption key itself is not changed, so no credentials are re-encrypted. If the tenant
+has no encryption key yet, one is generated. Requires --sm-url.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if provider == "" || keyRef == "" {
+				return fmt.Errorf("--provider and --key-ref must be provided")
+			}
+
+			client, closeFunc, err := opts.client()
+			if err != nil {
+				return err
+			}
+			defer closeFunc()
+
+			tenant := args[0]
+			if err := client.SetTenantKey(opts.ctx, tenant, provider, keyRef); err != nil {
+				return fmt.Errorf("could not rewrap encryption key of tenant %s: %s", tenant, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Encryption key of tenant %s is wrapped with key %s of provider %s\n", tenant, keyRef, provider)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&provider, "provider", "", "the key provider, e.g. vault-transit")
+	cmd.Flags().StringVar(&keyRef, "key-ref", "", "the reference of the key in the key management service")
+
+	return cmd
+}
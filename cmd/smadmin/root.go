@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// options holds the state shared between all commands
+type options struct {
+	ctx context.Context
+	cfg *config.Settings
+
+	smURL string
+	token string
+
+	// newMigrator returns the migrator of the database schema, the storage schema is migrated if it is nil
+	newMigrator func() (schemaMigrator, error)
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{
+		ctx: context.Background(),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "smadmin",
+		Short: "Service Manager administration tool",
+		Long: `smadmin performs operational tasks against a Service Manager deployment.
+It uses the same configuration as the Service Manager (configuration file, environment variables and flags).
+Commands operate directly on the storage unless --sm-url is provided, in which case the Service Manager API is used.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			environment, err := env.NewForParsedFlags(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("error loading environment: %s", err)
+			}
+			if opts.cfg, err = config.NewForEnv(environment); err != nil {
+				return err
+			}
+			opts.ctx = log.Configure(opts.ctx, opts.cfg.Log)
+			return nil
+		},
+	}
+
+	config.AddPFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&opts.smURL, "sm-url", "", "base URL of the Service Manager API; if empty the storage is used directly")
+	cmd.PersistentFlags().StringVar(&opts.token, "token", "", "bearer token used when calling the Service Manager API")
+
+	cmd.AddCommand(
+		newMigrateCommand(opts),
+		newRotateKeyCommand(opts),
+		newRewrapTenantKeyCommand(opts),
+		newDeleteCommand(opts),
+		newRefetchCatalogCommand(opts),
+		newExportCommand(opts),
+		newImportCommand(opts),
+	)
+
+	return cmd
+}
+
+// openStorage opens the Service Manager storage which also applies any pending migrations
+func (o *options) openStorage() (*postgres.Storage, error) {
+	smStorage := &postgres.Storage{
+		ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+			return sql.Open(driver, url)
+		},
+	}
+	if err := smStorage.Open(o.cfg.Storage); err != nil {
+		return nil, fmt.Errorf("error opening storage: %s", err)
+	}
+	return smStorage, nil
+}
+
+// migrator returns the migrator of the database schema of the storage
+func (o *options) migrator() (schemaMigrator, error) {
+	if o.newMigrator != nil {
+		return o.newMigrator()
+	}
+	db, err := sql.Open("postgres", postgres.ConnectionURL(o.cfg.Storage))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to PostgreSQL: %s", err)
+	}
+	return &postgresMigrator{
+		db:            db,
+		migrationsURL: o.cfg.Storage.MigrationsURL,
+	}, nil
+}
+
+// client returns a resource client for the Service Manager API if --sm-url is provided and for the storage otherwise.
+// The returned function must be called to release the resources held by the client.
+func (o *options) client() (resourceClient, func(), error) {
+	if o.smURL != "" {
+		if o.token == "" {
+			return nil, nil, fmt.Errorf("--token is required to call the Service Manager API")
+		}
+		return newAPIClient(o.smURL, o.token), func() {}, nil
+	}
+
+	smStorage, err := o.openStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFunc := func() {
+		if err := smStorage.Close(); err != nil {
+			log.C(o.ctx).WithError(err).Error("could not close storage")
+		}
+	}
+
+	encryptingDecorator := storage.EncryptingDecorator(o.ctx, &security.AESEncrypter{}, smStorage, storage.NewTenantKeys(o.cfg.Storage.TenantEncryption, http.DefaultClient))
+	repository, err := encryptingDecorator(smStorage)
+	if err != nil {
+		closeFunc()
+		return nil, nil, err
+	}
+
+	return &storageClient{repository: repository}, closeFunc, nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+)
+
+func newRotateKeyCommand(opts *options) *cobra.Command {
+	var newKey string
+
+	cmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypts the stored encryption key with a new storage encryption key",
+		Long: `Re-encrypts the stored encryption key with the provided new storage encryption key.
+After a successful rotation all Service Manager instances must be configured with the new key (storage.encryption_key).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(newKey) != 32 {
+				return fmt.Errorf("new encryption key must be exactly 32 symbols long but was %d symbols long", len(newKey))
+			}
+
+			smStorage, err := opts.openStorage()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := smStorage.Close(); err != nil {
+					log.C(opts.ctx).WithError(err).Error("could not close storage")
+				}
+			}()
+
+			if err := smStorage.Lock(opts.ctx); err != nil {
+				return err
+			}
+			defer func() {
+				if err := smStorage.Unlock(opts.ctx); err != nil {
+					log.C(opts.ctx).WithError(err).Error("could not unlock keystore")
+				}
+			}()
+
+			encrypter := &security.AESEncrypter{}
+			if err := smStorage.RotateEncryptionKey(opts.ctx, []byte(newKey), encrypter.Decrypt, encrypter.Encrypt); err != nil {
+				return fmt.Errorf("could not rotate encryption key: %s", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Encryption key rotated successfully")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&newKey, "new-encryption-key", "", "the new storage encryption key")
+
+	return cmd
+}
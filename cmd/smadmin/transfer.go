@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// export contains the exported resources grouped by resource name
+type export map[string][]json.RawMessage
+
+func newExportCommand(opts *options) *cobra.Command {
+	var file string
+	var resourceNames []string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports resources as JSON",
+		Long: `Exports resources as JSON to a file or to the standard output.
+When operating on the storage the export contains decrypted credentials and must be handled with care.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selected, err := selectResources(resourceNames)
+			if err != nil {
+				return err
+			}
+
+			client, closeFunc, err := opts.client()
+			if err != nil {
+				return err
+			}
+			defer closeFunc()
+
+			result := export{}
+			for _, r := range selected {
+				list, err := client.List(opts.ctx, r)
+				if err != nil {
+					return fmt.Errorf("could not list %s: %s", r.name, err)
+				}
+				items := make([]json.RawMessage, 0, list.Len())
+				for i := 0; i < list.Len(); i++ {
+					bytes, err := json.Marshal(list.ItemAt(i))
+					if err != nil {
+						return err
+					}
+					items = append(items, bytes)
+				}
+				result[r.name] = items
+			}
+
+			bytes, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				_, err = cmd.OutOrStdout().Write(bytes)
+				return err
+			}
+			return ioutil.WriteFile(file, bytes, 0600)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "file to write the export to; if empty the standard output is used")
+	cmd.Flags().StringSliceVar(&resourceNames, "resources", nil, "resources to export; if empty all resources are exported")
+
+	return cmd
+}
+
+func newImportCommand(opts *options) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Imports resources from a JSON export",
+		Long: `Imports resources from a JSON export created with the export command.
+Resources that already exist are skipped. When importing through the API service offerings and plans
+are skipped as they are created from the broker catalogs, brokers must have credentials, which only
+exports from the storage contain, and platforms get new credentials, which are printed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var reader io.Reader = os.Stdin
+			if file != "" {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				reader = f
+			}
+			bytes, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			imported := export{}
+			if err := json.Unmarshal(bytes, &imported); err != nil {
+				return fmt.Errorf("could not parse import: %s", err)
+			}
+			for name := range imported {
+				if _, err := resourceByName(name); err != nil {
+					return err
+				}
+			}
+
+			client, closeFunc, err := opts.client()
+			if err != nil {
+				return err
+			}
+			defer closeFunc()
+			_, viaAPI := client.(*apiClient)
+
+			objects := make(map[string][]types.Object)
+			for _, r := range resources {
+				for _, item := range imported[r.name] {
+					object := r.newObject()
+					if err := json.Unmarshal(item, object); err != nil {
+						return fmt.Errorf("could not parse %s: %s", r.name, err)
+					}
+					if viaAPI && r.objectType == types.ServiceBrokerType && !hasCredentials(object) {
+						return fmt.Errorf("service broker with id %s has no credentials, brokers can only be imported through the API from an export of the storage", object.GetID())
+					}
+					objects[r.name] = append(objects[r.name], object)
+				}
+			}
+
+			for _, r := range resources {
+				items, found := objects[r.name]
+				if !found {
+					continue
+				}
+				if viaAPI && (r.name == "service_offerings" || r.name == "service_plans") {
+					fmt.Fprintf(cmd.OutOrStdout(), "Skipping %d %s\n", len(items), r.name)
+					continue
+				}
+				created, skipped := 0, 0
+				for _, object := range items {
+					result, err := client.Create(opts.ctx, r, object)
+					if err == util.ErrAlreadyExistsInStorage {
+						skipped++
+						continue
+					}
+					if err != nil {
+						return fmt.Errorf("could not import %s with id %s: %s", r.name, object.GetID(), err)
+					}
+					if viaAPI && r.objectType == types.PlatformType && hasCredentials(result) {
+						basic := result.(types.Secured).GetCredentials().Basic
+						fmt.Fprintf(cmd.OutOrStdout(), "Platform with id %s has new credentials, username: %s password: %s\n", result.GetID(), basic.Username, basic.Password)
+					}
+					created++
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported %d %s, skipped %d already existing\n", created, r.name, skipped)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "file to read the import from; if empty the standard input is used")
+
+	return cmd
+}
+
+// hasCredentials returns whether the object has basic credentials
+func hasCredentials(object types.Object) bool {
+	secured, isSecured := object.(types.Secured)
+	if !isSecured || secured.GetCredentials() == nil || secured.GetCredentials().Basic == nil {
+		return false
+	}
+	basic := secured.GetCredentials().Basic
+	return basic.Username != "" && basic.Password != ""
+}
+
+func selectResources(names []string) ([]resource, error) {
+	if len(names) == 0 {
+		return resources, nil
+	}
+	selected := make([]resource, 0, len(names))
+	for _, name := range names {
+		r, err := resourceByName(name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, r)
+	}
+	return selected, nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// smconformance runs the Service Manager conformance tests against a Service Manager deployment
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Peripli/service-manager/test/conformance"
+)
+
+// result records whether a conformance test failed
+type result struct {
+	failed bool
+}
+
+// Fail implements ginkgo.GinkgoTestingT
+func (r *result) Fail() {
+	r.failed = true
+}
+
+func main() {
+	settings := &conformance.Settings{}
+	conformance.AddFlags(pflag.CommandLine, settings)
+	pflag.Parse()
+
+	if err := settings.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\nUsage of smconformance:\n", err)
+		pflag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	r := &result{}
+	if !conformance.Run(r, settings) || r.failed {
+		os.Exit(1)
+	}
+}
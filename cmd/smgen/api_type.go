@@ -31,11 +31,15 @@ type ApiType struct {
 	TypePlural          string
 	TypePluralLowercase string
 	Type                string
+	ObjectTypeName      string
 	TypesPackageImport  string
 	TypesPackage        string
 }
 
-func GenerateApiTypeFile(apiTypeDir, packageName, typeName string) error {
+func GenerateApiTypeFile(apiTypeDir, packageName, typeName, objectTypeName string) error {
+	if objectTypeName == "" {
+		objectTypeName = typeName + "Type"
+	}
 	typeNamePlural := fmt.Sprintf("%ss", typeName)
 	if strings.HasSuffix(typeName, "y") {
 		typeNamePlural = fmt.Sprintf("%sies", typeName[:len(typeName)-1])
@@ -59,6 +63,7 @@ func GenerateApiTypeFile(apiTypeDir, packageName, typeName string) error {
 		TypePlural:          typeNamePlural,
 		TypePluralLowercase: builder.String(),
 		Type:                typeName,
+		ObjectTypeName:      objectTypeName,
 		TypesPackage:        typesPackage,
 		TypesPackageImport:  typesPackageImport,
 	}
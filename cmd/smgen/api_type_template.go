@@ -28,7 +28,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/util"
 )
 
-const {{.Type}}Type {{.TypesPackage}}ObjectType = "{{.PackageName}}.{{.Type}}"
+const {{.ObjectTypeName}} {{.TypesPackage}}ObjectType = "{{.PackageName}}.{{.Type}}"
 
 type {{.TypePlural}} struct {
 	{{.TypePlural}} []*{{.Type}} ` + "`json:\"{{.TypePluralLowercase}}\"`" + `
@@ -47,7 +47,7 @@ func (e *{{.TypePlural}}) Len() int {
 }
 
 func (e *{{.Type}}) GetType() {{.TypesPackage}}ObjectType {
-	return {{.Type}}Type
+	return {{.ObjectTypeName}}
 }
 
 // MarshalJSON override json serialization for http response
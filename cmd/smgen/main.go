@@ -44,7 +44,12 @@ func main() {
 	fmt.Println(packageName)
 	switch generationTarget {
 	case "api":
-		if err := GenerateApiTypeFile(dir, packageName, typeName); err != nil {
+		// the name of the object type constant defaults to <type_name>Type
+		var objectTypeName string
+		if len(args) > 2 {
+			objectTypeName = args[2]
+		}
+		if err := GenerateApiTypeFile(dir, packageName, typeName, objectTypeName); err != nil {
 			panic(err)
 		}
 	case "storage":
@@ -19,9 +19,20 @@ package config
 import (
 	"fmt"
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/bootstrap"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/cfvisibility"
+	"github.com/Peripli/service-manager/pkg/credentials"
 	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/federation"
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/jobs"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/resync"
 	"github.com/Peripli/service-manager/pkg/server"
+	"github.com/Peripli/service-manager/pkg/visibilitypolicy"
+	"github.com/Peripli/service-manager/pkg/visibilityschedule"
 	"github.com/Peripli/service-manager/pkg/ws"
 	"github.com/Peripli/service-manager/storage"
 	"github.com/spf13/pflag"
@@ -34,6 +45,21 @@ type Settings struct {
 	Log       *log.Settings
 	API       *api.Settings
 	WebSocket *ws.Settings
+	Features  *features.Settings
+	Bootstrap *bootstrap.Settings
+	Jobs      *jobs.Settings
+	Resync    *resync.Settings
+	Cache     *cache.Settings
+
+	CFVisibility *cfvisibility.Settings
+	Federation   *federation.Settings
+
+	VisibilitySchedule *visibilityschedule.Settings
+	VisibilityPolicies *visibilitypolicy.Settings
+
+	PlatformCredentials *credentials.Settings
+
+	HTTPClient *httpclient.Settings
 }
 
 // AddPFlags adds the SM config flags to the provided flag set
@@ -50,6 +76,21 @@ func DefaultSettings() *Settings {
 		Log:       log.DefaultSettings(),
 		API:       api.DefaultSettings(),
 		WebSocket: ws.DefaultSettings(),
+		Features:  features.DefaultSettings(),
+		Bootstrap: bootstrap.DefaultSettings(),
+		Jobs:      jobs.DefaultSettings(),
+		Resync:    resync.DefaultSettings(),
+		Cache:     cache.DefaultSettings(),
+
+		CFVisibility: cfvisibility.DefaultSettings(),
+		Federation:   federation.DefaultSettings(),
+
+		VisibilitySchedule: visibilityschedule.DefaultSettings(),
+		VisibilityPolicies: visibilitypolicy.DefaultSettings(),
+
+		PlatformCredentials: credentials.DefaultSettings(),
+
+		HTTPClient: httpclient.DefaultSettings(),
 	}
 }
 
@@ -77,7 +118,7 @@ func NewForEnv(env env.Environment) (*Settings, error) {
 func (c *Settings) Validate() error {
 	validatable := []interface {
 		Validate() error
-	}{c.Server, c.Storage, c.Log, c.API, c.WebSocket}
+	}{c.Server, c.Storage, c.Log, c.API, c.WebSocket, c.Features, c.Bootstrap, c.Jobs, c.Resync, c.Cache, c.CFVisibility, c.Federation, c.VisibilitySchedule, c.VisibilityPolicies, c.PlatformCredentials, c.HTTPClient}
 
 	for _, item := range validatable {
 		if err := item.Validate(); err != nil {
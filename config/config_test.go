@@ -150,12 +150,43 @@ var _ = Describe("config", func() {
 			})
 		})
 
+		Context("when notification clean batch size is 0", func() {
+			It("returns an error", func() {
+				config.Storage.Notification.CleanBatchSize = 0
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when notification min reconnect interval is < 0", func() {
 			It("returns an error", func() {
 				config.Storage.Notification.MinReconnectInterval = -time.Second
 				assertErrorDuringValidate()
 			})
 		})
+
+		Context("when a feature is both enabled and disabled", func() {
+			It("returns an error", func() {
+				config.Features.Enabled = []string{"feature"}
+				config.Features.Disabled = []string{"feature"}
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when catalog resync is enabled and its interval is 0", func() {
+			It("returns an error", func() {
+				config.Resync.Enabled = true
+				config.Resync.Interval = 0
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when CF visibility mapping is enabled without org label key", func() {
+			It("returns an error", func() {
+				config.CFVisibility.Enabled = true
+				config.CFVisibility.OrgLabelKey = ""
+				assertErrorDuringValidate()
+			})
+		})
 	})
 
 	Describe("New", func() {
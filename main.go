@@ -18,8 +18,12 @@ package main
 
 import (
 	"context"
+	"os"
+
 	"github.com/Peripli/service-manager/config"
+	"github.com/spf13/pflag"
 
+	"github.com/Peripli/service-manager/pkg/preflight"
 	"github.com/Peripli/service-manager/pkg/sm"
 	"github.com/Peripli/service-manager/version"
 )
@@ -27,6 +31,8 @@ import (
 func main() {
 	version.Log()
 
+	runPreflight := pflag.Bool("preflight", false, "check the configuration and the dependencies, print a report and exit instead of starting the server")
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -35,6 +41,15 @@ func main() {
 		panic(err)
 	}
 
+	if *runPreflight {
+		report := preflight.Run(ctx, cfg.Server.RequestTimeout, preflight.Checks(cfg)...)
+		report.Write(os.Stdout)
+		if report.Failed() {
+			os.Exit(1)
+		}
+		return
+	}
+
 	serviceManager, err := sm.New(ctx, cancel, cfg)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package bootstrap contains logic for reconciling resources declared in a bootstrap file on Service Manager startup
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	File        string `mapstructure:"file" description:"path to a YAML or JSON file describing platforms, brokers and visibilities that should exist on startup"`
+	UpdateDrift bool   `mapstructure:"update_drift" description:"whether existing resources which differ from the bootstrap file should be updated"`
+}
+
+// DefaultSettings returns default values for bootstrap settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		File:        "",
+		UpdateDrift: false,
+	}
+}
+
+// Validate validates the bootstrap settings
+func (s *Settings) Validate() error {
+	if len(s.File) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(s.File); err != nil {
+		return fmt.Errorf("validate Settings: bootstrap file %s is not accessible: %s", s.File, err)
+	}
+	return nil
+}
+
+// Bootstrapper reconciles the resources described in the bootstrap file with the ones in the storage
+type Bootstrapper struct {
+	Repository storage.Repository
+	Settings   *Settings
+}
+
+// Bootstrap creates the resources from the bootstrap file which are missing and, if configured, updates the ones
+// that differ from their declaration. If no bootstrap file is configured, Bootstrap does nothing.
+func (b *Bootstrapper) Bootstrap(ctx context.Context) error {
+	if b.Settings == nil || len(b.Settings.File) == 0 {
+		return nil
+	}
+	log.C(ctx).Infof("Bootstrapping resources from %s...", b.Settings.File)
+
+	resources, err := Load(b.Settings.File)
+	if err != nil {
+		return err
+	}
+	for _, platform := range resources.Platforms {
+		if err := b.reconcilePlatform(ctx, platform); err != nil {
+			return fmt.Errorf("could not bootstrap platform %s: %s", platform.Name, err)
+		}
+	}
+	for _, broker := range resources.ServiceBrokers {
+		if err := b.reconcileBroker(ctx, broker); err != nil {
+			return fmt.Errorf("could not bootstrap service broker %s: %s", broker.Name, err)
+		}
+	}
+	for _, visibility := range resources.Visibilities {
+		if err := b.reconcileVisibility(ctx, visibility); err != nil {
+			return fmt.Errorf("could not bootstrap visibility for plan %s of broker %s: %s", visibility.PlanCatalogID, visibility.BrokerName, err)
+		}
+	}
+
+	log.C(ctx).Info("Successfully bootstrapped resources")
+	return nil
+}
+
+func (b *Bootstrapper) reconcilePlatform(ctx context.Context, platform *types.Platform) error {
+	existing, err := b.findOne(ctx, types.PlatformType, "name", platform.Name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		declaredCredentials := platform.Credentials
+		created, err := b.create(ctx, platform)
+		if err != nil {
+			return err
+		}
+		if declaredCredentials == nil {
+			return nil
+		}
+		// credentials of newly created platforms are generated, so the declared ones are set afterwards
+		createdPlatform := created.(*types.Platform)
+		createdPlatform.Credentials = declaredCredentials
+		_, err = b.Repository.Update(ctx, createdPlatform)
+		return err
+	}
+
+	current := existing.(*types.Platform)
+	drift := current.Type != platform.Type || current.Description != platform.Description ||
+		(platform.Credentials != nil && !sameCredentials(current.Credentials, platform.Credentials))
+	if !drift && !missingLabels(current.Labels, platform.Labels) {
+		return nil
+	}
+	if !b.Settings.UpdateDrift {
+		log.C(ctx).Warnf("Platform %s differs from its bootstrap declaration", platform.Name)
+		return nil
+	}
+	current.Type = platform.Type
+	current.Description = platform.Description
+	if platform.Credentials != nil {
+		current.Credentials = platform.Credentials
+	}
+	return b.update(ctx, current, platform.Labels)
+}
+
+func (b *Bootstrapper) reconcileBroker(ctx context.Context, broker *types.ServiceBroker) error {
+	existing, err := b.findOne(ctx, types.ServiceBrokerType, "name", broker.Name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		_, err := b.create(ctx, broker)
+		return err
+	}
+
+	current := existing.(*types.ServiceBroker)
+	drift := current.BrokerURL != broker.BrokerURL || current.Description != broker.Description ||
+		!sameCredentials(current.Credentials, broker.Credentials)
+	if !drift && !missingLabels(current.Labels, broker.Labels) {
+		return nil
+	}
+	if !b.Settings.UpdateDrift {
+		log.C(ctx).Warnf("Service broker %s differs from its bootstrap declaration", broker.Name)
+		return nil
+	}
+	current.BrokerURL = broker.BrokerURL
+	current.Description = broker.Description
+	current.Credentials = broker.Credentials
+	return b.update(ctx, current, broker.Labels)
+}
+
+func (b *Bootstrapper) reconcileVisibility(ctx context.Context, visibility *Visibility) error {
+	platformID := ""
+	if len(visibility.PlatformName) != 0 {
+		platform, err := b.findOne(ctx, types.PlatformType, "name", visibility.PlatformName)
+		if err != nil {
+			return err
+		}
+		if platform == nil {
+			return fmt.Errorf("platform %s not found", visibility.PlatformName)
+		}
+		platformID = platform.GetID()
+	}
+
+	planID, err := b.findPlanID(ctx, visibility.BrokerName, visibility.PlanCatalogID)
+	if err != nil {
+		return err
+	}
+
+	visibilities, err := b.Repository.List(ctx, types.VisibilityType, query.ByField(query.EqualsOperator, "service_plan_id", planID))
+	if err != nil {
+		return err
+	}
+	for i := 0; i < visibilities.Len(); i++ {
+		current := visibilities.ItemAt(i).(*types.Visibility)
+		if current.PlatformID != platformID {
+			continue
+		}
+		if !missingLabels(current.Labels, visibility.Labels) {
+			return nil
+		}
+		if !b.Settings.UpdateDrift {
+			log.C(ctx).Warnf("Visibility %s differs from its bootstrap declaration", current.ID)
+			return nil
+		}
+		return b.update(ctx, current, visibility.Labels)
+	}
+
+	_, err = b.create(ctx, &types.Visibility{
+		Base: types.Base{
+			Labels: visibility.Labels,
+		},
+		PlatformID:    platformID,
+		ServicePlanID: planID,
+	})
+	return err
+}
+
+func (b *Bootstrapper) findPlanID(ctx context.Context, brokerName, planCatalogID string) (string, error) {
+	broker, err := b.findOne(ctx, types.ServiceBrokerType, "name", brokerName)
+	if err != nil {
+		return "", err
+	}
+	if broker == nil {
+		return "", fmt.Errorf("service broker %s not found", brokerName)
+	}
+
+	offerings, err := b.Repository.List(ctx, types.ServiceOfferingType, query.ByField(query.EqualsOperator, "broker_id", broker.GetID()))
+	if err != nil {
+		return "", err
+	}
+	offeringIDs := make([]string, 0, offerings.Len())
+	for i := 0; i < offerings.Len(); i++ {
+		offeringIDs = append(offeringIDs, offerings.ItemAt(i).GetID())
+	}
+	if len(offeringIDs) == 0 {
+		return "", fmt.Errorf("service broker %s has no service offerings", brokerName)
+	}
+
+	plans, err := b.Repository.List(ctx, types.ServicePlanType,
+		query.ByField(query.InOperator, "service_offering_id", offeringIDs...),
+		query.ByField(query.EqualsOperator, "catalog_id", planCatalogID))
+	if err != nil {
+		return "", err
+	}
+	if plans.Len() == 0 {
+		return "", fmt.Errorf("service plan with catalog id %s not found", planCatalogID)
+	}
+	return plans.ItemAt(0).GetID(), nil
+}
+
+func (b *Bootstrapper) findOne(ctx context.Context, objectType types.ObjectType, field, value string) (types.Object, error) {
+	objects, err := b.Repository.List(ctx, objectType, query.ByField(query.EqualsOperator, field, value))
+	if err != nil {
+		return nil, err
+	}
+	if objects.Len() == 0 {
+		return nil, nil
+	}
+	return objects.ItemAt(0), nil
+}
+
+func (b *Bootstrapper) create(ctx context.Context, object types.Object) (types.Object, error) {
+	if err := object.Validate(); err != nil {
+		return nil, err
+	}
+	if object.GetID() == "" {
+		UUID, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("could not generate GUID: %s", err)
+		}
+		object.SetID(UUID.String())
+	}
+	currentTime := time.Now().UTC()
+	object.SetCreatedAt(currentTime)
+	object.SetUpdatedAt(currentTime)
+
+	log.C(ctx).Infof("Creating %s with id %s from bootstrap declaration", object.GetType(), object.GetID())
+	created, err := b.Repository.Create(ctx, object)
+	if err == util.ErrAlreadyExistsInStorage {
+		return nil, fmt.Errorf("%s conflicts with an existing resource", object.GetType())
+	}
+	return created, err
+}
+
+func (b *Bootstrapper) update(ctx context.Context, object types.Object, labels types.Labels) error {
+	labelChanges := make([]*query.LabelChange, 0, len(labels))
+	for key, values := range labels {
+		labelChanges = append(labelChanges, &query.LabelChange{
+			Operation: query.AddLabelValuesOperation,
+			Key:       key,
+			Values:    values,
+		})
+	}
+	mergedLabels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, object.GetLabels())
+	object.SetLabels(mergedLabels)
+
+	if err := object.Validate(); err != nil {
+		return err
+	}
+
+	log.C(ctx).Infof("Updating %s with id %s to match its bootstrap declaration", object.GetType(), object.GetID())
+	_, err := b.Repository.Update(ctx, object, labelChanges...)
+	return err
+}
+
+// sameCredentials returns true if the declared credentials match the persisted ones, whose password is a hash of
+// the declared one if platform passwords are hashed
+func sameCredentials(current, declared *types.Credentials) bool {
+	if current == nil || declared == nil {
+		return current == declared
+	}
+	if current.Basic == nil || declared.Basic == nil {
+		return current.Basic == declared.Basic
+	}
+	return current.Basic.Username == declared.Basic.Username &&
+		credentials.Verify(current.Basic.Password, declared.Basic.Password)
+}
+
+func missingLabels(current, declared types.Labels) bool {
+	for key, declaredValues := range declared {
+		for _, declaredValue := range declaredValues {
+			found := false
+			for _, value := range current[key] {
+				if value == declaredValue {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package bootstrap_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Peripli/service-manager/pkg/bootstrap"
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const bootstrapYAML = `
+platforms:
+  - name: cf-platform
+    type: cloudfoundry
+    description: bootstrapped platform
+service_brokers:
+  - name: broker
+    broker_url: http://broker.example.com
+    credentials:
+      basic:
+        username: admin
+        password: secret
+    labels:
+      tier: [gold]
+`
+
+var _ = Describe("Bootstrap", func() {
+	var (
+		dir          string
+		settings     *bootstrap.Settings
+		fakeStorage  *storagefakes.FakeStorage
+		bootstrapper *bootstrap.Bootstrapper
+	)
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "bootstrap")
+		Expect(err).ToNot(HaveOccurred())
+
+		settings = bootstrap.DefaultSettings()
+		settings.File = writeFile("bootstrap.yml", bootstrapYAML)
+
+		fakeStorage = &storagefakes.FakeStorage{}
+		fakeStorage.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.PlatformType {
+				return &types.Platforms{}, nil
+			}
+			return &types.ServiceBrokers{}, nil
+		}
+		fakeStorage.CreateStub = func(ctx context.Context, object types.Object) (types.Object, error) {
+			return object, nil
+		}
+
+		bootstrapper = &bootstrap.Bootstrapper{
+			Repository: fakeStorage,
+			Settings:   settings,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("Load", func() {
+		It("parses YAML files", func() {
+			resources, err := bootstrap.Load(settings.File)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources.Platforms).To(HaveLen(1))
+			Expect(resources.Platforms[0].Type).To(Equal("cloudfoundry"))
+			Expect(resources.ServiceBrokers).To(HaveLen(1))
+			Expect(resources.ServiceBrokers[0].BrokerURL).To(Equal("http://broker.example.com"))
+			Expect(resources.ServiceBrokers[0].Credentials.Basic.Password).To(Equal("secret"))
+			Expect(resources.ServiceBrokers[0].Labels).To(Equal(types.Labels{"tier": {"gold"}}))
+		})
+
+		It("parses JSON files", func() {
+			path := writeFile("bootstrap.json", `{"platforms":[{"name":"k8s-platform","type":"kubernetes"}]}`)
+			resources, err := bootstrap.Load(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources.Platforms).To(HaveLen(1))
+			Expect(resources.Platforms[0].Name).To(Equal("k8s-platform"))
+		})
+
+		It("fails for visibilities without plan reference", func() {
+			path := writeFile("bootstrap.json", `{"visibilities":[{"platform_name":"cf-platform"}]}`)
+			_, err := bootstrap.Load(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Bootstrap", func() {
+		Context("when no file is configured", func() {
+			It("does nothing", func() {
+				settings.File = ""
+				Expect(bootstrapper.Bootstrap(context.Background())).To(Succeed())
+				Expect(fakeStorage.ListCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when resources are missing", func() {
+			It("creates them", func() {
+				Expect(bootstrapper.Bootstrap(context.Background())).To(Succeed())
+				Expect(fakeStorage.CreateCallCount()).To(Equal(2))
+
+				_, platform := fakeStorage.CreateArgsForCall(0)
+				Expect(platform.GetID()).ToNot(BeEmpty())
+				Expect(platform.(*types.Platform).Name).To(Equal("cf-platform"))
+				_, broker := fakeStorage.CreateArgsForCall(1)
+				Expect(broker.(*types.ServiceBroker).Name).To(Equal("broker"))
+			})
+		})
+
+		Context("when resources exist", func() {
+			var existingBroker *types.ServiceBroker
+
+			BeforeEach(func() {
+				existingBroker = &types.ServiceBroker{
+					Base:      types.Base{ID: "broker-id", Labels: types.Labels{"tier": {"gold"}}},
+					Name:      "broker",
+					BrokerURL: "http://old.example.com",
+					Credentials: &types.Credentials{
+						Basic: &types.Basic{Username: "admin", Password: "secret"},
+					},
+				}
+				fakeStorage.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+					if objectType == types.PlatformType {
+						return &types.Platforms{Platforms: []*types.Platform{
+							{Base: types.Base{ID: "platform-id"}, Name: "cf-platform", Type: "cloudfoundry", Description: "bootstrapped platform"},
+						}}, nil
+					}
+					return &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{existingBroker}}, nil
+				}
+			})
+
+			It("does not update drifted resources by default", func() {
+				Expect(bootstrapper.Bootstrap(context.Background())).To(Succeed())
+				Expect(fakeStorage.CreateCallCount()).To(Equal(0))
+				Expect(fakeStorage.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("updates drifted resources if configured", func() {
+				settings.UpdateDrift = true
+				Expect(bootstrapper.Bootstrap(context.Background())).To(Succeed())
+				Expect(fakeStorage.CreateCallCount()).To(Equal(0))
+				Expect(fakeStorage.UpdateCallCount()).To(Equal(1))
+
+				_, updated, _ := fakeStorage.UpdateArgsForCall(0)
+				Expect(updated.GetID()).To(Equal("broker-id"))
+				Expect(updated.(*types.ServiceBroker).BrokerURL).To(Equal("http://broker.example.com"))
+			})
+
+			It("does not update platforms whose persisted password is a hash of the declared one", func() {
+				hash, err := credentials.Hash("platform-secret", 4)
+				Expect(err).ToNot(HaveOccurred())
+				settings.File = writeFile("bootstrap.yml", `
+platforms:
+  - name: cf-platform
+    type: cloudfoundry
+    description: bootstrapped platform
+    credentials:
+      basic:
+        username: platform-admin
+        password: platform-secret
+`)
+				fakeStorage.ListReturns(&types.Platforms{Platforms: []*types.Platform{
+					{
+						Base:        types.Base{ID: "platform-id"},
+						Name:        "cf-platform",
+						Type:        "cloudfoundry",
+						Description: "bootstrapped platform",
+						Credentials: &types.Credentials{
+							Basic: &types.Basic{Username: "platform-admin", Password: hash},
+						},
+					},
+				}}, nil)
+				settings.UpdateDrift = true
+				Expect(bootstrapper.Bootstrap(context.Background())).To(Succeed())
+				Expect(fakeStorage.UpdateCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// Resources describes the resources that should exist in the Service Manager
+type Resources struct {
+	Platforms      []*types.Platform      `json:"platforms"`
+	ServiceBrokers []*types.ServiceBroker `json:"service_brokers"`
+	Visibilities   []*Visibility          `json:"visibilities"`
+}
+
+// Visibility describes a visibility using names and catalog ids since the ids of
+// platforms and service plans are not known upfront
+type Visibility struct {
+	// PlatformName is the name of the platform for which the plan is visible. If empty, the plan is visible for all platforms.
+	PlatformName string `json:"platform_name"`
+
+	// BrokerName is the name of the broker that provides the plan
+	BrokerName string `json:"broker_name"`
+
+	// PlanCatalogID is the id of the plan in the catalog of the broker
+	PlanCatalogID string `json:"plan_catalog_id"`
+
+	Labels types.Labels `json:"labels,omitempty"`
+}
+
+// Load reads the resources from the file with the given path. Files with .yml or .yaml extension
+// are parsed as YAML and all others are parsed as JSON.
+func Load(path string) (*Resources, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read bootstrap file: %s", err)
+	}
+
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension == ".yml" || extension == ".yaml" {
+		if bytes, err = yamlToJSON(bytes); err != nil {
+			return nil, fmt.Errorf("could not parse bootstrap file: %s", err)
+		}
+	}
+
+	resources := &Resources{}
+	if err := json.Unmarshal(bytes, resources); err != nil {
+		return nil, fmt.Errorf("could not parse bootstrap file: %s", err)
+	}
+	for _, visibility := range resources.Visibilities {
+		if len(visibility.BrokerName) == 0 || len(visibility.PlanCatalogID) == 0 {
+			return nil, fmt.Errorf("bootstrap visibilities require broker_name and plan_catalog_id")
+		}
+	}
+	return resources, nil
+}
+
+// yamlToJSON converts YAML to JSON so that the json tags of the Service Manager types are respected
+func yamlToJSON(bytes []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(bytes, &value); err != nil {
+		return nil, err
+	}
+	converted, err := convertYAMLValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(converted)
+}
+
+func convertYAMLValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			stringKey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v", key)
+			}
+			converted, err := convertYAMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[stringKey] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := convertYAMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
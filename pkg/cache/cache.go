@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package cache contains the key-value stores which hold the state shared by the components of the Service Manager,
+// either in the memory of each instance or in Redis so that all instances of a deployment share it
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// MemoryType is the type of the store which keeps the state in the memory of each instance
+	MemoryType = "memory"
+
+	// RedisType is the type of the store which keeps the state in Redis
+	RedisType = "redis"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Type  string         `mapstructure:"type" description:"where the state shared by the components is stored, memory keeps it per instance and redis shares it between all instances"`
+	Redis *RedisSettings `mapstructure:"redis"`
+}
+
+// DefaultSettings returns default values for the cache settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		Type:  MemoryType,
+		Redis: DefaultRedisSettings(),
+	}
+}
+
+// Validate validates the cache settings
+func (s *Settings) Validate() error {
+	switch s.Type {
+	case MemoryType:
+		return nil
+	case RedisType:
+		return s.Redis.Validate()
+	default:
+		return fmt.Errorf("validate Settings: unknown cache type %q, supported types are %s and %s", s.Type, MemoryType, RedisType)
+	}
+}
+
+// Store is a key-value store with expiring keys
+type Store interface {
+	// Get returns the value of the key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores the value under the key, the key does not expire if the ttl is zero
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// SetIfAbsent stores the value under the key only if the key does not exist and returns whether it was stored
+	SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+
+	// Increment increments the counter stored under the key and returns its new value. A new counter starts at one
+	// and expires after the ttl, unless the ttl is zero.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Delete removes the keys
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// New returns the store configured in the settings
+func New(settings *Settings) (Store, error) {
+	if settings == nil {
+		settings = DefaultSettings()
+	}
+	switch settings.Type {
+	case MemoryType:
+		return NewMemoryStore(), nil
+	case RedisType:
+		return NewRedisStore(settings.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", settings.Type)
+	}
+}
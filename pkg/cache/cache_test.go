@@ -0,0 +1,295 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRedis is a Redis server supporting the commands used by the RedisStore
+type fakeRedis struct {
+	listener net.Listener
+
+	mutex   sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis() *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	server := &fakeRedis{
+		listener: listener,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.execute(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) execute(args []string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+		if expiresAt, found := f.expires[key]; found && time.Now().After(expiresAt) {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, found := f.values[key]
+		if !found {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		_, found := f.values[key]
+		var ttl time.Duration
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if found {
+					return "$-1\r\n"
+				}
+			case "PX":
+				milliseconds, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(milliseconds) * time.Millisecond
+				i++
+			}
+		}
+		f.values[key] = args[2]
+		delete(f.expires, key)
+		if ttl > 0 {
+			f.expires[key] = time.Now().Add(ttl)
+		}
+		return "+OK\r\n"
+	case "INCR":
+		counter, _ := strconv.ParseInt(f.values[key], 10, 64)
+		counter++
+		f.values[key] = strconv.FormatInt(counter, 10)
+		return fmt.Sprintf(":%d\r\n", counter)
+	case "PEXPIRE":
+		milliseconds, _ := strconv.Atoi(args[2])
+		f.expires[key] = time.Now().Add(time.Duration(milliseconds) * time.Millisecond)
+		return ":1\r\n"
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		args[i] = string(value[:length])
+	}
+	return args, nil
+}
+
+var _ = Describe("Cache", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(cache.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("require an address for redis", func() {
+			settings := cache.DefaultSettings()
+			settings.Type = cache.RedisType
+			Expect(settings.Validate()).To(HaveOccurred())
+
+			settings.Redis.Address = "localhost:6379"
+			Expect(settings.Validate()).To(Succeed())
+		})
+
+		It("reject unknown types", func() {
+			settings := cache.DefaultSettings()
+			settings.Type = "memcached"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	storeBehavior := func(newStore func() cache.Store) {
+		var store cache.Store
+
+		BeforeEach(func() {
+			store = newStore()
+		})
+
+		It("returns stored values", func() {
+			Expect(store.Set(ctx, "key", []byte("value"), time.Minute)).To(Succeed())
+			value, found, err := store.Get(ctx, "key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(string(value)).To(Equal("value"))
+		})
+
+		It("does not return missing or deleted values", func() {
+			_, found, err := store.Get(ctx, "missing")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+
+			Expect(store.Set(ctx, "key", []byte("value"), 0)).To(Succeed())
+			Expect(store.Delete(ctx, "key")).To(Succeed())
+			_, found, err = store.Get(ctx, "key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("expires values after their ttl", func() {
+			Expect(store.Set(ctx, "key", []byte("value"), 50*time.Millisecond)).To(Succeed())
+			Eventually(func() bool {
+				_, found, _ := store.Get(ctx, "key")
+				return found
+			}).Should(BeFalse())
+		})
+
+		It("stores values only if absent when requested", func() {
+			stored, err := store.SetIfAbsent(ctx, "key", []byte("first"), time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(BeTrue())
+
+			stored, err = store.SetIfAbsent(ctx, "key", []byte("second"), time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(BeFalse())
+
+			value, _, _ := store.Get(ctx, "key")
+			Expect(string(value)).To(Equal("first"))
+		})
+
+		It("increments counters which expire after their ttl", func() {
+			for expected := int64(1); expected <= 3; expected++ {
+				counter, err := store.Increment(ctx, "counter", 50*time.Millisecond)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(counter).To(Equal(expected))
+			}
+			Eventually(func() bool {
+				_, found, _ := store.Get(ctx, "counter")
+				return found
+			}).Should(BeFalse())
+		})
+	}
+
+	Describe("MemoryStore", func() {
+		storeBehavior(func() cache.Store {
+			return cache.NewMemoryStore()
+		})
+	})
+
+	Describe("RedisStore", func() {
+		var server *fakeRedis
+
+		BeforeEach(func() {
+			server = newFakeRedis()
+		})
+
+		AfterEach(func() {
+			server.listener.Close()
+		})
+
+		storeBehavior(func() cache.Store {
+			settings := cache.DefaultRedisSettings()
+			settings.Address = server.listener.Addr().String()
+			return cache.NewRedisStore(settings)
+		})
+
+		It("prefixes the keys", func() {
+			settings := cache.DefaultRedisSettings()
+			settings.Address = server.listener.Addr().String()
+			store := cache.NewRedisStore(settings)
+			Expect(store.Set(ctx, "key", []byte("value"), 0)).To(Succeed())
+			Expect(server.values).To(HaveKeyWithValue("service-manager:key", "value"))
+		})
+
+		It("returns the errors of the server", func() {
+			settings := cache.DefaultRedisSettings()
+			settings.Address = server.listener.Addr().String()
+			settings.Password = "secret"
+			store := cache.NewRedisStore(settings)
+			Expect(store.Ping(ctx)).To(MatchError(ContainSubstring("unknown command 'AUTH'")))
+		})
+	})
+})
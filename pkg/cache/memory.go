@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the minimum time between two removals of all expired keys of a MemoryStore
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore is a Store which keeps the keys in the memory of the instance. Expired keys are removed when they are
+// read and periodically when keys are written. As a PubSub it delivers messages within the instance.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+
+	subscriptionsMutex sync.Mutex
+	subscriptions      map[string]map[*memorySubscription]struct{}
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:       make(map[string]memoryEntry),
+		lastSweep:     time.Now(),
+		subscriptions: make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, found := s.get(key, time.Now())
+	if !found {
+		return nil, false, nil
+	}
+	return append([]byte{}, entry.value...), true, nil
+}
+
+// Set implements Store
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.set(key, value, ttl, time.Now())
+	return nil
+}
+
+// SetIfAbsent implements Store
+func (s *MemoryStore) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if _, found := s.get(key, now); found {
+		return false, nil
+	}
+	s.set(key, value, ttl, now)
+	return true, nil
+}
+
+// Increment implements Store
+func (s *MemoryStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	entry, found := s.get(key, now)
+	if !found {
+		s.set(key, []byte("1"), ttl, now)
+		return 1, nil
+	}
+	counter, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	counter++
+	entry.value = []byte(strconv.FormatInt(counter, 10))
+	s.entries[key] = entry
+	return counter, nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of keys which did not expire
+func (s *MemoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(time.Now())
+	return len(s.entries)
+}
+
+func (s *MemoryStore) get(key string, now time.Time) (memoryEntry, bool) {
+	entry, found := s.entries[key]
+	if !found {
+		return memoryEntry{}, false
+	}
+	if entry.expired(now) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+func (s *MemoryStore) set(key string, value []byte, ttl time.Duration, now time.Time) {
+	entry := memoryEntry{
+		value: append([]byte{}, value...),
+	}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	s.entries[key] = entry
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriptionBufferSize is the number of received messages buffered per subscription
+const subscriptionBufferSize = 1000
+
+// PubSub delivers the messages published to a channel to all of its current subscribers
+type PubSub interface {
+	// Publish sends the message to the current subscribers of the channel
+	Publish(ctx context.Context, channel string, message []byte) error
+
+	// Subscribe subscribes to the channel. It returns once the subscription is established, so that the messages
+	// published afterwards are received.
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+}
+
+// Subscription receives the messages published to a channel
+type Subscription interface {
+	// Messages returns the received messages. The channel is closed when the subscription ends, either because it was
+	// closed or because the connection was lost, in which case messages may have been missed.
+	Messages() <-chan []byte
+
+	// Close ends the subscription
+	Close() error
+}
+
+// memorySubscription is a subscription to a channel of a MemoryStore. A subscriber which does not keep up with the
+// messages is unsubscribed, as the messages would otherwise have to be dropped.
+type memorySubscription struct {
+	store    *MemoryStore
+	channel  string
+	messages chan []byte
+	once     sync.Once
+}
+
+func (s *memorySubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.store.unsubscribe(s)
+	return nil
+}
+
+func (s *memorySubscription) close() {
+	s.once.Do(func() {
+		close(s.messages)
+	})
+}
+
+// Publish implements PubSub, the messages are delivered to the subscribers in this instance
+func (s *MemoryStore) Publish(ctx context.Context, channel string, message []byte) error {
+	s.subscriptionsMutex.Lock()
+	defer s.subscriptionsMutex.Unlock()
+
+	for subscription := range s.subscriptions[channel] {
+		select {
+		case subscription.messages <- append([]byte{}, message...):
+		default:
+			delete(s.subscriptions[channel], subscription)
+			subscription.close()
+		}
+	}
+	return nil
+}
+
+// Subscribe implements PubSub
+func (s *MemoryStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	s.subscriptionsMutex.Lock()
+	defer s.subscriptionsMutex.Unlock()
+
+	subscription := &memorySubscription{
+		store:    s,
+		channel:  channel,
+		messages: make(chan []byte, subscriptionBufferSize),
+	}
+	if s.subscriptions[channel] == nil {
+		s.subscriptions[channel] = make(map[*memorySubscription]struct{})
+	}
+	s.subscriptions[channel][subscription] = struct{}{}
+	return subscription, nil
+}
+
+func (s *MemoryStore) unsubscribe(subscription *memorySubscription) {
+	s.subscriptionsMutex.Lock()
+	defer s.subscriptionsMutex.Unlock()
+
+	delete(s.subscriptions[subscription.channel], subscription)
+	subscription.close()
+}
+
+// redisSubscription is a subscription holding a dedicated connection to Redis
+type redisSubscription struct {
+	conn     *redisConn
+	messages chan []byte
+}
+
+func (s *redisSubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	return s.conn.Close()
+}
+
+// receive delivers the messages until the connection is closed or lost
+func (s *redisSubscription) receive() {
+	defer close(s.messages)
+	for {
+		reply, err := s.conn.readReply()
+		if err != nil {
+			return
+		}
+		values, ok := reply.([]interface{})
+		if !ok || len(values) != 3 || string(toBytes(values[0])) != "message" {
+			continue
+		}
+		select {
+		case s.messages <- toBytes(values[2]):
+		default:
+			// the subscriber does not keep up with the messages
+			return
+		}
+	}
+}
+
+// Publish implements PubSub, the messages are delivered to the subscribers in all instances
+func (s *RedisStore) Publish(ctx context.Context, channel string, message []byte) error {
+	_, err := s.do(ctx, "PUBLISH", s.key(channel), message)
+	return err
+}
+
+// Subscribe implements PubSub
+func (s *RedisStore) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.do(s.deadline(ctx), "SUBSCRIBE", s.key(channel)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the connection waits for messages from now on
+	var noDeadline time.Time
+	if err := conn.SetDeadline(noDeadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	subscription := &redisSubscription{
+		conn:     conn,
+		messages: make(chan []byte, subscriptionBufferSize),
+	}
+	go subscription.receive()
+	return subscription, nil
+}
+
+func toBytes(value interface{}) []byte {
+	switch value := value.(type) {
+	case []byte:
+		return value
+	case string:
+		return []byte(value)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,306 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisSettings configures the connection to Redis
+type RedisSettings struct {
+	Address   string        `mapstructure:"address" description:"host:port of the Redis server"`
+	Password  string        `mapstructure:"password" description:"password used to authenticate to the Redis server"`
+	DB        int           `mapstructure:"db" description:"number of the Redis database"`
+	KeyPrefix string        `mapstructure:"key_prefix" description:"prefix of all keys stored in Redis, allows sharing a Redis database between deployments"`
+	PoolSize  int           `mapstructure:"pool_size" description:"maximum number of idle connections to the Redis server"`
+	Timeout   time.Duration `mapstructure:"timeout" description:"timeout of connecting to the Redis server and of each command"`
+}
+
+// DefaultRedisSettings returns default values for the Redis settings
+func DefaultRedisSettings() *RedisSettings {
+	return &RedisSettings{
+		KeyPrefix: "service-manager:",
+		PoolSize:  10,
+		Timeout:   time.Second,
+	}
+}
+
+// Validate validates the Redis settings
+func (s *RedisSettings) Validate() error {
+	if s.Address == "" {
+		return fmt.Errorf("validate Settings: redis address missing")
+	}
+	if s.PoolSize <= 0 {
+		return fmt.Errorf("validate Settings: redis pool size must be > 0")
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("validate Settings: redis timeout must be > 0")
+	}
+	return nil
+}
+
+// redisError is an error reply of the Redis server, the connection remains usable after it
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// RedisStore is a Store which keeps the keys in Redis so that they are shared by all instances. It speaks the
+// Redis protocol over a pool of connections.
+type RedisStore struct {
+	settings *RedisSettings
+	pool     chan *redisConn
+}
+
+// NewRedisStore returns a RedisStore which connects to Redis lazily
+func NewRedisStore(settings *RedisSettings) *RedisStore {
+	return &RedisStore{
+		settings: settings,
+		pool:     make(chan *redisConn, settings.PoolSize),
+	}
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", s.key(key))
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+	return value, true, nil
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.do(ctx, setArgs(s.key(key), value, ttl)...)
+	return err
+}
+
+// SetIfAbsent implements Store
+func (s *RedisStore) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	reply, err := s.do(ctx, append(setArgs(s.key(key), value, ttl), "NX")...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// Increment implements Store. The expiration of a new counter is set by a second command, so a counter may not
+// expire if the connection is lost in between.
+func (s *RedisStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	reply, err := s.do(ctx, "INCR", s.key(key))
+	if err != nil {
+		return 0, err
+	}
+	counter, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %v to INCR", reply)
+	}
+	if counter == 1 && ttl > 0 {
+		if _, err := s.do(ctx, "PEXPIRE", s.key(key), strconv.FormatInt(int64(ttl/time.Millisecond), 10)); err != nil {
+			return 0, err
+		}
+	}
+	return counter, nil
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []interface{}{"DEL"}
+	for _, key := range keys {
+		args = append(args, s.key(key))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+// Ping checks the connection to Redis
+func (s *RedisStore) Ping(ctx context.Context) error {
+	_, err := s.do(ctx, "PING")
+	return err
+}
+
+func (s *RedisStore) key(key string) string {
+	return s.settings.KeyPrefix + key
+}
+
+func setArgs(key string, value []byte, ttl time.Duration) []interface{} {
+	args := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+	return args
+}
+
+// do sends the command to Redis and returns its reply, which is nil, an int64, a string, a []byte or a []interface{}
+func (s *RedisStore) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(s.deadline(ctx), args...)
+	if _, isRedisError := err.(redisError); err != nil && !isRedisError {
+		conn.Close()
+		return nil, err
+	}
+	s.release(conn)
+	return reply, err
+}
+
+func (s *RedisStore) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(s.settings.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Deadline: s.deadline(ctx)}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.settings.Address)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to redis: %s", err)
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if s.settings.Password != "" {
+		if _, err := conn.do(s.deadline(ctx), "AUTH", s.settings.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.settings.DB != 0 {
+		if _, err := conn.do(s.deadline(ctx), "SELECT", strconv.Itoa(s.settings.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (s *RedisStore) release(conn *redisConn) {
+	select {
+	case s.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (c *redisConn) do(deadline time.Time, args ...interface{}) (interface{}, error) {
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(c.Conn)
+	fmt.Fprintf(writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var value []byte
+		switch arg := arg.(type) {
+		case string:
+			value = []byte(arg)
+		case []byte:
+			value = arg
+		default:
+			return nil, fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+		fmt.Fprintf(writer, "$%d\r\n", len(value))
+		writer.Write(value)
+		writer.WriteString("\r\n")
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		value := make([]byte, length+2)
+		if _, err := io.ReadFull(c.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:length], nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, length)
+		for i := range values {
+			if values[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed reply %q", line)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cfvisibility contains logic for mapping the visibilities of Cloud Foundry platforms to the service plan
+// visibilities of Cloud Foundry, which are either public or restricted to organizations or spaces
+package cfvisibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// PlatformType is the type of Cloud Foundry platforms
+	PlatformType = "cloudfoundry"
+
+	// OrgLabelKeyLabel is the platform label which overrides the org label key for a single platform
+	OrgLabelKeyLabel = "cf_org_label_key"
+
+	// SpaceLabelKeyLabel is the platform label which overrides the space label key for a single platform
+	SpaceLabelKeyLabel = "cf_space_label_key"
+)
+
+// ErrUnsupportedPlatform is returned when the visibilities of a platform which is not a Cloud Foundry are mapped
+var ErrUnsupportedPlatform = errors.New("visibilities can only be mapped for platforms of type " + PlatformType)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled       bool   `mapstructure:"enabled" description:"whether the visibilities of cloudfoundry platforms are exposed as CF service plan visibilities"`
+	OrgLabelKey   string `mapstructure:"org_label_key" description:"key of the visibility label with the GUIDs of the CF organizations unless overridden by a platform label"`
+	SpaceLabelKey string `mapstructure:"space_label_key" description:"key of the visibility label with the GUIDs of the CF spaces unless overridden by a platform label"`
+}
+
+// DefaultSettings returns default values for CF visibility settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:       false,
+		OrgLabelKey:   "organization_guid",
+		SpaceLabelKey: "space_guid",
+	}
+}
+
+// Validate validates the CF visibility settings
+func (s *Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.OrgLabelKey == "" {
+		return fmt.Errorf("validate Settings: CF visibility org label key missing")
+	}
+	if s.SpaceLabelKey == "" {
+		return fmt.Errorf("validate Settings: CF visibility space label key missing")
+	}
+	return nil
+}
+
+// PlanVisibility is the visibility of a service plan in a Cloud Foundry platform. The plan is identified by the
+// catalog IDs of its broker so that it can be matched with the plans known to Cloud Foundry.
+type PlanVisibility struct {
+	ServicePlanID    string `json:"service_plan_id"`
+	BrokerID         string `json:"broker_id"`
+	CatalogServiceID string `json:"catalog_service_id"`
+	CatalogPlanID    string `json:"catalog_plan_id"`
+
+	// Public is true if the plan is visible in all organizations, in that case there are no organizations and spaces
+	Public        bool     `json:"public"`
+	Organizations []string `json:"organization_guids,omitempty"`
+	Spaces        []string `json:"space_guids,omitempty"`
+}
+
+// Mapper maps the visibilities of Cloud Foundry platforms
+type Mapper struct {
+	settings   *Settings
+	repository storage.Repository
+}
+
+// NewMapper returns a mapper which reads the visibilities from the provided repository
+func NewMapper(settings *Settings, repository storage.Repository) *Mapper {
+	return &Mapper{
+		settings:   settings,
+		repository: repository,
+	}
+}
+
+// Map returns the visibilities of the service plans in the provided platform. A visibility which is not bound to a
+// platform or has neither an org nor a space label makes the plan public.
+func (m *Mapper) Map(ctx context.Context, platform *types.Platform) ([]*PlanVisibility, error) {
+	if platform.Type != PlatformType {
+		return nil, ErrUnsupportedPlatform
+	}
+	orgLabelKey := m.labelKey(platform, OrgLabelKeyLabel, m.settings.OrgLabelKey)
+	spaceLabelKey := m.labelKey(platform, SpaceLabelKeyLabel, m.settings.SpaceLabelKey)
+
+	visibilities, err := m.repository.List(ctx, types.VisibilityType, query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID))
+	if err != nil {
+		return nil, fmt.Errorf("could not list visibilities of platform %s: %s", platform.ID, err)
+	}
+
+	now := time.Now()
+	byPlanID := make(map[string]*PlanVisibility)
+	for i := 0; i < visibilities.Len(); i++ {
+		visibility := visibilities.ItemAt(i).(*types.Visibility)
+		if !visibility.IsActive(now) {
+			continue
+		}
+		planVisibility, found := byPlanID[visibility.ServicePlanID]
+		if !found {
+			planVisibility = &PlanVisibility{ServicePlanID: visibility.ServicePlanID}
+			byPlanID[visibility.ServicePlanID] = planVisibility
+		}
+
+		orgs, spaces := visibility.Labels[orgLabelKey], visibility.Labels[spaceLabelKey]
+		if visibility.PlatformID == "" || len(orgs) == 0 && len(spaces) == 0 {
+			planVisibility.Public = true
+		}
+		planVisibility.Organizations = append(planVisibility.Organizations, orgs...)
+		planVisibility.Spaces = append(planVisibility.Spaces, spaces...)
+	}
+
+	if len(byPlanID) == 0 {
+		return []*PlanVisibility{}, nil
+	}
+
+	if err := m.addCatalogIDs(ctx, byPlanID); err != nil {
+		return nil, err
+	}
+
+	result := make([]*PlanVisibility, 0, len(byPlanID))
+	for _, planVisibility := range byPlanID {
+		if planVisibility.Public {
+			planVisibility.Organizations = nil
+			planVisibility.Spaces = nil
+		} else {
+			planVisibility.Organizations = unique(planVisibility.Organizations)
+			planVisibility.Spaces = unique(planVisibility.Spaces)
+		}
+		result = append(result, planVisibility)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ServicePlanID < result[j].ServicePlanID
+	})
+	return result, nil
+}
+
+// addCatalogIDs adds the catalog IDs of the plans and their offerings as well as the IDs of the brokers
+func (m *Mapper) addCatalogIDs(ctx context.Context, byPlanID map[string]*PlanVisibility) error {
+	planIDs := make([]string, 0, len(byPlanID))
+	for planID := range byPlanID {
+		planIDs = append(planIDs, planID)
+	}
+	plans, err := m.repository.List(ctx, types.ServicePlanType, query.ByField(query.InOperator, "id", planIDs...))
+	if err != nil {
+		return fmt.Errorf("could not list service plans: %s", err)
+	}
+
+	offeringIDs := make([]string, 0, plans.Len())
+	for i := 0; i < plans.Len(); i++ {
+		offeringIDs = append(offeringIDs, plans.ItemAt(i).(*types.ServicePlan).ServiceOfferingID)
+	}
+	offerings, err := m.repository.List(ctx, types.ServiceOfferingType, query.ByField(query.InOperator, "id", unique(offeringIDs)...))
+	if err != nil {
+		return fmt.Errorf("could not list service offerings: %s", err)
+	}
+	offeringsByID := make(map[string]*types.ServiceOffering, offerings.Len())
+	for i := 0; i < offerings.Len(); i++ {
+		offering := offerings.ItemAt(i).(*types.ServiceOffering)
+		offeringsByID[offering.ID] = offering
+	}
+
+	for i := 0; i < plans.Len(); i++ {
+		plan := plans.ItemAt(i).(*types.ServicePlan)
+		planVisibility, found := byPlanID[plan.ID]
+		if !found {
+			continue
+		}
+		planVisibility.CatalogPlanID = plan.CatalogID
+		if offering, found := offeringsByID[plan.ServiceOfferingID]; found {
+			planVisibility.CatalogServiceID = offering.CatalogID
+			planVisibility.BrokerID = offering.BrokerID
+		}
+	}
+	return nil
+}
+
+func (m *Mapper) labelKey(platform *types.Platform, overrideLabel, defaultKey string) string {
+	if values := platform.Labels[overrideLabel]; len(values) == 1 && values[0] != "" {
+		return values[0]
+	}
+	return defaultKey
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
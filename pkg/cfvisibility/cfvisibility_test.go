@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cfvisibility_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cfvisibility"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CF visibility mapper", func() {
+	var (
+		ctx          context.Context
+		settings     *cfvisibility.Settings
+		repository   *storagefakes.FakeRepository
+		platform     *types.Platform
+		visibilities []*types.Visibility
+		mapper       *cfvisibility.Mapper
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		settings = cfvisibility.DefaultSettings()
+		settings.Enabled = true
+		platform = &types.Platform{
+			Base: types.Base{ID: "platform-id", Labels: types.Labels{}},
+			Type: cfvisibility.PlatformType,
+		}
+		visibilities = nil
+
+		repository = &storagefakes.FakeRepository{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.VisibilityType:
+				return &types.Visibilities{Visibilities: visibilities}, nil
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: []*types.ServicePlan{
+					{Base: types.Base{ID: "plan-1"}, CatalogID: "catalog-plan-1", ServiceOfferingID: "offering-id"},
+					{Base: types.Base{ID: "plan-2"}, CatalogID: "catalog-plan-2", ServiceOfferingID: "offering-id"},
+				}}, nil
+			case types.ServiceOfferingType:
+				return &types.ServiceOfferings{ServiceOfferings: []*types.ServiceOffering{
+					{Base: types.Base{ID: "offering-id"}, CatalogID: "catalog-service", BrokerID: "broker-id"},
+				}}, nil
+			}
+			return nil, nil
+		})
+		mapper = cfvisibility.NewMapper(settings, repository)
+	})
+
+	Describe("Settings", func() {
+		It("requires the label keys when enabled", func() {
+			settings.SpaceLabelKey = ""
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("Map", func() {
+		It("rejects platforms which are not of type cloudfoundry", func() {
+			platform.Type = "kubernetes"
+			_, err := mapper.Map(ctx, platform)
+			Expect(err).To(Equal(cfvisibility.ErrUnsupportedPlatform))
+		})
+
+		It("lists the visibilities of the platform and the public ones", func() {
+			_, err := mapper.Map(ctx, platform)
+			Expect(err).ToNot(HaveOccurred())
+			_, objectType, criteria := repository.ListArgsForCall(0)
+			Expect(objectType).To(Equal(types.VisibilityType))
+			Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID)))
+		})
+
+		It("maps the org and space labels of the visibilities", func() {
+			visibilities = []*types.Visibility{
+				{PlatformID: platform.ID, ServicePlanID: "plan-1", Base: types.Base{Labels: types.Labels{"organization_guid": {"org-2", "org-1"}}}},
+				{PlatformID: platform.ID, ServicePlanID: "plan-1", Base: types.Base{Labels: types.Labels{"organization_guid": {"org-1"}, "space_guid": {"space-1"}}}},
+				{ServicePlanID: "plan-2"},
+			}
+			result, err := mapper.Map(ctx, platform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal([]*cfvisibility.PlanVisibility{
+				{
+					ServicePlanID:    "plan-1",
+					BrokerID:         "broker-id",
+					CatalogServiceID: "catalog-service",
+					CatalogPlanID:    "catalog-plan-1",
+					Organizations:    []string{"org-1", "org-2"},
+					Spaces:           []string{"space-1"},
+				},
+				{
+					ServicePlanID:    "plan-2",
+					BrokerID:         "broker-id",
+					CatalogServiceID: "catalog-service",
+					CatalogPlanID:    "catalog-plan-2",
+					Public:           true,
+				},
+			}))
+		})
+
+		It("makes the plan public for visibilities of the platform without org and space labels", func() {
+			visibilities = []*types.Visibility{
+				{PlatformID: platform.ID, ServicePlanID: "plan-1", Base: types.Base{Labels: types.Labels{"organization_guid": {"org-1"}}}},
+				{PlatformID: platform.ID, ServicePlanID: "plan-1"},
+			}
+			result, err := mapper.Map(ctx, platform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Public).To(BeTrue())
+			Expect(result[0].Organizations).To(BeNil())
+		})
+
+		It("ignores visibilities which are not in effect", func() {
+			past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+			visibilities = []*types.Visibility{
+				{PlatformID: platform.ID, ServicePlanID: "plan-1", ValidFrom: &future},
+				{PlatformID: platform.ID, ServicePlanID: "plan-2", ValidUntil: &past},
+			}
+			result, err := mapper.Map(ctx, platform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(BeEmpty())
+		})
+
+		It("uses the label keys configured for the platform", func() {
+			platform.Labels[cfvisibility.OrgLabelKeyLabel] = []string{"org"}
+			visibilities = []*types.Visibility{
+				{PlatformID: platform.ID, ServicePlanID: "plan-1", Base: types.Base{Labels: types.Labels{"org": {"org-1"}}}},
+			}
+			result, err := mapper.Map(ctx, platform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result[0].Public).To(BeFalse())
+			Expect(result[0].Organizations).To(ConsistOf("org-1"))
+		})
+	})
+})
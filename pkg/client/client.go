@@ -0,0 +1,303 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client provides a Go client for the Service Manager API
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// Settings type contains the configuration of the Service Manager client
+type Settings struct {
+	URL string `mapstructure:"url" description:"URL of the Service Manager"`
+
+	// Token is a bearer token used for all requests. It takes precedence over the other credentials.
+	Token string `mapstructure:"token" description:"bearer token used to authenticate the requests"`
+	// ClientID and ClientSecret are used to fetch tokens with the client credentials grant from the token issuer of the Service Manager
+	ClientID     string `mapstructure:"client_id" description:"client ID used to fetch tokens from the token issuer of the Service Manager"`
+	ClientSecret string `mapstructure:"client_secret" description:"client secret used to fetch tokens from the token issuer of the Service Manager"`
+	// User and Password are used for basic authentication, e.g. by platforms
+	User     string `mapstructure:"user" description:"user used for basic authentication"`
+	Password string `mapstructure:"password" description:"password used for basic authentication"`
+
+	SkipSSLValidation bool          `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the Service Manager"`
+	RequestTimeout    time.Duration `mapstructure:"request_timeout" description:"timeout of a single request to the Service Manager"`
+	MaxRetries        int           `mapstructure:"max_retries" description:"maximum number of retries of idempotent requests which failed with a connection error or a temporary server error"`
+	RetryInterval     time.Duration `mapstructure:"retry_interval" description:"interval between retries, doubled for each retry"`
+}
+
+// DefaultSettings returns the default values for the Service Manager client settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		RequestTimeout: 30 * time.Second,
+		MaxRetries:     3,
+		RetryInterval:  500 * time.Millisecond,
+	}
+}
+
+// Validate validates the client settings
+func (s *Settings) Validate() error {
+	if s.URL == "" {
+		return fmt.Errorf("validate Settings: Service Manager URL missing")
+	}
+	if s.ClientID != "" && s.ClientSecret == "" {
+		return fmt.Errorf("validate Settings: client secret missing")
+	}
+	if s.User != "" && s.Password == "" {
+		return fmt.Errorf("validate Settings: password missing")
+	}
+	if s.MaxRetries < 0 {
+		return fmt.Errorf("validate Settings: max retries (%d) should not be negative", s.MaxRetries)
+	}
+	return nil
+}
+
+// Client is a client for the Service Manager API
+type Client struct {
+	settings   *Settings
+	httpClient *http.Client
+	tokens     *tokenSource
+}
+
+// New returns a client configured with the provided settings
+func New(settings *Settings) (*Client, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport)
+	httpClient := &http.Client{
+		Timeout: settings.RequestTimeout,
+		Transport: &http.Transport{
+			Proxy:                 transport.Proxy,
+			DialContext:           transport.DialContext,
+			MaxIdleConns:          transport.MaxIdleConns,
+			IdleConnTimeout:       transport.IdleConnTimeout,
+			TLSHandshakeTimeout:   transport.TLSHandshakeTimeout,
+			ExpectContinueTimeout: transport.ExpectContinueTimeout,
+			TLSClientConfig:       &tls.Config{InsecureSkipVerify: settings.SkipSSLValidation},
+		},
+	}
+	return NewWithHTTPClient(settings, httpClient)
+}
+
+// NewWithHTTPClient returns a client which uses the provided http client. An http client which already authenticates
+// the requests, e.g. one created with the golang.org/x/oauth2 package, can be used without credentials in the settings.
+func NewWithHTTPClient(settings *Settings, httpClient *http.Client) (*Client, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	client := &Client{
+		settings:   settings,
+		httpClient: httpClient,
+	}
+	if settings.Token == "" && settings.ClientID != "" {
+		client.tokens = &tokenSource{client: client}
+	}
+	return client, nil
+}
+
+// Error is returned when the Service Manager responds with an unexpected status code
+type Error struct {
+	util.HTTPError
+	Method string
+	URL    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s failed with status code %d: %s", e.Method, e.URL, e.StatusCode, e.Description)
+}
+
+// IsNotFound returns true if the error was caused by a resource which does not exist
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// IsConflict returns true if the error was caused by a resource which conflicts with an existing one
+func IsConflict(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusConflict
+}
+
+// call sends a request to the Service Manager and decodes the response body in result if the response has the expected status code
+func (c *Client) call(ctx context.Context, method, path string, params map[string]string, body, result interface{}, expectedStatus int) error {
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	response, err := c.send(ctx, method, path, params, bodyBytes)
+	if err != nil {
+		return err
+	}
+	defer closeBody(ctx, response.Body)
+
+	if response.StatusCode != expectedStatus {
+		return responseError(method, response)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, params map[string]string, body []byte) (*http.Response, error) {
+	url := strings.TrimSuffix(c.settings.URL, "/") + path
+	interval := c.settings.RetryInterval
+	for attempt := 0; ; attempt++ {
+		response, err := c.sendOnce(ctx, method, url, params, body)
+		if attempt >= c.settings.MaxRetries || !isIdempotent(method) || !shouldRetry(response, err) {
+			return response, err
+		}
+		if response != nil {
+			closeBody(ctx, response.Body)
+		}
+		log.C(ctx).Debugf("Retrying %s %s in %s, attempt %d failed", method, url, interval, attempt+1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, method, url string, params map[string]string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequest(method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if len(params) > 0 {
+		q := request.URL.Query()
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		request.URL.RawQuery = q.Encode()
+	}
+	if err := c.authenticate(ctx, request); err != nil {
+		return nil, err
+	}
+	if correlationID := util.CorrelationIDFromContext(ctx); correlationID != "" {
+		request.Header.Set(log.CorrelationIDHeaders[0], correlationID)
+	}
+	return c.httpClient.Do(request.WithContext(ctx))
+}
+
+func (c *Client) authenticate(ctx context.Context, request *http.Request) error {
+	switch {
+	case c.settings.Token != "":
+		request.Header.Set("Authorization", "Bearer "+c.settings.Token)
+	case c.tokens != nil:
+		token, err := c.tokens.token(ctx)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	case c.settings.User != "":
+		request.SetBasicAuth(c.settings.User, c.settings.Password)
+	}
+	return nil
+}
+
+// criteriaParams converts the criteria to the field and label query parameters of the Service Manager API
+func criteriaParams(criteria []query.Criterion) (map[string]string, error) {
+	queries := make(map[query.CriterionType][]string)
+	for _, criterion := range criteria {
+		if criterion.Type != query.FieldQuery && criterion.Type != query.LabelQuery {
+			return nil, fmt.Errorf("criterion of type %s is not supported by the Service Manager API", criterion.Type)
+		}
+		if err := criterion.Validate(); err != nil {
+			return nil, err
+		}
+		queries[criterion.Type] = append(queries[criterion.Type], formatCriterion(criterion))
+	}
+	params := make(map[string]string, len(queries))
+	for criterionType, segments := range queries {
+		params[string(criterionType)] = strings.Join(segments, string(query.Separator))
+	}
+	return params, nil
+}
+
+func formatCriterion(criterion query.Criterion) string {
+	rightOp := make([]string, 0, len(criterion.RightOp))
+	for _, value := range criterion.RightOp {
+		rightOp = append(rightOp, strings.Replace(value, string(query.Separator), `\`+string(query.Separator), -1))
+	}
+	value := strings.Join(rightOp, "")
+	if criterion.Operator.IsMultiVariate() {
+		value = string(query.OpenBracket) + strings.Join(rightOp, strings.Repeat(string(query.Separator), 2)) + string(query.CloseBracket)
+	}
+	return fmt.Sprintf("%s%c%s%c%s", criterion.LeftOp, query.OperandSeparator, criterion.Operator, query.OperandSeparator, value)
+}
+
+func responseError(method string, response *http.Response) error {
+	err := &Error{
+		Method: method,
+		URL:    response.Request.URL.String(),
+	}
+	body, readErr := util.BodyToBytes(response.Body)
+	if readErr == nil {
+		if decodeErr := json.Unmarshal(body, &err.HTTPError); decodeErr != nil {
+			err.Description = string(body)
+		}
+	}
+	err.StatusCode = response.StatusCode
+	return err
+}
+
+func shouldRetry(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+func closeBody(ctx context.Context, body io.Closer) {
+	if err := body.Close(); err != nil {
+		log.C(ctx).WithError(err).Debug("Could not close response body")
+	}
+}
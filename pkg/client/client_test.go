@@ -0,0 +1,319 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/client"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/gorilla/websocket"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		handler  http.HandlerFunc
+		requests []*http.Request
+		bodies   []string
+		mutex    sync.Mutex
+		settings *client.Settings
+		smClient *client.Client
+		ctx      context.Context
+	)
+
+	respond := func(status int, body interface{}) http.HandlerFunc {
+		return func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(status)
+			Expect(json.NewEncoder(rw).Encode(body)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		requests = nil
+		bodies = nil
+		handler = respond(http.StatusOK, map[string]interface{}{})
+		server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			Expect(err).ToNot(HaveOccurred())
+			mutex.Lock()
+			requests = append(requests, req)
+			bodies = append(bodies, string(body))
+			mutex.Unlock()
+			handler(rw, req)
+		}))
+
+		settings = client.DefaultSettings()
+		settings.URL = server.URL
+		settings.Token = "token"
+		settings.RetryInterval = time.Millisecond
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		smClient, err = client.New(settings)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Settings", func() {
+		It("requires the Service Manager URL", func() {
+			settings.URL = ""
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("requires a client secret when a client ID is provided", func() {
+			settings.ClientID = "id"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("requires a password when a user is provided", func() {
+			settings.User = "user"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("ListBrokers", func() {
+		BeforeEach(func() {
+			handler = respond(http.StatusOK, map[string]interface{}{
+				"service_brokers": []map[string]interface{}{
+					{"id": "broker-1", "name": "broker"},
+				},
+			})
+		})
+
+		It("returns the brokers", func() {
+			brokers, err := smClient.ListBrokers(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(brokers).To(HaveLen(1))
+			Expect(brokers[0].ID).To(Equal("broker-1"))
+			Expect(requests[0].URL.Path).To(Equal(web.ServiceBrokersURL))
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer token"))
+		})
+
+		It("sends the criteria as field and label queries", func() {
+			_, err := smClient.ListBrokers(ctx,
+				query.ByField(query.EqualsOperator, "name", "a|b"),
+				query.ByField(query.InOperator, "id", "1", "2"),
+				query.ByLabel(query.EqualsOperator, "env", "dev"))
+			Expect(err).ToNot(HaveOccurred())
+
+			q := requests[0].URL.Query()
+			Expect(q.Get("fieldQuery")).To(Equal(`name = a\|b|id in [1||2]`))
+			Expect(q.Get("labelQuery")).To(Equal("env = dev"))
+
+			criteria, err := query.BuildCriteriaFromRequest(requests[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(criteria).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "a|b"),
+				query.ByField(query.InOperator, "id", "1", "2"),
+				query.ByLabel(query.EqualsOperator, "env", "dev")))
+		})
+
+		It("rejects result criteria", func() {
+			_, err := smClient.ListBrokers(ctx, query.LimitResultBy(10))
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Describe("RegisterPlatform", func() {
+		BeforeEach(func() {
+			handler = respond(http.StatusCreated, map[string]interface{}{
+				"id":   "platform-1",
+				"name": "platform",
+				"credentials": map[string]interface{}{
+					"basic": map[string]interface{}{"username": "user", "password": "password"},
+				},
+			})
+		})
+
+		It("returns the platform with its credentials", func() {
+			platform, err := smClient.RegisterPlatform(ctx, &types.Platform{Name: "platform", Type: "kubernetes"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.ID).To(Equal("platform-1"))
+			Expect(platform.Credentials.Basic.Username).To(Equal("user"))
+			Expect(requests[0].Method).To(Equal(http.MethodPost))
+			Expect(bodies[0]).To(ContainSubstring(`"type":"kubernetes"`))
+		})
+	})
+
+	Describe("UpdateVisibility", func() {
+		It("sends only the changed fields and the label changes", func() {
+			_, err := smClient.UpdateVisibility(ctx, "visibility-1", client.Fields{"platform_id": "platform-1"},
+				&query.LabelChange{Operation: query.AddLabelOperation, Key: "org", Values: []string{"org-1"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests[0].Method).To(Equal(http.MethodPatch))
+			Expect(requests[0].URL.Path).To(Equal(web.VisibilitiesURL + "/visibility-1"))
+			Expect(bodies[0]).To(MatchJSON(`{"platform_id":"platform-1","labels":[{"op":"add","key":"org","values":["org-1"]}]}`))
+		})
+	})
+
+	Describe("errors", func() {
+		BeforeEach(func() {
+			handler = respond(http.StatusNotFound, map[string]interface{}{
+				"error":       "NotFound",
+				"description": "could not find broker",
+			})
+		})
+
+		It("returns the error of the Service Manager", func() {
+			_, err := smClient.GetBroker(ctx, "broker-1")
+			Expect(err).To(HaveOccurred())
+			Expect(client.IsNotFound(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("could not find broker"))
+		})
+	})
+
+	Describe("retries", func() {
+		var calls int
+
+		BeforeEach(func() {
+			calls = 0
+			handler = func(rw http.ResponseWriter, req *http.Request) {
+				calls++
+				if calls < 3 {
+					rw.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				respond(http.StatusOK, map[string]interface{}{"platforms": []interface{}{}})(rw, req)
+			}
+		})
+
+		It("retries idempotent requests which failed with a temporary error", func() {
+			_, err := smClient.ListPlatforms(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal(3))
+		})
+
+		It("does not retry requests which are not idempotent", func() {
+			_, err := smClient.RegisterPlatform(ctx, &types.Platform{Name: "platform"})
+			Expect(err).To(HaveOccurred())
+			Expect(calls).To(Equal(1))
+		})
+
+		Context("when the retries are exhausted", func() {
+			BeforeEach(func() {
+				settings.MaxRetries = 1
+			})
+
+			It("returns the last error", func() {
+				_, err := smClient.ListPlatforms(ctx)
+				Expect(err).To(HaveOccurred())
+				Expect(calls).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("client credentials", func() {
+		var tokenRequests int
+
+		BeforeEach(func() {
+			tokenRequests = 0
+			settings.Token = ""
+			settings.ClientID = "client"
+			settings.ClientSecret = "secret"
+			handler = func(rw http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case web.InfoURL:
+					respond(http.StatusOK, map[string]interface{}{"token_issuer_url": server.URL + "/issuer"})(rw, req)
+				case "/issuer/.well-known/openid-configuration":
+					respond(http.StatusOK, map[string]interface{}{"token_endpoint": server.URL + "/issuer/token"})(rw, req)
+				case "/issuer/token":
+					tokenRequests++
+					user, password, ok := req.BasicAuth()
+					Expect(ok).To(BeTrue())
+					Expect(user).To(Equal("client"))
+					Expect(password).To(Equal("secret"))
+					respond(http.StatusOK, map[string]interface{}{"access_token": "fetched-token", "expires_in": 3600})(rw, req)
+				default:
+					Expect(req.Header.Get("Authorization")).To(Equal("Bearer fetched-token"))
+					respond(http.StatusOK, map[string]interface{}{"service_plans": []interface{}{}})(rw, req)
+				}
+			}
+		})
+
+		It("fetches a token from the token issuer of the Service Manager and reuses it", func() {
+			_, err := smClient.ListServicePlans(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = smClient.ListServicePlans(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenRequests).To(Equal(1))
+		})
+	})
+
+	Describe("StreamNotifications", func() {
+		BeforeEach(func() {
+			settings.Token = ""
+			settings.User = "platform"
+			settings.Password = "password"
+			handler = func(rw http.ResponseWriter, req *http.Request) {
+				defer GinkgoRecover()
+				user, _, _ := req.BasicAuth()
+				Expect(user).To(Equal("platform"))
+				Expect(req.URL.Query().Get("last_notification_revision")).To(Equal("5"))
+
+				upgrader := websocket.Upgrader{}
+				conn, err := upgrader.Upgrade(rw, req, http.Header{"max_ping_period": []string{"1m"}})
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+				Expect(conn.WriteJSON(&types.Notification{Base: types.Base{ID: "notification-6"}, Revision: 6})).To(Succeed())
+				conn.ReadMessage()
+			}
+		})
+
+		It("receives the notifications after the provided revision", func() {
+			stream, err := smClient.StreamNotifications(ctx, 5)
+			Expect(err).ToNot(HaveOccurred())
+			defer stream.Close()
+
+			var notification *types.Notification
+			Eventually(stream.Notifications()).Should(Receive(&notification))
+			Expect(notification.ID).To(Equal("notification-6"))
+			Expect(stream.Revision()).To(Equal(int64(6)))
+		})
+
+		Context("when the revision is no longer known", func() {
+			BeforeEach(func() {
+				handler = respond(http.StatusGone, map[string]interface{}{"description": "revision not known"})
+			})
+
+			It("returns an error with status code 410", func() {
+				_, err := smClient.StreamNotifications(ctx, 5)
+				Expect(err).To(HaveOccurred())
+				Expect(strings.Contains(err.Error(), "410")).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	lastKnownRevisionParam = "last_notification_revision"
+	maxPingPeriodHeader    = "max_ping_period"
+)
+
+// NotificationStream is a connection to the notifications websocket of the Service Manager
+type NotificationStream struct {
+	conn          *websocket.Conn
+	notifications chan *types.Notification
+	done          chan struct{}
+	closeOnce     sync.Once
+
+	mutex    sync.Mutex
+	revision int64
+	err      error
+}
+
+// StreamNotifications connects to the notifications websocket of the Service Manager with the credentials of a platform.
+// Only notifications after the provided revision are received. If the revision is types.InvalidRevision only new
+// notifications are received. If the revision is no longer known to the Service Manager the connection is rejected
+// with an Error with status code 410 Gone and the platform should resync its state.
+func (c *Client) StreamNotifications(ctx context.Context, revision int64) (*NotificationStream, error) {
+	wsURL := "ws" + strings.TrimPrefix(strings.TrimSuffix(c.settings.URL, "/"), "http") + web.NotificationsURL
+	if revision != types.InvalidRevision {
+		wsURL += "?" + lastKnownRevisionParam + "=" + strconv.FormatInt(revision, 10)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, wsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authenticate(ctx, request); err != nil {
+		return nil, err
+	}
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: c.settings.RequestTimeout,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: c.settings.SkipSSLValidation},
+	}
+	conn, response, err := dialer.DialContext(ctx, wsURL, request.Header)
+	if err != nil {
+		if response != nil {
+			defer closeBody(ctx, response.Body)
+			return nil, responseError(http.MethodGet, response)
+		}
+		return nil, err
+	}
+
+	pingPeriod, err := time.ParseDuration(response.Header.Get(maxPingPeriodHeader))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid %s header: %s", maxPingPeriodHeader, err)
+	}
+
+	stream := &NotificationStream{
+		conn:          conn,
+		notifications: make(chan *types.Notification, 100),
+		done:          make(chan struct{}),
+		revision:      revision,
+	}
+	go stream.read()
+	go stream.ping(ctx, pingPeriod/2)
+	return stream, nil
+}
+
+// Notifications returns the channel of the received notifications. The channel is closed when the connection is closed.
+func (s *NotificationStream) Notifications() <-chan *types.Notification {
+	return s.notifications
+}
+
+// Revision returns the revision of the last received notification. It can be used to continue from where the stream
+// stopped after reconnecting.
+func (s *NotificationStream) Revision() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.revision
+}
+
+// Err returns the error which closed the connection
+func (s *NotificationStream) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Close closes the connection
+func (s *NotificationStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		deadline := time.Now().Add(time.Second)
+		message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		if writeErr := s.conn.WriteControl(websocket.CloseMessage, message, deadline); writeErr != nil {
+			err = writeErr
+		}
+		if closeErr := s.conn.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+func (s *NotificationStream) read() {
+	defer close(s.notifications)
+	for {
+		notification := &types.Notification{}
+		if err := s.conn.ReadJSON(notification); err != nil {
+			s.mutex.Lock()
+			s.err = err
+			s.mutex.Unlock()
+			return
+		}
+		s.mutex.Lock()
+		if notification.Revision > s.revision {
+			s.revision = notification.Revision
+		}
+		s.mutex.Unlock()
+
+		select {
+		case s.notifications <- notification:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// ping keeps the connection alive as the Service Manager closes connections which do not send pings
+func (s *NotificationStream) ping(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(period)); err != nil {
+				log.C(ctx).WithError(err).Debug("Could not ping Service Manager notifications websocket")
+				return
+			}
+		}
+	}
+}
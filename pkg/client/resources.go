@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Fields contains the fields of a resource which are changed by an update. Fields which are not present are not changed.
+type Fields map[string]interface{}
+
+// ListBrokers returns the brokers matching the criteria. The Service Manager API returns all matching resources in
+// a single response, so no paging is required.
+func (c *Client) ListBrokers(ctx context.Context, criteria ...query.Criterion) ([]*types.ServiceBroker, error) {
+	result := &types.ServiceBrokers{}
+	if err := c.list(ctx, web.ServiceBrokersURL, criteria, result); err != nil {
+		return nil, err
+	}
+	return result.ServiceBrokers, nil
+}
+
+// GetBroker returns the broker with the provided ID
+func (c *Client) GetBroker(ctx context.Context, id string) (*types.ServiceBroker, error) {
+	result := &types.ServiceBroker{}
+	if err := c.call(ctx, http.MethodGet, web.ServiceBrokersURL+"/"+id, nil, nil, result, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RegisterBroker registers the broker and returns it as stored by the Service Manager
+func (c *Client) RegisterBroker(ctx context.Context, broker *types.ServiceBroker) (*types.ServiceBroker, error) {
+	result := &types.ServiceBroker{}
+	if err := c.call(ctx, http.MethodPost, web.ServiceBrokersURL, nil, broker, result, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateBroker changes the fields and labels of the broker with the provided ID. The catalog of the broker is refetched.
+func (c *Client) UpdateBroker(ctx context.Context, id string, fields Fields, labelChanges ...*query.LabelChange) (*types.ServiceBroker, error) {
+	result := &types.ServiceBroker{}
+	if err := c.update(ctx, web.ServiceBrokersURL+"/"+id, fields, labelChanges, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteBroker deletes the broker with the provided ID
+func (c *Client) DeleteBroker(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, web.ServiceBrokersURL+"/"+id, nil, nil, nil, http.StatusOK)
+}
+
+// DeleteBrokers deletes the brokers matching the criteria
+func (c *Client) DeleteBrokers(ctx context.Context, criteria ...query.Criterion) error {
+	return c.deleteAll(ctx, web.ServiceBrokersURL, criteria)
+}
+
+// ListPlatforms returns the platforms matching the criteria
+func (c *Client) ListPlatforms(ctx context.Context, criteria ...query.Criterion) ([]*types.Platform, error) {
+	result := &types.Platforms{}
+	if err := c.list(ctx, web.PlatformsURL, criteria, result); err != nil {
+		return nil, err
+	}
+	return result.Platforms, nil
+}
+
+// GetPlatform returns the platform with the provided ID
+func (c *Client) GetPlatform(ctx context.Context, id string) (*types.Platform, error) {
+	result := &types.Platform{}
+	if err := c.call(ctx, http.MethodGet, web.PlatformsURL+"/"+id, nil, nil, result, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RegisterPlatform registers the platform. The returned platform contains the credentials generated for it.
+func (c *Client) RegisterPlatform(ctx context.Context, platform *types.Platform) (*types.Platform, error) {
+	result := &types.Platform{}
+	if err := c.call(ctx, http.MethodPost, web.PlatformsURL, nil, platform, result, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdatePlatform changes the fields and labels of the platform with the provided ID
+func (c *Client) UpdatePlatform(ctx context.Context, id string, fields Fields, labelChanges ...*query.LabelChange) (*types.Platform, error) {
+	result := &types.Platform{}
+	if err := c.update(ctx, web.PlatformsURL+"/"+id, fields, labelChanges, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeletePlatform deletes the platform with the provided ID
+func (c *Client) DeletePlatform(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, web.PlatformsURL+"/"+id, nil, nil, nil, http.StatusOK)
+}
+
+// DeletePlatforms deletes the platforms matching the criteria
+func (c *Client) DeletePlatforms(ctx context.Context, criteria ...query.Criterion) error {
+	return c.deleteAll(ctx, web.PlatformsURL, criteria)
+}
+
+// ListVisibilities returns the visibilities matching the criteria
+func (c *Client) ListVisibilities(ctx context.Context, criteria ...query.Criterion) ([]*types.Visibility, error) {
+	result := &types.Visibilities{}
+	if err := c.list(ctx, web.VisibilitiesURL, criteria, result); err != nil {
+		return nil, err
+	}
+	return result.Visibilities, nil
+}
+
+// GetVisibility returns the visibility with the provided ID
+func (c *Client) GetVisibility(ctx context.Context, id string) (*types.Visibility, error) {
+	result := &types.Visibility{}
+	if err := c.call(ctx, http.MethodGet, web.VisibilitiesURL+"/"+id, nil, nil, result, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateVisibility creates the visibility
+func (c *Client) CreateVisibility(ctx context.Context, visibility *types.Visibility) (*types.Visibility, error) {
+	result := &types.Visibility{}
+	if err := c.call(ctx, http.MethodPost, web.VisibilitiesURL, nil, visibility, result, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateVisibility changes the fields and labels of the visibility with the provided ID
+func (c *Client) UpdateVisibility(ctx context.Context, id string, fields Fields, labelChanges ...*query.LabelChange) (*types.Visibility, error) {
+	result := &types.Visibility{}
+	if err := c.update(ctx, web.VisibilitiesURL+"/"+id, fields, labelChanges, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteVisibility deletes the visibility with the provided ID
+func (c *Client) DeleteVisibility(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, web.VisibilitiesURL+"/"+id, nil, nil, nil, http.StatusOK)
+}
+
+// DeleteVisibilities deletes the visibilities matching the criteria
+func (c *Client) DeleteVisibilities(ctx context.Context, criteria ...query.Criterion) error {
+	return c.deleteAll(ctx, web.VisibilitiesURL, criteria)
+}
+
+// ListServiceOfferings returns the service offerings matching the criteria
+func (c *Client) ListServiceOfferings(ctx context.Context, criteria ...query.Criterion) ([]*types.ServiceOffering, error) {
+	result := &types.ServiceOfferings{}
+	if err := c.list(ctx, web.ServiceOfferingsURL, criteria, result); err != nil {
+		return nil, err
+	}
+	return result.ServiceOfferings, nil
+}
+
+// GetServiceOffering returns the service offering with the provided ID
+func (c *Client) GetServiceOffering(ctx context.Context, id string) (*types.ServiceOffering, error) {
+	result := &types.ServiceOffering{}
+	if err := c.call(ctx, http.MethodGet, web.ServiceOfferingsURL+"/"+id, nil, nil, result, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListServicePlans returns the service plans matching the criteria
+func (c *Client) ListServicePlans(ctx context.Context, criteria ...query.Criterion) ([]*types.ServicePlan, error) {
+	result := &types.ServicePlans{}
+	if err := c.list(ctx, web.ServicePlansURL, criteria, result); err != nil {
+		return nil, err
+	}
+	return result.ServicePlans, nil
+}
+
+// GetServicePlan returns the service plan with the provided ID
+func (c *Client) GetServicePlan(ctx context.Context, id string) (*types.ServicePlan, error) {
+	result := &types.ServicePlan{}
+	if err := c.call(ctx, http.MethodGet, web.ServicePlansURL+"/"+id, nil, nil, result, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) list(ctx context.Context, path string, criteria []query.Criterion, result interface{}) error {
+	params, err := criteriaParams(criteria)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, http.MethodGet, path, params, nil, result, http.StatusOK)
+}
+
+func (c *Client) update(ctx context.Context, path string, fields Fields, labelChanges []*query.LabelChange, result interface{}) error {
+	body := make(map[string]interface{}, len(fields)+1)
+	for field, value := range fields {
+		body[field] = value
+	}
+	if len(labelChanges) > 0 {
+		body["labels"] = labelChanges
+	}
+	return c.call(ctx, http.MethodPatch, path, nil, body, result, http.StatusOK)
+}
+
+func (c *Client) deleteAll(ctx context.Context, path string, criteria []query.Criterion) error {
+	params, err := criteriaParams(criteria)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, http.MethodDelete, path, params, nil, nil, http.StatusOK)
+}
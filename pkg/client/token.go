@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// tokenExpiryDelta is the time before the expiry of a token after which a new token is fetched
+const tokenExpiryDelta = 10 * time.Second
+
+// tokenSource fetches tokens with the client credentials grant from the token issuer advertised by the Service Manager
+type tokenSource struct {
+	client *Client
+
+	mutex         sync.Mutex
+	tokenEndpoint string
+	accessToken   string
+	expiresAt     time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (ts *tokenSource) token(ctx context.Context) (string, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.accessToken != "" && time.Now().Add(tokenExpiryDelta).Before(ts.expiresAt) {
+		return ts.accessToken, nil
+	}
+	if ts.tokenEndpoint == "" {
+		endpoint, err := ts.discoverTokenEndpoint(ctx)
+		if err != nil {
+			return "", err
+		}
+		ts.tokenEndpoint = endpoint
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	request, err := http.NewRequest(http.MethodPost, ts.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(ts.client.settings.ClientID), url.QueryEscape(ts.client.settings.ClientSecret))
+
+	token := &tokenResponse{}
+	if err := ts.fetch(ctx, request, token); err != nil {
+		return "", fmt.Errorf("could not fetch token: %s", err)
+	}
+	ts.accessToken = token.AccessToken
+	ts.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.accessToken, nil
+}
+
+func (ts *tokenSource) discoverTokenEndpoint(ctx context.Context) (string, error) {
+	info := &struct {
+		TokenIssuer string `json:"token_issuer_url"`
+	}{}
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(ts.client.settings.URL, "/")+web.InfoURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := ts.fetch(ctx, request, info); err != nil {
+		return "", fmt.Errorf("could not fetch Service Manager info: %s", err)
+	}
+
+	configuration := &struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}{}
+	request, err = http.NewRequest(http.MethodGet, strings.TrimSuffix(info.TokenIssuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", err
+	}
+	if err := ts.fetch(ctx, request, configuration); err != nil {
+		return "", fmt.Errorf("could not fetch openid configuration of token issuer %s: %s", info.TokenIssuer, err)
+	}
+	return configuration.TokenEndpoint, nil
+}
+
+func (ts *tokenSource) fetch(ctx context.Context, request *http.Request, result interface{}) error {
+	response, err := ts.client.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeBody(ctx, response.Body)
+	if response.StatusCode != http.StatusOK {
+		return responseError(request.Method, response)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
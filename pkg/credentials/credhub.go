@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// CredHubStoreName is the name of the secret store backed by CredHub
+const CredHubStoreName = "credhub"
+
+// CredHubSettings configures the secret store backed by CredHub
+type CredHubSettings struct {
+	URL          string `mapstructure:"url" description:"URL of the CredHub server which stores the passwords of platforms, the passwords are persisted by the Service Manager if empty"`
+	TokenURL     string `mapstructure:"token_url" description:"URL of the UAA token endpoint used to authenticate to CredHub"`
+	ClientID     string `mapstructure:"client_id" description:"UAA client used to authenticate to CredHub"`
+	ClientSecret string `mapstructure:"client_secret" description:"secret of the UAA client used to authenticate to CredHub"`
+	PathPrefix   string `mapstructure:"path_prefix" description:"CredHub path under which the passwords of platforms are stored"`
+}
+
+// DefaultCredHubSettings returns the default values for the CredHub secret store
+func DefaultCredHubSettings() *CredHubSettings {
+	return &CredHubSettings{
+		URL:          "",
+		TokenURL:     "",
+		ClientID:     "",
+		ClientSecret: "",
+		PathPrefix:   "/service-manager/platforms",
+	}
+}
+
+// Validate validates the CredHub settings
+func (s *CredHubSettings) Validate() error {
+	if s.URL == "" {
+		return nil
+	}
+	if s.TokenURL == "" {
+		return fmt.Errorf("validate Settings: credhub token URL missing")
+	}
+	if s.ClientID == "" {
+		return fmt.Errorf("validate Settings: credhub client ID missing")
+	}
+	if !strings.HasPrefix(s.PathPrefix, "/") {
+		return fmt.Errorf("validate Settings: credhub path prefix must start with /")
+	}
+	return nil
+}
+
+// CredHubStore stores the passwords of platforms as CredHub password credentials named after the platform IDs
+type CredHubStore struct {
+	settings  *CredHubSettings
+	client    *http.Client
+	doRequest util.DoRequestFunc
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewCredHubStore returns a secret store which uses the provided client to call CredHub and UAA
+func NewCredHubStore(settings *CredHubSettings, client *http.Client) *CredHubStore {
+	store := &CredHubStore{
+		settings: settings,
+		client:   client,
+	}
+	store.doRequest = func(request *http.Request) (*http.Response, error) {
+		accessToken, err := store.token(request.Context())
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		return client.Do(request)
+	}
+	return store
+}
+
+// Name implements SecretStore
+func (*CredHubStore) Name() string {
+	return CredHubStoreName
+}
+
+type credHubCredential struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Put implements SecretStore and returns the name of the CredHub credential as reference
+func (s *CredHubStore) Put(ctx context.Context, platformID, password string) (string, error) {
+	name := strings.TrimSuffix(s.settings.PathPrefix, "/") + "/" + platformID
+	response, err := util.SendRequest(ctx, s.doRequest, http.MethodPut, s.url(), nil, &credHubCredential{
+		Name:  name,
+		Type:  "password",
+		Value: password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not store password of platform %s in credhub: %s", platformID, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not store password of platform %s in credhub: %s", platformID, util.HandleResponseError(response))
+	}
+	return name, nil
+}
+
+// Get implements SecretStore
+func (s *CredHubStore) Get(ctx context.Context, reference string) (string, error) {
+	response, err := util.SendRequest(ctx, s.doRequest, http.MethodGet, s.url(), map[string]string{
+		"name":    reference,
+		"current": "true",
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not get password %s from credhub: %s", reference, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get password %s from credhub: %s", reference, util.HandleResponseError(response))
+	}
+	result := struct {
+		Data []credHubCredential `json:"data"`
+	}{}
+	if err := util.BodyToObject(response.Body, &result); err != nil {
+		return "", fmt.Errorf("could not parse credhub response: %s", err)
+	}
+	if len(result.Data) == 0 {
+		return "", fmt.Errorf("password %s not found in credhub", reference)
+	}
+	return result.Data[0].Value, nil
+}
+
+// Delete implements SecretStore. Deleting a password which does not exist succeeds.
+func (s *CredHubStore) Delete(ctx context.Context, reference string) error {
+	response, err := util.SendRequest(ctx, s.doRequest, http.MethodDelete, s.url(), map[string]string{
+		"name": reference,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("could not delete password %s from credhub: %s", reference, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("could not delete password %s from credhub: %s", reference, util.HandleResponseError(response))
+	}
+	return nil
+}
+
+func (s *CredHubStore) url() string {
+	return strings.TrimSuffix(s.settings.URL, "/") + "/api/v1/data"
+}
+
+// token returns an access token of the configured UAA client, a new token is requested shortly before the
+// current one expires
+func (s *CredHubStore) token(ctx context.Context) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	request, err := http.NewRequest(http.MethodPost, s.settings.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(s.settings.ClientID, s.settings.ClientSecret)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("could not get credhub access token: %s", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get credhub access token: %s", util.HandleResponseError(response))
+	}
+	result := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err := util.BodyToObject(response.Body, &result); err != nil {
+		return "", fmt.Errorf("could not parse credhub access token response: %s", err)
+	}
+	s.accessToken = result.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 30*time.Second)
+	return s.accessToken, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package credentials_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CredHub secret store", func() {
+	var (
+		credhub       *httptest.Server
+		passwords     map[string]string
+		tokenRequests int
+		store         *credentials.CredHubStore
+	)
+
+	BeforeEach(func() {
+		passwords = make(map[string]string)
+		tokenRequests = 0
+		// the fake server plays both UAA and CredHub
+		credhub = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/oauth/token" {
+				username, password, _ := r.BasicAuth()
+				if username != "sm" || password != "secret" || r.FormValue("grant_type") != "client_credentials" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				tokenRequests++
+				w.Write([]byte(`{"access_token": "credhub-token", "expires_in": 3600}`))
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer credhub-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			name := r.URL.Query().Get("name")
+			switch r.Method {
+			case http.MethodPut:
+				body := make(map[string]string)
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body["type"]).To(Equal("password"))
+				passwords[body["name"]] = body["value"]
+				w.Write([]byte(`{}`))
+			case http.MethodGet:
+				password, found := passwords[name]
+				if !found {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"error": "The request could not be completed because the credential does not exist"}`))
+					return
+				}
+				w.Write([]byte(`{"data": [{"name": "` + name + `", "type": "password", "value": "` + password + `"}]}`))
+			case http.MethodDelete:
+				if _, found := passwords[name]; !found {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				delete(passwords, name)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+		settings := credentials.DefaultCredHubSettings()
+		settings.URL = credhub.URL
+		settings.TokenURL = credhub.URL + "/oauth/token"
+		settings.ClientID = "sm"
+		settings.ClientSecret = "secret"
+		store = credentials.NewCredHubStore(settings, http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		credhub.Close()
+	})
+
+	It("stores, resolves and deletes passwords", func() {
+		reference, err := store.Put(context.TODO(), "platform-id", "password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reference).To(Equal("/service-manager/platforms/platform-id"))
+
+		password, err := store.Get(context.TODO(), reference)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(password).To(Equal("password"))
+
+		Expect(store.Delete(context.TODO(), reference)).To(Succeed())
+		_, err = store.Get(context.TODO(), reference)
+		Expect(err).To(HaveOccurred())
+		Expect(store.Delete(context.TODO(), reference)).To(Succeed())
+	})
+
+	It("replaces the password of the same platform", func() {
+		first, err := store.Put(context.TODO(), "platform-id", "password")
+		Expect(err).ToNot(HaveOccurred())
+		second, err := store.Put(context.TODO(), "platform-id", "rotated")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(Equal(first))
+
+		password, err := store.Get(context.TODO(), second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(password).To(Equal("rotated"))
+	})
+
+	It("reuses the access token until it expires", func() {
+		_, err := store.Put(context.TODO(), "platform-id", "password")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = store.Get(context.TODO(), "/service-manager/platforms/platform-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokenRequests).To(Equal(1))
+	})
+
+	It("fails if UAA rejects the client", func() {
+		settings := credentials.DefaultCredHubSettings()
+		settings.URL = credhub.URL
+		settings.TokenURL = credhub.URL + "/oauth/token"
+		settings.ClientID = "sm"
+		settings.ClientSecret = "wrong"
+		_, err := credentials.NewCredHubStore(settings, http.DefaultClient).Put(context.TODO(), "platform-id", "password")
+		Expect(err).To(MatchError(ContainSubstring("access token")))
+	})
+})
+
+var _ = Describe("Secret references", func() {
+	It("are distinguished from passwords", func() {
+		reference, ok := credentials.ParseReference(credentials.Reference("/service-manager/platforms/id"))
+		Expect(ok).To(BeTrue())
+		Expect(reference).To(Equal("/service-manager/platforms/id"))
+
+		_, ok = credentials.ParseReference("password")
+		Expect(ok).To(BeFalse())
+	})
+})
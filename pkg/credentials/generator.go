@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package credentials contains the generators of the credentials of new platforms and the stores of their passwords
+package credentials
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RandomGeneratorName is the credentials type of the platforms whose credentials were generated by the random generator
+const RandomGeneratorName = "random"
+
+// Generator generates the credentials of new platforms, e.g. by creating a client in an external identity provider
+type Generator interface {
+	// Name returns the credentials type which is stored with the platforms whose credentials the generator generated
+	Name() string
+
+	// Generate returns the credentials of the new platform
+	Generate(ctx context.Context, platform *types.Platform) (*types.Credentials, error)
+}
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Length  int              `mapstructure:"length" description:"number of characters of the generated usernames and passwords"`
+	Charset string           `mapstructure:"charset" description:"characters of which the generated usernames and passwords consist"`
+	CredHub *CredHubSettings `mapstructure:"credhub"`
+
+	HashPasswords         bool          `mapstructure:"hash_passwords" description:"persist salted bcrypt hashes of the platform passwords instead of the encrypted passwords"`
+	HashCost              int           `mapstructure:"hash_cost" description:"bcrypt cost of the hashes of the platform passwords"`
+	HashMigrationInterval time.Duration `mapstructure:"hash_migration_interval" description:"interval of the job which hashes the platform passwords persisted before hashing was enabled"`
+}
+
+// DefaultSettings returns default values for the random credentials generator
+func DefaultSettings() *Settings {
+	return &Settings{
+		Length:  44,
+		Charset: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",
+		CredHub: DefaultCredHubSettings(),
+
+		HashPasswords:         false,
+		HashCost:              bcrypt.DefaultCost,
+		HashMigrationInterval: time.Hour,
+	}
+}
+
+// Validate validates the random credentials generator settings
+func (s *Settings) Validate() error {
+	if s.Length < 16 {
+		return fmt.Errorf("validate Settings: credentials length must be at least 16")
+	}
+	if len(s.Charset) < 2 {
+		return fmt.Errorf("validate Settings: credentials charset must contain at least 2 characters")
+	}
+	for _, ch := range s.Charset {
+		if ch == ':' || ch > 126 || ch < 33 {
+			return fmt.Errorf("validate Settings: credentials charset must contain only printable ASCII characters except colon")
+		}
+	}
+	if s.HashPasswords {
+		if s.HashCost < bcrypt.MinCost || s.HashCost > bcrypt.MaxCost {
+			return fmt.Errorf("validate Settings: credentials hash cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		if s.HashMigrationInterval <= 0 {
+			return fmt.Errorf("validate Settings: credentials hash migration interval must be positive")
+		}
+		if s.CredHub.URL != "" {
+			return fmt.Errorf("validate Settings: credentials cannot be hashed when stored in credhub")
+		}
+	}
+	return s.CredHub.Validate()
+}
+
+// RandomGenerator generates usernames and passwords of random characters of the configured charset
+type RandomGenerator struct {
+	settings *Settings
+}
+
+// NewRandomGenerator returns a random credentials generator
+func NewRandomGenerator(settings *Settings) *RandomGenerator {
+	return &RandomGenerator{
+		settings: settings,
+	}
+}
+
+// Name implements Generator
+func (g *RandomGenerator) Name() string {
+	return RandomGeneratorName
+}
+
+// Generate implements Generator
+func (g *RandomGenerator) Generate(ctx context.Context, platform *types.Platform) (*types.Credentials, error) {
+	username, err := g.random()
+	if err != nil {
+		return nil, err
+	}
+	password, err := g.random()
+	if err != nil {
+		return nil, err
+	}
+	return &types.Credentials{
+		Basic: &types.Basic{
+			Username: username,
+			Password: password,
+		},
+	}, nil
+}
+
+func (g *RandomGenerator) random() (string, error) {
+	charsetLength := big.NewInt(int64(len(g.settings.Charset)))
+	result := make([]byte, g.settings.Length)
+	for i := range result {
+		index, err := rand.Int(rand.Reader, charsetLength)
+		if err != nil {
+			return "", err
+		}
+		result[i] = g.settings.Charset[index.Int64()]
+	}
+	return string(result), nil
+}
+
+// Provider provides the generator of the credentials of new platforms and the store of their passwords. Both can
+// be replaced until the Service Manager is run.
+type Provider struct {
+	mutex       sync.RWMutex
+	generator   Generator
+	secretStore SecretStore
+}
+
+// NewProvider returns a provider of the specified generator
+func NewProvider(generator Generator) *Provider {
+	return &Provider{
+		generator: generator,
+	}
+}
+
+// Use replaces the generator
+func (p *Provider) Use(generator Generator) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.generator = generator
+}
+
+// Generator returns the generator
+func (p *Provider) Generator() Generator {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.generator
+}
+
+// UseSecretStore replaces the secret store, passwords are persisted by the Service Manager if it is nil
+func (p *Provider) UseSecretStore(store SecretStore) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.secretStore = store
+}
+
+// SecretStore returns the secret store or nil if the passwords are persisted by the Service Manager
+func (p *Provider) SecretStore() SecretStore {
+	if p == nil {
+		return nil
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.secretStore
+}
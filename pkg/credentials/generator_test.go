@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package credentials_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type staticGenerator struct{}
+
+func (staticGenerator) Name() string {
+	return "static"
+}
+
+func (staticGenerator) Generate(ctx context.Context, platform *types.Platform) (*types.Credentials, error) {
+	return &types.Credentials{Basic: &types.Basic{Username: platform.Name, Password: "password"}}, nil
+}
+
+var _ = Describe("Credentials generators", func() {
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(credentials.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid with short credentials", func() {
+			settings := credentials.DefaultSettings()
+			settings.Length = 8
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("are invalid with hashing and a secret store", func() {
+			settings := credentials.DefaultSettings()
+			settings.HashPasswords = true
+			settings.CredHub.URL = "https://credhub.example.com"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("are invalid with a charset containing a colon", func() {
+			settings := credentials.DefaultSettings()
+			settings.Charset = "abc:"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("RandomGenerator", func() {
+		It("generates credentials of the configured length and charset", func() {
+			settings := &credentials.Settings{Length: 20, Charset: "ab"}
+			generated, err := credentials.NewRandomGenerator(settings).Generate(context.TODO(), &types.Platform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(generated.Basic.Username).To(MatchRegexp("^[ab]{20}$"))
+			Expect(generated.Basic.Password).To(MatchRegexp("^[ab]{20}$"))
+		})
+
+		It("generates different credentials each time", func() {
+			generator := credentials.NewRandomGenerator(credentials.DefaultSettings())
+			first, err := generator.Generate(context.TODO(), &types.Platform{})
+			Expect(err).ToNot(HaveOccurred())
+			second, err := generator.Generate(context.TODO(), &types.Platform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first.Basic.Password).ToNot(Equal(second.Basic.Password))
+			Expect(first.Basic.Username).ToNot(Equal(first.Basic.Password))
+		})
+	})
+
+	Describe("Provider", func() {
+		It("provides the generator it uses", func() {
+			provider := credentials.NewProvider(credentials.NewRandomGenerator(credentials.DefaultSettings()))
+			Expect(provider.Generator().Name()).To(Equal(credentials.RandomGeneratorName))
+
+			provider.Use(staticGenerator{})
+			generated, err := provider.Generator().Generate(context.TODO(), &types.Platform{Name: "platform"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(generated.Basic.Username).To(Equal("platform"))
+		})
+	})
+})
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package credentials
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPrefix marks the platform passwords persisted by the Service Manager which are bcrypt hashes of the passwords
+const hashPrefix = "bcrypt:"
+
+// Hash returns the value persisted as password of a platform whose password is stored as a salted bcrypt hash of
+// the specified cost
+func Hash(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return hashPrefix + string(hash), nil
+}
+
+// IsHash returns true if the persisted password is a hash of the password
+func IsHash(password string) bool {
+	return strings.HasPrefix(password, hashPrefix)
+}
+
+// Verify returns true if the password matches the persisted one, which is either a hash of the password or, for
+// platforms whose password is not hashed yet, the password itself
+func Verify(persisted, password string) bool {
+	if IsHash(persisted) {
+		return bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(persisted, hashPrefix)), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(persisted), []byte(password)) == 1
+}
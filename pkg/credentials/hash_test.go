@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package credentials_test
+
+import (
+	"github.com/Peripli/service-manager/pkg/credentials"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Password hashes", func() {
+	It("verify the hashed password", func() {
+		hash, err := credentials.Hash("password", 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials.IsHash(hash)).To(BeTrue())
+		Expect(hash).ToNot(ContainSubstring("password"))
+		Expect(credentials.Verify(hash, "password")).To(BeTrue())
+		Expect(credentials.Verify(hash, "other")).To(BeFalse())
+	})
+
+	It("are salted", func() {
+		first, err := credentials.Hash("password", 4)
+		Expect(err).ToNot(HaveOccurred())
+		second, err := credentials.Hash("password", 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first).ToNot(Equal(second))
+	})
+
+	It("verify passwords persisted before hashing was enabled", func() {
+		Expect(credentials.IsHash("password")).To(BeFalse())
+		Expect(credentials.Verify("password", "password")).To(BeTrue())
+		Expect(credentials.Verify("password", "other")).To(BeFalse())
+	})
+})
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package credentials
+
+import (
+	"context"
+	"strings"
+)
+
+// referencePrefix marks the platform passwords persisted by the Service Manager which are references to passwords
+// stored in the secret store
+const referencePrefix = "secret-ref:"
+
+// SecretStore stores the passwords of platforms outside of the Service Manager, e.g. in CredHub. The Service Manager
+// persists only the references returned by the store.
+type SecretStore interface {
+	// Name returns the name of the secret store
+	Name() string
+
+	// Put stores the password of the platform and returns its reference. Storing a password for the same platform
+	// again replaces the previous password.
+	Put(ctx context.Context, platformID, password string) (string, error)
+
+	// Get returns the password stored under the reference
+	Get(ctx context.Context, reference string) (string, error)
+
+	// Delete deletes the password stored under the reference
+	Delete(ctx context.Context, reference string) error
+}
+
+// Reference returns the value persisted as password of a platform whose password is stored under the reference
+func Reference(reference string) string {
+	return referencePrefix + reference
+}
+
+// ParseReference returns the reference of the password in the secret store if the persisted password is a reference
+func ParseReference(password string) (string, bool) {
+	if !strings.HasPrefix(password, referencePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(password, referencePrefix), true
+}
@@ -90,16 +90,21 @@ func CreatePFlags(set *pflag.FlagSet, value interface{}) {
 // New creates a new environment. It accepts a flag set that should contain all the flags that the
 // environment should be aware of.
 func New(set *pflag.FlagSet) (*ViperEnv, error) {
+	if err := set.Parse(os.Args[1:]); err != nil {
+		return nil, err
+	}
+
+	return NewForParsedFlags(set)
+}
+
+// NewForParsedFlags creates a new environment from a flag set that has already been parsed (e.g. by a CLI framework).
+func NewForParsedFlags(set *pflag.FlagSet) (*ViperEnv, error) {
 	v := &ViperEnv{
 		Viper: viper.New(),
 	}
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
-	if err := set.Parse(os.Args[1:]); err != nil {
-		return nil, err
-	}
-
 	set.VisitAll(func(flag *pflag.Flag) {
 		if err := v.BindPFlag(flag.Name, flag); err != nil {
 			log.D().Panic(err)
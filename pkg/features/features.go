@@ -0,0 +1,237 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package features contains logic for toggling Service Manager features on and off
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled         []string      `mapstructure:"enabled" description:"names of features that should be enabled regardless of their default state"`
+	Disabled        []string      `mapstructure:"disabled" description:"names of features that should be disabled regardless of their default state"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" description:"interval in which the feature states set through the features API are read from the cache store"`
+	AdminScope      string        `mapstructure:"admin_scope" description:"scope required for changing the feature states through the features API"`
+}
+
+// DefaultSettings returns default values for feature settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:         []string{},
+		Disabled:        []string{},
+		RefreshInterval: 10 * time.Second,
+		AdminScope:      "sm.admin",
+	}
+}
+
+// Validate validates the feature settings
+func (s *Settings) Validate() error {
+	if s.RefreshInterval <= 0 {
+		return fmt.Errorf("validate Settings: feature refresh interval (%s) should be greater than 0", s.RefreshInterval)
+	}
+	if len(s.AdminScope) == 0 {
+		return fmt.Errorf("validate Settings: feature admin scope missing")
+	}
+	for _, enabled := range s.Enabled {
+		for _, disabled := range s.Disabled {
+			if enabled == disabled {
+				return fmt.Errorf("validate Settings: feature %s cannot be both enabled and disabled", enabled)
+			}
+		}
+	}
+	return nil
+}
+
+// Flag describes a feature that can be toggled
+type Flag struct {
+	// Name is the unique identifier of the feature
+	Name string `json:"name"`
+
+	// Description is a human readable explanation of what the feature does
+	Description string `json:"description,omitempty"`
+
+	// Default is the state of the feature when neither the settings nor any source specify otherwise
+	Default bool `json:"default"`
+}
+
+// State represents the current state of a registered feature flag
+type State struct {
+	Flag
+
+	Enabled bool   `json:"enabled"`
+	Origin  string `json:"origin"`
+}
+
+// Source provides feature states that may change at runtime (e.g. a remote configuration source)
+type Source interface {
+	// Name returns the name of the source
+	Name() string
+
+	// State returns whether the feature with the given name is enabled and whether the source knows the feature at all
+	State(feature string) (enabled bool, found bool)
+}
+
+const (
+	originDefault  = "default"
+	originSettings = "settings"
+)
+
+// Manager keeps track of the registered feature flags and resolves their current state. Sources take precedence
+// over the settings and the settings take precedence over the flag defaults.
+type Manager struct {
+	mutex    sync.RWMutex
+	flags    map[string]Flag
+	settings *Settings
+	sources  []Source
+}
+
+// NewManager returns a new feature flags manager for the provided settings and sources
+func NewManager(settings *Settings, sources ...Source) *Manager {
+	if settings == nil {
+		settings = DefaultSettings()
+	}
+	return &Manager{
+		flags:    make(map[string]Flag),
+		settings: settings,
+		sources:  sources,
+	}
+}
+
+// Register registers the given feature flags. Registering a flag with an already registered name overrides it.
+func (m *Manager) Register(flags ...Flag) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, flag := range flags {
+		m.flags[flag.Name] = flag
+	}
+}
+
+// AddSource adds a source that is consulted before the ones already added
+func (m *Manager) AddSource(source Source) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sources = append([]Source{source}, m.sources...)
+}
+
+// AdminScope returns the scope required for changing the feature states at runtime
+func (m *Manager) AdminScope() string {
+	return m.settings.AdminScope
+}
+
+// Flag returns the registered feature flag with the given name and whether it is registered
+func (m *Manager) Flag(feature string) (Flag, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	flag, found := m.flags[feature]
+	return flag, found
+}
+
+// Names returns the names of the registered feature flags
+func (m *Manager) Names() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	names := make([]string, 0, len(m.flags))
+	for name := range m.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsEnabled returns whether the feature with the given name is enabled. Unregistered features are considered disabled.
+func (m *Manager) IsEnabled(feature string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	flag, found := m.flags[feature]
+	if !found {
+		return false
+	}
+	enabled, _ := m.resolve(flag)
+	return enabled
+}
+
+// States returns the current states of all registered feature flags ordered by name
+func (m *Manager) States() []State {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	states := make([]State, 0, len(m.flags))
+	for _, flag := range m.flags {
+		enabled, origin := m.resolve(flag)
+		states = append(states, State{
+			Flag:    flag,
+			Enabled: enabled,
+			Origin:  origin,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+func (m *Manager) resolve(flag Flag) (bool, string) {
+	for _, source := range m.sources {
+		if enabled, found := source.State(flag.Name); found {
+			return enabled, source.Name()
+		}
+	}
+	for _, name := range m.settings.Enabled {
+		if name == flag.Name {
+			return true, originSettings
+		}
+	}
+	for _, name := range m.settings.Disabled {
+		if name == flag.Name {
+			return false, originSettings
+		}
+	}
+	return flag.Default, originDefault
+}
+
+type contextKey struct{}
+
+// ContextWithManager returns a context which carries the provided feature flags manager
+func ContextWithManager(ctx context.Context, manager *Manager) context.Context {
+	return context.WithValue(ctx, contextKey{}, manager)
+}
+
+// ManagerFromContext returns the feature flags manager stored in the context, if any
+func ManagerFromContext(ctx context.Context) (*Manager, bool) {
+	manager, ok := ctx.Value(contextKey{}).(*Manager)
+	return manager, ok
+}
+
+// IsEnabled returns whether the feature with the given name is enabled according to the manager in the context.
+// If the context contains no manager the feature is considered disabled.
+func IsEnabled(ctx context.Context, feature string) bool {
+	manager, ok := ManagerFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return manager.IsEnabled(feature)
+}
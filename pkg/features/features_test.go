@@ -0,0 +1,210 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package features_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/features"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type staticSource map[string]bool
+
+func (s staticSource) Name() string {
+	return "static"
+}
+
+func (s staticSource) State(feature string) (bool, bool) {
+	enabled, found := s[feature]
+	return enabled, found
+}
+
+var _ = Describe("Features", func() {
+	var (
+		settings *features.Settings
+		manager  *features.Manager
+	)
+
+	BeforeEach(func() {
+		settings = features.DefaultSettings()
+		manager = features.NewManager(settings)
+		manager.Register(
+			features.Flag{Name: "on_by_default", Default: true},
+			features.Flag{Name: "off_by_default", Default: false},
+		)
+	})
+
+	Describe("IsEnabled", func() {
+		Context("when feature is not registered", func() {
+			It("returns false", func() {
+				Expect(manager.IsEnabled("unknown")).To(BeFalse())
+			})
+		})
+
+		Context("when nothing overrides the default", func() {
+			It("returns the default", func() {
+				Expect(manager.IsEnabled("on_by_default")).To(BeTrue())
+				Expect(manager.IsEnabled("off_by_default")).To(BeFalse())
+			})
+		})
+
+		Context("when settings override the default", func() {
+			It("returns the state from the settings", func() {
+				settings.Enabled = []string{"off_by_default"}
+				settings.Disabled = []string{"on_by_default"}
+
+				Expect(manager.IsEnabled("on_by_default")).To(BeFalse())
+				Expect(manager.IsEnabled("off_by_default")).To(BeTrue())
+			})
+		})
+
+		Context("when a source knows the feature", func() {
+			It("takes precedence over the settings", func() {
+				settings.Enabled = []string{"off_by_default"}
+				manager.AddSource(staticSource{"off_by_default": false})
+
+				Expect(manager.IsEnabled("off_by_default")).To(BeFalse())
+			})
+		})
+
+		Context("when multiple sources know the feature", func() {
+			It("uses the last added source", func() {
+				manager.AddSource(staticSource{"off_by_default": false})
+				manager.AddSource(staticSource{"off_by_default": true})
+
+				Expect(manager.IsEnabled("off_by_default")).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("States", func() {
+		It("returns all registered flags ordered by name with their origin", func() {
+			settings.Enabled = []string{"off_by_default"}
+
+			states := manager.States()
+			Expect(states).To(HaveLen(2))
+			Expect(states[0].Name).To(Equal("off_by_default"))
+			Expect(states[0].Enabled).To(BeTrue())
+			Expect(states[0].Origin).To(Equal("settings"))
+			Expect(states[1].Name).To(Equal("on_by_default"))
+			Expect(states[1].Enabled).To(BeTrue())
+			Expect(states[1].Origin).To(Equal("default"))
+		})
+	})
+
+	Describe("StoreSource", func() {
+		var (
+			store  *cache.MemoryStore
+			source *features.StoreSource
+		)
+
+		BeforeEach(func() {
+			store = cache.NewMemoryStore()
+			source = features.NewStoreSource(store)
+			manager.AddSource(source)
+		})
+
+		It("overrides the settings with the stored state", func() {
+			settings.Enabled = []string{"off_by_default"}
+			Expect(source.Set(context.Background(), "off_by_default", false)).To(Succeed())
+
+			Expect(manager.IsEnabled("off_by_default")).To(BeFalse())
+			Expect(manager.States()[0].Origin).To(Equal("store"))
+		})
+
+		It("resolves the state from the settings again when it is reset", func() {
+			settings.Enabled = []string{"off_by_default"}
+			Expect(source.Set(context.Background(), "off_by_default", false)).To(Succeed())
+			Expect(source.Reset(context.Background(), "off_by_default")).To(Succeed())
+
+			Expect(manager.IsEnabled("off_by_default")).To(BeTrue())
+		})
+
+		It("reads the states stored by other instances when refreshed", func() {
+			other := features.NewStoreSource(store)
+			Expect(other.Set(context.Background(), "on_by_default", false)).To(Succeed())
+			Expect(manager.IsEnabled("on_by_default")).To(BeTrue())
+
+			Expect(source.Refresh(context.Background(), manager.Names()...)).To(Succeed())
+			Expect(manager.IsEnabled("on_by_default")).To(BeFalse())
+
+			Expect(other.Reset(context.Background(), "on_by_default")).To(Succeed())
+			Expect(source.Refresh(context.Background(), manager.Names()...)).To(Succeed())
+			Expect(manager.IsEnabled("on_by_default")).To(BeTrue())
+		})
+
+		It("refreshes the states of the registered features periodically", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			wg := &sync.WaitGroup{}
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+			source.Start(ctx, manager, 10*time.Millisecond, wg)
+
+			Expect(features.NewStoreSource(store).Set(context.Background(), "on_by_default", false)).To(Succeed())
+			Eventually(func() bool { return manager.IsEnabled("on_by_default") }).Should(BeFalse())
+		})
+	})
+
+	Describe("Flags", func() {
+		It("returns the registered flags", func() {
+			Expect(manager.Names()).To(Equal([]string{"off_by_default", "on_by_default"}))
+			flag, found := manager.Flag("on_by_default")
+			Expect(found).To(BeTrue())
+			Expect(flag.Default).To(BeTrue())
+			_, found = manager.Flag("unknown")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("Settings", func() {
+		It("require a positive refresh interval", func() {
+			settings.RefreshInterval = 0
+
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+
+		It("fails validation if a feature is both enabled and disabled", func() {
+			settings.Enabled = []string{"feature"}
+			settings.Disabled = []string{"feature"}
+
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("Context", func() {
+		Context("when context has no manager", func() {
+			It("considers features disabled", func() {
+				Expect(features.IsEnabled(context.Background(), "on_by_default")).To(BeFalse())
+			})
+		})
+
+		Context("when context has a manager", func() {
+			It("uses the manager", func() {
+				ctx := features.ContextWithManager(context.Background(), manager)
+				Expect(features.IsEnabled(ctx, "on_by_default")).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package features
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const storeKeyPrefix = "features:"
+
+// StoreSource is a Source which keeps the feature states set at runtime through the features API in a cache store.
+// If the store is shared between the instances (e.g. Redis) the states apply to all of them. The states are resolved
+// on every request, so they are read from the store periodically instead of on every lookup.
+type StoreSource struct {
+	store cache.Store
+
+	mutex  sync.RWMutex
+	states map[string]bool
+}
+
+// NewStoreSource returns a StoreSource which keeps the feature states in the provided store
+func NewStoreSource(store cache.Store) *StoreSource {
+	return &StoreSource{
+		store:  store,
+		states: make(map[string]bool),
+	}
+}
+
+// Name implements Source and returns the name of the source
+func (s *StoreSource) Name() string {
+	return "store"
+}
+
+// State implements Source and returns the state of the feature last read from the store
+func (s *StoreSource) State(feature string) (bool, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	enabled, found := s.states[feature]
+	return enabled, found
+}
+
+// Set stores the state of the feature, overriding the settings and the default of the feature
+func (s *StoreSource) Set(ctx context.Context, feature string, enabled bool) error {
+	if err := s.store.Set(ctx, storeKeyPrefix+feature, []byte(strconv.FormatBool(enabled)), 0); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[feature] = enabled
+	return nil
+}
+
+// Reset removes the stored state of the feature, so that it is resolved from the settings and its default again
+func (s *StoreSource) Reset(ctx context.Context, feature string) error {
+	if err := s.store.Delete(ctx, storeKeyPrefix+feature); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.states, feature)
+	return nil
+}
+
+// Refresh reads the stored states of the provided features
+func (s *StoreSource) Refresh(ctx context.Context, features ...string) error {
+	states := make(map[string]bool)
+	for _, feature := range features {
+		value, found, err := s.store.Get(ctx, storeKeyPrefix+feature)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		enabled, err := strconv.ParseBool(string(value))
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Ignoring invalid stored state of feature %s", feature)
+			continue
+		}
+		states[feature] = enabled
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states = states
+	return nil
+}
+
+// Start refreshes the stored states of the features registered in the manager periodically until the context is done
+func (s *StoreSource) Start(ctx context.Context, manager *Manager, interval time.Duration, group *sync.WaitGroup) {
+	util.StartInWaitGroupWithContext(ctx, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Refresh(ctx, manager.Names()...); err != nil {
+				log.C(ctx).WithError(err).Warn("Could not refresh the stored feature states")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}, group)
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package federation contains logic for importing the brokers of peer Service Manager instances.
+// An imported broker points to the OSB API of its peer, so that OSB calls are proxied to the owning instance.
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/client"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// JobName is the name under which the peer import job is registered
+	JobName = "federation_import"
+
+	// PeerIDLabel is the label of an imported broker which contains the ID of the peer owning the broker
+	PeerIDLabel = "federation_peer_id"
+
+	// PeerNameLabel is the label of an imported broker which contains the name of the peer owning the broker
+	PeerNameLabel = "federation_peer_name"
+
+	// BrokerIDLabel is the label of an imported broker which contains the ID of the broker in its peer
+	BrokerIDLabel = "federation_broker_id"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled        bool          `mapstructure:"enabled" description:"whether peer Service Manager instances can be registered and their brokers are imported"`
+	ImportInterval time.Duration `mapstructure:"import_interval" description:"time between two imports of the brokers of the registered peers"`
+}
+
+// DefaultSettings returns default values for federation settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:        false,
+		ImportInterval: 5 * time.Minute,
+	}
+}
+
+// Validate validates the federation settings
+func (s *Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.ImportInterval <= 0 {
+		return fmt.Errorf("validate Settings: federation import interval must be > 0")
+	}
+	return nil
+}
+
+// BrokerLister lists the brokers registered in a peer
+type BrokerLister interface {
+	ListBrokers(ctx context.Context, criteria ...query.Criterion) ([]*types.ServiceBroker, error)
+}
+
+// ClientFactory returns a broker lister for the provided peer
+type ClientFactory func(peer *types.Peer) (BrokerLister, error)
+
+// NewClient is the default client factory which uses the Service Manager client with the peer credentials
+func NewClient(peer *types.Peer) (BrokerLister, error) {
+	return NewClientFactory(http.DefaultClient)(peer)
+}
+
+// NewClientFactory returns a client factory like NewClient which calls the peers with the HTTP client
+func NewClientFactory(httpClient *http.Client) ClientFactory {
+	return func(peer *types.Peer) (BrokerLister, error) {
+		settings := client.DefaultSettings()
+		settings.URL = peer.URL
+		if peer.Credentials != nil && peer.Credentials.Basic != nil {
+			settings.User = peer.Credentials.Basic.Username
+			settings.Password = peer.Credentials.Basic.Password
+		}
+		return client.NewWithHTTPClient(settings, httpClient)
+	}
+}
+
+// Job is a background job which imports the brokers of the registered peers. An imported broker is named after its
+// peer and broker, points to the OSB API of the peer and uses the peer credentials, so OSB calls are proxied
+// to the peer. Imported brokers which no longer exist in their peer or whose peer is deleted are deleted.
+type Job struct {
+	repository storage.Repository
+	newClient  ClientFactory
+}
+
+// NewJob returns a peer import job which uses the provided factory to list the brokers of the peers
+func NewJob(repository storage.Repository, newClient ClientFactory) *Job {
+	return &Job{
+		repository: repository,
+		newClient:  newClient,
+	}
+}
+
+// Name implements jobs.Job
+func (j *Job) Name() string {
+	return JobName
+}
+
+// Run implements jobs.Job and imports the brokers of all peers
+func (j *Job) Run(ctx context.Context) error {
+	peers, err := j.repository.List(ctx, types.PeerType)
+	if err != nil {
+		return fmt.Errorf("could not list peers: %s", err)
+	}
+	brokers, err := j.repository.List(ctx, types.ServiceBrokerType)
+	if err != nil {
+		return fmt.Errorf("could not list brokers: %s", err)
+	}
+
+	// imported brokers by peer ID and the ID of the broker in the peer
+	imported := make(map[string]map[string]*types.ServiceBroker)
+	for i := 0; i < brokers.Len(); i++ {
+		broker := brokers.ItemAt(i).(*types.ServiceBroker)
+		peerID, remoteID := labelValue(broker.Labels, PeerIDLabel), labelValue(broker.Labels, BrokerIDLabel)
+		if peerID == "" {
+			continue
+		}
+		if imported[peerID] == nil {
+			imported[peerID] = make(map[string]*types.ServiceBroker)
+		}
+		imported[peerID][remoteID] = broker
+	}
+
+	failed := make([]string, 0)
+	for i := 0; i < peers.Len(); i++ {
+		peer := peers.ItemAt(i).(*types.Peer)
+		if err := j.importPeer(ctx, peer, imported[peer.ID]); err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not import brokers of peer %s", peer.Name)
+			failed = append(failed, peer.Name)
+		}
+		delete(imported, peer.ID)
+	}
+
+	// the remaining brokers belong to deleted peers
+	for _, orphans := range imported {
+		for _, broker := range orphans {
+			if err := j.deleteBroker(ctx, broker); err != nil {
+				failed = append(failed, broker.Name)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("could not import brokers of %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (j *Job) importPeer(ctx context.Context, peer *types.Peer, imported map[string]*types.ServiceBroker) error {
+	peerClient, err := j.newClient(peer)
+	if err != nil {
+		return err
+	}
+	remoteBrokers, err := peerClient.ListBrokers(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(remoteBrokers))
+	for _, remoteBroker := range remoteBrokers {
+		// brokers which the peer imported itself are owned by another instance
+		if labelValue(remoteBroker.Labels, PeerIDLabel) != "" {
+			continue
+		}
+		seen[remoteBroker.ID] = true
+
+		desired := importedBroker(peer, remoteBroker)
+		current, found := imported[remoteBroker.ID]
+		if !found {
+			err = j.createBroker(ctx, desired)
+		} else {
+			err = j.updateBroker(ctx, current, desired)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for remoteID, broker := range imported {
+		if !seen[remoteID] {
+			if err := j.deleteBroker(ctx, broker); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (j *Job) createBroker(ctx context.Context, broker *types.ServiceBroker) error {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID: %s", err)
+	}
+	broker.SetID(UUID.String())
+	currentTime := time.Now().UTC()
+	broker.SetCreatedAt(currentTime)
+	broker.SetUpdatedAt(currentTime)
+
+	log.C(ctx).Infof("Importing broker %s from peer %s", broker.Name, labelValue(broker.Labels, PeerNameLabel))
+	_, err = j.repository.Create(ctx, broker)
+	if err == util.ErrAlreadyExistsInStorage {
+		return fmt.Errorf("imported broker %s conflicts with an existing broker", broker.Name)
+	}
+	return err
+}
+
+func (j *Job) updateBroker(ctx context.Context, current, desired *types.ServiceBroker) error {
+	if current.Name == desired.Name && current.Description == desired.Description &&
+		current.BrokerURL == desired.BrokerURL && sameCredentials(current.Credentials, desired.Credentials) {
+		return nil
+	}
+	current.Name = desired.Name
+	current.Description = desired.Description
+	current.BrokerURL = desired.BrokerURL
+	current.Credentials = desired.Credentials
+
+	log.C(ctx).Infof("Updating imported broker %s", current.Name)
+	_, err := j.repository.Update(ctx, current)
+	return err
+}
+
+func (j *Job) deleteBroker(ctx context.Context, broker *types.ServiceBroker) error {
+	log.C(ctx).Infof("Deleting imported broker %s", broker.Name)
+	_, err := j.repository.Delete(ctx, types.ServiceBrokerType, query.ByField(query.EqualsOperator, "id", broker.ID))
+	if err != nil && err != util.ErrNotFoundInStorage {
+		log.C(ctx).WithError(err).Errorf("Could not delete imported broker %s", broker.Name)
+		return err
+	}
+	return nil
+}
+
+// importedBroker returns the local representation of a broker of the peer
+func importedBroker(peer *types.Peer, remoteBroker *types.ServiceBroker) *types.ServiceBroker {
+	return &types.ServiceBroker{
+		Base: types.Base{
+			Labels: types.Labels{
+				PeerIDLabel:   {peer.ID},
+				PeerNameLabel: {peer.Name},
+				BrokerIDLabel: {remoteBroker.ID},
+			},
+		},
+		Name:        peer.Name + "-" + remoteBroker.Name,
+		Description: remoteBroker.Description,
+		BrokerURL:   strings.TrimSuffix(peer.URL, "/") + web.OSBURL + "/" + remoteBroker.ID,
+		Credentials: peer.Credentials,
+	}
+}
+
+func sameCredentials(current, desired *types.Credentials) bool {
+	if current == nil || desired == nil {
+		return current == desired
+	}
+	if current.Basic == nil || desired.Basic == nil {
+		return current.Basic == desired.Basic
+	}
+	return *current.Basic == *desired.Basic
+}
+
+func labelValue(labels types.Labels, key string) string {
+	if values := labels[key]; len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package federation_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/federation"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeLister struct {
+	brokers []*types.ServiceBroker
+	err     error
+}
+
+func (l *fakeLister) ListBrokers(ctx context.Context, criteria ...query.Criterion) ([]*types.ServiceBroker, error) {
+	return l.brokers, l.err
+}
+
+var _ = Describe("Federation", func() {
+	var (
+		ctx          context.Context
+		repository   *storagefakes.FakeStorage
+		peer         *types.Peer
+		localBrokers []*types.ServiceBroker
+		lister       *fakeLister
+		job          *federation.Job
+	)
+
+	imported := func(name, remoteID string) *types.ServiceBroker {
+		return &types.ServiceBroker{
+			Base: types.Base{
+				ID: "local-" + remoteID,
+				Labels: types.Labels{
+					federation.PeerIDLabel:   {peer.ID},
+					federation.PeerNameLabel: {peer.Name},
+					federation.BrokerIDLabel: {remoteID},
+				},
+			},
+			Name:        peer.Name + "-" + name,
+			BrokerURL:   peer.URL + web.OSBURL + "/" + remoteID,
+			Credentials: peer.Credentials,
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		peer = &types.Peer{
+			Base: types.Base{ID: "peer-id"},
+			Name: "eu",
+			URL:  "https://sm.eu.example.com",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "user", Password: "password"},
+			},
+		}
+		localBrokers = []*types.ServiceBroker{}
+		lister = &fakeLister{}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.PeerType {
+				return &types.Peers{Peers: []*types.Peer{peer}}, nil
+			}
+			return &types.ServiceBrokers{ServiceBrokers: localBrokers}, nil
+		})
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			return obj, nil
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+			return obj, nil
+		})
+
+		job = federation.NewJob(repository, func(p *types.Peer) (federation.BrokerLister, error) {
+			return lister, nil
+		})
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(federation.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid when enabled with a non positive import interval", func() {
+			settings := federation.DefaultSettings()
+			settings.Enabled = true
+			settings.ImportInterval = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("Import job", func() {
+		Context("when the peer has a new broker", func() {
+			It("imports the broker with provenance labels and the OSB API of the peer", func() {
+				lister.brokers = []*types.ServiceBroker{{Base: types.Base{ID: "remote-id"}, Name: "broker", Description: "desc"}}
+
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.CreateCallCount()).To(Equal(1))
+				_, obj := repository.CreateArgsForCall(0)
+				broker := obj.(*types.ServiceBroker)
+				Expect(broker.ID).ToNot(BeEmpty())
+				Expect(broker.Name).To(Equal("eu-broker"))
+				Expect(broker.Description).To(Equal("desc"))
+				Expect(broker.BrokerURL).To(Equal("https://sm.eu.example.com/v1/osb/remote-id"))
+				Expect(broker.Credentials).To(Equal(peer.Credentials))
+				Expect(broker.Labels).To(HaveKeyWithValue(federation.PeerIDLabel, []string{"peer-id"}))
+				Expect(broker.Labels).To(HaveKeyWithValue(federation.PeerNameLabel, []string{"eu"}))
+				Expect(broker.Labels).To(HaveKeyWithValue(federation.BrokerIDLabel, []string{"remote-id"}))
+			})
+		})
+
+		Context("when the peer imported the broker itself", func() {
+			It("does not import the broker", func() {
+				lister.brokers = []*types.ServiceBroker{{
+					Base: types.Base{ID: "remote-id", Labels: types.Labels{federation.PeerIDLabel: {"other-peer"}}},
+					Name: "broker",
+				}}
+
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the broker is already imported", func() {
+			BeforeEach(func() {
+				localBrokers = append(localBrokers, imported("broker", "remote-id"))
+			})
+
+			It("does not update the broker if nothing has changed", func() {
+				lister.brokers = []*types.ServiceBroker{{Base: types.Base{ID: "remote-id"}, Name: "broker"}}
+
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.CreateCallCount()).To(Equal(0))
+				Expect(repository.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("updates the broker if it was renamed in the peer", func() {
+				lister.brokers = []*types.ServiceBroker{{Base: types.Base{ID: "remote-id"}, Name: "renamed"}}
+
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.UpdateCallCount()).To(Equal(1))
+				_, obj, _ := repository.UpdateArgsForCall(0)
+				Expect(obj.(*types.ServiceBroker).Name).To(Equal("eu-renamed"))
+			})
+
+			It("deletes the broker if it no longer exists in the peer", func() {
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.DeleteCallCount()).To(Equal(1))
+				_, objectType, criteria := repository.DeleteArgsForCall(0)
+				Expect(objectType).To(Equal(types.ServiceBrokerType))
+				Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "id", "local-remote-id")))
+			})
+
+			It("keeps the broker if the peer cannot be reached", func() {
+				lister.err = errors.New("connection refused")
+
+				Expect(job.Run(ctx)).To(HaveOccurred())
+				Expect(repository.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("deletes the broker if its peer is deleted", func() {
+				peer.ID = "another-peer-id"
+
+				Expect(job.Run(ctx)).To(Succeed())
+				Expect(repository.DeleteCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("Read only interceptor", func() {
+		var (
+			userCtx context.Context
+			broker  *types.ServiceBroker
+		)
+
+		BeforeEach(func() {
+			userCtx = web.ContextWithUser(ctx, &web.UserContext{Name: "admin"})
+			broker = imported("broker", "remote-id")
+		})
+
+		expectStatus := func(err error, statusCode int) {
+			Expect(err).To(HaveOccurred())
+			httpErr, ok := err.(*util.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.StatusCode).To(Equal(statusCode))
+		}
+
+		It("forbids users to update imported brokers", func() {
+			update := (&federation.ReadOnlyBrokersUpdateInterceptorProvider{}).Provide().OnTxUpdate(
+				func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+					return newObj, nil
+				})
+
+			_, err := update(userCtx, repository, broker, broker)
+			expectStatus(err, http.StatusForbidden)
+
+			_, err = update(ctx, repository, broker, broker)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("forbids users to delete imported brokers", func() {
+			deleteBrokers := (&federation.ReadOnlyBrokersDeleteInterceptorProvider{}).Provide().OnTxDelete(
+				func(ctx context.Context, txStorage storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+					return objects, nil
+				})
+
+			_, err := deleteBrokers(userCtx, repository, &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker}})
+			expectStatus(err, http.StatusForbidden)
+		})
+
+		It("forbids users to create brokers with the provenance labels", func() {
+			create := (&federation.ReadOnlyBrokersCreateInterceptorProvider{}).Provide().AroundTxCreate(
+				func(ctx context.Context, obj types.Object) (types.Object, error) {
+					return obj, nil
+				})
+
+			_, err := create(userCtx, broker)
+			expectStatus(err, http.StatusBadRequest)
+		})
+	})
+})
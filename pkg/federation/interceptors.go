@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	ReadOnlyBrokersCreateInterceptorName = "FederationReadOnlyBrokersCreateInterceptor"
+	ReadOnlyBrokersUpdateInterceptorName = "FederationReadOnlyBrokersUpdateInterceptor"
+	ReadOnlyBrokersDeleteInterceptorName = "FederationReadOnlyBrokersDeleteInterceptor"
+)
+
+// ReadOnlyBrokersCreateInterceptorProvider provides an interceptor which forbids users to create brokers
+// with the federation labels
+type ReadOnlyBrokersCreateInterceptorProvider struct {
+}
+
+func (*ReadOnlyBrokersCreateInterceptorProvider) Name() string {
+	return ReadOnlyBrokersCreateInterceptorName
+}
+
+func (*ReadOnlyBrokersCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &readOnlyBrokersInterceptor{}
+}
+
+// ReadOnlyBrokersUpdateInterceptorProvider provides an interceptor which forbids users to update imported brokers
+type ReadOnlyBrokersUpdateInterceptorProvider struct {
+}
+
+func (*ReadOnlyBrokersUpdateInterceptorProvider) Name() string {
+	return ReadOnlyBrokersUpdateInterceptorName
+}
+
+func (*ReadOnlyBrokersUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &readOnlyBrokersInterceptor{}
+}
+
+// ReadOnlyBrokersDeleteInterceptorProvider provides an interceptor which forbids users to delete imported brokers
+type ReadOnlyBrokersDeleteInterceptorProvider struct {
+}
+
+func (*ReadOnlyBrokersDeleteInterceptorProvider) Name() string {
+	return ReadOnlyBrokersDeleteInterceptorName
+}
+
+func (*ReadOnlyBrokersDeleteInterceptorProvider) Provide() storage.DeleteInterceptor {
+	return &readOnlyBrokersInterceptor{}
+}
+
+// readOnlyBrokersInterceptor rejects changes of imported brokers made by users. Changes made by background
+// jobs, e.g. the peer import or the catalog resync, have no user in the context and are allowed.
+type readOnlyBrokersInterceptor struct {
+}
+
+func (*readOnlyBrokersInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		if byUser(ctx) && isImported(obj) {
+			return nil, reservedLabelsError()
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (*readOnlyBrokersInterceptor) OnTxCreate(f storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return f
+}
+
+func (*readOnlyBrokersInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*readOnlyBrokersInterceptor) OnTxUpdate(f storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if byUser(ctx) {
+			if isImported(oldObj) {
+				return nil, readOnlyError(oldObj.(*types.ServiceBroker))
+			}
+			for _, labelChange := range labelChanges {
+				if labelChange.Key == PeerIDLabel || labelChange.Key == BrokerIDLabel {
+					return nil, reservedLabelsError()
+				}
+			}
+		}
+		return f(ctx, txStorage, oldObj, newObj, labelChanges...)
+	}
+}
+
+func (*readOnlyBrokersInterceptor) AroundTxDelete(h storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
+	return h
+}
+
+func (*readOnlyBrokersInterceptor) OnTxDelete(f storage.InterceptDeleteOnTxFunc) storage.InterceptDeleteOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		if byUser(ctx) {
+			for i := 0; i < objects.Len(); i++ {
+				if broker := objects.ItemAt(i); isImported(broker) {
+					return nil, readOnlyError(broker.(*types.ServiceBroker))
+				}
+			}
+		}
+		return f(ctx, txStorage, objects, deletionCriteria...)
+	}
+}
+
+func reservedLabelsError() error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("labels %s and %s are reserved for brokers imported from peers", PeerIDLabel, BrokerIDLabel),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
+
+func readOnlyError(broker *types.ServiceBroker) error {
+	return &util.HTTPError{
+		ErrorType:   "Forbidden",
+		Description: fmt.Sprintf("broker %s is imported from peer %s and cannot be modified", broker.Name, labelValue(broker.Labels, PeerNameLabel)),
+		StatusCode:  http.StatusForbidden,
+	}
+}
+
+func byUser(ctx context.Context) bool {
+	_, found := web.UserFromContext(ctx)
+	return found
+}
+
+func isImported(obj types.Object) bool {
+	labels := obj.GetLabels()
+	return labelValue(labels, PeerIDLabel) != "" || labelValue(labels, BrokerIDLabel) != ""
+}
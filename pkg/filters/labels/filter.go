@@ -53,8 +53,13 @@ func (flo *ForibiddenLabelOperationsFilter) Run(req *web.Request, next web.Handl
 		return next.Handle(req)
 	}
 
+	requestBody, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+
 	if req.Method == http.MethodPost {
-		body := gjson.ParseBytes(req.Body).Map()
+		body := gjson.ParseBytes(requestBody).Map()
 		if _, found := body["labels"]; !found {
 			return next.Handle(req)
 		}
@@ -81,7 +86,7 @@ func (flo *ForibiddenLabelOperationsFilter) Run(req *web.Request, next web.Handl
 			}
 		}
 	} else if req.Method == http.MethodPatch {
-		labelChanges, err := query.LabelChangesFromJSON(req.Body)
+		labelChanges, err := query.LabelChangesFromJSON(requestBody)
 		if err != nil {
 			return nil, err
 		}
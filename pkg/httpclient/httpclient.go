@@ -0,0 +1,277 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package httpclient provides the HTTP clients and transports of the outbound calls of the Service Manager, e.g. to
+// brokers, token issuers and key management services
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Timeout               time.Duration `mapstructure:"timeout" description:"timeout of outbound requests including reading the response, the server request timeout is used if 0"`
+	DialTimeout           time.Duration `mapstructure:"dial_timeout" description:"timeout for establishing outbound connections"`
+	KeepAlive             time.Duration `mapstructure:"keep_alive" description:"interval of the keep-alive probes of outbound connections"`
+	TLSHandshakeTimeout   time.Duration `mapstructure:"tls_handshake_timeout" description:"timeout of the TLS handshakes of outbound connections"`
+	IdleConnTimeout       time.Duration `mapstructure:"idle_conn_timeout" description:"time after which idle outbound connections are closed"`
+	MaxIdleConns          int           `mapstructure:"max_idle_conns" description:"maximum number of idle outbound connections across all hosts"`
+	MaxIdleConnsPerHost   int           `mapstructure:"max_idle_conns_per_host" description:"maximum number of idle outbound connections per host"`
+	MaxConnsPerHost       int           `mapstructure:"max_conns_per_host" description:"maximum number of outbound connections per host, unlimited if 0"`
+	ExpectContinueTimeout time.Duration `mapstructure:"expect_continue_timeout" description:"time to wait for the response headers of requests with an Expect: 100-continue header"`
+	CAFile                string        `mapstructure:"ca_file" description:"path of PEM encoded certificates of CAs trusted in addition to the ones of the system"`
+	CertificatePins       []string      `mapstructure:"certificate_pins" description:"expected certificates of hosts in the form host=pin,pin where a pin is sha256:<hex fingerprint> of a certificate of the chain or ca:<path of a PEM CA bundle>, e.g. *.brokers.example.com=ca:/etc/sm/brokers-ca.pem"`
+}
+
+// DefaultSettings returns the default values for the outbound HTTP clients
+func DefaultSettings() *Settings {
+	return &Settings{
+		DialTimeout:           30 * time.Second,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Validate validates the outbound HTTP client settings
+func (s *Settings) Validate() error {
+	if s.Timeout < 0 || s.DialTimeout < 0 || s.TLSHandshakeTimeout < 0 || s.IdleConnTimeout < 0 {
+		return fmt.Errorf("validate Settings: HTTP client timeouts must not be negative")
+	}
+	if s.MaxIdleConns < 0 || s.MaxIdleConnsPerHost < 0 || s.MaxConnsPerHost < 0 {
+		return fmt.Errorf("validate Settings: HTTP client connection limits must not be negative")
+	}
+	if _, err := ParseHostPins(s.CertificatePins); err != nil {
+		return fmt.Errorf("validate Settings: %s", err)
+	}
+	return nil
+}
+
+// Factory creates the HTTP clients of the outbound calls. The clients share one transport, so that connections are
+// pooled across all calls to the same host. Requests are logged with their duration and carry the correlation id
+// of the incoming request they are made for. Hosts with pinned certificates are only called if they present them.
+type Factory struct {
+	settings  *Settings
+	tlsConfig *tls.Config
+	pins      []HostPins
+	transport *Transport
+}
+
+// NewFactory returns a factory of clients configured by the settings which use the timeout if the settings specify
+// none. Certificates of servers are not verified if skipSSLValidation is true.
+func NewFactory(settings *Settings, timeout time.Duration, skipSSLValidation bool) (*Factory, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipSSLValidation}
+	if settings.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if tlsConfig.RootCAs, err = readCertificates(pool, settings.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	pins, err := ParseHostPins(settings.CertificatePins)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Timeout == 0 {
+		copied := *settings
+		copied.Timeout = timeout
+		settings = &copied
+	}
+	factory := &Factory{
+		settings:  settings,
+		tlsConfig: tlsConfig,
+		pins:      pins,
+	}
+	factory.transport = factory.NewTransport(http.ProxyFromEnvironment)
+	return factory, nil
+}
+
+// Timeout returns the timeout of the clients
+func (f *Factory) Timeout() time.Duration {
+	return f.settings.Timeout
+}
+
+// Transport returns the shared transport of the clients, which uses the proxies of the environment
+func (f *Factory) Transport() *Transport {
+	return f.transport
+}
+
+// NewTransport returns a new transport with the settings of the factory which uses the provided proxy function, for
+// calls which go through other proxies than the ones of the environment. The pins are verified in addition to the
+// configured ones. Transports should be reused.
+func (f *Factory) NewTransport(proxy func(*http.Request) (*url.URL, error), pins ...HostPins) *Transport {
+	return &Transport{
+		Transport: f.newHTTPTransport(proxy, f.tlsConfig),
+		pins:      append(append([]HostPins{}, f.pins...), pins...),
+		newPinnedTransport: func(host string, pins [][]Pin) *http.Transport {
+			tlsConfig := f.tlsConfig.Clone()
+			tlsConfig.VerifyPeerCertificate = verifyPins(host, pins)
+			return f.newHTTPTransport(proxy, tlsConfig)
+		},
+		pinned: make(map[string]*http.Transport),
+	}
+}
+
+func (f *Factory) newHTTPTransport(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   f.settings.DialTimeout,
+			KeepAlive: f.settings.KeepAlive,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   f.settings.TLSHandshakeTimeout,
+		IdleConnTimeout:       f.settings.IdleConnTimeout,
+		MaxIdleConns:          f.settings.MaxIdleConns,
+		MaxIdleConnsPerHost:   f.settings.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       f.settings.MaxConnsPerHost,
+		ExpectContinueTimeout: f.settings.ExpectContinueTimeout,
+	}
+}
+
+// Client returns a client using the shared transport whose requests are logged under the name
+func (f *Factory) Client(name string) *http.Client {
+	return f.ClientWithTransport(name, f.transport)
+}
+
+// ClientWithTransport returns a client using the transport whose requests are logged under the name
+func (f *Factory) ClientWithTransport(name string, transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: Instrument(name, transport),
+		Timeout:   f.settings.Timeout,
+	}
+}
+
+// Transport is an http.Transport which calls the hosts with pinned certificates through transports verifying the pins
+// during the TLS handshakes. Hosts with pinned certificates cannot be called with plain HTTP.
+type Transport struct {
+	*http.Transport
+
+	pins               []HostPins
+	newPinnedTransport func(host string, pins [][]Pin) *http.Transport
+
+	mutex  sync.Mutex
+	pinned map[string]*http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	host := request.URL.Hostname()
+	pins := pinsOf(t.pins, host)
+	if len(pins) == 0 {
+		return t.Transport.RoundTrip(request)
+	}
+	if request.URL.Scheme != "https" {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		return nil, fmt.Errorf("host %s has pinned certificates and can only be called with https", host)
+	}
+	return t.pinnedTransport(host, pins).RoundTrip(request)
+}
+
+// CloseIdleConnections closes the idle connections of the transport and the transports of the pinned hosts
+func (t *Transport) CloseIdleConnections() {
+	t.Transport.CloseIdleConnections()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, transport := range t.pinned {
+		transport.CloseIdleConnections()
+	}
+}
+
+func (t *Transport) pinnedTransport(host string, pins [][]Pin) *http.Transport {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	transport, found := t.pinned[host]
+	if !found {
+		transport = t.newPinnedTransport(host, pins)
+		t.pinned[host] = transport
+	}
+	return transport
+}
+
+// Instrument returns a round tripper which logs the requests sent through the transport under the name and adds
+// the correlation id of the context of the requests to them
+func Instrument(name string, transport http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{
+		name:      name,
+		transport: transport,
+	}
+}
+
+type instrumentedTransport struct {
+	name      string
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *instrumentedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	if correlationID := web.MetadataFromContext(ctx).CorrelationID(); correlationID != "" && request.Header.Get(log.CorrelationIDHeaders[0]) == "" {
+		// round trippers must not modify the request of the caller
+		request = withHeader(request, log.CorrelationIDHeaders[0], correlationID)
+	}
+
+	start := time.Now()
+	response, err := t.transport.RoundTrip(request)
+	duration := time.Since(start)
+	if err != nil {
+		log.C(ctx).WithError(err).Debugf("Outbound %s request %s %s failed after %s", t.name, request.Method, request.URL.Host, duration)
+		return nil, err
+	}
+	log.C(ctx).Debugf("Outbound %s request %s %s returned %d in %s", t.name, request.Method, request.URL.Host, response.StatusCode, duration)
+	return response, nil
+}
+
+func withHeader(request *http.Request, name, value string) *http.Request {
+	copied := request.WithContext(request.Context())
+	copied.Header = make(http.Header, len(request.Header)+1)
+	for k, v := range request.Header {
+		copied.Header[k] = v
+	}
+	copied.Header.Set(name, value)
+	return copied
+}
+
+// readCertificates adds the PEM encoded certificates of the file to the pool
+func readCertificates(pool *x509.CertPool, caFile string) (*x509.CertPool, error) {
+	certificates, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA file %s: %s", caFile, err)
+	}
+	if !pool.AppendCertsFromPEM(certificates) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	return pool, nil
+}
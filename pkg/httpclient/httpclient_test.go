@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package httpclient_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Factory", func() {
+	var (
+		settings *httpclient.Settings
+		factory  *httpclient.Factory
+	)
+
+	BeforeEach(func() {
+		settings = httpclient.DefaultSettings()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		factory, err = httpclient.NewFactory(settings, 3*time.Second, false)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("shares the transport between the clients", func() {
+		Expect(factory.Client("broker").Transport).ToNot(BeIdenticalTo(factory.Transport()))
+		Expect(factory.Transport()).To(BeIdenticalTo(factory.Transport()))
+		Expect(factory.Transport().MaxIdleConnsPerHost).To(Equal(settings.MaxIdleConnsPerHost))
+	})
+
+	Context("when no timeout is configured", func() {
+		It("uses the provided timeout", func() {
+			Expect(factory.Client("broker").Timeout).To(Equal(3 * time.Second))
+		})
+	})
+
+	Context("when a timeout is configured", func() {
+		BeforeEach(func() {
+			settings.Timeout = time.Minute
+		})
+
+		It("uses the configured timeout", func() {
+			Expect(factory.Client("broker").Timeout).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("Client", func() {
+		var (
+			server      *httptest.Server
+			correlation string
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				correlation = req.Header.Get("X-Correlation-ID")
+				rw.WriteHeader(http.StatusNoContent)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("propagates the correlation id of the context without changing the request", func() {
+			ctx := web.ContextWithMetadata(context.Background(), web.Metadata{}.WithCorrelationID("correlation"))
+			request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err := factory.Client("broker").Do(request.WithContext(ctx))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(correlation).To(Equal("correlation"))
+			Expect(request.Header.Get("X-Correlation-ID")).To(BeEmpty())
+		})
+	})
+
+	Context("when the CA file contains no certificates", func() {
+		It("returns an error", func() {
+			file, err := ioutil.TempFile("", "sm-ca-")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(file.Name())
+
+			settings.CAFile = file.Name()
+			_, err = httpclient.NewFactory(settings, time.Second, false)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Settings", func() {
+		It("rejects negative connection limits", func() {
+			settings.MaxIdleConns = -1
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	fingerprintPinPrefix = "sha256:"
+	caPinPrefix          = "ca:"
+)
+
+// Pin is an expected certificate of a host. It is either the SHA-256 fingerprint of a certificate of the chain of the
+// host or a bundle of CAs to which the chain of the host has to lead.
+type Pin struct {
+	Fingerprint []byte
+	CAs         *x509.CertPool
+}
+
+// HostPins are the pins of the hosts matching the pattern, a pattern *.example.com matches all subdomains of
+// example.com. The certificate chain of the hosts has to match one of the pins of each matching HostPins.
+type HostPins struct {
+	Host string
+	Pins []Pin
+}
+
+// ParsePin parses a pin in the form sha256:<hex fingerprint>, in which the bytes of the fingerprint can be separated
+// by colons, or ca:<path of a PEM encoded CA bundle>
+func ParsePin(pin string) (Pin, error) {
+	switch {
+	case strings.HasPrefix(pin, fingerprintPinPrefix):
+		fingerprint, err := hex.DecodeString(strings.Replace(strings.TrimPrefix(pin, fingerprintPinPrefix), ":", "", -1))
+		if err != nil || len(fingerprint) != sha256.Size {
+			return Pin{}, fmt.Errorf("invalid SHA-256 fingerprint in pin %s", pin)
+		}
+		return Pin{Fingerprint: fingerprint}, nil
+	case strings.HasPrefix(pin, caPinPrefix):
+		cas, err := readCertificates(x509.NewCertPool(), strings.TrimPrefix(pin, caPinPrefix))
+		if err != nil {
+			return Pin{}, err
+		}
+		return Pin{CAs: cas}, nil
+	default:
+		return Pin{}, fmt.Errorf("pin %s is neither a sha256: fingerprint nor a ca: bundle", pin)
+	}
+}
+
+// ParseHostPins parses pins in the form host=pin,pin, e.g. broker.example.com=sha256:9F:86:D0:...
+func ParseHostPins(rules []string) ([]HostPins, error) {
+	result := make([]HostPins, 0, len(rules))
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("certificate pins %s are not in the form host=pin", rule)
+		}
+		hostPins := HostPins{Host: strings.ToLower(parts[0])}
+		for _, pin := range strings.Split(parts[1], ",") {
+			parsed, err := ParsePin(strings.TrimSpace(pin))
+			if err != nil {
+				return nil, err
+			}
+			hostPins.Pins = append(hostPins.Pins, parsed)
+		}
+		result = append(result, hostPins)
+	}
+	return result, nil
+}
+
+// pinsOf returns the pins of the patterns matching the host
+func pinsOf(hostPins []HostPins, host string) [][]Pin {
+	host = strings.ToLower(host)
+	var pins [][]Pin
+	for _, hp := range hostPins {
+		if hp.Host == host || (strings.HasPrefix(hp.Host, "*.") && strings.HasSuffix(host, hp.Host[1:])) {
+			pins = append(pins, hp.Pins)
+		}
+	}
+	return pins
+}
+
+// verifyPins returns a function verifying during the TLS handshake that the certificate chain of the host matches
+// one pin of each of the pin sets, so that no request is sent to a host presenting other certificates. Fingerprints
+// are matched against the verified chains, or only against the certificate of the host if the chains are not verified.
+func verifyPins(host string, pinSets [][]Pin) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, pins := range pinSets {
+			if !matchesAny(pins, host, rawCerts, verifiedChains) {
+				return fmt.Errorf("certificate of %s does not match any of its pins", host)
+			}
+		}
+		return nil
+	}
+}
+
+func matchesAny(pins []Pin, host string, rawCerts [][]byte, verifiedChains [][]*x509.Certificate) bool {
+	for _, pin := range pins {
+		if pin.matches(host, rawCerts, verifiedChains) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Pin) matches(host string, rawCerts [][]byte, verifiedChains [][]*x509.Certificate) bool {
+	if len(rawCerts) == 0 {
+		return false
+	}
+	if p.CAs == nil {
+		if len(verifiedChains) == 0 {
+			return p.matchesFingerprint(rawCerts[0])
+		}
+		for _, chain := range verifiedChains {
+			for _, certificate := range chain {
+				if p.matchesFingerprint(certificate.Raw) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		certificate, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return false
+		}
+		certificates = append(certificates, certificate)
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := certificates[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         p.CAs,
+		Intermediates: intermediates,
+	})
+	return err == nil
+}
+
+func (p Pin) matchesFingerprint(raw []byte) bool {
+	fingerprint := sha256.Sum256(raw)
+	return bytes.Equal(fingerprint[:], p.Fingerprint)
+}
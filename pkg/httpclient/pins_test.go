@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package httpclient_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificate pins", func() {
+	var (
+		server   *httptest.Server
+		settings *httpclient.Settings
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		settings = httpclient.DefaultSettings()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	call := func(url string) error {
+		// the certificate of the test server is self-signed, so only the pins are verified
+		factory, err := httpclient.NewFactory(settings, time.Second, true)
+		Expect(err).ToNot(HaveOccurred())
+		response, err := factory.Client("test").Get(url)
+		if err == nil {
+			response.Body.Close()
+		}
+		return err
+	}
+
+	fingerprint := func() string {
+		sum := sha256.Sum256(server.Certificate().Raw)
+		return hex.EncodeToString(sum[:])
+	}
+
+	It("calls hosts presenting a pinned certificate", func() {
+		settings.CertificatePins = []string{"127.0.0.1=sha256:" + fingerprint()}
+		Expect(call(server.URL)).To(Succeed())
+	})
+
+	It("does not call hosts presenting other certificates", func() {
+		settings.CertificatePins = []string{"127.0.0.1=sha256:" + strings.Repeat("ab", sha256.Size)}
+		Expect(call(server.URL)).To(HaveOccurred())
+	})
+
+	It("does not match pinned certificates appended to the chain of hosts which is not verified", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "pinned.example.com"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		pinned, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+		server.TLS.Certificates[0].Certificate = append(server.TLS.Certificates[0].Certificate, pinned)
+
+		sum := sha256.Sum256(pinned)
+		settings.CertificatePins = []string{"127.0.0.1=sha256:" + hex.EncodeToString(sum[:])}
+		Expect(call(server.URL)).To(HaveOccurred())
+	})
+
+	It("requires a match of each entry of the host", func() {
+		settings.CertificatePins = []string{
+			"127.0.0.1=sha256:" + fingerprint(),
+			"127.0.0.1=sha256:" + strings.Repeat("ab", sha256.Size),
+		}
+		Expect(call(server.URL)).To(HaveOccurred())
+	})
+
+	It("calls hosts whose certificates lead to a pinned CA bundle", func() {
+		file, err := ioutil.TempFile("", "sm-pinned-ca-")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(file.Name())
+		Expect(pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		settings.CertificatePins = []string{"127.0.0.1=ca:" + file.Name()}
+		Expect(call(server.URL)).To(Succeed())
+	})
+
+	It("does not call hosts with pinned certificates with plain HTTP", func() {
+		settings.CertificatePins = []string{"127.0.0.1=sha256:" + fingerprint()}
+		Expect(call(strings.Replace(server.URL, "https://", "http://", 1))).To(HaveOccurred())
+	})
+
+	It("rejects invalid pins", func() {
+		for _, pins := range []string{"127.0.0.1", "127.0.0.1=md5:abcd", "127.0.0.1=sha256:abcd"} {
+			_, err := httpclient.ParseHostPins([]string{pins})
+			Expect(err).To(HaveOccurred(), pins)
+		}
+	})
+})
@@ -0,0 +1,308 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package jobs contains logic for running background jobs on a single Service Manager instance at a time
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const leaderLockName = "sm-jobs-leader"
+
+// ErrJobNotFound is returned when there is no job registered with the requested name
+var ErrJobNotFound = errors.New("job not found")
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	LeaderElectionInterval time.Duration `mapstructure:"leader_election_interval" description:"time between attempts to become the instance which runs the background jobs"`
+	Jitter                 time.Duration `mapstructure:"jitter" description:"maximum random delay added to job intervals unless the job specifies its own"`
+}
+
+// DefaultSettings returns default values for jobs settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		LeaderElectionInterval: 15 * time.Second,
+		Jitter:                 0,
+	}
+}
+
+// Validate validates the jobs settings
+func (s *Settings) Validate() error {
+	if s.LeaderElectionInterval <= 0 {
+		return fmt.Errorf("validate Settings: jobs leader election interval must be > 0")
+	}
+	if s.Jitter < 0 {
+		return fmt.Errorf("validate Settings: jobs jitter must be >= 0")
+	}
+	return nil
+}
+
+// Job is a background task which is executed periodically
+type Job interface {
+	// Name returns the unique name of the job
+	Name() string
+
+	// Run executes the job once
+	Run(ctx context.Context) error
+}
+
+// Options specifies how often a job is executed
+type Options struct {
+	// Interval is the time between two consecutive runs of the job
+	Interval time.Duration
+
+	// Jitter is the maximum random delay added to the interval. If zero, the scheduler default is used.
+	Jitter time.Duration
+}
+
+// Status represents the state of a registered job
+type Status struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	Running      bool       `json:"running"`
+	Runs         int64      `json:"runs"`
+	LastStarted  *time.Time `json:"last_started,omitempty"`
+	LastFinished *time.Time `json:"last_finished,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+type scheduledJob struct {
+	job     Job
+	options Options
+
+	mutex  sync.Mutex
+	status Status
+}
+
+// Scheduler runs the registered jobs on the instance which holds the leader lock
+type Scheduler struct {
+	settings *Settings
+	locker   storage.Locker
+
+	mutex  sync.RWMutex
+	jobs   map[string]*scheduledJob
+	leader storage.Lock
+	ctx    context.Context
+}
+
+// NewScheduler returns a scheduler which uses the provided locker for electing the instance that runs the jobs
+func NewScheduler(settings *Settings, locker storage.Locker) *Scheduler {
+	return &Scheduler{
+		settings: settings,
+		locker:   locker,
+		jobs:     make(map[string]*scheduledJob),
+	}
+}
+
+// Register registers a job which will be executed periodically once the scheduler is started
+func (s *Scheduler) Register(job Job, options Options) error {
+	if options.Interval <= 0 {
+		return fmt.Errorf("interval of job %s must be > 0", job.Name())
+	}
+	if options.Jitter == 0 {
+		options.Jitter = s.settings.Jitter
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ctx != nil {
+		return fmt.Errorf("cannot register job %s after the scheduler is started", job.Name())
+	}
+	if _, found := s.jobs[job.Name()]; found {
+		return fmt.Errorf("job %s is already registered", job.Name())
+	}
+	s.jobs[job.Name()] = &scheduledJob{
+		job:     job,
+		options: options,
+		status: Status{
+			Name:     job.Name(),
+			Interval: options.Interval.String(),
+		},
+	}
+	return nil
+}
+
+// Start starts the leader election and the scheduling of the registered jobs. It cannot be used concurrently.
+func (s *Scheduler) Start(ctx context.Context, group *sync.WaitGroup) error {
+	s.mutex.Lock()
+	if s.ctx != nil {
+		s.mutex.Unlock()
+		return errors.New("jobs scheduler already started")
+	}
+	s.ctx = ctx
+	jobs := make([]*scheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mutex.Unlock()
+
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		s.electLeader(ctx)
+	}()
+
+	for _, job := range jobs {
+		job := job
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			s.schedule(ctx, job)
+		}()
+	}
+	return nil
+}
+
+// IsLeader returns whether this instance currently runs the scheduled jobs
+func (s *Scheduler) IsLeader() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.leader != nil
+}
+
+// Statuses returns the statuses of all registered jobs ordered by name
+func (s *Scheduler) Statuses() []Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		job.mutex.Lock()
+		statuses = append(statuses, job.status)
+		job.mutex.Unlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Trigger runs the job with the given name in the background on this instance regardless of leadership.
+// If the job is already running it is not started again.
+func (s *Scheduler) Trigger(name string) error {
+	s.mutex.RLock()
+	job, found := s.jobs[name]
+	ctx := s.ctx
+	s.mutex.RUnlock()
+
+	if !found {
+		return ErrJobNotFound
+	}
+	if ctx == nil {
+		return errors.New("jobs scheduler is not started")
+	}
+
+	go s.run(ctx, job)
+	return nil
+}
+
+func (s *Scheduler) electLeader(ctx context.Context) {
+	logger := log.C(ctx)
+	ticker := time.NewTicker(s.settings.LeaderElectionInterval)
+	defer ticker.Stop()
+
+	// only this goroutine changes the leader lock, so it can be read without synchronization here
+	for {
+		if s.leader == nil {
+			lock, err := s.locker.TryLock(ctx, leaderLockName)
+			if err != nil {
+				logger.WithError(err).Error("could not acquire jobs leader lock")
+			} else if lock != nil {
+				logger.Info("This instance is now running the background jobs")
+				s.setLeader(lock)
+			}
+		} else if err := s.leader.Check(ctx); err != nil {
+			logger.WithError(err).Error("lost jobs leader lock")
+			s.setLeader(nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			if s.leader != nil {
+				if err := s.leader.Release(context.Background()); err != nil {
+					logger.WithError(err).Error("could not release jobs leader lock")
+				}
+				s.setLeader(nil)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) setLeader(lock storage.Lock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.leader = lock
+}
+
+func (s *Scheduler) schedule(ctx context.Context, job *scheduledJob) {
+	log.C(ctx).Infof("Scheduling job %s every %s", job.job.Name(), job.options.Interval)
+	for {
+		delay := job.options.Interval
+		if job.options.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(job.options.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+			if s.IsLeader() {
+				s.run(ctx, job)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob) {
+	job.mutex.Lock()
+	if job.status.Running {
+		job.mutex.Unlock()
+		log.C(ctx).Infof("Job %s is already running", job.job.Name())
+		return
+	}
+	started := time.Now().UTC()
+	job.status.Running = true
+	job.status.LastStarted = &started
+	job.mutex.Unlock()
+
+	log.C(ctx).Debugf("Running job %s", job.job.Name())
+	err := job.job.Run(ctx)
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	finished := time.Now().UTC()
+	job.status.Running = false
+	job.status.Runs++
+	job.status.LastFinished = &finished
+	job.status.LastError = ""
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Job %s failed", job.job.Name())
+		job.status.LastError = err.Error()
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/jobs"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testLock struct{}
+
+func (*testLock) Check(ctx context.Context) error {
+	return nil
+}
+
+func (*testLock) Release(ctx context.Context) error {
+	return nil
+}
+
+type testLocker struct {
+	acquirable bool
+}
+
+func (l *testLocker) TryLock(ctx context.Context, name string) (storage.Lock, error) {
+	if !l.acquirable {
+		return nil, nil
+	}
+	return &testLock{}, nil
+}
+
+type testJob struct {
+	runs int32
+	err  error
+}
+
+func (j *testJob) Name() string {
+	return "test_job"
+}
+
+func (j *testJob) Run(ctx context.Context) error {
+	atomic.AddInt32(&j.runs, 1)
+	return j.err
+}
+
+func (j *testJob) Runs() int32 {
+	return atomic.LoadInt32(&j.runs)
+}
+
+var _ = Describe("Scheduler", func() {
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		wg        *sync.WaitGroup
+		locker    *testLocker
+		job       *testJob
+		scheduler *jobs.Scheduler
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		locker = &testLocker{acquirable: true}
+		job = &testJob{}
+		settings := jobs.DefaultSettings()
+		settings.LeaderElectionInterval = 10 * time.Millisecond
+		scheduler = jobs.NewScheduler(settings, locker)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("Register", func() {
+		It("fails for non-positive intervals", func() {
+			Expect(scheduler.Register(job, jobs.Options{})).To(HaveOccurred())
+		})
+
+		It("fails for already registered jobs", func() {
+			Expect(scheduler.Register(job, jobs.Options{Interval: time.Second})).To(Succeed())
+			Expect(scheduler.Register(job, jobs.Options{Interval: time.Second})).To(HaveOccurred())
+		})
+	})
+
+	Describe("Start", func() {
+		BeforeEach(func() {
+			Expect(scheduler.Register(job, jobs.Options{Interval: 10 * time.Millisecond})).To(Succeed())
+		})
+
+		It("fails if already started", func() {
+			Expect(scheduler.Start(ctx, wg)).To(Succeed())
+			Expect(scheduler.Start(ctx, wg)).To(HaveOccurred())
+		})
+
+		Context("when the leader lock is acquired", func() {
+			It("runs the jobs", func() {
+				Expect(scheduler.Start(ctx, wg)).To(Succeed())
+				Eventually(scheduler.IsLeader).Should(BeTrue())
+				Eventually(job.Runs).Should(BeNumerically(">", 1))
+			})
+		})
+
+		Context("when the leader lock is held by another instance", func() {
+			It("does not run the jobs", func() {
+				locker.acquirable = false
+				Expect(scheduler.Start(ctx, wg)).To(Succeed())
+				Consistently(job.Runs, 100*time.Millisecond).Should(BeZero())
+				Expect(scheduler.IsLeader()).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Trigger", func() {
+		BeforeEach(func() {
+			locker.acquirable = false
+			Expect(scheduler.Register(job, jobs.Options{Interval: time.Hour})).To(Succeed())
+			Expect(scheduler.Start(ctx, wg)).To(Succeed())
+		})
+
+		It("runs the job regardless of leadership", func() {
+			Expect(scheduler.Trigger(job.Name())).To(Succeed())
+			Eventually(job.Runs).Should(Equal(int32(1)))
+		})
+
+		It("records failures in the job status", func() {
+			job.err = errors.New("expected")
+			Expect(scheduler.Trigger(job.Name())).To(Succeed())
+			Eventually(func() string {
+				return scheduler.Statuses()[0].LastError
+			}).Should(Equal("expected"))
+		})
+
+		It("fails for unknown jobs", func() {
+			Expect(scheduler.Trigger("unknown")).To(Equal(jobs.ErrJobNotFound))
+		})
+	})
+})
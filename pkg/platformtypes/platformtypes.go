@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package platformtypes contains the plugins which customize the behavior of the Service Manager for the platforms
+// of a type, e.g. cloudfoundry or kubernetes. Platforms whose type has no plugins are treated identically.
+package platformtypes
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/tidwall/gjson"
+)
+
+// Plugin customizes the behavior of the Service Manager for the platforms of a type.
+// To customize a behavior a plugin implements one or more of the interfaces defined in this package.
+type Plugin interface {
+	// PlatformType returns the type of the platforms to which the plugin applies
+	PlatformType() string
+}
+
+// CredentialsGenerator should be implemented by plugins that need to change the format of the credentials
+// which are generated for new platforms
+type CredentialsGenerator interface {
+	Plugin
+
+	GenerateCredentials(platform *types.Platform) (*types.Credentials, error)
+}
+
+// NotificationTransformer should be implemented by plugins that need to change the notifications which are sent to
+// the platforms, e.g. the shape of their payload. The provided notification is shared between the platforms and
+// must not be modified, a changed copy has to be returned instead.
+type NotificationTransformer interface {
+	Plugin
+
+	TransformNotification(platform *types.Platform, notification *types.Notification) (*types.Notification, error)
+}
+
+// VisibilityPolicy should be implemented by plugins that need to restrict which visibilities apply to the platforms.
+// It is only consulted for the visibilities of the platform and the public visibilities.
+type VisibilityPolicy interface {
+	Plugin
+
+	IsVisible(platform *types.Platform, visibility *types.Visibility) bool
+}
+
+// Registry holds the platform type plugins. A nil registry has no plugins.
+type Registry struct {
+	plugins map[string][]Plugin
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		plugins: make(map[string][]Plugin),
+	}
+}
+
+// Register adds plugins to the registry. It must not be called concurrently and not after the Service Manager is run.
+func (r *Registry) Register(plugins ...Plugin) {
+	for _, plugin := range plugins {
+		platformType := plugin.PlatformType()
+		r.plugins[platformType] = append(r.plugins[platformType], plugin)
+	}
+}
+
+func (r *Registry) pluginsFor(platform *types.Platform) []Plugin {
+	if r == nil || platform == nil {
+		return nil
+	}
+	return r.plugins[platform.Type]
+}
+
+// CredentialsGenerator returns the first credentials generator of the type of the platform or nil if there is none
+func (r *Registry) CredentialsGenerator(platform *types.Platform) CredentialsGenerator {
+	for _, plugin := range r.pluginsFor(platform) {
+		if generator, ok := plugin.(CredentialsGenerator); ok {
+			return generator
+		}
+	}
+	return nil
+}
+
+// GenerateCredentials generates the credentials of a new platform with the first credentials generator of its type.
+// Random basic credentials are generated if there is none.
+func (r *Registry) GenerateCredentials(platform *types.Platform) (*types.Credentials, error) {
+	if generator := r.CredentialsGenerator(platform); generator != nil {
+		return generator.GenerateCredentials(platform)
+	}
+	return types.GenerateCredentials()
+}
+
+// TransformNotification returns the notification which is sent to the platform after applying all
+// notification transformers of its type in the order of their registration
+func (r *Registry) TransformNotification(platform *types.Platform, notification *types.Notification) (*types.Notification, error) {
+	for _, plugin := range r.pluginsFor(platform) {
+		if transformer, ok := plugin.(NotificationTransformer); ok {
+			var err error
+			if notification, err = transformer.TransformNotification(platform, notification); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return notification, nil
+}
+
+// IsVisible returns whether the visibility applies to the platform, which is the case if all visibility policies
+// of its type agree
+func (r *Registry) IsVisible(platform *types.Platform, visibility *types.Visibility) bool {
+	for _, plugin := range r.pluginsFor(platform) {
+		if policy, ok := plugin.(VisibilityPolicy); ok && !policy.IsVisible(platform, visibility) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterVisibilityRecipients implements storage.ReceiversFilterFunc. A visibility notification is only sent to
+// the platforms to which the old or the new state of the visibility applies.
+func (r *Registry) FilterVisibilityRecipients(recipients []*types.Platform, notification *types.Notification) []*types.Platform {
+	if notification.Resource != types.VisibilityType {
+		return recipients
+	}
+	visibilities := make([]*types.Visibility, 0, 2)
+	for _, path := range []string{"old.resource", "new.resource"} {
+		if resource := gjson.GetBytes(notification.Payload, path); resource.Exists() {
+			visibility := &types.Visibility{}
+			if err := json.Unmarshal([]byte(resource.Raw), visibility); err == nil {
+				visibilities = append(visibilities, visibility)
+			}
+		}
+	}
+	if len(visibilities) == 0 {
+		return recipients
+	}
+
+	filtered := make([]*types.Platform, 0, len(recipients))
+	for _, platform := range recipients {
+		for _, visibility := range visibilities {
+			if r.IsVisible(platform, visibility) {
+				filtered = append(filtered, platform)
+				break
+			}
+		}
+	}
+	return filtered
+}
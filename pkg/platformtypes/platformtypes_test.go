@@ -0,0 +1,181 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package platformtypes_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Peripli/service-manager/pkg/platformtypes"
+	"github.com/Peripli/service-manager/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type kubernetesPlugin struct {
+	transformErr error
+}
+
+func (*kubernetesPlugin) PlatformType() string {
+	return "kubernetes"
+}
+
+func (*kubernetesPlugin) GenerateCredentials(platform *types.Platform) (*types.Credentials, error) {
+	return &types.Credentials{
+		Basic: &types.Basic{
+			Username: "k8s-" + platform.Name,
+			Password: "secret",
+		},
+	}, nil
+}
+
+func (p *kubernetesPlugin) TransformNotification(platform *types.Platform, notification *types.Notification) (*types.Notification, error) {
+	if p.transformErr != nil {
+		return nil, p.transformErr
+	}
+	transformed := *notification
+	transformed.Payload = json.RawMessage(`{"platform":"` + platform.Name + `"}`)
+	return &transformed, nil
+}
+
+func (*kubernetesPlugin) IsVisible(platform *types.Platform, visibility *types.Visibility) bool {
+	return visibility.PlatformID == platform.ID
+}
+
+var _ = Describe("Registry", func() {
+	var (
+		registry            *platformtypes.Registry
+		plugin              *kubernetesPlugin
+		cfPlatform          *types.Platform
+		k8sPlatform         *types.Platform
+		publicVisibility    *types.Visibility
+		k8sVisibility       *types.Visibility
+		visibilityPayload   func(visibility *types.Visibility) json.RawMessage
+		visibilityToPublish *types.Notification
+	)
+
+	BeforeEach(func() {
+		plugin = &kubernetesPlugin{}
+		registry = platformtypes.NewRegistry()
+		registry.Register(plugin)
+
+		cfPlatform = &types.Platform{Base: types.Base{ID: "cf-id"}, Name: "cf", Type: "cloudfoundry"}
+		k8sPlatform = &types.Platform{Base: types.Base{ID: "k8s-id"}, Name: "k8s", Type: "kubernetes"}
+		publicVisibility = &types.Visibility{Base: types.Base{ID: "public"}, ServicePlanID: "plan-id"}
+		k8sVisibility = &types.Visibility{Base: types.Base{ID: "k8s"}, ServicePlanID: "plan-id", PlatformID: k8sPlatform.ID}
+
+		visibilityPayload = func(visibility *types.Visibility) json.RawMessage {
+			payload, err := json.Marshal(map[string]interface{}{
+				"new": map[string]interface{}{"resource": visibility},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return payload
+		}
+		visibilityToPublish = &types.Notification{
+			Resource: types.VisibilityType,
+			Type:     types.CREATED,
+			Payload:  visibilityPayload(publicVisibility),
+		}
+	})
+
+	Describe("CredentialsGenerator", func() {
+		It("returns the credentials generator of the platform type", func() {
+			Expect(registry.CredentialsGenerator(k8sPlatform)).ToNot(BeNil())
+			Expect(registry.CredentialsGenerator(cfPlatform)).To(BeNil())
+		})
+	})
+
+	Describe("GenerateCredentials", func() {
+		It("uses the credentials generator of the platform type", func() {
+			credentials, err := registry.GenerateCredentials(k8sPlatform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentials.Basic.Username).To(Equal("k8s-k8s"))
+		})
+
+		It("generates random credentials for other platform types", func() {
+			credentials, err := registry.GenerateCredentials(cfPlatform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentials.Basic.Username).ToNot(BeEmpty())
+			Expect(credentials.Basic.Username).ToNot(Equal("k8s-cf"))
+		})
+
+		It("generates random credentials without a registry", func() {
+			var nilRegistry *platformtypes.Registry
+			credentials, err := nilRegistry.GenerateCredentials(k8sPlatform)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentials.Basic.Password).ToNot(BeEmpty())
+		})
+	})
+
+	Describe("TransformNotification", func() {
+		It("applies the notification transformers of the platform type without changing the original", func() {
+			transformed, err := registry.TransformNotification(k8sPlatform, visibilityToPublish)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(transformed.Payload)).To(MatchJSON(`{"platform":"k8s"}`))
+			Expect(string(visibilityToPublish.Payload)).To(MatchJSON(string(visibilityPayload(publicVisibility))))
+		})
+
+		It("does not change the notifications of other platform types", func() {
+			transformed, err := registry.TransformNotification(cfPlatform, visibilityToPublish)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transformed).To(Equal(visibilityToPublish))
+		})
+
+		It("returns the error of a transformer", func() {
+			plugin.transformErr = errors.New("transformation failed")
+			_, err := registry.TransformNotification(k8sPlatform, visibilityToPublish)
+			Expect(err).To(MatchError("transformation failed"))
+		})
+	})
+
+	Describe("IsVisible", func() {
+		It("applies the visibility policies of the platform type", func() {
+			Expect(registry.IsVisible(k8sPlatform, publicVisibility)).To(BeFalse())
+			Expect(registry.IsVisible(k8sPlatform, k8sVisibility)).To(BeTrue())
+		})
+
+		It("makes all visibilities visible to other platform types", func() {
+			Expect(registry.IsVisible(cfPlatform, publicVisibility)).To(BeTrue())
+		})
+	})
+
+	Describe("FilterVisibilityRecipients", func() {
+		It("removes the platforms to which the visibility does not apply", func() {
+			recipients := registry.FilterVisibilityRecipients([]*types.Platform{cfPlatform, k8sPlatform}, visibilityToPublish)
+			Expect(recipients).To(ConsistOf(cfPlatform))
+		})
+
+		It("keeps the platforms to which the old visibility applied", func() {
+			visibilityToPublish.Type = types.MODIFIED
+			payload, err := json.Marshal(map[string]interface{}{
+				"old": map[string]interface{}{"resource": k8sVisibility},
+				"new": map[string]interface{}{"resource": publicVisibility},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			visibilityToPublish.Payload = payload
+
+			recipients := registry.FilterVisibilityRecipients([]*types.Platform{cfPlatform, k8sPlatform}, visibilityToPublish)
+			Expect(recipients).To(ConsistOf(cfPlatform, k8sPlatform))
+		})
+
+		It("keeps all platforms for notifications of other resources", func() {
+			visibilityToPublish.Resource = types.ServiceBrokerType
+			recipients := registry.FilterVisibilityRecipients([]*types.Platform{cfPlatform, k8sPlatform}, visibilityToPublish)
+			Expect(recipients).To(ConsistOf(cfPlatform, k8sPlatform))
+		})
+	})
+})
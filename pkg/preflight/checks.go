@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/server"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// Checks returns the checks of the configuration, the database, the token issuer, the TLS material and
+// the encryption key of the Service Manager. They do not change the database, pending migrations are only reported.
+func Checks(cfg *config.Settings) []Check {
+	return []Check{
+		{Name: "configuration", Run: ConfigurationCheck(cfg)},
+		{Name: "database connectivity", Run: DatabaseCheck(cfg.Storage)},
+		{Name: "database migrations", Run: MigrationsCheck(cfg.Storage)},
+		{Name: "token issuer", Run: TokenIssuerCheck(cfg)},
+		{Name: "TLS material", Run: TLSCheck(cfg.Server, cfg.HTTPClient)},
+		{Name: "encryption key", Run: EncryptionKeyCheck(cfg.Storage)},
+	}
+}
+
+// ConfigurationCheck validates the configuration
+func ConfigurationCheck(cfg *config.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if err := cfg.Validate(); err != nil {
+			return "", err
+		}
+		return "valid", nil
+	}
+}
+
+// DatabaseCheck connects to the database of the storage
+func DatabaseCheck(settings *storage.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var version string
+		err := withDatabase(settings, func(db *sql.DB) error {
+			return db.QueryRowContext(ctx, "SHOW server_version").Scan(&version)
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("connected to PostgreSQL %s", version), nil
+	}
+}
+
+// MigrationsCheck compares the version of the database schema with the migrations. Pending migrations are applied
+// on start, so only a dirty schema or a schema newer than the migrations fails the check.
+func MigrationsCheck(settings *storage.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var status *postgres.SchemaStatus
+		err := withDatabase(settings, func(db *sql.DB) error {
+			var err error
+			status, err = postgres.ReadSchemaStatus(ctx, db, settings.MigrationsURL)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case status.Dirty:
+			return "", fmt.Errorf("schema version %d is dirty, the migration failed and has to be fixed manually", status.Version)
+		case status.Version > status.Latest:
+			return "", fmt.Errorf("schema version %d is newer than the latest migration %d", status.Version, status.Latest)
+		case status.Pending():
+			return fmt.Sprintf("schema version %d, migrations up to %d are applied on start", status.Version, status.Latest), nil
+		default:
+			return fmt.Sprintf("schema version %d is up to date", status.Version), nil
+		}
+	}
+}
+
+// TokenIssuerCheck reads the OpenID configuration of the token issuer
+func TokenIssuerCheck(cfg *config.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		httpClients, err := httpclient.NewFactory(cfg.HTTPClient, cfg.Server.RequestTimeout, cfg.API.SkipSSLValidation)
+		if err != nil {
+			return "", err
+		}
+		wellKnown := strings.TrimSuffix(cfg.API.TokenIssuerURL, "/") + "/.well-known/openid-configuration"
+		request, err := http.NewRequest(http.MethodGet, wellKnown, nil)
+		if err != nil {
+			return "", err
+		}
+		response, err := httpClients.Client("token issuer").Do(request.WithContext(ctx))
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GET %s returned status %d", wellKnown, response.StatusCode)
+		}
+		configuration := struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}{}
+		if err := json.NewDecoder(response.Body).Decode(&configuration); err != nil {
+			return "", fmt.Errorf("could not read OpenID configuration from %s: %s", wellKnown, err)
+		}
+		if configuration.JWKSURI == "" {
+			return "", fmt.Errorf("OpenID configuration from %s does not contain jwks_uri", wellKnown)
+		}
+		return fmt.Sprintf("issuer %s is reachable", configuration.Issuer), nil
+	}
+}
+
+// TLSCheck loads the certificates of the https listeners and the CA file of the outbound calls. Expired certificates
+// and certificates which are not valid yet fail the check.
+func TLSCheck(serverSettings *server.Settings, clientSettings *httpclient.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		details := make([]string, 0, len(serverSettings.Listeners)+1)
+		for _, listenerValue := range serverSettings.Listeners {
+			listener, err := server.ParseListener(listenerValue)
+			if err != nil {
+				return "", err
+			}
+			if !listener.TLS() {
+				continue
+			}
+			certificate, err := tls.LoadX509KeyPair(listener.CertFile, listener.KeyFile)
+			if err != nil {
+				return "", fmt.Errorf("could not load TLS certificate of listener %s: %s", listener.Address, err)
+			}
+			leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+			if err != nil {
+				return "", fmt.Errorf("could not parse TLS certificate of listener %s: %s", listener.Address, err)
+			}
+			now := time.Now()
+			if now.After(leaf.NotAfter) {
+				return "", fmt.Errorf("TLS certificate of listener %s expired on %s", listener.Address, leaf.NotAfter.Format(time.RFC3339))
+			}
+			if now.Before(leaf.NotBefore) {
+				return "", fmt.Errorf("TLS certificate of listener %s is not valid before %s", listener.Address, leaf.NotBefore.Format(time.RFC3339))
+			}
+			details = append(details, fmt.Sprintf("certificate of listener %s valid until %s", listener.Address, leaf.NotAfter.Format(time.RFC3339)))
+		}
+		if clientSettings.CAFile != "" {
+			if _, err := httpclient.NewFactory(clientSettings, 0, false); err != nil {
+				return "", err
+			}
+			details = append(details, fmt.Sprintf("CA file %s loaded", clientSettings.CAFile))
+		}
+		if len(details) == 0 {
+			return "no TLS material configured", nil
+		}
+		return strings.Join(details, ", "), nil
+	}
+}
+
+// EncryptionKeyCheck checks that the stored encryption key can be decrypted with the storage encryption key
+func EncryptionKeyCheck(settings *storage.Settings) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if len(settings.EncryptionKey) != 32 {
+			return "", fmt.Errorf("storage encryption key must be exactly 32 symbols long but was %d symbols long", len(settings.EncryptionKey))
+		}
+		err := withDatabase(settings, func(db *sql.DB) error {
+			encrypter := &security.AESEncrypter{}
+			return postgres.VerifyEncryptionKey(ctx, db, []byte(settings.EncryptionKey), encrypter.Decrypt)
+		})
+		if err != nil {
+			return "", err
+		}
+		return "stored encryption key is readable", nil
+	}
+}
+
+func withDatabase(settings *storage.Settings, f func(db *sql.DB) error) error {
+	if settings.URI == "" {
+		return fmt.Errorf("storage URI missing")
+	}
+	db, err := sql.Open("postgres", postgres.ConnectionURL(settings))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return f(db)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package preflight checks whether the Service Manager can start with a configuration, so that problems with its
+// dependencies are reported before the server is started, e.g. by an init container.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Check validates a single precondition of starting the Service Manager. Run returns a short description of what was
+// found or an error describing why the Service Manager cannot start.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string
+	Detail   string
+	Err      error
+	Duration time.Duration
+}
+
+// Report contains the results of the checks in the order they were run
+type Report struct {
+	Results []Result
+}
+
+// Failed returns whether any of the checks failed
+func (r *Report) Failed() bool {
+	return r.FailedCount() > 0
+}
+
+// FailedCount returns the number of failed checks
+func (r *Report) FailedCount() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write writes the report in a human readable form
+func (r *Report) Write(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "Service Manager preflight"); err != nil {
+		return err
+	}
+	for _, result := range r.Results {
+		state, message := "OK", result.Detail
+		if result.Err != nil {
+			state, message = "FAIL", result.Err.Error()
+		}
+		if _, err := fmt.Fprintf(w, "  [%4s] %s: %s (%s)\n", state, result.Name, message, result.Duration.Round(time.Millisecond)); err != nil {
+			return err
+		}
+	}
+	summary := fmt.Sprintf("Preflight passed: all %d checks succeeded", len(r.Results))
+	if r.Failed() {
+		summary = fmt.Sprintf("Preflight failed: %d of %d checks failed", r.FailedCount(), len(r.Results))
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+// Run runs the checks one after the other, each one limited by the timeout. A failed check does not stop the
+// remaining ones, so that all problems are reported at once.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) *Report {
+	report := &Report{Results: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		started := time.Now()
+		detail, err := check.Run(checkCtx)
+		cancel()
+		report.Results = append(report.Results, Result{
+			Name:     check.Name,
+			Detail:   detail,
+			Err:      err,
+			Duration: time.Since(started),
+		})
+	}
+	return report
+}
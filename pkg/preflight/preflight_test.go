@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preflight_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/preflight"
+	"github.com/Peripli/service-manager/pkg/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preflight", func() {
+	Describe("Run", func() {
+		It("runs all checks and reports the failed ones", func() {
+			report := preflight.Run(context.Background(), time.Second,
+				preflight.Check{Name: "first", Run: func(ctx context.Context) (string, error) {
+					return "", errors.New("unreachable")
+				}},
+				preflight.Check{Name: "second", Run: func(ctx context.Context) (string, error) {
+					return "fine", nil
+				}},
+			)
+			Expect(report.Results).To(HaveLen(2))
+			Expect(report.Failed()).To(BeTrue())
+			Expect(report.FailedCount()).To(Equal(1))
+
+			output := &bytes.Buffer{}
+			Expect(report.Write(output)).To(Succeed())
+			Expect(output.String()).To(ContainSubstring("[FAIL] first: unreachable"))
+			Expect(output.String()).To(ContainSubstring("[  OK] second: fine"))
+			Expect(output.String()).To(ContainSubstring("Preflight failed: 1 of 2 checks failed"))
+		})
+
+		It("limits each check by the timeout", func() {
+			report := preflight.Run(context.Background(), 10*time.Millisecond,
+				preflight.Check{Name: "slow", Run: func(ctx context.Context) (string, error) {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}},
+			)
+			Expect(report.Results[0].Err).To(Equal(context.DeadlineExceeded))
+		})
+	})
+
+	Describe("TokenIssuerCheck", func() {
+		var (
+			issuer   *httptest.Server
+			response string
+			cfg      *config.Settings
+		)
+
+		BeforeEach(func() {
+			response = `{"issuer": "https://uaa.example.com", "jwks_uri": "https://uaa.example.com/token_keys"}`
+			issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/.well-known/openid-configuration" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(response))
+			}))
+			cfg = config.DefaultSettings()
+			cfg.API.TokenIssuerURL = issuer.URL
+		})
+
+		AfterEach(func() {
+			issuer.Close()
+		})
+
+		It("succeeds when the OpenID configuration can be read", func() {
+			detail, err := preflight.TokenIssuerCheck(cfg)(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(detail).To(Equal("issuer https://uaa.example.com is reachable"))
+		})
+
+		It("fails when the OpenID configuration has no keys", func() {
+			response = `{"issuer": "https://uaa.example.com"}`
+			_, err := preflight.TokenIssuerCheck(cfg)(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("does not contain jwks_uri")))
+		})
+
+		It("fails when the token issuer does not provide an OpenID configuration", func() {
+			cfg.API.TokenIssuerURL = issuer.URL + "/missing"
+			_, err := preflight.TokenIssuerCheck(cfg)(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("returned status 404")))
+		})
+	})
+
+	Describe("TLSCheck", func() {
+		It("succeeds when there is no TLS material", func() {
+			serverSettings := server.DefaultSettings()
+			serverSettings.Listeners = []string{"http://0.0.0.0:8080"}
+			detail, err := preflight.TLSCheck(serverSettings, httpclient.DefaultSettings())(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(detail).To(Equal("no TLS material configured"))
+		})
+
+		It("fails when the certificate of a listener cannot be loaded", func() {
+			serverSettings := server.DefaultSettings()
+			serverSettings.Listeners = []string{"https://0.0.0.0:8443?cert_file=missing.crt&key_file=missing.key"}
+			_, err := preflight.TLSCheck(serverSettings, httpclient.DefaultSettings())(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("could not load TLS certificate of listener 0.0.0.0:8443")))
+		})
+	})
+})
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+// OperatorDescription describes the operands which an operator accepts
+type OperatorDescription struct {
+	Name         Operator `json:"name"`
+	MultiVariate bool     `json:"multivariate"`
+	Numeric      bool     `json:"numeric"`
+	Nullable     bool     `json:"nullable"`
+}
+
+// Grammar describes the syntax of the queries so that clients can build queries without hardcoding it
+type Grammar struct {
+	CriterionTypes      []CriterionType       `json:"criterion_types"`
+	Operators           []OperatorDescription `json:"operators"`
+	Separator           string                `json:"separator"`
+	OperandSeparator    string                `json:"operand_separator"`
+	MultiValueStart     string                `json:"multivalue_start"`
+	MultiValueEnd       string                `json:"multivalue_end"`
+	MultiValueSeparator string                `json:"multivalue_separator"`
+	EscapeCharacter     string                `json:"escape_character"`
+}
+
+// DescribeGrammar returns the description of the grammar which the query parser accepts
+func DescribeGrammar() *Grammar {
+	descriptions := make([]OperatorDescription, 0, len(operators))
+	for _, op := range operators {
+		descriptions = append(descriptions, OperatorDescription{
+			Name:         op,
+			MultiVariate: op.IsMultiVariate(),
+			Numeric:      op.IsNumeric(),
+			Nullable:     op.IsNullable(),
+		})
+	}
+	return &Grammar{
+		CriterionTypes:      append([]CriterionType{}, supportedQueryTypes...),
+		Operators:           descriptions,
+		Separator:           string(Separator),
+		OperandSeparator:    string(OperandSeparator),
+		MultiValueStart:     string(OpenBracket),
+		MultiValueEnd:       string(CloseBracket),
+		MultiValueSeparator: string([]rune{Separator, Separator}),
+		EscapeCharacter:     `\`,
+	}
+}
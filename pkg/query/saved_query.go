@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// VisibleSavedQueries returns the criterion which selects the saved queries of the user and the shared ones, which
+// have no owner
+func VisibleSavedQueries(user string) Criterion {
+	return ByField(EqualsOrNilOperator, "owner", user)
+}
+
+// SavedQueryCriteria parses the field and label queries and the order of the saved query into criteria
+func SavedQueryCriteria(savedQuery *types.SavedQuery) ([]Criterion, error) {
+	fieldCriteria, err := Parse(FieldQuery, savedQuery.FieldQuery)
+	if err != nil {
+		return nil, err
+	}
+	labelCriteria, err := Parse(LabelQuery, savedQuery.LabelQuery)
+	if err != nil {
+		return nil, err
+	}
+	criteria, err := mergeCriteria(nil, append(fieldCriteria, labelCriteria...))
+	if err != nil {
+		return nil, err
+	}
+	if savedQuery.OrderBy != "" {
+		order := OrderType(savedQuery.Order)
+		if order == "" {
+			order = AscOrder
+		}
+		if order != AscOrder && order != DescOrder {
+			return nil, &util.UnsupportedQueryError{Message: fmt.Sprintf("unsupported order %s of saved query %s, the order is either %s or %s", order, savedQuery.Name, AscOrder, DescOrder)}
+		}
+		criteria = append(criteria, OrderResultBy(savedQuery.OrderBy, order))
+	}
+	return criteria, nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Saved query criteria", func() {
+	It("parses the field and label queries and the order", func() {
+		criteria, err := SavedQueryCriteria(&types.SavedQuery{
+			FieldQuery: "name = broker",
+			LabelQuery: "env in [dev||test]",
+			OrderBy:    "created_at",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(criteria).To(ConsistOf(
+			ByField(EqualsOperator, "name", "broker"),
+			ByLabel(InOperator, "env", "dev", "test"),
+			OrderResultBy("created_at", AscOrder),
+		))
+	})
+
+	It("rejects invalid queries", func() {
+		_, err := SavedQueryCriteria(&types.SavedQuery{FieldQuery: "name in broker"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects duplicate field query keys", func() {
+		_, err := SavedQueryCriteria(&types.SavedQuery{FieldQuery: "name = a|name = b"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown orders", func() {
+		_, err := SavedQueryCriteria(&types.SavedQuery{OrderBy: "name", Order: "random"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Peripli/service-manager/pkg/util"
@@ -72,6 +73,11 @@ func (op Operator) IsNumeric() bool {
 var operators = []Operator{EqualsOperator, NotEqualsOperator, InOperator,
 	NotInOperator, GreaterThanOperator, GreaterThanOrEqualOperator, LessThanOperator, LessThanOrEqualOperator, EqualsOrNilOperator}
 
+// Operators returns the operators supported in field and label queries
+func Operators() []Operator {
+	return append([]Operator{}, operators...)
+}
+
 const (
 	// OpenBracket is the token that denotes the beginning of a multivariate operand
 	OpenBracket rune = '['
@@ -151,6 +157,18 @@ func newCriterion(leftOp string, operator Operator, rightOp []string, criteriaTy
 	return Criterion{LeftOp: leftOp, Operator: operator, RightOp: rightOp, Type: criteriaType}
 }
 
+// String returns the criterion in the syntax of the query it belongs to, e.g. fieldQuery name = broker
+func (c Criterion) String() string {
+	rightOp := strings.Join(c.RightOp, ",")
+	if c.Operator.IsMultiVariate() {
+		rightOp = fmt.Sprintf("%c%s%c", OpenBracket, rightOp, CloseBracket)
+	}
+	if c.Operator == NoOperator {
+		return fmt.Sprintf("%s %s %s", c.Type, c.LeftOp, rightOp)
+	}
+	return fmt.Sprintf("%s %s %s %s", c.Type, c.LeftOp, c.Operator, rightOp)
+}
+
 // Validate the criterion fields
 func (c Criterion) Validate() error {
 	if c.Type == ResultQuery {
@@ -242,6 +260,7 @@ func AddCriteria(ctx context.Context, newCriteria ...Criterion) (context.Context
 	if err != nil {
 		return nil, err
 	}
+	recordCriteria(ctx, criteria)
 	return context.WithValue(ctx, criteriaCtxKey{}, criteria), nil
 }
 
@@ -256,9 +275,43 @@ func CriteriaForContext(ctx context.Context) []Criterion {
 
 // ContextWithCriteria returns a new context with given criteria
 func ContextWithCriteria(ctx context.Context, criteria []Criterion) context.Context {
+	recordCriteria(ctx, criteria)
 	return context.WithValue(ctx, criteriaCtxKey{}, criteria)
 }
 
+type criteriaRecorderCtxKey struct{}
+
+// CriteriaRecorder records the criteria of a context and the contexts derived from it, so that the criteria
+// effectively applied by the filters and controllers processing a request can be inspected after it is handled
+type CriteriaRecorder struct {
+	mutex    sync.Mutex
+	criteria []Criterion
+}
+
+// ContextWithCriteriaRecorder returns a new context whose criteria and the criteria of the contexts derived from it
+// are recorded by the returned recorder
+func ContextWithCriteriaRecorder(ctx context.Context) (context.Context, *CriteriaRecorder) {
+	recorder := &CriteriaRecorder{criteria: CriteriaForContext(ctx)}
+	return context.WithValue(ctx, criteriaRecorderCtxKey{}, recorder), recorder
+}
+
+// Criteria returns the most recently set criteria
+func (r *CriteriaRecorder) Criteria() []Criterion {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.criteria
+}
+
+func recordCriteria(ctx context.Context, criteria []Criterion) {
+	recorder, ok := ctx.Value(criteriaRecorderCtxKey{}).(*CriteriaRecorder)
+	if !ok {
+		return
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.criteria = criteria
+}
+
 // BuildCriteriaFromRequest builds criteria for the given request's query params and returns an error if the query is not valid
 func BuildCriteriaFromRequest(request *http.Request) ([]Criterion, error) {
 	var criteria []Criterion
@@ -272,10 +325,80 @@ func BuildCriteriaFromRequest(request *http.Request) ([]Criterion, error) {
 			return nil, err
 		}
 	}
-	sort.Sort(ByLeftOp(criteria))
-	return criteria, nil
+	return Normalize(criteria), nil
+}
+
+// Parse parses a query of the given type, e.g. the value of the fieldQuery query parameter, into criteria
+func Parse(criteriaType CriterionType, input string) ([]Criterion, error) {
+	return process(input, criteriaType)
+}
+
+// Normalize returns the criteria in a canonical order, so that logically identical queries produce identical SQL and
+// cache keys. The field criteria come first and the label criteria second. Each group is sorted by left operand,
+// operator and right operands. The values of multivariate right operands are sorted and deduplicated. The result
+// criteria come last and keep their order, since the order of the orderBy criteria is significant. The provided
+// criteria are not modified.
+func Normalize(criteria []Criterion) []Criterion {
+	normalized := make([]Criterion, 0, len(criteria))
+	for _, criterion := range criteria {
+		if criterion.Operator.IsMultiVariate() {
+			criterion.RightOp = normalizeValues(criterion.RightOp)
+		}
+		normalized = append(normalized, criterion)
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return criterionLess(normalized[i], normalized[j])
+	})
+	return normalized
+}
+
+func normalizeValues(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	unique := result[:0]
+	for i, value := range result {
+		if i == 0 || value != result[i-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func criterionLess(c1, c2 Criterion) bool {
+	if c1.Type != c2.Type {
+		return criterionTypeRank(c1.Type) < criterionTypeRank(c2.Type)
+	}
+	if c1.Type == ResultQuery {
+		return false
+	}
+	if c1.LeftOp != c2.LeftOp {
+		return c1.LeftOp < c2.LeftOp
+	}
+	if c1.Operator != c2.Operator {
+		return c1.Operator < c2.Operator
+	}
+	for i := 0; i < len(c1.RightOp) && i < len(c2.RightOp); i++ {
+		if c1.RightOp[i] != c2.RightOp[i] {
+			return c1.RightOp[i] < c2.RightOp[i]
+		}
+	}
+	return len(c1.RightOp) < len(c2.RightOp)
+}
+
+func criterionTypeRank(criterionType CriterionType) int {
+	switch criterionType {
+	case FieldQuery:
+		return 0
+	case LabelQuery:
+		return 1
+	default:
+		return 2
+	}
 }
 
+// ByLeftOp sorts criteria by their left operand.
+//
+// Deprecated: Normalize orders the criteria canonically and should be used instead.
 type ByLeftOp []Criterion
 
 func (c ByLeftOp) Len() int {
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package query_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/query"
+)
+
+func benchmarkBuildCriteria(b *testing.B, fieldQuery, labelQuery string) {
+	values := url.Values{}
+	if fieldQuery != "" {
+		values.Set(string(query.FieldQuery), fieldQuery)
+	}
+	if labelQuery != "" {
+		values.Set(string(query.LabelQuery), labelQuery)
+	}
+	request, err := http.NewRequest(http.MethodGet, "http://localhost/v1/visibilities?"+values.Encode(), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := query.BuildCriteriaFromRequest(request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func repeat(count int, separator string, element func(i int) string) string {
+	elements := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		elements = append(elements, element(i))
+	}
+	return strings.Join(elements, separator)
+}
+
+func BenchmarkBuildCriteriaFromRequestSimple(b *testing.B) {
+	benchmarkBuildCriteria(b, "platform_id = 123", "env = dev")
+}
+
+func BenchmarkBuildCriteriaFromRequestManyCriteria(b *testing.B) {
+	benchmarkBuildCriteria(b,
+		repeat(500, "|", func(i int) string { return fmt.Sprintf("field%d = value%d", i, i) }),
+		repeat(500, "|", func(i int) string { return fmt.Sprintf("label%d = value%d", i, i) }))
+}
+
+func BenchmarkBuildCriteriaFromRequestLargeInOperator(b *testing.B) {
+	benchmarkBuildCriteria(b,
+		"id in ["+repeat(5000, "||", func(i int) string { return fmt.Sprintf("id-%d", i) })+"]",
+		"")
+}
+
+func BenchmarkBuildCriteriaFromRequestLongValues(b *testing.B) {
+	value := strings.Repeat("a", 64*1024)
+	benchmarkBuildCriteria(b, "name = "+value, "description = "+value)
+}
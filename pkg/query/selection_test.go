@@ -105,6 +105,33 @@ var _ = Describe("Selection", func() {
 		})
 	})
 
+	Describe("Criteria recorder", func() {
+		It("Records the criteria of derived contexts", func() {
+			recordedContext, recorder := ContextWithCriteriaRecorder(ContextWithCriteria(ctx, []Criterion{validCriterion}))
+			Expect(recorder.Criteria()).To(ConsistOf(validCriterion))
+
+			newCriterion := ByLabel(InOperator, "leftOp", "1", "2")
+			_, err := AddCriteria(recordedContext, newCriterion)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Criteria()).To(ConsistOf(validCriterion, newCriterion))
+		})
+
+		It("Does not record the criteria of other contexts", func() {
+			_, recorder := ContextWithCriteriaRecorder(ctx)
+			_, err := AddCriteria(ctx, validCriterion)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Criteria()).To(BeEmpty())
+		})
+	})
+
+	Describe("Criterion string", func() {
+		It("Formats the criterion like a query", func() {
+			Expect(validCriterion.String()).To(Equal("fieldQuery left = right"))
+			Expect(ByLabel(NotInOperator, "left", "a", "b").String()).To(Equal("labelQuery left notin [a,b]"))
+			Expect(LimitResultBy(10).String()).To(Equal("resultQuery limit 10"))
+		})
+	})
+
 	Describe("Build criteria from request", func() {
 		buildCriteria := func(url string) ([]Criterion, error) {
 			newRequest, err := http.NewRequest(http.MethodGet, url, nil)
@@ -315,3 +342,64 @@ var _ = Describe("Selection", func() {
 		})
 	})
 })
+
+var _ = Describe("Normalize", func() {
+	It("orders the field criteria before the label criteria and the result criteria last", func() {
+		normalized := Normalize([]Criterion{
+			OrderResultBy("name", DescOrder),
+			ByLabel(EqualsOperator, "env", "dev"),
+			LimitResultBy(10),
+			ByField(EqualsOperator, "name", "broker"),
+		})
+		Expect(normalized).To(Equal([]Criterion{
+			ByField(EqualsOperator, "name", "broker"),
+			ByLabel(EqualsOperator, "env", "dev"),
+			OrderResultBy("name", DescOrder),
+			LimitResultBy(10),
+		}))
+	})
+
+	It("sorts the criteria by left operand, operator and right operands", func() {
+		normalized := Normalize([]Criterion{
+			ByField(GreaterThanOperator, "created_at", "2020-01-01T00:00:00Z"),
+			ByField(EqualsOperator, "name", "b"),
+			ByField(EqualsOperator, "name", "a"),
+			ByField(LessThanOperator, "created_at", "2021-01-01T00:00:00Z"),
+		})
+		Expect(normalized).To(Equal([]Criterion{
+			ByField(GreaterThanOperator, "created_at", "2020-01-01T00:00:00Z"),
+			ByField(LessThanOperator, "created_at", "2021-01-01T00:00:00Z"),
+			ByField(EqualsOperator, "name", "a"),
+			ByField(EqualsOperator, "name", "b"),
+		}))
+	})
+
+	It("keeps the order of the result criteria", func() {
+		criteria := []Criterion{OrderResultBy("name", AscOrder), OrderResultBy("created_at", DescOrder)}
+		Expect(Normalize(criteria)).To(Equal(criteria))
+	})
+
+	It("sorts and deduplicates the values of multivariate operands", func() {
+		normalized := Normalize([]Criterion{ByLabel(InOperator, "env", "test", "dev", "test")})
+		Expect(normalized).To(Equal([]Criterion{ByLabel(InOperator, "env", "dev", "test")}))
+	})
+
+	It("produces the same criteria for the same query in a different order", func() {
+		buildCriteria := func(url string) ([]Criterion, error) {
+			newRequest, err := http.NewRequest(http.MethodGet, url, nil)
+			Expect(err).ToNot(HaveOccurred())
+			return BuildCriteriaFromRequest(newRequest)
+		}
+		c1, err := buildCriteria("http://localhost:8080/v1/visibilities?fieldQuery=b = 1|a in [y||x]&labelQuery=env = dev")
+		Expect(err).ToNot(HaveOccurred())
+		c2, err := buildCriteria("http://localhost:8080/v1/visibilities?labelQuery=env = dev&fieldQuery=a in [x||y]|b = 1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1).To(Equal(c2))
+	})
+
+	It("does not modify the provided criteria", func() {
+		criteria := []Criterion{ByField(InOperator, "name", "b", "a"), ByField(EqualsOperator, "id", "1")}
+		Normalize(criteria)
+		Expect(criteria).To(Equal([]Criterion{ByField(InOperator, "name", "b", "a"), ByField(EqualsOperator, "id", "1")}))
+	})
+})
@@ -19,6 +19,7 @@ package query
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/Peripli/service-manager/pkg/types"
 
@@ -141,3 +142,55 @@ func ApplyLabelChangesToLabels(changes LabelChanges, labels types.Labels) (types
 
 	return mergedLabels, labelsToAdd, labelsToRemove
 }
+
+// LabelChangesBetween returns the label changes which turn the current labels into the desired labels
+func LabelChangesBetween(current, desired types.Labels) []*LabelChange {
+	keys := make([]string, 0, len(current)+len(desired))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range desired {
+		if _, found := current[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*LabelChange, 0)
+	for _, key := range keys {
+		currentValues, inCurrent := current[key]
+		desiredValues, inDesired := desired[key]
+		switch {
+		case !inDesired:
+			changes = append(changes, &LabelChange{Operation: RemoveLabelOperation, Key: key})
+		case !inCurrent:
+			changes = append(changes, &LabelChange{Operation: AddLabelOperation, Key: key, Values: desiredValues})
+		default:
+			if added := missingValues(desiredValues, currentValues); len(added) > 0 {
+				changes = append(changes, &LabelChange{Operation: AddLabelValuesOperation, Key: key, Values: added})
+			}
+			if removed := missingValues(currentValues, desiredValues); len(removed) > 0 {
+				changes = append(changes, &LabelChange{Operation: RemoveLabelValuesOperation, Key: key, Values: removed})
+			}
+		}
+	}
+	return changes
+}
+
+// missingValues returns the values which are not contained in the other values
+func missingValues(values, otherValues []string) []string {
+	var result []string
+	for _, value := range values {
+		found := false
+		for _, otherValue := range otherValues {
+			if value == otherValue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -259,4 +259,26 @@ var _ = Describe("Update", func() {
 			}, entries...)
 		})
 	})
+
+	Describe("Label changes between labels", func() {
+		It("returns the changes which turn the current labels into the desired ones", func() {
+			current := types.Labels{"keep": {"a"}, "change": {"a", "b"}, "drop": {"a"}}
+			desired := types.Labels{"keep": {"a"}, "change": {"b", "c"}, "new": {"a"}}
+
+			changes := LabelChangesBetween(current, desired)
+			Expect(changes).To(Equal([]*LabelChange{
+				{Operation: AddLabelValuesOperation, Key: "change", Values: []string{"c"}},
+				{Operation: RemoveLabelValuesOperation, Key: "change", Values: []string{"a"}},
+				{Operation: RemoveLabelOperation, Key: "drop"},
+				{Operation: AddLabelOperation, Key: "new", Values: []string{"a"}},
+			}))
+
+			merged, _, _ := ApplyLabelChangesToLabels(changes, current)
+			Expect(merged).To(Equal(desired))
+		})
+
+		It("returns no changes for equal labels", func() {
+			Expect(LabelChangesBetween(types.Labels{"key": {"a"}}, types.Labels{"key": {"a"}})).To(BeEmpty())
+		})
+	})
 })
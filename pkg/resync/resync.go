@@ -0,0 +1,233 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package resync contains logic for periodically synchronizing the stored broker catalogs with the actual ones
+package resync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
+)
+
+const (
+	// JobName is the name under which the catalog resync job is registered
+	JobName = "catalog_resync"
+
+	// IntervalLabel is the broker label which overrides the resync interval for a single broker.
+	// The value is a duration (e.g. 30m) and a value of 0 disables the resync for the broker.
+	IntervalLabel = "catalog_resync_interval"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	Enabled       bool          `mapstructure:"enabled" description:"whether the catalogs of the registered brokers should be periodically resynchronized"`
+	Interval      time.Duration `mapstructure:"interval" description:"time between two resynchronizations of the catalog of a broker unless overridden by a broker label"`
+	Jitter        time.Duration `mapstructure:"jitter" description:"maximum random delay added to the resync interval of each broker"`
+	CheckInterval time.Duration `mapstructure:"check_interval" description:"time between checks for brokers which catalogs are due for resync"`
+}
+
+// DefaultSettings returns default values for catalog resync settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		Enabled:       false,
+		Interval:      time.Hour,
+		Jitter:        5 * time.Minute,
+		CheckInterval: time.Minute,
+	}
+}
+
+// Validate validates the catalog resync settings
+func (s *Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.Interval <= 0 {
+		return fmt.Errorf("validate Settings: catalog resync interval must be > 0")
+	}
+	if s.Jitter < 0 {
+		return fmt.Errorf("validate Settings: catalog resync jitter must be >= 0")
+	}
+	if s.CheckInterval <= 0 {
+		return fmt.Errorf("validate Settings: catalog resync check interval must be > 0")
+	}
+	return nil
+}
+
+// CatalogFetcher fetches the catalog of the provided broker
+type CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+
+type brokerState struct {
+	lastResync time.Time
+	jitter     time.Duration
+}
+
+// Job is a background job which fetches the catalogs of the registered brokers that are due for resync and
+// updates the brokers whose catalogs have changed. The update persists the new catalog and emits notifications.
+// Catalogs are fetched conditionally, so brokers which support ETag or Last-Modified validators need not send
+// their catalogs if they have not changed.
+type Job struct {
+	settings   *Settings
+	repository storage.Repository
+	fetcher    CatalogFetcher
+
+	mutex   sync.Mutex
+	brokers map[string]*brokerState
+}
+
+// NewJob returns a catalog resync job which uses the provided fetcher to obtain the actual broker catalogs
+func NewJob(settings *Settings, repository storage.Repository, fetcher CatalogFetcher) *Job {
+	return &Job{
+		settings:   settings,
+		repository: repository,
+		fetcher:    fetcher,
+		brokers:    make(map[string]*brokerState),
+	}
+}
+
+// Name implements jobs.Job
+func (j *Job) Name() string {
+	return JobName
+}
+
+// Run implements jobs.Job and resyncs the catalogs of the brokers which are due
+func (j *Job) Run(ctx context.Context) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	brokers, err := j.repository.List(ctx, types.ServiceBrokerType)
+	if err != nil {
+		return fmt.Errorf("could not list brokers: %s", err)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, brokers.Len())
+	failed := make([]string, 0)
+	for i := 0; i < brokers.Len(); i++ {
+		broker := brokers.ItemAt(i).(*types.ServiceBroker)
+		seen[broker.ID] = true
+
+		interval := j.intervalFor(ctx, broker)
+		if interval <= 0 {
+			continue
+		}
+
+		state := j.stateFor(broker)
+		if now.Before(state.lastResync.Add(interval + state.jitter)) {
+			continue
+		}
+		state.lastResync = now
+
+		if err := j.resync(ctx, broker); err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not resync catalog of broker with name %s", broker.Name)
+			failed = append(failed, broker.Name)
+		}
+	}
+
+	for brokerID := range j.brokers {
+		if !seen[brokerID] {
+			delete(j.brokers, brokerID)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not resync catalogs of brokers %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (j *Job) intervalFor(ctx context.Context, broker *types.ServiceBroker) time.Duration {
+	values, found := broker.Labels[IntervalLabel]
+	if !found || len(values) == 0 {
+		return j.settings.Interval
+	}
+
+	interval, err := time.ParseDuration(values[0])
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Invalid value %s of label %s of broker with name %s, using the default resync interval", values[0], IntervalLabel, broker.Name)
+		return j.settings.Interval
+	}
+	return interval
+}
+
+func (j *Job) stateFor(broker *types.ServiceBroker) *brokerState {
+	state, found := j.brokers[broker.ID]
+	if !found {
+		state = &brokerState{
+			lastResync: broker.UpdatedAt,
+		}
+		if j.settings.Jitter > 0 {
+			state.jitter = time.Duration(rand.Int63n(int64(j.settings.Jitter)))
+		}
+		j.brokers[broker.ID] = state
+	}
+	return state
+}
+
+func (j *Job) resync(ctx context.Context, broker *types.ServiceBroker) error {
+	actualCatalog, err := j.fetcher(catalog.ContextWithConditionalFetch(ctx), broker)
+	if err == catalog.ErrNotModified {
+		log.C(ctx).Debugf("Catalog of broker with name %s has not been modified", broker.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changed, err := catalogChanged(broker.Catalog, actualCatalog)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		log.C(ctx).Debugf("Catalog of broker with name %s is up to date", broker.Name)
+		return nil
+	}
+
+	log.C(ctx).Infof("Catalog of broker with name %s has changed, updating broker", broker.Name)
+	broker.UpdatedAt = time.Now().UTC()
+	// the broker interceptors store the catalog fetched above instead of fetching it again
+	_, err = j.repository.Update(catalog.ContextWithFetchedCatalog(ctx, actualCatalog), broker)
+	return err
+}
+
+func catalogChanged(stored, actual []byte) (bool, error) {
+	if bytes.Equal(stored, actual) {
+		return false, nil
+	}
+	if len(stored) == 0 {
+		return true, nil
+	}
+
+	var storedCatalog, actualCatalog interface{}
+	if err := json.Unmarshal(stored, &storedCatalog); err != nil {
+		return true, nil
+	}
+	if err := json.Unmarshal(actual, &actualCatalog); err != nil {
+		return false, fmt.Errorf("could not parse broker catalog: %s", err)
+	}
+	return !reflect.DeepEqual(storedCatalog, actualCatalog), nil
+}
@@ -0,0 +1,179 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package resync_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/resync"
+	"github.com/Peripli/service-manager/pkg/types"
+	storagecatalog "github.com/Peripli/service-manager/storage/catalog"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog resync job", func() {
+	const catalog = `{"services":[{"id":"service-id","name":"service"}]}`
+
+	var (
+		ctx          context.Context
+		settings     *resync.Settings
+		repository   *storagefakes.FakeStorage
+		broker       *types.ServiceBroker
+		fetchedCount int
+		fetchedBytes []byte
+		fetchErr     error
+		conditional  bool
+		job          *resync.Job
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		settings = resync.DefaultSettings()
+		settings.Enabled = true
+		settings.Jitter = 0
+
+		broker = &types.ServiceBroker{
+			Base: types.Base{
+				ID:        "broker-id",
+				UpdatedAt: time.Now().Add(-2 * settings.Interval),
+				Labels:    types.Labels{},
+			},
+			Name:    "broker",
+			Catalog: []byte(catalog),
+		}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			return &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker}}, nil
+		})
+		repository.UpdateCalls(func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+			return obj, nil
+		})
+
+		fetchedCount = 0
+		fetchedBytes = []byte(catalog)
+		fetchErr = nil
+		conditional = false
+		job = resync.NewJob(settings, repository, func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+			fetchedCount++
+			conditional = storagecatalog.IsConditionalFetch(ctx)
+			return fetchedBytes, fetchErr
+		})
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(resync.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid when enabled with a non positive check interval", func() {
+			settings.CheckInterval = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Context("when the broker catalog has not changed", func() {
+		It("does not update the broker", func() {
+			fetchedBytes = []byte(`{ "services": [ {"name":"service", "id":"service-id"} ] }`)
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(1))
+			Expect(repository.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the broker reports that its catalog has not been modified", func() {
+		It("fetches the catalog conditionally and does not update the broker", func() {
+			fetchedBytes = nil
+			fetchErr = storagecatalog.ErrNotModified
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(conditional).To(BeTrue())
+			Expect(repository.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the broker catalog has changed", func() {
+		It("updates the broker", func() {
+			fetchedBytes = []byte(`{"services":[]}`)
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(repository.UpdateCallCount()).To(Equal(1))
+			_, updatedBroker, _ := repository.UpdateArgsForCall(0)
+			Expect(updatedBroker.GetID()).To(Equal(broker.ID))
+		})
+
+		It("passes the fetched catalog to the update so that it is not fetched again", func() {
+			fetchedBytes = []byte(`{"services":[]}`)
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(1))
+			updateCtx, _, _ := repository.UpdateArgsForCall(0)
+			fetchedCatalog, found := storagecatalog.FetchedCatalog(updateCtx)
+			Expect(found).To(BeTrue())
+			Expect(string(fetchedCatalog)).To(Equal(`{"services":[]}`))
+		})
+	})
+
+	Context("when the broker was resynced recently", func() {
+		It("does not fetch its catalog again", func() {
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(1))
+		})
+	})
+
+	Context("when the broker overrides the interval", func() {
+		It("disables the resync when the interval is 0", func() {
+			broker.Labels[resync.IntervalLabel] = []string{"0"}
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(0))
+		})
+
+		It("uses the broker interval", func() {
+			broker.Labels[resync.IntervalLabel] = []string{"1ns"}
+
+			Expect(job.Run(ctx)).To(Succeed())
+			time.Sleep(time.Millisecond)
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(2))
+		})
+
+		It("falls back to the default interval when the label is invalid", func() {
+			broker.Labels[resync.IntervalLabel] = []string{"invalid"}
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(fetchedCount).To(Equal(1))
+		})
+	})
+
+	Context("when fetching the catalog fails", func() {
+		It("returns an error", func() {
+			fetchErr = errors.New("unreachable")
+
+			Expect(job.Run(ctx)).To(HaveOccurred())
+			Expect(repository.UpdateCallCount()).To(Equal(0))
+		})
+	})
+})
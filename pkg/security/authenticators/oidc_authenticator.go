@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	httpsec "github.com/Peripli/service-manager/pkg/security/http"
 
@@ -55,8 +56,17 @@ type OIDCOptions struct {
 	// ClientID is the id of the oauth client used to verify the tokens
 	ClientID string
 
-	// ReadConfigurationFunc is the function used to call the token issuer. If one is not provided, http.DefaultClient.Do will be used
+	// ReadConfigurationFunc is the function used to call the token issuer. If one is not provided, Client.Do will be used
 	ReadConfigurationFunc util.DoRequestFunc
+
+	// Client is the client used to call the token issuer and fetch its keys. If one is not provided, http.DefaultClient will be used
+	Client *http.Client
+
+	// VerificationCacheSize is the maximum number of verified tokens whose verification is reused, tokens are verified on every call if it is 0
+	VerificationCacheSize int
+
+	// VerificationCacheTTL is the time for which the verification of a token is reused, at most until the token expires
+	VerificationCacheTTL time.Duration
 }
 
 type oidcVerifier struct {
@@ -100,10 +110,18 @@ func NewOIDCAuthenticator(ctx context.Context, options *OIDCOptions) (httpsec.Au
 		return nil, "", fmt.Errorf("error decoding body of response with status %s: %s", resp.Status, err.Error())
 	}
 
-	keySet := goidc.NewRemoteKeySet(ctx, p.JWKSURL)
-	return &OauthAuthenticator{Verifier: &oidcVerifier{
+	keySetCtx := ctx
+	if options.Client != nil {
+		keySetCtx = goidc.ClientContext(ctx, options.Client)
+	}
+	keySet := goidc.NewRemoteKeySet(keySetCtx, p.JWKSURL)
+	var verifier httpsec.TokenVerifier = &oidcVerifier{
 		IDTokenVerifier: goidc.NewVerifier(p.Issuer, keySet, newOIDCConfig(options)),
-	}}, p.Issuer, nil
+	}
+	if options.VerificationCacheSize > 0 && options.VerificationCacheTTL > 0 {
+		verifier = newCachingVerifier(verifier, options.VerificationCacheSize, options.VerificationCacheTTL)
+	}
+	return &OauthAuthenticator{Verifier: verifier}, p.Issuer, nil
 }
 
 func newOIDCConfig(options *OIDCOptions) *goidc.Config {
@@ -155,6 +173,8 @@ func getOpenIDConfig(ctx context.Context, options *OIDCOptions) (*http.Response,
 	var readConfigFunc util.DoRequestFunc
 	if options.ReadConfigurationFunc != nil {
 		readConfigFunc = options.ReadConfigurationFunc
+	} else if options.Client != nil {
+		readConfigFunc = options.Client.Do
 	} else {
 		readConfigFunc = http.DefaultClient.Do
 	}
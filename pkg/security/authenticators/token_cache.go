@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+)
+
+// cachingVerifier reuses the verification of recently verified tokens, so that bursts of calls carrying the same
+// bearer token do not verify its signature again. The tokens are kept as hashes in a bounded LRU cache for a short
+// time, at most until they expire. Failed verifications are not cached.
+type cachingVerifier struct {
+	httpsec.TokenVerifier
+
+	size int
+	ttl  time.Duration
+
+	mutex   sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+}
+
+type cachedToken struct {
+	key       [sha256.Size]byte
+	token     httpsec.TokenData
+	expiresAt time.Time
+}
+
+func newCachingVerifier(verifier httpsec.TokenVerifier, size int, ttl time.Duration) *cachingVerifier {
+	return &cachingVerifier{
+		TokenVerifier: verifier,
+		size:          size,
+		ttl:           ttl,
+		entries:       make(map[[sha256.Size]byte]*list.Element),
+		order:         list.New(),
+	}
+}
+
+// Verify returns the cached verification of the token or verifies it with the underlying verifier
+func (v *cachingVerifier) Verify(ctx context.Context, token string) (httpsec.TokenData, error) {
+	key := sha256.Sum256([]byte(token))
+	if tokenData, found := v.get(key); found {
+		return tokenData, nil
+	}
+	tokenData, err := v.TokenVerifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	v.put(key, tokenData)
+	return tokenData, nil
+}
+
+func (v *cachingVerifier) get(key [sha256.Size]byte) (httpsec.TokenData, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	element, found := v.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*cachedToken)
+	if !time.Now().Before(entry.expiresAt) {
+		v.order.Remove(element)
+		delete(v.entries, key)
+		return nil, false
+	}
+	v.order.MoveToFront(element)
+	return entry.token, true
+}
+
+func (v *cachingVerifier) put(key [sha256.Size]byte, tokenData httpsec.TokenData) {
+	expiresAt := time.Now().Add(v.ttl)
+	expiry := &struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := tokenData.Claims(expiry); err == nil && expiry.Exp > 0 {
+		if tokenExpiry := time.Unix(expiry.Exp, 0); tokenExpiry.Before(expiresAt) {
+			expiresAt = tokenExpiry
+		}
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if element, found := v.entries[key]; found {
+		v.order.Remove(element)
+	}
+	v.entries[key] = v.order.PushFront(&cachedToken{key: key, token: tokenData, expiresAt: expiresAt})
+	for v.order.Len() > v.size {
+		oldest := v.order.Back()
+		v.order.Remove(oldest)
+		delete(v.entries, oldest.Value.(*cachedToken).key)
+	}
+}
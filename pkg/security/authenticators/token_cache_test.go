@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/security/http/httpfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Caching token verifier", func() {
+	var (
+		fakeVerifier *httpfakes.FakeTokenVerifier
+		verifier     *cachingVerifier
+		expiry       time.Time
+	)
+
+	BeforeEach(func() {
+		expiry = time.Now().Add(time.Hour)
+		fakeVerifier = &httpfakes.FakeTokenVerifier{}
+		fakeVerifier.VerifyCalls(func(ctx context.Context, token string) (httpsec.TokenData, error) {
+			if token == "invalid" {
+				return nil, errors.New("invalid token")
+			}
+			tokenData := &httpfakes.FakeTokenData{}
+			tokenData.ClaimsCalls(func(v interface{}) error {
+				return json.Unmarshal([]byte(fmt.Sprintf(`{"exp": %d}`, expiry.Unix())), v)
+			})
+			return tokenData, nil
+		})
+		verifier = newCachingVerifier(fakeVerifier, 2, time.Minute)
+	})
+
+	It("reuses the verification of a token", func() {
+		first, err := verifier.Verify(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+		second, err := verifier.Verify(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(1))
+	})
+
+	It("does not cache failed verifications", func() {
+		for i := 0; i < 2; i++ {
+			_, err := verifier.Verify(context.Background(), "invalid")
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(2))
+	})
+
+	It("evicts the least recently used tokens", func() {
+		for _, token := range []string{"a", "b", "a", "c", "a", "b"} {
+			_, err := verifier.Verify(context.Background(), token)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		// b was evicted when c was added
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(4))
+	})
+
+	It("verifies expired tokens again", func() {
+		expiry = time.Now().Add(-time.Second)
+		for i := 0; i < 2; i++ {
+			_, err := verifier.Verify(context.Background(), "token")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(fakeVerifier.VerifyCallCount()).To(Equal(2))
+	})
+})
@@ -26,6 +26,7 @@ func NewAuthenticationFilter(authenticator http.Authenticator, name string, matc
 	return &AuthenticationFilter{
 		Authentication: &middlewares.Authentication{
 			Authenticator: authenticator,
+			Name:          name,
 		},
 		matchers: matchers,
 		name:     name,
@@ -46,3 +47,18 @@ func (af *AuthenticationFilter) Name() string {
 func (af *AuthenticationFilter) FilterMatchers() []web.FilterMatcher {
 	return af.matchers
 }
+
+// FilterForRoute implements web.RouteFilter. The filter runs for the routes declaring it as one of their
+// authenticators, and for the routes declaring none if its matchers match.
+func (af *AuthenticationFilter) FilterForRoute(route web.Route) web.Filter {
+	if route.DeclaresAuthentication() {
+		if route.Authenticates(af.name) {
+			return af
+		}
+		return nil
+	}
+	if web.MatchesEndpoint(af, route.Endpoint) {
+		return af
+	}
+	return nil
+}
@@ -17,14 +17,27 @@
 package middlewares
 
 import (
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/security"
 	"github.com/Peripli/service-manager/pkg/security/http"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AuthenticationSucceededEvent is the audit event logged when a request is authenticated
+	AuthenticationSucceededEvent = "authentication_succeeded"
+
+	// AuthenticationFailedEvent is the audit event logged when the authentication of a request fails
+	AuthenticationFailedEvent = "authentication_failed"
 )
 
 // Authentication type represents an authentication middleware
 type Authentication struct {
 	Authenticator http.Authenticator
+
+	// Name is the name of the authentication filter reported in the audit events
+	Name string
 }
 
 // Run represents the authentication middleware function that delegates the authentication
@@ -37,6 +50,7 @@ func (m *Authentication) Run(request *web.Request, next web.Handler) (*web.Respo
 
 	user, decision, err := m.Authenticator.Authenticate(request.Request)
 	if err != nil {
+		m.audit(request, AuthenticationFailedEvent, "", err.Error())
 		if decision == http.Deny {
 			return nil, security.UnauthorizedHTTPError(err.Error())
 		}
@@ -46,12 +60,36 @@ func (m *Authentication) Run(request *web.Request, next web.Handler) (*web.Respo
 	switch decision {
 	case http.Allow:
 		if user == nil {
+			m.audit(request, AuthenticationFailedEvent, "", security.ErrUserNotFound.Error())
 			return nil, security.ErrUserNotFound
 		}
+		m.audit(request, AuthenticationSucceededEvent, user.Name, "")
 		request.Request = request.WithContext(web.ContextWithUser(ctx, user))
 	case http.Deny:
+		m.audit(request, AuthenticationFailedEvent, "", "authentication failed")
 		return nil, security.UnauthorizedHTTPError("authentication failed")
 	}
 
 	return next.Handle(request)
 }
+
+// audit logs a structured security event of the authentication of the request. The principal of a failed
+// authentication is the username of its basic credentials, if it has any.
+func (m *Authentication) audit(request *web.Request, event, principal, reason string) {
+	if principal == "" {
+		principal, _, _ = request.BasicAuth()
+	}
+	entry := log.C(request.Context()).WithFields(logrus.Fields{
+		"audit":         event,
+		"authenticator": m.Name,
+		"principal":     principal,
+		"ip":            request.ClientIP(),
+		"method":        request.Method,
+		"path":          request.URL.Path,
+	})
+	if event == AuthenticationSucceededEvent {
+		entry.Infof("Authenticated %s with %s", principal, m.Name)
+		return
+	}
+	entry.WithField("reason", reason).Warnf("Authentication with %s failed: %s", m.Name, reason)
+}
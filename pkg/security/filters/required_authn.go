@@ -53,17 +53,36 @@ func (raf *requiredAuthnFilter) FilterMatchers() []web.FilterMatcher {
 				web.Path(
 					web.ServiceBrokersURL+"/**",
 					web.PlatformsURL+"/**",
+					web.PeersURL+"/**",
 					web.OSBURL+"/**",
 					web.ServiceOfferingsURL+"/**",
 					web.ServicePlansURL+"/**",
 					web.VisibilitiesURL+"/**",
+					web.VisibilityPoliciesURL+"/**",
+					web.CompositeBrokersURL+"/**",
+					web.SavedQueriesURL+"/**",
+					web.OperationsURL+"/**",
+					web.AdminURL+"/**",
+					web.TenantKeysURL+"/**",
+					web.MonitorStorageURL,
 					web.NotificationsURL+"/**",
+					web.GraphQLURL,
+					web.ChangesURL,
 				),
 			},
 		},
 	}
 }
 
+// FilterForRoute implements web.RouteFilter. Authentication is required for the routes declaring their
+// authenticators and for the routes whose endpoints match.
+func (raf *requiredAuthnFilter) FilterForRoute(route web.Route) web.Filter {
+	if route.DeclaresAuthentication() || web.MatchesEndpoint(raf, route.Endpoint) {
+		return raf
+	}
+	return nil
+}
+
 // NewRequiredAuthnFilter returns web.Filter
 func NewRequiredAuthnFilter() web.Filter {
 	return &requiredAuthnFilter{}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// RequiredScopesFilterName is the name of RequiredScopesFilter
+const RequiredScopesFilterName = "RequiredScopesFilter"
+
+// requiredScopesFilter verifies that the authenticated user has one of the scopes the route requires
+type requiredScopesFilter struct {
+	scopes []string
+}
+
+// NewRequiredScopesFilter returns web.Filter which enforces the scopes declared by the routes
+func NewRequiredScopesFilter() web.Filter {
+	return &requiredScopesFilter{}
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter
+func (rsf *requiredScopesFilter) Name() string {
+	return RequiredScopesFilterName
+}
+
+// Run implements web.Filter and rejects the users without any of the required scopes
+func (rsf *requiredScopesFilter) Run(request *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := request.Context()
+	user, ok := web.UserFromContext(ctx)
+	if !ok {
+		log.C(ctx).Error("No authenticated user found in request context during execution of filter ", rsf.Name())
+		return nil, security.UnauthorizedHTTPError("No authenticated user found")
+	}
+	scopes := user.Scopes()
+	for _, scope := range rsf.scopes {
+		if scopes[scope] {
+			return next.Handle(request)
+		}
+	}
+	return nil, security.ForbiddenHTTPError(fmt.Sprintf("One of the scopes %v is required", rsf.scopes))
+}
+
+// FilterMatchers implements the web.Filter interface, the filter only runs for the routes requiring scopes
+func (rsf *requiredScopesFilter) FilterMatchers() []web.FilterMatcher {
+	return nil
+}
+
+// FilterForRoute implements web.RouteFilter and returns a filter requiring the scopes of the route
+func (rsf *requiredScopesFilter) FilterForRoute(route web.Route) web.Filter {
+	if len(route.RequiredScopes()) == 0 {
+		return nil
+	}
+	return &requiredScopesFilter{scopes: route.RequiredScopes()}
+}
@@ -17,6 +17,7 @@
 package filters
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -113,5 +114,71 @@ var _ = Describe("Filters tests", func() {
 			})
 		})
 
+		Describe("when the filter for a route is requested", func() {
+			It("should run for the routes declaring their authenticators", func() {
+				filter := NewRequiredAuthnFilter().(web.RouteFilter)
+				route := web.Route{Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/extension"}}
+				Expect(filter.FilterForRoute(route)).To(BeNil())
+				route.Security = &web.RouteSecurity{Authenticators: []string{"BearerAuthnFilter"}}
+				Expect(filter.FilterForRoute(route)).ToNot(BeNil())
+			})
+		})
+
+	})
+
+	Describe("Scopes required filter", func() {
+		var route web.Route
+
+		BeforeEach(func() {
+			route = web.Route{
+				Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/extension"},
+				Security: &web.RouteSecurity{Scopes: []string{"ext.admin", "ext.read"}},
+			}
+		})
+
+		withScopes := func(scopes string) {
+			data := &webfakes.FakeData{}
+			data.DataStub = func(v interface{}) error {
+				return json.Unmarshal([]byte(`{"scope": "`+scopes+`"}`), v)
+			}
+			req.Request = req.WithContext(web.ContextWithUser(req.Context(), &web.UserContext{Data: data}))
+		}
+
+		It("should not run for routes without scopes", func() {
+			route.Security = nil
+			Expect(NewRequiredScopesFilter().(web.RouteFilter).FilterForRoute(route)).To(BeNil())
+		})
+
+		It("should continue if the user has one of the scopes", func() {
+			withScopes("openid ext.read")
+			_, err := NewRequiredScopesFilter().(web.RouteFilter).FilterForRoute(route).Run(req, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.HandleCallCount()).To(Equal(1))
+		})
+
+		It("should return 403 if the user has none of the scopes", func() {
+			withScopes("openid")
+			_, err := NewRequiredScopesFilter().(web.RouteFilter).FilterForRoute(route).Run(req, handler)
+			httpErr, ok := err.(*util.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("Authentication filter", func() {
+		It("should run only for the routes declaring it if routes declare their authenticators", func() {
+			filter := NewAuthenticationFilter(nil, "BasicAuthnFilter", []web.FilterMatcher{{Matchers: []web.Matcher{web.Path("/v1/platforms/**")}}})
+			platforms := web.Route{Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/platforms"}}
+			Expect(filter.FilterForRoute(platforms)).ToNot(BeNil())
+			platforms.Security = &web.RouteSecurity{Authenticators: []string{"BearerAuthnFilter"}}
+			Expect(filter.FilterForRoute(platforms)).To(BeNil())
+
+			extension := web.Route{
+				Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/extension"},
+				Security: &web.RouteSecurity{Authenticators: []string{"BasicAuthnFilter"}},
+			}
+			Expect(filter.FilterForRoute(extension)).ToNot(BeNil())
+		})
 	})
 })
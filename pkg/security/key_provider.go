@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"context"
+)
+
+// KeyProvider wraps and unwraps data encryption keys with a key held by a key management service,
+// e.g. AWS KMS, GCP KMS or the transit secrets engine of Vault. The wrapping key never leaves the service.
+type KeyProvider interface {
+	// Name returns the name by which tenants reference the provider, e.g. vault-transit
+	Name() string
+
+	// WrapKey encrypts the key with the key management service key referenced by keyRef
+	WrapKey(ctx context.Context, keyRef string, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a key wrapped with the key management service key referenced by keyRef
+	UnwrapKey(ctx context.Context, keyRef string, wrappedKey []byte) ([]byte, error)
+}
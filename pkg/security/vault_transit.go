@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// VaultTransitProviderName is the name of the key provider backed by the transit secrets engine of Vault
+const VaultTransitProviderName = "vault-transit"
+
+// VaultTransitSettings configures the key provider backed by the transit secrets engine of Vault
+type VaultTransitSettings struct {
+	Address   string `mapstructure:"address" description:"address of the Vault server whose transit secrets engine wraps tenant keys, the provider is disabled if empty"`
+	Token     string `mapstructure:"token" description:"token used to authenticate to Vault"`
+	MountPath string `mapstructure:"mount_path" description:"path at which the transit secrets engine is mounted"`
+}
+
+// DefaultVaultTransitSettings returns the default values for the Vault transit key provider
+func DefaultVaultTransitSettings() *VaultTransitSettings {
+	return &VaultTransitSettings{
+		Address:   "",
+		Token:     "",
+		MountPath: "transit",
+	}
+}
+
+// Validate validates the Vault transit settings
+func (s *VaultTransitSettings) Validate() error {
+	if s.Address == "" {
+		return nil
+	}
+	if s.Token == "" {
+		return fmt.Errorf("validate Settings: vault transit token missing")
+	}
+	if s.MountPath == "" {
+		return fmt.Errorf("validate Settings: vault transit mount path missing")
+	}
+	return nil
+}
+
+// VaultTransitKeyProvider wraps keys with the named encryption keys of the transit secrets engine of Vault
+type VaultTransitKeyProvider struct {
+	settings  *VaultTransitSettings
+	doRequest util.DoRequestFunc
+}
+
+// NewVaultTransitKeyProvider returns a key provider which uses the provided client to call Vault
+func NewVaultTransitKeyProvider(settings *VaultTransitSettings, client *http.Client) *VaultTransitKeyProvider {
+	return &VaultTransitKeyProvider{
+		settings: settings,
+		doRequest: func(request *http.Request) (*http.Response, error) {
+			request.Header.Set("X-Vault-Token", settings.Token)
+			return client.Do(request)
+		},
+	}
+}
+
+func (*VaultTransitKeyProvider) Name() string {
+	return VaultTransitProviderName
+}
+
+type vaultTransitResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+}
+
+// WrapKey encrypts the key with the transit key named keyRef, the result is the Vault ciphertext
+func (p *VaultTransitKeyProvider) WrapKey(ctx context.Context, keyRef string, key []byte) ([]byte, error) {
+	response, err := p.call(ctx, "encrypt", keyRef, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(response.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a Vault ciphertext with the transit key named keyRef
+func (p *VaultTransitKeyProvider) UnwrapKey(ctx context.Context, keyRef string, wrappedKey []byte) ([]byte, error) {
+	response, err := p.call(ctx, "decrypt", keyRef, map[string]string{
+		"ciphertext": string(wrappedKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+func (p *VaultTransitKeyProvider) call(ctx context.Context, operation, keyRef string, body interface{}) (*vaultTransitResponse, error) {
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", strings.TrimSuffix(p.settings.Address, "/"), strings.Trim(p.settings.MountPath, "/"), operation, keyRef)
+	response, err := util.SendRequest(ctx, p.doRequest, http.MethodPost, url, nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("could not %s key with vault transit key %s: %s", operation, keyRef, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not %s key with vault transit key %s: %s", operation, keyRef, util.HandleResponseError(response))
+	}
+	result := &vaultTransitResponse{}
+	if err := util.BodyToObject(response.Body, result); err != nil {
+		return nil, fmt.Errorf("could not parse vault transit response: %s", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault transit key provider", func() {
+	var (
+		vault    *httptest.Server
+		provider *security.VaultTransitKeyProvider
+	)
+
+	BeforeEach(func() {
+		// the fake transit engine "encrypts" by prefixing the plaintext with the key name
+		vault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			body := make(map[string]string)
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			switch {
+			case r.URL.Path == "/v1/transit/encrypt/tenant-key":
+				w.Write([]byte(`{"data": {"ciphertext": "vault:v1:tenant-key:` + body["plaintext"] + `"}}`))
+			case r.URL.Path == "/v1/transit/decrypt/tenant-key" && strings.HasPrefix(body["ciphertext"], "vault:v1:tenant-key:"):
+				w.Write([]byte(`{"data": {"plaintext": "` + strings.TrimPrefix(body["ciphertext"], "vault:v1:tenant-key:") + `"}}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors": ["invalid request"]}`))
+			}
+		}))
+		settings := security.DefaultVaultTransitSettings()
+		settings.Address = vault.URL
+		settings.Token = "vault-token"
+		provider = security.NewVaultTransitKeyProvider(settings, http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		vault.Close()
+	})
+
+	It("wraps and unwraps keys", func() {
+		key := []byte("0123456789abcdef0123456789abcdef")
+		wrapped, err := provider.WrapKey(context.TODO(), "tenant-key", key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(wrapped)).To(Equal("vault:v1:tenant-key:" + base64.StdEncoding.EncodeToString(key)))
+
+		unwrapped, err := provider.UnwrapKey(context.TODO(), "tenant-key", wrapped)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unwrapped).To(Equal(key))
+	})
+
+	It("fails if the key is wrapped with another transit key", func() {
+		wrapped, err := provider.WrapKey(context.TODO(), "tenant-key", []byte("key"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.UnwrapKey(context.TODO(), "other-key", wrapped)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails if Vault rejects the token", func() {
+		settings := security.DefaultVaultTransitSettings()
+		settings.Address = vault.URL
+		settings.Token = "wrong-token"
+		_, err := security.NewVaultTransitKeyProvider(settings, http.DefaultClient).WrapKey(context.TODO(), "tenant-key", []byte("key"))
+		Expect(err).To(MatchError(ContainSubstring("tenant-key")))
+	})
+})
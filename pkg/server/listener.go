@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+)
+
+const (
+	// NetworkTCP listens on IPv4 and IPv6 addresses, a wildcard address is bound dual-stack
+	NetworkTCP = "tcp"
+	// NetworkTCP4 listens on IPv4 addresses only
+	NetworkTCP4 = "tcp4"
+	// NetworkTCP6 listens on IPv6 addresses only
+	NetworkTCP6 = "tcp6"
+	// NetworkUnix listens on a unix domain socket, e.g. for sidecars on the same host
+	NetworkUnix = "unix"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+}
+
+// Listener is an address on which the server accepts requests
+type Listener struct {
+	Network       string
+	Address       string
+	CertFile      string
+	KeyFile       string
+	MinTLSVersion string
+	ClientCAFile  string
+}
+
+// ParseListener parses a listener in the form scheme://address?options. The scheme is http, https or unix and the
+// address is host:port with IPv6 hosts in brackets or the path of a unix domain socket, e.g. http://[::]:8080,
+// https://0.0.0.0:8443?cert_file=/etc/sm/tls.crt&key_file=/etc/sm/tls.key or unix:///var/run/sm.sock.
+// The network option restricts http and https listeners to IPv4 (tcp4) or IPv6 (tcp6) and the min_tls_version option
+// sets the minimum TLS version (1.2) of https listeners. The client_ca_file option makes https listeners verify
+// the client certificates issued by the CAs in the file, e.g. the one of a trusted gateway. Clients without certificates
+// are still accepted.
+func ParseListener(listener string) (*Listener, error) {
+	u, err := url.Parse(listener)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listener %s: %s", listener, err)
+	}
+	options := u.Query()
+	result := &Listener{
+		Network:       options.Get("network"),
+		CertFile:      options.Get("cert_file"),
+		KeyFile:       options.Get("key_file"),
+		MinTLSVersion: options.Get("min_tls_version"),
+		ClientCAFile:  options.Get("client_ca_file"),
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return nil, fmt.Errorf("invalid address of listener %s: %s", listener, err)
+		}
+		result.Address = u.Host
+		switch result.Network {
+		case "":
+			result.Network = NetworkTCP
+		case NetworkTCP, NetworkTCP4, NetworkTCP6:
+		default:
+			return nil, fmt.Errorf("unsupported network %s of listener %s", result.Network, listener)
+		}
+	case NetworkUnix:
+		if u.Path == "" || result.Network != "" {
+			return nil, fmt.Errorf("invalid unix domain socket listener %s", listener)
+		}
+		result.Network = NetworkUnix
+		result.Address = u.Path
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s of listener %s", u.Scheme, listener)
+	}
+
+	if (u.Scheme == "https") != (result.CertFile != "" && result.KeyFile != "") {
+		return nil, fmt.Errorf("listener %s requires a certificate and a key file if and only if it is https", listener)
+	}
+	if _, found := tlsVersions[result.MinTLSVersion]; result.MinTLSVersion != "" && !found {
+		return nil, fmt.Errorf("unsupported minimum TLS version %s of listener %s", result.MinTLSVersion, listener)
+	}
+	if result.ClientCAFile != "" && u.Scheme != "https" {
+		return nil, fmt.Errorf("listener %s requires https to verify client certificates", listener)
+	}
+	return result, nil
+}
+
+// TLS returns whether the listener terminates TLS
+func (l *Listener) TLS() bool {
+	return l.CertFile != ""
+}
+
+// Listen opens the listener. A stale unix domain socket left behind by a previous process is removed.
+func (l *Listener) Listen() (net.Listener, error) {
+	if l.Network == NetworkUnix {
+		if info, err := os.Stat(l.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(l.Address); err != nil {
+				return nil, fmt.Errorf("could not remove stale socket %s: %s", l.Address, err)
+			}
+		}
+	}
+	listener, err := net.Listen(l.Network, l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if !l.TLS() {
+		return listener, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(l.CertFile, l.KeyFile)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not load TLS certificate of listener %s: %s", l.Address, err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"http/1.1"},
+	}
+	if l.MinTLSVersion != "" {
+		config.MinVersion = tlsVersions[l.MinTLSVersion]
+	}
+	if l.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(l.ClientCAFile)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("could not read client CA file of listener %s: %s", l.Address, err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			listener.Close()
+			return nil, fmt.Errorf("client CA file of listener %s contains no certificates", l.Address)
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tls.NewListener(listener, config), nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listener", func() {
+	Describe("ParseListener", func() {
+		It("parses dual-stack, IPv6 only and unix domain socket listeners", func() {
+			listener, err := ParseListener("http://[::]:8080")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listener).To(Equal(&Listener{Network: NetworkTCP, Address: "[::]:8080"}))
+
+			listener, err = ParseListener("http://[::1]:8080?network=tcp6")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listener.Network).To(Equal(NetworkTCP6))
+
+			listener, err = ParseListener("unix:///var/run/sm.sock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listener).To(Equal(&Listener{Network: NetworkUnix, Address: "/var/run/sm.sock"}))
+		})
+
+		It("parses the TLS options of https listeners", func() {
+			listener, err := ParseListener("https://0.0.0.0:8443?cert_file=tls.crt&key_file=tls.key&min_tls_version=1.2&client_ca_file=ca.crt")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listener.TLS()).To(BeTrue())
+			Expect(listener.CertFile).To(Equal("tls.crt"))
+			Expect(listener.KeyFile).To(Equal("tls.key"))
+			Expect(listener.MinTLSVersion).To(Equal("1.2"))
+			Expect(listener.ClientCAFile).To(Equal("ca.crt"))
+		})
+
+		It("rejects invalid listeners", func() {
+			for _, listener := range []string{
+				"ftp://0.0.0.0:21",
+				"http://0.0.0.0",
+				"http://0.0.0.0:8080?network=udp",
+				"https://0.0.0.0:8443",
+				"http://0.0.0.0:8080?cert_file=tls.crt&key_file=tls.key",
+				"https://0.0.0.0:8443?cert_file=tls.crt&key_file=tls.key&min_tls_version=1.0",
+				"http://0.0.0.0:8080?client_ca_file=ca.crt",
+				"unix://",
+			} {
+				_, err := ParseListener(listener)
+				Expect(err).To(HaveOccurred(), listener)
+			}
+		})
+	})
+
+	Describe("Listen", func() {
+		It("serves on a unix domain socket", func() {
+			dir, err := ioutil.TempDir("", "sm-listener-")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			socket := filepath.Join(dir, "sm.sock")
+
+			listener, err := (&Listener{Network: NetworkUnix, Address: socket}).Listen()
+			Expect(err).ToNot(HaveOccurred())
+			server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusNoContent)
+			})}
+			go server.Serve(listener)
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, NetworkUnix, socket)
+				},
+			}}
+			response, err := client.Get("http://sm/v1/info")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+		})
+	})
+})
@@ -20,8 +20,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,36 +41,51 @@ const (
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	Host            string        `mapstructure:"host" description:"host of the server"`
-	Port            int           `mapstructure:"port" description:"port of the server"`
-	RequestTimeout  time.Duration `mapstructure:"request_timeout" description:"read and write timeout duration for requests"`
-	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" description:"time to wait for the server to shutdown"`
-	MaxBodyBytes    int           `mapstructure:"max_body_bytes" description:"maximum bytes size of incoming body"`
-	MaxHeaderBytes  int           `mapstructure:"max_header_bytes" description:"the maximum number of bytes the server will read parsing the request header"`
+	Host                string        `mapstructure:"host" description:"host of the server"`
+	Port                int           `mapstructure:"port" description:"port of the server"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout" description:"read and write timeout duration for requests"`
+	ShutdownTimeout     time.Duration `mapstructure:"shutdown_timeout" description:"time to wait for the server to shutdown"`
+	MaxBodyBytes        int           `mapstructure:"max_body_bytes" description:"maximum bytes size of incoming body"`
+	MaxHeaderBytes      int           `mapstructure:"max_header_bytes" description:"the maximum number of bytes the server will read parsing the request header"`
+	PathPrefix          string        `mapstructure:"path_prefix" description:"URL path prefix under which the Service Manager is deployed behind a router, e.g. /service-manager"`
+	BodyMemoryThreshold int           `mapstructure:"body_memory_threshold" description:"maximum bytes size of incoming body kept in memory, larger bodies are buffered in temporary files"`
+	Listeners           []string      `mapstructure:"listeners" description:"listeners replacing the one on host and port in the form scheme://address?options, e.g. http://[::]:8080, https://0.0.0.0:8443?cert_file=tls.crt&key_file=tls.key&min_tls_version=1.2 or unix:///var/run/sm.sock"`
 }
 
 // DefaultSettings returns the default values for configuring the Service Manager
 func DefaultSettings() *Settings {
 	return &Settings{
-		Port:            8080,
-		RequestTimeout:  time.Second * 3,
-		ShutdownTimeout: time.Second * 3,
-		MaxBodyBytes:    mb,
-		MaxHeaderBytes:  kb,
+		Port:                8080,
+		RequestTimeout:      time.Second * 3,
+		ShutdownTimeout:     time.Second * 3,
+		MaxBodyBytes:        mb,
+		MaxHeaderBytes:      kb,
+		BodyMemoryThreshold: 256 * kb,
 	}
 }
 
 // Validate validates the server settings
 func (s *Settings) Validate() error {
-	if s.Port == 0 {
+	if s.Port == 0 && len(s.Listeners) == 0 {
 		return fmt.Errorf("validate Settings: Port missing")
 	}
+	for _, listener := range s.Listeners {
+		if _, err := ParseListener(listener); err != nil {
+			return fmt.Errorf("validate Settings: %s", err)
+		}
+	}
 	if s.RequestTimeout == 0 {
 		return fmt.Errorf("validate Settings: RequestTimeout missing")
 	}
 	if s.ShutdownTimeout == 0 {
 		return fmt.Errorf("validate Settings: ShutdownTimeout missing")
 	}
+	if s.PathPrefix != "" && (!strings.HasPrefix(s.PathPrefix, "/") || strings.HasSuffix(s.PathPrefix, "/")) {
+		return fmt.Errorf("validate Settings: PathPrefix must start and must not end with a slash")
+	}
+	if s.BodyMemoryThreshold < 0 {
+		return fmt.Errorf("validate Settings: BodyMemoryThreshold must not be negative")
+	}
 
 	return nil
 }
@@ -84,7 +101,11 @@ type Server struct {
 // Returns the new server and an error if creation was not successful
 func New(config *Settings, api *web.API) *Server {
 	router := mux.NewRouter().StrictSlash(true)
-	registerControllers(api, router, config)
+	if config.PathPrefix != "" {
+		registerControllers(api, router.PathPrefix(config.PathPrefix).Subrouter(), config)
+	} else {
+		registerControllers(api, router, config)
+	}
 
 	return &Server{
 		Router: router,
@@ -97,11 +118,25 @@ func registerControllers(API *web.API, router *mux.Router, config *Settings) {
 		for _, route := range ctrl.Routes() {
 			log.D().Debugf("Registering endpoint: %s %s", route.Endpoint.Method, route.Endpoint.Path)
 			handler := web.Filters(API.Filters).ChainMatching(route)
-			router.Handle(route.Endpoint.Path, api.NewHTTPHandler(handler, config.MaxBodyBytes)).Methods(route.Endpoint.Method)
+			var httpHandler http.Handler = api.NewHTTPHandler(handler, config.MaxBodyBytes, config.BodyMemoryThreshold)
+			if config.PathPrefix != "" {
+				httpHandler = stripPathPrefix(config.PathPrefix, httpHandler)
+			}
+			router.Handle(route.Endpoint.Path, httpHandler).Methods(route.Endpoint.Method)
 		}
 	}
 }
 
+// stripPathPrefix removes the path prefix from the requests, so that filters and controllers see the paths of the
+// API, and keeps it in the request metadata for building the URLs returned to the clients
+func stripPathPrefix(prefix string, handler http.Handler) http.Handler {
+	stripped := http.StripPrefix(prefix, handler)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		metadata := web.MetadataFromContext(req.Context()).WithPathPrefix(prefix)
+		stripped.ServeHTTP(rw, req.WithContext(web.ContextWithMetadata(req.Context(), metadata)))
+	})
+}
+
 // Run starts the server awaiting for incoming requests
 func (s *Server) Run(ctx context.Context, wg *sync.WaitGroup) {
 	if err := s.Config.Validate(); err != nil {
@@ -109,22 +144,53 @@ func (s *Server) Run(ctx context.Context, wg *sync.WaitGroup) {
 	}
 	handler := &http.Server{
 		Handler:        s.Router,
-		Addr:           s.Config.Host + ":" + strconv.Itoa(s.Config.Port),
 		WriteTimeout:   s.Config.RequestTimeout,
 		ReadTimeout:    s.Config.RequestTimeout,
 		MaxHeaderBytes: s.Config.MaxHeaderBytes,
 	}
-	startServer(ctx, handler, s.Config.ShutdownTimeout, wg)
+	listeners := make([]net.Listener, 0, len(s.Config.listeners()))
+	for _, l := range s.Config.listeners() {
+		listener, err := l.Listen()
+		if err != nil {
+			log.C(ctx).Fatal(err)
+		}
+		listeners = append(listeners, listener)
+	}
+	startServer(ctx, handler, listeners, s.Config.ShutdownTimeout, wg)
+}
+
+// listeners returns the configured listeners or a plain HTTP listener on host and port if there are none
+func (s *Settings) listeners() []*Listener {
+	if len(s.Listeners) == 0 {
+		return []*Listener{{
+			Network: NetworkTCP,
+			Address: net.JoinHostPort(s.Host, strconv.Itoa(s.Port)),
+		}}
+	}
+	listeners := make([]*Listener, 0, len(s.Listeners))
+	for _, listener := range s.Listeners {
+		// the listeners are validated before
+		l, _ := ParseListener(listener)
+		listeners = append(listeners, l)
+	}
+	return listeners
 }
 
-func startServer(ctx context.Context, server *http.Server, shutdownTimeout time.Duration, wg *sync.WaitGroup) {
+func startServer(ctx context.Context, server *http.Server, listeners []net.Listener, shutdownTimeout time.Duration, wg *sync.WaitGroup) {
 	wg.Add(1)
 	go gracefulShutdown(ctx, server, shutdownTimeout, wg)
 
-	log.C(ctx).Infof("Server listening on %s...", server.Addr)
-
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.C(ctx).Fatal(err)
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		log.C(ctx).Infof("Server listening on %s %s...", listener.Addr().Network(), listener.Addr())
+		go func(listener net.Listener) {
+			errs <- server.Serve(listener)
+		}(listener)
+	}
+	for range listeners {
+		if err := <-errs; err != nil && err != http.ErrServerClosed {
+			log.C(ctx).Fatal(err)
+		}
 	}
 }
 
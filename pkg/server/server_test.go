@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/gavv/httpexpect"
 	. "github.com/onsi/ginkgo"
@@ -82,6 +83,58 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	Describe("Path Prefix", func() {
+		var prefixed *httpexpect.Expect
+
+		BeforeEach(func() {
+			api := &web.API{}
+			testCtl := &testController{}
+			testCtl.RegisterRoutes(web.Route{
+				Endpoint: web.Endpoint{
+					Path:   "/v1/info",
+					Method: http.MethodGet,
+				},
+				Handler: func(req *web.Request) (*web.Response, error) {
+					return util.NewJSONResponse(http.StatusOK, map[string]string{
+						"path": req.URL.Path,
+						"url":  req.ExternalURL("/v1/info").String(),
+					})
+				},
+			})
+			api.RegisterControllers(testCtl)
+			server := New(&Settings{
+				PathPrefix:      "/service-manager",
+				RequestTimeout:  time.Second * 3,
+				ShutdownTimeout: time.Second * 3,
+			}, api)
+			testServer := httptest.NewServer(server.Router)
+			prefixed = httpexpect.New(GinkgoT(), testServer.URL)
+		})
+
+		It("routes the paths under the prefix", func() {
+			prefixed.GET("/service-manager/v1/info").Expect().
+				Status(http.StatusOK).
+				JSON().Object().ValueEqual("path", "/v1/info")
+			prefixed.GET("/v1/info").Expect().Status(http.StatusNotFound)
+		})
+
+		It("returns external URLs with the prefix and the forwarded scheme and host", func() {
+			prefixed.GET("/service-manager/v1/info").
+				WithHeader("X-Forwarded-Proto", "https").
+				WithHeader("X-Forwarded-Host", "sm.example.com").
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().ValueEqual("url", "https://sm.example.com/service-manager/v1/info")
+		})
+	})
+
+	Describe("Settings", func() {
+		It("rejects a path prefix with a trailing slash", func() {
+			settings := DefaultSettings()
+			settings.PathPrefix = "/service-manager/"
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
 })
 
 func assertRecover(query string) {
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sm
+
+import (
+	"net/url"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// FilterFactory creates a filter from the options of a filter spec of the configuration
+type FilterFactory func(options url.Values) (web.Filter, error)
+
+// defaultFilterFactories returns the factories of the built-in filters which are only registered if they are
+// configured in the filter specs
+func defaultFilterFactories() map[string]FilterFactory {
+	return map[string]FilterFactory{
+		"csrf": newCSRFFilter,
+	}
+}
+
+// newCSRFFilter creates the CSRF filter, the exempt_paths option adds to the default exempt paths
+func newCSRFFilter(options url.Values) (web.Filter, error) {
+	csrfOptions := filters.DefaultCSRFOptions()
+	if cookieName := options.Get("cookie_name"); cookieName != "" {
+		csrfOptions.CookieName = cookieName
+	}
+	if headerName := options.Get("header_name"); headerName != "" {
+		csrfOptions.HeaderName = headerName
+	}
+	csrfOptions.ExemptPaths = append(csrfOptions.ExemptPaths, options["exempt_paths"]...)
+	return filters.NewCSRFFilter(csrfOptions), nil
+}
+
+// RegisterFilterFactory adds a factory whose filters can be registered by the filter specs of the configuration
+// under the name, e.g. the one of an extension filter which is only used by some deployments
+func (smb *ServiceManagerBuilder) RegisterFilterFactory(name string, factory FilterFactory) *ServiceManagerBuilder {
+	if _, found := smb.filterFactories[name]; found {
+		log.D().Panicf("Filter factory %s is already registered", name)
+	}
+	smb.filterFactories[name] = factory
+	return smb
+}
+
+// installFilters applies the filter specs of the configuration to the registered filters in order, after the
+// extensions registered their filters and filter factories
+func (smb *ServiceManagerBuilder) installFilters() {
+	specs, err := filters.ParseFilterSpecs(smb.filterSpecs)
+	if err != nil {
+		log.D().Panicf("Could not parse filter specs: %s", err)
+	}
+	for _, spec := range specs {
+		if spec.Remove != "" {
+			log.D().Infof("Removing filter %s", spec.Remove)
+			smb.RemoveFilter(spec.Remove)
+			continue
+		}
+		factory, found := smb.filterFactories[spec.Factory]
+		if !found {
+			log.D().Panicf("Filter factory %s is not registered", spec.Factory)
+		}
+		filter, err := factory(spec.Options)
+		if err != nil {
+			log.D().Panicf("Could not create filter of factory %s: %s", spec.Factory, err)
+		}
+		switch {
+		case spec.Before != "":
+			smb.RegisterFiltersBefore(spec.Before, filter)
+		case spec.After != "":
+			smb.RegisterFiltersAfter(spec.After, filter)
+		default:
+			smb.RegisterFilters(filter)
+		}
+	}
+}
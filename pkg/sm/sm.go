@@ -30,15 +30,28 @@ import (
 
 	"github.com/Peripli/service-manager/pkg/security"
 
+	"github.com/Peripli/service-manager/pkg/bootstrap"
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/features"
+	"github.com/Peripli/service-manager/pkg/federation"
+	"github.com/Peripli/service-manager/pkg/jobs"
+	"github.com/Peripli/service-manager/pkg/platformtypes"
+	"github.com/Peripli/service-manager/pkg/resync"
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/visibilitypolicy"
+	"github.com/Peripli/service-manager/pkg/visibilityschedule"
 	"github.com/Peripli/service-manager/storage/interceptors"
 
 	"github.com/Peripli/service-manager/api"
 	"github.com/Peripli/service-manager/api/healthcheck"
+	"github.com/Peripli/service-manager/api/notifications"
 	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/httpclient"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/views"
 	"github.com/Peripli/service-manager/storage"
 	"github.com/Peripli/service-manager/storage/postgres"
 
@@ -54,9 +67,29 @@ type ServiceManagerBuilder struct {
 	Storage             *storage.InterceptableTransactionalRepository
 	Notificator         storage.Notificator
 	NotificationCleaner *storage.NotificationCleaner
+	Features            *features.Manager
+	Bootstrapper        *bootstrap.Bootstrapper
+	Scheduler           *jobs.Scheduler
+	CatalogPipeline     *catalog.Pipeline
+	CredentialsPipeline *osb.CredentialsPipeline
+	CredentialsPolicy   *filters.CredentialsPolicy
+	PlatformTypes       *platformtypes.Registry
+	Views               *views.Registry
+	CredentialsProvider *credentials.Provider
+	TenantKeys          *storage.TenantKeys
+	CacheStore          cache.Store
+	WSConnections       *notifications.Connections
+	HTTPClients         *httpclient.Factory
 	ctx                 context.Context
 	wg                  *sync.WaitGroup
 	cfg                 *server.Settings
+	indexAdvisor        *postgres.IndexAdvisor
+	objectCache         *storage.ObjectCache
+	responseCache       *filters.ResponseCache
+	featureStates       *features.StoreSource
+	featuresSettings    *features.Settings
+	filterFactories     map[string]FilterFactory
+	filterSpecs         []string
 }
 
 // ServiceManager  struct
@@ -66,6 +99,15 @@ type ServiceManager struct {
 	Server              *server.Server
 	Notificator         storage.Notificator
 	NotificationCleaner *storage.NotificationCleaner
+	Bootstrapper        *bootstrap.Bootstrapper
+	Scheduler           *jobs.Scheduler
+	indexAdvisor        *postgres.IndexAdvisor
+	objectCache         *storage.ObjectCache
+	responseCache       *filters.ResponseCache
+	features            *features.Manager
+	featureStates       *features.StoreSource
+	featuresSettings    *features.Settings
+	wsConnections       *notifications.Connections
 }
 
 // New returns service-manager Server with default setup
@@ -75,10 +117,14 @@ func New(ctx context.Context, cancel context.CancelFunc, cfg *config.Settings) (
 		return nil, fmt.Errorf("error validating configuration: %s", err)
 	}
 
-	// Setup the default http client
+	// Setup the clients of the outbound calls, the default http client is kept for extensions
+	httpClients, err := httpclient.NewFactory(cfg.HTTPClient, cfg.Server.RequestTimeout, cfg.API.SkipSSLValidation)
+	if err != nil {
+		return nil, fmt.Errorf("could not create http clients: %s", err)
+	}
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.API.SkipSSLValidation}
-	http.DefaultClient.Transport = http.DefaultTransport
-	http.DefaultClient.Timeout = cfg.Server.RequestTimeout
+	http.DefaultClient.Transport = httpClients.Transport()
+	http.DefaultClient.Timeout = httpClients.Timeout()
 
 	// Setup logging
 	ctx = log.Configure(ctx, cfg.Log)
@@ -94,7 +140,8 @@ func New(ctx context.Context, cancel context.CancelFunc, cfg *config.Settings) (
 	}
 
 	// Decorate the storage with credentials encryption/decryption
-	encryptingDecorator := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, smStorage)
+	tenantKeys := storage.NewTenantKeys(cfg.Storage.TenantEncryption, httpClients.Client("key provider"))
+	encryptingDecorator := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, smStorage, tenantKeys)
 
 	// Initialize the storage with graceful termination
 	var transactionalRepository storage.TransactionalRepository
@@ -114,22 +161,136 @@ func New(ctx context.Context, cancel context.CancelFunc, cfg *config.Settings) (
 		return nil, fmt.Errorf("could not create notificator: %v", err)
 	}
 
+	brokerTransports := osb.NewTransports(cfg.API.BrokerProxy, httpClients)
+	brokerTransports.Signer = osb.NewRequestSigner(cfg.API.RequestSigning)
+	osbStats := osb.NewStats(cfg.API.OSBCallHistorySize)
+	objectCache := storage.NewObjectCache(cfg.Storage.Cache)
+	cacheStore, err := cache.New(cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache store: %v", err)
+	}
+	responseCache := filters.NewResponseCache(cfg.API.ResponseCache, cacheStore)
+	loadShedder := filters.NewLoadShedder(cfg.API.LoadShedding)
+	loginThrottle := filters.NewLoginThrottle(cfg.API.LoginThrottle, cacheStore)
+
+	// Notifications are relayed between the instances through Redis instead of every instance listening to the storage
+	if cfg.Storage.Notification.MessageBus == storage.RedisMessageBus {
+		bus, ok := cacheStore.(cache.PubSub)
+		if !ok || cfg.Cache.Type != cache.RedisType {
+			return nil, fmt.Errorf("notification message bus %s requires cache type %s", storage.RedisMessageBus, cache.RedisType)
+		}
+		pgNotificator.UseMessageBus(bus, cacheStore)
+	}
+
+	// Feature states set through the features API take precedence over the configured ones
+	featureStates := features.NewStoreSource(cacheStore)
+	featuresManager := features.NewManager(cfg.Features, featureStates)
+	scheduler := jobs.NewScheduler(cfg.Jobs, smStorage)
+
+	// Visibility notifications are only sent to the platforms to which the visibility applies
+	platformTypes := platformtypes.NewRegistry()
+	pgNotificator.RegisterFilter(platformTypes.FilterVisibilityRecipients)
+
+	credentialsPipeline := &osb.CredentialsPipeline{}
+	credentialsPolicy := filters.NewCredentialsPolicy(cfg.API.CredentialsPolicy)
+	credentialsProvider := credentials.NewProvider(credentials.NewRandomGenerator(cfg.PlatformCredentials))
+	if cfg.PlatformCredentials.CredHub.URL != "" {
+		credentialsProvider.UseSecretStore(credentials.NewCredHubStore(cfg.PlatformCredentials.CredHub, httpClients.Client("credhub")))
+	}
+	wsConnections := notifications.NewConnections(cfg.WebSocket)
+	apiViews := views.NewRegistry()
+	notificationCleaner := &storage.NotificationCleaner{
+		Storage:  interceptableRepository,
+		Settings: *cfg.Storage,
+	}
+
 	apiOptions := &api.Options{
 		Repository:  interceptableRepository,
 		APISettings: cfg.API,
 		WSSettings:  cfg.WebSocket,
 		Notificator: pgNotificator,
+		Features:    featuresManager,
+		Scheduler:   scheduler,
+
+		FeatureStates:    featureStates,
+		BrokerTransports: brokerTransports,
+		OSBStats:         osbStats,
+		Cache:            objectCache,
+		ResponseCache:    responseCache,
+		LoadShedder:      loadShedder,
+		LoginThrottle:    loginThrottle,
+		CFVisibility:     cfg.CFVisibility,
+		Federation:       cfg.Federation,
+		PlatformTypes:    platformTypes,
+		Views:            apiViews,
+
+		CredentialsPipeline: credentialsPipeline,
+		CredentialsPolicy:   credentialsPolicy,
+		CredentialsProvider: credentialsProvider,
+		TenantKeys:          tenantKeys,
+		HTTPClients:         httpClients,
+		WSConnections:       wsConnections,
+		VisibilityResolver:  smStorage,
+		StorageStatistics:   smStorage,
+		QueryableFields:     smStorage,
+		NotificationCleaner: notificationCleaner,
+		CacheStore:          cacheStore,
 	}
 	API, err := api.New(ctx, apiOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error creating core api: %s", err)
 	}
 
-	API.HealthIndicators = append(API.HealthIndicators, &storage.HealthIndicator{Pinger: storage.PingFunc(smStorage.Ping)})
+	indexAdvisor := &postgres.IndexAdvisor{Storage: smStorage}
+	API.HealthIndicators = append(API.HealthIndicators, &storage.HealthIndicator{Pinger: storage.PingFunc(smStorage.Ping)}, indexAdvisor, objectCache, responseCache, loadShedder)
 
-	notificationCleaner := &storage.NotificationCleaner{
-		Storage:  interceptableRepository,
-		Settings: *cfg.Storage,
+	if err := scheduler.Register(notificationCleaner, jobs.Options{Interval: cfg.Storage.Notification.CleanInterval}); err != nil {
+		return nil, fmt.Errorf("could not schedule notification cleaner: %v", err)
+	}
+
+	catalogPipeline := &catalog.Pipeline{}
+	catalogFetcher := catalogPipeline.Fetcher(catalog.UploadFetcher(osb.BrokerCatalogFetcher(cfg.API.OSBResponses.DoRequestFuncProvider(brokerTransports.DoRequestFunc), cfg.API.OSBVersion)))
+	if cfg.Resync.Enabled {
+		resyncJob := resync.NewJob(cfg.Resync, interceptableRepository, catalogFetcher)
+		if err := scheduler.Register(resyncJob, jobs.Options{Interval: cfg.Resync.CheckInterval}); err != nil {
+			return nil, fmt.Errorf("could not schedule catalog resync: %v", err)
+		}
+	}
+
+	if cfg.Federation.Enabled {
+		importJob := federation.NewJob(interceptableRepository, federation.NewClientFactory(httpClients.Client("federation")))
+		if err := scheduler.Register(importJob, jobs.Options{Interval: cfg.Federation.ImportInterval}); err != nil {
+			return nil, fmt.Errorf("could not schedule federation import: %v", err)
+		}
+	}
+
+	if cfg.API.CatalogRecoveryInterval > 0 {
+		catalogRecoveryJob := api.NewCatalogRecoveryJob(cfg.API, interceptableRepository)
+		if err := scheduler.Register(catalogRecoveryJob, jobs.Options{Interval: cfg.API.CatalogRecoveryInterval}); err != nil {
+			return nil, fmt.Errorf("could not schedule broker catalog recovery: %v", err)
+		}
+	}
+
+	visibilityScheduleJob := visibilityschedule.NewJob(cfg.VisibilitySchedule, interceptableRepository)
+	if err := scheduler.Register(visibilityScheduleJob, jobs.Options{Interval: cfg.VisibilitySchedule.CheckInterval}); err != nil {
+		return nil, fmt.Errorf("could not schedule visibility schedule: %v", err)
+	}
+
+	visibilityPolicyJob := visibilitypolicy.NewJob(cfg.VisibilityPolicies, interceptableRepository)
+	if err := scheduler.Register(visibilityPolicyJob, jobs.Options{Interval: cfg.VisibilityPolicies.ReconcileInterval}); err != nil {
+		return nil, fmt.Errorf("could not schedule visibility policy reconciler: %v", err)
+	}
+
+	if cfg.PlatformCredentials.HashPasswords {
+		passwordMigration := &interceptors.PlatformPasswordMigration{Repository: interceptableRepository, Cost: cfg.PlatformCredentials.HashCost}
+		if err := scheduler.Register(passwordMigration, jobs.Options{Interval: cfg.PlatformCredentials.HashMigrationInterval}); err != nil {
+			return nil, fmt.Errorf("could not schedule platform password migration: %v", err)
+		}
+	}
+
+	bootstrapper := &bootstrap.Bootstrapper{
+		Repository: interceptableRepository,
+		Settings:   cfg.Bootstrap,
 	}
 
 	smb := &ServiceManagerBuilder{
@@ -137,30 +298,121 @@ func New(ctx context.Context, cancel context.CancelFunc, cfg *config.Settings) (
 		Storage:             interceptableRepository,
 		Notificator:         pgNotificator,
 		NotificationCleaner: notificationCleaner,
+		Features:            featuresManager,
+		Bootstrapper:        bootstrapper,
+		Scheduler:           scheduler,
+		CatalogPipeline:     catalogPipeline,
+		CredentialsPipeline: credentialsPipeline,
+		CredentialsPolicy:   credentialsPolicy,
+		PlatformTypes:       platformTypes,
+		Views:               apiViews,
+		CredentialsProvider: credentialsProvider,
+		TenantKeys:          tenantKeys,
+		CacheStore:          cacheStore,
+		WSConnections:       wsConnections,
+		HTTPClients:         httpClients,
 		ctx:                 ctx,
 		wg:                  waitGroup,
 		cfg:                 cfg.Server,
+		indexAdvisor:        indexAdvisor,
+		objectCache:         objectCache,
+		responseCache:       responseCache,
+		featureStates:       featureStates,
+		featuresSettings:    cfg.Features,
+		filterFactories:     defaultFilterFactories(),
+		filterSpecs:         cfg.API.Filters,
 	}
 
 	// Register default interceptors that represent the core SM business logic
 	smb.
 		WithCreateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerCreateCatalogInterceptorProvider{
-			CatalogFetcher: osb.CatalogFetcher(http.DefaultClient.Do, cfg.API.OSBVersion),
+			CatalogFetcher: catalogFetcher,
 		}).Register().
 		WithUpdateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerUpdateCatalogInterceptorProvider{
-			CatalogFetcher: osb.CatalogFetcher(http.DefaultClient.Do, cfg.API.OSBVersion),
+			CatalogFetcher: catalogFetcher,
 			CatalogLoader:  catalog.Load,
 		}).Register().
 		WithDeleteInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerDeleteCatalogInterceptorProvider{
 			CatalogLoader: catalog.Load,
 		}).Register().
-		WithCreateInterceptorProvider(types.PlatformType, &interceptors.GenerateCredentialsInterceptorProvider{}).Register().
+		WithCreateInterceptorProvider(types.PlatformType, &interceptors.GenerateCredentialsInterceptorProvider{
+			PlatformTypes: platformTypes,
+			Generators:    credentialsProvider,
+		}).Register().
+		WithCreateInterceptorProvider(types.PlatformType, &interceptors.PlatformSecretsCreateInterceptorProvider{
+			Secrets: credentialsProvider,
+		}).AroundTxAfter(interceptors.GenerateCredentialsInterceptorName).Register().
+		WithUpdateInterceptorProvider(types.PlatformType, &interceptors.PlatformSecretsUpdateInterceptorProvider{
+			Secrets: credentialsProvider,
+		}).Register().
+		WithDeleteInterceptorProvider(types.PlatformType, &interceptors.PlatformSecretsDeleteInterceptorProvider{
+			Secrets: credentialsProvider,
+		}).Register().
 		WithCreateInterceptorProvider(types.VisibilityType, &interceptors.VisibilityCreateNotificationsInterceptorProvider{}).Register().
 		WithUpdateInterceptorProvider(types.VisibilityType, &interceptors.VisibilityUpdateNotificationsInterceptorProvider{}).Register().
 		WithDeleteInterceptorProvider(types.VisibilityType, &interceptors.VisibilityDeleteNotificationsInterceptorProvider{}).Register().
 		WithCreateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerNotificationsCreateInterceptorProvider{}).Before(interceptors.BrokerCreateCatalogInterceptorName).Register().
 		WithUpdateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerNotificationsUpdateInterceptorProvider{}).Before(interceptors.BrokerUpdateCatalogInterceptorName).Register().
-		WithDeleteInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerNotificationsDeleteInterceptorProvider{}).After(interceptors.BrokerDeleteCatalogInterceptorName).Register()
+		WithDeleteInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerNotificationsDeleteInterceptorProvider{}).After(interceptors.BrokerDeleteCatalogInterceptorName).Register().
+		WithUpdateInterceptorProvider(types.ServiceOfferingType, &interceptors.LifecycleNotificationsInterceptorProvider{}).Register().
+		WithUpdateInterceptorProvider(types.ServicePlanType, &interceptors.LifecycleNotificationsInterceptorProvider{}).Register().
+		WithUpdateInterceptorProvider(types.ServicePlanType, &interceptors.MaintenanceInfoNotificationsInterceptorProvider{}).Register().
+		WithCreateInterceptorProvider(types.VisibilityPolicyType, &visibilitypolicy.SelectorsCreateInterceptorProvider{}).Register().
+		WithUpdateInterceptorProvider(types.VisibilityPolicyType, &visibilitypolicy.SelectorsUpdateInterceptorProvider{}).Register()
+
+	// Persist the revisions of the resources whose history is exposed
+	for _, objectType := range []types.ObjectType{types.ServiceBrokerType, types.PlatformType, types.VisibilityType} {
+		smb.
+			WithCreateInterceptorProvider(objectType, &interceptors.HistoryCreateInterceptorProvider{}).Register().
+			WithUpdateInterceptorProvider(objectType, &interceptors.HistoryUpdateInterceptorProvider{}).Register().
+			WithDeleteInterceptorProvider(objectType, &interceptors.HistoryDeleteInterceptorProvider{}).Register()
+	}
+
+	// Block changes of resources locked by another user
+	for _, objectType := range []types.ObjectType{types.ServiceBrokerType, types.PlatformType, types.VisibilityType} {
+		smb.
+			WithUpdateInterceptorProvider(objectType, &interceptors.LockUpdateInterceptorProvider{}).Register().
+			WithDeleteInterceptorProvider(objectType, &interceptors.LockDeleteInterceptorProvider{}).Register()
+	}
+
+	if cfg.Federation.Enabled {
+		smb.
+			WithCreateInterceptorProvider(types.ServiceBrokerType, &federation.ReadOnlyBrokersCreateInterceptorProvider{}).Before(interceptors.BrokerCreateCatalogInterceptorName).Register().
+			WithUpdateInterceptorProvider(types.ServiceBrokerType, &federation.ReadOnlyBrokersUpdateInterceptorProvider{}).Register().
+			WithDeleteInterceptorProvider(types.ServiceBrokerType, &federation.ReadOnlyBrokersDeleteInterceptorProvider{}).Register()
+	}
+
+	// Persist only hashes of the platform passwords as the Service Manager only verifies them. The broker passwords
+	// stay encrypted as they are sent to the brokers.
+	if cfg.PlatformCredentials.HashPasswords {
+		smb.
+			WithCreateInterceptorProvider(types.PlatformType, &interceptors.PlatformPasswordHashCreateInterceptorProvider{
+				Cost: cfg.PlatformCredentials.HashCost,
+			}).AroundTxAfter(interceptors.GenerateCredentialsInterceptorName).Register().
+			WithUpdateInterceptorProvider(types.PlatformType, &interceptors.PlatformPasswordHashUpdateInterceptorProvider{
+				Cost: cfg.PlatformCredentials.HashCost,
+			}).Register()
+	}
+
+	// Drop the statistics of the OSB calls to the brokers deleted in this instance
+	smb.WithDeleteInterceptorProvider(types.ServiceBrokerType, osbStats.DeleteHook()).Register()
+
+	// Invalidate the cached brokers when they are changed in this instance
+	updateHook, deleteHook := objectCache.Hooks(types.ServiceBrokerType)
+	smb.
+		WithUpdateInterceptorProvider(types.ServiceBrokerType, updateHook).Register().
+		WithDeleteInterceptorProvider(types.ServiceBrokerType, deleteHook).Register()
+
+	// Flush the cached offering and plan responses when the objects they are based on are changed in this instance
+	if responseCache.Enabled() {
+		for _, objectType := range filters.ResponseCachedTypes {
+			createHook, updateHook, deleteHook := responseCache.Hooks()
+			smb.
+				WithCreateInterceptorProvider(objectType, createHook).Register().
+				WithUpdateInterceptorProvider(objectType, updateHook).Register().
+				WithDeleteInterceptorProvider(objectType, deleteHook).Register()
+		}
+	}
 
 	return smb, nil
 }
@@ -168,6 +420,7 @@ func New(ctx context.Context, cancel context.CancelFunc, cfg *config.Settings) (
 // Build builds the Service Manager
 func (smb *ServiceManagerBuilder) Build() *ServiceManager {
 	// setup server and add relevant global middleware
+	smb.installFilters()
 	smb.installHealth()
 
 	srv := server.New(smb.cfg, smb.API)
@@ -179,6 +432,15 @@ func (smb *ServiceManagerBuilder) Build() *ServiceManager {
 		Server:              srv,
 		Notificator:         smb.Notificator,
 		NotificationCleaner: smb.NotificationCleaner,
+		Bootstrapper:        smb.Bootstrapper,
+		Scheduler:           smb.Scheduler,
+		indexAdvisor:        smb.indexAdvisor,
+		objectCache:         smb.objectCache,
+		responseCache:       smb.responseCache,
+		features:            smb.Features,
+		featureStates:       smb.featureStates,
+		featuresSettings:    smb.featuresSettings,
+		wsConnections:       smb.WSConnections,
 	}
 }
 
@@ -192,13 +454,27 @@ func (smb *ServiceManagerBuilder) installHealth() {
 func (sm *ServiceManager) Run() {
 	log.C(sm.ctx).Info("Running Service Manager...")
 
+	// Entities introduced by extensions are known at this point so their label tables are checked, too
+	sm.indexAdvisor.LogMissingIndexes(sm.ctx)
+
 	if err := sm.Notificator.Start(sm.ctx, sm.wg); err != nil {
 		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager notificator")
 	}
-	if err := sm.NotificationCleaner.Start(sm.ctx, sm.wg); err != nil {
-		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager notification cleaner")
+	if err := sm.objectCache.Start(sm.ctx, sm.Notificator, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager cache")
+	}
+	if err := sm.responseCache.Start(sm.ctx, sm.Notificator, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager response cache")
+	}
+	sm.featureStates.Start(sm.ctx, sm.features, sm.featuresSettings.RefreshInterval, sm.wg)
+	if err := sm.Scheduler.Start(sm.ctx, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager jobs scheduler")
+	}
+	if err := sm.Bootstrapper.Bootstrap(sm.ctx); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not bootstrap Service Manager resources")
 	}
 
+	sm.wsConnections.Start(sm.ctx, sm.wg)
 	sm.Server.Run(sm.ctx, sm.wg)
 
 	sm.wg.Wait()
@@ -208,6 +484,62 @@ func (smb *ServiceManagerBuilder) RegisterNotificationReceiversFilter(filterFunc
 	smb.Notificator.RegisterFilter(filterFunc)
 }
 
+// RegisterCatalogTransformers adds transformers which are applied to broker catalogs after they are fetched and
+// before they are persisted and served
+func (smb *ServiceManagerBuilder) RegisterCatalogTransformers(transformers ...catalog.Transformer) *ServiceManagerBuilder {
+	smb.CatalogPipeline.Register(transformers...)
+	return smb
+}
+
+// RegisterCredentialsTransformers adds transformers which are applied to the binding credentials returned by brokers
+// before they are returned to the platforms
+func (smb *ServiceManagerBuilder) RegisterCredentialsTransformers(transformers ...osb.CredentialsTransformer) *ServiceManagerBuilder {
+	smb.CredentialsPipeline.Register(transformers...)
+	return smb
+}
+
+// RegisterCredentialsChecks adds checks to the policy for the credentials supplied on broker registration, e.g. one
+// asking an external validation service
+func (smb *ServiceManagerBuilder) RegisterCredentialsChecks(checks ...filters.CredentialsCheck) *ServiceManagerBuilder {
+	smb.CredentialsPolicy.Register(checks...)
+	return smb
+}
+
+// RegisterKeyProviders adds key management services, e.g. AWS KMS or GCP KMS, whose keys tenants can supply
+// to encrypt the credentials of their brokers
+func (smb *ServiceManagerBuilder) RegisterKeyProviders(providers ...security.KeyProvider) *ServiceManagerBuilder {
+	smb.TenantKeys.RegisterProviders(providers...)
+	return smb
+}
+
+// RegisterPlatformTypePlugins adds plugins which customize the credentials, notifications and visibilities of the
+// platforms of their type
+func (smb *ServiceManagerBuilder) RegisterPlatformTypePlugins(plugins ...platformtypes.Plugin) *ServiceManagerBuilder {
+	smb.PlatformTypes.Register(plugins...)
+	return smb
+}
+
+// RegisterView adds an alternative serialization of the objects of the type in the API version, e.g. v1, which
+// clients request by its name with the view query parameter or the profile parameter of the Accept header
+func (smb *ServiceManagerBuilder) RegisterView(apiVersion string, objectType types.ObjectType, name string, view views.View) *ServiceManagerBuilder {
+	smb.Views.Register(apiVersion, objectType, name, view)
+	return smb
+}
+
+// WithSecretStore stores the passwords of platforms in the secret store instead of the Service Manager database,
+// which persists only references to them. It replaces the CredHub store configured in the settings.
+func (smb *ServiceManagerBuilder) WithSecretStore(store credentials.SecretStore) *ServiceManagerBuilder {
+	smb.CredentialsProvider.UseSecretStore(store)
+	return smb
+}
+
+// WithCredentialsGenerator replaces the random generator of the credentials of new platforms, e.g. with one which
+// creates clients in an external identity provider. The credentials generators of the platform types take precedence.
+func (smb *ServiceManagerBuilder) WithCredentialsGenerator(generator credentials.Generator) *ServiceManagerBuilder {
+	smb.CredentialsProvider.Use(generator)
+	return smb
+}
+
 func (smb *ServiceManagerBuilder) WithCreateInterceptorProvider(objectType types.ObjectType, provider storage.CreateInterceptorProvider) *interceptorRegistrationBuilder {
 	return &interceptorRegistrationBuilder{
 		order: storage.InterceptorOrder{
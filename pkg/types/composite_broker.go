@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api CompositeBroker
+// CompositeBroker exposes the selected service plans of several registered brokers as a single OSB broker of the
+// Service Manager, e.g. for a curated marketplace. The calls to it are routed to the brokers owning the plans.
+type CompositeBroker struct {
+	Base
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	PlanIDs     []string `json:"plan_ids"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *CompositeBroker) Validate() error {
+	if e.Name == "" {
+		return errors.New("missing composite broker name")
+	}
+	if len(e.PlanIDs) == 0 {
+		return errors.New("missing composite broker plan ids")
+	}
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	return e.Labels.Validate()
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const CompositeBrokerType ObjectType = "types.CompositeBroker"
+
+type CompositeBrokers struct {
+	CompositeBrokers []*CompositeBroker `json:"composite_brokers"`
+}
+
+func (e *CompositeBrokers) Add(object Object) {
+	e.CompositeBrokers = append(e.CompositeBrokers, object.(*CompositeBroker))
+}
+
+func (e *CompositeBrokers) ItemAt(index int) Object {
+	return e.CompositeBrokers[index]
+}
+
+func (e *CompositeBrokers) Len() int {
+	return len(e.CompositeBrokers)
+}
+
+func (e *CompositeBroker) GetType() ObjectType {
+	return CompositeBrokerType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *CompositeBroker) MarshalJSON() ([]byte, error) {
+	type E CompositeBroker
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -0,0 +1,44 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import "fmt"
+
+// LifecycleState is the lifecycle state of a service offering or plan. It is managed by the Service Manager
+// and not part of the broker catalog.
+type LifecycleState string
+
+const (
+	// LifecycleActive is the state of offerings and plans which are neither deprecated nor retired
+	LifecycleActive LifecycleState = ""
+
+	// LifecycleDeprecated is the state of offerings and plans which are still provisionable but will be retired
+	LifecycleDeprecated LifecycleState = "deprecated"
+
+	// LifecycleRetired is the state of offerings and plans which can no longer be provisioned
+	LifecycleRetired LifecycleState = "retired"
+)
+
+// Validate verifies that the lifecycle state is one of the known states
+func (s LifecycleState) Validate() error {
+	switch s {
+	case LifecycleActive, LifecycleDeprecated, LifecycleRetired:
+		return nil
+	default:
+		return fmt.Errorf("lifecycle must be one of %q, %q or empty but was %q", LifecycleDeprecated, LifecycleRetired, s)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+//go:generate smgen api Lock
+// Lock blocks the modification and deletion of a resource by other users than its owner until it expires
+type Lock struct {
+	Base
+	ResourceID   string     `json:"resource_id"`
+	ResourceType ObjectType `json:"resource_type"`
+	Owner        string     `json:"owner"`
+	Reason       string     `json:"reason"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+}
+
+// IsActive returns whether the lock has not expired at the provided time
+func (l *Lock) IsActive(at time.Time) bool {
+	return at.Before(l.ExpiresAt)
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (l *Lock) Validate() error {
+	if l.ResourceID == "" {
+		return fmt.Errorf("missing lock resource id")
+	}
+	if l.ResourceType == "" {
+		return fmt.Errorf("missing lock resource type")
+	}
+	if l.Reason == "" {
+		return fmt.Errorf("missing lock reason")
+	}
+	if l.ExpiresAt.IsZero() {
+		return fmt.Errorf("missing lock expiration")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const LockType ObjectType = "types.Lock"
+
+type Locks struct {
+	Locks []*Lock `json:"locks"`
+}
+
+func (e *Locks) Add(object Object) {
+	e.Locks = append(e.Locks, object.(*Lock))
+}
+
+func (e *Locks) ItemAt(index int) Object {
+	return e.Locks[index]
+}
+
+func (e *Locks) Len() int {
+	return len(e.Locks)
+}
+
+func (e *Lock) GetType() ObjectType {
+	return LockType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *Lock) MarshalJSON() ([]byte, error) {
+	type E Lock
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
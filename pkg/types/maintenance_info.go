@@ -0,0 +1,48 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// semanticVersionPattern matches versions as defined by Semantic Versioning 2.0.0
+var semanticVersionPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// MaintenanceInfo is the maintenance information of a service plan as defined by OSB API 2.15. A change of its
+// version means that the service instances of the plan can be upgraded.
+type MaintenanceInfo struct {
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Validate verifies that the version is a semantic version
+func (m *MaintenanceInfo) Validate() error {
+	if !semanticVersionPattern.MatchString(m.Version) {
+		return fmt.Errorf("maintenance info version %q is not a semantic version", m.Version)
+	}
+	return nil
+}
+
+// MaintenanceVersion returns the maintenance info version, which is empty if there is no maintenance info
+func (m *MaintenanceInfo) MaintenanceVersion() string {
+	if m == nil {
+		return ""
+	}
+	return m.Version
+}
@@ -0,0 +1,85 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// OperationCategory is the type of an operation
+type OperationCategory string
+
+const (
+	// CREATE represents an operation type for creating a resource
+	CREATE OperationCategory = "create"
+
+	// UPDATE represents an operation type for updating a resource
+	UPDATE OperationCategory = "update"
+
+	// DELETE represents an operation type for deleting a resource
+	DELETE OperationCategory = "delete"
+)
+
+// OperationState is the state of an operation
+type OperationState string
+
+const (
+	// IN_PROGRESS represents the state of an operation that is still being processed
+	IN_PROGRESS OperationState = "in progress"
+
+	// SUCCEEDED represents the state of an operation that completed successfully
+	SUCCEEDED OperationState = "succeeded"
+
+	// FAILED represents the state of an operation that completed with an error
+	FAILED OperationState = "failed"
+)
+
+//go:generate smgen api Operation OperationObjectType
+// Operation struct tracks the progress of a long running action on a resource
+type Operation struct {
+	Base
+	Type         OperationCategory `json:"type"`
+	State        OperationState    `json:"state"`
+	ResourceID   string            `json:"resource_id"`
+	ResourceType ObjectType        `json:"resource_type"`
+	Description  string            `json:"description,omitempty"`
+	Errors       json.RawMessage   `json:"errors,omitempty"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (o *Operation) Validate() error {
+	if util.HasRFC3986ReservedSymbols(o.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", o.ID)
+	}
+	if o.Type == "" {
+		return fmt.Errorf("missing operation type")
+	}
+	if o.State == "" {
+		return fmt.Errorf("missing operation state")
+	}
+	if o.ResourceID == "" {
+		return fmt.Errorf("missing operation resource id")
+	}
+	if o.ResourceType == "" {
+		return fmt.Errorf("missing operation resource type")
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api Peer
+// Peer is another Service Manager instance whose brokers are imported by this instance.
+// The credentials are the ones of a platform registered for this instance in the peer.
+type Peer struct {
+	Base
+	Secured     `json:"-"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	URL         string       `json:"url"`
+	Credentials *Credentials `json:"credentials,omitempty"`
+}
+
+func (e *Peer) SetCredentials(credentials *Credentials) {
+	e.Credentials = credentials
+}
+
+func (e *Peer) GetCredentials() *Credentials {
+	return e.Credentials
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *Peer) Validate() error {
+	if e.Name == "" {
+		return errors.New("missing peer name")
+	}
+	if e.URL == "" {
+		return errors.New("missing peer url")
+	}
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	if e.Credentials == nil {
+		return errors.New("missing credentials")
+	}
+	return e.Credentials.Validate()
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const PeerType ObjectType = "types.Peer"
+
+type Peers struct {
+	Peers []*Peer `json:"peers"`
+}
+
+func (e *Peers) Add(object Object) {
+	e.Peers = append(e.Peers, object.(*Peer))
+}
+
+func (e *Peers) ItemAt(index int) Object {
+	return e.Peers[index]
+}
+
+func (e *Peers) Len() int {
+	return len(e.Peers)
+}
+
+func (e *Peer) GetType() ObjectType {
+	return PeerType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *Peer) MarshalJSON() ([]byte, error) {
+	type E Peer
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -32,6 +32,9 @@ type Platform struct {
 	Name        string       `json:"name"`
 	Description string       `json:"description"`
 	Credentials *Credentials `json:"credentials,omitempty"`
+
+	// CredentialsType is the name of the generator of the credentials, e.g. random
+	CredentialsType string `json:"credentials_type,omitempty"`
 }
 
 func (e *Platform) SetCredentials(credentials *Credentials) {
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//go:generate smgen api Revision
+// Revision is the state of a resource after a create or update, or before a delete, together with the acting user
+type Revision struct {
+	Base
+	ResourceID   string            `json:"resource_id"`
+	ResourceType ObjectType        `json:"resource_type"`
+	Operation    OperationCategory `json:"operation"`
+	User         string            `json:"user,omitempty"`
+	Payload      json.RawMessage   `json:"payload"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (r *Revision) Validate() error {
+	if r.ResourceID == "" {
+		return fmt.Errorf("missing revision resource id")
+	}
+	if r.ResourceType == "" {
+		return fmt.Errorf("missing revision resource type")
+	}
+	if r.Operation == "" {
+		return fmt.Errorf("missing revision operation")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const RevisionType ObjectType = "types.Revision"
+
+type Revisions struct {
+	Revisions []*Revision `json:"revisions"`
+}
+
+func (e *Revisions) Add(object Object) {
+	e.Revisions = append(e.Revisions, object.(*Revision))
+}
+
+func (e *Revisions) ItemAt(index int) Object {
+	return e.Revisions[index]
+}
+
+func (e *Revisions) Len() int {
+	return len(e.Revisions)
+}
+
+func (e *Revision) GetType() ObjectType {
+	return RevisionType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *Revision) MarshalJSON() ([]byte, error) {
+	type E Revision
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api SavedQuery
+// SavedQuery is a named set of criteria which list calls reference with the query parameter, e.g. ?query=dev-brokers.
+// A saved query belongs to the user who created it, unless it is shared with all users.
+type SavedQuery struct {
+	Base
+	Name       string `json:"name"`
+	FieldQuery string `json:"field_query,omitempty"`
+	LabelQuery string `json:"label_query,omitempty"`
+	OrderBy    string `json:"order_by,omitempty"`
+	Order      string `json:"order,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+	Shared     bool   `json:"shared"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *SavedQuery) Validate() error {
+	if e.Name == "" {
+		return errors.New("missing saved query name")
+	}
+	if e.FieldQuery == "" && e.LabelQuery == "" && e.OrderBy == "" {
+		return errors.New("saved query selects and orders nothing")
+	}
+	if e.Order != "" && e.OrderBy == "" {
+		return errors.New("saved query order requires order_by")
+	}
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	return e.Labels.Validate()
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const SavedQueryType ObjectType = "types.SavedQuery"
+
+type SavedQueries struct {
+	SavedQueries []*SavedQuery `json:"saved_queries"`
+}
+
+func (e *SavedQueries) Add(object Object) {
+	e.SavedQueries = append(e.SavedQueries, object.(*SavedQuery))
+}
+
+func (e *SavedQueries) ItemAt(index int) Object {
+	return e.SavedQueries[index]
+}
+
+func (e *SavedQueries) Len() int {
+	return len(e.SavedQueries)
+}
+
+func (e *SavedQuery) GetType() ObjectType {
+	return SavedQueryType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *SavedQuery) MarshalJSON() ([]byte, error) {
+	type E SavedQuery
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
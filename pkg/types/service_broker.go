@@ -34,6 +34,11 @@ type ServiceBroker struct {
 
 	Catalog  json.RawMessage    `json:"-" structs:"-"`
 	Services []*ServiceOffering `json:"-" structs:"-"`
+
+	// CatalogETag and CatalogLastModified are the validators returned by the broker with its catalog. They are used
+	// to fetch the catalog conditionally.
+	CatalogETag         string `json:"-" structs:"-"`
+	CatalogLastModified string `json:"-" structs:"-"`
 }
 
 func (e *ServiceBroker) SetCredentials(credentials *Credentials) {
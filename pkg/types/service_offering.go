@@ -43,6 +43,8 @@ type ServiceOffering struct {
 	CatalogID   string `json:"catalog_id"`
 	CatalogName string `json:"catalog_name"`
 
+	Lifecycle LifecycleState `json:"lifecycle,omitempty"`
+
 	Plans []*ServicePlan `json:"plans"`
 }
 
@@ -80,6 +82,9 @@ func (e *ServiceOffering) Validate() error {
 			return fmt.Errorf("service offering metadata is invalid JSON")
 		}
 	}
+	if err := e.Lifecycle.Validate(); err != nil {
+		return fmt.Errorf("service offering %s", err)
+	}
 
 	return nil
 }
@@ -19,6 +19,9 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/Peripli/service-manager/pkg/util"
 )
@@ -39,9 +42,57 @@ type ServicePlan struct {
 	Metadata json.RawMessage `json:"metadata,omitempty"`
 	Schemas  json.RawMessage `json:"schemas,omitempty"`
 
+	// PriceAmount, PriceCurrency and PriceUnit are the pricing of the plan taken from the costs in its catalog metadata
+	PriceAmount   *float64 `json:"price_amount,omitempty"`
+	PriceCurrency string   `json:"price_currency,omitempty"`
+	PriceUnit     string   `json:"price_unit,omitempty"`
+
+	Lifecycle LifecycleState `json:"lifecycle,omitempty"`
+
+	MaintenanceInfo *MaintenanceInfo `json:"maintenance_info,omitempty"`
+
 	ServiceOfferingID string `json:"service_offering_id"`
 }
 
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// SetPricingFromMetadata sets the pricing of the plan from the first of the costs in its catalog metadata, which
+// have the form {"amount": {"usd": 99.0}, "unit": "MONTHLY"}. If the cost has amounts in several currencies, the
+// first currency in alphabetical order is used. The pricing is cleared if the metadata contains no costs.
+func (e *ServicePlan) SetPricingFromMetadata() error {
+	e.PriceAmount, e.PriceCurrency, e.PriceUnit = nil, "", ""
+	if len(e.Metadata) == 0 {
+		return nil
+	}
+	metadata := struct {
+		Costs []struct {
+			Amount map[string]float64 `json:"amount"`
+			Unit   string             `json:"unit"`
+		} `json:"costs"`
+	}{}
+	if err := json.Unmarshal(e.Metadata, &metadata); err != nil {
+		return fmt.Errorf("service plan metadata costs are invalid: %s", err)
+	}
+	if len(metadata.Costs) == 0 {
+		return nil
+	}
+
+	cost := metadata.Costs[0]
+	if len(cost.Amount) == 0 {
+		return fmt.Errorf("service plan metadata costs must contain an amount")
+	}
+	currencies := make([]string, 0, len(cost.Amount))
+	for currency := range cost.Amount {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	amount := cost.Amount[currencies[0]]
+	e.PriceAmount = &amount
+	e.PriceCurrency = strings.ToUpper(currencies[0])
+	e.PriceUnit = cost.Unit
+	return nil
+}
+
 // Validate implements InputValidator and verifies all mandatory fields are populated
 func (e *ServicePlan) Validate() error {
 	if util.HasRFC3986ReservedSymbols(e.ID) {
@@ -70,6 +121,25 @@ func (e *ServicePlan) Validate() error {
 			return fmt.Errorf("service plan metadata is invalid JSON")
 		}
 	}
+	if e.PriceAmount != nil || e.PriceCurrency != "" || e.PriceUnit != "" {
+		if e.PriceAmount == nil || *e.PriceAmount < 0 {
+			return fmt.Errorf("service plan price amount must not be negative")
+		}
+		if !currencyPattern.MatchString(e.PriceCurrency) {
+			return fmt.Errorf("service plan price currency %q is not a three letter currency code", e.PriceCurrency)
+		}
+		if e.PriceUnit == "" {
+			return fmt.Errorf("service plan price unit missing")
+		}
+	}
+	if err := e.Lifecycle.Validate(); err != nil {
+		return fmt.Errorf("service plan %s", err)
+	}
+	if e.MaintenanceInfo != nil {
+		if err := e.MaintenanceInfo.Validate(); err != nil {
+			return fmt.Errorf("service plan %s", err)
+		}
+	}
 
 	return nil
 }
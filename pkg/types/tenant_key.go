@@ -0,0 +1,45 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+)
+
+//go:generate smgen api TenantKey
+// TenantKey is the data encryption key of a tenant, wrapped by a key of the tenant in a key management service.
+// The ID of a tenant key is the tenant.
+type TenantKey struct {
+	Base
+	Provider   string `json:"provider"`
+	KeyRef     string `json:"key_ref"`
+	WrappedKey []byte `json:"-"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (k *TenantKey) Validate() error {
+	if k.ID == "" {
+		return fmt.Errorf("missing tenant")
+	}
+	if k.Provider == "" {
+		return fmt.Errorf("missing tenant key provider")
+	}
+	if k.KeyRef == "" {
+		return fmt.Errorf("missing tenant key reference")
+	}
+	return nil
+}
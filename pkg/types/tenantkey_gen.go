@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const TenantKeyType ObjectType = "types.TenantKey"
+
+type TenantKeys struct {
+	TenantKeys []*TenantKey `json:"tenant_keys"`
+}
+
+func (e *TenantKeys) Add(object Object) {
+	e.TenantKeys = append(e.TenantKeys, object.(*TenantKey))
+}
+
+func (e *TenantKeys) ItemAt(index int) Object {
+	return e.TenantKeys[index]
+}
+
+func (e *TenantKeys) Len() int {
+	return len(e.TenantKeys)
+}
+
+func (e *TenantKey) GetType() ObjectType {
+	return TenantKeyType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *TenantKey) MarshalJSON() ([]byte, error) {
+	type E TenantKey
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -19,6 +19,7 @@ package types
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/util"
 )
@@ -29,6 +30,21 @@ type Visibility struct {
 	Base
 	PlatformID    string `json:"platform_id"`
 	ServicePlanID string `json:"service_plan_id"`
+
+	// ValidFrom and ValidUntil optionally restrict the time in which the visibility is in effect, e.g. for trials
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// IsActive returns true if the visibility is in effect at the provided time
+func (e *Visibility) IsActive(at time.Time) bool {
+	if e.ValidFrom != nil && at.Before(*e.ValidFrom) {
+		return false
+	}
+	if e.ValidUntil != nil && !at.Before(*e.ValidUntil) {
+		return false
+	}
+	return true
 }
 
 // Validate implements InputValidator and verifies all mandatory fields are populated
@@ -42,5 +58,8 @@ func (e *Visibility) Validate() error {
 	if err := e.Labels.Validate(); err != nil {
 		return err
 	}
+	if e.ValidFrom != nil && e.ValidUntil != nil && !e.ValidUntil.After(*e.ValidFrom) {
+		return errors.New("visibility valid_until must be after valid_from")
+	}
 	return nil
 }
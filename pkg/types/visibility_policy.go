@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api VisibilityPolicy
+// VisibilityPolicy declares that the service plans matching its plan selector are visible to the platforms
+// matching its platform selector. The selectors are label queries, e.g. tier = free and env = dev.
+// The visibilities are created and deleted by a reconciler as plans and platforms change.
+type VisibilityPolicy struct {
+	Base
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	PlanSelector     string `json:"plan_selector"`
+	PlatformSelector string `json:"platform_selector"`
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *VisibilityPolicy) Validate() error {
+	if e.Name == "" {
+		return errors.New("missing visibility policy name")
+	}
+	if e.PlanSelector == "" {
+		return errors.New("missing visibility policy plan selector")
+	}
+	if e.PlatformSelector == "" {
+		return errors.New("missing visibility policy platform selector")
+	}
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	return e.Labels.Validate()
+}
@@ -0,0 +1,64 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const VisibilityPolicyType ObjectType = "types.VisibilityPolicy"
+
+type VisibilityPolicies struct {
+	VisibilityPolicies []*VisibilityPolicy `json:"visibility_policies"`
+}
+
+func (e *VisibilityPolicies) Add(object Object) {
+	e.VisibilityPolicies = append(e.VisibilityPolicies, object.(*VisibilityPolicy))
+}
+
+func (e *VisibilityPolicies) ItemAt(index int) Object {
+	return e.VisibilityPolicies[index]
+}
+
+func (e *VisibilityPolicies) Len() int {
+	return len(e.VisibilityPolicies)
+}
+
+func (e *VisibilityPolicy) GetType() ObjectType {
+	return VisibilityPolicyType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *VisibilityPolicy) MarshalJSON() ([]byte, error) {
+	type E VisibilityPolicy
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -19,19 +19,21 @@ package util
 
 import (
 	"encoding/json"
-	"github.com/Peripli/service-manager/pkg/web"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/web"
 )
 
 var (
 	reservedSymbolsRFC3986 = strings.Join([]string{
 		":", "/", "?", "#", "[", "]", "@", "!", "$", "&", "'", "(", ")", "*", "+", ",", ";", "=",
 	}, "")
-	supportedContentTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+	// application/octet-stream is accepted for bodies which are not JSON as a whole, e.g. compressed or partial uploads
+	supportedContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "application/octet-stream"}
 )
 
 // InputValidator should be implemented by types that need input validation check. For a reference refer to pkg/types
@@ -53,20 +55,8 @@ func ToRFCFormat(timestamp time.Time) string {
 // the media type is unsupported or if the body is not a valid JSON
 func RequestBodyToBytes(request *http.Request) ([]byte, error) {
 	contentType := request.Header.Get("Content-Type")
-	contentTypeSupported := false
-	for _, supportedType := range supportedContentTypes {
-		if strings.Contains(contentType, supportedType) {
-			contentTypeSupported = true
-			break
-		}
-	}
-
-	if !contentTypeSupported {
-		return nil, &HTTPError{
-			ErrorType:   "UnsupportedMediaType",
-			Description: "unsupported media type provided",
-			StatusCode:  http.StatusUnsupportedMediaType,
-		}
+	if err := validateContentType(contentType); err != nil {
+		return nil, err
 	}
 
 	body, err := BodyToBytes(request.Body)
@@ -75,16 +65,84 @@ func RequestBodyToBytes(request *http.Request) ([]byte, error) {
 	}
 
 	if strings.Contains(contentType, "application/json") && !json.Valid(body) {
-		return nil, &HTTPError{
-			ErrorType:   "BadRequest",
-			Description: "request body is not valid JSON",
-			StatusCode:  http.StatusBadRequest,
-		}
+		return nil, invalidJSONError()
 	}
 
 	return body, nil
 }
 
+// RequestBodyToBuffer reads the request body into a web.BufferedBody which keeps up to memoryThreshold bytes in
+// memory and spills larger bodies over to a temporary file. It returns an error if the media type is unsupported
+// or if the body is not a valid JSON. The returned body must be closed by the caller.
+func RequestBodyToBuffer(request *http.Request, memoryThreshold int64) (*web.BufferedBody, error) {
+	contentType := request.Header.Get("Content-Type")
+	if err := validateContentType(contentType); err != nil {
+		return nil, err
+	}
+
+	body, err := web.NewBufferedBody(request.Body, memoryThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(contentType, "application/json") && !validJSON(body) {
+		body.Close()
+		return nil, invalidJSONError()
+	}
+
+	return body, nil
+}
+
+func validateContentType(contentType string) error {
+	for _, supportedType := range supportedContentTypes {
+		if strings.Contains(contentType, supportedType) {
+			return nil
+		}
+	}
+	return &HTTPError{
+		ErrorType:   "UnsupportedMediaType",
+		Description: "unsupported media type provided",
+		StatusCode:  http.StatusUnsupportedMediaType,
+	}
+}
+
+// validJSON checks that the body contains exactly one JSON value without loading a spilled over body into memory
+func validJSON(body *web.BufferedBody) bool {
+	if body.InMemory() {
+		content, _ := body.Bytes()
+		return json.Valid(content)
+	}
+	decoder := json.NewDecoder(body.Reader())
+	depth, values := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return depth == 0 && values == 1
+		}
+		if err != nil {
+			return false
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			values++
+		}
+	}
+}
+
+func invalidJSONError() error {
+	return &HTTPError{
+		ErrorType:   "BadRequest",
+		Description: "request body is not valid JSON",
+		StatusCode:  http.StatusBadRequest,
+	}
+}
+
 // BytesToObject converts the provided bytes to object and validates it
 func BytesToObject(bytes []byte, object interface{}) error {
 	if err := unmarshal(bytes, object); err != nil {
@@ -142,6 +200,10 @@ func NewJSONResponse(code int, value interface{}) (*web.Response, error) {
 	headers := http.Header{}
 	headers.Add("Content-Type", "application/json")
 
+	if httpErr, ok := value.(*HTTPError); ok {
+		value = httpErr.withCode("")
+	}
+
 	body := make([]byte, 0)
 	var err error
 	if _, ok := value.(EmptyResponseBody); !ok {
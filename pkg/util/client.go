@@ -25,6 +25,7 @@ import (
 	"net/http"
 
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/web"
 )
 
 // DoRequestFunc is an alias for any function that takes an http request and returns a response and error
@@ -73,8 +74,7 @@ func SendRequestWithHeaders(ctx context.Context, doRequest DoRequestFunc, method
 
 	request = request.WithContext(ctx)
 	logger := log.C(ctx)
-	correlationID, exists := logger.Data[log.FieldCorrelationID].(string)
-	if exists {
+	if correlationID := CorrelationIDFromContext(ctx); correlationID != "" {
 		request.Header.Set(log.CorrelationIDHeaders[0], correlationID)
 	}
 
@@ -82,6 +82,16 @@ func SendRequestWithHeaders(ctx context.Context, doRequest DoRequestFunc, method
 	return doRequest(request)
 }
 
+// CorrelationIDFromContext returns the correlation id of the request the context belongs to. Contexts created outside
+// of a request, e.g. by background jobs, fall back to the correlation id of their logger.
+func CorrelationIDFromContext(ctx context.Context) string {
+	if correlationID := web.MetadataFromContext(ctx).CorrelationID(); correlationID != "" {
+		return correlationID
+	}
+	correlationID, _ := log.C(ctx).Data[log.FieldCorrelationID].(string)
+	return correlationID
+}
+
 // BodyToBytes of the request inside given struct
 func BodyToBytes(closer io.ReadCloser) ([]byte, error) {
 	defer func() {
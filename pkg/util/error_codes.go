@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrorCode is a stable identifier of a kind of error. Clients can branch on the code of an error response instead of
+// parsing its description, which may change or be localized.
+type ErrorCode struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	StatusCode  int    `json:"status_code"`
+	Description string `json:"description"`
+}
+
+// ErrorLocalizer returns the description of an error in one of the languages of an Accept-Language header. It returns
+// the description unchanged if none of the languages is supported.
+type ErrorLocalizer func(acceptLanguage string, code ErrorCode, description string) string
+
+var (
+	// ClientErrorCode is the code of client errors whose type has no registered code
+	ClientErrorCode = ErrorCode{Code: "SM-4000", Name: "ClientError", StatusCode: http.StatusBadRequest, Description: "the request failed for a reason without a more specific code"}
+
+	// ServerErrorCode is the code of server errors whose type has no registered code
+	ServerErrorCode = ErrorCode{Code: "SM-5000", Name: "ServerError", StatusCode: http.StatusInternalServerError, Description: "the request could not be processed for a reason without a more specific code"}
+)
+
+var (
+	errorCodesMutex sync.RWMutex
+	errorLocalizer  ErrorLocalizer
+
+	// errorCodes are the registered error codes by error type
+	errorCodes = map[string]ErrorCode{}
+)
+
+func init() {
+	for _, code := range []ErrorCode{
+		ClientErrorCode,
+		{Code: "SM-1001", Name: "BadRequest", StatusCode: http.StatusBadRequest, Description: "the request is invalid, e.g. it has an invalid body or query"},
+		{Code: "SM-1002", Name: "InvalidFieldQuery", StatusCode: http.StatusBadRequest, Description: "the field or label query of the request cannot be executed"},
+		{Code: "SM-1003", Name: "Unauthorized", StatusCode: http.StatusUnauthorized, Description: "the request is not authenticated"},
+		{Code: "SM-1004", Name: "Forbidden", StatusCode: http.StatusForbidden, Description: "the user is not permitted to perform the request"},
+		{Code: "SM-1005", Name: "NotFound", StatusCode: http.StatusNotFound, Description: "the resource does not exist"},
+		{Code: "SM-1006", Name: "Conflict", StatusCode: http.StatusConflict, Description: "the resource conflicts with an existing resource"},
+		{Code: "SM-1007", Name: "Gone", StatusCode: http.StatusGone, Description: "the requested revision is no longer known, the resources should be listed again"},
+		{Code: "SM-1008", Name: "ConcurrentResourceUpdate", StatusCode: http.StatusPreconditionFailed, Description: "the resource was updated concurrently, the update should be retried"},
+		{Code: "SM-1009", Name: "PayloadTooLarge", StatusCode: http.StatusRequestEntityTooLarge, Description: "the request body exceeds the maximum size"},
+		{Code: "SM-1010", Name: "UnsupportedMediaType", StatusCode: http.StatusUnsupportedMediaType, Description: "the request body does not have a supported content type"},
+		{Code: "SM-1011", Name: "Locked", StatusCode: http.StatusLocked, Description: "the resource is locked by another user"},
+		{Code: "SM-1012", Name: "MaintenanceInfoConflict", StatusCode: http.StatusUnprocessableEntity, Description: "the maintenance info of the request does not match the plan"},
+		{Code: "SM-1013", Name: "WebsocketUpgradeError", StatusCode: http.StatusBadRequest, Description: "the connection could not be upgraded to a websocket"},
+		{Code: "SM-1014", Name: "WeakCredentials", StatusCode: http.StatusBadRequest, Description: "the credentials violate the credentials policy, the violations are listed in the details"},
+		ServerErrorCode,
+		{Code: "SM-5001", Name: "InternalError", StatusCode: http.StatusInternalServerError, Description: "an unexpected error occurred"},
+		{Code: "SM-5002", Name: "ServiceBrokerErr", StatusCode: http.StatusBadGateway, Description: "the service broker could not be reached or returned an invalid response"},
+		{Code: "SM-5003", Name: "CredentialsTransformationErr", StatusCode: http.StatusBadGateway, Description: "the credentials returned by the service broker could not be transformed"},
+		{Code: "SM-5004", Name: "ServiceUnavailable", StatusCode: http.StatusServiceUnavailable, Description: "the Service Manager is overloaded, the request should be retried later"},
+	} {
+		if err := RegisterErrorCode(code); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterErrorCode registers the code of the errors of a type. Extensions register the codes of the error types they
+// introduce, codes and error types can only be registered once.
+func RegisterErrorCode(code ErrorCode) error {
+	if !strings.HasPrefix(code.Code, "SM-") || code.Name == "" {
+		return fmt.Errorf("invalid error code %s for error type %s", code.Code, code.Name)
+	}
+
+	errorCodesMutex.Lock()
+	defer errorCodesMutex.Unlock()
+	for _, registered := range errorCodes {
+		if registered.Code == code.Code || registered.Name == code.Name {
+			return fmt.Errorf("error code %s for error type %s is already registered", registered.Code, registered.Name)
+		}
+	}
+	errorCodes[code.Name] = code
+	return nil
+}
+
+// ErrorCodes returns all registered error codes ordered by code
+func ErrorCodes() []ErrorCode {
+	errorCodesMutex.RLock()
+	defer errorCodesMutex.RUnlock()
+
+	result := make([]ErrorCode, 0, len(errorCodes))
+	for _, code := range errorCodes {
+		result = append(result, code)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
+
+// ErrorCodeFor returns the code of errors of the type or, if the type has no registered code, the generic code of the
+// status code
+func ErrorCodeFor(errorType string, statusCode int) ErrorCode {
+	errorCodesMutex.RLock()
+	defer errorCodesMutex.RUnlock()
+
+	if code, found := errorCodes[errorType]; found {
+		return code
+	}
+	if statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError {
+		return ClientErrorCode
+	}
+	return ServerErrorCode
+}
+
+// SetErrorLocalizer sets the localizer of the descriptions of the error responses, the descriptions are not localized
+// if it is nil
+func SetErrorLocalizer(localizer ErrorLocalizer) {
+	errorCodesMutex.Lock()
+	defer errorCodesMutex.Unlock()
+	errorLocalizer = localizer
+}
+
+// withCode returns a copy of the error with its error code and its description localized for the Accept-Language header
+func (e *HTTPError) withCode(acceptLanguage string) *HTTPError {
+	result := *e
+	code := ErrorCodeFor(e.ErrorType, e.StatusCode)
+	if result.Code == "" {
+		result.Code = code.Code
+	}
+
+	errorCodesMutex.RLock()
+	for _, registered := range errorCodes {
+		if registered.Code == result.Code {
+			code = registered
+		}
+	}
+	localizer := errorLocalizer
+	errorCodesMutex.RUnlock()
+	if localizer != nil && acceptLanguage != "" {
+		result.Description = localizer(acceptLanguage, code, result.Description)
+	}
+	return &result
+}
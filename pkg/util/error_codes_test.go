@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error codes", func() {
+	var responseRecorder *httptest.ResponseRecorder
+
+	writtenError := func() *util.HTTPError {
+		httpErr := &util.HTTPError{}
+		Expect(json.Unmarshal(responseRecorder.Body.Bytes(), httpErr)).To(Succeed())
+		return httpErr
+	}
+
+	BeforeEach(func() {
+		responseRecorder = httptest.NewRecorder()
+	})
+
+	It("lists the registered codes ordered by code", func() {
+		codes := util.ErrorCodes()
+		Expect(codes).ToNot(BeEmpty())
+		for i := 1; i < len(codes); i++ {
+			Expect(codes[i-1].Code < codes[i].Code).To(BeTrue())
+		}
+	})
+
+	It("rejects codes which are registered already", func() {
+		Expect(util.RegisterErrorCode(util.ErrorCode{Code: "SM-9001", Name: "NotFound"})).ToNot(Succeed())
+		Expect(util.RegisterErrorCode(util.ErrorCode{Code: "SM-1005", Name: "Missing"})).ToNot(Succeed())
+		Expect(util.RegisterErrorCode(util.ErrorCode{Code: "1005", Name: "Missing"})).ToNot(Succeed())
+	})
+
+	It("returns the codes of extensions", func() {
+		Expect(util.RegisterErrorCode(util.ErrorCode{Code: "SM-9002", Name: "ExtensionErr", StatusCode: http.StatusTeapot})).To(Succeed())
+		Expect(util.ErrorCodeFor("ExtensionErr", http.StatusTeapot).Code).To(Equal("SM-9002"))
+	})
+
+	It("falls back to the codes of the status code class", func() {
+		Expect(util.ErrorCodeFor("UnknownErr", http.StatusTeapot)).To(Equal(util.ClientErrorCode))
+		Expect(util.ErrorCodeFor("UnknownErr", http.StatusBadGateway)).To(Equal(util.ServerErrorCode))
+	})
+
+	Describe("WriteError", func() {
+		It("writes the code of the error type", func() {
+			util.WriteError(&util.HTTPError{ErrorType: "NotFound", StatusCode: http.StatusNotFound}, responseRecorder)
+			Expect(writtenError().Code).To(Equal("SM-1005"))
+		})
+
+		It("keeps a more specific code", func() {
+			util.WriteError(&util.UnsupportedQueryError{Message: "invalid query"}, responseRecorder)
+			Expect(writtenError().ErrorType).To(Equal("BadRequest"))
+			Expect(writtenError().Code).To(Equal("SM-1002"))
+		})
+
+		It("does not modify the error", func() {
+			httpErr := &util.HTTPError{ErrorType: "NotFound", StatusCode: http.StatusNotFound}
+			util.WriteError(httpErr, responseRecorder)
+			Expect(httpErr.Code).To(BeEmpty())
+		})
+	})
+
+	Describe("WriteLocalizedError", func() {
+		BeforeEach(func() {
+			util.SetErrorLocalizer(func(acceptLanguage string, code util.ErrorCode, description string) string {
+				if strings.HasPrefix(acceptLanguage, "de") && code.Code == "SM-1005" {
+					return "nicht gefunden"
+				}
+				return description
+			})
+		})
+
+		AfterEach(func() {
+			util.SetErrorLocalizer(nil)
+		})
+
+		It("localizes the description for the languages of the request", func() {
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_brokers/id", nil)
+			request.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+			util.WriteLocalizedError(&util.HTTPError{ErrorType: "NotFound", Description: "not found", StatusCode: http.StatusNotFound}, responseRecorder, request)
+			Expect(writtenError().Description).To(Equal("nicht gefunden"))
+			Expect(writtenError().Code).To(Equal("SM-1005"))
+		})
+
+		It("keeps the description if no language is requested", func() {
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_brokers/id", nil)
+			util.WriteLocalizedError(&util.HTTPError{ErrorType: "NotFound", Description: "not found", StatusCode: http.StatusNotFound}, responseRecorder, request)
+			Expect(writtenError().Description).To(Equal("not found"))
+		})
+	})
+})
@@ -24,11 +24,15 @@ import (
 	"github.com/Peripli/service-manager/pkg/log"
 )
 
-// HTTPError is an error type that provides error details that Service Manager error handlers would propagate to the client
+// HTTPError is an error type that provides error details that Service Manager error handlers would propagate to the client.
+// The Code is set from the registered error codes when the error is written, unless a more specific code is set.
+// Details are returned as they are, e.g. the individual violations of a validation error.
 type HTTPError struct {
-	ErrorType   string `json:"error,omitempty"`
-	Description string `json:"description,omitempty"`
-	StatusCode  int    `json:"-"`
+	ErrorType   string      `json:"error,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Code        string      `json:"code,omitempty"`
+	Details     interface{} `json:"details,omitempty"`
+	StatusCode  int         `json:"-"`
 }
 
 // Error HTTPError should implement error
@@ -47,6 +51,15 @@ func (uq *UnsupportedQueryError) Error() string {
 
 // WriteError sends a JSON containing the error to the response writer
 func WriteError(err error, writer http.ResponseWriter) {
+	writeError(err, writer, "")
+}
+
+// WriteLocalizedError sends a JSON containing the error with its description localized for the request
+func WriteLocalizedError(err error, writer http.ResponseWriter, request *http.Request) {
+	writeError(err, writer, request.Header.Get("Accept-Language"))
+}
+
+func writeError(err error, writer http.ResponseWriter, acceptLanguage string) {
 	var respError *HTTPError
 	logger := log.D()
 	switch t := err.(type) {
@@ -55,6 +68,7 @@ func WriteError(err error, writer http.ResponseWriter) {
 		respError = &HTTPError{
 			ErrorType:   "BadRequest",
 			Description: err.Error(),
+			Code:        "SM-1002",
 			StatusCode:  http.StatusBadRequest,
 		}
 	case *HTTPError:
@@ -69,6 +83,7 @@ func WriteError(err error, writer http.ResponseWriter) {
 		}
 	}
 
+	respError = respError.withCode(acceptLanguage)
 	sendErr := WriteJSON(writer, respError.StatusCode, respError)
 	if sendErr != nil {
 		logger.Errorf("Could not write error to response: %v", sendErr)
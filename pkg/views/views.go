@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package views contains the alternative serializations of the resources of the API, e.g. a compact view of the
+// service brokers without their catalogs. Clients select a view with the view query parameter or with the profile
+// parameter of the Accept header, objects are serialized as they are if no view is requested.
+package views
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// QueryParam is the query parameter with which a view is requested, e.g. /v1/service_brokers?view=compact
+	QueryParam = "view"
+
+	// ProfileParam is the parameter of the Accept header with which a view is requested,
+	// e.g. Accept: application/json; profile="compact"
+	ProfileParam = "profile"
+)
+
+// View serializes an object of a resource. The returned value is serialized as JSON in place of the object.
+type View func(object types.Object) (interface{}, error)
+
+type viewKey struct {
+	apiVersion string
+	objectType types.ObjectType
+	name       string
+}
+
+// Registry holds the views of the resources per API version. A nil registry has no views.
+type Registry struct {
+	views map[viewKey]View
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		views: make(map[viewKey]View),
+	}
+}
+
+// Register adds a view of the objects of the type in the API version, e.g. v1. A view registered with the same name
+// before is replaced. It must not be called concurrently and not after the Service Manager is run.
+func (r *Registry) Register(apiVersion string, objectType types.ObjectType, name string, view View) {
+	r.views[viewKey{apiVersion: apiVersion, objectType: objectType, name: name}] = view
+}
+
+// View returns the view with the name of the objects of the type in the API version
+func (r *Registry) View(apiVersion string, objectType types.ObjectType, name string) (View, bool) {
+	if r == nil {
+		return nil, false
+	}
+	view, found := r.views[viewKey{apiVersion: apiVersion, objectType: objectType, name: name}]
+	return view, found
+}
+
+// Names returns the names of the views of the objects of the type in the API version in alphabetical order
+func (r *Registry) Names(apiVersion string, objectType types.ObjectType) []string {
+	var names []string
+	if r == nil {
+		return names
+	}
+	for key := range r.views {
+		if key.apiVersion == apiVersion && key.objectType == objectType {
+			names = append(names, key.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequestedView returns the name of the view requested with the view query parameter or, if there is none, with the
+// profile parameter of the Accept header. It returns an empty name if no view is requested.
+func RequestedView(request *http.Request) string {
+	if name := request.URL.Query().Get(QueryParam); name != "" {
+		return name
+	}
+	for _, mediaRange := range strings.Split(request.Header.Get("Accept"), ",") {
+		if _, params, err := mime.ParseMediaType(mediaRange); err == nil && params[ProfileParam] != "" {
+			return params[ProfileParam]
+		}
+	}
+	return ""
+}
+
+type viewContextKey struct{}
+
+type requestedView struct {
+	registry *Registry
+	name     string
+}
+
+// ContextWithView returns a context in which the objects are rendered with the view with the name of the registry
+func ContextWithView(ctx context.Context, registry *Registry, name string) context.Context {
+	return context.WithValue(ctx, viewContextKey{}, &requestedView{registry: registry, name: name})
+}
+
+// viewForContext returns the view requested in the context for the objects of the type or nil if none was requested.
+// Requesting a view which does not exist is an error of the client.
+func viewForContext(ctx context.Context, apiVersion string, objectType types.ObjectType) (View, error) {
+	requested, ok := ctx.Value(viewContextKey{}).(*requestedView)
+	if !ok {
+		return nil, nil
+	}
+	view, found := requested.registry.View(apiVersion, objectType, requested.name)
+	if !found {
+		return nil, &util.HTTPError{
+			ErrorType: "BadRequest",
+			Description: fmt.Sprintf("view %s is not supported for %s, supported views are %s", requested.name, objectType,
+				strings.Join(requested.registry.Names(apiVersion, objectType), ", ")),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	return view, nil
+}
+
+// Render returns the object serialized with the view requested in the context or the object as it is if no view
+// was requested
+func Render(ctx context.Context, apiVersion string, objectType types.ObjectType, object types.Object) (interface{}, error) {
+	view, err := viewForContext(ctx, apiVersion, objectType)
+	if err != nil || view == nil {
+		return object, err
+	}
+	return view(object)
+}
+
+// RenderList returns the list with its objects serialized with the view requested in the context or the list as it
+// is if no view was requested. The objects keep the JSON key of the list, e.g. service_brokers.
+func RenderList(ctx context.Context, apiVersion string, objectType types.ObjectType, list types.ObjectList) (interface{}, error) {
+	view, err := viewForContext(ctx, apiVersion, objectType)
+	if err != nil || view == nil {
+		return list, err
+	}
+	items := make([]interface{}, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item, err := view(list.ItemAt(i))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return map[string][]interface{}{listKey(list): items}, nil
+}
+
+// listKey returns the JSON key of the objects of a list, which is the tag of the only field of the generated lists
+func listKey(list types.ObjectList) string {
+	listType := reflect.TypeOf(list)
+	if listType.Kind() == reflect.Ptr {
+		listType = listType.Elem()
+	}
+	if listType.Kind() != reflect.Struct || listType.NumField() == 0 {
+		return "items"
+	}
+	return strings.Split(listType.Field(0).Tag.Get("json"), ",")[0]
+}
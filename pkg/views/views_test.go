@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package views_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/views"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Views", func() {
+	var (
+		registry *views.Registry
+		brokers  *types.ServiceBrokers
+	)
+
+	compactBroker := func(object types.Object) (interface{}, error) {
+		broker := object.(*types.ServiceBroker)
+		return map[string]string{"id": broker.ID, "name": broker.Name}, nil
+	}
+
+	BeforeEach(func() {
+		registry = views.NewRegistry()
+		registry.Register("v1", types.ServiceBrokerType, "compact", compactBroker)
+
+		brokers = &types.ServiceBrokers{}
+		brokers.Add(&types.ServiceBroker{Base: types.Base{ID: "1"}, Name: "first", BrokerURL: "https://first.example.com"})
+		brokers.Add(&types.ServiceBroker{Base: types.Base{ID: "2"}, Name: "second", BrokerURL: "https://second.example.com"})
+	})
+
+	Describe("RequestedView", func() {
+		It("prefers the query parameter over the Accept header", func() {
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_brokers?view=compact", nil)
+			request.Header.Set("Accept", `application/json; profile="full"`)
+			Expect(views.RequestedView(request)).To(Equal("compact"))
+		})
+
+		It("reads the profile parameter of the Accept header", func() {
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_brokers", nil)
+			request.Header.Set("Accept", `text/html, application/json; profile="compact"`)
+			Expect(views.RequestedView(request)).To(Equal("compact"))
+		})
+
+		It("returns no view if none is requested", func() {
+			request := httptest.NewRequest(http.MethodGet, "/v1/service_brokers", nil)
+			request.Header.Set("Accept", "application/json")
+			Expect(views.RequestedView(request)).To(BeEmpty())
+		})
+	})
+
+	Describe("Render", func() {
+		It("returns the object as it is if no view is requested", func() {
+			broker := brokers.ItemAt(0)
+			rendered, err := views.Render(context.Background(), "v1", types.ServiceBrokerType, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rendered).To(BeIdenticalTo(broker))
+		})
+
+		It("renders the object with the requested view", func() {
+			ctx := views.ContextWithView(context.Background(), registry, "compact")
+			rendered, err := views.Render(ctx, "v1", types.ServiceBrokerType, brokers.ItemAt(0))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rendered).To(Equal(map[string]string{"id": "1", "name": "first"}))
+		})
+
+		It("rejects views which are not registered for the type and API version", func() {
+			ctx := views.ContextWithView(context.Background(), registry, "compact")
+			_, err := views.Render(ctx, "v2", types.ServiceBrokerType, brokers.ItemAt(0))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("RenderList", func() {
+		It("renders the objects of the list under the key of the list", func() {
+			ctx := views.ContextWithView(context.Background(), registry, "compact")
+			rendered, err := views.RenderList(ctx, "v1", types.ServiceBrokerType, brokers)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rendered).To(Equal(map[string][]interface{}{
+				"service_brokers": {
+					map[string]string{"id": "1", "name": "first"},
+					map[string]string{"id": "2", "name": "second"},
+				},
+			}))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package visibilitypolicy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	SelectorsCreateInterceptorName = "VisibilityPolicySelectorsCreateInterceptor"
+	SelectorsUpdateInterceptorName = "VisibilityPolicySelectorsUpdateInterceptor"
+)
+
+// SelectorsCreateInterceptorProvider provides an interceptor which rejects visibility policies with invalid selectors
+type SelectorsCreateInterceptorProvider struct {
+}
+
+func (*SelectorsCreateInterceptorProvider) Name() string {
+	return SelectorsCreateInterceptorName
+}
+
+func (*SelectorsCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &selectorsInterceptor{}
+}
+
+// SelectorsUpdateInterceptorProvider provides an interceptor which rejects visibility policies with invalid selectors
+type SelectorsUpdateInterceptorProvider struct {
+}
+
+func (*SelectorsUpdateInterceptorProvider) Name() string {
+	return SelectorsUpdateInterceptorName
+}
+
+func (*SelectorsUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &selectorsInterceptor{}
+}
+
+// selectorsInterceptor validates the plan and platform selectors of visibility policies, so that invalid
+// selectors are reported to the user instead of being skipped by the reconciler
+type selectorsInterceptor struct {
+}
+
+func (*selectorsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		if err := validateSelectors(obj.(*types.VisibilityPolicy)); err != nil {
+			return nil, err
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (*selectorsInterceptor) OnTxCreate(f storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return f
+}
+
+func (*selectorsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*selectorsInterceptor) OnTxUpdate(f storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if err := validateSelectors(newObj.(*types.VisibilityPolicy)); err != nil {
+			return nil, err
+		}
+		return f(ctx, txStorage, oldObj, newObj, labelChanges...)
+	}
+}
+
+func validateSelectors(policy *types.VisibilityPolicy) error {
+	if _, err := ParseSelector(policy.PlanSelector); err != nil {
+		return invalidSelectorError("plan_selector", err)
+	}
+	if _, err := ParseSelector(policy.PlatformSelector); err != nil {
+		return invalidSelectorError("platform_selector", err)
+	}
+	return nil
+}
+
+func invalidSelectorError(field string, err error) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("invalid %s: %s", field, err),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package visibilitypolicy contains logic for materializing the visibilities declared by visibility policies
+package visibilitypolicy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// JobName is the name under which the visibility policy reconciler is registered
+	JobName = "visibility_policies"
+
+	// PolicyLabelKey is the label with which the visibilities created for a visibility policy are marked
+	PolicyLabelKey = "visibility_policy_id"
+)
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	ReconcileInterval time.Duration `mapstructure:"reconcile_interval" description:"time between reconciliations of the visibilities declared by visibility policies"`
+}
+
+// DefaultSettings returns default values for visibility policy settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		ReconcileInterval: time.Minute,
+	}
+}
+
+// Validate validates the visibility policy settings
+func (s *Settings) Validate() error {
+	if s.ReconcileInterval <= 0 {
+		return fmt.Errorf("validate Settings: visibility policy reconcile interval must be > 0")
+	}
+	return nil
+}
+
+// ParseSelector parses the label query of a visibility policy selector. Unlike label queries in list
+// requests, all criteria of a selector have to match.
+func ParseSelector(selector string) ([]query.Criterion, error) {
+	criteria, err := query.Parse(query.LabelQuery, selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("selector %s contains no label criteria", selector)
+	}
+	for _, criterion := range criteria {
+		switch criterion.Operator {
+		case query.EqualsOperator, query.NotEqualsOperator, query.InOperator, query.NotInOperator:
+		default:
+			return nil, fmt.Errorf("operator %s is not supported in selectors", criterion.Operator)
+		}
+	}
+	return criteria, nil
+}
+
+// Job is a background job which creates the visibilities declared by the visibility policies and deletes
+// the ones it created which are no longer declared, e.g. because a plan or platform was relabeled
+type Job struct {
+	settings   *Settings
+	repository storage.Repository
+
+	mutex sync.Mutex
+}
+
+// NewJob returns a visibility policy reconciler
+func NewJob(settings *Settings, repository storage.Repository) *Job {
+	return &Job{
+		settings:   settings,
+		repository: repository,
+	}
+}
+
+// Name implements jobs.Job
+func (j *Job) Name() string {
+	return JobName
+}
+
+// Run implements jobs.Job and reconciles the visibilities with the visibility policies
+func (j *Job) Run(ctx context.Context) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	desired, err := j.desiredVisibilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	visibilities, err := j.repository.List(ctx, types.VisibilityType)
+	if err != nil {
+		return fmt.Errorf("could not list visibilities: %s", err)
+	}
+	existing := make(map[visibilityKey]bool)
+	publicPlans := make(map[string]bool)
+	for i := 0; i < visibilities.Len(); i++ {
+		visibility := visibilities.ItemAt(i).(*types.Visibility)
+		key := visibilityKey{planID: visibility.ServicePlanID, platformID: visibility.PlatformID}
+		if visibility.PlatformID == "" {
+			publicPlans[visibility.ServicePlanID] = true
+		}
+		if policyID := policyOf(visibility); policyID != "" && desired[key] != policyID {
+			log.C(ctx).Infof("Deleting visibility %s which is no longer declared by visibility policy %s", visibility.ID, policyID)
+			if _, err := j.repository.Delete(ctx, types.VisibilityType, query.ByField(query.EqualsOperator, "id", visibility.ID)); err != nil {
+				return fmt.Errorf("could not delete visibility %s: %s", visibility.ID, err)
+			}
+			continue
+		}
+		existing[key] = true
+	}
+
+	for key, policyID := range desired {
+		// visibilities cannot be created for platforms of plans which are already public
+		if existing[key] || publicPlans[key.planID] {
+			continue
+		}
+		if err := j.createVisibility(ctx, key, policyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type visibilityKey struct {
+	planID     string
+	platformID string
+}
+
+// desiredVisibilities returns the plan and platform pairs declared by the visibility policies along with the
+// policy declaring them. If several policies declare the same pair, the oldest one manages its visibility.
+func (j *Job) desiredVisibilities(ctx context.Context) (map[visibilityKey]string, error) {
+	policies, err := j.repository.List(ctx, types.VisibilityPolicyType)
+	if err != nil {
+		return nil, fmt.Errorf("could not list visibility policies: %s", err)
+	}
+	desired := make(map[visibilityKey]string)
+	if policies.Len() == 0 {
+		return desired, nil
+	}
+
+	plans, err := j.repository.List(ctx, types.ServicePlanType)
+	if err != nil {
+		return nil, fmt.Errorf("could not list service plans: %s", err)
+	}
+	platforms, err := j.repository.List(ctx, types.PlatformType)
+	if err != nil {
+		return nil, fmt.Errorf("could not list platforms: %s", err)
+	}
+
+	for i := 0; i < policies.Len(); i++ {
+		policy := policies.ItemAt(i).(*types.VisibilityPolicy)
+		planIDs, err := matching(plans, policy.PlanSelector)
+		if err != nil {
+			log.C(ctx).Errorf("Skipping visibility policy %s with invalid plan selector: %s", policy.Name, err)
+			continue
+		}
+		platformIDs, err := matching(platforms, policy.PlatformSelector)
+		if err != nil {
+			log.C(ctx).Errorf("Skipping visibility policy %s with invalid platform selector: %s", policy.Name, err)
+			continue
+		}
+		for _, planID := range planIDs {
+			for _, platformID := range platformIDs {
+				key := visibilityKey{planID: planID, platformID: platformID}
+				if _, found := desired[key]; !found {
+					desired[key] = policy.ID
+				}
+			}
+		}
+	}
+	return desired, nil
+}
+
+func (j *Job) createVisibility(ctx context.Context, key visibilityKey, policyID string) error {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for visibility: %s", err)
+	}
+	currentTime := time.Now().UTC()
+	log.C(ctx).Infof("Creating visibility of plan %s for platform %s declared by visibility policy %s", key.planID, key.platformID, policyID)
+	_, err = j.repository.Create(ctx, &types.Visibility{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Labels:    types.Labels{PolicyLabelKey: {policyID}},
+		},
+		ServicePlanID: key.planID,
+		PlatformID:    key.platformID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create visibility of plan %s for platform %s: %s", key.planID, key.platformID, err)
+	}
+	return nil
+}
+
+// matching returns the IDs of the objects whose labels match all criteria of the selector
+func matching(objects types.ObjectList, selector string) ([]string, error) {
+	criteria, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for i := 0; i < objects.Len(); i++ {
+		obj := objects.ItemAt(i)
+		if MatchesSelector(obj.GetLabels(), criteria) {
+			ids = append(ids, obj.GetID())
+		}
+	}
+	return ids, nil
+}
+
+// MatchesSelector returns true if the labels match all criteria of a parsed selector
+func MatchesSelector(labels types.Labels, criteria []query.Criterion) bool {
+	for _, criterion := range criteria {
+		if !matches(labels[criterion.LeftOp], criterion) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches mirrors the label queries in storage: a label with several values matches if any of them does,
+// and a missing label never matches
+func matches(values []string, criterion query.Criterion) bool {
+	for _, value := range values {
+		contained := false
+		for _, rightOp := range criterion.RightOp {
+			if value == rightOp {
+				contained = true
+				break
+			}
+		}
+		switch criterion.Operator {
+		case query.EqualsOperator, query.InOperator:
+			if contained {
+				return true
+			}
+		case query.NotEqualsOperator, query.NotInOperator:
+			if !contained {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func policyOf(visibility *types.Visibility) string {
+	if values := visibility.Labels[PolicyLabelKey]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package visibilitypolicy_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/visibilitypolicy"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Visibility policies", func() {
+	var (
+		ctx          context.Context
+		repository   *storagefakes.FakeStorage
+		job          *visibilitypolicy.Job
+		policies     []*types.VisibilityPolicy
+		plans        []*types.ServicePlan
+		platforms    []*types.Platform
+		visibilities []*types.Visibility
+		created      []*types.Visibility
+		deleted      []string
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		policies = []*types.VisibilityPolicy{{
+			Base:             types.Base{ID: "policy-id"},
+			Name:             "free-for-dev",
+			PlanSelector:     "tier = free",
+			PlatformSelector: "env = dev|region in [eu||us]",
+		}}
+		plans = []*types.ServicePlan{
+			{Base: types.Base{ID: "free-plan", Labels: types.Labels{"tier": {"free"}}}},
+			{Base: types.Base{ID: "paid-plan", Labels: types.Labels{"tier": {"paid"}}}},
+		}
+		platforms = []*types.Platform{
+			{Base: types.Base{ID: "dev-platform", Labels: types.Labels{"env": {"dev"}, "region": {"eu"}}}},
+			{Base: types.Base{ID: "dev-platform-apac", Labels: types.Labels{"env": {"dev"}, "region": {"apac"}}}},
+			{Base: types.Base{ID: "prod-platform", Labels: types.Labels{"env": {"prod"}, "region": {"eu"}}}},
+		}
+		visibilities = []*types.Visibility{}
+		created = []*types.Visibility{}
+		deleted = []string{}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.VisibilityPolicyType:
+				return &types.VisibilityPolicies{VisibilityPolicies: policies}, nil
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: plans}, nil
+			case types.PlatformType:
+				return &types.Platforms{Platforms: platforms}, nil
+			default:
+				return &types.Visibilities{Visibilities: visibilities}, nil
+			}
+		})
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			created = append(created, obj.(*types.Visibility))
+			return obj, nil
+		})
+		repository.DeleteCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			deleted = append(deleted, criteria[0].RightOp[0])
+			return &types.Visibilities{}, nil
+		})
+
+		job = visibilitypolicy.NewJob(visibilitypolicy.DefaultSettings(), repository)
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(visibilitypolicy.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid with a non positive reconcile interval", func() {
+			settings := visibilitypolicy.DefaultSettings()
+			settings.ReconcileInterval = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseSelector", func() {
+		It("parses label queries", func() {
+			criteria, err := visibilitypolicy.ParseSelector("env = dev|region in [eu||us]")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(criteria).To(HaveLen(2))
+		})
+
+		It("rejects numeric operators", func() {
+			_, err := visibilitypolicy.ParseSelector("generation gt 1")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects invalid queries", func() {
+			_, err := visibilitypolicy.ParseSelector("env")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Run", func() {
+		It("creates the visibilities of the plans and platforms matching all criteria", func() {
+			Expect(job.Run(ctx)).To(Succeed())
+
+			Expect(created).To(HaveLen(1))
+			Expect(created[0].ServicePlanID).To(Equal("free-plan"))
+			Expect(created[0].PlatformID).To(Equal("dev-platform"))
+			Expect(created[0].Labels).To(HaveKeyWithValue(visibilitypolicy.PolicyLabelKey, []string{"policy-id"}))
+			Expect(deleted).To(BeEmpty())
+		})
+
+		It("does not recreate existing visibilities", func() {
+			visibilities = append(visibilities, &types.Visibility{
+				Base:          types.Base{ID: "visibility-id"},
+				ServicePlanID: "free-plan",
+				PlatformID:    "dev-platform",
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(created).To(BeEmpty())
+			Expect(deleted).To(BeEmpty())
+		})
+
+		It("does not create visibilities of public plans", func() {
+			visibilities = append(visibilities, &types.Visibility{
+				Base:          types.Base{ID: "visibility-id"},
+				ServicePlanID: "free-plan",
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(created).To(BeEmpty())
+		})
+
+		It("deletes the visibilities which are no longer declared", func() {
+			visibilities = append(visibilities, &types.Visibility{
+				Base:          types.Base{ID: "stale-id", Labels: types.Labels{visibilitypolicy.PolicyLabelKey: {"policy-id"}}},
+				ServicePlanID: "paid-plan",
+				PlatformID:    "dev-platform",
+			}, &types.Visibility{
+				Base:          types.Base{ID: "manual-id"},
+				ServicePlanID: "paid-plan",
+				PlatformID:    "prod-platform",
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(deleted).To(ConsistOf("stale-id"))
+			Expect(created).To(HaveLen(1))
+		})
+
+		It("deletes the visibilities of deleted policies", func() {
+			policies = []*types.VisibilityPolicy{}
+			visibilities = append(visibilities, &types.Visibility{
+				Base:          types.Base{ID: "managed-id", Labels: types.Labels{visibilitypolicy.PolicyLabelKey: {"policy-id"}}},
+				ServicePlanID: "free-plan",
+				PlatformID:    "dev-platform",
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(deleted).To(ConsistOf("managed-id"))
+			Expect(created).To(BeEmpty())
+		})
+
+		It("skips policies with invalid selectors", func() {
+			policies = append([]*types.VisibilityPolicy{{
+				Base:             types.Base{ID: "invalid-id"},
+				PlanSelector:     "tier",
+				PlatformSelector: "env = dev",
+			}}, policies...)
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(created).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package visibilityschedule contains logic for notifying platforms about visibilities which become active or
+// expire because of their valid_from and valid_until timestamps
+package visibilityschedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+)
+
+// JobName is the name under which the visibility schedule job is registered
+const JobName = "visibility_schedule"
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	CheckInterval time.Duration `mapstructure:"check_interval" description:"time between checks for visibilities which became active or expired"`
+}
+
+// DefaultSettings returns default values for visibility schedule settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		CheckInterval: time.Minute,
+	}
+}
+
+// Validate validates the visibility schedule settings
+func (s *Settings) Validate() error {
+	if s.CheckInterval <= 0 {
+		return fmt.Errorf("validate Settings: visibility schedule check interval must be > 0")
+	}
+	return nil
+}
+
+// Job is a background job which emits a creation notification for each visibility whose valid_from passed and
+// a deletion notification for each visibility whose valid_until passed since its previous run. Visibilities created,
+// updated or deleted while inactive are not notified by the visibility notifications interceptor.
+type Job struct {
+	settings   *Settings
+	repository storage.Repository
+
+	mutex     sync.Mutex
+	lastCheck time.Time
+}
+
+// NewJob returns a visibility schedule job. Its first run covers the check interval preceding it.
+func NewJob(settings *Settings, repository storage.Repository) *Job {
+	return &Job{
+		settings:   settings,
+		repository: repository,
+	}
+}
+
+// Name implements jobs.Job
+func (j *Job) Name() string {
+	return JobName
+}
+
+// Run implements jobs.Job and notifies the platforms about the visibilities which became active or expired
+func (j *Job) Run(ctx context.Context) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// the time criteria have second precision
+	now := time.Now().UTC().Truncate(time.Second)
+	since := j.lastCheck
+	if since.IsZero() {
+		since = now.Add(-j.settings.CheckInterval)
+	}
+	if !now.After(since) {
+		return nil
+	}
+
+	if err := j.notify(ctx, "valid_from", since, now, types.CREATED); err != nil {
+		return err
+	}
+	if err := j.notify(ctx, "valid_until", since, now, types.DELETED); err != nil {
+		return err
+	}
+	j.lastCheck = now
+	return nil
+}
+
+// notify emits a notification of the provided type for each visibility whose timestamp field is in (since, now]
+func (j *Job) notify(ctx context.Context, field string, since, now time.Time, op types.OperationType) error {
+	visibilities, err := j.repository.List(ctx, types.VisibilityType,
+		query.ByField(query.GreaterThanOperator, field, util.ToRFCFormat(since)),
+		query.ByField(query.LessThanOrEqualOperator, field, util.ToRFCFormat(now)))
+	if err != nil {
+		return fmt.Errorf("could not list visibilities by %s: %s", field, err)
+	}
+
+	notificationsInterceptor := interceptors.NewVisibilityNotificationsInterceptor()
+	for i := 0; i < visibilities.Len(); i++ {
+		visibility := visibilities.ItemAt(i).(*types.Visibility)
+		if !isDue(visibility, op, since, now) {
+			continue
+		}
+
+		additionalDetails, err := notificationsInterceptor.AdditionalDetailsFunc(ctx, visibility, j.repository)
+		if err != nil {
+			return err
+		}
+		payload := &interceptors.Payload{}
+		objectPayload := &interceptors.ObjectPayload{
+			Resource:   visibility,
+			Additional: additionalDetails,
+		}
+		if op == types.CREATED {
+			payload.New = objectPayload
+		} else {
+			payload.Old = objectPayload
+		}
+
+		log.C(ctx).Infof("Emitting %s notification for visibility %s due to its %s", op, visibility.ID, field)
+		if err := interceptors.CreateNotification(ctx, j.repository, op, types.VisibilityType, visibility.PlatformID, payload); err != nil {
+			return fmt.Errorf("could not notify about visibility %s: %s", visibility.ID, err)
+		}
+	}
+	return nil
+}
+
+// isDue returns false for visibilities which were already notified by the visibility notifications interceptor
+// because they were last changed after the timestamp passed, and for visibilities which expired in the same
+// interval in which they became active, because they were never in effect
+func isDue(visibility *types.Visibility, op types.OperationType, since, now time.Time) bool {
+	if op == types.CREATED {
+		return visibility.UpdatedAt.Before(*visibility.ValidFrom) && visibility.IsActive(now)
+	}
+	if visibility.ValidFrom != nil && visibility.ValidFrom.After(since) {
+		return false
+	}
+	return visibility.UpdatedAt.Before(*visibility.ValidUntil)
+}
@@ -0,0 +1,169 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package visibilityschedule_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/visibilityschedule"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Visibility schedule", func() {
+	var (
+		ctx           context.Context
+		repository    *storagefakes.FakeStorage
+		job           *visibilityschedule.Job
+		activated     []*types.Visibility
+		expired       []*types.Visibility
+		notifications []*types.Notification
+		now           time.Time
+	)
+
+	timeAt := func(offset time.Duration) *time.Time {
+		t := now.Add(offset)
+		return &t
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		now = time.Now().UTC()
+		activated = []*types.Visibility{}
+		expired = []*types.Visibility{}
+		notifications = []*types.Notification{}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if criteria[0].LeftOp == "valid_from" {
+				return &types.Visibilities{Visibilities: activated}, nil
+			}
+			return &types.Visibilities{Visibilities: expired}, nil
+		})
+		repository.GetCalls(func(ctx context.Context, objectType types.ObjectType, id string) (types.Object, error) {
+			switch objectType {
+			case types.ServicePlanType:
+				return &types.ServicePlan{Base: types.Base{ID: id}, ServiceOfferingID: "offering-id"}, nil
+			case types.ServiceOfferingType:
+				return &types.ServiceOffering{Base: types.Base{ID: id}, BrokerID: "broker-id"}, nil
+			default:
+				return &types.ServiceBroker{Base: types.Base{ID: id}, Name: "broker"}, nil
+			}
+		})
+		repository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			notifications = append(notifications, obj.(*types.Notification))
+			return obj, nil
+		})
+
+		job = visibilityschedule.NewJob(visibilityschedule.DefaultSettings(), repository)
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(visibilityschedule.DefaultSettings().Validate()).To(Succeed())
+		})
+
+		It("are invalid with a non positive check interval", func() {
+			settings := visibilityschedule.DefaultSettings()
+			settings.CheckInterval = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Describe("Run", func() {
+		It("lists the visibilities whose timestamps passed since the previous run", func() {
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(repository.ListCallCount()).To(Equal(2))
+			_, objectType, criteria := repository.ListArgsForCall(0)
+			Expect(objectType).To(Equal(types.VisibilityType))
+			Expect(criteria).To(HaveLen(2))
+			Expect(criteria[0].Operator).To(Equal(query.GreaterThanOperator))
+			Expect(criteria[1].Operator).To(Equal(query.LessThanOrEqualOperator))
+			_, _, criteria = repository.ListArgsForCall(1)
+			Expect(criteria[0].LeftOp).To(Equal("valid_until"))
+		})
+
+		It("emits a creation notification for a visibility which became active", func() {
+			activated = append(activated, &types.Visibility{
+				Base:          types.Base{ID: "visibility-id", UpdatedAt: now.Add(-time.Hour)},
+				PlatformID:    "platform-id",
+				ServicePlanID: "plan-id",
+				ValidFrom:     timeAt(-time.Second),
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(notifications).To(HaveLen(1))
+			Expect(notifications[0].Type).To(Equal(types.CREATED))
+			Expect(notifications[0].Resource).To(Equal(types.VisibilityType))
+			Expect(notifications[0].PlatformID).To(Equal("platform-id"))
+
+			payload := make(map[string]interface{})
+			Expect(json.Unmarshal(notifications[0].Payload, &payload)).To(Succeed())
+			Expect(payload).To(HaveKey("new"))
+			Expect(payload).ToNot(HaveKey("old"))
+		})
+
+		It("emits a deletion notification for a visibility which expired", func() {
+			expired = append(expired, &types.Visibility{
+				Base:          types.Base{ID: "visibility-id", UpdatedAt: now.Add(-time.Hour)},
+				PlatformID:    "platform-id",
+				ServicePlanID: "plan-id",
+				ValidUntil:    timeAt(-time.Second),
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(notifications).To(HaveLen(1))
+			Expect(notifications[0].Type).To(Equal(types.DELETED))
+
+			payload := make(map[string]interface{})
+			Expect(json.Unmarshal(notifications[0].Payload, &payload)).To(Succeed())
+			Expect(payload).To(HaveKey("old"))
+			Expect(payload).ToNot(HaveKey("new"))
+		})
+
+		It("does not notify about a visibility which was changed after it became active", func() {
+			activated = append(activated, &types.Visibility{
+				Base:          types.Base{ID: "visibility-id", UpdatedAt: now},
+				ServicePlanID: "plan-id",
+				ValidFrom:     timeAt(-time.Second),
+			})
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(notifications).To(BeEmpty())
+		})
+
+		It("does not notify about a visibility which expired in the interval in which it became active", func() {
+			visibility := &types.Visibility{
+				Base:          types.Base{ID: "visibility-id", UpdatedAt: now.Add(-time.Hour)},
+				ServicePlanID: "plan-id",
+				ValidFrom:     timeAt(-20 * time.Second),
+				ValidUntil:    timeAt(-10 * time.Second),
+			}
+			activated = append(activated, visibility)
+			expired = append(expired, visibility)
+
+			Expect(job.Run(ctx)).To(Succeed())
+			Expect(notifications).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package web
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// BufferedBody is a request body which can be read any number of times. Bodies up to a threshold are kept in memory,
+// larger ones spill over to a temporary file which is removed when the body is closed.
+type BufferedBody struct {
+	memory []byte
+	file   *os.File
+	size   int64
+}
+
+// NewBufferedBody reads the reader to its end and buffers its content in memory up to the threshold and in a
+// temporary file beyond it
+func NewBufferedBody(reader io.Reader, threshold int64) (*BufferedBody, error) {
+	memory := &bytes.Buffer{}
+	n, err := io.CopyN(memory, reader, threshold+1)
+	if err == io.EOF {
+		return &BufferedBody{memory: memory.Bytes(), size: n}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile("", "sm-request-body-")
+	if err != nil {
+		return nil, err
+	}
+	body := &BufferedBody{file: file}
+	if body.size, err = io.Copy(file, io.MultiReader(memory, reader)); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return body, nil
+}
+
+// Size returns the number of bytes of the body
+func (b *BufferedBody) Size() int64 {
+	return b.size
+}
+
+// InMemory returns whether the body is kept in memory
+func (b *BufferedBody) InMemory() bool {
+	return b.file == nil
+}
+
+// Reader returns a reader of the body from its start. Readers are independent of each other.
+func (b *BufferedBody) Reader() io.Reader {
+	if b.InMemory() {
+		return bytes.NewReader(b.memory)
+	}
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+// Bytes returns the content of the body, which is read from the temporary file if the body spilled over to one
+func (b *BufferedBody) Bytes() ([]byte, error) {
+	if b.InMemory() {
+		return b.memory, nil
+	}
+	return ioutil.ReadAll(b.Reader())
+}
+
+// Close removes the temporary file of the body, if it has one. Closing a body more than once has no effect.
+func (b *BufferedBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	if err := os.Remove(b.file.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package web_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BufferedBody", func() {
+	const content = `{"name":"sm-platform"}`
+
+	var body *web.BufferedBody
+
+	AfterEach(func() {
+		Expect(body.Close()).To(Succeed())
+	})
+
+	readAll := func() string {
+		bytes, err := ioutil.ReadAll(body.Reader())
+		Expect(err).ToNot(HaveOccurred())
+		return string(bytes)
+	}
+
+	Context("when the body does not exceed the threshold", func() {
+		BeforeEach(func() {
+			var err error
+			body, err = web.NewBufferedBody(strings.NewReader(content), int64(len(content)))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("is kept in memory", func() {
+			Expect(body.InMemory()).To(BeTrue())
+			Expect(body.Size()).To(Equal(int64(len(content))))
+			Expect(readAll()).To(Equal(content))
+		})
+	})
+
+	Context("when the body exceeds the threshold", func() {
+		BeforeEach(func() {
+			var err error
+			body, err = web.NewBufferedBody(strings.NewReader(content), 4)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("spills over to a temporary file", func() {
+			Expect(body.InMemory()).To(BeFalse())
+			Expect(body.Size()).To(Equal(int64(len(content))))
+			bytes, err := body.Bytes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(bytes)).To(Equal(content))
+		})
+
+		It("can be read more than once", func() {
+			Expect(readAll()).To(Equal(content))
+			Expect(readAll()).To(Equal(content))
+		})
+
+		It("removes the temporary file when closed", func() {
+			request := &web.Request{}
+			request.SetBufferedBody(body)
+			Expect(request.Body).To(BeNil())
+
+			files, err := ioutil.ReadDir(os.TempDir())
+			Expect(err).ToNot(HaveOccurred())
+			count := countBodyFiles(files)
+
+			Expect(body.Close()).To(Succeed())
+			files, err = ioutil.ReadDir(os.TempDir())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(countBodyFiles(files)).To(Equal(count - 1))
+		})
+	})
+})
+
+var _ = Describe("Request body accessors", func() {
+	var request *web.Request
+	var body *web.BufferedBody
+
+	BeforeEach(func() {
+		var err error
+		body, err = web.NewBufferedBody(strings.NewReader(`{"name":"sm-platform"}`), 4)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{}
+		request.SetBufferedBody(body)
+	})
+
+	AfterEach(func() {
+		Expect(body.Close()).To(Succeed())
+	})
+
+	It("reads a spilled over body", func() {
+		bytes, err := request.BodyBytes()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(bytes)).To(Equal(`{"name":"sm-platform"}`))
+	})
+
+	It("replaces the body", func() {
+		request.SetBody([]byte(`{}`))
+		Expect(request.BodySize()).To(Equal(int64(2)))
+		bytes, err := ioutil.ReadAll(request.BodyReader())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(bytes)).To(Equal(`{}`))
+	})
+})
+
+func countBodyFiles(files []os.FileInfo) int {
+	count := 0
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), "sm-request-body-") {
+			count++
+		}
+	}
+	return count
+}
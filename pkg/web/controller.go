@@ -29,6 +29,69 @@ type Route struct {
 
 	// Handler is the function that should handle incoming requests for this endpoint
 	Handler HandlerFunc
+
+	// Doc optionally describes the route in the API documentation of the Service Manager. Routes without
+	// documentation are still listed with the details which can be derived from their endpoint.
+	Doc *RouteDoc
+
+	// Security optionally declares how the requests of the route are authenticated and authorized. Routes without
+	// it are secured by the filters whose matchers match their endpoint.
+	Security *RouteSecurity
+
+	// Middlewares run after the filters only for this route, in order
+	Middlewares []Middleware
+}
+
+// RouteSecurity declares the security requirements of a route, which the security filters enforce without
+// matchers mirroring the route
+type RouteSecurity struct {
+	// Authenticators are the names of the authentication filters which authenticate the requests of the route,
+	// e.g. BasicAuthnFilter or BearerAuthnFilter. Only these run for the route and unauthenticated requests are
+	// rejected. If empty, the authentication filters whose matchers match the endpoint run.
+	Authenticators []string
+
+	// Scopes are the scopes of which the authenticated user needs at least one, any user is accepted if empty
+	Scopes []string
+}
+
+// authenticates returns true if the security of the route declares the authentication filter
+func (s *RouteSecurity) authenticates(filterName string) bool {
+	for _, name := range s.Authenticators {
+		if name == filterName {
+			return true
+		}
+	}
+	return false
+}
+
+// DeclaresAuthentication returns true if the route declares the authentication filters of its requests
+func (r Route) DeclaresAuthentication() bool {
+	return r.Security != nil && len(r.Security.Authenticators) != 0
+}
+
+// Authenticates returns true if the route declares the authentication filter with the name
+func (r Route) Authenticates(filterName string) bool {
+	return r.DeclaresAuthentication() && r.Security.authenticates(filterName)
+}
+
+// RequiredScopes returns the scopes of which the user of the requests of the route needs at least one
+func (r Route) RequiredScopes() []string {
+	if r.Security == nil {
+		return nil
+	}
+	return r.Security.Scopes
+}
+
+// RouteDoc describes a route in the API documentation of the Service Manager
+type RouteDoc struct {
+	// Summary is a short description of what the route does
+	Summary string
+
+	// Tag groups the route with related routes. It defaults to the first path segment after the API version.
+	Tag string
+
+	// Criteria specifies whether the route supports the fieldQuery and labelQuery query parameters
+	Criteria bool
 }
 
 // Endpoint is a combination of a Path and an HTTP Method
@@ -17,8 +17,13 @@
 package web
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/Peripli/service-manager/pkg/log"
 )
@@ -32,18 +37,101 @@ type Request struct {
 	// PathParams contains the URL path parameters
 	PathParams map[string]string
 
-	// Body is the loaded request body (usually JSON)
+	// Body is the loaded request body (usually JSON). It is only set if the body is kept in memory, filters and
+	// controllers should read the body with BodyBytes or BodyReader and replace it with SetBody.
 	Body []byte
 
+	bufferedBody *BufferedBody
+
 	responseWriter http.ResponseWriter
 
 	isResponseWriterHijacked bool
 }
 
+// SetBufferedBody sets the buffered body of the request, the Body field is set if the body is kept in memory
+func (r *Request) SetBufferedBody(body *BufferedBody) {
+	r.bufferedBody = body
+	r.Body = nil
+	if body.InMemory() {
+		r.Body, _ = body.Bytes()
+	}
+}
+
+// BodyBytes returns the content of the request body. A body which spilled over to a temporary file is read from it
+// on each call, so callers which only scan the body should prefer BodyReader.
+func (r *Request) BodyBytes() ([]byte, error) {
+	if r.Body != nil || r.bufferedBody == nil {
+		return r.Body, nil
+	}
+	return r.bufferedBody.Bytes()
+}
+
+// BodyReader returns a reader of the request body from its start, the body can be read any number of times
+func (r *Request) BodyReader() io.Reader {
+	if r.Body != nil || r.bufferedBody == nil {
+		return bytes.NewReader(r.Body)
+	}
+	return r.bufferedBody.Reader()
+}
+
+// BodySize returns the number of bytes of the request body
+func (r *Request) BodySize() int64 {
+	if r.Body != nil || r.bufferedBody == nil {
+		return int64(len(r.Body))
+	}
+	return r.bufferedBody.Size()
+}
+
+// SetBody replaces the body of the request, e.g. with the body changed by a filter
+func (r *Request) SetBody(body []byte) {
+	r.Body = body
+	r.bufferedBody = nil
+}
+
 func (r *Request) SetResponseWriter(rw http.ResponseWriter) {
 	r.responseWriter = rw
 }
 
+// ClientIP returns the address of the client which sent the request. Behind a router the address is taken from the
+// last entry of the X-Forwarded-For header, which is appended by the router. Clients connecting directly can forge
+// the header, so the address must not be relied on for security decisions unless the request came from a router.
+func (r *Request) ClientIP() string {
+	if forwardedFor := r.Header["X-Forwarded-For"]; len(forwardedFor) != 0 {
+		addresses := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+		return strings.TrimSpace(addresses[len(addresses)-1])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// ExternalURL returns the URL under which clients reach the path of the Service Manager. Behind a router the scheme and
+// host are taken from the X-Forwarded-Proto and X-Forwarded-Host headers, and the path is prefixed with the path prefix
+// the Service Manager is deployed under.
+func (r *Request) ExternalURL(path string) *url.URL {
+	result := &url.URL{
+		Scheme: "http",
+		Host:   r.Host,
+		Path:   r.Metadata().PathPrefix() + path,
+	}
+	if r.TLS != nil {
+		result.Scheme = "https"
+	}
+	if proto := firstForwardedValue(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+		result.Scheme = proto
+	}
+	if host := firstForwardedValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+		result.Host = host
+	}
+	return result
+}
+
+// firstForwardedValue returns the value set by the router closest to the client
+func firstForwardedValue(header string) string {
+	return strings.TrimSpace(strings.Split(header, ",")[0])
+}
+
 func (r *Request) IsResponseWriterHijacked() bool {
 	return r.isResponseWriterHijacked
 }
@@ -139,14 +227,43 @@ type Filter interface {
 	FilterMatchers() []FilterMatcher
 }
 
+// RouteFilter is a Filter which decides for each route whether and how it runs, e.g. based on the security the route
+// declares. Its matchers are only used when it delegates to MatchesEndpoint.
+type RouteFilter interface {
+	Filter
+
+	// FilterForRoute returns the filter which runs for the route, nil if none runs
+	FilterForRoute(route Route) Filter
+}
+
 // Filters represents a slice of Filter elements
 type Filters []Filter
 
-// ChainMatching builds a pkg/web.Handler that chains up the filters that match the provided route and
-// the actual handler
+// ChainMatching builds a pkg/web.Handler that chains up the filters that match the provided route, the middlewares of
+// the route and the actual handler
 func (fs Filters) ChainMatching(route Route) Handler {
-	filters := fs.Matching(route.Endpoint)
-	return filters.Chain(route.Handler)
+	filters := make(Filters, 0, len(fs))
+	for _, filter := range fs {
+		if routeFilter, ok := filter.(RouteFilter); ok {
+			if routeSpecific := routeFilter.FilterForRoute(route); routeSpecific != nil {
+				filters = append(filters, routeSpecific)
+			}
+			continue
+		}
+		if MatchesEndpoint(filter, route.Endpoint) {
+			filters = append(filters, filter)
+		}
+	}
+	log.D().Debugf("Filters for %s %s:%v", route.Endpoint.Method, route.Endpoint.Path, filters.names())
+
+	var handler Handler = route.Handler
+	for i := len(route.Middlewares) - 1; i >= 0; i-- {
+		middleware, next := route.Middlewares[i], handler
+		handler = HandlerFunc(func(r *Request) (*Response, error) {
+			return middleware.Run(r, next)
+		})
+	}
+	return filters.Chain(handler)
 }
 
 // Chain chains the Filters around the specified Handler and returns a Handler. It also adds logic for logging before
@@ -184,32 +301,45 @@ func (fs Filters) Chain(h Handler) Handler {
 
 // Matching returns a subset of Filters that match the specified endpoint
 func (fs Filters) Matching(endpoint Endpoint) Filters {
-	matchedFilters := make([]Filter, 0)
-	matchedNames := make([]string, 0)
+	matchedFilters := make(Filters, 0)
 	for _, filter := range fs {
-		if len(filter.FilterMatchers()) == 0 {
+		if MatchesEndpoint(filter, endpoint) {
 			matchedFilters = append(matchedFilters, filter)
-			matchedNames = append(matchedNames, filter.Name())
 		}
-		for _, routeMatcher := range filter.FilterMatchers() {
-			missMatch := false
-			for _, matcher := range routeMatcher.Matchers {
-				match, err := matcher.Matches(endpoint)
-				if err != nil {
-					panic(fmt.Sprintf("error matching filter %s: %s", filter.Name(), err.Error()))
-				}
-				if !match {
-					missMatch = true
-					break
-				}
+	}
+	log.D().Debugf("Filters for %s %s:%v", endpoint.Method, endpoint.Path, matchedFilters.names())
+	return matchedFilters
+}
+
+// MatchesEndpoint returns true if the filter has no matchers or all matchers of one of its filter matchers match the
+// endpoint
+func MatchesEndpoint(filter Filter, endpoint Endpoint) bool {
+	if len(filter.FilterMatchers()) == 0 {
+		return true
+	}
+	for _, routeMatcher := range filter.FilterMatchers() {
+		missMatch := false
+		for _, matcher := range routeMatcher.Matchers {
+			match, err := matcher.Matches(endpoint)
+			if err != nil {
+				panic(fmt.Sprintf("error matching filter %s: %s", filter.Name(), err.Error()))
 			}
-			if !missMatch {
-				matchedFilters = append(matchedFilters, filter)
-				matchedNames = append(matchedNames, filter.Name())
+			if !match {
+				missMatch = true
 				break
 			}
 		}
+		if !missMatch {
+			return true
+		}
 	}
-	log.D().Debugf("Filters for %s %s:%v", endpoint.Method, endpoint.Path, matchedNames)
-	return matchedFilters
+	return false
+}
+
+func (fs Filters) names() []string {
+	names := make([]string, 0, len(fs))
+	for _, filter := range fs {
+		names = append(names, filter.Name())
+	}
+	return names
 }
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package web_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request", func() {
+	Describe("ClientIP", func() {
+		var request *web.Request
+
+		BeforeEach(func() {
+			req, err := http.NewRequest(http.MethodGet, "/v1/platforms", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.RemoteAddr = "10.0.0.1:61000"
+			request = &web.Request{Request: req}
+		})
+
+		It("is the remote address without a router", func() {
+			Expect(request.ClientIP()).To(Equal("10.0.0.1"))
+		})
+
+		It("is the address appended last by the router", func() {
+			request.Header.Add("X-Forwarded-For", "1.2.3.4")
+			request.Header.Add("X-Forwarded-For", "5.6.7.8, 192.168.0.1")
+			Expect(request.ClientIP()).To(Equal("192.168.0.1"))
+		})
+	})
+})
+
+// recordingFilter records its name in the X-Chain header of the request
+type recordingFilter struct {
+	name     string
+	matchers []web.FilterMatcher
+}
+
+func (f *recordingFilter) Name() string {
+	return f.name
+}
+
+func (f *recordingFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	req.Header.Add("X-Chain", f.name)
+	return next.Handle(req)
+}
+
+func (f *recordingFilter) FilterMatchers() []web.FilterMatcher {
+	return f.matchers
+}
+
+// securedRouteFilter runs only for the routes requiring scopes
+type securedRouteFilter struct {
+	recordingFilter
+}
+
+func (f *securedRouteFilter) FilterForRoute(route web.Route) web.Filter {
+	if len(route.RequiredScopes()) == 0 {
+		return nil
+	}
+	return &recordingFilter{name: f.name + ":" + strings.Join(route.RequiredScopes(), ",")}
+}
+
+var _ = Describe("ChainMatching", func() {
+	chain := func(filters web.Filters, route web.Route) string {
+		route.Handler = func(req *web.Request) (*web.Response, error) {
+			return &web.Response{StatusCode: http.StatusOK, Body: []byte(strings.Join(req.Header["X-Chain"], " "))}, nil
+		}
+		req, err := http.NewRequest(route.Endpoint.Method, route.Endpoint.Path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := filters.ChainMatching(route).Handle(&web.Request{Request: req})
+		Expect(err).ToNot(HaveOccurred())
+		return string(resp.Body)
+	}
+
+	filters := web.Filters{
+		&recordingFilter{name: "all"},
+		&recordingFilter{name: "platforms", matchers: []web.FilterMatcher{{Matchers: []web.Matcher{web.Path(web.PlatformsURL + "/**")}}}},
+		&securedRouteFilter{recordingFilter{name: "scopes"}},
+	}
+
+	It("chains the matching filters and the filters for the route", func() {
+		Expect(chain(filters, web.Route{Endpoint: web.Endpoint{Method: http.MethodGet, Path: web.PlatformsURL}})).To(Equal("all platforms"))
+		Expect(chain(filters, web.Route{
+			Endpoint: web.Endpoint{Method: http.MethodGet, Path: "/v1/extension"},
+			Security: &web.RouteSecurity{Scopes: []string{"ext.read"}},
+		})).To(Equal("all scopes:ext.read"))
+	})
+
+	It("runs the middlewares of the route after the filters", func() {
+		middleware := func(name string) web.Middleware {
+			return web.MiddlewareFunc(func(req *web.Request, next web.Handler) (*web.Response, error) {
+				req.Header.Add("X-Chain", name)
+				return next.Handle(req)
+			})
+		}
+		Expect(chain(filters, web.Route{
+			Endpoint:    web.Endpoint{Method: http.MethodGet, Path: "/v1/extension"},
+			Middlewares: []web.Middleware{middleware("first"), middleware("second")},
+		})).To(Equal("all first second"))
+	})
+})
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+)
+
+type metadataKey struct{}
+
+// Metadata carries the cross-cutting data of a request, e.g. its user, tenant and correlation id, through the filters,
+// controllers and storage interceptors processing it. It is immutable, the With methods return modified copies which
+// are attached to the request with SetMetadata or to a context with ContextWithMetadata.
+type Metadata struct {
+	user          *UserContext
+	authorized    bool
+	tenant        string
+	correlationID string
+	apiVersion    string
+	pathPrefix    string
+
+	credentialsRevealed bool
+}
+
+// MetadataFromContext returns the metadata of the request the context belongs to, it is empty if there is none
+func MetadataFromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
+
+// ContextWithMetadata returns a copy of the context with the metadata
+func ContextWithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// Metadata returns the metadata of the request
+func (r *Request) Metadata() Metadata {
+	return MetadataFromContext(r.Context())
+}
+
+// SetMetadata replaces the metadata of the request
+func (r *Request) SetMetadata(metadata Metadata) {
+	r.Request = r.WithContext(ContextWithMetadata(r.Context(), metadata))
+}
+
+// User returns the authenticated user
+func (m Metadata) User() (*UserContext, bool) {
+	return m.user, m.user != nil
+}
+
+// WithUser returns a copy of the metadata with the authenticated user
+func (m Metadata) WithUser(user *UserContext) Metadata {
+	m.user = user
+	return m
+}
+
+// Authorized returns whether the request has been authorized
+func (m Metadata) Authorized() bool {
+	return m.authorized
+}
+
+// WithAuthorization returns a copy of the metadata of an authorized request
+func (m Metadata) WithAuthorization() Metadata {
+	m.authorized = true
+	return m
+}
+
+// Tenant returns the tenant on whose behalf the request is made or an empty string if it is not known
+func (m Metadata) Tenant() string {
+	return m.tenant
+}
+
+// WithTenant returns a copy of the metadata with the tenant, which is set by the authentication of the request
+func (m Metadata) WithTenant(tenant string) Metadata {
+	m.tenant = tenant
+	return m
+}
+
+// CorrelationID returns the id correlating the log messages and outgoing calls of the request
+func (m Metadata) CorrelationID() string {
+	return m.correlationID
+}
+
+// WithCorrelationID returns a copy of the metadata with the correlation id
+func (m Metadata) WithCorrelationID(correlationID string) Metadata {
+	m.correlationID = correlationID
+	return m
+}
+
+// APIVersion returns the version of the API the request is made to, e.g. v1
+func (m Metadata) APIVersion() string {
+	return m.apiVersion
+}
+
+// WithAPIVersion returns a copy of the metadata with the API version
+func (m Metadata) WithAPIVersion(apiVersion string) Metadata {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// PathPrefix returns the URL path prefix the Service Manager is deployed under, which is stripped from the path of the
+// request before it is routed
+func (m Metadata) PathPrefix() string {
+	return m.pathPrefix
+}
+
+// WithPathPrefix returns a copy of the metadata with the URL path prefix
+func (m Metadata) WithPathPrefix(pathPrefix string) Metadata {
+	m.pathPrefix = pathPrefix
+	return m
+}
+
+// CredentialsRevealed returns whether the credentials of brokers and platforms are returned to the caller
+func (m Metadata) CredentialsRevealed() bool {
+	return m.credentialsRevealed
+}
+
+// WithCredentialsRevealed returns a copy of the metadata of a request whose caller may see the credentials of
+// brokers and platforms
+func (m Metadata) WithCredentialsRevealed() Metadata {
+	m.credentialsRevealed = true
+	return m
+}
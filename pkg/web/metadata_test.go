@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package web_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metadata", func() {
+	var request *web.Request
+
+	BeforeEach(func() {
+		req, err := http.NewRequest(http.MethodGet, "/v1/service_brokers", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{Request: req}
+	})
+
+	Context("when none is set", func() {
+		It("is empty", func() {
+			metadata := web.MetadataFromContext(context.Background())
+			Expect(metadata).To(Equal(web.Metadata{}))
+			_, found := metadata.User()
+			Expect(found).To(BeFalse())
+			Expect(metadata.Authorized()).To(BeFalse())
+		})
+	})
+
+	It("is not modified by the With methods", func() {
+		metadata := web.Metadata{}.WithTenant("tenant")
+		modified := metadata.WithTenant("other").WithCorrelationID("correlation-id")
+
+		Expect(metadata.Tenant()).To(Equal("tenant"))
+		Expect(metadata.CorrelationID()).To(BeEmpty())
+		Expect(modified.Tenant()).To(Equal("other"))
+		Expect(modified.CorrelationID()).To(Equal("correlation-id"))
+	})
+
+	It("is carried by the request context", func() {
+		request.SetMetadata(request.Metadata().WithTenant("tenant").WithCorrelationID("correlation-id").WithAPIVersion("v1"))
+
+		metadata := web.MetadataFromContext(request.Context())
+		Expect(metadata.Tenant()).To(Equal("tenant"))
+		Expect(metadata.CorrelationID()).To(Equal("correlation-id"))
+		Expect(metadata.APIVersion()).To(Equal("v1"))
+	})
+
+	It("keeps the user and authorization set through the context helpers", func() {
+		user := &web.UserContext{Name: "user"}
+		ctx := web.ContextWithUser(request.Context(), user)
+		ctx = web.ContextWithAuthorization(ctx)
+		request.Request = request.WithContext(ctx)
+		request.SetMetadata(request.Metadata().WithTenant("tenant"))
+
+		actualUser, found := web.UserFromContext(request.Context())
+		Expect(found).To(BeTrue())
+		Expect(actualUser).To(Equal(user))
+		Expect(web.IsAuthorized(request.Context())).To(BeTrue())
+		Expect(request.Metadata().Tenant()).To(Equal("tenant"))
+	})
+})
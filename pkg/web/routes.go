@@ -18,15 +18,60 @@ const (
 	// NotificationsURL is the URL path to manage notifications
 	NotificationsURL = "/" + apiVersion + "/notifications"
 
+	// NotificationsTokenURL is the URL path to exchange platform credentials for a notifications connection token
+	NotificationsTokenURL = NotificationsURL + "/token"
+
 	// PlatformsURL is the URL path to manage platforms
 	PlatformsURL = "/" + apiVersion + "/platforms"
 
+	// PeersURL is the URL path to manage the Service Manager instances whose brokers are imported
+	PeersURL = "/" + apiVersion + "/peers"
+
+	// OperationsURL is the URL path to fetch operations
+	OperationsURL = "/" + apiVersion + "/operations"
+
 	// OSBURL is the OSB API base URL path
 	OSBURL = "/" + apiVersion + "/osb"
 
 	// MonitorHealthURL is the path of the healthcheck endpoint
 	MonitorHealthURL = "/" + apiVersion + "/monitor/health"
 
+	// MonitorStorageURL is the path of the storage statistics endpoint
+	MonitorStorageURL = "/" + apiVersion + "/monitor/storage"
+
 	// InfoURL is the path of the info endpoint
 	InfoURL = "/" + apiVersion + "/info"
+
+	// ChangesURL is the path of the resource change feed
+	ChangesURL = "/" + apiVersion + "/changes"
+
+	// GraphQLURL is the path of the GraphQL endpoint
+	GraphQLURL = "/" + apiVersion + "/graphql"
+
+	// VisibilityPoliciesURL is the URL path to manage the policies from which visibilities are created
+	VisibilityPoliciesURL = "/" + apiVersion + "/visibility_policies"
+
+	// CompositeBrokersURL is the URL path to manage the brokers composed of the plans of several registered brokers
+	CompositeBrokersURL = "/" + apiVersion + "/composite_brokers"
+
+	// SavedQueriesURL is the URL path to manage the named criteria which list calls reference
+	SavedQueriesURL = "/" + apiVersion + "/queries"
+
+	// TenantKeysURL is the URL path to manage the encryption keys supplied by tenants
+	TenantKeysURL = "/" + apiVersion + "/tenant_keys"
+
+	// ErrorCodesURL is the path of the catalog of the codes of the error responses
+	ErrorCodesURL = "/" + apiVersion + "/errors"
+
+	// APIDocsURL is the path of the OpenAPI document of the API
+	APIDocsURL = "/" + apiVersion + "/api-docs"
+
+	// AdminURL is the base URL path of the operational endpoints
+	AdminURL = "/" + apiVersion + "/admin"
+
+	// FeaturesURL is the path of the feature flags endpoint
+	FeaturesURL = AdminURL + "/features"
+
+	// JobsURL is the path of the background jobs endpoint
+	JobsURL = AdminURL + "/jobs"
 )
@@ -1,5 +1,10 @@
 package web
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // UserContext holds the information for the current user
 type UserContext struct {
 	Data
@@ -7,6 +12,32 @@ type UserContext struct {
 	Name string
 }
 
+// Scopes returns the scopes of the user, which are the scope claim of its data as a list or a space separated string
+func (u *UserContext) Scopes() map[string]bool {
+	scopes := make(map[string]bool)
+	if u == nil || u.Data == nil {
+		return scopes
+	}
+	claims := struct {
+		Scope json.RawMessage `json:"scope"`
+	}{}
+	if err := u.Data.Data(&claims); err != nil || len(claims.Scope) == 0 {
+		return scopes
+	}
+	var list []string
+	if err := json.Unmarshal(claims.Scope, &list); err != nil {
+		var spaceSeparated string
+		if err := json.Unmarshal(claims.Scope, &spaceSeparated); err != nil {
+			return scopes
+		}
+		list = strings.Fields(spaceSeparated)
+	}
+	for _, scope := range list {
+		scopes[scope] = true
+	}
+	return scopes
+}
+
 //go:generate counterfeiter . Data
 type Data interface {
 	// Data reads the additional data from the context into the specified struct
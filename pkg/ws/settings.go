@@ -8,6 +8,18 @@ import (
 type Settings struct {
 	PingTimeout  time.Duration `mapstructure:"ping_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// DrainRate is the number of connections closed per second when the server shuts down
+	DrainRate int `mapstructure:"drain_rate"`
+	// DrainTimeout is the time after which the connections left open when the server shuts down are closed at once
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// ReconnectBackoff is the maximum delay hinted to the clients of closed connections before reconnecting
+	ReconnectBackoff time.Duration `mapstructure:"reconnect_backoff"`
+
+	// TokenSigningKey signs the connection tokens with which platforms open connections, tokens are not issued if it is empty
+	TokenSigningKey string `mapstructure:"token_signing_key"`
+	// TokenTTL is the time for which connection tokens are valid
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
 }
 
 // DefaultSettings return the default values for ws server
@@ -15,6 +27,12 @@ func DefaultSettings() *Settings {
 	return &Settings{
 		PingTimeout:  time.Second * 5,
 		WriteTimeout: time.Second * 5,
+
+		DrainRate:        100,
+		DrainTimeout:     time.Second * 10,
+		ReconnectBackoff: time.Second * 5,
+
+		TokenTTL: time.Minute * 15,
 	}
 }
 
@@ -28,5 +46,21 @@ func (s *Settings) Validate() error {
 		return fmt.Errorf("validate ws settings: WriteTimeout should be > 0")
 	}
 
+	if s.DrainRate <= 0 {
+		return fmt.Errorf("validate ws settings: DrainRate should be > 0")
+	}
+
+	if s.DrainTimeout < 0 {
+		return fmt.Errorf("validate ws settings: DrainTimeout should be >= 0")
+	}
+
+	if s.ReconnectBackoff < 0 {
+		return fmt.Errorf("validate ws settings: ReconnectBackoff should be >= 0")
+	}
+
+	if s.TokenTTL <= 0 {
+		return fmt.Errorf("validate ws settings: TokenTTL should be > 0")
+	}
+
 	return nil
 }
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package ws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ConnectionToken is a short-lived token with which a platform opens a notifications connection instead of
+// sending its credentials
+type ConnectionToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// URL is the URL of the notifications endpoint to connect to with the token
+	URL string `json:"url,omitempty"`
+}
+
+// ConnectionClaims are the details signed in a connection token
+type ConnectionClaims struct {
+	PlatformID string `json:"platform_id"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// Expiry returns the time after which the token is no longer accepted
+func (c *ConnectionClaims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0).UTC()
+}
+
+// ConnectionTokens issues and verifies connection tokens. The tokens are signed with HMAC-SHA256, so all
+// instances of the Service Manager have to be configured with the same signing key.
+type ConnectionTokens struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewConnectionTokens returns the connection tokens for the settings or nil if no signing key is configured
+func NewConnectionTokens(settings *Settings) *ConnectionTokens {
+	if settings == nil || settings.TokenSigningKey == "" {
+		return nil
+	}
+	return &ConnectionTokens{
+		key: []byte(settings.TokenSigningKey),
+		ttl: settings.TokenTTL,
+	}
+}
+
+// Issue returns a token for the platform which expires after the configured TTL
+func (t *ConnectionTokens) Issue(platformID string) (*ConnectionToken, error) {
+	expiresAt := time.Now().Add(t.ttl).UTC().Truncate(time.Second)
+	claims, err := json.Marshal(&ConnectionClaims{
+		PlatformID: platformID,
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return &ConnectionToken{
+		Token:     payload + "." + t.sign(payload),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Verify returns the claims of the token if its signature is valid and it has not expired
+func (t *ConnectionTokens) Verify(token string) (*ConnectionClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("malformed connection token")
+	}
+	if !hmac.Equal([]byte(t.sign(parts[0])), []byte(parts[1])) {
+		return nil, errors.New("invalid connection token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed connection token")
+	}
+	claims := &ConnectionClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, errors.New("malformed connection token")
+	}
+	if !time.Now().Before(claims.Expiry()) {
+		return nil, errors.New("connection token expired")
+	}
+	if claims.PlatformID == "" {
+		return nil, errors.New("connection token has no platform")
+	}
+	return claims, nil
+}
+
+func (t *ConnectionTokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package ws_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/ws"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection tokens", func() {
+	var (
+		settings *ws.Settings
+		tokens   *ws.ConnectionTokens
+	)
+
+	BeforeEach(func() {
+		settings = ws.DefaultSettings()
+		settings.TokenSigningKey = "signing-key"
+		tokens = ws.NewConnectionTokens(settings)
+	})
+
+	It("are not issued without a signing key", func() {
+		Expect(ws.NewConnectionTokens(ws.DefaultSettings())).To(BeNil())
+	})
+
+	It("are verified with the platform and expiry they were issued with", func() {
+		token, err := tokens.Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.ExpiresAt).To(BeTemporally("~", time.Now().Add(settings.TokenTTL), time.Second))
+
+		claims, err := tokens.Verify(token.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.PlatformID).To(Equal("platform-id"))
+		Expect(claims.Expiry()).To(Equal(token.ExpiresAt))
+	})
+
+	It("are rejected if they are signed with another key", func() {
+		otherSettings := ws.DefaultSettings()
+		otherSettings.TokenSigningKey = "other-key"
+		token, err := ws.NewConnectionTokens(otherSettings).Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = tokens.Verify(token.Token)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("are rejected if they are modified", func() {
+		token, err := tokens.Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+		other, err := tokens.Issue("other-platform-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		signature := token.Token[strings.Index(token.Token, "."):]
+		payload := other.Token[:strings.Index(other.Token, ".")]
+		_, err = tokens.Verify(payload + signature)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("are rejected after they expire", func() {
+		settings.TokenTTL = -time.Second
+		token, err := ws.NewConnectionTokens(settings).Issue("platform-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = tokens.Verify(token.Token)
+		Expect(err).To(MatchError("connection token expired"))
+	})
+
+	It("are rejected if they are malformed", func() {
+		_, err := tokens.Verify("token")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotModified is returned by catalog fetchers when a conditional fetch was requested and the broker reports that
+// its catalog has not changed since it was last fetched
+var ErrNotModified = errors.New("broker catalog not modified")
+
+type conditionalFetchKey struct{}
+
+// ContextWithConditionalFetch returns a context which instructs catalog fetchers to send the validators of the stored
+// catalog of the broker (ETag and Last-Modified) and to return ErrNotModified if the broker responds with 304 Not Modified.
+// It should only be used by callers which do not need the catalog when it has not changed.
+func ContextWithConditionalFetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conditionalFetchKey{}, true)
+}
+
+// IsConditionalFetch returns whether a conditional catalog fetch is requested in the context
+func IsConditionalFetch(ctx context.Context) bool {
+	conditional, ok := ctx.Value(conditionalFetchKey{}).(bool)
+	return ok && conditional
+}
+
+type fetchedCatalogKey struct{}
+
+// ContextWithFetchedCatalog returns a context which instructs the broker interceptors to store the provided catalog,
+// which the caller has just fetched from the broker, instead of fetching it again
+func ContextWithFetchedCatalog(ctx context.Context, catalogBytes []byte) context.Context {
+	return context.WithValue(ctx, fetchedCatalogKey{}, catalogBytes)
+}
+
+// FetchedCatalog returns the fetched catalog in the context and whether there is one
+func FetchedCatalog(ctx context.Context) ([]byte, bool) {
+	catalogBytes, ok := ctx.Value(fetchedCatalogKey{}).([]byte)
+	return catalogBytes, ok
+}
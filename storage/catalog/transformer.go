@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// Service is a service offering as it appears in a broker catalog
+type Service map[string]interface{}
+
+// Plan is a service plan as it appears in a broker catalog
+type Plan map[string]interface{}
+
+// Catalog is a broker catalog as returned by the broker. Fields unknown to the Service Manager are preserved.
+type Catalog struct {
+	Services []Service `json:"services"`
+
+	// Extra contains the top-level fields of the catalog other than the services as they were returned by the broker
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the services of the catalog and keeps its other top-level fields in Extra
+func (c *Catalog) UnmarshalJSON(data []byte) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	c.Services = nil
+	if services, found := fields["services"]; found {
+		decoder := json.NewDecoder(bytes.NewReader(services))
+		decoder.UseNumber()
+		if err := decoder.Decode(&c.Services); err != nil {
+			return err
+		}
+		delete(fields, "services")
+	}
+	c.Extra = fields
+	return nil
+}
+
+// MarshalJSON encodes the services of the catalog together with its other top-level fields
+func (c Catalog) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(c.Extra)+1)
+	for name, value := range c.Extra {
+		fields[name] = value
+	}
+	fields["services"] = c.Services
+	return json.Marshal(fields)
+}
+
+// Plans returns the plans of the service
+func (s Service) Plans() []Plan {
+	rawPlans, ok := s["plans"].([]interface{})
+	if !ok {
+		return nil
+	}
+	plans := make([]Plan, 0, len(rawPlans))
+	for _, rawPlan := range rawPlans {
+		if plan, ok := rawPlan.(map[string]interface{}); ok {
+			plans = append(plans, plan)
+		}
+	}
+	return plans
+}
+
+// SetPlans replaces the plans of the service
+func (s Service) SetPlans(plans []Plan) {
+	rawPlans := make([]interface{}, 0, len(plans))
+	for _, plan := range plans {
+		rawPlans = append(rawPlans, map[string]interface{}(plan))
+	}
+	s["plans"] = rawPlans
+}
+
+// Transformer modifies broker catalogs after they are fetched from the broker and before they are persisted and served
+type Transformer interface {
+	// Name returns the name of the transformer
+	Name() string
+
+	// Transform modifies the catalog of the specified broker in place
+	Transform(ctx context.Context, broker *types.ServiceBroker, catalog *Catalog) error
+}
+
+// Pipeline applies the registered transformers in order of registration
+type Pipeline struct {
+	transformers []Transformer
+}
+
+// Register adds transformers to the end of the pipeline. Transformers should be registered before the pipeline is used.
+func (p *Pipeline) Register(transformers ...Transformer) {
+	p.transformers = append(p.transformers, transformers...)
+}
+
+// Transform applies the pipeline to the catalog bytes of the broker. If no transformers are registered the bytes are
+// returned as they are.
+func (p *Pipeline) Transform(ctx context.Context, broker *types.ServiceBroker, catalogBytes []byte) ([]byte, error) {
+	if len(p.transformers) == 0 {
+		return catalogBytes, nil
+	}
+
+	catalog := &Catalog{}
+	if err := json.Unmarshal(catalogBytes, catalog); err != nil {
+		return nil, fmt.Errorf("could not decode catalog of broker %s: %s", broker.Name, err)
+	}
+	for _, transformer := range p.transformers {
+		log.C(ctx).Debugf("Applying catalog transformer %s to catalog of broker %s", transformer.Name(), broker.Name)
+		if err := transformer.Transform(ctx, broker, catalog); err != nil {
+			return nil, fmt.Errorf("catalog transformer %s failed for broker %s: %s", transformer.Name(), broker.Name, err)
+		}
+	}
+	return json.Marshal(catalog)
+}
+
+// Fetcher decorates the provided fetcher so that fetched catalogs pass through the pipeline
+func (p *Pipeline) Fetcher(fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+	return func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+		catalogBytes, err := fetcher(ctx, broker)
+		if err != nil {
+			return nil, err
+		}
+		return p.Transform(ctx, broker, catalogBytes)
+	}
+}
+
+// ServiceNamePrefixer returns a transformer which prefixes the names of all services with the provided prefix
+func ServiceNamePrefixer(prefix string) Transformer {
+	return &serviceNamePrefixer{prefix: prefix}
+}
+
+type serviceNamePrefixer struct {
+	prefix string
+}
+
+func (t *serviceNamePrefixer) Name() string {
+	return "ServiceNamePrefixer"
+}
+
+func (t *serviceNamePrefixer) Transform(ctx context.Context, broker *types.ServiceBroker, catalog *Catalog) error {
+	for _, service := range catalog.Services {
+		if name, ok := service["name"].(string); ok {
+			service["name"] = t.prefix + name
+		}
+	}
+	return nil
+}
+
+// PlanFilter returns a transformer which drops the plans for which the provided function returns true
+func PlanFilter(drop func(service Service, plan Plan) bool) Transformer {
+	return &planFilter{drop: drop}
+}
+
+type planFilter struct {
+	drop func(service Service, plan Plan) bool
+}
+
+func (t *planFilter) Name() string {
+	return "PlanFilter"
+}
+
+func (t *planFilter) Transform(ctx context.Context, broker *types.ServiceBroker, catalog *Catalog) error {
+	for _, service := range catalog.Services {
+		plans := service.Plans()
+		kept := make([]Plan, 0, len(plans))
+		for _, plan := range plans {
+			if !t.drop(service, plan) {
+				kept = append(kept, plan)
+			}
+		}
+		service.SetPlans(kept)
+	}
+	return nil
+}
+
+// MetadataInjector returns a transformer which adds the provided entries to the metadata of all services.
+// Existing metadata entries with the same keys are overridden.
+func MetadataInjector(metadata map[string]interface{}) Transformer {
+	return &metadataInjector{metadata: metadata}
+}
+
+type metadataInjector struct {
+	metadata map[string]interface{}
+}
+
+func (t *metadataInjector) Name() string {
+	return "MetadataInjector"
+}
+
+func (t *metadataInjector) Transform(ctx context.Context, broker *types.ServiceBroker, catalog *Catalog) error {
+	for _, service := range catalog.Services {
+		serviceMetadata, ok := service["metadata"].(map[string]interface{})
+		if !ok {
+			serviceMetadata = make(map[string]interface{})
+		}
+		for key, value := range t.metadata {
+			serviceMetadata[key] = value
+		}
+		service["metadata"] = serviceMetadata
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type failingTransformer struct{}
+
+func (*failingTransformer) Name() string {
+	return "failing"
+}
+
+func (*failingTransformer) Transform(ctx context.Context, broker *types.ServiceBroker, catalog *catalog.Catalog) error {
+	return fmt.Errorf("transformation error")
+}
+
+var _ = Describe("Catalog Pipeline", func() {
+	const catalogJSON = `{
+		"extension": {"version": 2, "ratio": 0.50000000000000001},
+		"services": [{
+			"id": "service-id",
+			"name": "service",
+			"dashboard_client": {"id": "client"},
+			"metadata": {"displayName": "Service"},
+			"plans": [
+				{"id": "free-id", "name": "free", "free": true},
+				{"id": "paid-id", "name": "paid", "free": false, "maximum_polling_duration": 60}
+			]
+		}]
+	}`
+
+	var (
+		ctx      context.Context
+		broker   *types.ServiceBroker
+		pipeline *catalog.Pipeline
+		fetcher  func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		broker = &types.ServiceBroker{Name: "broker"}
+		pipeline = &catalog.Pipeline{}
+		fetcher = func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+			return []byte(catalogJSON), nil
+		}
+	})
+
+	Context("when no transformers are registered", func() {
+		It("returns the fetched catalog unchanged", func() {
+			result, err := pipeline.Fetcher(fetcher)(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(result)).To(Equal(catalogJSON))
+		})
+	})
+
+	Context("when the fetcher fails", func() {
+		It("returns the error", func() {
+			pipeline.Register(catalog.ServiceNamePrefixer("prefix-"))
+			_, err := pipeline.Fetcher(func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+				return nil, fmt.Errorf("fetch error")
+			})(ctx, broker)
+			Expect(err).To(MatchError("fetch error"))
+		})
+	})
+
+	Context("when transformers are registered", func() {
+		BeforeEach(func() {
+			pipeline.Register(
+				catalog.ServiceNamePrefixer("dev-"),
+				catalog.PlanFilter(func(service catalog.Service, plan catalog.Plan) bool {
+					return plan["free"] == false
+				}),
+				catalog.MetadataInjector(map[string]interface{}{"landscape": "dev"}),
+			)
+		})
+
+		It("applies them in order and preserves unknown fields", func() {
+			result, err := pipeline.Fetcher(fetcher)(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(result)).To(MatchJSON(`{
+				"extension": {"version": 2, "ratio": 0.50000000000000001},
+				"services": [{
+					"id": "service-id",
+					"name": "dev-service",
+					"dashboard_client": {"id": "client"},
+					"metadata": {"displayName": "Service", "landscape": "dev"},
+					"plans": [
+						{"id": "free-id", "name": "free", "free": true}
+					]
+				}]
+			}`))
+		})
+	})
+
+	Context("when the catalog passes through a transformer without changes", func() {
+		It("preserves all fields and numbers", func() {
+			pipeline.Register(catalog.MetadataInjector(map[string]interface{}{}))
+			catalogBytes := []byte(`{
+				"services": [{"id": "service-id", "plans": [{"id": "plan-id", "maximum_polling_duration": 12345678901234567890}]}],
+				"extension": {"version": 2},
+				"list": [1, "two"],
+				"empty": null
+			}`)
+
+			result, err := pipeline.Transform(ctx, broker, catalogBytes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(result)).To(MatchJSON(`{
+				"services": [{"id": "service-id", "metadata": {}, "plans": [{"id": "plan-id", "maximum_polling_duration": 12345678901234567890}]}],
+				"extension": {"version": 2},
+				"list": [1, "two"],
+				"empty": null
+			}`))
+			Expect(string(result)).To(ContainSubstring("12345678901234567890"))
+		})
+	})
+
+	Context("when a transformer fails", func() {
+		It("returns an error", func() {
+			pipeline.Register(&failingTransformer{})
+			_, err := pipeline.Fetcher(fetcher)(ctx, broker)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("transformation error"))
+		})
+	})
+
+	Context("when the catalog is invalid JSON", func() {
+		It("returns an error", func() {
+			pipeline.Register(catalog.ServiceNamePrefixer("prefix-"))
+			_, err := pipeline.Transform(ctx, broker, []byte("{"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
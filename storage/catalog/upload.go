@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+const (
+	// SourceLabel is the label of brokers which specifies where their catalog comes from
+	SourceLabel = "catalog_source"
+
+	// SourceUpload is the value of the source label of brokers whose catalog is uploaded by an operator instead of
+	// fetched from the broker, e.g. because the Service Manager cannot reach the broker directly
+	SourceUpload = "upload"
+)
+
+var emptyCatalog = []byte(`{"services":[]}`)
+
+type uploadedCatalogKey struct{}
+
+// ContextWithUploadedCatalog returns a context which instructs catalog fetchers decorated with UploadFetcher to return
+// the provided catalog instead of fetching it
+func ContextWithUploadedCatalog(ctx context.Context, catalogBytes []byte) context.Context {
+	return context.WithValue(ctx, uploadedCatalogKey{}, catalogBytes)
+}
+
+// UploadedCatalog returns the uploaded catalog in the context and whether there is one
+func UploadedCatalog(ctx context.Context) ([]byte, bool) {
+	catalogBytes, ok := ctx.Value(uploadedCatalogKey{}).([]byte)
+	return catalogBytes, ok
+}
+
+// IsUploaded returns whether the catalog of the broker is uploaded instead of fetched
+func IsUploaded(broker *types.ServiceBroker) bool {
+	for _, source := range broker.Labels[SourceLabel] {
+		if source == SourceUpload {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadFetcher decorates the provided fetcher so that the catalogs of brokers whose catalog is uploaded are never
+// fetched. The catalog uploaded in the context is returned for them, an empty catalog until their first upload and
+// ErrNotModified afterwards, so that their stored catalog is kept.
+func UploadFetcher(fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+	return func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+		if catalogBytes, ok := UploadedCatalog(ctx); ok {
+			// validators of a previously fetched catalog do not apply to the uploaded one
+			broker.CatalogETag = ""
+			broker.CatalogLastModified = ""
+			return catalogBytes, nil
+		}
+		if !IsUploaded(broker) {
+			return fetcher(ctx, broker)
+		}
+		if len(broker.Catalog) == 0 {
+			return emptyCatalog, nil
+		}
+		return nil, ErrNotModified
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package catalog_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage/catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog UploadFetcher", func() {
+	const fetchedCatalog = `{"services":[{"id":"fetched"}]}`
+	const uploadedCatalog = `{"services":[{"id":"uploaded"}]}`
+
+	var (
+		broker  *types.ServiceBroker
+		fetches int
+		fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	)
+
+	BeforeEach(func() {
+		broker = &types.ServiceBroker{
+			Base: types.Base{Labels: types.Labels{}},
+			Name: "broker",
+		}
+		fetches = 0
+		fetcher = catalog.UploadFetcher(func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+			fetches++
+			return []byte(fetchedCatalog), nil
+		})
+	})
+
+	Context("when the catalog of the broker is fetched", func() {
+		It("fetches the catalog", func() {
+			catalogBytes, err := fetcher(context.Background(), broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(catalogBytes)).To(Equal(fetchedCatalog))
+			Expect(fetches).To(Equal(1))
+		})
+	})
+
+	Context("when the catalog of the broker is uploaded", func() {
+		BeforeEach(func() {
+			broker.Labels[catalog.SourceLabel] = []string{catalog.SourceUpload}
+			broker.CatalogETag = "etag"
+		})
+
+		It("returns an empty catalog before the first upload", func() {
+			catalogBytes, err := fetcher(context.Background(), broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(catalogBytes).To(MatchJSON(`{"services":[]}`))
+			Expect(fetches).To(Equal(0))
+		})
+
+		It("keeps the stored catalog", func() {
+			broker.Catalog = []byte(uploadedCatalog)
+			_, err := fetcher(context.Background(), broker)
+			Expect(err).To(Equal(catalog.ErrNotModified))
+			Expect(fetches).To(Equal(0))
+		})
+
+		It("returns the uploaded catalog and clears the validators", func() {
+			broker.Catalog = []byte(fetchedCatalog)
+			catalogBytes, err := fetcher(catalog.ContextWithUploadedCatalog(context.Background(), []byte(uploadedCatalog)), broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(catalogBytes)).To(Equal(uploadedCatalog))
+			Expect(broker.CatalogETag).To(BeEmpty())
+			Expect(fetches).To(Equal(0))
+		})
+	})
+})
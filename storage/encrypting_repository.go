@@ -29,8 +29,9 @@ type KeyStore interface {
 	SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error
 }
 
-// EncryptingDecorator creates a TransactionalRepositoryDecorator that can be used to add encrypting/decrypting logic to a TransactionalRepository
-func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyStore KeyStore) TransactionalRepositoryDecorator {
+// EncryptingDecorator creates a TransactionalRepositoryDecorator that can be used to add encrypting/decrypting logic to a TransactionalRepository.
+// The credentials of objects of tenants which supplied their own key are encrypted with the key of the tenant if tenantKeys is provided.
+func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyStore KeyStore, tenantKeys *TenantKeys) TransactionalRepositoryDecorator {
 	return func(next TransactionalRepository) (TransactionalRepository, error) {
 		ctx, cancelFunc := context.WithTimeout(ctx, 2*time.Second)
 		defer cancelFunc()
@@ -64,7 +65,12 @@ func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyS
 			logger.Info("Successfully generated new encryption key")
 		}
 
-		return NewEncryptingRepository(next, encrypter, encryptionKey)
+		encryptingRepository, err := NewEncryptingRepository(next, encrypter, encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		encryptingRepository.tenantKeys = tenantKeys
+		return encryptingRepository, nil
 	}
 }
 
@@ -87,6 +93,7 @@ type encryptingRepository struct {
 	encrypter  security.Encrypter
 
 	encryptionKey []byte
+	tenantKeys    *TenantKeys
 }
 
 //TransactionalEncryptingRepository is a TransactionalRepository with that also encrypts credentials of Secured objects
@@ -98,7 +105,7 @@ type TransactionalEncryptingRepository struct {
 }
 
 func (er *encryptingRepository) Create(ctx context.Context, obj types.Object) (types.Object, error) {
-	if err := er.transformCredentials(ctx, obj, er.encrypter.Encrypt); err != nil {
+	if err := er.encryptCredentials(ctx, obj, obj.GetLabels()); err != nil {
 		return nil, err
 	}
 
@@ -107,7 +114,7 @@ func (er *encryptingRepository) Create(ctx context.Context, obj types.Object) (t
 		return nil, err
 	}
 
-	if err := er.transformCredentials(ctx, newObj, er.encrypter.Decrypt); err != nil {
+	if err := er.decryptCredentials(ctx, newObj); err != nil {
 		return nil, err
 	}
 
@@ -120,7 +127,7 @@ func (er *encryptingRepository) Get(ctx context.Context, objectType types.Object
 		return nil, err
 	}
 
-	if err := er.transformCredentials(ctx, obj, er.encrypter.Decrypt); err != nil {
+	if err := er.decryptCredentials(ctx, obj); err != nil {
 		return nil, err
 	}
 
@@ -134,7 +141,7 @@ func (er *encryptingRepository) List(ctx context.Context, objectType types.Objec
 	}
 
 	for i := 0; i < objList.Len(); i++ {
-		if err := er.transformCredentials(ctx, objList.ItemAt(i), er.encrypter.Decrypt); err != nil {
+		if err := er.decryptCredentials(ctx, objList.ItemAt(i)); err != nil {
 			return nil, err
 		}
 	}
@@ -143,7 +150,9 @@ func (er *encryptingRepository) List(ctx context.Context, objectType types.Objec
 }
 
 func (er *encryptingRepository) Update(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
-	if err := er.transformCredentials(ctx, obj, er.encrypter.Encrypt); err != nil {
+	// the tenant label may be changed by the update
+	labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, obj.GetLabels())
+	if err := er.encryptCredentials(ctx, obj, labels); err != nil {
 		return nil, err
 	}
 
@@ -152,7 +161,7 @@ func (er *encryptingRepository) Update(ctx context.Context, obj types.Object, la
 		return nil, err
 	}
 
-	if err := er.transformCredentials(ctx, updatedObj, er.encrypter.Decrypt); err != nil {
+	if err := er.decryptCredentials(ctx, updatedObj); err != nil {
 		return nil, err
 	}
 
@@ -166,7 +175,7 @@ func (er *encryptingRepository) Delete(ctx context.Context, objectType types.Obj
 	}
 
 	for i := 0; i < objList.Len(); i++ {
-		if err := er.transformCredentials(ctx, objList.ItemAt(i), er.encrypter.Decrypt); err != nil {
+		if err := er.decryptCredentials(ctx, objList.ItemAt(i)); err != nil {
 			return nil, err
 		}
 	}
@@ -174,23 +183,78 @@ func (er *encryptingRepository) Delete(ctx context.Context, objectType types.Obj
 	return objList, nil
 }
 
-func (er *encryptingRepository) transformCredentials(ctx context.Context, obj types.Object, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
-	securedObj, isSecured := obj.(types.Secured)
-	if isSecured {
-		credentials := securedObj.GetCredentials()
-		if credentials != nil {
-			transformedPassword, err := transformationFunc(ctx, []byte(credentials.Basic.Password), er.encryptionKey)
-			if err != nil {
-				return err
-			}
-			credentials.Basic.Password = string(transformedPassword)
-			securedObj.SetCredentials(credentials)
+// encryptCredentials encrypts the credentials with the key of the tenant from the provided labels if the tenant
+// supplied a key and with the key of the Service Manager otherwise
+func (er *encryptingRepository) encryptCredentials(ctx context.Context, obj types.Object, labels types.Labels) error {
+	credentials := credentialsOf(obj)
+	if credentials == nil {
+		return nil
+	}
+
+	key := er.encryptionKey
+	tenant := er.tenantKeys.TenantOf(labels)
+	if tenant != "" {
+		tenantKey, err := er.tenantKeys.Key(ctx, er.repository, tenant)
+		if err != nil {
+			return err
+		}
+		if tenantKey == nil {
+			tenant = ""
+		} else {
+			key = tenantKey
+		}
+	}
+
+	encryptedPassword, err := er.encrypter.Encrypt(ctx, []byte(credentials.Basic.Password), key)
+	if err != nil {
+		return err
+	}
+	if tenant != "" {
+		encryptedPassword = tenantCiphertext(tenant, encryptedPassword)
+	}
+	credentials.Basic.Password = string(encryptedPassword)
+	obj.(types.Secured).SetCredentials(credentials)
+	return nil
+}
+
+// decryptCredentials decrypts the credentials with the key with which they were encrypted
+func (er *encryptingRepository) decryptCredentials(ctx context.Context, obj types.Object) error {
+	credentials := credentialsOf(obj)
+	if credentials == nil {
+		return nil
+	}
+
+	key := er.encryptionKey
+	ciphertext := []byte(credentials.Basic.Password)
+	if tenant, encrypted := parseTenantCiphertext(ciphertext); tenant != "" && er.tenantKeys != nil {
+		tenantKey, err := er.tenantKeys.Key(ctx, er.repository, tenant)
+		if err != nil {
+			return err
 		}
+		if tenantKey == nil {
+			return fmt.Errorf("credentials of %s with id %s are encrypted with the key of tenant %s which does not exist", obj.GetType(), obj.GetID(), tenant)
+		}
+		key = tenantKey
+		ciphertext = encrypted
 	}
 
+	decryptedPassword, err := er.encrypter.Decrypt(ctx, ciphertext, key)
+	if err != nil {
+		return err
+	}
+	credentials.Basic.Password = string(decryptedPassword)
+	obj.(types.Secured).SetCredentials(credentials)
 	return nil
 }
 
+func credentialsOf(obj types.Object) *types.Credentials {
+	securedObj, isSecured := obj.(types.Secured)
+	if !isSecured {
+		return nil
+	}
+	return securedObj.GetCredentials()
+}
+
 // InTransaction wraps repository passed in the transaction to also encypt/decrypt credentials
 func (er *TransactionalEncryptingRepository) InTransaction(ctx context.Context, f func(ctx context.Context, storage Repository) error) error {
 	return er.repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
@@ -198,6 +262,7 @@ func (er *TransactionalEncryptingRepository) InTransaction(ctx context.Context,
 			repository:    storage,
 			encrypter:     er.encrypter,
 			encryptionKey: er.encryptionKey,
+			tenantKeys:    er.tenantKeys,
 		})
 	})
 }
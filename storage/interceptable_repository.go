@@ -430,6 +430,59 @@ func (itr *InterceptableTransactionalRepository) Update(ctx context.Context, obj
 	return obj, nil
 }
 
+// UpdateAll implements BulkUpdater. The objects are updated with their OnTx interceptors in a single transaction,
+// around which the AroundTx interceptors of the objects are nested, so that each object is prepared before and
+// completed after the transaction like by Update.
+func (itr *InterceptableTransactionalRepository) UpdateAll(ctx context.Context, objects []types.Object, labelChanges ...*query.LabelChange) ([]types.Object, error) {
+	providedCreateInterceptors, providedUpdateInterceptors, providedDeleteInterceptors := itr.provideInterceptors()
+
+	prepared := make([]types.Object, len(objects))
+	results := make([]types.Object, len(objects))
+	updateInTx := func(ctx context.Context) error {
+		return itr.smStorageRepository.InTransaction(ctx, func(ctx context.Context, txStorage Repository) error {
+			interceptableRepository := newInterceptableRepository(txStorage, providedCreateInterceptors, providedUpdateInterceptors, providedDeleteInterceptors)
+			for i, obj := range prepared {
+				result, err := interceptableRepository.Update(ctx, obj, labelChanges...)
+				if err != nil {
+					return err
+				}
+				results[i] = result
+			}
+			return nil
+		})
+	}
+
+	// aroundTx runs the AroundTx interceptors of the object at the index around the ones of the following objects
+	var aroundTx func(ctx context.Context, index int) error
+	aroundTx = func(ctx context.Context, index int) error {
+		if index == len(objects) {
+			return updateInTx(ctx)
+		}
+		h := func(ctx context.Context, obj types.Object, _ ...*query.LabelChange) (types.Object, error) {
+			prepared[index] = obj
+			if err := aroundTx(ctx, index+1); err != nil {
+				return nil, err
+			}
+			return results[index], nil
+		}
+		obj := objects[index]
+		if interceptor := providedUpdateInterceptors[obj.GetType()]; interceptor != nil {
+			h = interceptor.AroundTxUpdate(h)
+		}
+		result, err := h(ctx, obj, labelChanges...)
+		if err != nil {
+			return err
+		}
+		results[index] = result
+		return nil
+	}
+
+	if err := aroundTx(ctx, 0); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func (itr *InterceptableTransactionalRepository) validateCreateProviders(objectType types.ObjectType, providerName string, order InterceptorOrder) {
 	var existingProviderNames []string
 	for _, existingProvider := range itr.createProviders[objectType] {
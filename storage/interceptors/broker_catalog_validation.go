@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// validateCatalogUniqueness verifies that the services and plans in the catalog of the broker with the specified name
+// can be identified unambiguously. Service IDs and names must be unique across the catalog, plan IDs must be unique
+// across the catalog and plan names must be unique within their service.
+func validateCatalogUniqueness(brokerName string, services []*types.ServiceOffering) error {
+	serviceIDs := make(map[string]int)
+	serviceNames := make(map[string]int)
+	planIDs := make(map[string]string)
+
+	for serviceIndex, service := range services {
+		if service.ID != "" {
+			if existingIndex, found := serviceIDs[service.ID]; found {
+				return catalogConflictError(brokerName, "services at positions %d and %d have the same id %s", existingIndex, serviceIndex, service.ID)
+			}
+			serviceIDs[service.ID] = serviceIndex
+		}
+		if service.Name != "" {
+			if existingIndex, found := serviceNames[service.Name]; found {
+				return catalogConflictError(brokerName, "services at positions %d and %d have the same name %s", existingIndex, serviceIndex, service.Name)
+			}
+			serviceNames[service.Name] = serviceIndex
+		}
+
+		planNames := make(map[string]bool)
+		for _, plan := range service.Plans {
+			if plan.ID != "" {
+				if existingService, found := planIDs[plan.ID]; found {
+					return catalogConflictError(brokerName, "plan id %s is used by more than one plan (in services %s and %s)", plan.ID, existingService, service.Name)
+				}
+				planIDs[plan.ID] = service.Name
+			}
+			if plan.Name != "" {
+				if planNames[plan.Name] {
+					return catalogConflictError(brokerName, "service %s has more than one plan with name %s", service.Name, plan.Name)
+				}
+				planNames[plan.Name] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+func catalogConflictError(brokerName, format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("catalog of broker with name %s is invalid: %s", brokerName, fmt.Sprintf(format, args...)),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
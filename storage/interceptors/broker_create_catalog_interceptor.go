@@ -21,14 +21,29 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/catalog"
 	"github.com/gofrs/uuid"
 )
 
 const BrokerCreateCatalogInterceptorName = "BrokerCreateCatalogInterceptor"
 
+type deferredCatalogFetchKey struct{}
+
+// ContextWithDeferredCatalogFetch returns a context which instructs the broker create interceptor to persist the broker
+// without fetching its catalog. The catalog is expected to be fetched later on by updating the broker.
+func ContextWithDeferredCatalogFetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deferredCatalogFetchKey{}, true)
+}
+
+func isCatalogFetchDeferred(ctx context.Context) bool {
+	deferred, ok := ctx.Value(deferredCatalogFetchKey{}).(bool)
+	return ok && deferred
+}
+
 type BrokerCreateCatalogInterceptorProvider struct {
 	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
 }
@@ -51,6 +66,10 @@ type brokerCreateCatalogInterceptor struct {
 func (c *brokerCreateCatalogInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
 	return func(ctx context.Context, obj types.Object) (types.Object, error) {
 		broker := obj.(*types.ServiceBroker)
+		if isCatalogFetchDeferred(ctx) {
+			log.C(ctx).Debugf("Catalog fetch for broker with name %s is deferred", broker.Name)
+			return h(ctx, broker)
+		}
 		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher); err != nil {
 			return nil, err
 		}
@@ -84,32 +103,58 @@ func (c *brokerCreateCatalogInterceptor) OnTxCreate(f storage.InterceptCreateOnT
 }
 
 func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)) error {
-	catalogBytes, err := fetcher(ctx, broker)
+	catalogBytes, fetched := catalog.FetchedCatalog(ctx)
+	if !fetched {
+		var err error
+		catalogBytes, err = fetcher(ctx, broker)
+		if err == catalog.ErrNotModified && len(broker.Catalog) != 0 {
+			// the stored catalog is still current, e.g. the catalog of the broker is uploaded
+			catalogBytes, err = broker.Catalog, nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	broker.Catalog = catalogBytes
+
+	services, err := ParseCatalog(broker, catalogBytes)
 	if err != nil {
 		return err
 	}
-	broker.Catalog = catalogBytes
+	broker.Services = services
+
+	return nil
+}
 
+// ParseCatalog parses and validates the catalog of the broker and returns its service offerings and plans
+// with newly generated IDs, ready to be persisted for the broker
+func ParseCatalog(broker *types.ServiceBroker, catalogBytes []byte) ([]*types.ServiceOffering, error) {
 	catalogResponse := struct {
 		Services []*types.ServiceOffering `json:"services"`
 	}{}
 	if err := util.BytesToObject(catalogBytes, &catalogResponse); err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := validateCatalogUniqueness(broker.Name, catalogResponse.Services); err != nil {
+		return nil, err
 	}
 
 	for _, service := range catalogResponse.Services {
 		service.CatalogID = service.ID
 		service.CatalogName = service.Name
+		// the lifecycle is managed by the Service Manager and not by the broker
+		service.Lifecycle = types.LifecycleActive
 		service.BrokerID = broker.ID
 		service.CreatedAt = broker.UpdatedAt
 		service.UpdatedAt = broker.UpdatedAt
 		UUID, err := uuid.NewV4()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		service.ID = UUID.String()
 		if err := service.Validate(); err != nil {
-			return &util.HTTPError{
+			return nil, &util.HTTPError{
 				ErrorType:   "BadRequest",
 				Description: fmt.Sprintf("service offering constructed during catalog insertion for broker with name %s is invalid: %s", broker.Name, err),
 				StatusCode:  http.StatusBadRequest,
@@ -118,16 +163,21 @@ func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fet
 		for _, servicePlan := range service.Plans {
 			servicePlan.CatalogID = servicePlan.ID
 			servicePlan.CatalogName = servicePlan.Name
+			servicePlan.Lifecycle = types.LifecycleActive
 			servicePlan.ServiceOfferingID = service.ID
 			servicePlan.CreatedAt = broker.UpdatedAt
 			servicePlan.UpdatedAt = broker.UpdatedAt
 			UUID, err := uuid.NewV4()
 			if err != nil {
-				return err
+				return nil, err
 			}
 			servicePlan.ID = UUID.String()
-			if err := servicePlan.Validate(); err != nil {
-				return &util.HTTPError{
+			err = servicePlan.SetPricingFromMetadata()
+			if err == nil {
+				err = servicePlan.Validate()
+			}
+			if err != nil {
+				return nil, &util.HTTPError{
 					ErrorType:   "BadRequest",
 					Description: fmt.Sprintf("service plan constructed during catalog insertion for broker with name %s is invalid: %s", broker.Name, err),
 					StatusCode:  http.StatusBadRequest,
@@ -135,7 +185,5 @@ func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fet
 			}
 		}
 	}
-	broker.Services = catalogResponse.Services
-
-	return nil
+	return catalogResponse.Services, nil
 }
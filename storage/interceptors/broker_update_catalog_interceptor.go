@@ -32,6 +32,19 @@ import (
 
 const BrokerUpdateCatalogInterceptorName = "BrokerUpdateCatalogInterceptor"
 
+type labelsOnlyUpdateKey struct{}
+
+// ContextWithLabelsOnlyUpdate returns a context which instructs the broker update interceptor to keep the stored
+// catalog of the broker instead of fetching it again, as only the labels of the broker are changed
+func ContextWithLabelsOnlyUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, labelsOnlyUpdateKey{}, true)
+}
+
+func isLabelsOnlyUpdate(ctx context.Context) bool {
+	labelsOnly, ok := ctx.Value(labelsOnlyUpdateKey{}).(bool)
+	return ok && labelsOnly
+}
+
 // BrokerUpdateCatalogInterceptorProvider provides a broker interceptor for update operations
 type BrokerUpdateCatalogInterceptorProvider struct {
 	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
@@ -58,6 +71,10 @@ type brokerUpdateCatalogInterceptor struct {
 func (c *brokerUpdateCatalogInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
 	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
 		broker := obj.(*types.ServiceBroker)
+		if isLabelsOnlyUpdate(ctx) {
+			log.C(ctx).Debugf("Keeping the catalog of broker with name %s as only its labels are updated", broker.Name)
+			return h(ctx, broker, labelChanges...)
+		}
 		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher); err != nil {
 			return nil, err
 		}
@@ -69,6 +86,9 @@ func (c *brokerUpdateCatalogInterceptor) AroundTxUpdate(h storage.InterceptUpdat
 // OnTxUpdate stores the previously fetched broker catalog, in the transaction in which the broker is being updated
 func (c *brokerUpdateCatalogInterceptor) OnTxUpdate(f storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
 	return func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if isLabelsOnlyUpdate(ctx) {
+			return f(ctx, txStorage, oldObj, newObj, labelChanges...)
+		}
 		oldBroker := oldObj.(*types.ServiceBroker)
 
 		existingServiceOfferingsWithServicePlans, err := c.CatalogLoader(ctx, oldBroker.GetID(), txStorage)
@@ -103,6 +123,7 @@ func (c *brokerUpdateCatalogInterceptor) OnTxUpdate(f storage.InterceptUpdateOnT
 				catalogService.ID = existingServiceOffering.ID
 				catalogService.CreatedAt = existingServiceOffering.CreatedAt
 				catalogService.UpdatedAt = existingServiceOffering.UpdatedAt
+				catalogService.Lifecycle = existingServiceOffering.Lifecycle
 
 				if err := catalogService.Validate(); err != nil {
 					return nil, &util.HTTPError{
@@ -165,6 +186,7 @@ func (c *brokerUpdateCatalogInterceptor) OnTxUpdate(f storage.InterceptUpdateOnT
 							existingPlanUpdated.ID = existingServicePlan.ID
 							existingPlanUpdated.CreatedAt = existingServicePlan.CreatedAt
 							existingPlanUpdated.UpdatedAt = existingServicePlan.UpdatedAt
+							existingPlanUpdated.Lifecycle = existingServicePlan.Lifecycle
 						} else {
 							newPlansMapping = append(newPlansMapping, existingServicePlan)
 						}
@@ -0,0 +1,246 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	HistoryCreateInterceptorName = "HistoryCreateInterceptorProvider"
+	HistoryUpdateInterceptorName = "HistoryUpdateInterceptorProvider"
+	HistoryDeleteInterceptorName = "HistoryDeleteInterceptorProvider"
+)
+
+// historyIgnoredFields are the fields of a resource which do not make a difference between two of its revisions
+var historyIgnoredFields = []string{"credentials", "updated_at"}
+
+// HistoryCreateInterceptorProvider provides an interceptor which persists a revision of each created resource
+type HistoryCreateInterceptorProvider struct {
+}
+
+func (*HistoryCreateInterceptorProvider) Name() string {
+	return HistoryCreateInterceptorName
+}
+
+func (*HistoryCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &historyInterceptor{}
+}
+
+// HistoryUpdateInterceptorProvider provides an interceptor which persists a revision of each updated resource
+type HistoryUpdateInterceptorProvider struct {
+}
+
+func (*HistoryUpdateInterceptorProvider) Name() string {
+	return HistoryUpdateInterceptorName
+}
+
+func (*HistoryUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &historyInterceptor{}
+}
+
+// HistoryDeleteInterceptorProvider provides an interceptor which persists the last revision of each deleted resource
+type HistoryDeleteInterceptorProvider struct {
+}
+
+func (*HistoryDeleteInterceptorProvider) Name() string {
+	return HistoryDeleteInterceptorName
+}
+
+func (*HistoryDeleteInterceptorProvider) Provide() storage.DeleteInterceptor {
+	return &historyInterceptor{}
+}
+
+// historyInterceptor persists revisions of resources in the transaction which changes them,
+// so that a revision exists for each committed change
+type historyInterceptor struct {
+}
+
+func (*historyInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return h
+}
+
+func (*historyInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*historyInterceptor) AroundTxDelete(h storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
+	return h
+}
+
+func (*historyInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		newObj, err := h(ctx, repository, obj)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := RevisionPayload(newObj)
+		if err != nil {
+			return nil, err
+		}
+		return newObj, createRevision(ctx, repository, types.CREATE, newObj, payload)
+	}
+}
+
+func (*historyInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		oldPayload, err := RevisionPayload(oldObject)
+		if err != nil {
+			return nil, err
+		}
+		updatedObject, err := h(ctx, repository, oldObject, newObject, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		newPayload, err := RevisionPayload(updatedObject)
+		if err != nil {
+			return nil, err
+		}
+
+		// updates which only touch ignored fields, e.g. catalog resyncs of brokers, do not produce a revision
+		changes, err := RevisionChanges(oldPayload, newPayload)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			return updatedObject, nil
+		}
+		return updatedObject, createRevision(ctx, repository, types.UPDATE, updatedObject, newPayload)
+	}
+}
+
+func (*historyInterceptor) OnTxDelete(h storage.InterceptDeleteOnTxFunc) storage.InterceptDeleteOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		deletedObjects, err := h(ctx, repository, objects, deletionCriteria...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < deletedObjects.Len(); i++ {
+			oldObject := deletedObjects.ItemAt(i)
+			payload, err := RevisionPayload(oldObject)
+			if err != nil {
+				return nil, err
+			}
+			if err := createRevision(ctx, repository, types.DELETE, oldObject, payload); err != nil {
+				return nil, err
+			}
+		}
+		return deletedObjects, nil
+	}
+}
+
+// RevisionChange is the change of a field between two revisions of a resource. Labels are compared per key,
+// e.g. the field of the label with key "env" is "labels.env".
+type RevisionChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// RevisionPayload returns the JSON representation of the object which is stored in its revision.
+// The credentials of the object are not part of the payload.
+func RevisionPayload(obj types.Object) (json.RawMessage, error) {
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(bytes, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "credentials")
+	return json.Marshal(fields)
+}
+
+// RevisionChanges returns the changes of the fields between the payloads of two revisions in the order of the
+// field names. An empty old payload results in the changes of all fields of the new payload.
+func RevisionChanges(oldPayload, newPayload json.RawMessage) ([]*RevisionChange, error) {
+	oldFields, err := revisionFields(oldPayload)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := revisionFields(newPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, found := oldFields[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]*RevisionChange, 0)
+	for _, name := range names {
+		oldValue, newValue := oldFields[name], newFields[name]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, &RevisionChange{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes, nil
+}
+
+func revisionFields(payload json.RawMessage) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if len(payload) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("could not parse revision payload: %s", err)
+	}
+	for _, ignored := range historyIgnoredFields {
+		delete(fields, ignored)
+	}
+	if labels, ok := fields["labels"].(map[string]interface{}); ok {
+		delete(fields, "labels")
+		for key, values := range labels {
+			fields["labels."+key] = values
+		}
+	}
+	return fields, nil
+}
+
+func createRevision(ctx context.Context, repository storage.Repository, operation types.OperationCategory, obj types.Object, payload json.RawMessage) error {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for revision: %s", err)
+	}
+	user := ""
+	if userContext, found := web.UserFromContext(ctx); found {
+		user = userContext.Name
+	}
+
+	currentTime := time.Now().UTC()
+	revision := &types.Revision{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+		},
+		ResourceID:   obj.GetID(),
+		ResourceType: obj.GetType(),
+		Operation:    operation,
+		User:         user,
+		Payload:      payload,
+	}
+
+	log.C(ctx).Debugf("Persisting %s revision of %s with id %s", operation, obj.GetType(), obj.GetID())
+	_, err = repository.Create(ctx, revision)
+	return err
+}
@@ -0,0 +1,108 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const LifecycleNotificationsInterceptorName = "LifecycleNotificationsInterceptorProvider"
+
+// LifecycleNotificationsInterceptorProvider provides an interceptor which notifies all platforms when a service
+// offering or plan is deprecated, retired or reactivated, so that they can warn their users
+type LifecycleNotificationsInterceptorProvider struct {
+}
+
+func (*LifecycleNotificationsInterceptorProvider) Name() string {
+	return LifecycleNotificationsInterceptorName
+}
+
+func (*LifecycleNotificationsInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &lifecycleNotificationsInterceptor{}
+}
+
+type lifecycleNotificationsInterceptor struct {
+}
+
+func (*lifecycleNotificationsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*lifecycleNotificationsInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		updatedObject, err := h(ctx, repository, oldObject, newObject, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		if lifecycleOf(oldObject) == lifecycleOf(updatedObject) {
+			return updatedObject, nil
+		}
+
+		broker, err := brokerOf(ctx, updatedObject, repository)
+		if err != nil {
+			return nil, err
+		}
+		additionalDetails := &LifecycleAdditional{
+			BrokerID:   broker.ID,
+			BrokerName: broker.Name,
+		}
+		return updatedObject, CreateNotification(ctx, repository, types.MODIFIED, updatedObject.GetType(), "", &Payload{
+			New: &ObjectPayload{
+				Resource:   updatedObject,
+				Additional: additionalDetails,
+			},
+			Old: &ObjectPayload{
+				Resource:   oldObject,
+				Additional: additionalDetails,
+			},
+		})
+	}
+}
+
+// LifecycleAdditional contains the broker of the service offering or plan whose lifecycle changed
+type LifecycleAdditional struct {
+	BrokerID   string `json:"broker_id"`
+	BrokerName string `json:"broker_name"`
+}
+
+func (la LifecycleAdditional) Validate() error {
+	if la.BrokerID == "" {
+		return fmt.Errorf("broker id cannot be empty")
+	}
+	if la.BrokerName == "" {
+		return fmt.Errorf("broker name cannot be empty")
+	}
+	return nil
+}
+
+func lifecycleOf(obj types.Object) types.LifecycleState {
+	switch object := obj.(type) {
+	case *types.ServiceOffering:
+		return object.Lifecycle
+	case *types.ServicePlan:
+		return object.Lifecycle
+	default:
+		return types.LifecycleActive
+	}
+}
+
+// brokerOf returns the broker of a service offering or plan
+func brokerOf(ctx context.Context, obj types.Object, repository storage.Repository) (*types.ServiceBroker, error) {
+	offering, isOffering := obj.(*types.ServiceOffering)
+	if !isOffering {
+		object, err := repository.Get(ctx, types.ServiceOfferingType, obj.(*types.ServicePlan).ServiceOfferingID)
+		if err != nil {
+			return nil, err
+		}
+		offering = object.(*types.ServiceOffering)
+	}
+
+	broker, err := repository.Get(ctx, types.ServiceBrokerType, offering.BrokerID)
+	if err != nil {
+		return nil, err
+	}
+	return broker.(*types.ServiceBroker), nil
+}
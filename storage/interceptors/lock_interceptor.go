@@ -0,0 +1,128 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	LockUpdateInterceptorName = "LockUpdateInterceptorProvider"
+	LockDeleteInterceptorName = "LockDeleteInterceptorProvider"
+)
+
+// LockUpdateInterceptorProvider provides an interceptor which rejects updates of resources locked by another user
+type LockUpdateInterceptorProvider struct {
+}
+
+func (*LockUpdateInterceptorProvider) Name() string {
+	return LockUpdateInterceptorName
+}
+
+func (*LockUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &lockInterceptor{}
+}
+
+// LockDeleteInterceptorProvider provides an interceptor which rejects deletions of resources locked by another user
+// and removes the locks of the deleted resources
+type LockDeleteInterceptorProvider struct {
+}
+
+func (*LockDeleteInterceptorProvider) Name() string {
+	return LockDeleteInterceptorName
+}
+
+func (*LockDeleteInterceptorProvider) Provide() storage.DeleteInterceptor {
+	return &lockInterceptor{}
+}
+
+// lockInterceptor checks the locks of resources in the transaction which changes them. Changes without a user,
+// e.g. the ones of background jobs, are not blocked.
+type lockInterceptor struct {
+}
+
+func (*lockInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*lockInterceptor) AroundTxDelete(h storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
+	return h
+}
+
+func (*lockInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if err := checkLock(ctx, repository, oldObject); err != nil {
+			return nil, err
+		}
+		return h(ctx, repository, oldObject, newObject, labelChanges...)
+	}
+}
+
+func (*lockInterceptor) OnTxDelete(h storage.InterceptDeleteOnTxFunc) storage.InterceptDeleteOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		for i := 0; i < objects.Len(); i++ {
+			if err := checkLock(ctx, repository, objects.ItemAt(i)); err != nil {
+				return nil, err
+			}
+		}
+
+		deletedObjects, err := h(ctx, repository, objects, deletionCriteria...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < deletedObjects.Len(); i++ {
+			obj := deletedObjects.ItemAt(i)
+			if _, err := repository.Delete(ctx, types.LockType,
+				query.ByField(query.EqualsOperator, "resource_id", obj.GetID()),
+				query.ByField(query.EqualsOperator, "resource_type", string(obj.GetType()))); err != nil && err != util.ErrNotFoundInStorage {
+				return nil, err
+			}
+		}
+		return deletedObjects, nil
+	}
+}
+
+// FindLock returns the lock of the resource or nil if it has none. The returned lock may have expired.
+func FindLock(ctx context.Context, repository storage.Repository, objectType types.ObjectType, objectID string) (*types.Lock, error) {
+	locks, err := repository.List(ctx, types.LockType,
+		query.ByField(query.EqualsOperator, "resource_id", objectID),
+		query.ByField(query.EqualsOperator, "resource_type", string(objectType)))
+	if err != nil {
+		return nil, err
+	}
+	if locks.Len() == 0 {
+		return nil, nil
+	}
+	return locks.ItemAt(0).(*types.Lock), nil
+}
+
+func checkLock(ctx context.Context, repository storage.Repository, obj types.Object) error {
+	userContext, found := web.UserFromContext(ctx)
+	if !found {
+		return nil
+	}
+	lock, err := FindLock(ctx, repository, obj.GetType(), obj.GetID())
+	if err != nil {
+		return err
+	}
+	if lock == nil || !lock.IsActive(time.Now().UTC()) || lock.Owner == userContext.Name {
+		return nil
+	}
+
+	log.C(ctx).Infof("Rejecting change of %s with id %s by user %s because it is locked by %s", obj.GetType(), obj.GetID(), userContext.Name, lock.Owner)
+	return &util.HTTPError{
+		ErrorType: "Locked",
+		Description: fmt.Sprintf("%s with id %s is locked by %s until %s: %s",
+			obj.GetType(), obj.GetID(), lock.Owner, util.ToRFCFormat(lock.ExpiresAt), lock.Reason),
+		StatusCode: http.StatusLocked,
+	}
+}
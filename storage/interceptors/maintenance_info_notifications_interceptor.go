@@ -0,0 +1,80 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const MaintenanceInfoNotificationsInterceptorName = "MaintenanceInfoNotificationsInterceptorProvider"
+
+// MaintenanceInfoNotificationsInterceptorProvider provides an interceptor which notifies all platforms when the
+// maintenance info version of a service plan changes, so that they can schedule the upgrade of its instances
+type MaintenanceInfoNotificationsInterceptorProvider struct {
+}
+
+func (*MaintenanceInfoNotificationsInterceptorProvider) Name() string {
+	return MaintenanceInfoNotificationsInterceptorName
+}
+
+func (*MaintenanceInfoNotificationsInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &maintenanceInfoNotificationsInterceptor{}
+}
+
+type maintenanceInfoNotificationsInterceptor struct {
+}
+
+func (*maintenanceInfoNotificationsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return h
+}
+
+func (*maintenanceInfoNotificationsInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		updatedObject, err := h(ctx, repository, oldObject, newObject, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		oldPlan, updatedPlan := oldObject.(*types.ServicePlan), updatedObject.(*types.ServicePlan)
+		if oldPlan.MaintenanceInfo.MaintenanceVersion() == updatedPlan.MaintenanceInfo.MaintenanceVersion() {
+			return updatedObject, nil
+		}
+
+		broker, err := brokerOf(ctx, updatedPlan, repository)
+		if err != nil {
+			return nil, err
+		}
+		additionalDetails := &MaintenanceInfoAdditional{
+			BrokerID:   broker.ID,
+			BrokerName: broker.Name,
+		}
+		return updatedObject, CreateNotification(ctx, repository, types.MODIFIED, updatedObject.GetType(), "", &Payload{
+			New: &ObjectPayload{
+				Resource:   updatedObject,
+				Additional: additionalDetails,
+			},
+			Old: &ObjectPayload{
+				Resource:   oldObject,
+				Additional: additionalDetails,
+			},
+		})
+	}
+}
+
+// MaintenanceInfoAdditional contains the broker of the service plan whose maintenance info changed
+type MaintenanceInfoAdditional struct {
+	BrokerID   string `json:"broker_id"`
+	BrokerName string `json:"broker_name"`
+}
+
+func (ma MaintenanceInfoAdditional) Validate() error {
+	if ma.BrokerID == "" {
+		return fmt.Errorf("broker id cannot be empty")
+	}
+	if ma.BrokerName == "" {
+		return fmt.Errorf("broker name cannot be empty")
+	}
+	return nil
+}
@@ -31,6 +31,13 @@ type ObjectPayload struct {
 type NotificationsInterceptor struct {
 	PlatformIdProviderFunc func(ctx context.Context, object types.Object) string
 	AdditionalDetailsFunc  func(ctx context.Context, object types.Object, repository storage.Repository) (util.InputValidator, error)
+	// IsActiveFunc optionally returns whether the object is in effect. Platforms are not notified about inactive
+	// objects, an object becoming active or inactive is notified as its creation or deletion.
+	IsActiveFunc func(object types.Object) bool
+}
+
+func (ni *NotificationsInterceptor) isActive(object types.Object) bool {
+	return ni.IsActiveFunc == nil || ni.IsActiveFunc(object)
 }
 
 func (ni *NotificationsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
@@ -51,6 +58,9 @@ func (ni *NotificationsInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc
 		if err != nil {
 			return nil, err
 		}
+		if !ni.isActive(newObj) {
+			return newObj, nil
+		}
 
 		additionalDetails, err := ni.AdditionalDetailsFunc(ctx, obj, repository)
 		if err != nil {
@@ -83,6 +93,32 @@ func (ni *NotificationsInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc
 		oldPlatformID := ni.PlatformIdProviderFunc(ctx, oldObject)
 		newPlatformID := ni.PlatformIdProviderFunc(ctx, newObject)
 
+		wasActive, isActive := ni.isActive(oldObject), ni.isActive(updatedObject)
+		switch {
+		case !wasActive && !isActive:
+			return updatedObject, nil
+		case !wasActive:
+			if err := CreateNotification(ctx, repository, types.CREATED, updatedObject.GetType(), newPlatformID, &Payload{
+				New: &ObjectPayload{
+					Resource:   updatedObject,
+					Additional: additionalDetails,
+				},
+			}); err != nil {
+				return nil, err
+			}
+			return updatedObject, nil
+		case !isActive:
+			if err := CreateNotification(ctx, repository, types.DELETED, updatedObject.GetType(), oldPlatformID, &Payload{
+				Old: &ObjectPayload{
+					Resource:   oldObject,
+					Additional: additionalDetails,
+				},
+			}); err != nil {
+				return nil, err
+			}
+			return updatedObject, nil
+		}
+
 		// if the resource update contains change in the platform ID field this means that the notification would be processed by
 		// two platforms - one needs to perform a delete operation and the other needs to perform a create operation.
 		if oldPlatformID != newPlatformID {
@@ -144,6 +180,9 @@ func (ni *NotificationsInterceptor) OnTxDelete(h storage.InterceptDeleteOnTxFunc
 
 		for i := 0; i < deletedObjects.Len(); i++ {
 			oldObject := deletedObjects.ItemAt(i)
+			if !ni.isActive(oldObject) {
+				continue
+			}
 
 			platformID := ni.PlatformIdProviderFunc(ctx, oldObject)
 
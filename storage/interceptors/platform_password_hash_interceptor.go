@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	PlatformPasswordHashCreateInterceptorName = "PlatformPasswordHashCreateInterceptor"
+	PlatformPasswordHashUpdateInterceptorName = "PlatformPasswordHashUpdateInterceptor"
+
+	// PlatformPasswordMigrationJobName is the name under which the job hashing the passwords persisted before hashing
+	// was enabled is registered
+	PlatformPasswordMigrationJobName = "platform_password_migration"
+)
+
+// PlatformPasswordHashCreateInterceptorProvider provides an interceptor which persists salted hashes of the passwords
+// of new platforms instead of the passwords
+type PlatformPasswordHashCreateInterceptorProvider struct {
+	// Cost is the bcrypt cost of the hashes
+	Cost int
+}
+
+func (p *PlatformPasswordHashCreateInterceptorProvider) Name() string {
+	return PlatformPasswordHashCreateInterceptorName
+}
+
+func (p *PlatformPasswordHashCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &platformPasswordHashInterceptor{cost: p.Cost}
+}
+
+// PlatformPasswordHashUpdateInterceptorProvider provides an interceptor which persists salted hashes of the changed
+// passwords of platforms, e.g. rotated ones, instead of the passwords
+type PlatformPasswordHashUpdateInterceptorProvider struct {
+	// Cost is the bcrypt cost of the hashes
+	Cost int
+}
+
+func (p *PlatformPasswordHashUpdateInterceptorProvider) Name() string {
+	return PlatformPasswordHashUpdateInterceptorName
+}
+
+func (p *PlatformPasswordHashUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &platformPasswordHashInterceptor{cost: p.Cost}
+}
+
+// platformPasswordHashInterceptor replaces the passwords of platforms with their hashes outside of the transaction,
+// so that the transaction is not held open while hashing. The objects returned to the callers contain the passwords,
+// so that generated and rotated passwords can still be handed out once.
+type platformPasswordHashInterceptor struct {
+	cost int
+}
+
+func (i *platformPasswordHashInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		password, err := hashPassword(obj, i.cost)
+		if err != nil {
+			return nil, err
+		}
+		created, err := h(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		restorePlainPassword(created, password)
+		return created, nil
+	}
+}
+
+func (i *platformPasswordHashInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		password, err := hashPassword(obj, i.cost)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := h(ctx, obj, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		restorePlainPassword(updated, password)
+		return updated, nil
+	}
+}
+
+func (*platformPasswordHashInterceptor) OnTxCreate(f storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return f
+}
+
+func (*platformPasswordHashInterceptor) OnTxUpdate(f storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return f
+}
+
+// PlatformPasswordMigration is a job which hashes the passwords of the platforms persisted before hashing was
+// enabled. It is idempotent, platforms whose passwords are already hashed or stored in a secret store are skipped.
+type PlatformPasswordMigration struct {
+	Repository storage.Repository
+	// Cost is the bcrypt cost of the hashes
+	Cost int
+}
+
+// Name implements jobs.Job
+func (m *PlatformPasswordMigration) Name() string {
+	return PlatformPasswordMigrationJobName
+}
+
+// Run implements jobs.Job and hashes the passwords which are not hashed yet
+func (m *PlatformPasswordMigration) Run(ctx context.Context) error {
+	platforms, err := m.Repository.List(ctx, types.PlatformType)
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for j := 0; j < platforms.Len(); j++ {
+		platform := platforms.ItemAt(j)
+		if !hasPlainPassword(platform) {
+			continue
+		}
+		if _, err := hashPassword(platform, m.Cost); err != nil {
+			return err
+		}
+		if _, err := m.Repository.Update(ctx, platform); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.C(ctx).Infof("Hashed the passwords of %d platforms", migrated)
+	}
+	return nil
+}
+
+// hashPassword replaces the password of the platform with its hash and returns the password. Passwords which are
+// already hashed or are references to the secret store are left as they are.
+func hashPassword(obj types.Object, cost int) (string, error) {
+	if !hasPlainPassword(obj) {
+		return "", nil
+	}
+	basic := obj.(types.Secured).GetCredentials().Basic
+	hash, err := credentials.Hash(basic.Password, cost)
+	if err != nil {
+		return "", err
+	}
+	password := basic.Password
+	basic.Password = hash
+	return password, nil
+}
+
+func hasPlainPassword(obj types.Object) bool {
+	secured, ok := obj.(types.Secured)
+	if !ok || secured.GetCredentials() == nil || secured.GetCredentials().Basic == nil {
+		return false
+	}
+	password := secured.GetCredentials().Basic.Password
+	_, isReference := credentials.ParseReference(password)
+	return password != "" && !isReference && !credentials.IsHash(password)
+}
+
+func restorePlainPassword(obj types.Object, password string) {
+	if password == "" {
+		return
+	}
+	if secured, ok := obj.(types.Secured); ok && secured.GetCredentials() != nil && secured.GetCredentials().Basic != nil {
+		secured.GetCredentials().Basic.Password = password
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/credentials"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	PlatformSecretsCreateInterceptorName = "PlatformSecretsCreateInterceptor"
+	PlatformSecretsUpdateInterceptorName = "PlatformSecretsUpdateInterceptor"
+	PlatformSecretsDeleteInterceptorName = "PlatformSecretsDeleteInterceptor"
+)
+
+// PlatformSecretsCreateInterceptorProvider provides an interceptor which stores the passwords of new platforms in
+// the secret store and persists only their references
+type PlatformSecretsCreateInterceptorProvider struct {
+	// Secrets provides the secret store, the passwords are persisted as they are if it has none
+	Secrets *credentials.Provider
+}
+
+func (p *PlatformSecretsCreateInterceptorProvider) Name() string {
+	return PlatformSecretsCreateInterceptorName
+}
+
+func (p *PlatformSecretsCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &platformSecretsInterceptor{secrets: p.Secrets}
+}
+
+// PlatformSecretsUpdateInterceptorProvider provides an interceptor which stores the changed passwords of platforms,
+// e.g. rotated ones, in the secret store and persists only their references
+type PlatformSecretsUpdateInterceptorProvider struct {
+	// Secrets provides the secret store, the passwords are persisted as they are if it has none
+	Secrets *credentials.Provider
+}
+
+func (p *PlatformSecretsUpdateInterceptorProvider) Name() string {
+	return PlatformSecretsUpdateInterceptorName
+}
+
+func (p *PlatformSecretsUpdateInterceptorProvider) Provide() storage.UpdateInterceptor {
+	return &platformSecretsInterceptor{secrets: p.Secrets}
+}
+
+// PlatformSecretsDeleteInterceptorProvider provides an interceptor which deletes the passwords of deleted platforms
+// from the secret store
+type PlatformSecretsDeleteInterceptorProvider struct {
+	// Secrets provides the secret store, nothing is deleted if it has none
+	Secrets *credentials.Provider
+}
+
+func (p *PlatformSecretsDeleteInterceptorProvider) Name() string {
+	return PlatformSecretsDeleteInterceptorName
+}
+
+func (p *PlatformSecretsDeleteInterceptorProvider) Provide() storage.DeleteInterceptor {
+	return &platformSecretsInterceptor{secrets: p.Secrets}
+}
+
+// platformSecretsInterceptor replaces the passwords of platforms with references to the secret store outside of the
+// transaction, so that the secret store is not called while the transaction is open. The objects returned to the
+// callers contain the passwords, not the references.
+type platformSecretsInterceptor struct {
+	secrets *credentials.Provider
+}
+
+func (i *platformSecretsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		password, reference, err := i.store(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		created, err := h(ctx, obj)
+		if err != nil {
+			if reference != "" {
+				i.delete(ctx, reference)
+			}
+			return nil, err
+		}
+		restorePassword(created, reference, password)
+		return created, nil
+	}
+}
+
+func (i *platformSecretsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		password, reference, err := i.store(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := h(ctx, obj, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		restorePassword(updated, reference, password)
+		return updated, nil
+	}
+}
+
+func (i *platformSecretsInterceptor) AroundTxDelete(h storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
+	return func(ctx context.Context, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		deleted, err := h(ctx, deletionCriteria...)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < deleted.Len(); j++ {
+			if reference, ok := persistedReference(deleted.ItemAt(j)); ok {
+				i.delete(ctx, reference)
+			}
+		}
+		return deleted, nil
+	}
+}
+
+func (*platformSecretsInterceptor) OnTxCreate(f storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return f
+}
+
+func (*platformSecretsInterceptor) OnTxUpdate(f storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return f
+}
+
+func (*platformSecretsInterceptor) OnTxDelete(f storage.InterceptDeleteOnTxFunc) storage.InterceptDeleteOnTxFunc {
+	return f
+}
+
+// store stores the password of the platform in the secret store and replaces it with the reference. Passwords
+// which are already references or hashes are left as they are.
+func (i *platformSecretsInterceptor) store(ctx context.Context, obj types.Object) (string, string, error) {
+	store := i.secrets.SecretStore()
+	secured, ok := obj.(types.Secured)
+	if store == nil || !ok || secured.GetCredentials() == nil || secured.GetCredentials().Basic == nil {
+		return "", "", nil
+	}
+	basic := secured.GetCredentials().Basic
+	if _, isReference := credentials.ParseReference(basic.Password); isReference || basic.Password == "" || credentials.IsHash(basic.Password) {
+		return "", "", nil
+	}
+
+	password := basic.Password
+	reference, err := store.Put(ctx, obj.GetID(), password)
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not store password of %s with id %s in secret store %s", obj.GetType(), obj.GetID(), store.Name())
+		return "", "", err
+	}
+	basic.Password = credentials.Reference(reference)
+	return password, reference, nil
+}
+
+func (i *platformSecretsInterceptor) delete(ctx context.Context, reference string) {
+	store := i.secrets.SecretStore()
+	if store == nil {
+		log.C(ctx).Warnf("Could not delete password %s as no secret store is configured", reference)
+		return
+	}
+	if err := store.Delete(ctx, reference); err != nil {
+		log.C(ctx).WithError(err).Warnf("Could not delete password %s from secret store %s", reference, store.Name())
+	}
+}
+
+func restorePassword(obj types.Object, reference, password string) {
+	if reference == "" {
+		return
+	}
+	if secured, ok := obj.(types.Secured); ok && secured.GetCredentials() != nil && secured.GetCredentials().Basic != nil {
+		secured.GetCredentials().Basic.Password = password
+	}
+}
+
+func persistedReference(obj types.Object) (string, bool) {
+	secured, ok := obj.(types.Secured)
+	if !ok || secured.GetCredentials() == nil || secured.GetCredentials().Basic == nil {
+		return "", false
+	}
+	return credentials.ParseReference(secured.GetCredentials().Basic.Password)
+}
@@ -19,43 +19,82 @@ package interceptors
 import (
 	"context"
 
+	"github.com/Peripli/service-manager/pkg/credentials"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/platformtypes"
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/types"
 )
 
 const (
-	generateCredentialsInterceptorName = "CreateCredentialsInterceptor"
+	GenerateCredentialsInterceptorName = "CreateCredentialsInterceptor"
 )
 
+// PlatformTypeCredentialsType is the credentials type of the platforms whose credentials were generated by a
+// plugin of their platform type
+const PlatformTypeCredentialsType = "platform_type"
+
 type GenerateCredentialsInterceptorProvider struct {
+	// PlatformTypes provides the credentials format of the platform types, random basic credentials are generated if it is nil
+	PlatformTypes *platformtypes.Registry
+
+	// Generators provides the generator of the credentials of platforms whose type has no credentials generator,
+	// random basic credentials are generated if it is nil
+	Generators *credentials.Provider
 }
 
 func (c *GenerateCredentialsInterceptorProvider) Provide() storage.CreateInterceptor {
-	return &generateCredentialsInterceptor{}
+	return &generateCredentialsInterceptor{
+		platformTypes: c.PlatformTypes,
+		generators:    c.Generators,
+	}
 }
 
 func (c *GenerateCredentialsInterceptorProvider) Name() string {
-	return generateCredentialsInterceptorName
+	return GenerateCredentialsInterceptorName
 }
 
-type generateCredentialsInterceptor struct{}
+type generateCredentialsInterceptor struct {
+	platformTypes *platformtypes.Registry
+	generators    *credentials.Provider
+}
 
 // AroundTxCreate generates new credentials for the secured object
 func (c *generateCredentialsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
 	return func(ctx context.Context, obj types.Object) (types.Object, error) {
-		credentials, err := types.GenerateCredentials()
+		platform, _ := obj.(*types.Platform)
+		generated, credentialsType, err := GenerateCredentials(ctx, c.platformTypes, c.generators, platform)
 		if err != nil {
 			log.C(ctx).Error("Could not generate credentials for platform")
 			return nil, err
 		}
-		(obj.(types.Secured)).SetCredentials(credentials)
+		(obj.(types.Secured)).SetCredentials(generated)
+		if platform != nil {
+			platform.CredentialsType = credentialsType
+		}
 
 		return h(ctx, obj)
 	}
 }
 
+// GenerateCredentials returns new credentials of the platform and their type. The credentials generator of the
+// platform type takes precedence over the configured generator.
+func GenerateCredentials(ctx context.Context, platformTypes *platformtypes.Registry, generators *credentials.Provider, platform *types.Platform) (*types.Credentials, string, error) {
+	if generator := platformTypes.CredentialsGenerator(platform); generator != nil {
+		generated, err := generator.GenerateCredentials(platform)
+		return generated, PlatformTypeCredentialsType, err
+	}
+	if generators != nil {
+		if generator := generators.Generator(); generator != nil {
+			generated, err := generator.Generate(ctx, platform)
+			return generated, generator.Name(), err
+		}
+	}
+	generated, err := types.GenerateCredentials()
+	return generated, credentials.RandomGeneratorName, err
+}
+
 // OnTxCreate invokes the next interceptor in the chain
 func (*generateCredentialsInterceptor) OnTxCreate(f storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
 	return f
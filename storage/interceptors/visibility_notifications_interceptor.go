@@ -3,6 +3,7 @@ package interceptors
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/util"
 
@@ -43,6 +44,9 @@ func NewVisibilityNotificationsInterceptor() *NotificationsInterceptor {
 				ServicePlan: plan.(*types.ServicePlan),
 			}, nil
 		},
+		IsActiveFunc: func(obj types.Object) bool {
+			return obj.(*types.Visibility).IsActive(time.Now())
+		},
 	}
 }
 
@@ -66,11 +70,17 @@ func (va VisibilityAdditional) Validate() error {
 	return va.ServicePlan.Validate()
 }
 
+const (
+	VisibilityCreateNotificationInterceptorName = "VisibilityCreateNotificationsInterceptorProvider"
+	VisibilityUpdateNotificationInterceptorName = "VisibilityUpdateNotificationsInterceptorProvider"
+	VisibilityDeleteNotificationInterceptorName = "VisibilityDeleteNotificationsInterceptorProvider"
+)
+
 type VisibilityCreateNotificationsInterceptorProvider struct {
 }
 
 func (*VisibilityCreateNotificationsInterceptorProvider) Name() string {
-	return "VisibilityCreateNotificationsInterceptorProvider"
+	return VisibilityCreateNotificationInterceptorName
 }
 
 func (*VisibilityCreateNotificationsInterceptorProvider) Provide() storage.CreateInterceptor {
@@ -81,7 +91,7 @@ type VisibilityUpdateNotificationsInterceptorProvider struct {
 }
 
 func (*VisibilityUpdateNotificationsInterceptorProvider) Name() string {
-	return "VisibilityUpdateNotificationsInterceptorProvider"
+	return VisibilityUpdateNotificationInterceptorName
 }
 
 func (*VisibilityUpdateNotificationsInterceptorProvider) Provide() storage.UpdateInterceptor {
@@ -92,7 +102,7 @@ type VisibilityDeleteNotificationsInterceptorProvider struct {
 }
 
 func (*VisibilityDeleteNotificationsInterceptorProvider) Name() string {
-	return "VisibilityDeleteNotificationsInterceptorProvider"
+	return VisibilityDeleteNotificationInterceptorName
 }
 
 func (*VisibilityDeleteNotificationsInterceptorProvider) Provide() storage.DeleteInterceptor {
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// CreateHook is a CreateInterceptorProvider built from plain functions. Before is invoked in the transaction
+// prior to the creation and may veto it by returning an error. After is invoked once the transaction is committed.
+// Errors returned by After are propagated to the caller but do not revert the creation.
+type CreateHook struct {
+	HookName string
+	Before   func(ctx context.Context, txStorage Repository, obj types.Object) error
+	After    func(ctx context.Context, obj types.Object) error
+}
+
+// Name returns the name of the hook which can be used by other interceptors to declare their order relative to it
+func (h *CreateHook) Name() string {
+	return h.HookName
+}
+
+// Provide returns the hook itself as it holds no per-request state
+func (h *CreateHook) Provide() CreateInterceptor {
+	return h
+}
+
+// AroundTxCreate invokes the After function once the object is created
+func (h *CreateHook) AroundTxCreate(f InterceptCreateAroundTxFunc) InterceptCreateAroundTxFunc {
+	if h.After == nil {
+		return f
+	}
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		createdObj, err := f(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.After(ctx, createdObj); err != nil {
+			return nil, err
+		}
+		return createdObj, nil
+	}
+}
+
+// OnTxCreate invokes the Before function prior to creating the object
+func (h *CreateHook) OnTxCreate(f InterceptCreateOnTxFunc) InterceptCreateOnTxFunc {
+	if h.Before == nil {
+		return f
+	}
+	return func(ctx context.Context, txStorage Repository, obj types.Object) (types.Object, error) {
+		if err := h.Before(ctx, txStorage, obj); err != nil {
+			return nil, err
+		}
+		return f(ctx, txStorage, obj)
+	}
+}
+
+// UpdateHook is an UpdateInterceptorProvider built from plain functions. Before is invoked in the transaction
+// prior to the update and may veto it by returning an error. After is invoked once the transaction is committed.
+// Errors returned by After are propagated to the caller but do not revert the update.
+type UpdateHook struct {
+	HookName string
+	Before   func(ctx context.Context, txStorage Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) error
+	After    func(ctx context.Context, obj types.Object) error
+}
+
+// Name returns the name of the hook which can be used by other interceptors to declare their order relative to it
+func (h *UpdateHook) Name() string {
+	return h.HookName
+}
+
+// Provide returns the hook itself as it holds no per-request state
+func (h *UpdateHook) Provide() UpdateInterceptor {
+	return h
+}
+
+// AroundTxUpdate invokes the After function once the object is updated
+func (h *UpdateHook) AroundTxUpdate(f InterceptUpdateAroundTxFunc) InterceptUpdateAroundTxFunc {
+	if h.After == nil {
+		return f
+	}
+	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		updatedObj, err := f(ctx, obj, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.After(ctx, updatedObj); err != nil {
+			return nil, err
+		}
+		return updatedObj, nil
+	}
+}
+
+// OnTxUpdate invokes the Before function prior to updating the object
+func (h *UpdateHook) OnTxUpdate(f InterceptUpdateOnTxFunc) InterceptUpdateOnTxFunc {
+	if h.Before == nil {
+		return f
+	}
+	return func(ctx context.Context, txStorage Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if err := h.Before(ctx, txStorage, oldObj, newObj, labelChanges...); err != nil {
+			return nil, err
+		}
+		return f(ctx, txStorage, oldObj, newObj, labelChanges...)
+	}
+}
+
+// DeleteHook is a DeleteInterceptorProvider built from plain functions. Before is invoked in the transaction
+// with the objects that are about to be deleted and may veto the deletion by returning an error. After is invoked
+// once the transaction is committed. Errors returned by After are propagated to the caller but do not revert the deletion.
+type DeleteHook struct {
+	HookName string
+	Before   func(ctx context.Context, txStorage Repository, objects types.ObjectList) error
+	After    func(ctx context.Context, objects types.ObjectList) error
+}
+
+// Name returns the name of the hook which can be used by other interceptors to declare their order relative to it
+func (h *DeleteHook) Name() string {
+	return h.HookName
+}
+
+// Provide returns the hook itself as it holds no per-request state
+func (h *DeleteHook) Provide() DeleteInterceptor {
+	return h
+}
+
+// AroundTxDelete invokes the After function once the objects are deleted
+func (h *DeleteHook) AroundTxDelete(f InterceptDeleteAroundTxFunc) InterceptDeleteAroundTxFunc {
+	if h.After == nil {
+		return f
+	}
+	return func(ctx context.Context, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		objects, err := f(ctx, deletionCriteria...)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.After(ctx, objects); err != nil {
+			return nil, err
+		}
+		return objects, nil
+	}
+}
+
+// OnTxDelete invokes the Before function prior to deleting the objects
+func (h *DeleteHook) OnTxDelete(f InterceptDeleteOnTxFunc) InterceptDeleteOnTxFunc {
+	if h.Before == nil {
+		return f
+	}
+	return func(ctx context.Context, txStorage Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+		if err := h.Before(ctx, txStorage, objects); err != nil {
+			return nil, err
+		}
+		return f(ctx, txStorage, objects, deletionCriteria...)
+	}
+}
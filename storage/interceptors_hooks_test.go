@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hooks", func() {
+	var (
+		ctx         context.Context
+		fakeStorage *storagefakes.FakeStorage
+		calls       []string
+		vetoErr     error
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		fakeStorage = &storagefakes.FakeStorage{}
+		calls = []string{}
+		vetoErr = errors.New("vetoed")
+	})
+
+	Describe("CreateHook", func() {
+		var hook *storage.CreateHook
+
+		createOnTx := func(ctx context.Context, txStorage storage.Repository, obj types.Object) (types.Object, error) {
+			calls = append(calls, "create")
+			return obj, nil
+		}
+
+		BeforeEach(func() {
+			hook = &storage.CreateHook{
+				HookName: "hook",
+				Before: func(ctx context.Context, txStorage storage.Repository, obj types.Object) error {
+					calls = append(calls, "before")
+					return nil
+				},
+				After: func(ctx context.Context, obj types.Object) error {
+					calls = append(calls, "after")
+					return nil
+				},
+			}
+		})
+
+		It("provides itself under its name", func() {
+			Expect(hook.Name()).To(Equal("hook"))
+			Expect(hook.Provide()).To(Equal(hook))
+		})
+
+		It("invokes before and after around the creation", func() {
+			aroundTx := hook.AroundTxCreate(func(ctx context.Context, obj types.Object) (types.Object, error) {
+				return hook.OnTxCreate(createOnTx)(ctx, fakeStorage, obj)
+			})
+			_, err := aroundTx(ctx, &types.ServiceBroker{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal([]string{"before", "create", "after"}))
+		})
+
+		It("vetoes the creation when before returns an error", func() {
+			hook.Before = func(ctx context.Context, txStorage storage.Repository, obj types.Object) error {
+				return vetoErr
+			}
+			_, err := hook.OnTxCreate(createOnTx)(ctx, fakeStorage, &types.ServiceBroker{})
+			Expect(err).To(Equal(vetoErr))
+			Expect(calls).To(BeEmpty())
+		})
+
+		It("skips nil functions", func() {
+			hook.Before = nil
+			_, err := hook.OnTxCreate(createOnTx)(ctx, fakeStorage, &types.ServiceBroker{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal([]string{"create"}))
+		})
+	})
+
+	Describe("UpdateHook", func() {
+		It("vetoes the update when before returns an error", func() {
+			hook := &storage.UpdateHook{
+				HookName: "hook",
+				Before: func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) error {
+					return vetoErr
+				},
+			}
+			_, err := hook.OnTxUpdate(func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+				calls = append(calls, "update")
+				return newObj, nil
+			})(ctx, fakeStorage, &types.ServiceBroker{}, &types.ServiceBroker{})
+			Expect(err).To(Equal(vetoErr))
+			Expect(calls).To(BeEmpty())
+		})
+
+		It("invokes after with the updated object", func() {
+			updated := &types.ServiceBroker{Name: "updated"}
+			var afterObj types.Object
+			hook := &storage.UpdateHook{
+				HookName: "hook",
+				After: func(ctx context.Context, obj types.Object) error {
+					afterObj = obj
+					return nil
+				},
+			}
+			_, err := hook.AroundTxUpdate(func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+				return updated, nil
+			})(ctx, &types.ServiceBroker{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(afterObj).To(Equal(updated))
+		})
+	})
+
+	Describe("DeleteHook", func() {
+		It("passes the objects to be deleted to before and vetoes the deletion on error", func() {
+			objects := &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{{Name: "broker"}}}
+			var beforeObjects types.ObjectList
+			hook := &storage.DeleteHook{
+				HookName: "hook",
+				Before: func(ctx context.Context, txStorage storage.Repository, objects types.ObjectList) error {
+					beforeObjects = objects
+					return vetoErr
+				},
+			}
+			_, err := hook.OnTxDelete(func(ctx context.Context, txStorage storage.Repository, objects types.ObjectList, deletionCriteria ...query.Criterion) (types.ObjectList, error) {
+				calls = append(calls, "delete")
+				return objects, nil
+			})(ctx, fakeStorage, objects)
+			Expect(err).To(Equal(vetoErr))
+			Expect(beforeObjects).To(Equal(objects))
+			Expect(calls).To(BeEmpty())
+		})
+	})
+})
@@ -51,12 +51,15 @@ var (
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	URI                string                `mapstructure:"uri" description:"URI of the storage"`
-	MigrationsURL      string                `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
-	EncryptionKey      string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
-	SkipSSLValidation  bool                  `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
-	MaxIdleConnections int                   `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
-	Notification       *NotificationSettings `mapstructure:"notification"`
+	URI                string                    `mapstructure:"uri" description:"URI of the storage"`
+	MigrationsURL      string                    `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
+	EncryptionKey      string                    `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
+	SkipSSLValidation  bool                      `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
+	MaxIdleConnections int                       `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
+	Notification       *NotificationSettings     `mapstructure:"notification"`
+	Cache              *CacheSettings            `mapstructure:"cache"`
+	TenantEncryption   *TenantEncryptionSettings `mapstructure:"tenant_encryption"`
+	SlowQuery          *SlowQuerySettings        `mapstructure:"slow_query"`
 }
 
 // DefaultSettings returns default values for storage settings
@@ -68,6 +71,9 @@ func DefaultSettings() *Settings {
 		SkipSSLValidation:  false,
 		MaxIdleConnections: 5,
 		Notification:       DefaultNotificationSettings(),
+		Cache:              DefaultCacheSettings(),
+		TenantEncryption:   DefaultTenantEncryptionSettings(),
+		SlowQuery:          DefaultSlowQuerySettings(),
 	}
 }
 
@@ -82,9 +88,62 @@ func (s *Settings) Validate() error {
 	if len(s.EncryptionKey) != 32 {
 		return fmt.Errorf("validate Settings: StorageEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.EncryptionKey))
 	}
+	if s.Cache != nil {
+		if err := s.Cache.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.TenantEncryption != nil {
+		if err := s.TenantEncryption.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.SlowQuery != nil {
+		if err := s.SlowQuery.Validate(); err != nil {
+			return err
+		}
+	}
 	return s.Notification.Validate()
 }
 
+// SlowQuerySettings type to be loaded from the environment
+type SlowQuerySettings struct {
+	Threshold            time.Duration `mapstructure:"threshold" description:"duration after which a storage query is logged as slow together with its execution plan and criteria, 0 disables the logging"`
+	SampleRate           float64       `mapstructure:"sample_rate" description:"fraction of the slow queries whose execution plan is requested with EXPLAIN"`
+	MaxExplainsPerMinute int           `mapstructure:"max_explains_per_minute" description:"maximum number of execution plans of slow queries requested per minute"`
+}
+
+// DefaultSlowQuerySettings returns default values for the slow query settings
+func DefaultSlowQuerySettings() *SlowQuerySettings {
+	return &SlowQuerySettings{
+		Threshold:            0,
+		SampleRate:           1,
+		MaxExplainsPerMinute: 10,
+	}
+}
+
+// Validate validates the slow query settings
+func (s *SlowQuerySettings) Validate() error {
+	if s.Threshold < 0 {
+		return fmt.Errorf("validate Settings: slow query threshold must not be negative")
+	}
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("validate Settings: slow query sample rate (%v) must be between 0 and 1", s.SampleRate)
+	}
+	if s.MaxExplainsPerMinute < 0 {
+		return fmt.Errorf("validate Settings: slow query max explains per minute must not be negative")
+	}
+	return nil
+}
+
+const (
+	// PostgresMessageBus lets every instance listen to the notifications of the storage
+	PostgresMessageBus = "postgres"
+
+	// RedisMessageBus lets one instance relay the notifications of the storage to all instances through Redis
+	RedisMessageBus = "redis"
+)
+
 // NotificationSettings type to be loaded from the environment
 type NotificationSettings struct {
 	QueuesSize           int           `mapstructure:"queues_size" description:"maximum number of notifications queued for sending to a client"`
@@ -92,6 +151,13 @@ type NotificationSettings struct {
 	MaxReconnectInterval time.Duration `mapstructure:"max_reconnect_interval" description:"maximum timeout between storage listen reconnects"`
 	CleanInterval        time.Duration `mapstructure:"clean_interval" description:"time between notification clean-up"`
 	KeepFor              time.Duration `mapstructure:"keep_for" description:"the time to keep a notification in the storage"`
+	CleanBatchSize       int           `mapstructure:"clean_batch_size" description:"maximum number of notifications deleted in a single statement during notification clean-up"`
+	CleanBatchPause      time.Duration `mapstructure:"clean_batch_pause" description:"time to wait between the delete statements of a notification clean-up"`
+	CleanMaxPerRun       int           `mapstructure:"clean_max_per_run" description:"maximum number of notifications deleted by a single notification clean-up, the rest is deleted by the next ones, 0 means no limit"`
+	MessageBus           string        `mapstructure:"message_bus" description:"how notifications reach the instances, postgres lets every instance listen to the storage and redis lets one instance relay them through the redis of the cache"`
+	RelayLeaseTTL        time.Duration `mapstructure:"relay_lease_ttl" description:"time after which another instance takes over relaying notifications to the message bus if the relaying instance stopped"`
+	RebalanceInterval    time.Duration `mapstructure:"rebalance_interval" description:"time between comparisons of the number of consumers of the instances connected to the message bus, 0 disables rebalancing"`
+	RebalanceBatchSize   int           `mapstructure:"rebalance_batch_size" description:"maximum number of consumers an instance above its share disconnects per rebalance interval"`
 }
 
 // DefaultNotificationSettings returns default values for Notificator settings
@@ -102,6 +168,13 @@ func DefaultNotificationSettings() *NotificationSettings {
 		MaxReconnectInterval: time.Second * 20,
 		CleanInterval:        time.Hour,
 		KeepFor:              time.Hour * 12,
+		CleanBatchSize:       1000,
+		CleanBatchPause:      100 * time.Millisecond,
+		CleanMaxPerRun:       100000,
+		MessageBus:           PostgresMessageBus,
+		RelayLeaseTTL:        10 * time.Second,
+		RebalanceInterval:    30 * time.Second,
+		RebalanceBatchSize:   10,
 	}
 }
 
@@ -123,6 +196,29 @@ func (s *NotificationSettings) Validate() error {
 	if s.CleanInterval < 0 {
 		return fmt.Errorf("notification clean interval (%d) should be grater or equal to 0", s.CleanInterval)
 	}
+	if s.CleanBatchSize < 1 {
+		return fmt.Errorf("notification clean batch size (%d) should be at least 1", s.CleanBatchSize)
+	}
+	if s.CleanBatchPause < 0 {
+		return fmt.Errorf("notification clean batch pause (%s) should be greater or equal to 0", s.CleanBatchPause)
+	}
+	if s.CleanMaxPerRun < 0 {
+		return fmt.Errorf("notification clean max per run (%d) should be greater or equal to 0", s.CleanMaxPerRun)
+	}
+	if s.MessageBus != PostgresMessageBus && s.MessageBus != RedisMessageBus {
+		return fmt.Errorf("notification message bus (%s) should be %s or %s", s.MessageBus, PostgresMessageBus, RedisMessageBus)
+	}
+	if s.MessageBus == RedisMessageBus {
+		if s.RelayLeaseTTL <= 0 {
+			return fmt.Errorf("notification relay lease ttl (%s) should be greater than 0", s.RelayLeaseTTL)
+		}
+		if s.RebalanceInterval < 0 {
+			return fmt.Errorf("notification rebalance interval (%s) should be greater or equal to 0", s.RebalanceInterval)
+		}
+		if s.RebalanceBatchSize < 1 {
+			return fmt.Errorf("notification rebalance batch size (%d) should be at least 1", s.RebalanceBatchSize)
+		}
+	}
 	return nil
 }
 
@@ -136,6 +232,7 @@ type OpenCloser interface {
 }
 
 // Pinger allows pinging the storage to check liveliness
+//
 //go:generate counterfeiter . Pinger
 type Pinger interface {
 	// Ping verifies a connection to the database is still alive, establishing a connection if necessary.
@@ -150,6 +247,9 @@ func (mf PingFunc) Ping() error {
 	return mf()
 }
 
+// Repository is a storage of Service Manager objects
+//
+//go:generate counterfeiter . Repository
 type Repository interface {
 	// Create stores a broker in SM DB
 	Create(ctx context.Context, obj types.Object) (types.Object, error)
@@ -167,7 +267,16 @@ type Repository interface {
 	Update(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error)
 }
 
+// BulkUpdater updates several objects at once
+type BulkUpdater interface {
+	// UpdateAll updates the objects with the same label changes in a single transaction, so that either all or
+	// none of them are updated
+	UpdateAll(ctx context.Context, objects []types.Object, labelChanges ...*query.LabelChange) ([]types.Object, error)
+}
+
 // TransactionalRepository is a storage repository that can initiate a transaction
+//
+//go:generate counterfeiter . TransactionalRepository
 type TransactionalRepository interface {
 	Repository
 
@@ -179,6 +288,7 @@ type TransactionalRepository interface {
 type TransactionalRepositoryDecorator func(TransactionalRepository) (TransactionalRepository, error)
 
 // Storage interface provides entity-specific storages
+//
 //go:generate counterfeiter . Storage
 type Storage interface {
 	OpenCloser
@@ -195,6 +305,7 @@ var ErrQueueClosed = errors.New("queue closed")
 var ErrQueueFull = errors.New("queue is full")
 
 // NotificationQueue is used for receiving notifications
+//
 //go:generate counterfeiter . NotificationQueue
 type NotificationQueue interface {
 	// Enqueue adds a new notification for processing.
@@ -211,6 +322,7 @@ type NotificationQueue interface {
 }
 
 // Notificator is used for receiving notifications for SM events
+//
 //go:generate counterfeiter . Notificator
 type Notificator interface {
 	// Start starts the Notificator
@@ -229,5 +341,68 @@ type Notificator interface {
 	RegisterFilter(f ReceiversFilterFunc)
 }
 
+// Lock represents a lock acquired through a Locker
+type Lock interface {
+	// Check verifies that the lock is still held
+	Check(ctx context.Context) error
+
+	// Release releases the lock
+	Release(ctx context.Context) error
+}
+
+// Locker allows acquiring named locks which are shared between all Service Manager instances using the same storage
+type Locker interface {
+	// TryLock tries to acquire the lock with the given name without waiting. If the lock is held by someone else
+	// a nil Lock is returned.
+	TryLock(ctx context.Context, name string) (Lock, error)
+}
+
+// VisibilityResolver resolves which platforms can see which service plans through the visibilities in effect
+type VisibilityResolver interface {
+	// PlatformsForPlan returns the platforms which can see the service plan with the given id at the provided time
+	PlatformsForPlan(ctx context.Context, planID string, at time.Time) (types.ObjectList, error)
+
+	// PlansForPlatform returns the service plans which the platform with the given id can see at the provided time
+	PlansForPlatform(ctx context.Context, platformID string, at time.Time) (types.ObjectList, error)
+}
+
+// TableStatistics describes the size of a storage table. The number of rows is an estimate of the storage.
+type TableStatistics struct {
+	Table     string `json:"table"`
+	Rows      int64  `json:"rows"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// EntityStatistics describes the sizes of the tables of the objects of a type and of their labels
+type EntityStatistics struct {
+	Type   types.ObjectType `json:"type"`
+	Table  TableStatistics  `json:"table"`
+	Labels *TableStatistics `json:"labels,omitempty"`
+}
+
+// Statistics describes the sizes of the storage tables for capacity planning
+type Statistics struct {
+	Entities []EntityStatistics `json:"entities"`
+
+	// NotificationBacklog is the estimated number of notifications which are not cleaned yet
+	NotificationBacklog int64 `json:"notification_backlog"`
+}
+
+// StatisticsProvider reports statistics about the storage which are cheap to collect
+type StatisticsProvider interface {
+	Statistics(ctx context.Context) (*Statistics, error)
+}
+
+// QueryableField is a field of a resource which can be used in field queries
+type QueryableField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QueryableFieldsProvider lists the fields which can be used in field queries for each object type
+type QueryableFieldsProvider interface {
+	QueryableFields() map[types.ObjectType][]QueryableField
+}
+
 // ReceiversFilterFunc filters recipients for a given notifications
 type ReceiversFilterFunc func(recipients []*types.Platform, notification *types.Notification) (filteredRecipients []*types.Platform)
@@ -29,14 +29,41 @@ import (
 	"github.com/Peripli/service-manager/pkg/types"
 )
 
+// NotificationCleanerJobName is the name under which the notification cleaner is registered as a job
+const NotificationCleanerJobName = "notification_cleaner"
+
+// NotificationCleanerMetrics describes the notification clean-ups done by this instance
+type NotificationCleanerMetrics struct {
+	Runs         int64 `json:"runs"`
+	TotalDeleted int64 `json:"total_deleted"`
+
+	LastDeleted    int64   `json:"last_deleted"`
+	LastBatches    int     `json:"last_batches"`
+	LastDurationMs float64 `json:"last_duration_ms"`
+	// LastLimitReached shows that the last clean-up stopped at the maximum number of notifications per run
+	// and the remaining old notifications are left for the next one
+	LastLimitReached bool `json:"last_limit_reached"`
+}
+
 // NotificationCleaner schedules a go routine which cleans old notifications
 type NotificationCleaner struct {
 	started bool
 
+	metricsMutex sync.RWMutex
+	metrics      NotificationCleanerMetrics
+
 	Storage  Repository
 	Settings Settings
 }
 
+// Metrics returns the metrics of the clean-ups done so far
+func (nc *NotificationCleaner) Metrics() NotificationCleanerMetrics {
+	nc.metricsMutex.RLock()
+	defer nc.metricsMutex.RUnlock()
+
+	return nc.metrics
+}
+
 // Start schedules the cleaner. It cannot be used concurrently.
 func (nc *NotificationCleaner) Start(ctx context.Context, group *sync.WaitGroup) error {
 	if nc.started {
@@ -63,18 +90,87 @@ func (nc *NotificationCleaner) Start(ctx context.Context, group *sync.WaitGroup)
 	return nil
 }
 
+// Name returns the name of the notification cleaner job
+func (nc *NotificationCleaner) Name() string {
+	return NotificationCleanerJobName
+}
+
+// Run deletes the old notifications once
+func (nc *NotificationCleaner) Run(ctx context.Context) error {
+	nc.clean(ctx)
+	return nil
+}
+
 func (nc *NotificationCleaner) clean(ctx context.Context) {
 	cleanTimestamp := time.Now().Add(-nc.Settings.Notification.KeepFor).Format(time.RFC3339)
 	log.C(ctx).Infof("Deleting notifications created before %s", cleanTimestamp)
 
-	q := query.ByField(query.LessThanOperator, "created_at", cleanTimestamp)
-	deletedNotifications, err := nc.Storage.Delete(ctx, types.NotificationType, q)
+	// Deleting in bounded batches with pauses in between keeps the transactions short and spreads the WAL
+	// over time, which allows autovacuum and the replicas to keep up while the clean-up is still in progress
+	settings := nc.Settings.Notification
+	started := time.Now()
+	deletedCount := 0
+	batches := 0
+	limitReached := false
+	defer func() {
+		nc.recordRun(deletedCount, batches, limitReached, time.Since(started))
+	}()
+	for ctx.Err() == nil {
+		batchSize := settings.CleanBatchSize
+		if settings.CleanMaxPerRun > 0 {
+			if remaining := settings.CleanMaxPerRun - deletedCount; remaining <= 0 {
+				limitReached = true
+				break
+			} else if remaining < batchSize {
+				batchSize = remaining
+			}
+		}
+		if batches > 0 && settings.CleanBatchPause > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(settings.CleanBatchPause):
+			}
+		}
 
-	if err == util.ErrNotFoundInStorage {
+		criteria := []query.Criterion{
+			query.ByField(query.LessThanOperator, "created_at", cleanTimestamp),
+			query.OrderResultBy("created_at", query.AscOrder),
+			query.LimitResultBy(batchSize),
+		}
+		deletedNotifications, err := nc.Storage.Delete(ctx, types.NotificationType, criteria...)
+		if err == util.ErrNotFoundInStorage {
+			break
+		}
+		if err != nil {
+			log.C(ctx).WithError(err).Error("could not delete old notifications")
+			return
+		}
+		batches++
+		deletedCount += deletedNotifications.Len()
+		if deletedNotifications.Len() < batchSize {
+			break
+		}
+	}
+
+	if deletedCount == 0 {
 		log.C(ctx).Debug("no old notifications to delete")
-	} else if err != nil {
-		log.C(ctx).WithError(err).Error("could not delete old notifications")
 	} else {
-		log.C(ctx).Infof("successfully deleted %d old notifications", deletedNotifications.Len())
+		log.C(ctx).Infof("successfully deleted %d old notifications in %d batches for %s", deletedCount, batches, time.Since(started))
+	}
+	if limitReached {
+		log.C(ctx).Warnf("notification clean-up stopped at the limit of %d notifications per run, the remaining old notifications are deleted by the next clean-up", settings.CleanMaxPerRun)
 	}
 }
+
+func (nc *NotificationCleaner) recordRun(deletedCount, batches int, limitReached bool, duration time.Duration) {
+	nc.metricsMutex.Lock()
+	defer nc.metricsMutex.Unlock()
+
+	nc.metrics.Runs++
+	nc.metrics.TotalDeleted += int64(deletedCount)
+	nc.metrics.LastDeleted = int64(deletedCount)
+	nc.metrics.LastBatches = batches
+	nc.metrics.LastDurationMs = float64(duration) / float64(time.Millisecond)
+	nc.metrics.LastLimitReached = limitReached
+}
@@ -19,6 +19,7 @@ package storage_test
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -91,14 +92,78 @@ var _ = Describe("Notification cleaner", func() {
 				Expect(err).ToNot(HaveOccurred())
 				wg.Wait()
 				Expect(objType).To(Equal(types.NotificationType))
-				Expect(criteria).To(HaveLen(1))
+				Expect(criteria).To(HaveLen(3))
 				Expect(criteria[0].LeftOp).To(Equal("created_at"))
+				Expect(criteria[2]).To(Equal(query.LimitResultBy(nc.Settings.Notification.CleanBatchSize)))
 				timeString := criteria[0].RightOp[0]
 				timeQueryParameter, err := time.Parse(time.RFC3339, timeString)
 				Expect(timeQueryParameter).To(BeTemporally("<", time.Now()))
 			})
 		})
 
+		Context("When there are more old notifications than the batch size", func() {
+			It("Should delete them in batches", func() {
+				nc.Settings.Notification.CleanBatchSize = 2
+				batches := []int{2, 2, 1}
+				deleteCalls := 0
+				fakeStorage.DeleteStub = func(ctx context.Context, objectType types.ObjectType, criterion ...query.Criterion) (types.ObjectList, error) {
+					notifications := &types.Notifications{}
+					for i := 0; i < batches[deleteCalls]; i++ {
+						notifications.Add(&types.Notification{})
+					}
+					deleteCalls++
+					return notifications, nil
+				}
+				err := nc.Run(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeStorage.DeleteCallCount()).To(Equal(3))
+			})
+		})
+
+		Context("When there are more old notifications than the maximum per run", func() {
+			It("Should stop at the maximum and record it in the metrics", func() {
+				nc.Settings.Notification.CleanBatchSize = 2
+				nc.Settings.Notification.CleanBatchPause = 0
+				nc.Settings.Notification.CleanMaxPerRun = 5
+				var limits []int
+				fakeStorage.DeleteStub = func(ctx context.Context, objectType types.ObjectType, criterion ...query.Criterion) (types.ObjectList, error) {
+					limit, err := strconv.Atoi(criterion[2].RightOp[0])
+					Expect(err).ToNot(HaveOccurred())
+					limits = append(limits, limit)
+					notifications := &types.Notifications{}
+					for i := 0; i < limit; i++ {
+						notifications.Add(&types.Notification{})
+					}
+					return notifications, nil
+				}
+				err := nc.Run(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits).To(Equal([]int{2, 2, 1}))
+
+				metrics := nc.Metrics()
+				Expect(metrics.Runs).To(Equal(int64(1)))
+				Expect(metrics.LastDeleted).To(Equal(int64(5)))
+				Expect(metrics.LastBatches).To(Equal(3))
+				Expect(metrics.LastLimitReached).To(BeTrue())
+			})
+		})
+
+		Context("When cleaned several times", func() {
+			It("Should accumulate the deleted notifications in the metrics", func() {
+				fakeStorage.DeleteReturns(&types.Notifications{
+					Notifications: []*types.Notification{{}, {}},
+				}, nil)
+				Expect(nc.Run(ctx)).To(Succeed())
+				Expect(nc.Run(ctx)).To(Succeed())
+
+				metrics := nc.Metrics()
+				Expect(metrics.Runs).To(Equal(int64(2)))
+				Expect(metrics.TotalDeleted).To(Equal(int64(4)))
+				Expect(metrics.LastDeleted).To(Equal(int64(2)))
+				Expect(metrics.LastLimitReached).To(BeFalse())
+			})
+		})
+
 		checkCleanerNotStopped := func(storageError error) {
 			nc.Settings.Notification.CleanInterval = 0
 			called := false
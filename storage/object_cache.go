@@ -0,0 +1,387 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/tidwall/gjson"
+)
+
+// ObjectCacheHookName is the name of the hooks which invalidate cached objects on changes in this instance
+const ObjectCacheHookName = "ObjectCacheHook"
+
+// CacheSettings type to be loaded from the environment
+type CacheSettings struct {
+	Enabled bool          `mapstructure:"enabled" description:"whether broker lookups are cached"`
+	TTL     time.Duration `mapstructure:"ttl" description:"maximum time an object is cached, bounds the staleness of objects which are not invalidated through notifications"`
+}
+
+// DefaultCacheSettings returns default values for the cache settings
+func DefaultCacheSettings() *CacheSettings {
+	return &CacheSettings{
+		Enabled: true,
+		TTL:     time.Minute,
+	}
+}
+
+// Validate validates the cache settings
+func (s *CacheSettings) Validate() error {
+	if s.Enabled && s.TTL <= 0 {
+		return fmt.Errorf("validate Settings: cache TTL (%s) should be greater than 0", s.TTL)
+	}
+	return nil
+}
+
+// CacheStatistics contains the usage statistics of an ObjectCache
+type CacheStatistics struct {
+	Entries       int     `json:"entries"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	Invalidations uint64  `json:"invalidations"`
+	HitRate       float64 `json:"hit_rate"`
+	Revision      int64   `json:"revision"`
+}
+
+type cacheEntry struct {
+	object    types.Object
+	expiresAt time.Time
+}
+
+// ObjectCache is an in-process cache of objects looked up by a key. Cached objects are invalidated when they are changed
+// in this instance, when a notification about them is received from the notification stream or when their TTL expires.
+// While the cache is not consuming the notification stream nothing is cached so that changes made by other instances are
+// not missed. Only objects which emit notifications may be cached, as changes made by other instances are missed otherwise.
+type ObjectCache struct {
+	settings *CacheSettings
+
+	mutex     sync.RWMutex
+	entries   map[types.ObjectType]map[string]cacheEntry
+	consuming bool
+	revision  int64
+	// generation counts the invalidations, so that objects loaded before an invalidation are not cached
+	generation uint64
+
+	hits, misses, invalidations uint64
+}
+
+// NewObjectCache returns an ObjectCache configured with the provided settings
+func NewObjectCache(settings *CacheSettings) *ObjectCache {
+	if settings == nil {
+		settings = DefaultCacheSettings()
+	}
+	return &ObjectCache{
+		settings: settings,
+		entries:  make(map[types.ObjectType]map[string]cacheEntry),
+		revision: types.InvalidRevision,
+	}
+}
+
+// Get returns a copy of the object of the specified type cached with the key
+func (c *ObjectCache) Get(objectType types.ObjectType, key string) (types.Object, bool) {
+	if c == nil || !c.settings.Enabled {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.entries[objectType][key]
+	if !found || time.Now().After(entry.expiresAt) {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return copyObject(entry.object), true
+}
+
+// Load returns the object of the specified type cached with the key. If it is not cached it is loaded with the provided
+// function and a copy of it is cached, unless objects were invalidated or the cache was flushed while it was loaded, as
+// it might be stale then. Nothing is cached while the notification stream is not consumed.
+func (c *ObjectCache) Load(objectType types.ObjectType, key string, load func() (types.Object, error)) (types.Object, error) {
+	if object, found := c.Get(objectType, key); found {
+		return object, nil
+	}
+	generation := c.currentGeneration()
+	object, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, object, generation)
+	return object, nil
+}
+
+func (c *ObjectCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.generation
+}
+
+func (c *ObjectCache) put(key string, object types.Object, generation uint64) {
+	if c == nil || !c.settings.Enabled {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	objectType := object.GetType()
+	if !c.consuming || c.generation != generation {
+		return
+	}
+	if c.entries[objectType] == nil {
+		c.entries[objectType] = make(map[string]cacheEntry)
+	}
+	c.entries[objectType][key] = cacheEntry{
+		object:    copyObject(object),
+		expiresAt: time.Now().Add(c.settings.TTL),
+	}
+}
+
+// Invalidate removes all cached objects of the specified type with the specified id
+func (c *ObjectCache) Invalidate(objectType types.ObjectType, id string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	for key, entry := range c.entries[objectType] {
+		if entry.object.GetID() == id {
+			delete(c.entries[objectType], key)
+			c.invalidations++
+		}
+	}
+}
+
+// Flush removes all cached objects
+func (c *ObjectCache) Flush() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[types.ObjectType]map[string]cacheEntry)
+	c.generation++
+}
+
+// Statistics returns the usage statistics of the cache
+func (c *ObjectCache) Statistics() CacheStatistics {
+	if c == nil {
+		return CacheStatistics{}
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entries := 0
+	for _, typeEntries := range c.entries {
+		entries += len(typeEntries)
+	}
+	statistics := CacheStatistics{
+		Entries:       entries,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Invalidations: c.invalidations,
+		Revision:      c.revision,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		statistics.HitRate = float64(c.hits) / float64(lookups)
+	}
+	return statistics
+}
+
+// Name implements health.Indicator and returns the name of the cache component
+func (c *ObjectCache) Name() string {
+	return "cache"
+}
+
+// Health implements health.Indicator and reports the cache statistics
+func (c *ObjectCache) Health() *health.Health {
+	healthz := health.New().Up()
+	if c == nil || !c.settings.Enabled {
+		return healthz.WithDetail("enabled", false)
+	}
+	return healthz.WithDetail("enabled", true).WithDetail("statistics", c.Statistics())
+}
+
+// Hooks returns the hooks which invalidate the cached objects of the specified type when they are changed in this
+// instance. The objects are invalidated after the transaction is committed, objects loaded before are not cached then.
+func (c *ObjectCache) Hooks(objectType types.ObjectType) (*UpdateHook, *DeleteHook) {
+	updateHook := &UpdateHook{
+		HookName: ObjectCacheHookName,
+		After: func(ctx context.Context, obj types.Object) error {
+			c.Invalidate(objectType, obj.GetID())
+			return nil
+		},
+	}
+	deleteHook := &DeleteHook{
+		HookName: ObjectCacheHookName,
+		After: func(ctx context.Context, objects types.ObjectList) error {
+			for i := 0; i < objects.Len(); i++ {
+				c.Invalidate(objectType, objects.ItemAt(i).GetID())
+			}
+			return nil
+		},
+	}
+	return updateHook, deleteHook
+}
+
+// Start consumes the notification stream and invalidates the cached objects referenced by the received notifications.
+// If the notification queue is closed the cache is flushed and the consumer is registered again.
+func (c *ObjectCache) Start(ctx context.Context, notificator Notificator, group *sync.WaitGroup) error {
+	if c == nil || !c.settings.Enabled {
+		return nil
+	}
+	util.StartInWaitGroupWithContext(ctx, func(ctx context.Context) {
+		consumer := &types.Platform{
+			Base: types.Base{
+				ID: "service-manager-cache",
+			},
+			Name: "service-manager-cache",
+		}
+		for {
+			c.consume(ctx, notificator, consumer)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}, group)
+	return nil
+}
+
+func (c *ObjectCache) consume(ctx context.Context, notificator Notificator, consumer *types.Platform) {
+	queue, revision, err := notificator.RegisterConsumer(consumer, types.InvalidRevision)
+	if err != nil {
+		log.C(ctx).WithError(err).Debug("Could not register cache as notification consumer")
+		return
+	}
+	defer func() {
+		if err := notificator.UnregisterConsumer(queue); err != nil {
+			log.C(ctx).WithError(err).Warn("Could not unregister cache notification consumer")
+		}
+	}()
+	c.setConsuming(true, revision)
+	defer c.setConsuming(false, types.InvalidRevision)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-queue.Channel():
+			if !ok {
+				log.C(ctx).Info("Cache notification queue closed, flushing cache")
+				return
+			}
+			c.process(notification)
+		}
+	}
+}
+
+func (c *ObjectCache) setConsuming(consuming bool, revision int64) {
+	c.Flush()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.consuming = consuming
+	c.revision = revision
+}
+
+func (c *ObjectCache) process(notification *types.Notification) {
+	for _, path := range []string{"new.resource.id", "old.resource.id"} {
+		if id := gjson.GetBytes(notification.Payload, path).String(); id != "" {
+			c.Invalidate(notification.Resource, id)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if notification.Revision > c.revision {
+		c.revision = notification.Revision
+	}
+}
+
+// copyObject returns a deep copy of the object, so that the cached objects are not changed by their users
+func copyObject(object types.Object) types.Object {
+	return copyValue(reflect.ValueOf(object)).Interface().(types.Object)
+}
+
+func copyValue(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Elem().Type())
+		copied.Elem().Set(copyValue(value.Elem()))
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(copyValue(value.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(copyValue(value.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(copyValue(value.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		for _, key := range value.MapKeys() {
+			copied.SetMapIndex(key, copyValue(value.MapIndex(key)))
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Object cache", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		wg          *sync.WaitGroup
+		settings    *storage.CacheSettings
+		cache       *storage.ObjectCache
+		notificator *storagefakes.FakeNotificator
+		queue       *storagefakes.FakeNotificationQueue
+		channel     chan *types.Notification
+		broker      *types.ServiceBroker
+	)
+
+	loadBroker := func() (types.Object, error) {
+		return broker, nil
+	}
+
+	put := func() {
+		_, err := cache.Load(types.ServiceBrokerType, broker.ID, loadBroker)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	startCache := func() {
+		Expect(cache.Start(ctx, notificator, wg)).To(Succeed())
+		Eventually(notificator.RegisterConsumerCallCount).Should(Equal(1))
+		Eventually(func() bool {
+			put()
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			return found
+		}).Should(BeTrue())
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		settings = storage.DefaultCacheSettings()
+		channel = make(chan *types.Notification)
+		queue = &storagefakes.FakeNotificationQueue{}
+		queue.ChannelReturns(channel)
+		notificator = &storagefakes.FakeNotificator{}
+		notificator.RegisterConsumerReturns(queue, 5, nil)
+		broker = &types.ServiceBroker{
+			Base: types.Base{
+				ID: "broker-id",
+			},
+			Name: "broker",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{
+					Username: "admin",
+					Password: "admin",
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		cache = storage.NewObjectCache(settings)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("Settings", func() {
+		It("are valid by default", func() {
+			Expect(storage.DefaultCacheSettings().Validate()).To(Succeed())
+		})
+
+		It("require a positive TTL when enabled", func() {
+			settings.TTL = 0
+			Expect(settings.Validate()).To(HaveOccurred())
+		})
+	})
+
+	Context("when the notification stream is not consumed", func() {
+		It("does not cache objects", func() {
+			put()
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when the cache is disabled", func() {
+		BeforeEach(func() {
+			settings.Enabled = false
+		})
+
+		It("does not consume the notification stream", func() {
+			Expect(cache.Start(ctx, notificator, wg)).To(Succeed())
+			Consistently(notificator.RegisterConsumerCallCount, 100*time.Millisecond).Should(Equal(0))
+		})
+	})
+
+	Context("when the cache is nil", func() {
+		JustBeforeEach(func() {
+			cache = nil
+		})
+
+		It("loads the objects without caching them", func() {
+			Expect(cache.Start(ctx, notificator, wg)).To(Succeed())
+			put()
+			cache.Invalidate(types.ServiceBrokerType, broker.ID)
+			cache.Flush()
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+			Expect(cache.Statistics()).To(Equal(storage.CacheStatistics{}))
+			Expect(cache.Health().Details).To(HaveKeyWithValue("enabled", false))
+			Expect(notificator.RegisterConsumerCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the notification stream is consumed", func() {
+		JustBeforeEach(func() {
+			startCache()
+		})
+
+		It("returns cached objects", func() {
+			obj, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeTrue())
+			Expect(obj).To(Equal(broker))
+			Expect(cache.Statistics().Revision).To(Equal(int64(5)))
+		})
+
+		It("returns copies of the cached objects", func() {
+			obj, _ := cache.Get(types.ServiceBrokerType, broker.ID)
+			obj.(*types.ServiceBroker).Credentials.Basic.Password = "changed"
+			obj.(*types.ServiceBroker).Name = "changed"
+
+			obj, _ = cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(obj.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("admin"))
+			Expect(obj.(*types.ServiceBroker).Name).To(Equal("broker"))
+		})
+
+		It("does not change the cached objects when the loaded objects are changed", func() {
+			cache.Invalidate(types.ServiceBrokerType, broker.ID)
+			obj, err := cache.Load(types.ServiceBrokerType, broker.ID, loadBroker)
+			Expect(err).ToNot(HaveOccurred())
+			obj.(*types.ServiceBroker).Credentials.Basic.Password = "changed"
+
+			obj, _ = cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(obj.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("admin"))
+		})
+
+		It("does not cache objects loaded before an invalidation", func() {
+			updateHook, _ := cache.Hooks(types.ServiceBrokerType)
+			Expect(updateHook.After(ctx, broker)).To(Succeed())
+
+			obj, err := cache.Load(types.ServiceBrokerType, broker.ID, func() (types.Object, error) {
+				Expect(updateHook.After(ctx, broker)).To(Succeed())
+				return broker, nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(obj).To(Equal(broker))
+
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+		})
+
+		It("does not cache objects which failed to load", func() {
+			cache.Invalidate(types.ServiceBrokerType, broker.ID)
+			_, err := cache.Load(types.ServiceBrokerType, broker.ID, func() (types.Object, error) {
+				return nil, fmt.Errorf("error")
+			})
+			Expect(err).To(HaveOccurred())
+
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+		})
+
+		It("does not return objects of other types", func() {
+			_, found := cache.Get(types.PlatformType, broker.ID)
+			Expect(found).To(BeFalse())
+		})
+
+		It("invalidates objects referenced by notifications", func() {
+			channel <- &types.Notification{
+				Resource: types.ServiceBrokerType,
+				Revision: 6,
+				Payload:  []byte(`{"old":{"resource":{"id":"broker-id"}}}`),
+			}
+			Eventually(func() bool {
+				_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+				return found
+			}).Should(BeFalse())
+			Eventually(func() int64 { return cache.Statistics().Revision }).Should(Equal(int64(6)))
+		})
+
+		It("invalidates objects changed through the hooks", func() {
+			updateHook, deleteHook := cache.Hooks(types.ServiceBrokerType)
+			Expect(updateHook.After(ctx, broker)).To(Succeed())
+			_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+
+			put()
+			Expect(deleteHook.After(ctx, &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker}})).To(Succeed())
+			_, found = cache.Get(types.ServiceBrokerType, broker.ID)
+			Expect(found).To(BeFalse())
+			Expect(cache.Statistics().Invalidations).To(Equal(uint64(2)))
+		})
+
+		It("flushes the cache when the notification queue is closed", func() {
+			close(channel)
+			Eventually(func() int { return cache.Statistics().Entries }).Should(Equal(0))
+			Eventually(notificator.UnregisterConsumerCallCount).Should(Equal(1))
+		})
+
+		It("reports the hit rate", func() {
+			cache.Get(types.PlatformType, "unknown")
+			statistics := cache.Statistics()
+			Expect(statistics.Hits).To(BeNumerically(">", 0))
+			Expect(statistics.HitRate).To(BeNumerically("<", 1))
+		})
+
+		Context("when the TTL of an object expires", func() {
+			BeforeEach(func() {
+				settings.TTL = 50 * time.Millisecond
+			})
+
+			It("is no longer returned", func() {
+				Eventually(func() bool {
+					_, found := cache.Get(types.ServiceBrokerType, broker.ID)
+					return found
+				}).Should(BeFalse())
+			})
+		})
+	})
+})
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/query"
 
@@ -55,9 +56,9 @@ type getterContext interface {
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
 
-//go:generate counterfeiter . pgDB
-// pgDB represents a PG database API
-type pgDB interface {
+//go:generate counterfeiter . PgDB
+// PgDB represents a PG database API
+type PgDB interface {
 	prepareNamedContext
 	namedExecerContext
 	namedQuerierContext
@@ -66,7 +67,7 @@ type pgDB interface {
 	sqlx.ExtContext
 }
 
-func create(ctx context.Context, db pgDB, table string, resultDto interface{}, argsDto interface{}) error {
+func create(ctx context.Context, db PgDB, table string, resultDto interface{}, argsDto interface{}) error {
 	setTagType := getDBTags(argsDto, isAutoIncrementable)
 	dbTags := make([]string, 0, len(setTagType))
 	for _, tagType := range setTagType {
@@ -128,28 +129,15 @@ func constructBaseQueryForLabelable(labelsEntity PostgresLabel, baseTableName st
 	return fmt.Sprintf(baseQuery, baseTableName, labelsTableName)
 }
 
-func update(ctx context.Context, db namedExecerContext, table string, dto interface{}) error {
-	updateQueryString := updateQuery(table, dto)
-	if updateQueryString == "" {
-		log.C(ctx).Debugf("%s update: Nothing to update", table)
-		return nil
-	}
-	log.C(ctx).Debugf("Executing query %s", updateQueryString)
-	result, err := db.NamedExecContext(ctx, updateQueryString, dto)
-	if err = checkIntegrityViolation(ctx, checkUniqueViolation(ctx, err)); err != nil {
-		return err
-	}
-	return checkRowsAffected(ctx, result)
-}
-
 func isAutoIncrementable(tagValue string) bool {
 	// auto_increment states that the value will be calculated in the DB
 	return strings.Contains(tagValue, "auto_increment")
 }
 
 type tagType struct {
-	Tag  string
-	Type reflect.Type
+	Tag   string
+	Type  reflect.Type
+	Value interface{}
 }
 
 func getDBTags(structure interface{}, predicate func(string) bool) []tagType {
@@ -180,28 +168,16 @@ func getTags(fields []*structs.Field, set *[]tagType, predicate func(string) boo
 			if dbTag == "" {
 				dbTag = strings.ToLower(field.Name())
 			}
-			ttype := reflect.ValueOf(field.Value()).Type()
+			value := field.Value()
 			*set = append(*set, tagType{
-				Tag:  dbTag,
-				Type: ttype,
+				Tag:   dbTag,
+				Type:  reflect.ValueOf(value).Type(),
+				Value: value,
 			})
 		}
 	}
 }
 
-func updateQuery(tableName string, structure interface{}) string {
-	dbTags := getDBTags(structure, isAutoIncrementable)
-	set := make([]string, 0, len(dbTags))
-	for _, dbTag := range dbTags {
-		set = append(set, fmt.Sprintf("%s = :%s", dbTag.Tag, dbTag.Tag))
-	}
-	if len(set) == 0 {
-		return ""
-	}
-	return fmt.Sprintf("UPDATE "+tableName+" SET %s WHERE id = :id",
-		strings.Join(set, ", "))
-}
-
 func checkUniqueViolation(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
@@ -258,6 +234,37 @@ func toNullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: s != ""}
 }
 
+// toNullTime converts the time to UTC as timestamps are stored without time zone
+func toNullTime(t *time.Time) pq.NullTime {
+	if t == nil {
+		return pq.NullTime{}
+	}
+	return pq.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func fromNullTime(t pq.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	result := t.Time
+	return &result
+}
+
+func toNullFloat64(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func fromNullFloat64(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	result := f.Float64
+	return &result
+}
+
 func getJSONText(item json.RawMessage) sqlxtypes.JSONText {
 	if len(item) == len("null") && string(item) == "null" {
 		return sqlxtypes.JSONText("{}")
@@ -36,6 +36,9 @@ type Broker struct {
 	Password    string             `db:"password"`
 	Catalog     sqlxtypes.JSONText `db:"catalog"`
 
+	CatalogETag         string `db:"catalog_etag"`
+	CatalogLastModified string `db:"catalog_last_modified"`
+
 	Services []*ServiceOffering `db:"-"`
 }
 
@@ -60,8 +63,10 @@ func (e *Broker) ToObject() types.Object {
 				Password: e.Password,
 			},
 		},
-		Catalog:  getJSONRawMessage(e.Catalog),
-		Services: services,
+		Catalog:             getJSONRawMessage(e.Catalog),
+		Services:            services,
+		CatalogETag:         e.CatalogETag,
+		CatalogLastModified: e.CatalogLastModified,
 	}
 	return broker
 }
@@ -89,6 +94,9 @@ func (*Broker) FromObject(object types.Object) (storage.Entity, bool) {
 		BrokerURL:   broker.BrokerURL,
 		Catalog:     getJSONText(broker.Catalog),
 		Services:    services,
+
+		CatalogETag:         broker.CatalogETag,
+		CatalogLastModified: broker.CatalogLastModified,
 	}
 	if broker.Credentials != nil && broker.Credentials.Basic != nil {
 		b.Username = broker.Credentials.Basic.Username
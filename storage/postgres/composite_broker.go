@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+//go:generate smgen storage CompositeBroker github.com/Peripli/service-manager/pkg/types
+// CompositeBroker entity
+type CompositeBroker struct {
+	BaseEntity
+	Name        string             `db:"name"`
+	Description sql.NullString     `db:"description"`
+	PlanIDs     sqlxtypes.JSONText `db:"plan_ids"`
+}
+
+func (c *CompositeBroker) FromObject(object types.Object) (storage.Entity, bool) {
+	composite, ok := object.(*types.CompositeBroker)
+	if !ok {
+		return nil, false
+	}
+	planIDs, err := json.Marshal(composite.PlanIDs)
+	if err != nil {
+		return nil, false
+	}
+	return &CompositeBroker{
+		BaseEntity: BaseEntity{
+			ID:        composite.ID,
+			CreatedAt: composite.CreatedAt,
+			UpdatedAt: composite.UpdatedAt,
+		},
+		Name:        composite.Name,
+		Description: toNullString(composite.Description),
+		PlanIDs:     sqlxtypes.JSONText(planIDs),
+	}, true
+}
+
+func (c *CompositeBroker) ToObject() types.Object {
+	var planIDs []string
+	if len(c.PlanIDs) != 0 {
+		_ = json.Unmarshal(c.PlanIDs, &planIDs)
+	}
+	return &types.CompositeBroker{
+		Base: types.Base{
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Name:        c.Name,
+		Description: c.Description.String,
+		PlanIDs:     planIDs,
+	}
+}
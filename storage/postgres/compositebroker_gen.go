@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &CompositeBroker{}
+
+const CompositeBrokerTable = "composite_brokers"
+
+func (*CompositeBroker) LabelEntity() PostgresLabel {
+	return &CompositeBrokerLabel{}
+}
+
+func (*CompositeBroker) TableName() string {
+	return CompositeBrokerTable
+}
+
+func (e *CompositeBroker) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &CompositeBrokerLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		CompositeBrokerID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *CompositeBroker) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*CompositeBroker
+			CompositeBrokerLabel `db:"composite_broker_labels"`
+		}{}
+	}
+	result := &types.CompositeBrokers{
+		CompositeBrokers: make([]*types.CompositeBroker, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type CompositeBrokerLabel struct {
+	BaseLabelEntity
+	CompositeBrokerID sql.NullString `db:"composite_broker_id"`
+}
+
+func (el CompositeBrokerLabel) LabelsTableName() string {
+	return "composite_broker_labels"
+}
+
+func (el CompositeBrokerLabel) ReferenceColumn() string {
+	return "composite_broker_id"
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/jmoiron/sqlx"
+)
+
+const indexColumnsQuery = `SELECT t.relname AS table_name, array_to_string(array_agg(a.attname ORDER BY k.n), ',') AS columns
+FROM pg_index i
+JOIN pg_class t ON t.oid = i.indrelid
+JOIN LATERAL unnest(i.indkey::smallint[]) WITH ORDINALITY AS k(attnum, n) ON true
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE t.relname IN (?)
+GROUP BY t.relname, i.indexrelid`
+
+// IndexRecommendation is an index which is recommended for the queries the storage executes
+type IndexRecommendation struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// String returns the statement which creates the recommended index
+func (r IndexRecommendation) String() string {
+	return fmt.Sprintf("CREATE INDEX %s_%s ON %s (%s)", r.Table, strings.Join(r.Columns, "_"), r.Table, strings.Join(r.Columns, ", "))
+}
+
+// IndexAdvisor checks whether the indexes recommended for the introduced entities are present.
+// Label queries filter label tables by reference column, key and value so a composite index on these
+// columns is recommended for each label table, including the ones of entities introduced by extensions.
+type IndexAdvisor struct {
+	Storage *Storage
+}
+
+// Recommendations returns the recommended indexes for all introduced entities
+func (a *IndexAdvisor) Recommendations() ([]IndexRecommendation, error) {
+	a.Storage.checkOpen()
+	var recommendations []IndexRecommendation
+	for _, provide := range a.Storage.scheme.instanceProviders {
+		entity, err := provide()
+		if err != nil {
+			return nil, err
+		}
+		labelEntity := entity.LabelEntity()
+		if labelEntity == nil {
+			continue
+		}
+		recommendations = append(recommendations, IndexRecommendation{
+			Table:   labelEntity.LabelsTableName(),
+			Columns: []string{labelEntity.ReferenceColumn(), "key", "val"},
+		})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		return recommendations[i].Table < recommendations[j].Table
+	})
+	return recommendations, nil
+}
+
+// MissingIndexes returns the recommended indexes which are not present. A recommendation is satisfied
+// by any index whose leading columns are the recommended columns.
+func (a *IndexAdvisor) MissingIndexes(ctx context.Context) ([]IndexRecommendation, error) {
+	recommendations, err := a.Recommendations()
+	if err != nil {
+		return nil, err
+	}
+	if len(recommendations) == 0 {
+		return nil, nil
+	}
+
+	tables := make([]string, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		tables = append(tables, recommendation.Table)
+	}
+	sqlQuery, args, err := sqlx.In(indexColumnsQuery, tables)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []struct {
+		Table   string `db:"table_name"`
+		Columns string `db:"columns"`
+	}
+	if err := a.Storage.pgDB.SelectContext(ctx, &indexes, a.Storage.pgDB.Rebind(sqlQuery), args...); err != nil {
+		return nil, err
+	}
+
+	var missing []IndexRecommendation
+	for _, recommendation := range recommendations {
+		recommendedPrefix := strings.Join(recommendation.Columns, ",")
+		found := false
+		for _, index := range indexes {
+			if index.Table == recommendation.Table && (index.Columns == recommendedPrefix || strings.HasPrefix(index.Columns, recommendedPrefix+",")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, recommendation)
+		}
+	}
+	return missing, nil
+}
+
+// LogMissingIndexes logs a warning for each recommended index which is not present
+func (a *IndexAdvisor) LogMissingIndexes(ctx context.Context) {
+	missing, err := a.MissingIndexes(ctx)
+	if err != nil {
+		log.C(ctx).WithError(err).Warn("Could not check for missing recommended indexes")
+		return
+	}
+	for _, index := range missing {
+		log.C(ctx).Warnf("Recommended index on table %s is missing, label queries may be slow. Consider executing: %s", index.Table, index)
+	}
+}
+
+// Name implements health.Indicator and returns the name of the index advisor component
+func (a *IndexAdvisor) Name() string {
+	return "storage_indexes"
+}
+
+// Health implements health.Indicator. Missing indexes are reported as a detail and do not affect the status.
+func (a *IndexAdvisor) Health() *health.Health {
+	healthz := health.New()
+	missing, err := a.MissingIndexes(context.Background())
+	if err != nil {
+		return healthz.Unknown().WithDetail("error", err.Error())
+	}
+	if len(missing) > 0 {
+		healthz.WithDetail("missing_indexes", missing)
+	}
+	return healthz.Up()
+}
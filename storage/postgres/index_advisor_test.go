@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/health"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Index advisor", func() {
+	var (
+		mockdb  *sql.DB
+		mock    sqlmock.Sqlmock
+		advisor *IndexAdvisor
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockdb, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		s := &Storage{
+			pgDB:   sqlx.NewDb(mockdb, postgresDriverName),
+			scheme: newScheme(),
+		}
+		s.scheme.introduce(&Broker{})
+		s.scheme.introduce(&Visibility{})
+		advisor = &IndexAdvisor{Storage: s}
+	})
+
+	AfterEach(func() {
+		mockdb.Close()
+	})
+
+	It("recommends an index for the label table of each introduced entity", func() {
+		recommendations, err := advisor.Recommendations()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recommendations).To(Equal([]IndexRecommendation{
+			{Table: "broker_labels", Columns: []string{"broker_id", "key", "val"}},
+			{Table: "visibility_labels", Columns: []string{"visibility_id", "key", "val"}},
+		}))
+	})
+
+	Context("when an index with the recommended leading columns exists", func() {
+		BeforeEach(func() {
+			mock.ExpectQuery("SELECT t.relname").
+				WithArgs("broker_labels", "visibility_labels").
+				WillReturnRows(sqlmock.NewRows([]string{"table_name", "columns"}).
+					AddRow("broker_labels", "id").
+					AddRow("broker_labels", "key,val,broker_id").
+					AddRow("visibility_labels", "visibility_id,key,val,created_at"))
+		})
+
+		It("reports only the missing indexes", func() {
+			missing, err := advisor.MissingIndexes(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(HaveLen(1))
+			Expect(missing[0].Table).To(Equal("broker_labels"))
+			Expect(missing[0].String()).To(Equal("CREATE INDEX broker_labels_broker_id_key_val ON broker_labels (broker_id, key, val)"))
+		})
+
+		It("keeps the health up and adds the missing indexes as detail", func() {
+			healthz := advisor.Health()
+			Expect(healthz.Status).To(Equal(health.StatusUp))
+			Expect(healthz.Details).To(HaveKey("missing_indexes"))
+		})
+	})
+
+	Context("when the indexes cannot be listed", func() {
+		BeforeEach(func() {
+			mock.ExpectQuery("SELECT t.relname").WillReturnError(fmt.Errorf("expected error"))
+		})
+
+		It("reports an unknown health", func() {
+			healthz := advisor.Health()
+			Expect(healthz.Status).To(Equal(health.StatusUnknown))
+		})
+	})
+})
@@ -160,3 +160,29 @@ func (s *Storage) SetEncryptionKey(ctx context.Context, key []byte, transformati
 
 	return err
 }
+
+// RotateEncryptionKey re-encrypts the stored encryption key with the provided layer one encryption key. The data
+// encrypted with the stored key is left untouched. The provided key must be used as storage encryption key afterwards.
+func (s *Storage) RotateEncryptionKey(ctx context.Context, newLayerOneKey []byte, decryptFunc, encryptFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	s.checkOpen()
+
+	key, err := s.GetEncryptionKey(ctx, decryptFunc)
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("no encryption key is present")
+	}
+
+	bytes, err := encryptFunc(ctx, key, newLayerOneKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE safe SET secret = $1, updated_at = $2", bytes, time.Now()); err != nil {
+		return err
+	}
+	s.layerOneEncryptionKey = newLayerOneKey
+
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Lock entity
+//go:generate smgen storage lock github.com/Peripli/service-manager/pkg/types:Lock
+type Lock struct {
+	BaseEntity
+	ResourceID   string    `db:"resource_id"`
+	ResourceType string    `db:"resource_type"`
+	Owner        string    `db:"owner"`
+	Reason       string    `db:"reason"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (l *Lock) ToObject() types.Object {
+	return &types.Lock{
+		Base: types.Base{
+			ID:        l.ID,
+			CreatedAt: l.CreatedAt,
+			UpdatedAt: l.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		ResourceID:   l.ResourceID,
+		ResourceType: types.ObjectType(l.ResourceType),
+		Owner:        l.Owner,
+		Reason:       l.Reason,
+		ExpiresAt:    l.ExpiresAt,
+	}
+}
+
+func (*Lock) FromObject(object types.Object) (storage.Entity, bool) {
+	lock, ok := object.(*types.Lock)
+	if !ok {
+		return nil, false
+	}
+
+	l := &Lock{
+		BaseEntity: BaseEntity{
+			ID:        lock.ID,
+			CreatedAt: lock.CreatedAt,
+			UpdatedAt: lock.UpdatedAt,
+		},
+		ResourceID:   lock.ResourceID,
+		ResourceType: string(lock.ResourceType),
+		Owner:        lock.Owner,
+		Reason:       lock.Reason,
+		ExpiresAt:    lock.ExpiresAt,
+	}
+	return l, true
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &Lock{}
+
+const LockTable = "locks"
+
+func (*Lock) LabelEntity() PostgresLabel {
+	return &LockLabel{}
+}
+
+func (*Lock) TableName() string {
+	return LockTable
+}
+
+func (e *Lock) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &LockLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		LockID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *Lock) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*Lock
+			LockLabel `db:"lock_labels"`
+		}{}
+	}
+	result := &types.Locks{
+		Locks: make([]*types.Lock, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type LockLabel struct {
+	BaseLabelEntity
+	LockID sql.NullString `db:"lock_id"`
+}
+
+func (el LockLabel) LabelsTableName() string {
+	return "lock_labels"
+}
+
+func (el LockLabel) ReferenceColumn() string {
+	return "lock_id"
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// advisoryLock is a postgres session level advisory lock. Since session level locks are bound to the
+// connection which acquired them, the lock keeps the connection until it is released.
+type advisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// Check verifies that the connection holding the lock is still alive
+func (l *advisoryLock) Check(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Release releases the lock and returns its connection to the pool
+func (l *advisoryLock) Release(ctx context.Context) error {
+	defer func() {
+		if err := l.conn.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("could not release lock connection")
+		}
+	}()
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	return err
+}
+
+// TryLock tries to acquire a postgres advisory lock for the given name without waiting
+func (ps *Storage) TryLock(ctx context.Context, name string) (storage.Lock, error) {
+	ps.checkOpen()
+
+	conn, err := ps.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKey(name)
+	acquired := false
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil || !acquired {
+		if closeErr := conn.Close(); closeErr != nil {
+			log.C(ctx).WithError(closeErr).Error("could not release lock connection")
+		}
+		return nil, err
+	}
+
+	return &advisoryLock{
+		conn: conn,
+		key:  key,
+	}, nil
+}
+
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS operation_labels;
+DROP TABLE IF EXISTS operations;
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE operations
+(
+  id            varchar(100) PRIMARY KEY,
+  type          varchar(100) NOT NULL,
+  state         varchar(100) NOT NULL,
+  resource_id   varchar(100) NOT NULL,
+  resource_type varchar(100) NOT NULL,
+  description   text         NOT NULL DEFAULT '',
+  errors        json         NOT NULL DEFAULT '{}',
+  created_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX operations_resource_id ON operations (resource_id);
+
+CREATE TABLE operation_labels
+(
+  id           varchar(100) PRIMARY KEY,
+  key          varchar(255) NOT NULL CHECK (key <> ''),
+  val          varchar(255) NOT NULL CHECK (val <> ''),
+  operation_id varchar(100) NOT NULL REFERENCES operations (id) ON DELETE CASCADE,
+  created_at   timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at   timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, operation_id)
+);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS broker_labels_broker_id_key_val;
+DROP INDEX IF EXISTS platform_labels_platform_id_key_val;
+DROP INDEX IF EXISTS service_offering_labels_service_offering_id_key_val;
+DROP INDEX IF EXISTS service_plan_labels_service_plan_id_key_val;
+DROP INDEX IF EXISTS visibility_labels_visibility_id_key_val;
+DROP INDEX IF EXISTS notification_labels_notification_id_key_val;
+DROP INDEX IF EXISTS operation_labels_operation_id_key_val;
+
+COMMIT;
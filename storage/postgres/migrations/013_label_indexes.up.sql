@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS broker_labels_broker_id_key_val ON broker_labels (broker_id, key, val);
+CREATE INDEX IF NOT EXISTS platform_labels_platform_id_key_val ON platform_labels (platform_id, key, val);
+CREATE INDEX IF NOT EXISTS service_offering_labels_service_offering_id_key_val ON service_offering_labels (service_offering_id, key, val);
+CREATE INDEX IF NOT EXISTS service_plan_labels_service_plan_id_key_val ON service_plan_labels (service_plan_id, key, val);
+CREATE INDEX IF NOT EXISTS visibility_labels_visibility_id_key_val ON visibility_labels (visibility_id, key, val);
+CREATE INDEX IF NOT EXISTS notification_labels_notification_id_key_val ON notification_labels (notification_id, key, val);
+CREATE INDEX IF NOT EXISTS operation_labels_operation_id_key_val ON operation_labels (operation_id, key, val);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE notifications RESET (autovacuum_vacuum_scale_factor, autovacuum_analyze_scale_factor);
+
+DROP INDEX IF EXISTS notifications_created_at;
+
+COMMIT;
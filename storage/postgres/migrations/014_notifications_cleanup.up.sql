@@ -0,0 +1,9 @@
+BEGIN;
+
+-- the notification cleaner deletes notifications by creation time
+CREATE INDEX IF NOT EXISTS notifications_created_at ON notifications (created_at);
+
+-- vacuum the table more often than the default as old notifications are continuously deleted
+ALTER TABLE notifications SET (autovacuum_vacuum_scale_factor = 0.01, autovacuum_analyze_scale_factor = 0.02);
+
+COMMIT;
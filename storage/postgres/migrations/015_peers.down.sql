@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS peer_labels;
+DROP TABLE IF EXISTS peers;
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE peers
+(
+  id          varchar(100) PRIMARY KEY NOT NULL,
+  name        varchar(255) NOT NULL UNIQUE,
+  description text,
+  url         text         NOT NULL,
+  username    varchar(255) NOT NULL,
+  password    varchar(500) NOT NULL,
+  created_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE peer_labels
+(
+  id         varchar(100) PRIMARY KEY,
+  key        varchar(255) NOT NULL CHECK (key <> ''),
+  val        varchar(255) NOT NULL CHECK (val <> ''),
+  peer_id    varchar(100) NOT NULL REFERENCES peers (id) ON DELETE CASCADE,
+  created_at timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, peer_id)
+);
+
+CREATE INDEX IF NOT EXISTS peer_labels_peer_id_key_val ON peer_labels (peer_id, key, val);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS revision_labels;
+DROP TABLE IF EXISTS revisions;
+
+COMMIT;
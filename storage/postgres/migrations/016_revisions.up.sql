@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TABLE revisions
+(
+  id            varchar(100) PRIMARY KEY,
+  resource_id   varchar(100) NOT NULL,
+  resource_type varchar(100) NOT NULL,
+  operation     varchar(100) NOT NULL,
+  username      varchar(255) NOT NULL DEFAULT '',
+  payload       json         NOT NULL DEFAULT '{}',
+  created_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the history of a resource is listed by its id in the order of creation
+CREATE INDEX revisions_resource_id_created_at ON revisions (resource_id, created_at);
+
+CREATE TABLE revision_labels
+(
+  id          varchar(100) PRIMARY KEY,
+  key         varchar(255) NOT NULL CHECK (key <> ''),
+  val         varchar(255) NOT NULL CHECK (val <> ''),
+  revision_id varchar(100) NOT NULL REFERENCES revisions (id) ON DELETE CASCADE,
+  created_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, revision_id)
+);
+
+CREATE INDEX IF NOT EXISTS revision_labels_revision_id_key_val ON revision_labels (revision_id, key, val);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE visibilities DROP COLUMN IF EXISTS valid_from;
+ALTER TABLE visibilities DROP COLUMN IF EXISTS valid_until;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS valid_from timestamp;
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS valid_until timestamp;
+
+COMMIT;
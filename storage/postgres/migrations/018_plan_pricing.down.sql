@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE service_plans DROP COLUMN IF EXISTS price_amount;
+ALTER TABLE service_plans DROP COLUMN IF EXISTS price_currency;
+ALTER TABLE service_plans DROP COLUMN IF EXISTS price_unit;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS price_amount numeric;
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS price_currency varchar(3);
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS price_unit varchar(255);
+
+COMMIT;
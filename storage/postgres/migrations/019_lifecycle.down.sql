@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE service_offerings DROP COLUMN IF EXISTS lifecycle;
+ALTER TABLE service_plans DROP COLUMN IF EXISTS lifecycle;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE service_offerings ADD COLUMN IF NOT EXISTS lifecycle varchar(255);
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS lifecycle varchar(255);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE service_plans DROP COLUMN IF EXISTS maintenance_info_version;
+ALTER TABLE service_plans DROP COLUMN IF EXISTS maintenance_info_description;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS maintenance_info_version varchar(255);
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS maintenance_info_description text;
+
+COMMIT;
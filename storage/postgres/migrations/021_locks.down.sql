@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS lock_labels;
+DROP TABLE IF EXISTS locks;
+
+COMMIT;
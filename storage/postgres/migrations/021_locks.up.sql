@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE locks
+(
+  id            varchar(100) PRIMARY KEY,
+  resource_id   varchar(100) NOT NULL,
+  resource_type varchar(100) NOT NULL,
+  owner         varchar(255) NOT NULL DEFAULT '',
+  reason        text         NOT NULL,
+  expires_at    timestamp    NOT NULL,
+  created_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- a resource has at most one lock, an expired lock is replaced when the resource is locked again
+  UNIQUE (resource_type, resource_id)
+);
+
+CREATE TABLE lock_labels
+(
+  id         varchar(100) PRIMARY KEY,
+  key        varchar(255) NOT NULL CHECK (key <> ''),
+  val        varchar(255) NOT NULL CHECK (val <> ''),
+  lock_id    varchar(100) NOT NULL REFERENCES locks (id) ON DELETE CASCADE,
+  created_at timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, lock_id)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS tenant_key_labels;
+DROP TABLE IF EXISTS tenant_keys;
+
+COMMIT;
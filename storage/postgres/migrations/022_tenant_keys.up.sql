@@ -0,0 +1,25 @@
+BEGIN;
+
+-- the id of a tenant key is the tenant
+CREATE TABLE tenant_keys
+(
+  id          varchar(100) PRIMARY KEY,
+  provider    varchar(100) NOT NULL,
+  key_ref     text         NOT NULL,
+  wrapped_key bytea        NOT NULL,
+  created_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE tenant_key_labels
+(
+  id            varchar(100) PRIMARY KEY,
+  key           varchar(255) NOT NULL CHECK (key <> ''),
+  val           varchar(255) NOT NULL CHECK (val <> ''),
+  tenant_key_id varchar(100) NOT NULL REFERENCES tenant_keys (id) ON DELETE CASCADE,
+  created_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at    timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, tenant_key_id)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS visibility_policy_labels;
+DROP TABLE IF EXISTS visibility_policies;
+
+COMMIT;
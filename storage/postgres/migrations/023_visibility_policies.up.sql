@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TABLE visibility_policies
+(
+  id                varchar(100) PRIMARY KEY NOT NULL,
+  name              varchar(255) NOT NULL UNIQUE,
+  description       text,
+  plan_selector     text         NOT NULL,
+  platform_selector text         NOT NULL,
+  created_at        timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at        timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE visibility_policy_labels
+(
+  id                   varchar(100) PRIMARY KEY,
+  key                  varchar(255) NOT NULL CHECK (key <> ''),
+  val                  varchar(255) NOT NULL CHECK (val <> ''),
+  visibility_policy_id varchar(100) NOT NULL REFERENCES visibility_policies (id) ON DELETE CASCADE,
+  created_at           timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at           timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, visibility_policy_id)
+);
+
+COMMIT;
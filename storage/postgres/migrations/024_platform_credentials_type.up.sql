@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS credentials_type varchar(255) NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE brokers DROP COLUMN IF EXISTS catalog_last_modified;
+ALTER TABLE brokers DROP COLUMN IF EXISTS catalog_etag;
+
+COMMIT;
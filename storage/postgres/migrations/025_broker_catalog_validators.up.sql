@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN IF NOT EXISTS catalog_etag varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN IF NOT EXISTS catalog_last_modified varchar(255) NOT NULL DEFAULT '';
+
+COMMIT;
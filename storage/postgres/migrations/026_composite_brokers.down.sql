@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS composite_broker_labels;
+DROP TABLE IF EXISTS composite_brokers;
+
+COMMIT;
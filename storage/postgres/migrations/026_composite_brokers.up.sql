@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TABLE composite_brokers
+(
+  id          varchar(100) PRIMARY KEY NOT NULL,
+  name        varchar(255) NOT NULL UNIQUE,
+  description text,
+  plan_ids    json         NOT NULL DEFAULT '[]',
+  created_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE composite_broker_labels
+(
+  id                  varchar(100) PRIMARY KEY,
+  key                 varchar(255) NOT NULL CHECK (key <> ''),
+  val                 varchar(255) NOT NULL CHECK (val <> ''),
+  composite_broker_id varchar(100) NOT NULL REFERENCES composite_brokers (id) ON DELETE CASCADE,
+  created_at          timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at          timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, composite_broker_id)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS saved_query_labels;
+DROP TABLE IF EXISTS saved_queries;
+
+COMMIT;
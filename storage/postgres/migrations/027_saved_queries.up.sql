@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE saved_queries
+(
+  id          varchar(100) PRIMARY KEY NOT NULL,
+  name        varchar(255) NOT NULL,
+  field_query text,
+  label_query text,
+  order_by    varchar(255),
+  order_type  varchar(10),
+  owner       varchar(255),
+  created_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at  timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- shared queries have no owner, the names are unique per owner and among the shared queries
+CREATE UNIQUE INDEX saved_queries_name_owner ON saved_queries (name, COALESCE(owner, ''));
+
+CREATE TABLE saved_query_labels
+(
+  id             varchar(100) PRIMARY KEY,
+  key            varchar(255) NOT NULL CHECK (key <> ''),
+  val            varchar(255) NOT NULL CHECK (val <> ''),
+  saved_query_id varchar(100) NOT NULL REFERENCES saved_queries (id) ON DELETE CASCADE,
+  created_at     timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at     timestamp    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, saved_query_id)
+);
+
+COMMIT;
@@ -0,0 +1,407 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/cache"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// notificationsBusChannel is the channel of the message bus to which the notifications are relayed
+	notificationsBusChannel = "notificator:notifications"
+
+	// instancesBusChannel is the channel of the message bus on which the instances announce their number of consumers
+	instancesBusChannel = "notificator:instances"
+
+	// relayLeaseKey is the key of the lease of the instance relaying the notifications to the message bus
+	relayLeaseKey = "notificator:relay"
+
+	// internalConsumerIDPrefix is the prefix of the consumers of the Service Manager itself, e.g. its caches, which are
+	// not moved to other instances when rebalancing
+	internalConsumerIDPrefix = "service-manager-"
+)
+
+// busMessage is a message relayed to the message bus. A message without notification announces that notifications
+// may have been missed, e.g. because the relaying instance changed, so that the consumers have to reconnect.
+type busMessage struct {
+	Notification *types.Notification `json:"notification,omitempty"`
+}
+
+// instanceLoad is the number of consumers announced by an instance
+type instanceLoad struct {
+	InstanceID string `json:"instance_id"`
+	Consumers  int    `json:"consumers"`
+}
+
+func (n *Notificator) startWithMessageBus(ctx context.Context, group *sync.WaitGroup) error {
+	atomic.StoreInt32(&n.isConnected, aTrue)
+
+	relay := &notificationRelay{
+		instanceID:        n.instanceID,
+		bus:               n.bus,
+		leases:            n.leases,
+		leaseTTL:          n.busSettings.RelayLeaseTTL,
+		storage:           n.storage,
+		connectionCreator: n.connectionCreator,
+	}
+	util.StartInWaitGroupWithContext(ctx, relay.run, group)
+	if n.busSettings.RebalanceInterval > 0 {
+		util.StartInWaitGroupWithContext(ctx, n.rebalance, group)
+	}
+	util.StartInWaitGroupWithContext(ctx, func(c context.Context) {
+		<-c.Done()
+		log.C(c).Info("context cancelled, stopping Notificator...")
+		if err := n.stopListening(); err != nil {
+			log.C(c).WithError(err).Info("could not unsubscribe from message bus")
+		}
+	}, group)
+	return nil
+}
+
+// subscribe subscribes to the notifications relayed to the message bus. It must be called with the connection mutex.
+func (n *Notificator) subscribe() error {
+	subscription, err := n.bus.Subscribe(n.ctx, notificationsBusChannel)
+	if err != nil {
+		return err
+	}
+	lastKnownRevision, err := n.storage.GetLastRevision(n.ctx)
+	if err != nil {
+		if errClose := subscription.Close(); errClose != nil {
+			log.C(n.ctx).WithError(errClose).Error("could not unsubscribe from message bus")
+		}
+		return err
+	}
+	atomic.StoreInt64(&n.lastKnownRevision, lastKnownRevision)
+	atomic.StoreInt32(&n.isListening, aTrue)
+	n.subscription = subscription
+	go n.processBusMessages(subscription)
+	return nil
+}
+
+// unsubscribe ends the subscription to the message bus. It must be called with the connection mutex.
+func (n *Notificator) unsubscribe() error {
+	subscription := n.subscription
+	n.subscription = nil
+	atomic.StoreInt32(&n.isListening, aFalse)
+	if subscription == nil {
+		return nil
+	}
+	return subscription.Close()
+}
+
+func (n *Notificator) processBusMessages(subscription cache.Subscription) {
+	for data := range subscription.Messages() {
+		message := &busMessage{}
+		if err := json.Unmarshal(data, message); err != nil {
+			log.C(n.ctx).WithError(err).Error("could not unmarshal message bus notification")
+			n.closeAllConsumers() // Ensures no notifications are lost
+			continue
+		}
+		if message.Notification == nil {
+			log.C(n.ctx).Info("notifications may have been missed by the message bus, closing consumers")
+			n.resetConsumers()
+			continue
+		}
+		n.processBusNotification(message.Notification)
+	}
+
+	// the subscription ended without being closed by the Notificator, e.g. because the connection was lost
+	n.connectionMutex.Lock()
+	lost := n.subscription == subscription
+	if lost {
+		n.subscription = nil
+		atomic.StoreInt32(&n.isListening, aFalse)
+	}
+	n.connectionMutex.Unlock()
+	if lost {
+		log.C(n.ctx).Info("message bus subscription lost, closing all consumers")
+		n.closeAllConsumers()
+	}
+}
+
+func (n *Notificator) processBusNotification(notification *types.Notification) {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+	atomic.StoreInt64(&n.lastKnownRevision, notification.Revision)
+
+	recipients := n.getRecipients(notification.PlatformID)
+	if len(recipients) == 0 {
+		return
+	}
+	n.sendNotificationToRecipients(recipients, notification)
+}
+
+// resetConsumers closes all consumers after notifications may have been missed. The last known revision is read from
+// the storage again, so that reconnecting consumers receive the missed notifications from the storage.
+func (n *Notificator) resetConsumers() {
+	lastKnownRevision, err := n.storage.GetLastRevision(n.ctx)
+	if err != nil {
+		log.C(n.ctx).WithError(err).Error("could not get last notification revision")
+	} else {
+		atomic.StoreInt64(&n.lastKnownRevision, lastKnownRevision)
+	}
+	n.closeAllConsumers()
+}
+
+// rebalance announces the number of consumers of this instance on the message bus and disconnects consumers while
+// this instance has more than its share, so that they reconnect to other instances, e.g. after the instances were
+// scaled up. At most a batch of consumers is disconnected per interval, so that they do not reconnect at once.
+func (n *Notificator) rebalance(ctx context.Context) {
+	interval := n.busSettings.RebalanceInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	peers := make(map[string]instanceLoad)
+	lastSeen := make(map[string]time.Time)
+	var messages <-chan []byte
+	var subscription cache.Subscription
+	defer func() {
+		if subscription != nil {
+			subscription.Close()
+		}
+	}()
+
+	for {
+		if subscription == nil {
+			var err error
+			if subscription, err = n.bus.Subscribe(ctx, instancesBusChannel); err != nil {
+				log.C(ctx).WithError(err).Debug("could not subscribe to instance announcements")
+				subscription = nil
+			} else {
+				messages = subscription.Messages()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-messages:
+			if !ok {
+				subscription, messages = nil, nil
+				continue
+			}
+			load := instanceLoad{}
+			if err := json.Unmarshal(data, &load); err != nil || load.InstanceID == n.instanceID {
+				continue
+			}
+			peers[load.InstanceID] = load
+			lastSeen[load.InstanceID] = time.Now()
+		case <-ticker.C:
+			for instanceID, seen := range lastSeen {
+				if time.Since(seen) > 3*interval {
+					delete(peers, instanceID)
+					delete(lastSeen, instanceID)
+				}
+			}
+
+			consumers := n.externalConsumers()
+			n.announce(ctx, len(consumers))
+			total := len(consumers)
+			for _, peer := range peers {
+				total += peer.Consumers
+			}
+			excess := len(consumers) - share(total, len(peers)+1)
+			if excess <= 1 {
+				continue
+			}
+			if excess > n.busSettings.RebalanceBatchSize {
+				excess = n.busSettings.RebalanceBatchSize
+			}
+			log.C(ctx).Infof("Disconnecting %d of %d consumers to rebalance them between %d instances", excess, len(consumers), len(peers)+1)
+			n.disconnectConsumers(consumers[:excess])
+		}
+	}
+}
+
+func (n *Notificator) announce(ctx context.Context, consumers int) {
+	data, err := json.Marshal(instanceLoad{InstanceID: n.instanceID, Consumers: consumers})
+	if err != nil {
+		return
+	}
+	if err := n.bus.Publish(ctx, instancesBusChannel, data); err != nil {
+		log.C(ctx).WithError(err).Debug("could not announce number of consumers")
+	}
+}
+
+// externalConsumers returns the queues of the consumers which are not part of the Service Manager itself
+func (n *Notificator) externalConsumers() []storage.NotificationQueue {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+
+	result := make([]storage.NotificationQueue, 0)
+	for platformID, queues := range n.consumers.queues {
+		if !strings.HasPrefix(platformID, internalConsumerIDPrefix) {
+			result = append(result, queues...)
+		}
+	}
+	return result
+}
+
+// disconnectConsumers closes the queues of the consumers, which makes them reconnect
+func (n *Notificator) disconnectConsumers(queues []storage.NotificationQueue) {
+	n.consumersMutex.Lock()
+	defer n.consumersMutex.Unlock()
+
+	for _, queue := range queues {
+		n.consumers.Delete(queue)
+		queue.Close()
+	}
+}
+
+// share returns the number of consumers each instance should have, rounded up
+func share(consumers, instances int) int {
+	return (consumers + instances - 1) / instances
+}
+
+// notificationRelay listens to the notifications of the storage and publishes them to the message bus while the
+// instance holds the relay lease
+type notificationRelay struct {
+	instanceID        string
+	bus               cache.PubSub
+	leases            cache.Store
+	leaseTTL          time.Duration
+	storage           notificationStorage
+	connectionCreator notificationConnectionCreator
+}
+
+func (r *notificationRelay) run(ctx context.Context) {
+	ticker := time.NewTicker(r.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		acquired, err := r.leases.SetIfAbsent(ctx, relayLeaseKey, []byte(r.instanceID), r.leaseTTL)
+		if err != nil {
+			log.C(ctx).WithError(err).Debug("could not acquire notification relay lease")
+		}
+		if acquired {
+			log.C(ctx).Infof("Instance %s relays the notifications to the message bus", r.instanceID)
+			if err := r.relay(ctx); err != nil {
+				log.C(ctx).WithError(err).Warn("Stopped relaying notifications to the message bus")
+			}
+			r.releaseLease()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *notificationRelay) relay(ctx context.Context) error {
+	connection := r.connectionCreator.NewConnection(func(isConnected bool, err error) {
+		if !isConnected {
+			log.C(ctx).WithError(err).Info("notification relay connection to db closed")
+		}
+	})
+	defer func() {
+		if err := connection.Close(); err != nil {
+			log.C(ctx).WithError(err).Info("could not close notification relay db connection")
+		}
+	}()
+	if err := connection.Listen(postgresChannel); err != nil {
+		return err
+	}
+
+	// the notifications sent before this instance started relaying may have been missed
+	if err := r.publish(ctx, &busMessage{}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(r.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.renewLease(ctx); err != nil {
+				return err
+			}
+		case pqNotification, ok := <-connection.NotificationChannel():
+			if !ok {
+				return errors.New("notification relay db connection closed")
+			}
+			// a nil notification is received after the connection was reestablished
+			message := &busMessage{}
+			if pqNotification != nil {
+				notification, err := r.notification(ctx, pqNotification.Extra)
+				if err != nil {
+					log.C(ctx).WithError(err).Error("could not relay notification")
+				}
+				message.Notification = notification
+			}
+			if err := r.publish(ctx, message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *notificationRelay) notification(ctx context.Context, data string) (*types.Notification, error) {
+	payload, err := getPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.storage.GetNotification(ctx, payload.NotificationID)
+}
+
+func (r *notificationRelay) publish(ctx context.Context, message *busMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(ctx, notificationsBusChannel, data)
+}
+
+// renewLease extends the relay lease. Another instance may acquire the lease between checking and extending it if
+// it expired in between, which is prevented by renewing it well before it expires.
+func (r *notificationRelay) renewLease(ctx context.Context) error {
+	holder, found, err := r.leases.Get(ctx, relayLeaseKey)
+	if err != nil {
+		return err
+	}
+	if !found || string(holder) != r.instanceID {
+		return errors.New("notification relay lease lost")
+	}
+	return r.leases.Set(ctx, relayLeaseKey, []byte(r.instanceID), r.leaseTTL)
+}
+
+// releaseLease lets another instance take over relaying notifications without waiting for the lease to expire
+func (r *notificationRelay) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	holder, found, err := r.leases.Get(ctx, relayLeaseKey)
+	if err != nil || !found || string(holder) != r.instanceID {
+		return
+	}
+	if err := r.leases.Delete(ctx, relayLeaseKey); err != nil {
+		log.C(ctx).WithError(err).Debug("could not release notification relay lease")
+	}
+}
@@ -24,12 +24,14 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/Peripli/service-manager/pkg/cache"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
 	notificationConnection "github.com/Peripli/service-manager/storage/postgres/notification_connection"
 
+	"github.com/gofrs/uuid"
 	"github.com/lib/pq"
 )
 
@@ -58,6 +60,14 @@ type Notificator struct {
 	ctx context.Context
 
 	lastKnownRevision int64
+
+	// instanceID identifies the instance among the instances connected to the message bus
+	instanceID string
+	// bus distributes the notifications between the instances, every instance listens to the storage if it is nil
+	bus          cache.PubSub
+	leases       cache.Store
+	subscription cache.Subscription
+	busSettings  *storage.NotificationSettings
 }
 
 // NewNotificator returns new Notificator based on a given NotificatorStorage and desired queue size
@@ -72,7 +82,14 @@ func NewNotificator(st storage.Storage, settings *storage.Settings) (*Notificato
 		return nil, err
 	}
 
+	instanceID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for notificator: %s", err)
+	}
+
 	return &Notificator{
+		instanceID:      instanceID.String(),
+		busSettings:     settings.Notification,
 		queueSize:       settings.Notification.QueuesSize,
 		connectionMutex: &sync.Mutex{},
 		consumersMutex:  &sync.Mutex{},
@@ -86,12 +103,23 @@ func NewNotificator(st storage.Storage, settings *storage.Settings) (*Notificato
 	}, nil
 }
 
+// UseMessageBus makes the Notificator receive the notifications through the message bus instead of listening to the
+// storage. The instance holding the relay lease in the store listens to the storage and publishes the notifications
+// to the message bus. It must be called before the Notificator is started.
+func (n *Notificator) UseMessageBus(bus cache.PubSub, leases cache.Store) {
+	n.bus = bus
+	n.leases = leases
+}
+
 // Start starts the Notificator. It must not be called concurrently.
 func (n *Notificator) Start(ctx context.Context, group *sync.WaitGroup) error {
 	if n.ctx != nil {
 		return errors.New("notificator already started")
 	}
 	n.ctx = ctx
+	if n.bus != nil {
+		return n.startWithMessageBus(ctx, group)
+	}
 	n.setConnection(n.connectionCreator.NewConnection(func(isConnected bool, err error) {
 		if isConnected {
 			atomic.StoreInt32(&n.isConnected, aTrue)
@@ -302,11 +330,15 @@ func (n *Notificator) processNotificationPayload(payload *notifyEventPayload) er
 	if err != nil {
 		return fmt.Errorf("notification %s could not be retrieved from the DB: %v", notificationID, err.Error())
 	}
+	n.sendNotificationToRecipients(recipients, notification)
+	return nil
+}
+
+func (n *Notificator) sendNotificationToRecipients(recipients []*types.Platform, notification *types.Notification) {
 	recipients = n.filterRecipients(recipients, notification)
 	for _, platform := range recipients {
 		n.sendNotificationToPlatformConsumers(n.consumers.GetQueuesForPlatform(platform.ID), notification)
 	}
-	return nil
 }
 
 func (n *Notificator) getRecipients(platformID string) []*types.Platform {
@@ -348,6 +380,9 @@ func (n *Notificator) stopListening() error {
 	if atomic.LoadInt32(&n.isListening) == aFalse {
 		return nil
 	}
+	if n.bus != nil {
+		return n.unsubscribe()
+	}
 	return n.connection.Unlisten(postgresChannel)
 }
 
@@ -357,6 +392,9 @@ func (n *Notificator) startListening() error {
 	if atomic.LoadInt32(&n.isListening) == aTrue {
 		return nil
 	}
+	if n.bus != nil {
+		return n.subscribe()
+	}
 	err := n.connection.Listen(postgresChannel)
 	if err != nil {
 		return err
@@ -20,10 +20,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 
+	"github.com/Peripli/service-manager/pkg/cache"
 	"github.com/Peripli/service-manager/pkg/util"
 
 	notificationConnection "github.com/Peripli/service-manager/storage/postgres/notification_connection"
@@ -521,4 +524,102 @@ var _ = Describe("Notificator", func() {
 			})
 		})
 	})
+
+	Describe("Message bus", func() {
+		var (
+			bus         *cache.MemoryStore
+			busSettings *storage.NotificationSettings
+		)
+
+		newBusNotificator := func(instanceID string) *Notificator {
+			notificator := newNotificator(defaultQueueSize).(*Notificator)
+			notificator.instanceID = instanceID
+			notificator.busSettings = busSettings
+			notificator.UseMessageBus(bus, bus)
+			return notificator
+		}
+
+		BeforeEach(func() {
+			bus = cache.NewMemoryStore()
+			busSettings = storage.DefaultNotificationSettings()
+			busSettings.MessageBus = storage.RedisMessageBus
+			busSettings.RebalanceInterval = 0
+		})
+
+		JustBeforeEach(func() {
+			// the relay announces that notifications may have been missed before it started relaying
+			probe, err := bus.Subscribe(ctx, notificationsBusChannel)
+			Expect(err).ToNot(HaveOccurred())
+			testNotificator = newBusNotificator("instance-1")
+			Expect(testNotificator.Start(ctx, wg)).To(Succeed())
+			Eventually(probe.Messages()).Should(Receive())
+			Expect(probe.Close()).To(Succeed())
+		})
+
+		It("relays the notifications of the storage to the consumers", func() {
+			q := registerDefaultPlatform()
+			notification := createNotification(defaultPlatform.ID)
+			notification.Payload = json.RawMessage(`{}`)
+			fakeStorage.GetNotificationReturns(notification, nil)
+			notificationChannel <- &pq.Notification{
+				Extra: createNotificationPayload(defaultPlatform.ID, notification.ID),
+			}
+			expectReceivedNotification(notification, q)
+		})
+
+		It("relays the notifications from a single instance", func() {
+			Expect(newBusNotificator("instance-2").Start(ctx, wg)).To(Succeed())
+			Consistently(fakeConnectionCreator.NewConnectionCallCount, 100*time.Millisecond).Should(Equal(1))
+		})
+
+		It("closes the consumers when notifications may have been missed", func() {
+			q := registerDefaultPlatform()
+			lastRevisionCalls := fakeStorage.GetLastRevisionCallCount()
+			Expect(bus.Publish(ctx, notificationsBusChannel, []byte("{}"))).To(Succeed())
+			_, ok := <-q.Channel()
+			Expect(ok).To(BeFalse())
+			Expect(fakeStorage.GetLastRevisionCallCount()).To(BeNumerically(">", lastRevisionCalls))
+		})
+
+		It("closes the consumers when the subscription is lost", func() {
+			q := registerDefaultPlatform()
+			Expect(testNotificator.(*Notificator).subscription.Close()).To(Succeed())
+			_, ok := <-q.Channel()
+			Expect(ok).To(BeFalse())
+		})
+
+		Context("when another instance has fewer consumers", func() {
+			BeforeEach(func() {
+				busSettings.RebalanceInterval = 20 * time.Millisecond
+			})
+
+			It("disconnects the consumers above its share", func() {
+				queues := make([]storage.NotificationQueue, 0, 4)
+				for i := 0; i < 4; i++ {
+					platform := &types.Platform{Base: types.Base{ID: fmt.Sprintf("platform-%d", i)}}
+					queues = append(queues, expectRegisterConsumerSuccess(platform, types.InvalidRevision))
+				}
+				closed := func() int {
+					Expect(bus.Publish(ctx, instancesBusChannel, []byte(`{"instance_id":"instance-2","consumers":0}`))).To(Succeed())
+					count := 0
+					for _, queue := range queues {
+						select {
+						case _, ok := <-queue.Channel():
+							if !ok {
+								count++
+							}
+						default:
+						}
+					}
+					return count
+				}
+				Eventually(closed).Should(Equal(2))
+			})
+		})
+
+		It("computes the share of the instances", func() {
+			Expect(share(10, 3)).To(Equal(4))
+			Expect(share(0, 2)).To(Equal(0))
+		})
+	})
 })
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+)
+
+// Operation entity
+//go:generate smgen storage operation github.com/Peripli/service-manager/pkg/types:Operation
+type Operation struct {
+	BaseEntity
+	Type         string             `db:"type"`
+	State        string             `db:"state"`
+	ResourceID   string             `db:"resource_id"`
+	ResourceType string             `db:"resource_type"`
+	Description  string             `db:"description"`
+	Errors       sqlxtypes.JSONText `db:"errors"`
+}
+
+func (o *Operation) ToObject() types.Object {
+	return &types.Operation{
+		Base: types.Base{
+			ID:        o.ID,
+			CreatedAt: o.CreatedAt,
+			UpdatedAt: o.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Type:         types.OperationCategory(o.Type),
+		State:        types.OperationState(o.State),
+		ResourceID:   o.ResourceID,
+		ResourceType: types.ObjectType(o.ResourceType),
+		Description:  o.Description,
+		Errors:       getJSONRawMessage(o.Errors),
+	}
+}
+
+func (*Operation) FromObject(object types.Object) (storage.Entity, bool) {
+	operation, ok := object.(*types.Operation)
+	if !ok {
+		return nil, false
+	}
+
+	o := &Operation{
+		BaseEntity: BaseEntity{
+			ID:        operation.ID,
+			CreatedAt: operation.CreatedAt,
+			UpdatedAt: operation.UpdatedAt,
+		},
+		Type:         string(operation.Type),
+		State:        string(operation.State),
+		ResourceID:   operation.ResourceID,
+		ResourceType: string(operation.ResourceType),
+		Description:  operation.Description,
+		Errors:       getJSONText(operation.Errors),
+	}
+	return o, true
+}
@@ -0,0 +1,78 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+//go:generate smgen storage peer github.com/Peripli/service-manager/pkg/types
+// Peer entity
+type Peer struct {
+	BaseEntity
+	Name        string         `db:"name"`
+	Description sql.NullString `db:"description"`
+	URL         string         `db:"url"`
+	Username    string         `db:"username"`
+	Password    string         `db:"password"`
+}
+
+func (p *Peer) FromObject(object types.Object) (storage.Entity, bool) {
+	peer, ok := object.(*types.Peer)
+	if !ok {
+		return nil, false
+	}
+	result := &Peer{
+		BaseEntity: BaseEntity{
+			ID:        peer.ID,
+			CreatedAt: peer.CreatedAt,
+			UpdatedAt: peer.UpdatedAt,
+		},
+		Name:        peer.Name,
+		Description: toNullString(peer.Description),
+		URL:         peer.URL,
+	}
+	if peer.Credentials != nil && peer.Credentials.Basic != nil {
+		result.Username = peer.Credentials.Basic.Username
+		result.Password = peer.Credentials.Basic.Password
+	}
+	return result, true
+}
+
+func (p *Peer) ToObject() types.Object {
+	return &types.Peer{
+		Base: types.Base{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Name:        p.Name,
+		Description: p.Description.String,
+		URL:         p.URL,
+		Credentials: &types.Credentials{
+			Basic: &types.Basic{
+				Username: p.Username,
+				Password: p.Password,
+			},
+		},
+	}
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &Peer{}
+
+const PeerTable = "peers"
+
+func (*Peer) LabelEntity() PostgresLabel {
+	return &PeerLabel{}
+}
+
+func (*Peer) TableName() string {
+	return PeerTable
+}
+
+func (e *Peer) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &PeerLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		PeerID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *Peer) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*Peer
+			PeerLabel `db:"peer_labels"`
+		}{}
+	}
+	result := &types.Peers{
+		Peers: make([]*types.Peer, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type PeerLabel struct {
+	BaseLabelEntity
+	PeerID sql.NullString `db:"peer_id"`
+}
+
+func (el PeerLabel) LabelsTableName() string {
+	return "peer_labels"
+}
+
+func (el PeerLabel) ReferenceColumn() string {
+	return "peer_id"
+}
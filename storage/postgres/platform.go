@@ -33,6 +33,8 @@ type Platform struct {
 	Description sql.NullString `db:"description"`
 	Username    string         `db:"username"`
 	Password    string         `db:"password"`
+
+	CredentialsType string `db:"credentials_type"`
 }
 
 func (p *Platform) FromObject(object types.Object) (storage.Entity, bool) {
@@ -46,9 +48,10 @@ func (p *Platform) FromObject(object types.Object) (storage.Entity, bool) {
 			CreatedAt: platform.CreatedAt,
 			UpdatedAt: platform.UpdatedAt,
 		},
-		Type:        platform.Type,
-		Name:        platform.Name,
-		Description: toNullString(platform.Description),
+		Type:            platform.Type,
+		Name:            platform.Name,
+		Description:     toNullString(platform.Description),
+		CredentialsType: platform.CredentialsType,
 	}
 
 	if platform.Description != "" {
@@ -77,5 +80,6 @@ func (p *Platform) ToObject() types.Object {
 				Password: p.Password,
 			},
 		},
+		CredentialsType: p.CredentialsType,
 	}
 }
@@ -2,10 +2,14 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/jmoiron/sqlx"
@@ -29,26 +33,35 @@ func (qsb *queryStringBuilder) Replace(old, new string) {
 
 // QueryBuilder is used to construct new queries. It is safe for concurrent usage
 type QueryBuilder struct {
-	db pgDB
+	db          PgDB
+	slowQueries *SlowQueryLogger
 }
 
 // NewQueryBuilder constructs new query builder for the current db
-func NewQueryBuilder(db pgDB) *QueryBuilder {
+func NewQueryBuilder(db PgDB) *QueryBuilder {
 	return &QueryBuilder{
 		db: db,
 	}
 }
 
+// WithSlowQueryLogger reports the queries constructed by the query builder to the provided slow query logger
+func (qb *QueryBuilder) WithSlowQueryLogger(logger *SlowQueryLogger) *QueryBuilder {
+	qb.slowQueries = logger
+	return qb
+}
+
 // NewQuery constructs new queries for the current query builder db
 func (qb *QueryBuilder) NewQuery() *pgQuery {
 	return &pgQuery{
-		db: qb.db,
+		db:          qb.db,
+		slowQueries: qb.slowQueries,
 	}
 }
 
 // pgQuery is used to construct postgres queries. It should be constructed only via the query builder. It is not safe for concurrent use.
 type pgQuery struct {
-	db          pgDB
+	db          PgDB
+	slowQueries *SlowQueryLogger
 	sql         queryStringBuilder
 	queryParams []interface{}
 
@@ -57,6 +70,7 @@ type pgQuery struct {
 	limit                        string
 	criteria                     []query.Criterion
 	hasLock                      bool
+	hasSubQuery                  bool
 	returningFields              []string
 
 	err error
@@ -74,6 +88,7 @@ func (pgq *pgQuery) List(ctx context.Context, entity PostgresEntity) (*sqlx.Rows
 		return nil, err
 	}
 
+	defer pgq.observe(ctx, time.Now())
 	return pgq.db.QueryxContext(ctx, pgq.sql.String(), pgq.queryParams...)
 }
 
@@ -82,17 +97,61 @@ func (pgq *pgQuery) Delete(ctx context.Context, entity PostgresEntity) (*sqlx.Ro
 		return nil, pgq.err
 	}
 	baseTableName := entity.TableName()
-	pgq.sql.WriteString(fmt.Sprintf("DELETE FROM %s", baseTableName))
 	for len(pgq.labelCriteria) > 0 {
 		return nil, &util.UnsupportedQueryError{Message: "conditional delete is only supported for field queries"}
 	}
+	if len(pgq.orderByFields) > 0 || len(pgq.limit) > 0 {
+		// DELETE does not support ORDER BY and LIMIT so the rows to delete are selected in a sub query
+		pgq.sql.WriteString(fmt.Sprintf("DELETE FROM %[1]s WHERE %[1]s.id IN (SELECT %[1]s.id FROM %[1]s", baseTableName))
+		pgq.hasSubQuery = true
+	} else {
+		pgq.sql.WriteString(fmt.Sprintf("DELETE FROM %s", baseTableName))
+	}
 
 	if err := pgq.finalizeSQL(entity); err != nil {
 		return nil, err
 	}
+	defer pgq.observe(ctx, time.Now())
 	return pgq.db.QueryxContext(ctx, pgq.sql.String(), pgq.queryParams...)
 }
 
+// Update updates the rows matching the criteria with the values of the entity fields. Fields with nil values are not updated.
+// If all fields are nil, nothing is executed and the result is nil.
+func (pgq *pgQuery) Update(ctx context.Context, entity PostgresEntity) (sql.Result, error) {
+	if pgq.err != nil {
+		return nil, pgq.err
+	}
+	if len(pgq.labelCriteria) > 0 {
+		return nil, &util.UnsupportedQueryError{Message: "conditional update is only supported for field queries"}
+	}
+	if len(pgq.orderByFields) > 0 || len(pgq.limit) > 0 {
+		return nil, &util.UnsupportedQueryError{Message: "order by and limit are not supported for update"}
+	}
+
+	dbTags := getDBTags(entity, isAutoIncrementable)
+	if len(dbTags) == 0 {
+		log.C(ctx).Debugf("%s update: Nothing to update", entity.TableName())
+		return nil, nil
+	}
+	set := make([]string, 0, len(dbTags))
+	for _, dbTag := range dbTags {
+		set = append(set, fmt.Sprintf("%s = ?", dbTag.Tag))
+		pgq.queryParams = append(pgq.queryParams, dbTag.Value)
+	}
+	pgq.sql.WriteString(fmt.Sprintf("UPDATE %s SET %s", entity.TableName(), strings.Join(set, ", ")))
+
+	if err := pgq.finalizeSQL(entity); err != nil {
+		return nil, err
+	}
+	defer pgq.observe(ctx, time.Now())
+	return pgq.db.ExecContext(ctx, pgq.sql.String(), pgq.queryParams...)
+}
+
+// observe reports the duration of the query started at the provided time to the slow query logger
+func (pgq *pgQuery) observe(ctx context.Context, started time.Time) {
+	pgq.slowQueries.observe(ctx, time.Since(started), pgq.sql.String(), pgq.queryParams, pgq.criteria)
+}
+
 func (pgq *pgQuery) Return(fields ...string) *pgQuery {
 	pgq.returningFields = append(pgq.returningFields, fields...)
 
@@ -139,11 +198,16 @@ func (pgq *pgQuery) finalizeSQL(entity PostgresEntity) error {
 		return err
 	}
 
+	// identical criteria in a different order must produce identical SQL, e.g. for the statement caches of the database
+	pgq.labelCriteria = query.Normalize(pgq.labelCriteria)
+	pgq.fieldCriteria = query.Normalize(pgq.fieldCriteria)
+
 	pgq.labelCriteriaSQL(entity, pgq.labelCriteria).
 		fieldCriteriaSQL(entity, pgq.fieldCriteria).
 		orderBySQL().
 		limitSQL().
 		lockSQL(entity.TableName()).
+		subQueryEndSQL().
 		returningSQL().
 		expandMultivariateOp()
 
@@ -172,7 +236,20 @@ func (pgq *pgQuery) orderBySQL() *pgQuery {
 
 func (pgq *pgQuery) limitSQL() *pgQuery {
 	if len(pgq.limit) > 0 {
-		pgq.sql.WriteString(fmt.Sprintf(" LIMIT %s", pgq.limit))
+		limit, err := strconv.Atoi(pgq.limit)
+		if err != nil {
+			pgq.err = &util.UnsupportedQueryError{Message: fmt.Sprintf("limit (%s) is invalid: %s", pgq.limit, err)}
+			return pgq
+		}
+		pgq.sql.WriteString(" LIMIT ?")
+		pgq.queryParams = append(pgq.queryParams, limit)
+	}
+	return pgq
+}
+
+func (pgq *pgQuery) subQueryEndSQL() *pgQuery {
+	if pgq.hasSubQuery {
+		pgq.sql.WriteString(")")
 	}
 	return pgq
 }
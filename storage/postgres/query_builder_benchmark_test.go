@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/storage/postgres"
+	"github.com/Peripli/service-manager/storage/postgres/postgresfakes"
+)
+
+func BenchmarkListQueryWithLabelCriteria(b *testing.B) {
+	db := &postgresfakes.FakePgDB{}
+	db.RebindStub = func(s string) string {
+		return s
+	}
+	db.QueryxContextReturns(&sqlx.Rows{}, nil)
+	qb := postgres.NewQueryBuilder(db)
+
+	criteria := make([]query.Criterion, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		criteria = append(criteria, query.ByLabel(query.EqualsOperator, fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)))
+	}
+	criteria = append(criteria, query.ByField(query.InOperator, "platform_id", "platform1", "platform2"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := qb.NewQuery().WithCriteria(criteria...).List(context.Background(), &postgres.Visibility{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkRowsToList(b *testing.B, platforms, labelsPerPlatform int) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mockdb.Close()
+	db := sqlx.NewDb(mockdb, "sqlmock")
+
+	columns := []string{"id", "type", "name", "description", "username", "password", "created_at", "updated_at",
+		"platform_labels.id", "platform_labels.key", "platform_labels.val", "platform_labels.platform_id",
+		"platform_labels.created_at", "platform_labels.updated_at"}
+	now := time.Now()
+	values := make([][]driver.Value, 0, platforms*labelsPerPlatform)
+	for p := 0; p < platforms; p++ {
+		platformID := fmt.Sprintf("platform-%d", p)
+		for l := 0; l < labelsPerPlatform; l++ {
+			values = append(values, []driver.Value{platformID, "type", platformID, "description", "user", "password", now, now,
+				fmt.Sprintf("%s-label-%d", platformID, l), fmt.Sprintf("key%d", l%10), fmt.Sprintf("value%d", l), platformID, now, now})
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		result := sqlmock.NewRows(columns)
+		for _, row := range values {
+			result.AddRow(row...)
+		}
+		mock.ExpectQuery("SELECT").WillReturnRows(result)
+		rows, err := db.QueryxContext(context.Background(), "SELECT")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		list, err := (&postgres.Platform{}).RowsToList(rows)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if list.Len() != platforms {
+			b.Fatalf("expected %d platforms but got %d", platforms, list.Len())
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkRowsToListWithThousandsOfLabels(b *testing.B) {
+	benchmarkRowsToList(b, 1, 5000)
+}
+
+func BenchmarkRowsToListWithManyEntities(b *testing.B) {
+	benchmarkRowsToList(b, 1000, 5)
+}
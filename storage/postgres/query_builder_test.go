@@ -123,8 +123,8 @@ var _ = Describe("Postgres Storage Query builder", func() {
 					WithCriteria(query.LimitResultBy(10)).
 					List(ctx, entity)
 				Expect(err).ShouldNot(HaveOccurred())
-				Expect(executedQuery).Should(MatchRegexp("SELECT.*FROM visibilities .* LIMIT 10;"))
-				Expect(queryArgs).To(HaveLen(0))
+				Expect(executedQuery).Should(MatchRegexp("SELECT.*FROM visibilities .* LIMIT \\?;"))
+				Expect(queryArgs).To(Equal([]interface{}{10}))
 			})
 
 			It("should build query with order by and limit clause", func() {
@@ -132,8 +132,8 @@ var _ = Describe("Postgres Storage Query builder", func() {
 					WithCriteria(query.LimitResultBy(10), query.OrderResultBy("id", query.AscOrder)).
 					List(ctx, entity)
 				Expect(err).ShouldNot(HaveOccurred())
-				Expect(executedQuery).Should(MatchRegexp("SELECT.*FROM visibilities .* ORDER BY id ASC LIMIT 10;"))
-				Expect(queryArgs).To(HaveLen(0))
+				Expect(executedQuery).Should(MatchRegexp("SELECT.*FROM visibilities .* ORDER BY id ASC LIMIT \\?;"))
+				Expect(queryArgs).To(Equal([]interface{}{10}))
 			})
 		})
 
@@ -199,6 +199,20 @@ var _ = Describe("Postgres Storage Query builder", func() {
 			})
 		})
 
+		Context("When limit and order by are used", func() {
+			It("Should select the rows to delete in a sub query", func() {
+				_, err := qb.NewQuery().
+					WithCriteria(query.ByField(query.LessThanOperator, "created_at", "2019-01-01T00:00:00Z"),
+						query.OrderResultBy("created_at", query.AscOrder),
+						query.LimitResultBy(100)).
+					Return("*").
+					Delete(ctx, entity)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(executedQuery).To(Equal("DELETE FROM visibilities WHERE visibilities.id IN (SELECT visibilities.id FROM visibilities WHERE visibilities.created_at < ? ORDER BY created_at ASC LIMIT ?) RETURNING *;"))
+				Expect(queryArgs).To(Equal([]interface{}{"2019-01-01T00:00:00Z", 100}))
+			})
+		})
+
 		Context("When criteria uses missing field", func() {
 			It("Should return error", func() {
 				criteria := query.ByField(query.EqualsOperator, "non-existing-field", "value")
@@ -207,4 +221,69 @@ var _ = Describe("Postgres Storage Query builder", func() {
 			})
 		})
 	})
+
+	Describe("Update", func() {
+		BeforeEach(func() {
+			entity = &postgres.Visibility{
+				BaseEntity: postgres.BaseEntity{
+					ID: "visibility-id",
+				},
+				ServicePlanID: "plan-id",
+			}
+		})
+
+		Context("When updating by field criteria", func() {
+			It("Should construct parameterized query", func() {
+				criteria := query.ByField(query.EqualsOperator, "id", "visibility-id")
+				_, err := qb.NewQuery().WithCriteria(criteria).Update(ctx, entity)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(executedQuery).To(MatchRegexp("^UPDATE visibilities SET id = \\?, .*service_plan_id = \\?.* WHERE visibilities.id::text = \\?;$"))
+				Expect(queryArgs[0]).To(Equal("visibility-id"))
+				Expect(queryArgs[len(queryArgs)-1]).To(Equal("visibility-id"))
+			})
+		})
+
+		Context("When there are no fields to update", func() {
+			It("Should not execute a query", func() {
+				execCalls := db.ExecContextCallCount()
+				criteria := query.ByField(query.EqualsOperator, "id", "visibility-id")
+				result, err := qb.NewQuery().WithCriteria(criteria).Update(ctx, &noFieldsEntity{entity})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(BeNil())
+				Expect(db.ExecContextCallCount()).To(Equal(execCalls))
+			})
+		})
+
+		Context("When updating by label", func() {
+			It("Should return an error", func() {
+				criteria := query.ByLabel(query.EqualsOperator, "left", "right")
+				_, err := qb.NewQuery().WithCriteria(criteria).Update(ctx, entity)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When limit is used", func() {
+			It("Should return an error", func() {
+				_, err := qb.NewQuery().WithCriteria(query.LimitResultBy(1)).Update(ctx, entity)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When criteria uses missing field", func() {
+			It("Should return error", func() {
+				criteria := query.ByField(query.EqualsOperator, "non-existing-field", "value")
+				_, err := qb.NewQuery().WithCriteria(criteria).Update(ctx, entity)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 })
+
+type postgresEntity interface {
+	postgres.PostgresEntity
+}
+
+// noFieldsEntity hides the fields of the embedded entity, so that it has no fields to update
+type noFieldsEntity struct {
+	postgresEntity
+}
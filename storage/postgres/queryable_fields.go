@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/lib/pq"
+)
+
+// QueryableFields implements storage.QueryableFieldsProvider and lists the columns of the introduced entities, which
+// are the keys accepted in their field queries
+func (ps *Storage) QueryableFields() map[types.ObjectType][]storage.QueryableField {
+	return ps.scheme.queryableFields()
+}
+
+func (s *scheme) queryableFields() map[types.ObjectType][]storage.QueryableField {
+	result := make(map[types.ObjectType][]storage.QueryableField, len(s.instanceProviders))
+	for objectType, provide := range s.instanceProviders {
+		entity, err := provide()
+		if err != nil {
+			continue
+		}
+		tags := getDBTags(entity, nil)
+		fields := make([]storage.QueryableField, 0, len(tags))
+		for _, tag := range tags {
+			fields = append(fields, storage.QueryableField{
+				Name: strings.Split(tag.Tag, ",")[0],
+				Type: fieldType(tag.Type),
+			})
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+		result[objectType] = fields
+	}
+	return result
+}
+
+// fieldType returns the kind of operands which a field query on a column of the given type accepts
+func fieldType(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(pq.NullTime{}):
+		return "datetime"
+	case reflect.TypeOf(sql.NullString{}):
+		return "string"
+	case reflect.TypeOf(sql.NullFloat64{}), reflect.TypeOf(sql.NullInt64{}):
+		return "number"
+	case reflect.TypeOf(sql.NullBool{}):
+		return "boolean"
+	case reflect.TypeOf([]byte{}):
+		return "binary"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "json"
+	}
+}
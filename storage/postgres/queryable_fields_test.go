@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queryable fields", func() {
+	var s *Storage
+
+	BeforeEach(func() {
+		s = &Storage{scheme: newScheme()}
+		s.scheme.introduce(&Broker{})
+		s.scheme.introduce(&Operation{})
+	})
+
+	It("lists the fields of the introduced entities", func() {
+		fields := s.QueryableFields()
+		Expect(fields).To(HaveLen(2))
+		Expect(fields).To(HaveKey(types.ServiceBrokerType))
+		Expect(fields).To(HaveKey(types.OperationObjectType))
+	})
+
+	It("lists the columns of the entity with the kind of their operands", func() {
+		fields := s.QueryableFields()[types.ServiceBrokerType]
+		Expect(fields).To(ContainElement(storage.QueryableField{Name: "id", Type: "string"}))
+		Expect(fields).To(ContainElement(storage.QueryableField{Name: "created_at", Type: "datetime"}))
+		Expect(fields).To(ContainElement(storage.QueryableField{Name: "description", Type: "string"}))
+		Expect(fields).To(ContainElement(storage.QueryableField{Name: "catalog", Type: "json"}))
+	})
+
+	It("omits the fields which are not stored in columns", func() {
+		for _, field := range s.QueryableFields()[types.ServiceBrokerType] {
+			Expect(field.Name).ToNot(Equal("-"))
+			Expect(field.Name).ToNot(Equal("services"))
+		}
+	})
+})
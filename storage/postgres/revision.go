@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+)
+
+// Revision entity
+//go:generate smgen storage revision github.com/Peripli/service-manager/pkg/types:Revision
+type Revision struct {
+	BaseEntity
+	ResourceID   string             `db:"resource_id"`
+	ResourceType string             `db:"resource_type"`
+	Operation    string             `db:"operation"`
+	User         string             `db:"username"`
+	Payload      sqlxtypes.JSONText `db:"payload"`
+}
+
+func (r *Revision) ToObject() types.Object {
+	return &types.Revision{
+		Base: types.Base{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		ResourceID:   r.ResourceID,
+		ResourceType: types.ObjectType(r.ResourceType),
+		Operation:    types.OperationCategory(r.Operation),
+		User:         r.User,
+		Payload:      getJSONRawMessage(r.Payload),
+	}
+}
+
+func (*Revision) FromObject(object types.Object) (storage.Entity, bool) {
+	revision, ok := object.(*types.Revision)
+	if !ok {
+		return nil, false
+	}
+
+	r := &Revision{
+		BaseEntity: BaseEntity{
+			ID:        revision.ID,
+			CreatedAt: revision.CreatedAt,
+			UpdatedAt: revision.UpdatedAt,
+		},
+		ResourceID:   revision.ResourceID,
+		ResourceType: string(revision.ResourceType),
+		Operation:    string(revision.Operation),
+		User:         revision.User,
+		Payload:      getJSONText(revision.Payload),
+	}
+	return r, true
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &Revision{}
+
+const RevisionTable = "revisions"
+
+func (*Revision) LabelEntity() PostgresLabel {
+	return &RevisionLabel{}
+}
+
+func (*Revision) TableName() string {
+	return RevisionTable
+}
+
+func (e *Revision) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &RevisionLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		RevisionID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *Revision) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*Revision
+			RevisionLabel `db:"revision_labels"`
+		}{}
+	}
+	result := &types.Revisions{
+		Revisions: make([]*types.Revision, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type RevisionLabel struct {
+	BaseLabelEntity
+	RevisionID sql.NullString `db:"revision_id"`
+}
+
+func (el RevisionLabel) LabelsTableName() string {
+	return "revision_labels"
+}
+
+func (el RevisionLabel) ReferenceColumn() string {
+	return "revision_id"
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+//go:generate smgen storage SavedQuery github.com/Peripli/service-manager/pkg/types
+// SavedQuery entity. Shared queries have no owner.
+type SavedQuery struct {
+	BaseEntity
+	Name       string         `db:"name"`
+	FieldQuery sql.NullString `db:"field_query"`
+	LabelQuery sql.NullString `db:"label_query"`
+	OrderBy    sql.NullString `db:"order_by"`
+	Order      sql.NullString `db:"order_type"`
+	Owner      sql.NullString `db:"owner"`
+}
+
+func (q *SavedQuery) FromObject(object types.Object) (storage.Entity, bool) {
+	savedQuery, ok := object.(*types.SavedQuery)
+	if !ok {
+		return nil, false
+	}
+	owner := toNullString(savedQuery.Owner)
+	if savedQuery.Shared {
+		owner = sql.NullString{}
+	}
+	return &SavedQuery{
+		BaseEntity: BaseEntity{
+			ID:        savedQuery.ID,
+			CreatedAt: savedQuery.CreatedAt,
+			UpdatedAt: savedQuery.UpdatedAt,
+		},
+		Name:       savedQuery.Name,
+		FieldQuery: toNullString(savedQuery.FieldQuery),
+		LabelQuery: toNullString(savedQuery.LabelQuery),
+		OrderBy:    toNullString(savedQuery.OrderBy),
+		Order:      toNullString(savedQuery.Order),
+		Owner:      owner,
+	}, true
+}
+
+func (q *SavedQuery) ToObject() types.Object {
+	return &types.SavedQuery{
+		Base: types.Base{
+			ID:        q.ID,
+			CreatedAt: q.CreatedAt,
+			UpdatedAt: q.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Name:       q.Name,
+		FieldQuery: q.FieldQuery.String,
+		LabelQuery: q.LabelQuery.String,
+		OrderBy:    q.OrderBy.String,
+		Order:      q.Order.String,
+		Owner:      q.Owner.String,
+		Shared:     !q.Owner.Valid,
+	}
+}
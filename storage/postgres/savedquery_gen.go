@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &SavedQuery{}
+
+const SavedQueryTable = "saved_queries"
+
+func (*SavedQuery) LabelEntity() PostgresLabel {
+	return &SavedQueryLabel{}
+}
+
+func (*SavedQuery) TableName() string {
+	return SavedQueryTable
+}
+
+func (e *SavedQuery) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &SavedQueryLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		SavedQueryID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *SavedQuery) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*SavedQuery
+			SavedQueryLabel `db:"saved_query_labels"`
+		}{}
+	}
+	result := &types.SavedQueries{
+		SavedQueries: make([]*types.SavedQuery, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type SavedQueryLabel struct {
+	BaseLabelEntity
+	SavedQueryID sql.NullString `db:"saved_query_id"`
+}
+
+func (el SavedQueryLabel) LabelsTableName() string {
+	return "saved_query_labels"
+}
+
+func (el SavedQueryLabel) ReferenceColumn() string {
+	return "saved_query_id"
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/Peripli/service-manager/storage"
+	migratepg "github.com/golang-migrate/migrate/database/postgres"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// SchemaStatus describes the version of the database schema compared to the available migrations
+type SchemaStatus struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+}
+
+// Pending returns whether there are migrations which are not applied yet. They are applied on the next start.
+func (s *SchemaStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// ConnectionURL returns the URL with which the storage connects to PostgreSQL
+func ConnectionURL(settings *storage.Settings) string {
+	if settings.SkipSSLValidation {
+		return settings.URI + "?sslmode=disable"
+	}
+	return settings.URI
+}
+
+// ReadSchemaStatus reads the version of the database schema and the latest version of the migrations
+// without applying any of them
+func ReadSchemaStatus(ctx context.Context, db *sql.DB, migrationsURL string) (*SchemaStatus, error) {
+	latest, err := latestMigrationVersion(migrationsURL)
+	if err != nil {
+		return nil, err
+	}
+	status := &SchemaStatus{Latest: latest}
+
+	var migrated bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", migratepg.DefaultMigrationsTable).Scan(&migrated); err != nil {
+		return nil, err
+	}
+	if !migrated {
+		return status, nil
+	}
+	query := fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", migratepg.DefaultMigrationsTable)
+	var version int64
+	if err := db.QueryRowContext(ctx, query).Scan(&version, &status.Dirty); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if version > 0 {
+		status.Version = uint(version)
+	}
+	return status, nil
+}
+
+func latestMigrationVersion(migrationsURL string) (uint, error) {
+	migrations, err := source.Open(migrationsURL)
+	if err != nil {
+		return 0, fmt.Errorf("could not open migrations %s: %s", migrationsURL, err)
+	}
+	defer migrations.Close()
+
+	version, err := migrations.First()
+	if err != nil {
+		return 0, fmt.Errorf("could not read migrations %s: %s", migrationsURL, err)
+	}
+	for {
+		next, err := migrations.Next(version)
+		if os.IsNotExist(err) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not read migrations %s: %s", migrationsURL, err)
+		}
+		version = next
+	}
+}
+
+// VerifyEncryptionKey checks that the stored encryption key can be decrypted with the storage encryption key.
+// It succeeds if no encryption key is stored yet, e.g. on a new database.
+func VerifyEncryptionKey(ctx context.Context, db *sql.DB, layerOneKey []byte, decryptFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	var initialized bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('safe') IS NOT NULL").Scan(&initialized); err != nil {
+		return err
+	}
+	if !initialized {
+		return nil
+	}
+	var secret []byte
+	err := db.QueryRowContext(ctx, "SELECT secret FROM safe LIMIT 1").Scan(&secret)
+	if err == sql.ErrNoRows || (err == nil && len(secret) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := decryptFunc(ctx, secret, layerOneKey); err != nil {
+		return fmt.Errorf("stored encryption key cannot be decrypted with the storage encryption key: %s", err)
+	}
+	return nil
+}
@@ -17,6 +17,8 @@
 package postgres
 
 import (
+	"database/sql"
+
 	"github.com/Peripli/service-manager/storage"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
 
@@ -40,6 +42,8 @@ type ServiceOffering struct {
 	Requires sqlxtypes.JSONText `db:"requires"`
 	Metadata sqlxtypes.JSONText `db:"metadata"`
 
+	Lifecycle sql.NullString `db:"lifecycle"`
+
 	BrokerID string         `db:"broker_id"`
 	Plans    []*ServicePlan `db:"-"`
 }
@@ -66,6 +70,7 @@ func (e *ServiceOffering) ToObject() types.Object {
 		Tags:                 getJSONRawMessage(e.Tags),
 		Requires:             getJSONRawMessage(e.Requires),
 		Metadata:             getJSONRawMessage(e.Metadata),
+		Lifecycle:            types.LifecycleState(e.Lifecycle.String),
 		BrokerID:             e.BrokerID,
 		Plans:                plans,
 	}
@@ -100,6 +105,7 @@ func (*ServiceOffering) FromObject(object types.Object) (storage.Entity, bool) {
 		Tags:                 getJSONText(offering.Tags),
 		Requires:             getJSONText(offering.Requires),
 		Metadata:             getJSONText(offering.Metadata),
+		Lifecycle:            toNullString(string(offering.Lifecycle)),
 		BrokerID:             offering.BrokerID,
 		Plans:                plans,
 	}
@@ -17,6 +17,8 @@
 package postgres
 
 import (
+	"database/sql"
+
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/storage"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
@@ -37,6 +39,15 @@ type ServicePlan struct {
 	Metadata sqlxtypes.JSONText `db:"metadata"`
 	Schemas  sqlxtypes.JSONText `db:"schemas"`
 
+	PriceAmount   sql.NullFloat64 `db:"price_amount"`
+	PriceCurrency sql.NullString  `db:"price_currency"`
+	PriceUnit     sql.NullString  `db:"price_unit"`
+
+	Lifecycle sql.NullString `db:"lifecycle"`
+
+	MaintenanceInfoVersion     sql.NullString `db:"maintenance_info_version"`
+	MaintenanceInfoDescription sql.NullString `db:"maintenance_info_description"`
+
 	ServiceOfferingID string `db:"service_offering_id"`
 }
 
@@ -56,15 +67,34 @@ func (sp *ServicePlan) ToObject() types.Object {
 		PlanUpdatable:     sp.PlanUpdatable,
 		Metadata:          getJSONRawMessage(sp.Metadata),
 		Schemas:           getJSONRawMessage(sp.Schemas),
+		PriceAmount:       fromNullFloat64(sp.PriceAmount),
+		PriceCurrency:     sp.PriceCurrency.String,
+		PriceUnit:         sp.PriceUnit.String,
+		Lifecycle:         types.LifecycleState(sp.Lifecycle.String),
+		MaintenanceInfo:   sp.maintenanceInfo(),
 		ServiceOfferingID: sp.ServiceOfferingID,
 	}
 }
 
+func (sp *ServicePlan) maintenanceInfo() *types.MaintenanceInfo {
+	if !sp.MaintenanceInfoVersion.Valid {
+		return nil
+	}
+	return &types.MaintenanceInfo{
+		Version:     sp.MaintenanceInfoVersion.String,
+		Description: sp.MaintenanceInfoDescription.String,
+	}
+}
+
 func (sp *ServicePlan) FromObject(object types.Object) (storage.Entity, bool) {
 	plan, ok := object.(*types.ServicePlan)
 	if !ok {
 		return nil, false
 	}
+	var maintenanceInfoVersion, maintenanceInfoDescription string
+	if plan.MaintenanceInfo != nil {
+		maintenanceInfoVersion, maintenanceInfoDescription = plan.MaintenanceInfo.Version, plan.MaintenanceInfo.Description
+	}
 	return &ServicePlan{
 		BaseEntity: BaseEntity{
 			ID:        plan.ID,
@@ -80,6 +110,13 @@ func (sp *ServicePlan) FromObject(object types.Object) (storage.Entity, bool) {
 		CatalogName:       plan.CatalogName,
 		Metadata:          getJSONText(plan.Metadata),
 		Schemas:           getJSONText(plan.Schemas),
+		PriceAmount:       toNullFloat64(plan.PriceAmount),
+		PriceCurrency:     toNullString(plan.PriceCurrency),
+		PriceUnit:         toNullString(plan.PriceUnit),
+		Lifecycle:         toNullString(string(plan.Lifecycle)),
 		ServiceOfferingID: plan.ServiceOfferingID,
+
+		MaintenanceInfoVersion:     toNullString(maintenanceInfoVersion),
+		MaintenanceInfoDescription: toNullString(maintenanceInfoDescription),
 	}, true
 }
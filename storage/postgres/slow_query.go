@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const explainTimeout = 10 * time.Second
+
+// SlowQueryLogger logs the storage queries which take longer than the threshold together with the criteria which
+// produced them. The execution plans of a sample of the slow queries are requested with EXPLAIN and logged as well.
+// The number of plans requested per minute is bounded, so that an overloaded database is not burdened further.
+type SlowQueryLogger struct {
+	settings *storage.SlowQuerySettings
+	db       PgDB
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	explained   int
+}
+
+// NewSlowQueryLogger returns a slow query logger which requests the execution plans from the provided database.
+// It returns nil if the logging of slow queries is disabled.
+func NewSlowQueryLogger(settings *storage.SlowQuerySettings, db PgDB) *SlowQueryLogger {
+	if settings == nil || settings.Threshold <= 0 {
+		return nil
+	}
+	return &SlowQueryLogger{
+		settings: settings,
+		db:       db,
+	}
+}
+
+// observe logs the query if it took longer than the threshold and requests its execution plan in the background.
+// The plan is requested outside of the transaction of the query, as the transaction may still be reading the results.
+func (l *SlowQueryLogger) observe(ctx context.Context, duration time.Duration, sql string, params []interface{}, criteria []query.Criterion) {
+	if l == nil || duration < l.settings.Threshold {
+		return
+	}
+
+	logger := log.C(ctx).WithField("criteria", criteriaString(criteria))
+	logger.Warnf("Storage query took %s: %s", duration, sql)
+	if !l.allowExplain() {
+		return
+	}
+
+	go func() {
+		explainCtx, cancel := context.WithTimeout(log.ContextWithLogger(context.Background(), logger), explainTimeout)
+		defer cancel()
+		plan, err := l.explain(explainCtx, sql, params)
+		if err != nil {
+			logger.WithError(err).Warnf("Could not explain slow storage query: %s", sql)
+			return
+		}
+		logger.Warnf("Execution plan of slow storage query %s\n%s", sql, plan)
+	}()
+}
+
+func (l *SlowQueryLogger) allowExplain() bool {
+	if l.settings.SampleRate < 1 && rand.Float64() >= l.settings.SampleRate {
+		return false
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(l.windowStart) >= time.Minute {
+		l.windowStart = now
+		l.explained = 0
+	}
+	if l.explained >= l.settings.MaxExplainsPerMinute {
+		return false
+	}
+	l.explained++
+	return true
+}
+
+func (l *SlowQueryLogger) explain(ctx context.Context, sql string, params []interface{}) (string, error) {
+	rows, err := l.db.QueryxContext(ctx, "EXPLAIN "+sql, params...)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not release connection")
+		}
+	}()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func criteriaString(criteria []query.Criterion) string {
+	result := make([]string, 0, len(criteria))
+	for _, criterion := range criteria {
+		result = append(result, string(criterion.Type)+": "+criterion.String())
+	}
+	return strings.Join(result, "; ")
+}
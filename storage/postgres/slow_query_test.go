@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Slow query logger", func() {
+	var (
+		mockdb   *sql.DB
+		mock     sqlmock.Sqlmock
+		db       *sqlx.DB
+		settings *storage.SlowQuerySettings
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockdb, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+		db = sqlx.NewDb(mockdb, postgresDriverName)
+
+		settings = storage.DefaultSlowQuerySettings()
+		settings.Threshold = time.Nanosecond
+	})
+
+	AfterEach(func() {
+		mockdb.Close()
+	})
+
+	It("is disabled without a threshold", func() {
+		Expect(NewSlowQueryLogger(storage.DefaultSlowQuerySettings(), db)).To(BeNil())
+	})
+
+	It("explains the slow queries of the query builder", func() {
+		mock.ExpectQuery("SELECT (.+) FROM visibilities").
+			WithArgs("platform-id").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("EXPLAIN SELECT (.+) FROM visibilities").
+			WithArgs("platform-id").
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Seq Scan on visibilities"))
+
+		qb := NewQueryBuilder(db).WithSlowQueryLogger(NewSlowQueryLogger(settings, db))
+		rows, err := qb.NewQuery().
+			WithCriteria(query.ByField(query.EqualsOperator, "platform_id", "platform-id")).
+			List(context.Background(), &Visibility{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rows.Close()).To(Succeed())
+
+		Eventually(mock.ExpectationsWereMet).Should(Succeed())
+	})
+
+	It("returns the execution plan of a query", func() {
+		mock.ExpectQuery("EXPLAIN SELECT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Result").AddRow("  cost=0.00..0.01"))
+
+		plan, err := NewSlowQueryLogger(settings, db).explain(context.Background(), "SELECT 1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plan).To(Equal("Result\n  cost=0.00..0.01"))
+	})
+
+	It("bounds the number of explained queries per minute", func() {
+		settings.MaxExplainsPerMinute = 2
+		logger := NewSlowQueryLogger(settings, db)
+
+		Expect(logger.allowExplain()).To(BeTrue())
+		Expect(logger.allowExplain()).To(BeTrue())
+		Expect(logger.allowExplain()).To(BeFalse())
+	})
+
+	It("explains no queries with a sample rate of 0", func() {
+		settings.SampleRate = 0
+		Expect(NewSlowQueryLogger(settings, db).allowExplain()).To(BeFalse())
+	})
+})
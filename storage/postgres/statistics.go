@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+// tableStatisticsQuery reads the sizes of the tables from the statistics of the planner instead of counting the rows,
+// so that it stays cheap for large tables. The estimates are as recent as the last analyze of the tables.
+const tableStatisticsQuery = `SELECT c.relname AS table_name, GREATEST(c.reltuples, 0)::bigint AS row_estimate, pg_total_relation_size(c.oid) AS size_bytes
+FROM pg_class c
+WHERE c.relkind = 'r' AND pg_table_is_visible(c.oid) AND c.relname IN (?)`
+
+// Statistics implements storage.StatisticsProvider and reports the estimated sizes of the tables of the introduced
+// entities and of their labels
+func (ps *Storage) Statistics(ctx context.Context) (*storage.Statistics, error) {
+	ps.checkOpen()
+	type entityTables struct {
+		objectType types.ObjectType
+		table      string
+		labels     string
+	}
+	entities := make([]entityTables, 0, len(ps.scheme.instanceProviders))
+	tables := make([]string, 0, 2*len(ps.scheme.instanceProviders))
+	for objectType, provide := range ps.scheme.instanceProviders {
+		entity, err := provide()
+		if err != nil {
+			return nil, err
+		}
+		tablesOfEntity := entityTables{objectType: objectType, table: entity.TableName()}
+		tables = append(tables, tablesOfEntity.table)
+		if labelEntity := entity.LabelEntity(); labelEntity != nil {
+			tablesOfEntity.labels = labelEntity.LabelsTableName()
+			tables = append(tables, tablesOfEntity.labels)
+		}
+		entities = append(entities, tablesOfEntity)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].objectType < entities[j].objectType
+	})
+	sort.Strings(tables)
+
+	sqlQuery, args, err := sqlx.In(tableStatisticsQuery, tables)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Table     string `db:"table_name"`
+		Rows      int64  `db:"row_estimate"`
+		SizeBytes int64  `db:"size_bytes"`
+	}
+	if err := ps.pgDB.SelectContext(ctx, &rows, ps.pgDB.Rebind(sqlQuery), args...); err != nil {
+		return nil, err
+	}
+	tableStatistics := make(map[string]storage.TableStatistics, len(rows))
+	for _, row := range rows {
+		tableStatistics[row.Table] = storage.TableStatistics{
+			Table:     row.Table,
+			Rows:      row.Rows,
+			SizeBytes: row.SizeBytes,
+		}
+	}
+	statisticsOf := func(table string) storage.TableStatistics {
+		if statistics, found := tableStatistics[table]; found {
+			return statistics
+		}
+		return storage.TableStatistics{Table: table}
+	}
+
+	statistics := &storage.Statistics{
+		Entities: make([]storage.EntityStatistics, 0, len(entities)),
+	}
+	for _, entity := range entities {
+		entityStatistics := storage.EntityStatistics{
+			Type:  entity.objectType,
+			Table: statisticsOf(entity.table),
+		}
+		if entity.labels != "" {
+			labels := statisticsOf(entity.labels)
+			entityStatistics.Labels = &labels
+		}
+		statistics.Entities = append(statistics.Entities, entityStatistics)
+		if entity.objectType == types.NotificationType {
+			statistics.NotificationBacklog = entityStatistics.Table.Rows
+		}
+	}
+	return statistics, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage statistics", func() {
+	var (
+		mockdb *sql.DB
+		mock   sqlmock.Sqlmock
+		s      *Storage
+	)
+
+	BeforeEach(func() {
+		var err error
+		mockdb, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		s = &Storage{
+			pgDB:   sqlx.NewDb(mockdb, postgresDriverName),
+			scheme: newScheme(),
+		}
+		s.scheme.introduce(&Broker{})
+		s.scheme.introduce(&Notification{})
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+		mockdb.Close()
+	})
+
+	It("reports the estimated sizes of the entity and label tables", func() {
+		mock.ExpectQuery("SELECT c.relname").
+			WithArgs("broker_labels", "brokers", "notification_labels", "notifications").
+			WillReturnRows(sqlmock.NewRows([]string{"table_name", "row_estimate", "size_bytes"}).
+				AddRow("brokers", 3, 16384).
+				AddRow("broker_labels", 5, 8192).
+				AddRow("notifications", 42, 65536))
+
+		statistics, err := s.Statistics(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statistics.NotificationBacklog).To(Equal(int64(42)))
+		Expect(statistics.Entities).To(Equal([]storage.EntityStatistics{
+			{
+				Type:   types.NotificationType,
+				Table:  storage.TableStatistics{Table: "notifications", Rows: 42, SizeBytes: 65536},
+				Labels: &storage.TableStatistics{Table: "notification_labels"},
+			},
+			{
+				Type:   types.ServiceBrokerType,
+				Table:  storage.TableStatistics{Table: "brokers", Rows: 3, SizeBytes: 16384},
+				Labels: &storage.TableStatistics{Table: "broker_labels", Rows: 5, SizeBytes: 8192},
+			},
+		}))
+	})
+
+	It("returns the error of the statistics query", func() {
+		mock.ExpectQuery("SELECT c.relname").WillReturnError(fmt.Errorf("connection lost"))
+
+		_, err := s.Statistics(context.Background())
+		Expect(err).To(MatchError("connection lost"))
+	})
+})
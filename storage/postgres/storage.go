@@ -40,9 +40,10 @@ const postgresDriverName = "postgres"
 type Storage struct {
 	ConnectFunc func(driver string, url string) (*sql.DB, error)
 
-	pgDB                  pgDB
+	pgDB                  PgDB
 	db                    *sqlx.DB
 	queryBuilder          *QueryBuilder
+	slowQueries           *SlowQueryLogger
 	state                 *storageState
 	layerOneEncryptionKey []byte
 	scheme                *scheme
@@ -67,11 +68,7 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 	if ps.db == nil {
-		sslModeParam := ""
-		if settings.SkipSSLValidation {
-			sslModeParam = "?sslmode=disable"
-		}
-		db, err := ps.ConnectFunc(postgresDriverName, settings.URI+sslModeParam)
+		db, err := ps.ConnectFunc(postgresDriverName, ConnectionURL(settings))
 		if err != nil {
 			return fmt.Errorf("could not connect to PostgreSQL: %s", err)
 		}
@@ -86,10 +83,11 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.layerOneEncryptionKey = []byte(settings.EncryptionKey)
 		ps.db.SetMaxIdleConns(settings.MaxIdleConnections)
 		ps.pgDB = ps.db
-		ps.queryBuilder = NewQueryBuilder(ps.pgDB)
+		ps.slowQueries = NewSlowQueryLogger(settings.SlowQuery, ps.pgDB)
+		ps.queryBuilder = NewQueryBuilder(ps.pgDB).WithSlowQueryLogger(ps.slowQueries)
 
 		log.D().Debugf("Updating database schema using migrations from %s", settings.MigrationsURL)
-		if err := ps.updateSchema(settings.MigrationsURL, postgresDriverName); err != nil {
+		if err := MigrateSchema(ps.db.DB, settings.MigrationsURL); err != nil {
 			return fmt.Errorf("could not update database schema: %s", err)
 		}
 		ps.scheme = newScheme()
@@ -99,6 +97,14 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.scheme.introduce(&ServicePlan{})
 		ps.scheme.introduce(&Visibility{})
 		ps.scheme.introduce(&Notification{})
+		ps.scheme.introduce(&Operation{})
+		ps.scheme.introduce(&Peer{})
+		ps.scheme.introduce(&Revision{})
+		ps.scheme.introduce(&Lock{})
+		ps.scheme.introduce(&TenantKey{})
+		ps.scheme.introduce(&VisibilityPolicy{})
+		ps.scheme.introduce(&CompositeBroker{})
+		ps.scheme.introduce(&SavedQuery{})
 	}
 
 	return nil
@@ -120,12 +126,13 @@ func (ps *Storage) checkOpen() {
 	}
 }
 
-func (ps *Storage) updateSchema(migrationsURL, pgDriverName string) error {
-	driver, err := migratepg.WithInstance(ps.db.DB, &migratepg.Config{})
+// MigrateSchema applies the pending migrations from the migrations URL to the database schema
+func MigrateSchema(db *sql.DB, migrationsURL string) error {
+	driver, err := migratepg.WithInstance(db, &migratepg.Config{})
 	if err != nil {
 		return err
 	}
-	m, err := migrate.NewWithDatabaseInstance(migrationsURL, pgDriverName, driver)
+	m, err := migrate.NewWithDatabaseInstance(migrationsURL, postgresDriverName, driver)
 	if err != nil {
 		return err
 	}
@@ -255,15 +262,21 @@ func (ps *Storage) Update(ctx context.Context, obj types.Object, labelChanges ..
 	if err != nil {
 		return nil, err
 	}
-	if err = update(ctx, ps.pgDB, entity.TableName(), entity); err != nil {
+	byPrimaryColumn := query.ByField(query.EqualsOperator, "id", entity.GetID())
+	result, err := ps.queryBuilder.NewQuery().WithCriteria(byPrimaryColumn).Update(ctx, entity)
+	if err = checkIntegrityViolation(ctx, checkUniqueViolation(ctx, err)); err != nil {
 		return nil, err
 	}
+	if result != nil {
+		if err = checkRowsAffected(ctx, result); err != nil {
+			return nil, err
+		}
+	}
 	if err = ps.updateLabels(ctx, entity.GetID(), entity, labelChanges); err != nil {
 		return nil, err
 	}
 
-	result := entity.ToObject()
-	return result, nil
+	return entity.ToObject(), nil
 }
 
 func (ps *Storage) updateLabels(ctx context.Context, entityID string, entity PostgresEntity, updateActions []*query.LabelChange) error {
@@ -295,7 +308,8 @@ func (ps *Storage) InTransaction(ctx context.Context, f func(ctx context.Context
 	transactionalStorage := &Storage{
 		pgDB:                  tx,
 		db:                    ps.db,
-		queryBuilder:          NewQueryBuilder(tx),
+		queryBuilder:          NewQueryBuilder(tx).WithSlowQueryLogger(ps.slowQueries),
+		slowQueries:           ps.slowQueries,
 		scheme:                ps.scheme,
 		layerOneEncryptionKey: ps.layerOneEncryptionKey,
 	}
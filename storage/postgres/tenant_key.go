@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// TenantKey entity
+//go:generate smgen storage tenantkey github.com/Peripli/service-manager/pkg/types:TenantKey
+type TenantKey struct {
+	BaseEntity
+	Provider   string `db:"provider"`
+	KeyRef     string `db:"key_ref"`
+	WrappedKey []byte `db:"wrapped_key"`
+}
+
+func (k *TenantKey) ToObject() types.Object {
+	return &types.TenantKey{
+		Base: types.Base{
+			ID:        k.ID,
+			CreatedAt: k.CreatedAt,
+			UpdatedAt: k.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Provider:   k.Provider,
+		KeyRef:     k.KeyRef,
+		WrappedKey: k.WrappedKey,
+	}
+}
+
+func (*TenantKey) FromObject(object types.Object) (storage.Entity, bool) {
+	tenantKey, ok := object.(*types.TenantKey)
+	if !ok {
+		return nil, false
+	}
+
+	k := &TenantKey{
+		BaseEntity: BaseEntity{
+			ID:        tenantKey.ID,
+			CreatedAt: tenantKey.CreatedAt,
+			UpdatedAt: tenantKey.UpdatedAt,
+		},
+		Provider:   tenantKey.Provider,
+		KeyRef:     tenantKey.KeyRef,
+		WrappedKey: tenantKey.WrappedKey,
+	}
+	return k, true
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &TenantKey{}
+
+const TenantKeyTable = "tenant_keys"
+
+func (*TenantKey) LabelEntity() PostgresLabel {
+	return &TenantKeyLabel{}
+}
+
+func (*TenantKey) TableName() string {
+	return TenantKeyTable
+}
+
+func (e *TenantKey) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &TenantKeyLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		TenantKeyID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *TenantKey) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*TenantKey
+			TenantKeyLabel `db:"tenant_key_labels"`
+		}{}
+	}
+	result := &types.TenantKeys{
+		TenantKeys: make([]*types.TenantKey, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type TenantKeyLabel struct {
+	BaseLabelEntity
+	TenantKeyID sql.NullString `db:"tenant_key_id"`
+}
+
+func (el TenantKeyLabel) LabelsTableName() string {
+	return "tenant_key_labels"
+}
+
+func (el TenantKeyLabel) ReferenceColumn() string {
+	return "tenant_key_id"
+}
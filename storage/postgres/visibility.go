@@ -19,6 +19,8 @@ package postgres
 import (
 	"database/sql"
 
+	"github.com/lib/pq"
+
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/types"
@@ -29,6 +31,8 @@ type Visibility struct {
 	BaseEntity
 	PlatformID    sql.NullString `db:"platform_id"`
 	ServicePlanID string         `db:"service_plan_id"`
+	ValidFrom     pq.NullTime    `db:"valid_from"`
+	ValidUntil    pq.NullTime    `db:"valid_until"`
 }
 
 func (v *Visibility) ToObject() types.Object {
@@ -41,6 +45,8 @@ func (v *Visibility) ToObject() types.Object {
 		},
 		PlatformID:    v.PlatformID.String,
 		ServicePlanID: v.ServicePlanID,
+		ValidFrom:     fromNullTime(v.ValidFrom),
+		ValidUntil:    fromNullTime(v.ValidUntil),
 	}
 }
 
@@ -57,5 +63,7 @@ func (v *Visibility) FromObject(visibility types.Object) (storage.Entity, bool)
 		},
 		PlatformID:    toNullString(vis.PlatformID),
 		ServicePlanID: vis.ServicePlanID,
+		ValidFrom:     toNullTime(vis.ValidFrom),
+		ValidUntil:    toNullTime(vis.ValidUntil),
 	}, true
 }
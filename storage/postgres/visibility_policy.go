@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+//go:generate smgen storage VisibilityPolicy github.com/Peripli/service-manager/pkg/types
+// VisibilityPolicy entity
+type VisibilityPolicy struct {
+	BaseEntity
+	Name             string         `db:"name"`
+	Description      sql.NullString `db:"description"`
+	PlanSelector     string         `db:"plan_selector"`
+	PlatformSelector string         `db:"platform_selector"`
+}
+
+func (p *VisibilityPolicy) FromObject(object types.Object) (storage.Entity, bool) {
+	policy, ok := object.(*types.VisibilityPolicy)
+	if !ok {
+		return nil, false
+	}
+	return &VisibilityPolicy{
+		BaseEntity: BaseEntity{
+			ID:        policy.ID,
+			CreatedAt: policy.CreatedAt,
+			UpdatedAt: policy.UpdatedAt,
+		},
+		Name:             policy.Name,
+		Description:      toNullString(policy.Description),
+		PlanSelector:     policy.PlanSelector,
+		PlatformSelector: policy.PlatformSelector,
+	}, true
+}
+
+func (p *VisibilityPolicy) ToObject() types.Object {
+	return &types.VisibilityPolicy{
+		Base: types.Base{
+			ID:        p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			Labels:    map[string][]string{},
+		},
+		Name:             p.Name,
+		Description:      p.Description.String,
+		PlanSelector:     p.PlanSelector,
+		PlatformSelector: p.PlatformSelector,
+	}
+}
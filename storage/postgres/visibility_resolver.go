@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// activeVisibilityCondition matches the visibilities in effect at the time of the second parameter,
+// a visibility without platform applies to all platforms
+const activeVisibilityCondition = `(%[1]s.platform_id = %[2]s.id OR %[1]s.platform_id IS NULL)
+	AND (%[1]s.valid_from IS NULL OR %[1]s.valid_from <= $2)
+	AND (%[1]s.valid_until IS NULL OR %[1]s.valid_until > $2)`
+
+// PlatformsForPlan implements storage.VisibilityResolver and returns the platforms which have a visibility in effect
+// for the service plan, including the visibilities restricted by labels
+func (ps *Storage) PlatformsForPlan(ctx context.Context, planID string, at time.Time) (types.ObjectList, error) {
+	condition := fmt.Sprintf(" WHERE EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.service_plan_id = $1 AND "+activeVisibilityCondition+")",
+		VisibilityTable, PlatformTable)
+	return ps.listResolved(ctx, types.PlatformType, condition, planID, at)
+}
+
+// PlansForPlatform implements storage.VisibilityResolver and returns the service plans for which a visibility of the
+// platform or a visibility of all platforms is in effect, including the visibilities restricted by labels
+func (ps *Storage) PlansForPlatform(ctx context.Context, platformID string, at time.Time) (types.ObjectList, error) {
+	condition := fmt.Sprintf(" WHERE EXISTS (SELECT 1 FROM %[1]s JOIN %[2]s ON %[2]s.id = $1 WHERE %[1]s.service_plan_id = %[3]s.id AND "+activeVisibilityCondition+")",
+		VisibilityTable, PlatformTable, ServicePlanTable)
+	return ps.listResolved(ctx, types.ServicePlanType, condition, platformID, at)
+}
+
+func (ps *Storage) listResolved(ctx context.Context, objectType types.ObjectType, condition string, id string, at time.Time) (types.ObjectList, error) {
+	ps.checkOpen()
+	entity, err := ps.scheme.provide(objectType)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlQuery := constructBaseQueryForLabelable(entity.LabelEntity(), entity.TableName()) + condition +
+		fmt.Sprintf(" ORDER BY %s.created_at;", entity.TableName())
+	rows, err := ps.pgDB.QueryxContext(ctx, sqlQuery, id, at)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.C(ctx).Errorf("Could not release connection when resolving visibilities. Error: %s", err)
+		}
+	}()
+	return entity.RowsToList(rows)
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &VisibilityPolicy{}
+
+const VisibilityPolicyTable = "visibility_policies"
+
+func (*VisibilityPolicy) LabelEntity() PostgresLabel {
+	return &VisibilityPolicyLabel{}
+}
+
+func (*VisibilityPolicy) TableName() string {
+	return VisibilityPolicyTable
+}
+
+func (e *VisibilityPolicy) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &VisibilityPolicyLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		VisibilityPolicyID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *VisibilityPolicy) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*VisibilityPolicy
+			VisibilityPolicyLabel `db:"visibility_policy_labels"`
+		}{}
+	}
+	result := &types.VisibilityPolicies{
+		VisibilityPolicies: make([]*types.VisibilityPolicy, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type VisibilityPolicyLabel struct {
+	BaseLabelEntity
+	VisibilityPolicyID sql.NullString `db:"visibility_policy_id"`
+}
+
+func (el VisibilityPolicyLabel) LabelsTableName() string {
+	return "visibility_policy_labels"
+}
+
+func (el VisibilityPolicyLabel) ReferenceColumn() string {
+	return "visibility_policy_id"
+}
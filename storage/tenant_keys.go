@@ -0,0 +1,275 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// tenantCiphertextPrefix marks credentials encrypted with the data encryption key of a tenant. It is followed by
+// the tenant and a separator, so that credentials can be decrypted regardless of the current labels of their object.
+var tenantCiphertextPrefix = []byte("tenant:")
+
+// TenantEncryptionSettings configures the encryption of credentials with keys supplied by tenants
+type TenantEncryptionSettings struct {
+	LabelKey     string                         `mapstructure:"label_key" description:"label of brokers identifying the tenant whose key encrypts their credentials"`
+	KeyCacheTTL  time.Duration                  `mapstructure:"key_cache_ttl" description:"time for which an unwrapped encryption key of a tenant is reused before it is unwrapped again by the key management service, keys are unwrapped on every use if 0"`
+	VaultTransit *security.VaultTransitSettings `mapstructure:"vault_transit"`
+}
+
+// DefaultTenantEncryptionSettings returns the default values for the tenant encryption
+func DefaultTenantEncryptionSettings() *TenantEncryptionSettings {
+	return &TenantEncryptionSettings{
+		LabelKey:     "tenant",
+		KeyCacheTTL:  time.Minute,
+		VaultTransit: security.DefaultVaultTransitSettings(),
+	}
+}
+
+// Validate validates the tenant encryption settings
+func (s *TenantEncryptionSettings) Validate() error {
+	if s.LabelKey == "" {
+		return fmt.Errorf("validate Settings: tenant encryption label key missing")
+	}
+	if s.KeyCacheTTL < 0 {
+		return fmt.Errorf("validate Settings: tenant encryption key cache TTL must not be negative")
+	}
+	return s.VaultTransit.Validate()
+}
+
+// TenantKeys provides the data encryption keys of the tenants which supplied a key of a key management service.
+// The data encryption key of a tenant is generated once and stored wrapped by the key of the tenant, so that
+// rewrapping it with another key does not require re-encrypting any credentials. Unwrapped keys are cached for
+// the key cache TTL, so that revoking the key of a tenant in its key management service takes effect after it.
+type TenantKeys struct {
+	labelKey    string
+	keyCacheTTL time.Duration
+
+	mutex     sync.RWMutex
+	providers map[string]security.KeyProvider
+	keys      map[string]*cachedKey
+}
+
+// cachedKey is an unwrapped data encryption key of a tenant which is reused until it expires
+type cachedKey struct {
+	key       []byte
+	expiresAt time.Time
+}
+
+// NewTenantKeys returns the tenant keys with the key providers which are enabled in the settings, the key providers
+// call the key management services with the HTTP client
+func NewTenantKeys(settings *TenantEncryptionSettings, client *http.Client) *TenantKeys {
+	tenantKeys := &TenantKeys{
+		labelKey:    settings.LabelKey,
+		keyCacheTTL: settings.KeyCacheTTL,
+		providers:   make(map[string]security.KeyProvider),
+		keys:        make(map[string]*cachedKey),
+	}
+	if settings.VaultTransit.Address != "" {
+		tenantKeys.RegisterProviders(security.NewVaultTransitKeyProvider(settings.VaultTransit, client))
+	}
+	return tenantKeys
+}
+
+// RegisterProviders adds key providers which tenants can reference by their names
+func (tk *TenantKeys) RegisterProviders(providers ...security.KeyProvider) {
+	tk.mutex.Lock()
+	defer tk.mutex.Unlock()
+	for _, provider := range providers {
+		tk.providers[provider.Name()] = provider
+	}
+}
+
+// TenantOf returns the tenant of the object from its tenant label or an empty string if it has none
+func (tk *TenantKeys) TenantOf(labels types.Labels) string {
+	if tk == nil || len(labels[tk.labelKey]) == 0 {
+		return ""
+	}
+	return labels[tk.labelKey][0]
+}
+
+// Key returns the data encryption key of the tenant or nil if the tenant did not supply a key
+func (tk *TenantKeys) Key(ctx context.Context, repository Repository, tenant string) ([]byte, error) {
+	if key, found := tk.cached(tenant); found {
+		return key, nil
+	}
+
+	tenantKey, err := repository.Get(ctx, types.TenantKeyType, tenant)
+	if err == util.ErrNotFoundInStorage {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tk.unwrap(ctx, tenantKey.(*types.TenantKey))
+}
+
+// Set wraps the data encryption key of the tenant with the provided key. The data encryption key is generated
+// if the tenant has none yet, otherwise it is rewrapped.
+func (tk *TenantKeys) Set(ctx context.Context, repository Repository, tenant, providerName, keyRef string) (*types.TenantKey, error) {
+	// the tenant is part of the marker of encrypted credentials which ends with a colon
+	if tenant == "" || util.HasRFC3986ReservedSymbols(tenant) {
+		return nil, &util.ErrBadRequestStorage{Cause: fmt.Errorf("tenant %q must not be empty or contain reserved characters", tenant)}
+	}
+	provider, err := tk.provider(providerName)
+	if err != nil {
+		return nil, &util.ErrBadRequestStorage{Cause: err}
+	}
+
+	existing, err := repository.Get(ctx, types.TenantKeyType, tenant)
+	if err != nil && err != util.ErrNotFoundInStorage {
+		return nil, err
+	}
+
+	var key []byte
+	if existing != nil {
+		if key, err = tk.unwrap(ctx, existing.(*types.TenantKey)); err != nil {
+			return nil, err
+		}
+	} else {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("could not generate encryption key of tenant %s: %v", tenant, err)
+		}
+	}
+
+	wrappedKey, err := provider.WrapKey(ctx, keyRef, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not wrap encryption key of tenant %s: %s", tenant, err)
+	}
+
+	var result types.Object
+	if existing != nil {
+		log.C(ctx).Infof("Rewrapping encryption key of tenant %s with key %s of provider %s", tenant, keyRef, providerName)
+		tenantKey := existing.(*types.TenantKey)
+		tenantKey.Provider = providerName
+		tenantKey.KeyRef = keyRef
+		tenantKey.WrappedKey = wrappedKey
+		result, err = repository.Update(ctx, tenantKey)
+	} else {
+		log.C(ctx).Infof("Creating encryption key of tenant %s wrapped with key %s of provider %s", tenant, keyRef, providerName)
+		currentTime := time.Now().UTC()
+		result, err = repository.Create(ctx, &types.TenantKey{
+			Base: types.Base{
+				ID:        tenant,
+				CreatedAt: currentTime,
+				UpdatedAt: currentTime,
+			},
+			Provider:   providerName,
+			KeyRef:     keyRef,
+			WrappedKey: wrappedKey,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tk.cache(tenant, key)
+	return result.(*types.TenantKey), nil
+}
+
+func (tk *TenantKeys) unwrap(ctx context.Context, tenantKey *types.TenantKey) ([]byte, error) {
+	provider, err := tk.provider(tenantKey.Provider)
+	if err != nil {
+		return nil, err
+	}
+	key, err := provider.UnwrapKey(ctx, tenantKey.KeyRef, tenantKey.WrappedKey)
+	if err != nil {
+		tk.evict(tenantKey.ID)
+		return nil, fmt.Errorf("could not unwrap encryption key of tenant %s: %s", tenantKey.ID, err)
+	}
+
+	tk.cache(tenantKey.ID, key)
+	return key, nil
+}
+
+// cached returns the unwrapped key of the tenant if it is cached and has not expired
+func (tk *TenantKeys) cached(tenant string) ([]byte, bool) {
+	tk.mutex.RLock()
+	entry, found := tk.keys[tenant]
+	tk.mutex.RUnlock()
+	if !found {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		tk.evict(tenant)
+		return nil, false
+	}
+	return entry.key, true
+}
+
+func (tk *TenantKeys) cache(tenant string, key []byte) {
+	if tk.keyCacheTTL <= 0 {
+		return
+	}
+	tk.mutex.Lock()
+	defer tk.mutex.Unlock()
+	tk.keys[tenant] = &cachedKey{
+		key:       key,
+		expiresAt: time.Now().Add(tk.keyCacheTTL),
+	}
+}
+
+func (tk *TenantKeys) evict(tenant string) {
+	tk.mutex.Lock()
+	defer tk.mutex.Unlock()
+	delete(tk.keys, tenant)
+}
+
+func (tk *TenantKeys) provider(name string) (security.KeyProvider, error) {
+	tk.mutex.RLock()
+	defer tk.mutex.RUnlock()
+	provider, found := tk.providers[name]
+	if !found {
+		return nil, fmt.Errorf("unknown key provider %s", name)
+	}
+	return provider, nil
+}
+
+// tenantCiphertext marks ciphertext encrypted with the key of the tenant
+func tenantCiphertext(tenant string, ciphertext []byte) []byte {
+	result := make([]byte, 0, len(tenantCiphertextPrefix)+len(tenant)+1+len(ciphertext))
+	result = append(result, tenantCiphertextPrefix...)
+	result = append(result, tenant...)
+	result = append(result, ':')
+	return append(result, ciphertext...)
+}
+
+// parseTenantCiphertext returns the tenant and the ciphertext of credentials encrypted with the key of a tenant or
+// an empty tenant for credentials encrypted with the key of the Service Manager
+func parseTenantCiphertext(value []byte) (string, []byte) {
+	if !bytes.HasPrefix(value, tenantCiphertextPrefix) {
+		return "", value
+	}
+	rest := value[len(tenantCiphertextPrefix):]
+	separator := bytes.IndexByte(rest, ':')
+	if separator <= 0 {
+		return "", value
+	}
+	return string(rest[:separator]), rest[separator+1:]
+}
@@ -0,0 +1,203 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeKeyStore holds the encryption key of the Service Manager in memory
+type fakeKeyStore struct {
+	key []byte
+}
+
+func (*fakeKeyStore) Lock(ctx context.Context) error {
+	return nil
+}
+
+func (*fakeKeyStore) Unlock(ctx context.Context) error {
+	return nil
+}
+
+func (ks *fakeKeyStore) GetEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	return ks.key, nil
+}
+
+func (ks *fakeKeyStore) SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	ks.key = key
+	return nil
+}
+
+// fakeKeyProvider "wraps" keys by prefixing them with the key reference
+type fakeKeyProvider struct {
+	unwrapCalls int
+	revoked     map[string]bool
+}
+
+func (*fakeKeyProvider) Name() string {
+	return "fake-kms"
+}
+
+func (*fakeKeyProvider) WrapKey(ctx context.Context, keyRef string, key []byte) ([]byte, error) {
+	return append([]byte(keyRef+":"), key...), nil
+}
+
+func (p *fakeKeyProvider) UnwrapKey(ctx context.Context, keyRef string, wrappedKey []byte) ([]byte, error) {
+	p.unwrapCalls++
+	if p.revoked[keyRef] {
+		return nil, fmt.Errorf("key %s is revoked", keyRef)
+	}
+	if !bytes.HasPrefix(wrappedKey, []byte(keyRef+":")) {
+		return nil, fmt.Errorf("key is not wrapped with %s", keyRef)
+	}
+	return wrappedKey[len(keyRef)+1:], nil
+}
+
+var _ = Describe("Tenant keys", func() {
+	var (
+		ctx            context.Context
+		fakeRepository *storagefakes.FakeStorage
+		provider       *fakeKeyProvider
+		tenantKeys     *storage.TenantKeys
+		repository     storage.TransactionalRepository
+		stored         map[string]types.Object
+	)
+
+	newBroker := func(tenant string) *types.ServiceBroker {
+		broker := &types.ServiceBroker{
+			Base: types.Base{ID: "broker-id", Labels: types.Labels{}},
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "admin", Password: "secret"},
+			},
+		}
+		if tenant != "" {
+			broker.Labels["tenant"] = []string{tenant}
+		}
+		return broker
+	}
+
+	storedPassword := func() string {
+		return stored["broker-id"].(*types.ServiceBroker).Credentials.Basic.Password
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		stored = make(map[string]types.Object)
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.CreateStub = func(ctx context.Context, obj types.Object) (types.Object, error) {
+			if broker, ok := obj.(*types.ServiceBroker); ok {
+				// the encrypting repository decrypts the returned object, so a copy is stored
+				copied := *broker
+				credentials := *broker.Credentials
+				basic := *broker.Credentials.Basic
+				credentials.Basic = &basic
+				copied.Credentials = &credentials
+				stored[obj.GetID()] = &copied
+				return broker, nil
+			}
+			stored[obj.GetID()] = obj
+			return obj, nil
+		}
+		fakeRepository.UpdateStub = func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+			stored[obj.GetID()] = obj
+			return obj, nil
+		}
+		fakeRepository.GetStub = func(ctx context.Context, objectType types.ObjectType, id string) (types.Object, error) {
+			if obj, found := stored[id]; found && obj.GetType() == objectType {
+				return obj, nil
+			}
+			return nil, util.ErrNotFoundInStorage
+		}
+
+		provider = &fakeKeyProvider{revoked: make(map[string]bool)}
+		settings := storage.DefaultTenantEncryptionSettings()
+		settings.KeyCacheTTL = 100 * time.Millisecond
+		tenantKeys = storage.NewTenantKeys(settings, http.DefaultClient)
+		tenantKeys.RegisterProviders(provider)
+
+		var err error
+		keyStore := &fakeKeyStore{key: []byte("0123456789abcdef0123456789abcdef")}
+		repository, err = storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, keyStore, tenantKeys)(fakeRepository)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("encrypts the credentials of tenants with their key", func() {
+		_, err := tenantKeys.Set(ctx, fakeRepository, "tenant-a", "fake-kms", "key-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		created, err := repository.Create(ctx, newBroker("tenant-a"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("secret"))
+		Expect(storedPassword()).To(HavePrefix("tenant:tenant-a:"))
+
+		fetched, err := repository.Get(ctx, types.ServiceBrokerType, "broker-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fetched.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("secret"))
+	})
+
+	It("encrypts the credentials of tenants without a key with the key of the Service Manager", func() {
+		_, err := repository.Create(ctx, newBroker("tenant-b"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(storedPassword()).ToNot(HavePrefix("tenant:"))
+
+		fetched, err := repository.Get(ctx, types.ServiceBrokerType, "broker-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fetched.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("secret"))
+	})
+
+	It("keeps the credentials decryptable after rewrapping the key", func() {
+		_, err := tenantKeys.Set(ctx, fakeRepository, "tenant-a", "fake-kms", "key-1")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = repository.Create(ctx, newBroker("tenant-a"))
+		Expect(err).ToNot(HaveOccurred())
+
+		rewrapped, err := tenantKeys.Set(ctx, fakeRepository, "tenant-a", "fake-kms", "key-2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rewrapped.KeyRef).To(Equal("key-2"))
+
+		// a new instance does not have the unwrapped key cached
+		otherTenantKeys := storage.NewTenantKeys(storage.DefaultTenantEncryptionSettings(), http.DefaultClient)
+		otherTenantKeys.RegisterProviders(provider)
+		keyStore := &fakeKeyStore{key: []byte("0123456789abcdef0123456789abcdef")}
+		otherRepository, err := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, keyStore, otherTenantKeys)(fakeRepository)
+		Expect(err).ToNot(HaveOccurred())
+
+		unwrapCallsBefore := provider.unwrapCalls
+		fetched, err := otherRepository.Get(ctx, types.ServiceBrokerType, "broker-id")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fetched.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("secret"))
+		Expect(provider.unwrapCalls - unwrapCallsBefore).To(Equal(1))
+	})
+
+	It("stops decrypting the credentials once the key is revoked and the cached key expired", func() {
+		_, err := tenantKeys.Set(ctx, fakeRepository, "tenant-a", "fake-kms", "key-1")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = repository.Create(ctx, newBroker("tenant-a"))
+		Expect(err).ToNot(HaveOccurred())
+
+		provider.revoked["key-1"] = true
+		Eventually(func() error {
+			_, err := repository.Get(ctx, types.ServiceBrokerType, "broker-id")
+			return err
+		}, time.Second, 20*time.Millisecond).Should(HaveOccurred())
+	})
+
+	It("rejects unknown providers and invalid tenants", func() {
+		_, err := tenantKeys.Set(ctx, fakeRepository, "tenant-a", "unknown-kms", "key-1")
+		Expect(err).To(HaveOccurred())
+
+		_, err = tenantKeys.Set(ctx, fakeRepository, "tenant:a", "fake-kms", "key-1")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -69,6 +69,49 @@ var _ = Describe("Service Manager Authentication", func() {
 		})
 	})
 
+	Context("Token validation scenarios", func() {
+		var oauthServer *common.OAuthServer
+
+		BeforeEach(func() {
+			oauthServer = ctx.Servers[common.OauthServer].(*common.OAuthServer)
+		})
+
+		expectTokenRejected := func(token string) {
+			expectUnauthorizedRequest(ctx, "GET", "/v1/service_brokers", "Bearer "+token)
+		}
+
+		It("Expired token", func() {
+			expectTokenRejected(oauthServer.CreateTokenWithOptions(map[string]interface{}{}, common.Expired()))
+		})
+
+		It("Token for another audience", func() {
+			expectTokenRejected(oauthServer.CreateTokenWithOptions(map[string]interface{}{}, common.WithAudience("other")))
+		})
+
+		It("Token from another issuer", func() {
+			expectTokenRejected(oauthServer.CreateTokenWithOptions(map[string]interface{}{}, common.WithIssuer("http://other-issuer")))
+		})
+
+		It("Token signed with a shared secret", func() {
+			hmacServer := common.NewOAuthServerWithAlgorithm(common.HS256)
+			defer hmacServer.Close()
+
+			expectTokenRejected(hmacServer.CreateToken(map[string]interface{}{"iss": oauthServer.BaseURL + "/oauth/token"}))
+		})
+
+		It("Token signed with a rotated key", func() {
+			oauthServer.RotateKey(common.RS256, true)
+
+			ctx.SM.GET("/v1/service_brokers").
+				WithHeader("Authorization", "Bearer "+oauthServer.CreateToken(map[string]interface{}{})).
+				Expect().
+				Status(http.StatusOK)
+			ctx.SMWithOAuth.GET("/v1/service_brokers").
+				Expect().
+				Status(http.StatusOK)
+		})
+	})
+
 	Context("Failing security scenarios", func() {
 		authRequests := []struct{ name, method, path, authHeader string }{
 			// PLATFORMS
@@ -191,6 +234,22 @@ var _ = Describe("Service Manager Authentication", func() {
 			{"Invalid authorization schema", "DELETE", "/v1/visibilities/999", "Basic abc"},
 			{"Missing token in authorization header", "DELETE", "/v1/visibilities/999", "Bearer "},
 			{"Invalid token in authorization header", "DELETE", "/v1/visibilities/999", "Bearer abc"},
+
+			// TENANT KEYS
+			{"Missing authorization header", "GET", "/v1/tenant_keys/999", ""},
+			{"Invalid authorization schema", "GET", "/v1/tenant_keys/999", "Basic abc"},
+			{"Missing token in authorization header", "GET", "/v1/tenant_keys/999", "Bearer "},
+			{"Invalid token in authorization header", "GET", "/v1/tenant_keys/999", "Bearer abc"},
+
+			{"Missing authorization header", "GET", "/v1/tenant_keys", ""},
+			{"Invalid authorization schema", "GET", "/v1/tenant_keys", "Basic abc"},
+			{"Missing token in authorization header", "GET", "/v1/tenant_keys", "Bearer "},
+			{"Invalid token in authorization header", "GET", "/v1/tenant_keys", "Bearer abc"},
+
+			{"Missing authorization header", "PUT", "/v1/tenant_keys/999", ""},
+			{"Invalid authorization schema", "PUT", "/v1/tenant_keys/999", "Basic abc"},
+			{"Missing token in authorization header", "PUT", "/v1/tenant_keys/999", "Bearer "},
+			{"Invalid token in authorization header", "PUT", "/v1/tenant_keys/999", "Bearer abc"},
 		}
 
 		for _, request := range authRequests {
@@ -22,6 +22,9 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/api"
 
 	"github.com/Peripli/service-manager/storage"
 
@@ -334,6 +337,80 @@ var _ = test.DescribeTestsFor(test.TestCase{
 								r.Status(http.StatusBadRequest).JSON().Object().Keys().NotContains("services", "credentials")
 							}, "services.0.plans.0.schemas", "{invalid")
 						})
+
+						Context("that has costs with a non numeric amount", func() {
+							verifyPOSTWhenCatalogFieldHasValue(func(r *httpexpect.Response) {
+								r.Status(http.StatusBadRequest).JSON().Object().Keys().NotContains("services", "credentials")
+							}, "services.0.plans.0.metadata.costs", common.Array{common.Object{"amount": common.Object{"usd": "free"}, "unit": "MONTHLY"}})
+						})
+
+						Context("that has costs with an invalid currency", func() {
+							verifyPOSTWhenCatalogFieldHasValue(func(r *httpexpect.Response) {
+								r.Status(http.StatusBadRequest).JSON().Object().Keys().NotContains("services", "credentials")
+							}, "services.0.plans.0.metadata.costs", common.Array{common.Object{"amount": common.Object{"dollars": 10}, "unit": "MONTHLY"}})
+						})
+
+						Context("that has costs without a unit", func() {
+							verifyPOSTWhenCatalogFieldHasValue(func(r *httpexpect.Response) {
+								r.Status(http.StatusBadRequest).JSON().Object().Keys().NotContains("services", "credentials")
+							}, "services.0.plans.0.metadata.costs", common.Array{common.Object{"amount": common.Object{"usd": 10}}})
+						})
+
+						Context("that has maintenance info without a semantic version", func() {
+							verifyPOSTWhenCatalogFieldHasValue(func(r *httpexpect.Response) {
+								r.Status(http.StatusBadRequest).JSON().Object().Keys().NotContains("services", "credentials")
+							}, "services.0.plans.0.maintenance_info", common.Object{"version": "latest"})
+						})
+					})
+				})
+
+				Context("when the broker catalog contains conflicting ids", func() {
+					verifyPOSTWithCatalogReturns400 := func(catalogFunc func() common.SBCatalog, expectedDescription string) {
+						BeforeEach(func() {
+							brokerServer.Catalog = catalogFunc()
+						})
+
+						It("returns 400 with a description of the conflict", func() {
+							ctx.SMWithOAuth.POST("/v1/service_brokers").WithJSON(postBrokerRequestWithNoLabels).
+								Expect().
+								Status(http.StatusBadRequest).
+								JSON().Object().
+								Value("description").String().Contains(expectedDescription)
+
+							assertInvocationCount(brokerServer.CatalogEndpointRequests, 1)
+						})
+					}
+
+					Context("when two services have the same id", func() {
+						verifyPOSTWithCatalogReturns400(func() common.SBCatalog {
+							catalog := common.NewEmptySBCatalog()
+							service := common.GenerateTestServiceWithPlans(common.GenerateFreeTestPlan())
+							catalog.AddService(service)
+							catalog.AddService(service)
+							return catalog
+						}, "services at positions 0 and 1 have the same id")
+					})
+
+					Context("when two plans in different services have the same id", func() {
+						verifyPOSTWithCatalogReturns400(func() common.SBCatalog {
+							catalog := common.NewEmptySBCatalog()
+							plan := common.GenerateFreeTestPlan()
+							catalog.AddService(common.GenerateTestServiceWithPlans(plan))
+							catalog.AddService(common.GenerateTestServiceWithPlans(plan))
+							return catalog
+						}, "is used by more than one plan")
+					})
+
+					Context("when a service has two plans with the same name", func() {
+						verifyPOSTWithCatalogReturns400(func() common.SBCatalog {
+							plan := common.GenerateFreeTestPlan()
+							otherPlan, err := sjson.Set(common.GenerateFreeTestPlan(), "name", gjson.Get(plan, "name").String())
+							Expect(err).ToNot(HaveOccurred())
+
+							catalog := common.NewEmptySBCatalog()
+							catalog.AddService(common.GenerateTestServiceWithPlans(plan, otherPlan))
+							return catalog
+						}, "has more than one plan with name")
 					})
 				})
 
@@ -444,6 +521,275 @@ var _ = test.DescribeTestsFor(test.TestCase{
 						})
 					})
 				})
+
+				Context("when registering asynchronously", func() {
+					postAsync := func() *httpexpect.Object {
+						return ctx.SMWithOAuth.POST("/v1/service_brokers").
+							WithQuery("async", "true").
+							WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusAccepted).
+							JSON().Object()
+					}
+
+					assertOperationState := func(operationID string, state types.OperationState) *httpexpect.Object {
+						Eventually(func() string {
+							return ctx.SMWithOAuth.GET(web.OperationsURL + "/" + operationID).
+								Expect().Status(http.StatusOK).
+								JSON().Object().Value("state").String().Raw()
+						}, 10*time.Second, 100*time.Millisecond).Should(Equal(string(state)))
+
+						return ctx.SMWithOAuth.GET(web.OperationsURL + "/" + operationID).
+							Expect().Status(http.StatusOK).
+							JSON().Object()
+					}
+
+					Context("when fetching the catalog is successful", func() {
+						It("returns 202 and stores the catalog in the background", func() {
+							operation := postAsync()
+							operation.ValueEqual("type", types.CREATE).
+								ValueEqual("resource_type", types.ServiceBrokerType)
+							brokerID := operation.Value("resource_id").String().Raw()
+
+							assertOperationState(operation.Value("id").String().Raw(), types.SUCCEEDED)
+
+							ctx.SMWithOAuth.GET("/v1/service_brokers/" + brokerID).
+								Expect().
+								Status(http.StatusOK).
+								JSON().Object().
+								ContainsMap(expectedBrokerResponse)
+
+							ctx.SMWithOAuth.GET("/v1/service_offerings").
+								WithQuery("fieldQuery", "broker_id = "+brokerID).
+								Expect().
+								Status(http.StatusOK).
+								JSON().Object().Value("service_offerings").Array().NotEmpty()
+						})
+					})
+
+					Context("when fetching the catalog keeps failing", func() {
+						BeforeEach(func() {
+							brokerServer.CatalogHandler = func(w http.ResponseWriter, req *http.Request) {
+								common.SetResponse(w, http.StatusInternalServerError, common.Object{})
+							}
+						})
+
+						It("retries and marks the operation as failed", func() {
+							operation := postAsync()
+
+							failedOperation := assertOperationState(operation.Value("id").String().Raw(), types.FAILED)
+							failedOperation.Value("errors").Object().Keys().Contains("description")
+
+							assertInvocationCount(brokerServer.CatalogEndpointRequests, api.DefaultSettings().CatalogFetchAttempts)
+						})
+					})
+
+					Context("when the request body is invalid", func() {
+						It("returns 400 without creating an operation", func() {
+							delete(postBrokerRequestWithNoLabels, "broker_url")
+
+							ctx.SMWithOAuth.POST("/v1/service_brokers").
+								WithQuery("async", "true").
+								WithJSON(postBrokerRequestWithNoLabels).
+								Expect().
+								Status(http.StatusBadRequest)
+
+							ctx.SMWithOAuth.GET(web.OperationsURL).
+								Expect().
+								Status(http.StatusOK).
+								JSON().Object().Value("operations").Array().Empty()
+						})
+					})
+				})
+			})
+
+			Describe("Validate", func() {
+				assertChecks := func(report *httpexpect.Object, statuses map[string]string) {
+					checks := report.Value("checks").Array()
+					checks.Length().Equal(len(statuses))
+					for i := range checks.Iter() {
+						check := checks.Element(i).Object()
+						name := check.Value("name").String().Raw()
+						check.Value("status").Equal(statuses[name])
+					}
+				}
+
+				assertNoBrokers := func() {
+					ctx.SMWithOAuth.GET("/v1/service_brokers").
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("items").Array().Empty()
+				}
+
+				Context("when the broker is valid", func() {
+					It("returns a passed report and does not register the broker", func() {
+						report := ctx.SMWithOAuth.POST("/v1/service_brokers/validate").WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object()
+
+						report.Value("valid").Equal(true)
+						assertChecks(report, map[string]string{
+							"reachability": "passed",
+							"credentials":  "passed",
+							"osb_version":  "passed",
+							"catalog":      "passed",
+						})
+						assertInvocationCount(brokerServer.CatalogEndpointRequests, 1)
+						assertNoBrokers()
+					})
+				})
+
+				Context("when the credentials are wrong", func() {
+					BeforeEach(func() {
+						brokerServer.Password = "wrong"
+					})
+
+					It("reports the credentials check as failed", func() {
+						report := ctx.SMWithOAuth.POST("/v1/service_brokers/validate").WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object()
+
+						report.Value("valid").Equal(false)
+						assertChecks(report, map[string]string{
+							"reachability": "passed",
+							"credentials":  "failed",
+							"osb_version":  "skipped",
+							"catalog":      "skipped",
+						})
+						assertNoBrokers()
+					})
+				})
+
+				Context("when the broker is not reachable", func() {
+					BeforeEach(func() {
+						postBrokerRequestWithNoLabels["broker_url"] = "http://localhost:1"
+					})
+
+					It("reports the reachability check as failed", func() {
+						report := ctx.SMWithOAuth.POST("/v1/service_brokers/validate").WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object()
+
+						report.Value("valid").Equal(false)
+						assertChecks(report, map[string]string{
+							"reachability": "failed",
+							"credentials":  "skipped",
+							"osb_version":  "skipped",
+							"catalog":      "skipped",
+						})
+					})
+				})
+
+				Context("when the catalog is invalid", func() {
+					BeforeEach(func() {
+						catalog, err := sjson.Delete(string(brokerServer.Catalog), "services.0.id")
+						Expect(err).ToNot(HaveOccurred())
+						brokerServer.Catalog = common.SBCatalog(catalog)
+					})
+
+					It("reports the catalog check as failed", func() {
+						report := ctx.SMWithOAuth.POST("/v1/service_brokers/validate").WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object()
+
+						report.Value("valid").Equal(false)
+						assertChecks(report, map[string]string{
+							"reachability": "passed",
+							"credentials":  "passed",
+							"osb_version":  "passed",
+							"catalog":      "failed",
+						})
+						assertNoBrokers()
+					})
+				})
+
+				Context("when the broker is created with validate=true", func() {
+					It("returns the report and does not register the broker", func() {
+						ctx.SMWithOAuth.POST("/v1/service_brokers").WithQuery("validate", "true").
+							WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object().Value("valid").Equal(true)
+
+						assertNoBrokers()
+					})
+				})
+
+				Context("when the broker is updated with validate=true", func() {
+					It("returns the report of the updated broker and does not change it", func() {
+						id := ctx.SMWithOAuth.POST("/v1/service_brokers").WithJSON(postBrokerRequestWithNoLabels).
+							Expect().
+							Status(http.StatusCreated).
+							JSON().Object().Value("id").String().Raw()
+
+						report := ctx.SMWithOAuth.PATCH("/v1/service_brokers/"+id).WithQuery("validate", "true").
+							WithJSON(common.Object{
+								"credentials": common.Object{
+									"basic": common.Object{
+										"username": brokerServer.Username,
+										"password": "wrong",
+									},
+								},
+							}).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object()
+
+						report.Value("valid").Equal(false)
+						assertChecks(report, map[string]string{
+							"reachability": "passed",
+							"credentials":  "failed",
+							"osb_version":  "skipped",
+							"catalog":      "skipped",
+						})
+
+						brokerFromDB, err := repository.Get(context.Background(), types.ServiceBrokerType, id)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(brokerFromDB.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal(brokerServer.Password))
+					})
+				})
+			})
+
+			Describe("Refetch catalog", func() {
+				var brokerID string
+
+				BeforeEach(func() {
+					brokerID = ctx.SMWithOAuth.POST("/v1/service_brokers").WithJSON(postBrokerRequestWithNoLabels).
+						Expect().
+						Status(http.StatusCreated).
+						JSON().Object().Value("id").String().Raw()
+					brokerServer.ResetCallHistory()
+				})
+
+				It("fetches and stores the catalog of the broker again", func() {
+					catalog := common.NewRandomSBCatalog()
+					brokerServer.Catalog = catalog
+
+					ctx.SMWithOAuth.POST("/v1/service_brokers/" + brokerID + "/catalog/refetch").
+						WithJSON(common.Object{}).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().
+						ContainsMap(expectedBrokerResponse)
+
+					assertInvocationCount(brokerServer.CatalogEndpointRequests, 1)
+					brokerFromDB, err := repository.Get(context.TODO(), types.ServiceBrokerType, brokerID)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(brokerFromDB.(*types.ServiceBroker).Catalog)).To(MatchJSON(string(catalog)))
+				})
+
+				It("returns 404 for unknown brokers", func() {
+					ctx.SMWithOAuth.POST("/v1/service_brokers/no_such_id/catalog/refetch").
+						WithJSON(common.Object{}).
+						Expect().
+						Status(http.StatusNotFound)
+
+					assertInvocationCount(brokerServer.CatalogEndpointRequests, 0)
+				})
 			})
 
 			Describe("PATCH", func() {
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package broker_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/catalog"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog upload", func() {
+	var (
+		ctx            *common.TestContext
+		brokerID       string
+		catalogBytes   []byte
+		catalogURL     string
+		serviceName    string
+		offeringsQuery string
+	)
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().Build()
+		// the broker is not reachable, its catalog is routed through a tunnel
+		brokerID = ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(common.Object{
+			"name":       "offline",
+			"broker_url": "http://unreachable.example.com",
+			"credentials": common.Object{
+				"basic": common.Object{"username": "username", "password": "password"},
+			},
+			"labels": common.Object{catalog.SourceLabel: common.Array{catalog.SourceUpload}},
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		sbCatalog := common.NewRandomSBCatalog()
+		catalogBytes = []byte(sbCatalog)
+		serviceName = common.JSONToMap(string(sbCatalog))["services"].([]interface{})[0].(map[string]interface{})["name"].(string)
+		catalogURL = fmt.Sprintf("%s/%s/catalog", web.ServiceBrokersURL, brokerID)
+		offeringsQuery = "broker_id = " + brokerID
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	expectOfferings := func(count int) {
+		ctx.SMWithOAuth.GET(web.ServiceOfferingsURL).WithQuery("fieldQuery", offeringsQuery).
+			Expect().Status(http.StatusOK).JSON().Path("$.service_offerings").Array().Length().Equal(count)
+	}
+
+	It("registers the broker without fetching its catalog", func() {
+		expectOfferings(0)
+	})
+
+	It("applies an uploaded catalog", func() {
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/json").WithBytes(catalogBytes).
+			Expect().Status(http.StatusOK)
+		expectOfferings(1)
+		ctx.SMWithOAuth.GET(web.ServiceOfferingsURL).WithQuery("fieldQuery", offeringsQuery).
+			Expect().Status(http.StatusOK).JSON().Path("$.service_offerings[0].name").Equal(serviceName)
+	})
+
+	It("applies a gzipped catalog", func() {
+		compressed := &bytes.Buffer{}
+		writer := gzip.NewWriter(compressed)
+		_, err := writer.Write(catalogBytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/octet-stream").WithHeader("Content-Encoding", "gzip").WithBytes(compressed.Bytes()).
+			Expect().Status(http.StatusOK)
+		expectOfferings(1)
+	})
+
+	It("applies a catalog uploaded in chunks once the last chunk is received", func() {
+		middle := len(catalogBytes) / 2
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/octet-stream").
+			WithHeader("Content-Range", fmt.Sprintf("bytes 0-%d/%d", middle-1, len(catalogBytes))).
+			WithBytes(catalogBytes[:middle]).
+			Expect().Status(http.StatusAccepted).JSON().Object().Value("received_bytes").Equal(middle)
+		expectOfferings(0)
+
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/octet-stream").
+			WithHeader("Content-Range", fmt.Sprintf("bytes %d-%d/%d", middle, len(catalogBytes)-1, len(catalogBytes))).
+			WithBytes(catalogBytes[middle:]).
+			Expect().Status(http.StatusOK)
+		expectOfferings(1)
+	})
+
+	It("rejects chunks out of order", func() {
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/octet-stream").
+			WithHeader("Content-Range", fmt.Sprintf("bytes 1-%d/%d", len(catalogBytes)-1, len(catalogBytes))).
+			WithBytes(catalogBytes[1:]).
+			Expect().Status(http.StatusConflict).JSON().Path("$.details.received_bytes").Equal(0)
+	})
+
+	It("keeps the uploaded catalog when the broker is updated", func() {
+		ctx.SMWithOAuth.PUT(catalogURL).WithHeader("Content-Type", "application/json").WithBytes(catalogBytes).
+			Expect().Status(http.StatusOK)
+		ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).WithJSON(common.Object{"description": "updated"}).
+			Expect().Status(http.StatusOK)
+		expectOfferings(1)
+	})
+
+	It("rejects uploads for brokers whose catalog is fetched", func() {
+		brokerServer := common.NewBrokerServer()
+		defer brokerServer.Close()
+		fetchedID := ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(common.Object{
+			"name":       "online",
+			"broker_url": brokerServer.URL(),
+			"credentials": common.Object{
+				"basic": common.Object{"username": brokerServer.Username, "password": brokerServer.Password},
+			},
+		}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+
+		ctx.SMWithOAuth.PUT(fmt.Sprintf("%s/%s/catalog", web.ServiceBrokersURL, fetchedID)).
+			WithHeader("Content-Type", "application/json").WithBytes(catalogBytes).
+			Expect().Status(http.StatusBadRequest)
+	})
+})
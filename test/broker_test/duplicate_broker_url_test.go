@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package broker_test
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+)
+
+var _ = Describe("Duplicate broker URLs", func() {
+	var (
+		ctx          *common.TestContext
+		brokerServer *common.BrokerServer
+	)
+
+	brokerRequest := func(name string) common.Object {
+		return common.Object{
+			"name":       name,
+			"broker_url": brokerServer.URL(),
+			"credentials": common.Object{
+				"basic": common.Object{
+					"username": brokerServer.Username,
+					"password": brokerServer.Password,
+				},
+			},
+		}
+	}
+
+	buildContext := func(policy string) {
+		ctx = common.NewTestContextBuilder().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("api.duplicate_broker_urls", policy)
+		}).Build()
+		brokerServer = common.NewBrokerServer()
+		ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(brokerRequest("first")).
+			Expect().Status(http.StatusCreated)
+	}
+
+	AfterEach(func() {
+		brokerServer.Close()
+		ctx.Cleanup()
+	})
+
+	Context("when the policy is reject", func() {
+		BeforeEach(func() {
+			buildContext(api.DuplicateBrokerURLsReject)
+		})
+
+		It("rejects a second broker with the same URL", func() {
+			ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(brokerRequest("second")).
+				Expect().Status(http.StatusConflict)
+		})
+
+		It("rejects changing the URL of another broker to the same URL", func() {
+			otherBrokerID, _, _ := ctx.RegisterBroker()
+			ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + otherBrokerID).
+				WithJSON(common.Object{"broker_url": brokerServer.URL()}).
+				Expect().Status(http.StatusConflict)
+		})
+	})
+
+	Context("when the policy is adopt", func() {
+		BeforeEach(func() {
+			buildContext(api.DuplicateBrokerURLsAdopt)
+		})
+
+		It("registers the name as an alias of the existing broker", func() {
+			adopted := ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(brokerRequest("second")).
+				Expect().Status(http.StatusOK).JSON().Object()
+			adopted.Value("name").Equal("first")
+			adopted.Path("$.labels." + api.BrokerAliasesLabel).Array().Contains("second")
+			adopted.NotContainsKey("credentials")
+
+			ctx.SMWithOAuth.GET(web.ServiceBrokersURL).
+				Expect().Status(http.StatusOK).JSON().Path("$.service_brokers").Array().Length().Equal(1)
+		})
+
+		It("rejects the adoption with other credentials", func() {
+			request := brokerRequest("second")
+			request["credentials"] = common.Object{"basic": common.Object{"username": "other", "password": "other"}}
+			ctx.SMWithOAuth.POST(web.ServiceBrokersURL).WithJSON(request).
+				Expect().Status(http.StatusConflict)
+		})
+	})
+})
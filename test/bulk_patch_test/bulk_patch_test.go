@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package bulk_patch_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBulkPatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bulk Patch Suite")
+}
+
+var _ = Describe("Bulk patch", func() {
+	var (
+		ctx         *common.TestContext
+		platformIDs []string
+	)
+
+	registerPlatform := func(tenant string) string {
+		platform := common.GenerateRandomPlatform()
+		platform["labels"] = common.Object{"tenant": common.Array{tenant}}
+		return common.RegisterPlatformInSM(platform, ctx.SMWithOAuth, map[string]string{}).ID
+	}
+
+	labelsOf := func(id string) map[string]interface{} {
+		return ctx.SMWithOAuth.GET(web.PlatformsURL + "/" + id).Expect().
+			Status(http.StatusOK).JSON().Object().Value("labels").Object().Raw()
+	}
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().
+			WithDefaultTokenClaims(map[string]interface{}{"user_name": "operator"}).
+			Build()
+		platformIDs = []string{registerPlatform("decommissioned"), registerPlatform("decommissioned"), registerPlatform("active")}
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("updates the labels of the matching resources", func() {
+		updated := ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithQuery("labelQuery", "tenant = decommissioned").
+			WithJSON(common.Object{
+				"labels": common.Array{
+					common.Object{"op": "add", "key": "state", "values": common.Array{"retired"}},
+				},
+			}).Expect().Status(http.StatusOK).JSON().Path("$.platforms[*].id").Array().Raw()
+
+		Expect(updated).To(ConsistOf(platformIDs[0], platformIDs[1]))
+		Expect(labelsOf(platformIDs[0])).To(HaveKey("state"))
+		Expect(labelsOf(platformIDs[1])).To(HaveKey("state"))
+		Expect(labelsOf(platformIDs[2])).ToNot(HaveKey("state"))
+	})
+
+	It("updates the fields of the matching resources", func() {
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithQuery("fieldQuery", "id = "+platformIDs[2]).
+			WithJSON(common.Object{"description": "updated"}).
+			Expect().Status(http.StatusOK).JSON().Path("$.platforms[0].description").Equal("updated")
+
+		ctx.SMWithOAuth.GET(web.PlatformsURL + "/" + platformIDs[0]).Expect().
+			Status(http.StatusOK).JSON().Object().Value("description").NotEqual("updated")
+	})
+
+	It("updates none of the matching resources if one of them cannot be updated", func() {
+		token := ctx.Servers[common.OauthServer].(*common.OAuthServer).CreateToken(map[string]interface{}{
+			"user_name": "automation",
+		})
+		ctx.SM.PUT(web.PlatformsURL+"/"+platformIDs[1]+"/lock").
+			WithHeader("Authorization", "Bearer "+token).
+			WithJSON(common.Object{"reason": "migration", "ttl": "30m"}).
+			Expect().Status(http.StatusCreated)
+
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithQuery("labelQuery", "tenant = decommissioned").
+			WithJSON(common.Object{
+				"labels": common.Array{
+					common.Object{"op": "add", "key": "state", "values": common.Array{"retired"}},
+				},
+			}).Expect().Status(http.StatusLocked)
+
+		Expect(labelsOf(platformIDs[0])).ToNot(HaveKey("state"))
+		Expect(labelsOf(platformIDs[1])).ToNot(HaveKey("state"))
+	})
+
+	It("does not fetch the catalogs of brokers whose labels only are changed", func() {
+		var brokerIDs []string
+		var brokerServers []*common.BrokerServer
+		for i := 0; i < 2; i++ {
+			brokerID, _, brokerServer := ctx.RegisterBroker()
+			ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+				WithJSON(common.Object{
+					"labels": common.Array{
+						common.Object{"op": "add", "key": "tenant", "values": common.Array{"decommissioned"}},
+					},
+				}).Expect().Status(http.StatusOK)
+			brokerServer.ResetCallHistory()
+			brokerIDs = append(brokerIDs, brokerID)
+			brokerServers = append(brokerServers, brokerServer)
+		}
+		brokerServers[1].CatalogHandler = func(rw http.ResponseWriter, req *http.Request) {
+			common.SetResponse(rw, http.StatusInternalServerError, common.Object{})
+		}
+
+		updated := ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL).
+			WithQuery("labelQuery", "tenant = decommissioned").
+			WithJSON(common.Object{
+				"labels": common.Array{
+					common.Object{"op": "add", "key": "state", "values": common.Array{"retired"}},
+				},
+			}).Expect().Status(http.StatusOK).JSON().Path("$.service_brokers[*].id").Array().Raw()
+
+		Expect(updated).To(ConsistOf(brokerIDs[0], brokerIDs[1]))
+		for _, brokerServer := range brokerServers {
+			Expect(brokerServer.CatalogEndpointRequests).To(BeEmpty())
+		}
+	})
+
+	It("does not return the credentials", func() {
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithQuery("labelQuery", "tenant = active").
+			WithJSON(common.Object{"description": "updated"}).
+			Expect().Status(http.StatusOK).Body().NotContains("credentials")
+	})
+
+	It("requires criteria", func() {
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithJSON(common.Object{"description": "updated"}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("rejects invalid criteria", func() {
+		ctx.SMWithOAuth.PATCH(web.PlatformsURL).
+			WithQuery("fieldQuery", "unknown = value").
+			WithJSON(common.Object{"description": "updated"}).
+			Expect().Status(http.StatusBadRequest)
+	})
+})
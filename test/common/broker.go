@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/gorilla/mux"
@@ -51,6 +52,10 @@ type BrokerServer struct {
 	BindingLastOpEndpointRequests           []*http.Request
 	BindingAdaptCredentialsEndpointRequests []*http.Request
 
+	// Requests contains all requests received by the broker in the order of their arrival
+	Requests      []RecordedRequest
+	requestsMutex sync.Mutex
+
 	router *mux.Router
 }
 
@@ -108,6 +113,11 @@ func (b *BrokerServer) ResetCallHistory() {
 	b.ServiceInstanceLastOpEndpointRequests = make([]*http.Request, 0)
 	b.BindingEndpointRequests = make([]*http.Request, 0)
 	b.BindingLastOpEndpointRequests = make([]*http.Request, 0)
+	b.BindingAdaptCredentialsEndpointRequests = make([]*http.Request, 0)
+
+	b.requestsMutex.Lock()
+	b.Requests = make([]RecordedRequest, 0)
+	b.requestsMutex.Unlock()
 }
 
 func (b *BrokerServer) initRouter() {
@@ -192,6 +202,7 @@ func (b *BrokerServer) saveRequestMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		b.LastRequestBody = bodyBytes
+		b.recordRequest(req, bodyBytes)
 		next.ServeHTTP(w, req)
 	})
 }
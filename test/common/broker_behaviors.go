@@ -0,0 +1,178 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedRequest is a request received by a BrokerServer together with its body
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Header http.Header
+	Body   []byte
+}
+
+// JSON returns the body of the recorded request as JSON object
+func (r RecordedRequest) JSON() map[string]interface{} {
+	return JSONToMap(string(r.Body))
+}
+
+// RequestsFor returns the requests received by the broker with the provided method and path, the path may contain
+// "*" as placeholder for a single path segment, e.g. /v2/service_instances/*/last_operation
+func (b *BrokerServer) RequestsFor(method, path string) []RecordedRequest {
+	b.requestsMutex.Lock()
+	defer b.requestsMutex.Unlock()
+
+	result := make([]RecordedRequest, 0)
+	for _, request := range b.Requests {
+		if request.Method == method && pathMatches(path, request.Path) {
+			result = append(result, request)
+		}
+	}
+	return result
+}
+
+// LastRequestFor returns the last request received by the broker with the provided method and path and false if no such
+// request was received
+func (b *BrokerServer) LastRequestFor(method, path string) (RecordedRequest, bool) {
+	requests := b.RequestsFor(method, path)
+	if len(requests) == 0 {
+		return RecordedRequest{}, false
+	}
+	return requests[len(requests)-1], true
+}
+
+func (b *BrokerServer) recordRequest(req *http.Request, body []byte) {
+	b.requestsMutex.Lock()
+	defer b.requestsMutex.Unlock()
+
+	b.Requests = append(b.Requests, RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header,
+		Body:   body,
+	})
+}
+
+func pathMatches(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DelayingHandler returns a handler which waits for the specified delay before invoking the provided handler
+func DelayingHandler(delay time.Duration, handler http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+		handler(rw, req)
+	}
+}
+
+// StatusHandler returns a handler which responds with the provided status code and JSON body
+func StatusHandler(status int, body interface{}) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		SetResponse(rw, status, body)
+	}
+}
+
+// MalformedBodyHandler returns a handler which responds with the provided status code and a body which is not valid JSON
+func MalformedBodyHandler(status int) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		rw.Write([]byte(`{"malformed":`))
+	}
+}
+
+// NthCallHandler returns a handler which invokes the override handler on the nth call (starting from 1) and the provided
+// handler on all other calls
+func NthCallHandler(n int, override, handler http.HandlerFunc) http.HandlerFunc {
+	var mutex sync.Mutex
+	calls := 0
+	return func(rw http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		calls++
+		call := calls
+		mutex.Unlock()
+
+		if call == n {
+			override(rw, req)
+			return
+		}
+		handler(rw, req)
+	}
+}
+
+// SequenceHandler returns a handler which invokes the provided handlers one after another on subsequent calls. Once all
+// handlers are invoked, the last one is used for all further calls.
+func SequenceHandler(handlers ...http.HandlerFunc) http.HandlerFunc {
+	if len(handlers) == 0 {
+		panic("at least one handler must be provided")
+	}
+	var mutex sync.Mutex
+	calls := 0
+	return func(rw http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		handler := handlers[len(handlers)-1]
+		if calls < len(handlers) {
+			handler = handlers[calls]
+		}
+		calls++
+		mutex.Unlock()
+
+		handler(rw, req)
+	}
+}
+
+// AsyncHandler returns a handler which accepts the request for asynchronous processing and responds with the provided
+// operation
+func AsyncHandler(operation string) http.HandlerFunc {
+	return StatusHandler(http.StatusAccepted, Object{
+		"operation": operation,
+	})
+}
+
+// LastOperationHandler returns a last operation handler which responds with the provided states one after another on
+// subsequent polls, e.g. "in progress", "in progress", "succeeded". The last state is returned on all further polls.
+func LastOperationHandler(states ...string) http.HandlerFunc {
+	handlers := make([]http.HandlerFunc, 0, len(states))
+	for _, state := range states {
+		handlers = append(handlers, StatusHandler(http.StatusOK, Object{
+			"state": state,
+		}))
+	}
+	return SequenceHandler(handlers...)
+}
@@ -0,0 +1,180 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/gavv/httpexpect"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// AddPlan adds the plan to the service with the provided index in the catalog served by the broker and returns the
+// catalog ID of the plan
+func (b *BrokerServer) AddPlan(serviceIndex int, plan string) string {
+	b.Catalog.AddPlanToService(plan, serviceIndex)
+	return gjson.Get(plan, "id").String()
+}
+
+// RemoveService removes the service with the provided index from the catalog served by the broker and returns the
+// catalog ID of the service
+func (b *BrokerServer) RemoveService(serviceIndex int) string {
+	_, service := b.Catalog.RemoveService(serviceIndex)
+	return gjson.Get(service, "id").String()
+}
+
+// RemovePlan removes the plan with the provided index from the catalog served by the broker and returns the catalog
+// ID of the plan
+func (b *BrokerServer) RemovePlan(serviceIndex, planIndex int) string {
+	_, plan := b.Catalog.RemovePlan(serviceIndex, planIndex)
+	return gjson.Get(plan, "id").String()
+}
+
+// SetServiceBindable changes whether the service with the provided index in the catalog served by the broker is
+// bindable and returns the catalog ID of the service
+func (b *BrokerServer) SetServiceBindable(serviceIndex int, bindable bool) string {
+	b.Catalog.SetServiceProperty(serviceIndex, "bindable", bindable)
+	return gjson.Get(string(b.Catalog), fmt.Sprintf("services.%d.id", serviceIndex)).String()
+}
+
+// SetServiceProperty sets the property of the service with the provided index to the provided value
+func (sbc *SBCatalog) SetServiceProperty(serviceIndex int, property string, value interface{}) {
+	s, err := sjson.Set(string(*sbc), fmt.Sprintf("services.%d.%s", serviceIndex, property), value)
+	if err != nil {
+		panic(err)
+	}
+
+	*sbc = SBCatalog(s)
+}
+
+// RefreshBrokerCatalog triggers a refetch of the catalog of the broker with the provided ID
+func (ctx *TestContext) RefreshBrokerCatalog(brokerID string) {
+	ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+		WithJSON(Object{}).
+		Expect().
+		Status(http.StatusOK)
+}
+
+// ExpectServiceOffering asserts that the broker with the provided ID has a service offering with the provided catalog ID
+// and returns it
+func (ctx *TestContext) ExpectServiceOffering(brokerID, catalogID string) *httpexpect.Object {
+	offerings := ctx.listServiceOfferings(brokerID, catalogID)
+	offerings.Length().Equal(1)
+	return offerings.First().Object()
+}
+
+// ExpectNoServiceOffering asserts that the broker with the provided ID has no service offering with the provided catalog ID
+func (ctx *TestContext) ExpectNoServiceOffering(brokerID, catalogID string) {
+	ctx.listServiceOfferings(brokerID, catalogID).Empty()
+}
+
+// ExpectServicePlan asserts that a service plan with the provided catalog ID exists and returns it
+func (ctx *TestContext) ExpectServicePlan(catalogID string) *httpexpect.Object {
+	plans := ctx.listServicePlans(catalogID)
+	plans.Length().Equal(1)
+	return plans.First().Object()
+}
+
+// ExpectNoServicePlan asserts that no service plan with the provided catalog ID exists
+func (ctx *TestContext) ExpectNoServicePlan(catalogID string) {
+	ctx.listServicePlans(catalogID).Empty()
+}
+
+func (ctx *TestContext) listServiceOfferings(brokerID, catalogID string) *httpexpect.Array {
+	return ctx.SMWithOAuth.GET(web.ServiceOfferingsURL).
+		WithQuery("fieldQuery", fmt.Sprintf("broker_id = %s|catalog_id = %s", brokerID, catalogID)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("service_offerings").Array()
+}
+
+func (ctx *TestContext) listServicePlans(catalogID string) *httpexpect.Array {
+	return ctx.SMWithOAuth.GET(web.ServicePlansURL).
+		WithQuery("fieldQuery", "catalog_id = "+catalogID).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("service_plans").Array()
+}
+
+// WithCatalogService matches broker notifications whose new catalog contains the service with the provided catalog ID
+func WithCatalogService(catalogID string) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("with catalog service %s", catalogID),
+		Matches: func(notification *types.Notification) bool {
+			return catalogService(notification, catalogID).Exists()
+		},
+	}
+}
+
+// WithoutCatalogService matches broker notifications whose new catalog does not contain the service with the provided
+// catalog ID
+func WithoutCatalogService(catalogID string) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("without catalog service %s", catalogID),
+		Matches: func(notification *types.Notification) bool {
+			return notification.Resource == types.ServiceBrokerType && !catalogService(notification, catalogID).Exists()
+		},
+	}
+}
+
+// WithCatalogPlan matches broker notifications whose new catalog contains the plan with the provided catalog ID
+func WithCatalogPlan(catalogID string) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("with catalog plan %s", catalogID),
+		Matches: func(notification *types.Notification) bool {
+			if notification.Resource != types.ServiceBrokerType {
+				return false
+			}
+			for _, service := range gjson.GetBytes(notification.Payload, "new.additional.services").Array() {
+				for _, plan := range service.Get("plans").Array() {
+					if plan.Get("catalog_id").String() == catalogID {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+}
+
+// WithCatalogServiceBindable matches broker notifications whose new catalog contains the service with the provided
+// catalog ID with the provided bindable value
+func WithCatalogServiceBindable(catalogID string, bindable bool) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("with catalog service %s bindable %t", catalogID, bindable),
+		Matches: func(notification *types.Notification) bool {
+			service := catalogService(notification, catalogID)
+			return service.Exists() && service.Get("bindable").Bool() == bindable
+		},
+	}
+}
+
+func catalogService(notification *types.Notification, catalogID string) gjson.Result {
+	if notification.Resource != types.ServiceBrokerType {
+		return gjson.Result{}
+	}
+	for _, service := range gjson.GetBytes(notification.Payload, "new.additional.services").Array() {
+		if service.Get("catalog_id").String() == catalogID {
+			return service
+		}
+	}
+	return gjson.Result{}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/onsi/ginkgo"
 	"github.com/spf13/pflag"
 
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
@@ -38,6 +39,7 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"math/big"
 
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/gavv/httpexpect"
@@ -271,8 +273,12 @@ type jwkResponse struct {
 	Algorithm string `json:"alg"`
 	Value     string `json:"value"`
 
-	PublicKeyExponent string `json:"e"`
-	PublicKeyModulus  string `json:"n"`
+	PublicKeyExponent string `json:"e,omitempty"`
+	PublicKeyModulus  string `json:"n,omitempty"`
+
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
 }
 
 func newJwkResponse(keyID string, publicKey rsa.PublicKey) *jwkResponse {
@@ -294,15 +300,30 @@ func newJwkResponse(keyID string, publicKey rsa.PublicKey) *jwkResponse {
 	}
 }
 
-func MakePlatform(id string, name string, atype string, description string) Object {
-	return Object{
-		"id":          id,
-		"name":        name,
-		"type":        atype,
-		"description": description,
+func newECJwkResponse(keyID string, publicKey ecdsa.PublicKey) *jwkResponse {
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	coordinate := func(value *big.Int) string {
+		data := make([]byte, size)
+		raw := value.Bytes()
+		copy(data[size-len(raw):], raw)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	return &jwkResponse{
+		KeyType:   "EC",
+		Use:       "sig",
+		KeyID:     keyID,
+		Algorithm: "ES256",
+		Curve:     publicKey.Curve.Params().Name,
+		X:         coordinate(publicKey.X),
+		Y:         coordinate(publicKey.Y),
 	}
 }
 
+func MakePlatform(id string, name string, atype string, description string) Object {
+	return PlatformFixture().WithID(id).WithName(name).WithType(atype).WithDescription(description).JSON()
+}
+
 func GenerateRandomNotification() *types.Notification {
 	uid, err := uuid.NewV4()
 	if err != nil {
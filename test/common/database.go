@@ -0,0 +1,94 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/onsi/ginkgo/config"
+)
+
+// IsolatedDatabaseEnvVar is the environment variable which makes all test contexts use an isolated database when
+// it is set to "true". This allows running the test packages in parallel with "go test -p".
+const IsolatedDatabaseEnvVar = "SM_TEST_ISOLATED_DATABASE"
+
+// IsolatedDatabase is a database which is created for a single test context so that test contexts which run in
+// parallel, e.g. on different ginkgo nodes, do not share their tables, notification channels and locks
+type IsolatedDatabase struct {
+	Name string
+	URI  string
+
+	adminURI string
+}
+
+// CreateIsolatedDatabase creates a new uniquely named database on the Postgres server referenced by the provided
+// storage URI and returns a URI to it. The Service Manager migrations are applied when the storage is opened.
+func CreateIsolatedDatabase(storageURI string) (*IsolatedDatabase, error) {
+	uri, err := url.Parse(storageURI)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse storage URI: %s", err)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate database name: %s", err)
+	}
+	name := fmt.Sprintf("sm_test_%d_%s", config.GinkgoConfig.ParallelNode, strings.Replace(id.String(), "-", "", -1))
+
+	if err := execAdmin(storageURI, "CREATE DATABASE "+name); err != nil {
+		return nil, fmt.Errorf("could not create database %s: %s", name, err)
+	}
+
+	uri.Path = "/" + name
+	return &IsolatedDatabase{
+		Name:     name,
+		URI:      uri.String(),
+		adminURI: storageURI,
+	}, nil
+}
+
+// Drop terminates the remaining connections to the database and drops it
+func (d *IsolatedDatabase) Drop() {
+	if d == nil {
+		return
+	}
+	statements := []string{
+		fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = '%s' AND pid <> pg_backend_pid()", d.Name),
+		"DROP DATABASE IF EXISTS " + d.Name,
+	}
+	for _, statement := range statements {
+		if err := execAdmin(d.adminURI, statement); err != nil {
+			fmt.Fprintf(os.Stderr, "could not drop database %s: %s\n", d.Name, err)
+			return
+		}
+	}
+}
+
+func execAdmin(uri, statement string) error {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(statement)
+	return err
+}
@@ -0,0 +1,304 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Fixture is a resource which can be loaded in the Service Manager through the API or directly through the repository
+type Fixture interface {
+	// URL returns the API URL of the resources of the fixture's type
+	URL() string
+
+	// JSON returns the fixture as API request body
+	JSON() Object
+
+	// Object returns an empty object of the fixture's type
+	Object() types.Object
+
+	// prepare is invoked prior to loading the fixture and allows starting the servers the fixture depends on
+	prepare(ctx *TestContext)
+
+	// loaded is invoked with the response of the API or the object stored in the repository
+	loaded(ctx *TestContext, resource Object)
+}
+
+type fixture struct {
+	json Object
+}
+
+func newFixture() fixture {
+	return fixture{
+		json: Object{
+			"name":        randomName(),
+			"description": randomName(),
+		},
+	}
+}
+
+func (f *fixture) JSON() Object {
+	result := Object{}
+	for key, value := range f.json {
+		result[key] = value
+	}
+	return result
+}
+
+func (f *fixture) set(key string, value interface{}) {
+	f.json[key] = value
+}
+
+func (f *fixture) setLabels(labels map[string][]string) {
+	f.json["labels"] = labels
+}
+
+func (f *fixture) prepare(ctx *TestContext) {}
+
+func (f *fixture) loaded(ctx *TestContext, resource Object) {}
+
+// BrokerFixtureBuilder builds a broker which is backed by a fake broker server started on load
+type BrokerFixtureBuilder struct {
+	fixture
+
+	Server  *BrokerServer
+	catalog SBCatalog
+}
+
+// BrokerFixture returns a builder for a broker with random name and catalog
+func BrokerFixture() *BrokerFixtureBuilder {
+	return &BrokerFixtureBuilder{
+		fixture: newFixture(),
+		catalog: NewRandomSBCatalog(),
+	}
+}
+
+// WithName sets the name of the broker
+func (b *BrokerFixtureBuilder) WithName(name string) *BrokerFixtureBuilder {
+	b.set("name", name)
+	return b
+}
+
+// WithDescription sets the description of the broker
+func (b *BrokerFixtureBuilder) WithDescription(description string) *BrokerFixtureBuilder {
+	b.set("description", description)
+	return b
+}
+
+// WithCatalog sets the catalog served by the fake broker server
+func (b *BrokerFixtureBuilder) WithCatalog(catalog SBCatalog) *BrokerFixtureBuilder {
+	b.catalog = catalog
+	return b
+}
+
+// WithLabels sets the labels of the broker
+func (b *BrokerFixtureBuilder) WithLabels(labels map[string][]string) *BrokerFixtureBuilder {
+	b.setLabels(labels)
+	return b
+}
+
+// URL implements Fixture
+func (b *BrokerFixtureBuilder) URL() string {
+	return web.ServiceBrokersURL
+}
+
+// Object implements Fixture
+func (b *BrokerFixtureBuilder) Object() types.Object {
+	return &types.ServiceBroker{}
+}
+
+func (b *BrokerFixtureBuilder) prepare(ctx *TestContext) {
+	b.Server = NewBrokerServerWithCatalog(b.catalog)
+	b.set("broker_url", b.Server.URL())
+	b.set("credentials", Object{
+		"basic": Object{
+			"username": b.Server.Username,
+			"password": b.Server.Password,
+		},
+	})
+}
+
+func (b *BrokerFixtureBuilder) loaded(ctx *TestContext, resource Object) {
+	b.Server.ResetCallHistory()
+	ctx.Servers[BrokerServerPrefix+resource["id"].(string)] = b.Server
+}
+
+// PlatformFixtureBuilder builds a platform
+type PlatformFixtureBuilder struct {
+	fixture
+}
+
+// PlatformFixture returns a builder for a platform with random name
+func PlatformFixture() *PlatformFixtureBuilder {
+	platform := &PlatformFixtureBuilder{
+		fixture: newFixture(),
+	}
+	platform.set("type", "test-platform-type")
+	return platform
+}
+
+// WithID sets the ID of the platform
+func (p *PlatformFixtureBuilder) WithID(id string) *PlatformFixtureBuilder {
+	p.set("id", id)
+	return p
+}
+
+// WithName sets the name of the platform
+func (p *PlatformFixtureBuilder) WithName(name string) *PlatformFixtureBuilder {
+	p.set("name", name)
+	return p
+}
+
+// WithType sets the type of the platform
+func (p *PlatformFixtureBuilder) WithType(platformType string) *PlatformFixtureBuilder {
+	p.set("type", platformType)
+	return p
+}
+
+// WithDescription sets the description of the platform
+func (p *PlatformFixtureBuilder) WithDescription(description string) *PlatformFixtureBuilder {
+	p.set("description", description)
+	return p
+}
+
+// WithLabels sets the labels of the platform
+func (p *PlatformFixtureBuilder) WithLabels(labels map[string][]string) *PlatformFixtureBuilder {
+	p.setLabels(labels)
+	return p
+}
+
+// URL implements Fixture
+func (p *PlatformFixtureBuilder) URL() string {
+	return web.PlatformsURL
+}
+
+// Object implements Fixture
+func (p *PlatformFixtureBuilder) Object() types.Object {
+	return &types.Platform{}
+}
+
+// VisibilityFixtureBuilder builds a visibility
+type VisibilityFixtureBuilder struct {
+	fixture
+}
+
+// VisibilityFixture returns a builder for a visibility of the provided plan to all platforms
+func VisibilityFixture(servicePlanID string) *VisibilityFixtureBuilder {
+	return &VisibilityFixtureBuilder{
+		fixture: fixture{
+			json: Object{
+				"service_plan_id": servicePlanID,
+			},
+		},
+	}
+}
+
+// ForPlatform restricts the visibility to the provided platform
+func (v *VisibilityFixtureBuilder) ForPlatform(platformID string) *VisibilityFixtureBuilder {
+	v.set("platform_id", platformID)
+	return v
+}
+
+// WithLabels sets the labels of the visibility
+func (v *VisibilityFixtureBuilder) WithLabels(labels map[string][]string) *VisibilityFixtureBuilder {
+	v.setLabels(labels)
+	return v
+}
+
+// URL implements Fixture
+func (v *VisibilityFixtureBuilder) URL() string {
+	return web.VisibilitiesURL
+}
+
+// Object implements Fixture
+func (v *VisibilityFixtureBuilder) Object() types.Object {
+	return &types.Visibility{}
+}
+
+// LoadFixtures creates the fixtures one after another through the API and returns the created resources
+func (ctx *TestContext) LoadFixtures(fixtures ...Fixture) []Object {
+	resources := make([]Object, 0, len(fixtures))
+	for _, f := range fixtures {
+		f.prepare(ctx)
+		resource := ctx.SMWithOAuth.POST(f.URL()).
+			WithJSON(f.JSON()).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().Object().Raw()
+		f.loaded(ctx, resource)
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// LoadFixturesInRepository creates the fixtures one after another directly in the repository, bypassing the API
+// filters and validation, and returns the created resources. The storage interceptors are still invoked.
+func (ctx *TestContext) LoadFixturesInRepository(fixtures ...Fixture) []Object {
+	resources := make([]Object, 0, len(fixtures))
+	for _, f := range fixtures {
+		f.prepare(ctx)
+		resourceJSON := f.JSON()
+		if _, found := resourceJSON["id"]; !found {
+			resourceJSON["id"] = randomName()
+		}
+		now := time.Now().UTC()
+		resourceJSON["created_at"] = now
+		resourceJSON["updated_at"] = now
+
+		obj := f.Object()
+		if err := remarshal(resourceJSON, obj); err != nil {
+			panic(fmt.Sprintf("could not convert fixture to %T: %s", obj, err))
+		}
+		createdObj, err := ctx.SMRepository.Create(context.Background(), obj)
+		if err != nil {
+			panic(fmt.Sprintf("could not create fixture %v: %s", resourceJSON, err))
+		}
+
+		resource := Object{}
+		if err := remarshal(createdObj, &resource); err != nil {
+			panic(fmt.Sprintf("could not convert %T to fixture: %s", createdObj, err))
+		}
+		f.loaded(ctx, resource)
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func remarshal(source, target interface{}) error {
+	bytes, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, target)
+}
+
+func randomName() string {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return UUID.String()
+}
@@ -0,0 +1,232 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/onsi/ginkgo"
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/api/notifications"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// DefaultNotificationTimeout is the time NotificationClient expectations wait for a notification
+const DefaultNotificationTimeout = 5 * time.Second
+
+// NotificationMatcher matches notifications received by a NotificationClient
+type NotificationMatcher struct {
+	Description string
+	Matches     func(notification *types.Notification) bool
+}
+
+// OfType matches notifications of the provided type
+func OfType(notificationType types.OperationType) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("of type %s", notificationType),
+		Matches: func(notification *types.Notification) bool {
+			return notification.Type == notificationType
+		},
+	}
+}
+
+// ForResource matches notifications about the resource of the provided type and ID
+func ForResource(objectType types.ObjectType, id string) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("for %s with id %s", objectType, id),
+		Matches: func(notification *types.Notification) bool {
+			if notification.Resource != objectType {
+				return false
+			}
+			for _, path := range []string{"new.resource.id", "old.resource.id"} {
+				if gjson.GetBytes(notification.Payload, path).String() == id {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// WithID matches the notification with the provided ID
+func WithID(id string) NotificationMatcher {
+	return NotificationMatcher{
+		Description: fmt.Sprintf("with id %s", id),
+		Matches: func(notification *types.Notification) bool {
+			return notification.ID == id
+		},
+	}
+}
+
+// NotificationClient is a platform connected to the notifications websocket of the Service Manager. Notifications are
+// read in the background and can be asserted on with the Expect functions.
+type NotificationClient struct {
+	Platform *types.Platform
+	Conn     *websocket.Conn
+	Response *http.Response
+	Timeout  time.Duration
+
+	ctx           *TestContext
+	notifications chan *types.Notification
+
+	mutex        sync.Mutex
+	lastRevision int64
+	readErr      error
+}
+
+// ConnectAsPlatform connects to the notifications websocket with the credentials of the provided platform
+func (ctx *TestContext) ConnectAsPlatform(platform *types.Platform, queryParams map[string]string) (*NotificationClient, error) {
+	conn, resp, err := ctx.ConnectWebSocket(platform, queryParams)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect platform %s to notifications websocket: %s", platform.ID, err)
+	}
+
+	client := &NotificationClient{
+		Platform:      platform,
+		Conn:          conn,
+		Response:      resp,
+		Timeout:       DefaultNotificationTimeout,
+		ctx:           ctx,
+		notifications: make(chan *types.Notification, 100),
+		lastRevision:  types.InvalidRevision,
+	}
+	if revision := resp.Header.Get(notifications.LastKnownRevisionHeader); revision != "" {
+		if client.lastRevision, err = strconv.ParseInt(revision, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s header %s: %s", notifications.LastKnownRevisionHeader, revision, err)
+		}
+	}
+	if lastKnownRevision, found := queryParams[notifications.LastKnownRevisionQueryParam]; found {
+		if revision, err := strconv.ParseInt(lastKnownRevision, 10, 64); err == nil {
+			client.lastRevision = revision
+		}
+	}
+
+	go client.read()
+	return client, nil
+}
+
+// LastRevision returns the revision of the last notification read by the client or the revision known to the
+// Service Manager on connect if no notification was read
+func (c *NotificationClient) LastRevision() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lastRevision
+}
+
+// ExpectNotification waits for a notification matching all matchers and fails the test if no such notification is
+// received within the timeout. Notifications which do not match are skipped.
+func (c *NotificationClient) ExpectNotification(matchers ...NotificationMatcher) *types.Notification {
+	timeout := time.After(c.Timeout)
+	skipped := make([]string, 0)
+	for {
+		select {
+		case notification, ok := <-c.notifications:
+			if !ok {
+				ginkgo.Fail(fmt.Sprintf("connection closed while waiting for notification %s: %v", describe(matchers), c.err()))
+				return nil
+			}
+			if matchesAll(notification, matchers) {
+				return notification
+			}
+			skipped = append(skipped, fmt.Sprintf("%s %s %s", notification.ID, notification.Type, notification.Resource))
+		case <-timeout:
+			ginkgo.Fail(fmt.Sprintf("no notification %s received within %s, received: %v", describe(matchers), c.Timeout, skipped))
+			return nil
+		}
+	}
+}
+
+// ExpectNoNotification fails the test if a notification matching all matchers is received within the provided duration
+func (c *NotificationClient) ExpectNoNotification(within time.Duration, matchers ...NotificationMatcher) {
+	timeout := time.After(within)
+	for {
+		select {
+		case notification, ok := <-c.notifications:
+			if !ok {
+				return
+			}
+			if matchesAll(notification, matchers) {
+				ginkgo.Fail(fmt.Sprintf("unexpected notification %s %s received: %s %s %s", describe(matchers), notification.ID, notification.Type, notification.Resource, notification.Payload))
+				return
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// Reconnect closes the connection and connects again providing the last read revision, as a proxy which restarts would do
+func (c *NotificationClient) Reconnect() (*NotificationClient, error) {
+	c.Close()
+	return c.ctx.ConnectAsPlatform(c.Platform, map[string]string{
+		notifications.LastKnownRevisionQueryParam: strconv.FormatInt(c.LastRevision(), 10),
+	})
+}
+
+// Close closes the connection to the Service Manager
+func (c *NotificationClient) Close() {
+	c.ctx.CloseWebSocket(c.Conn)
+}
+
+func (c *NotificationClient) read() {
+	defer close(c.notifications)
+	for {
+		notification := &types.Notification{}
+		if err := c.Conn.ReadJSON(notification); err != nil {
+			c.mutex.Lock()
+			c.readErr = err
+			c.mutex.Unlock()
+			return
+		}
+		c.mutex.Lock()
+		if notification.Revision > c.lastRevision {
+			c.lastRevision = notification.Revision
+		}
+		c.mutex.Unlock()
+		c.notifications <- notification
+	}
+}
+
+func (c *NotificationClient) err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.readErr
+}
+
+func matchesAll(notification *types.Notification, matchers []NotificationMatcher) bool {
+	for _, matcher := range matchers {
+		if !matcher.Matches(notification) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(matchers []NotificationMatcher) string {
+	descriptions := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		descriptions = append(descriptions, matcher.Description)
+	}
+	return strings.Join(descriptions, " and ")
+}
@@ -17,34 +17,91 @@
 package common
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"time"
 
 	"github.com/gbrlsnchs/jwt"
 )
 
+// SigningAlgorithm is an algorithm with which the OAuthServer signs tokens
+type SigningAlgorithm string
+
+const (
+	// RS256 signs tokens with an RSA key
+	RS256 SigningAlgorithm = "RS256"
+	// ES256 signs tokens with an ECDSA P-256 key
+	ES256 SigningAlgorithm = "ES256"
+	// HS256 signs tokens with a shared secret which is not published in the token keys
+	HS256 SigningAlgorithm = "HS256"
+)
+
 type OAuthServer struct {
 	BaseURL string
 
-	server     *httptest.Server
-	mux        *http.ServeMux
-	privateKey *rsa.PrivateKey // public key privateKey.PublicKey
-	signer     jwt.Signer
-	keyID      string
+	server *httptest.Server
+	mux    *http.ServeMux
+
+	mutex      sync.RWMutex
+	signingKey *signingKey
+	keys       []*signingKey
+	keyCounter int
+}
+
+// TokenOption modifies the options with which a token is signed
+type TokenOption func(options *jwt.Options)
+
+// WithLifetime makes the token expire after the provided duration
+func WithLifetime(lifetime time.Duration) TokenOption {
+	return func(options *jwt.Options) {
+		options.ExpirationTime = time.Now().Add(lifetime)
+	}
+}
+
+// Expired makes the token already expired
+func Expired() TokenOption {
+	return WithLifetime(-time.Hour)
+}
+
+// NotYetValid makes the token valid only in the future
+func NotYetValid() TokenOption {
+	return func(options *jwt.Options) {
+		options.NotBefore = time.Now().Add(time.Hour)
+	}
+}
+
+// WithAudience sets the audience of the token
+func WithAudience(audience string) TokenOption {
+	return func(options *jwt.Options) {
+		options.Audience = audience
+	}
+}
+
+// WithIssuer sets the issuer of the token
+func WithIssuer(issuer string) TokenOption {
+	return func(options *jwt.Options) {
+		options.Issuer = issuer
+	}
 }
 
 func NewOAuthServer() *OAuthServer {
-	privateKey := generatePrivateKey()
+	return NewOAuthServerWithAlgorithm(RS256)
+}
 
+// NewOAuthServerWithAlgorithm returns an OAuthServer which signs the tokens with the provided algorithm
+func NewOAuthServerWithAlgorithm(algorithm SigningAlgorithm) *OAuthServer {
 	os := &OAuthServer{
-		privateKey: privateKey,
-		signer:     jwt.RS256(privateKey, &privateKey.PublicKey),
-		keyID:      "test-key",
-		mux:        http.NewServeMux(),
+		mux: http.NewServeMux(),
 	}
+	os.RotateKey(algorithm, false)
 	os.mux.HandleFunc("/.well-known/openid-configuration", os.getOpenIDConfig)
 	os.mux.HandleFunc("/oauth/token", os.getToken)
 	os.mux.HandleFunc("/token_keys", os.getTokenKeys)
@@ -72,6 +129,26 @@ func (os *OAuthServer) URL() string {
 	return os.BaseURL
 }
 
+// RotateKey generates a new key with the provided algorithm which is used for signing all further tokens. If keepPrevious
+// is true the previous keys are still published so that tokens signed with them remain valid.
+func (os *OAuthServer) RotateKey(algorithm SigningAlgorithm, keepPrevious bool) {
+	os.mutex.Lock()
+	defer os.mutex.Unlock()
+
+	keyID := "test-key"
+	if os.keyCounter > 0 {
+		keyID = fmt.Sprintf("test-key-%d", os.keyCounter)
+	}
+	os.keyCounter++
+
+	os.signingKey = newSigningKey(keyID, algorithm)
+	if keepPrevious {
+		os.keys = append(os.keys, os.signingKey)
+	} else {
+		os.keys = []*signingKey{os.signingKey}
+	}
+}
+
 func (os *OAuthServer) getOpenIDConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(`{
@@ -89,20 +166,34 @@ func (os *OAuthServer) getToken(w http.ResponseWriter, r *http.Request) {
 }
 
 func (os *OAuthServer) CreateToken(payload map[string]interface{}) string {
+	return os.CreateTokenWithOptions(payload)
+}
+
+// CreateTokenWithOptions creates a token with the provided payload which is valid for a year, unless modified by the options
+func (os *OAuthServer) CreateTokenWithOptions(payload map[string]interface{}, tokenOptions ...TokenOption) string {
 	var issuerURL string
 	if iss, ok := payload["iss"]; ok {
 		issuerURL = iss.(string)
 	} else {
 		issuerURL = os.BaseURL + "/oauth/token"
 	}
+
+	os.mutex.RLock()
+	key := os.signingKey
+	os.mutex.RUnlock()
+
 	nextYear := time.Now().Add(365 * 24 * time.Hour)
-	token, err := jwt.Sign(os.signer, &jwt.Options{
+	options := &jwt.Options{
 		Issuer:         issuerURL,
-		KeyID:          os.keyID,
+		KeyID:          key.id,
 		Audience:       "sm",
 		ExpirationTime: nextYear,
 		Public:         payload,
-	})
+	}
+	for _, tokenOption := range tokenOptions {
+		tokenOption(options)
+	}
+	token, err := jwt.Sign(key.signer, options)
 	if err != nil {
 		panic(err)
 	}
@@ -110,13 +201,68 @@ func (os *OAuthServer) CreateToken(payload map[string]interface{}) string {
 }
 
 func (os *OAuthServer) getTokenKeys(w http.ResponseWriter, r *http.Request) {
-	jwk := newJwkResponse(os.keyID, os.privateKey.PublicKey)
+	os.mutex.RLock()
+	keys := make([]jwkResponse, 0, len(os.keys))
+	for _, key := range os.keys {
+		if jwk := key.jwk(); jwk != nil {
+			keys = append(keys, *jwk)
+		}
+	}
+	os.mutex.RUnlock()
+
 	responseBody, _ := json.Marshal(&struct {
 		Keys []jwkResponse `json:"keys"`
 	}{
-		Keys: []jwkResponse{*jwk},
+		Keys: keys,
 	})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(responseBody)
 }
+
+type signingKey struct {
+	id        string
+	algorithm SigningAlgorithm
+	signer    jwt.Signer
+	rsaKey    *rsa.PrivateKey
+	ecdsaKey  *ecdsa.PrivateKey
+}
+
+func newSigningKey(id string, algorithm SigningAlgorithm) *signingKey {
+	key := &signingKey{
+		id:        id,
+		algorithm: algorithm,
+	}
+	switch algorithm {
+	case RS256:
+		key.rsaKey = generatePrivateKey()
+		key.signer = jwt.RS256(key.rsaKey, &key.rsaKey.PublicKey)
+	case ES256:
+		ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		key.ecdsaKey = ecdsaKey
+		key.signer = jwt.ES256(ecdsaKey, &ecdsaKey.PublicKey)
+	case HS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+		key.signer = jwt.HS256(base64.RawURLEncoding.EncodeToString(secret))
+	default:
+		panic(fmt.Sprintf("unsupported signing algorithm %s", algorithm))
+	}
+	return key
+}
+
+func (k *signingKey) jwk() *jwkResponse {
+	switch k.algorithm {
+	case RS256:
+		return newJwkResponse(k.id, k.rsaKey.PublicKey)
+	case ES256:
+		return newECJwkResponse(k.id, k.ecdsaKey.PublicKey)
+	default:
+		return nil
+	}
+}
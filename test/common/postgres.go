@@ -0,0 +1,105 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ThrowawayPostgresEnvVar is the environment variable which makes all test contexts use a throwaway Postgres
+// when it is set to "docker"
+const ThrowawayPostgresEnvVar = "SM_TEST_POSTGRES"
+
+const (
+	postgresImage        = "postgres:10-alpine"
+	postgresPassword     = "postgres"
+	postgresStartTimeout = 60 * time.Second
+)
+
+// PostgresContainer is a throwaway Postgres database running in a docker container
+type PostgresContainer struct {
+	ID  string
+	URI string
+}
+
+// StartPostgresContainer starts a Postgres docker container on a random local port and waits until the database
+// accepts connections. The Service Manager migrations are applied when the storage is opened.
+func StartPostgresContainer() (*PostgresContainer, error) {
+	output, err := docker("run", "-d", "--rm", "-e", "POSTGRES_PASSWORD="+postgresPassword, "-p", "127.0.0.1::5432", postgresImage)
+	if err != nil {
+		return nil, fmt.Errorf("could not start postgres container: %s", err)
+	}
+	container := &PostgresContainer{ID: output}
+
+	address, err := docker("port", container.ID, "5432/tcp")
+	if err != nil {
+		container.Stop()
+		return nil, fmt.Errorf("could not get postgres container port: %s", err)
+	}
+	container.URI = fmt.Sprintf("postgres://postgres:%s@%s/postgres?sslmode=disable", postgresPassword, strings.Split(address, "\n")[0])
+
+	if err := container.waitUntilReady(); err != nil {
+		container.Stop()
+		return nil, err
+	}
+	return container, nil
+}
+
+// Stop removes the container together with its data
+func (c *PostgresContainer) Stop() {
+	if c == nil {
+		return
+	}
+	if _, err := docker("rm", "-f", "-v", c.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "could not remove postgres container %s: %s\n", c.ID, err)
+	}
+}
+
+func (c *PostgresContainer) waitUntilReady() error {
+	db, err := sql.Open("postgres", c.URI)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	deadline := time.Now().Add(postgresStartTimeout)
+	for {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres container did not become ready in %s: %s", postgresStartTimeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func docker(args ...string) (string, error) {
+	output, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %s: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
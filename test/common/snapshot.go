@@ -0,0 +1,150 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// StateSnapshot is a copy of the Service Manager tables and the fake servers of a TestContext
+type StateSnapshot struct {
+	schema  string
+	tables  []string
+	servers map[string]FakeServer
+}
+
+// SnapshotState copies all Service Manager tables to a separate schema so that they can be restored with RestoreState.
+// Restoring a snapshot is considerably faster than deleting and recreating brokers and platforms before each spec.
+// Snapshots are dropped on cleanup of the test context.
+func (ctx *TestContext) SnapshotState() *StateSnapshot {
+	id, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	snapshot := &StateSnapshot{
+		schema:  "sm_snapshot_" + strings.Replace(id.String(), "-", "", -1),
+		servers: make(map[string]FakeServer, len(ctx.Servers)),
+	}
+	for name, server := range ctx.Servers {
+		snapshot.servers[name] = server
+	}
+
+	err = ctx.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'
+			ORDER BY table_name`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				return err
+			}
+			snapshot.tables = append(snapshot.tables, table)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("CREATE SCHEMA " + snapshot.schema); err != nil {
+			return err
+		}
+		for _, table := range snapshot.tables {
+			if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s.%s AS TABLE %s", snapshot.schema, table, table)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("could not snapshot Service Manager state: %s", err))
+	}
+
+	ctx.snapshots = append(ctx.snapshots, snapshot)
+	return snapshot
+}
+
+// RestoreState restores the Service Manager tables to the provided snapshot and closes the fake servers which were
+// started after the snapshot was taken. Websocket connections should be closed before the state is restored as the
+// notifications known to the connected platforms might no longer exist.
+func (ctx *TestContext) RestoreState(snapshot *StateSnapshot) {
+	for name, server := range ctx.Servers {
+		if _, found := snapshot.servers[name]; !found {
+			server.Close()
+			delete(ctx.Servers, name)
+		}
+	}
+
+	err := ctx.inTransaction(func(tx *sql.Tx) error {
+		// foreign keys are not checked while restoring as the tables are restored one after another
+		if _, err := tx.Exec("SET LOCAL session_replication_role = replica"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("TRUNCATE " + strings.Join(snapshot.tables, ", ")); err != nil {
+			return err
+		}
+		for _, table := range snapshot.tables {
+			if _, err := tx.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM %s.%s", table, snapshot.schema, table)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("could not restore Service Manager state: %s", err))
+	}
+}
+
+func (ctx *TestContext) dropSnapshots() {
+	for _, snapshot := range ctx.snapshots {
+		err := ctx.inTransaction(func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP SCHEMA IF EXISTS " + snapshot.schema + " CASCADE")
+			return err
+		})
+		if err != nil {
+			Print("Could not drop snapshot %s: %s", snapshot.schema, err)
+		}
+	}
+	ctx.snapshots = nil
+}
+
+func (ctx *TestContext) inTransaction(f func(tx *sql.Tx) error) error {
+	db, err := sql.Open("postgres", ctx.storageURI)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%s: could not rollback transaction: %s", err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
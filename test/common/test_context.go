@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
 	"runtime"
 	"sync"
@@ -32,6 +33,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/onsi/ginkgo"
+	ginkgoconfig "github.com/onsi/ginkgo/config"
 	"github.com/spf13/pflag"
 
 	"github.com/Peripli/service-manager/config"
@@ -61,6 +63,8 @@ type TestContextBuilder struct {
 	defaultTokenClaims map[string]interface{}
 
 	shouldSkipBasicAuthClient bool
+	useThrowawayPostgres      bool
+	useIsolatedDatabase       bool
 
 	Environment func(f ...func(set *pflag.FlagSet)) env.Environment
 	Servers     map[string]FakeServer
@@ -78,6 +82,11 @@ type TestContext struct {
 	TestPlatform *types.Platform
 
 	Servers map[string]FakeServer
+
+	postgres   *PostgresContainer
+	database   *IsolatedDatabase
+	storageURI string
+	snapshots  []*StateSnapshot
 }
 
 type testSMServer struct {
@@ -121,8 +130,10 @@ func NewTestContextBuilder() *TestContextBuilder {
 				})
 			},
 		},
-		smExtensions:       []func(ctx context.Context, smb *sm.ServiceManagerBuilder, env env.Environment) error{},
-		defaultTokenClaims: make(map[string]interface{}, 0),
+		smExtensions:         []func(ctx context.Context, smb *sm.ServiceManagerBuilder, env env.Environment) error{},
+		defaultTokenClaims:   make(map[string]interface{}, 0),
+		useThrowawayPostgres: os.Getenv(ThrowawayPostgresEnvVar) == "docker",
+		useIsolatedDatabase:  ginkgoconfig.GinkgoConfig.ParallelTotal > 1 || os.Getenv(IsolatedDatabaseEnvVar) == "true",
 		Servers: map[string]FakeServer{
 			"oauth-server": NewOAuthServer(),
 		},
@@ -176,6 +187,23 @@ func (tcb *TestContextBuilder) SkipBasicAuthClientSetup(shouldSkip bool) *TestCo
 	return tcb
 }
 
+// WithThrowawayPostgres makes the test context run against a Postgres docker container which is started when the
+// context is built and removed on cleanup. The storage URI of the environment is ignored.
+func (tcb *TestContextBuilder) WithThrowawayPostgres() *TestContextBuilder {
+	tcb.useThrowawayPostgres = true
+
+	return tcb
+}
+
+// WithIsolatedDatabase makes the test context run against its own database which is created when the context is built
+// and dropped on cleanup. This is the default when the tests are run on multiple ginkgo nodes or when the
+// IsolatedDatabaseEnvVar is set to "true".
+func (tcb *TestContextBuilder) WithIsolatedDatabase() *TestContextBuilder {
+	tcb.useIsolatedDatabase = true
+
+	return tcb
+}
+
 func (tcb *TestContextBuilder) WithDefaultEnv(envCreateFunc func(f ...func(set *pflag.FlagSet)) env.Environment) *TestContextBuilder {
 	tcb.Environment = envCreateFunc
 
@@ -224,6 +252,24 @@ func (tcb *TestContextBuilder) Build() *TestContext {
 	for _, envPostHook := range tcb.envPostHooks {
 		envPostHook(environment, tcb.Servers)
 	}
+
+	var postgres *PostgresContainer
+	if tcb.useThrowawayPostgres {
+		var err error
+		if postgres, err = StartPostgresContainer(); err != nil {
+			panic(err)
+		}
+		environment.Set("storage.uri", postgres.URI)
+	}
+
+	var database *IsolatedDatabase
+	if tcb.useIsolatedDatabase {
+		var err error
+		if database, err = CreateIsolatedDatabase(fmt.Sprint(environment.Get("storage.uri"))); err != nil {
+			panic(err)
+		}
+		environment.Set("storage.uri", database.URI)
+	}
 	wg := &sync.WaitGroup{}
 
 	smServer, smRepository := newSMServer(environment, wg, tcb.smExtensions)
@@ -244,6 +290,9 @@ func (tcb *TestContextBuilder) Build() *TestContext {
 		SMWithOAuth:  SMWithOAuth,
 		Servers:      tcb.Servers,
 		SMRepository: smRepository,
+		postgres:     postgres,
+		database:     database,
+		storageURI:   fmt.Sprint(environment.Get("storage.uri")),
 	}
 
 	if !tcb.shouldSkipBasicAuthClient {
@@ -300,6 +349,7 @@ func newSMServer(smEnv env.Environment, wg *sync.WaitGroup, fs []func(ctx contex
 	if err != nil {
 		panic(err)
 	}
+	smb.WSConnections.Start(ctx, wg)
 
 	return &testSMServer{
 		cancel: cancel,
@@ -404,6 +454,10 @@ func (ctx *TestContext) Cleanup() {
 	ctx.Servers = map[string]FakeServer{}
 
 	ctx.wg.Wait()
+
+	ctx.dropSnapshots()
+	ctx.database.Drop()
+	ctx.postgres.Stop()
 }
 
 func (ctx *TestContext) CleanupAdditionalResources() {
@@ -416,6 +470,17 @@ func (ctx *TestContext) CleanupAdditionalResources() {
 		panic(err)
 	}
 
+	_, err = ctx.SMRepository.Delete(context.TODO(), types.OperationObjectType)
+	if err != nil && err != util.ErrNotFoundInStorage {
+		panic(err)
+	}
+
+	// locks of other users would block the deletion of the resources
+	_, err = ctx.SMRepository.Delete(context.TODO(), types.LockType)
+	if err != nil && err != util.ErrNotFoundInStorage {
+		panic(err)
+	}
+
 	ctx.SMWithOAuth.DELETE("/v1/service_brokers").Expect()
 
 	if ctx.TestPlatform != nil {
@@ -423,6 +488,11 @@ func (ctx *TestContext) CleanupAdditionalResources() {
 	} else {
 		ctx.SMWithOAuth.DELETE("/v1/platforms").Expect()
 	}
+
+	_, err = ctx.SMRepository.Delete(context.TODO(), types.RevisionType)
+	if err != nil && err != util.ErrNotFoundInStorage {
+		panic(err)
+	}
 	var smServer FakeServer
 	for serverName, server := range ctx.Servers {
 		if serverName == SMServer {
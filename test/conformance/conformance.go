@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package conformance contains API tests which only use the public Service Manager API and can therefore be run against
+// any Service Manager deployment to verify that it is installed and configured properly.
+package conformance
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/gavv/httpexpect"
+	"github.com/gofrs/uuid"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// Settings specifies the Service Manager deployment the conformance tests are run against
+type Settings struct {
+	URL               string
+	Token             string
+	SkipSSLValidation bool
+}
+
+// AddFlags adds flags for the conformance settings to the provided flag set
+func AddFlags(set *pflag.FlagSet, settings *Settings) {
+	set.StringVar(&settings.URL, "sm-url", "", "base URL of the Service Manager deployment")
+	set.StringVar(&settings.Token, "token", "", "bearer token with which the Service Manager API is called")
+	set.BoolVar(&settings.SkipSSLValidation, "skip-ssl-validation", false, "whether to skip validation of the Service Manager certificate")
+}
+
+// Validate validates the conformance settings
+func (s *Settings) Validate() error {
+	if s.URL == "" {
+		return fmt.Errorf("validate Settings: URL missing")
+	}
+	if s.Token == "" {
+		return fmt.Errorf("validate Settings: Token missing")
+	}
+	return nil
+}
+
+// Run runs the conformance tests against the deployment specified by the settings and returns whether they passed
+func Run(t ginkgo.GinkgoTestingT, settings *Settings) bool {
+	gomega.RegisterFailHandler(ginkgo.Fail)
+	DescribeSpecs(func() *Settings {
+		return settings
+	})
+	return ginkgo.RunSpecs(t, "Service Manager Conformance Suite")
+}
+
+// DescribeSpecs registers the conformance specs. The settings are obtained when the first spec is run.
+func DescribeSpecs(settings func() *Settings) bool {
+	return ginkgo.Describe("Service Manager conformance", func() {
+		var (
+			once        sync.Once
+			SM          *httpexpect.Expect
+			SMWithOAuth *httpexpect.Expect
+		)
+
+		ginkgo.BeforeEach(func() {
+			once.Do(func() {
+				s := settings()
+				gomega.Expect(s.Validate()).To(gomega.Succeed())
+
+				SM = httpexpect.WithConfig(httpexpect.Config{
+					BaseURL:  s.URL,
+					Reporter: httpexpect.NewAssertReporter(ginkgo.GinkgoT()),
+					Client: &http.Client{
+						Transport: &http.Transport{
+							Proxy:           http.ProxyFromEnvironment,
+							TLSClientConfig: &tls.Config{InsecureSkipVerify: s.SkipSSLValidation},
+						},
+					},
+				})
+				SMWithOAuth = SM.Builder(func(req *httpexpect.Request) {
+					req.WithHeader("Authorization", "Bearer "+s.Token)
+				})
+			})
+		})
+
+		ginkgo.Describe("Info API", func() {
+			ginkgo.It("returns the token issuer", func() {
+				SM.GET(web.InfoURL).
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object().Keys().Contains("token_issuer_url")
+			})
+		})
+
+		ginkgo.Describe("Health API", func() {
+			ginkgo.It("reports the status of the deployment", func() {
+				SM.GET(web.MonitorHealthURL).
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object().Value("status").Equal("UP")
+			})
+		})
+
+		ginkgo.Describe("Authentication", func() {
+			ginkgo.It("rejects requests without credentials", func() {
+				SM.GET(web.PlatformsURL).
+					Expect().
+					Status(http.StatusUnauthorized)
+			})
+
+			ginkgo.It("rejects requests with invalid tokens", func() {
+				SM.GET(web.PlatformsURL).
+					WithHeader("Authorization", "Bearer invalid").
+					Expect().
+					Status(http.StatusUnauthorized)
+			})
+		})
+
+		ginkgo.Describe("Resource APIs", func() {
+			for _, url := range []string{web.ServiceBrokersURL, web.PlatformsURL, web.ServiceOfferingsURL, web.ServicePlansURL, web.VisibilitiesURL} {
+				url := url
+				ginkgo.It("lists "+url, func() {
+					SMWithOAuth.GET(url).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Keys().Contains(path.Base(url))
+				})
+			}
+		})
+
+		ginkgo.Describe("Platforms API", func() {
+			var platformID, platformName string
+
+			ginkgo.BeforeEach(func() {
+				platformName = "conformance-" + randomID()
+				platform := SMWithOAuth.POST(web.PlatformsURL).
+					WithJSON(map[string]interface{}{
+						"name":        platformName,
+						"type":        "conformance",
+						"description": "created by the Service Manager conformance tests",
+						"labels": map[string]interface{}{
+							"conformance": []string{"true"},
+						},
+					}).
+					Expect().
+					Status(http.StatusCreated).
+					JSON().Object()
+				platform.Value("credentials").Object().Value("basic").Object().Keys().Contains("username", "password")
+				platformID = platform.Value("id").String().Raw()
+			})
+
+			ginkgo.AfterEach(func() {
+				SMWithOAuth.DELETE(web.PlatformsURL + "/" + platformID).Expect()
+			})
+
+			ginkgo.It("returns created platforms without their credentials", func() {
+				platform := SMWithOAuth.GET(web.PlatformsURL + "/" + platformID).
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object()
+				platform.Value("name").Equal(platformName)
+				platform.Keys().NotContains("credentials")
+			})
+
+			ginkgo.It("filters platforms by field and label queries", func() {
+				SMWithOAuth.GET(web.PlatformsURL).
+					WithQuery("fieldQuery", "name = "+platformName).
+					WithQuery("labelQuery", "conformance = true").
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object().Value("platforms").Array().Length().Equal(1)
+			})
+
+			ginkgo.It("rejects platforms with duplicate names", func() {
+				SMWithOAuth.POST(web.PlatformsURL).
+					WithJSON(map[string]interface{}{
+						"name": platformName,
+						"type": "conformance",
+					}).
+					Expect().
+					Status(http.StatusConflict)
+			})
+
+			ginkgo.It("updates platforms", func() {
+				SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).
+					WithJSON(map[string]interface{}{
+						"description": "updated",
+					}).
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object().Value("description").Equal("updated")
+			})
+
+			ginkgo.It("deletes platforms", func() {
+				SMWithOAuth.DELETE(web.PlatformsURL + "/" + platformID).
+					Expect().
+					Status(http.StatusOK)
+				SMWithOAuth.GET(web.PlatformsURL + "/" + platformID).
+					Expect().
+					Status(http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func randomID() string {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return UUID.String()
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/Peripli/service-manager/test/conformance"
+	. "github.com/onsi/ginkgo"
+)
+
+// TestConformance runs the conformance tests against a local Service Manager to verify the conformance tests themselves
+func TestConformance(t *testing.T) {
+	conformance.Run(t, settings)
+}
+
+var (
+	ctx      *common.TestContext
+	settings = &conformance.Settings{}
+)
+
+var _ = BeforeSuite(func() {
+	ctx = common.DefaultTestContext()
+
+	settings.URL = ctx.Servers[common.SMServer].URL()
+	settings.Token = ctx.Servers[common.OauthServer].(*common.OAuthServer).CreateToken(map[string]interface{}{})
+})
+
+var _ = AfterSuite(func() {
+	ctx.Cleanup()
+})
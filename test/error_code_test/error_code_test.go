@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package error_code_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestErrorCodes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Error Codes Suite")
+}
+
+var _ = Describe("Error codes", func() {
+	var ctx *common.TestContext
+
+	BeforeEach(func() {
+		ctx = common.DefaultTestContext()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("documents the error codes without authentication", func() {
+		items := ctx.SM.GET(web.ErrorCodesURL).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("items").Array()
+		items.NotEmpty()
+		items.Element(0).Object().Keys().ContainsOnly("code", "name", "status_code", "description")
+	})
+
+	It("returns the code of the error with the error response", func() {
+		ctx.SMWithOAuth.GET(web.ServiceBrokersURL+"/unknown").
+			Expect().Status(http.StatusNotFound).
+			JSON().Object().ValueEqual("code", "SM-1005")
+	})
+
+	It("returns the code of invalid queries", func() {
+		ctx.SMWithOAuth.GET(web.ServiceBrokersURL).WithQuery("fieldQuery", "unknown_field eq 'value'").
+			Expect().Status(http.StatusBadRequest).
+			JSON().Object().ContainsKey("code")
+	})
+})
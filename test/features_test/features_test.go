@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package features_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFeatures(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Features Suite")
+}
+
+var _ = Describe("Features API", func() {
+	var ctx *common.TestContext
+
+	featureURL := web.FeaturesURL + "/" + api.AsyncOperationsFeature
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().
+			WithDefaultTokenClaims(map[string]interface{}{"scope": []string{"sm.admin"}}).
+			Build()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	It("lists the registered features", func() {
+		features := ctx.SMWithOAuth.GET(web.FeaturesURL).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("features").Array()
+		features.Length().Equal(1)
+		features.Element(0).Object().
+			ValueEqual("name", api.AsyncOperationsFeature).
+			ValueEqual("enabled", true).
+			ValueEqual("origin", "default")
+	})
+
+	It("requires authentication", func() {
+		ctx.SM.PUT(featureURL).
+			WithJSON(common.Object{"enabled": false}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	It("requires the admin scope for changing the state", func() {
+		token := ctx.Servers[common.OauthServer].(*common.OAuthServer).CreateToken(map[string]interface{}{
+			"scope": []string{"sm.read"},
+		})
+		smWithoutScope := ctx.SM.Builder(func(req *httpexpect.Request) {
+			req.WithHeader("Authorization", "Bearer "+token)
+		})
+
+		smWithoutScope.PUT(featureURL).
+			WithJSON(common.Object{"enabled": false}).
+			Expect().
+			Status(http.StatusForbidden)
+		smWithoutScope.DELETE(featureURL).
+			Expect().
+			Status(http.StatusForbidden)
+		smWithoutScope.GET(web.FeaturesURL).
+			Expect().
+			Status(http.StatusOK)
+	})
+
+	It("returns 404 for unknown features", func() {
+		ctx.SMWithOAuth.PUT(web.FeaturesURL + "/unknown").
+			WithJSON(common.Object{"enabled": false}).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	It("returns 400 if the state is missing", func() {
+		ctx.SMWithOAuth.PUT(featureURL).
+			WithJSON(common.Object{}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	Context("when asynchronous operations are disabled", func() {
+		BeforeEach(func() {
+			ctx.SMWithOAuth.PUT(featureURL).
+				WithJSON(common.Object{"enabled": false}).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				ValueEqual("enabled", false).
+				ValueEqual("origin", "store")
+		})
+
+		It("registers brokers synchronously", func() {
+			brokerServer := common.NewBrokerServer()
+			defer brokerServer.Close()
+
+			ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+				WithQuery("async", "true").
+				WithJSON(common.Object{
+					"name":       "broker",
+					"broker_url": brokerServer.URL(),
+					"credentials": common.Object{
+						"basic": common.Object{
+							"username": brokerServer.Username,
+							"password": brokerServer.Password,
+						},
+					},
+				}).
+				Expect().
+				Status(http.StatusCreated)
+		})
+
+		It("resolves the state from the settings again when it is reset", func() {
+			ctx.SMWithOAuth.DELETE(featureURL).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				ValueEqual("enabled", true).
+				ValueEqual("origin", "default")
+		})
+	})
+})
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package history_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHistory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "History Suite")
+}
+
+var _ = Describe("Resource history", func() {
+	var (
+		ctx      *common.TestContext
+		brokerID string
+	)
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().Build()
+		brokerID, _, _ = ctx.RegisterBroker()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	historyURL := func(id string) string {
+		return web.ServiceBrokersURL + "/" + id + "/history"
+	}
+
+	It("contains the creation of the broker by the acting user", func() {
+		history := ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+			Status(http.StatusOK).JSON().Object().Value("history").Array()
+		history.Length().Equal(1)
+
+		created := history.Element(0).Object()
+		created.Value("operation").Equal("create")
+		created.Value("user").Equal("testUser")
+		created.Value("changes").Array().NotEmpty()
+	})
+
+	It("contains the changed fields of updates", func() {
+		ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).WithJSON(common.Object{
+			"description": "new description",
+		}).Expect().Status(http.StatusOK)
+
+		history := ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+			Status(http.StatusOK).JSON().Object().Value("history").Array()
+		history.Length().Equal(2)
+
+		updated := history.Element(1).Object()
+		updated.Value("operation").Equal("update")
+		changes := updated.Value("changes").Array()
+		changes.Length().Equal(1)
+		changes.Element(0).Object().Value("field").Equal("description")
+		changes.Element(0).Object().Value("new").Equal("new description")
+	})
+
+	It("does not contain the credentials", func() {
+		ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+			Status(http.StatusOK).Body().NotContains("credentials")
+	})
+
+	It("is kept after the deletion", func() {
+		ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + brokerID).Expect().Status(http.StatusOK)
+
+		history := ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+			Status(http.StatusOK).JSON().Object().Value("history").Array()
+		history.Length().Equal(2)
+		history.Element(1).Object().Value("operation").Equal("delete")
+	})
+
+	It("returns 404 for unknown resources", func() {
+		ctx.SMWithOAuth.GET(historyURL("unknown")).Expect().Status(http.StatusNotFound)
+	})
+
+	Describe("revert", func() {
+		revertURL := func(id string) string {
+			return web.ServiceBrokersURL + "/" + id + "/revert"
+		}
+
+		It("restores a previous revision", func() {
+			ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).WithJSON(common.Object{
+				"description": "bad description",
+			}).Expect().Status(http.StatusOK)
+
+			history := ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+				Status(http.StatusOK).JSON().Object().Value("history").Array()
+			createdRevision := history.Element(0).Object().Value("id").String().Raw()
+			description := ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + brokerID).Expect().
+				Status(http.StatusOK).JSON().Object().Value("description").String().Raw()
+			Expect(description).To(Equal("bad description"))
+
+			reverted := ctx.SMWithOAuth.POST(revertURL(brokerID)).WithQuery("to_revision", createdRevision).Expect().
+				Status(http.StatusOK).JSON().Object()
+			reverted.NotContainsKey("credentials")
+			reverted.Value("description").NotEqual("bad description")
+
+			ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+				Status(http.StatusOK).JSON().Object().Value("history").Array().Length().Equal(3)
+		})
+
+		It("requires the revision", func() {
+			ctx.SMWithOAuth.POST(revertURL(brokerID)).Expect().Status(http.StatusBadRequest)
+		})
+
+		It("returns 404 for revisions of other resources", func() {
+			otherID, _, _ := ctx.RegisterBroker()
+			otherRevision := ctx.SMWithOAuth.GET(historyURL(otherID)).Expect().
+				Status(http.StatusOK).JSON().Path("$.history[0].id").String().Raw()
+
+			ctx.SMWithOAuth.POST(revertURL(brokerID)).WithQuery("to_revision", otherRevision).Expect().
+				Status(http.StatusNotFound)
+		})
+
+		It("rejects reverting to a deletion", func() {
+			ctx.SMWithOAuth.DELETE(web.ServiceBrokersURL + "/" + brokerID).Expect().Status(http.StatusOK)
+			deletion := ctx.SMWithOAuth.GET(historyURL(brokerID)).Expect().
+				Status(http.StatusOK).JSON().Path("$.history[1].id").String().Raw()
+
+			ctx.SMWithOAuth.POST(revertURL(brokerID)).WithQuery("to_revision", deletion).Expect().
+				Status(http.StatusBadRequest)
+		})
+	})
+})
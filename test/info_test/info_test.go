@@ -23,6 +23,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/env"
 
 	"github.com/Peripli/service-manager/api/info"
+	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/test/common"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -67,4 +68,28 @@ var _ = Describe("Info API", func() {
 			})
 		})
 	}
+
+	It("Returns the query grammar and the queryable fields", func() {
+		ctx := common.DefaultTestContext()
+		defer ctx.Cleanup()
+
+		body := ctx.SM.GET(info.QueryURL).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+
+		body.Value("separator").Equal("|")
+		body.Value("multivalue_separator").Equal("||")
+		body.Value("criterion_types").Array().Contains("fieldQuery", "labelQuery")
+		body.Value("operators").Array().Contains(common.Object{
+			"name":         "in",
+			"multivariate": true,
+			"numeric":      false,
+			"nullable":     false,
+		})
+		body.Value("fields").Object().Value(string(types.ServiceBrokerType)).Array().Contains(
+			common.Object{"name": "id", "type": "string"},
+			common.Object{"name": "created_at", "type": "datetime"},
+		)
+	})
 })
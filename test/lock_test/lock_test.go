@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package lock_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLocks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Lock Suite")
+}
+
+var _ = Describe("Resource locks", func() {
+	var (
+		ctx        *common.TestContext
+		automation *httpexpect.Expect
+		brokerID   string
+	)
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().
+			WithDefaultTokenClaims(map[string]interface{}{"user_name": "operator"}).
+			Build()
+		token := ctx.Servers[common.OauthServer].(*common.OAuthServer).CreateToken(map[string]interface{}{
+			"user_name": "automation",
+		})
+		automation = ctx.SM.Builder(func(req *httpexpect.Request) {
+			req.WithHeader("Authorization", "Bearer "+token)
+		})
+		brokerID, _, _ = ctx.RegisterBroker()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	brokerURL := func() string {
+		return web.ServiceBrokersURL + "/" + brokerID
+	}
+
+	lockURL := func() string {
+		return brokerURL() + "/lock"
+	}
+
+	lock := func() {
+		ctx.SMWithOAuth.PUT(lockURL()).WithJSON(common.Object{
+			"reason": "debugging catalog issues",
+			"ttl":    "30m",
+		}).Expect().Status(http.StatusCreated)
+	}
+
+	It("places a lock with owner, reason and expiration", func() {
+		lock()
+
+		locked := ctx.SMWithOAuth.GET(lockURL()).Expect().Status(http.StatusOK).JSON().Object()
+		locked.Value("owner").Equal("operator")
+		locked.Value("reason").Equal("debugging catalog issues")
+		locked.Value("resource_id").Equal(brokerID)
+		locked.Value("expires_at").String().NotEmpty()
+	})
+
+	It("blocks updates and deletions by other users", func() {
+		lock()
+
+		automation.PATCH(brokerURL()).WithJSON(common.Object{"description": "changed"}).Expect().
+			Status(http.StatusLocked).JSON().Object().Value("description").String().Contains("debugging catalog issues")
+		automation.DELETE(brokerURL()).Expect().Status(http.StatusLocked)
+		ctx.SMWithOAuth.GET(brokerURL()).Expect().Status(http.StatusOK)
+	})
+
+	It("allows updates and deletions by the owner", func() {
+		lock()
+
+		ctx.SMWithOAuth.PATCH(brokerURL()).WithJSON(common.Object{"description": "changed"}).Expect().Status(http.StatusOK)
+		ctx.SMWithOAuth.DELETE(brokerURL()).Expect().Status(http.StatusOK)
+		ctx.SMWithOAuth.GET(lockURL()).Expect().Status(http.StatusNotFound)
+	})
+
+	It("blocks the deletion by other users in asynchronous bulk deletes", func() {
+		otherBrokerID, _, _ := ctx.RegisterBroker()
+		lock()
+
+		operationID := automation.DELETE(web.ServiceBrokersURL).WithQuery("async", "true").Expect().
+			Status(http.StatusAccepted).JSON().Object().Value("id").String().Raw()
+
+		Eventually(func() string {
+			return automation.GET(web.OperationsURL + "/" + operationID).Expect().Status(http.StatusOK).
+				JSON().Object().Value("state").String().Raw()
+		}, 10*time.Second, 100*time.Millisecond).Should(Equal(string(types.FAILED)))
+
+		failures := automation.GET(web.OperationsURL + "/" + operationID).Expect().Status(http.StatusOK).
+			JSON().Object().Value("errors").Object().Value("resources").Array()
+		failures.Length().Equal(1)
+		failures.First().Object().Value("id").Equal(brokerID)
+		failures.First().Object().Value("error").String().Contains("locked by operator")
+
+		ctx.SMWithOAuth.GET(brokerURL()).Expect().Status(http.StatusOK)
+		ctx.SMWithOAuth.GET(web.ServiceBrokersURL + "/" + otherBrokerID).Expect().Status(http.StatusNotFound)
+	})
+
+	It("does not allow other users to take over the lock", func() {
+		lock()
+
+		automation.PUT(lockURL()).WithJSON(common.Object{"reason": "re-register"}).Expect().Status(http.StatusConflict)
+	})
+
+	It("is extended by the owner", func() {
+		lock()
+
+		ctx.SMWithOAuth.PUT(lockURL()).WithJSON(common.Object{"reason": "still debugging", "ttl": "2h"}).Expect().
+			Status(http.StatusOK).JSON().Object().Value("reason").Equal("still debugging")
+	})
+
+	It("is removed by other users only with force", func() {
+		lock()
+
+		automation.DELETE(lockURL()).Expect().Status(http.StatusConflict)
+		automation.DELETE(lockURL()).WithQuery("force", "true").Expect().Status(http.StatusOK)
+		automation.PATCH(brokerURL()).WithJSON(common.Object{"description": "changed"}).Expect().Status(http.StatusOK)
+	})
+
+	It("rejects invalid lock requests", func() {
+		ctx.SMWithOAuth.PUT(lockURL()).WithJSON(common.Object{"ttl": "1h"}).Expect().Status(http.StatusBadRequest)
+		ctx.SMWithOAuth.PUT(lockURL()).WithJSON(common.Object{"reason": "debugging", "ttl": "forever"}).Expect().Status(http.StatusBadRequest)
+		ctx.SMWithOAuth.PUT(lockURL()).WithJSON(common.Object{"reason": "debugging", "ttl": "48h"}).Expect().Status(http.StatusBadRequest)
+	})
+
+	It("returns 404 for unknown resources", func() {
+		ctx.SMWithOAuth.PUT(web.ServiceBrokersURL + "/unknown/lock").WithJSON(common.Object{"reason": "debugging"}).Expect().
+			Status(http.StatusNotFound)
+	})
+})
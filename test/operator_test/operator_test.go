@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operator_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOperator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Operator Suite")
+}
+
+var _ = Describe("Operator API", func() {
+	var (
+		ctx    *common.TestContext
+		planID string
+	)
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().WithEnvPostExtensions(func(e env.Environment, servers map[string]common.FakeServer) {
+			e.Set("api.operator_mode", true)
+			e.Set("api.field_scopes", []string{web.VisibilitiesURL + ":labels.internal.*=sm.internal"})
+		}).Build()
+		ctx.RegisterBroker()
+		planID = ctx.SMWithOAuth.GET(web.ServicePlansURL).Expect().
+			Status(http.StatusOK).JSON().Path("$.service_plans[0].id").String().Raw()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	Describe("PUT", func() {
+		visibilityURL := web.VisibilitiesURL + "/visibility-id"
+
+		It("creates the object and updates it only if its state changes", func() {
+			desired := common.Object{
+				"service_plan_id": planID,
+				"labels":          common.Object{"organization_guid": common.Array{"org-1"}},
+			}
+			created := ctx.SMWithOAuth.PUT(visibilityURL).WithJSON(desired).Expect().
+				Status(http.StatusCreated).JSON().Object()
+			created.Value("id").Equal("visibility-id")
+			updatedAt := created.Value("updated_at").String().Raw()
+
+			ctx.SMWithOAuth.PUT(visibilityURL).WithJSON(desired).Expect().
+				Status(http.StatusOK).JSON().Object().Value("updated_at").Equal(updatedAt)
+
+			desired["labels"] = common.Object{"space_guid": common.Array{"space-1"}}
+			ctx.SMWithOAuth.PUT(visibilityURL).WithJSON(desired).Expect().
+				Status(http.StatusOK).JSON().Object().Value("labels").Equal(common.Object{"space_guid": common.Array{"space-1"}})
+		})
+
+		It("rejects an id in the body which differs from the path", func() {
+			ctx.SMWithOAuth.PUT(visibilityURL).WithJSON(common.Object{"id": "other", "service_plan_id": planID}).Expect().
+				Status(http.StatusBadRequest)
+		})
+	})
+
+	Describe("GET changes", func() {
+		It("returns the changes after the provided revision", func() {
+			ctx.SMWithOAuth.PUT(web.VisibilitiesURL + "/visibility-id").WithJSON(common.Object{"service_plan_id": planID}).Expect().
+				Status(http.StatusCreated)
+
+			changes := ctx.SMWithOAuth.GET(web.ChangesURL).WithQuery("resource", "visibilities").Expect().
+				Status(http.StatusOK).JSON().Object()
+			changes.Value("changes").Array().NotEmpty()
+			revision := changes.Value("revision").Number().Gt(0).Raw()
+
+			ctx.SMWithOAuth.GET(web.ChangesURL).WithQuery("since_revision", int64(revision)).Expect().
+				Status(http.StatusOK).JSON().Object().Value("changes").Array().Empty()
+		})
+
+		It("removes the fields the caller may not see from the changes", func() {
+			ctx.SMWithOAuth.PUT(web.VisibilitiesURL + "/visibility-id").WithJSON(common.Object{
+				"service_plan_id": planID,
+				"labels": common.Object{
+					"internal.owner":    common.Array{"team"},
+					"organization_guid": common.Array{"org-1"},
+				},
+			}).Expect().Status(http.StatusCreated)
+
+			changes := ctx.SMWithOAuth.GET(web.ChangesURL).WithQuery("resource", "visibilities").Expect().
+				Status(http.StatusOK).JSON().Object().Value("changes").Array()
+			changes.NotEmpty()
+			labels := changes.Last().Object().Path("$.payload.new.resource.labels").Object()
+			labels.ContainsKey("organization_guid")
+			labels.NotContainsKey("internal.owner")
+		})
+
+		It("returns 410 Gone for unknown revisions", func() {
+			ctx.SMWithOAuth.GET(web.ChangesURL).WithQuery("since_revision", 999999999).Expect().
+				Status(http.StatusGone)
+		})
+
+		It("rejects unsupported resources", func() {
+			ctx.SMWithOAuth.GET(web.ChangesURL).WithQuery("resource", "platforms").Expect().
+				Status(http.StatusBadRequest)
+		})
+	})
+})
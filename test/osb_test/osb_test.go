@@ -314,6 +314,18 @@ var _ = Describe("Service Manager OSB API", func() {
 			})
 		})
 
+		Context("when call contains headers", func() {
+			It("forwards only the headers permitted by the header policy", func() {
+				assertWorkingBrokerResponse(
+					ctx.SMWithBasic.PUT(smUrlToWorkingBroker+"/v2/service_instances/12345").WithHeader("X-Broker-API-Version", "oidc_authn.13").
+						WithHeader("X-Custom-Header", "value").WithHeader("Cookie", "session=1").
+						WithJSON(getDummyService()).Expect(), http.StatusCreated)
+
+				Expect(validBrokerServer.LastRequest.Header.Get("X-Custom-Header")).To(Equal("value"))
+				Expect(validBrokerServer.LastRequest.Header.Get("Cookie")).To(BeEmpty())
+			})
+		})
+
 		Context("when call contains query params", func() {
 			It("propagates them to the service broker", func() {
 				assertWorkingBrokerResponse(
@@ -569,6 +581,35 @@ var _ = Describe("Service Manager OSB API", func() {
 		})
 	})
 
+	Describe("Statistics", func() {
+		Context("when calls were proxied to the broker", func() {
+			It("returns the counts by operation and status code and the last error", func() {
+				ctx.SMWithBasic.PUT(smUrlToFailingBroker+"/v2/service_instances/12345").WithHeader("X-Broker-API-Version", "oidc_authn.13").
+					WithJSON(getDummyService()).Expect()
+
+				statistics := ctx.SMWithOAuth.GET("/v1/service_brokers/" + failingBrokerID + "/statistics").
+					Expect().
+					Status(http.StatusOK).
+					JSON().Object()
+
+				statistics.Value("broker_id").Equal(failingBrokerID)
+				statistics.Value("calls_by_operation").Object().
+					Value("provision").Object().
+					Value("406").Number().Gt(0)
+				statistics.Value("last_error").Object().
+					Value("error").String().Contains("Failing service broker error")
+			})
+		})
+
+		Context("when the broker does not exist", func() {
+			It("returns 404", func() {
+				ctx.SMWithOAuth.GET("/v1/service_brokers/missing/statistics").
+					Expect().
+					Status(http.StatusNotFound)
+			})
+		})
+	})
+
 	Describe("Prefixed broker path", func() {
 		Context("when call to working broker", func() {
 
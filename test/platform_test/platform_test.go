@@ -179,6 +179,7 @@ var _ = test.DescribeTestsFor(test.TestCase{
 						basic := reply.Value("credentials").Object().Value("basic").Object()
 						basic.Value("username").String().NotEmpty()
 						basic.Value("password").String().NotEmpty()
+						reply.Value("credentials_type").Equal("random")
 
 						By("GET returns the same platform")
 
@@ -186,6 +187,7 @@ var _ = test.DescribeTestsFor(test.TestCase{
 							Expect().Status(http.StatusOK).JSON().Object()
 
 						common.MapContains(reply.Raw(), platform)
+						reply.Value("credentials_type").Equal("random")
 					})
 				})
 			})
@@ -321,6 +323,34 @@ var _ = test.DescribeTestsFor(test.TestCase{
 					})
 				})
 			})
+
+			Describe("POST credentials", func() {
+				It("replaces the credentials of the platform", func() {
+					platform := ctx.SMWithOAuth.POST("/v1/platforms").
+						WithJSON(common.MakePlatform("p1", "cf-10", "cf", "descr")).
+						Expect().Status(http.StatusCreated).JSON().Object()
+					oldBasic := platform.Value("credentials").Object().Value("basic").Object()
+					oldUsername := oldBasic.Value("username").String().Raw()
+					oldPassword := oldBasic.Value("password").String().Raw()
+
+					reply := ctx.SMWithOAuth.POST("/v1/platforms/p1/credentials").
+						Expect().Status(http.StatusOK).JSON().Object()
+					newBasic := reply.Value("credentials").Object().Value("basic").Object()
+					newUsername := newBasic.Value("username").String().NotEqual(oldUsername).Raw()
+					newPassword := newBasic.Value("password").String().NotEqual(oldPassword).Raw()
+					reply.Value("credentials_type").Equal("random")
+
+					ctx.SM.GET("/v1/platforms").WithBasicAuth(oldUsername, oldPassword).
+						Expect().Status(http.StatusUnauthorized)
+					ctx.SM.GET("/v1/platforms").WithBasicAuth(newUsername, newPassword).
+						Expect().Status(http.StatusOK)
+				})
+
+				It("returns 404 for a missing platform", func() {
+					ctx.SMWithOAuth.POST("/v1/platforms/missing/credentials").
+						Expect().Status(http.StatusNotFound)
+				})
+			})
 		})
 	},
 })
@@ -157,6 +157,7 @@ var _ = Describe("Service Manager Plugins", func() {
 				Expect().Status(http.StatusBadRequest).JSON().Object().Equal(object{
 				"error":       "PluginErr",
 				"description": "Plugin error",
+				"code":        util.ClientErrorCode.Code,
 			})
 
 			Expect(len(brokerServer.CatalogEndpointRequests)).To(Equal(0))
@@ -17,6 +17,7 @@
 package service_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
@@ -29,6 +30,8 @@ import (
 	. "github.com/onsi/ginkgo"
 
 	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 func TestServicePlans(t *testing.T) {
@@ -94,6 +97,144 @@ var _ = test.DescribeTestsFor(test.TestCase{
 				})
 			})
 
+			Describe("Lifecycle", func() {
+				var id string
+
+				BeforeEach(func() {
+					id = blueprint(ctx)["id"].(string)
+				})
+
+				It("can be patched", func() {
+					ctx.SMWithOAuth.PATCH(web.ServicePlansURL+"/"+id).
+						WithJSON(common.Object{"lifecycle": "deprecated"}).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().ValueEqual("lifecycle", "deprecated")
+
+					ctx.SMWithOAuth.GET(web.ServicePlansURL).
+						WithQuery("fieldQuery", fmt.Sprintf("id = %s|lifecycle = deprecated", id)).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("service_plans").Array().Length().Equal(1)
+				})
+
+				It("rejects unknown lifecycle states", func() {
+					ctx.SMWithOAuth.PATCH(web.ServicePlansURL + "/" + id).
+						WithJSON(common.Object{"lifecycle": "archived"}).
+						Expect().
+						Status(http.StatusBadRequest)
+				})
+			})
+
+			Describe("Maintenance info", func() {
+				var (
+					planID       string
+					brokerID     string
+					brokerServer *common.BrokerServer
+				)
+
+				withVersion := func(catalog common.SBCatalog, version string) common.SBCatalog {
+					updated, err := sjson.Set(string(catalog), "services.0.plans.0.maintenance_info.version", version)
+					Expect(err).ToNot(HaveOccurred())
+					return common.SBCatalog(updated)
+				}
+
+				planNotifications := func() []*types.Notification {
+					objectList, err := ctx.SMRepository.List(context.Background(), types.NotificationType,
+						query.ByField(query.EqualsOperator, "resource", string(types.ServicePlanType)))
+					Expect(err).ToNot(HaveOccurred())
+					notifications := make([]*types.Notification, 0, objectList.Len())
+					for i := 0; i < objectList.Len(); i++ {
+						notification := objectList.ItemAt(i).(*types.Notification)
+						if gjson.GetBytes(notification.Payload, "new.resource.id").String() == planID {
+							notifications = append(notifications, notification)
+						}
+					}
+					return notifications
+				}
+
+				BeforeEach(func() {
+					plan, err := sjson.Set(common.GeneratePaidTestPlan(), "maintenance_info", common.Object{"version": "1.0.0", "description": "upgrade"})
+					Expect(err).ToNot(HaveOccurred())
+					catalog := common.NewEmptySBCatalog()
+					catalog.AddService(common.GenerateTestServiceWithPlans(plan))
+					brokerID, _, brokerServer = ctx.RegisterBrokerWithCatalog(catalog)
+					planID = ctx.SMWithOAuth.GET(web.ServicePlansURL).
+						WithQuery("fieldQuery", "catalog_id = "+gjson.Get(plan, "id").String()).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("service_plans").Array().First().Object().Value("id").String().Raw()
+					brokerServer.Catalog = withVersion(catalog, "2.0.0")
+				})
+
+				It("is taken from the catalog", func() {
+					ctx.SMWithOAuth.GET(web.ServicePlansURL+"/"+planID).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().
+						ValueEqual("maintenance_info", common.Object{"version": "1.0.0", "description": "upgrade"})
+				})
+
+				It("notifies the platforms when its version changes", func() {
+					ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+						WithJSON(common.Object{}).
+						Expect().
+						Status(http.StatusOK)
+
+					ctx.SMWithOAuth.GET(web.ServicePlansURL+"/"+planID).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("maintenance_info").Object().ValueEqual("version", "2.0.0")
+
+					notifications := planNotifications()
+					Expect(notifications).To(HaveLen(1))
+					Expect(notifications[0].Type).To(Equal(types.MODIFIED))
+					Expect(notifications[0].PlatformID).To(BeEmpty())
+					Expect(gjson.GetBytes(notifications[0].Payload, "old.resource.maintenance_info.version").String()).To(Equal("1.0.0"))
+					Expect(gjson.GetBytes(notifications[0].Payload, "new.additional.broker_id").String()).To(Equal(brokerID))
+				})
+
+				It("does not notify the platforms when its version is unchanged", func() {
+					brokerServer.Catalog = withVersion(brokerServer.Catalog, "1.0.0")
+					ctx.SMWithOAuth.PATCH(web.ServiceBrokersURL + "/" + brokerID).
+						WithJSON(common.Object{}).
+						Expect().
+						Status(http.StatusOK)
+
+					Expect(planNotifications()).To(BeEmpty())
+				})
+			})
+
+			Describe("Pricing", func() {
+				var plan common.Object
+
+				BeforeEach(func() {
+					plan = blueprint(ctx)
+				})
+
+				It("is taken from the first costs in the catalog metadata", func() {
+					ctx.SMWithOAuth.GET(web.ServicePlansURL + "/" + plan["id"].(string)).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().
+						ContainsMap(common.Object{"price_amount": 199.0, "price_currency": "USD", "price_unit": "MONTHLY"})
+				})
+
+				It("can be queried with field queries", func() {
+					ctx.SMWithOAuth.GET(web.ServicePlansURL).
+						WithQuery("fieldQuery", fmt.Sprintf("id = %s|price_currency = USD|price_amount gt 100", plan["id"])).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("service_plans").Array().Length().Equal(1)
+
+					ctx.SMWithOAuth.GET(web.ServicePlansURL).
+						WithQuery("fieldQuery", fmt.Sprintf("id = %s|price_amount lt 100", plan["id"])).
+						Expect().
+						Status(http.StatusOK).
+						JSON().Object().Value("service_plans").Array().Empty()
+				})
+			})
+
 			Describe("Labelled", func() {
 				var id string
 
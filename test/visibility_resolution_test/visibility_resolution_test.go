@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package visibility_resolution_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestVisibilityResolution(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Visibility Resolution Suite")
+}
+
+var _ = Describe("Visibility resolution", func() {
+	var (
+		ctx       *common.TestContext
+		planIDs   []interface{}
+		platform  *types.Platform
+		unrelated *types.Platform
+	)
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().Build()
+		ctx.RegisterBroker()
+		planIDs = ctx.SMWithOAuth.GET(web.ServicePlansURL).Expect().
+			Status(http.StatusOK).JSON().Path("$.service_plans[*].id").Array().Raw()
+		Expect(len(planIDs)).To(BeNumerically(">=", 2))
+
+		platform = ctx.RegisterPlatform()
+		unrelated = ctx.RegisterPlatform()
+		common.RemoveAllVisibilities(ctx.SMWithOAuth)
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	createVisibility := func(visibility common.Object) {
+		ctx.SMWithOAuth.POST(web.VisibilitiesURL).WithJSON(visibility).Expect().Status(http.StatusCreated)
+	}
+
+	platformsOf := func(planID interface{}) []interface{} {
+		return ctx.SMWithOAuth.GET(web.ServicePlansURL + "/" + planID.(string) + "/platforms").Expect().
+			Status(http.StatusOK).JSON().Path("$.platforms[*].id").Array().Raw()
+	}
+
+	plansOf := func(platformID string) []interface{} {
+		return ctx.SMWithOAuth.GET(web.PlatformsURL + "/" + platformID + "/service_plans").Expect().
+			Status(http.StatusOK).JSON().Path("$.service_plans[*].id").Array().Raw()
+	}
+
+	It("resolves the visibilities of a platform", func() {
+		createVisibility(common.Object{
+			"platform_id":     platform.ID,
+			"service_plan_id": planIDs[0],
+		})
+
+		Expect(platformsOf(planIDs[0])).To(ConsistOf(platform.ID))
+		Expect(platformsOf(planIDs[1])).To(BeEmpty())
+		Expect(plansOf(platform.ID)).To(ConsistOf(planIDs[0]))
+		Expect(plansOf(unrelated.ID)).To(BeEmpty())
+	})
+
+	It("resolves the visibilities restricted by labels", func() {
+		createVisibility(common.Object{
+			"platform_id":     platform.ID,
+			"service_plan_id": planIDs[0],
+			"labels": common.Object{
+				"org_id": common.Array{"org"},
+			},
+		})
+
+		Expect(platformsOf(planIDs[0])).To(ConsistOf(platform.ID))
+		Expect(plansOf(platform.ID)).To(ConsistOf(planIDs[0]))
+	})
+
+	It("resolves the visibilities of all platforms", func() {
+		createVisibility(common.Object{
+			"service_plan_id": planIDs[1],
+		})
+
+		Expect(platformsOf(planIDs[1])).To(ContainElement(platform.ID))
+		Expect(platformsOf(planIDs[1])).To(ContainElement(unrelated.ID))
+		Expect(plansOf(platform.ID)).To(ConsistOf(planIDs[1]))
+	})
+
+	It("ignores the visibilities which are not in effect", func() {
+		createVisibility(common.Object{
+			"platform_id":     platform.ID,
+			"service_plan_id": planIDs[0],
+			"valid_from":      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+
+		Expect(platformsOf(planIDs[0])).To(BeEmpty())
+		Expect(plansOf(platform.ID)).To(BeEmpty())
+	})
+
+	It("does not return the credentials of the platforms", func() {
+		createVisibility(common.Object{
+			"platform_id":     platform.ID,
+			"service_plan_id": planIDs[0],
+		})
+
+		ctx.SMWithOAuth.GET(web.ServicePlansURL + "/" + planIDs[0].(string) + "/platforms").Expect().
+			Status(http.StatusOK).Body().NotContains("credentials")
+	})
+
+	It("returns 404 for unknown resources", func() {
+		ctx.SMWithOAuth.GET(web.ServicePlansURL + "/unknown/platforms").Expect().Status(http.StatusNotFound)
+		ctx.SMWithOAuth.GET(web.PlatformsURL + "/unknown/service_plans").Expect().Status(http.StatusNotFound)
+	})
+})
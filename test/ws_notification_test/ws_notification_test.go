@@ -245,6 +245,41 @@ var _ = Describe("WS", func() {
 		})
 	})
 
+	Context("when proxy reconnects with the last revision it knows", func() {
+		It("should receive the notifications created while it was disconnected", func() {
+			client, err := ctx.ConnectAsPlatform(platform, queryParams)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			notification := createNotification(repository, platform.ID)
+			client.ExpectNotification(common.WithID(notification.ID))
+
+			client.Close()
+			missedNotification := createNotification(repository, platform.ID)
+
+			client, err = client.Reconnect()
+			Expect(err).ShouldNot(HaveOccurred())
+			client.ExpectNotification(common.WithID(missedNotification.ID), common.OfType(types.CREATED))
+			client.ExpectNoNotification(100*time.Millisecond, common.WithID(notification.ID))
+		})
+	})
+
+	Context("when the catalog of a broker changes", func() {
+		It("should receive a broker notification with the refreshed catalog after refresh", func() {
+			brokerID, _, brokerServer := ctx.RegisterBroker()
+			client, err := ctx.ConnectAsPlatform(platform, queryParams)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			planID := brokerServer.AddPlan(0, common.GenerateFreeTestPlan())
+			serviceID := brokerServer.SetServiceBindable(0, false)
+			ctx.RefreshBrokerCatalog(brokerID)
+
+			client.ExpectNotification(common.OfType(types.MODIFIED), common.ForResource(types.ServiceBrokerType, brokerID),
+				common.WithCatalogPlan(planID), common.WithCatalogServiceBindable(serviceID, false))
+			ctx.ExpectServicePlan(planID)
+			ctx.ExpectServiceOffering(brokerID, serviceID).Value("bindable").Boolean().False()
+		})
+	})
+
 	Context("when revision known to proxy is invalid number", func() {
 		It("should return status 400", func() {
 			queryParams[notifications.LastKnownRevisionQueryParam] = "not_a_number"